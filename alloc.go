@@ -0,0 +1,123 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+)
+
+// Allocator represents an allocator of the memory which holds the values of the numeric
+// columns. The memory can be placed outside of the Go heap, in which case the garbage
+// collector neither scans nor reclaims it. Each buffer is freed explicitly, when its chunk
+// is released, when the column is dropped or when the collection is closed. The buffers
+// returned must be aligned to 8 bytes, and must remain valid until they are freed.
+type Allocator interface {
+	Alloc(size int) ([]byte, error)
+	Free(buffer []byte) error
+}
+
+// OffHeap allocates the values of the columns using anonymous memory maps, outside of the Go
+// heap. On the platforms which do not support them, the values are kept on the Go heap.
+var OffHeap Allocator = anonymousMemory{}
+
+// WithAllocator places the values of a numeric column in the memory provided by the specified
+// allocator, such as OffHeap, rather than on the Go heap. This avoids having the garbage
+// collector scan very large columns, while the values are read and written as usual. The
+// column must not be used once the collection has been closed, which frees the memory.
+func WithAllocator(allocator Allocator) ColumnOption {
+	return func(o *columnOptions) {
+		o.Allocator = allocator
+	}
+}
+
+// allocatable represents a column whose values can be placed in the memory of an allocator
+type allocatable interface {
+	allocateWith(allocator Allocator) error
+}
+
+// allocateColumn places the values of the column in the memory of the allocator
+func allocateColumn(column Column, allocator Allocator) error {
+	m, ok := column.(allocatable)
+	if !ok {
+		return fmt.Errorf("column: unable to allocate %T, its values are not of a fixed size", column)
+	}
+
+	return m.allocateWith(allocator)
+}
+
+// offHeap represents a column which holds its values in memory which is not managed by the
+// garbage collector, and which needs to be freed once the column is no longer used.
+type offHeap interface {
+	free() error
+}
+
+// anonymousMemory represents an allocator of anonymous memory maps
+type anonymousMemory struct{}
+
+// Alloc allocates a buffer of the specified size
+func (anonymousMemory) Alloc(size int) ([]byte, error) {
+	return allocAnonymous(size)
+}
+
+// Free frees a buffer previously allocated
+func (anonymousMemory) Free(buffer []byte) error {
+	return freeAnonymous(buffer)
+}
+
+// --------------------------- Numeric Allocation ----------------------------
+
+// allocateWith places the values of the column in the memory of the allocator
+func (c *numericColumn[T]) allocateWith(allocator Allocator) error {
+	if len(c.chunks) > 0 || c.file != nil {
+		return fmt.Errorf("column: unable to allocate a column which already has values")
+	}
+
+	c.alloc = allocator
+	return nil
+}
+
+// allocate allocates the data list of a chunk, using the allocator of the column if any
+func (c *numericColumn[T]) allocate() []T {
+	if c.alloc == nil {
+		return make([]T, chunkSize)
+	}
+
+	data, err := allocSlice[T](c.alloc, chunkSize)
+	if err != nil {
+		panic(fmt.Errorf("column: unable to allocate a chunk, %w", err))
+	}
+
+	return data
+}
+
+// release frees the data list of a chunk, which was allocated by the allocator of the column
+func (c *numericColumn[T]) release(data []T) error {
+	if c.alloc == nil || data == nil {
+		return nil
+	}
+
+	return freeSlice(c.alloc, data)
+}
+
+// free unmaps the file backing the column, or frees the memory of its values, so that the
+// column can no longer be used. The columns held on the heap are left untouched.
+func (c *numericColumn[T]) free() (err error) {
+	if c.file == nil && c.alloc == nil {
+		return nil
+	}
+
+	switch {
+	case c.file != nil:
+		err = c.file.Close()
+	case c.alloc != nil:
+		for i := range c.chunks {
+			if e := c.release(c.chunks[i].data); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+
+	c.chunks, c.file = nil, nil
+	return
+}
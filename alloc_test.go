@@ -0,0 +1,141 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && !wasm
+
+package column
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocator(t *testing.T) {
+	alloc := new(fakeAllocator)
+	c := NewCollection()
+	assert.NoError(t, c.CreateColumn("age", ForInt32(), WithAllocator(alloc)))
+	for i := 0; i < 40000; i++ {
+		c.InsertObject(Object{"age": i})
+	}
+
+	assert.Equal(t, 3, alloc.live())
+	assert.NoError(t, c.QueryAt(20000, func(r Row) error {
+		age, _ := r.Int32("age")
+		assert.Equal(t, int32(20000), age)
+		return nil
+	}))
+
+	// The memory of an empty chunk is freed, and allocated again once it is written to
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+			if idx >= chunkSize && idx < 2*chunkSize {
+				txn.DeleteAt(idx)
+			}
+		})
+	}))
+
+	c.release()
+	assert.Equal(t, 2, alloc.live())
+	c.InsertObject(Object{"age": 1})
+	assert.Equal(t, 3, alloc.live())
+
+	// The remaining memory is freed once the collection is closed
+	assert.NoError(t, c.Close())
+	assert.Equal(t, 0, alloc.live())
+}
+
+func TestAllocatorOffHeap(t *testing.T) {
+	c := NewCollection()
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("score", ForFloat64(), WithAllocator(OffHeap)))
+	for i := 0; i < 20000; i++ {
+		c.InsertObject(Object{"score": float64(i)})
+	}
+
+	// The clone has its own copy of the values
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	defer clone.Close()
+
+	assert.NoError(t, c.QueryAt(19999, func(r Row) error {
+		r.AddFloat64("score", 1)
+		return nil
+	}))
+
+	assert.NoError(t, clone.QueryAt(19999, func(r Row) error {
+		score, _ := r.Float64("score")
+		assert.Equal(t, float64(19999), score)
+		return nil
+	}))
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 10000, txn.WithFloat("score", func(v float64) bool {
+			return v >= 10000
+		}).Count())
+		assert.Equal(t, float64(149995001), txn.Float64("score").Sum())
+		return nil
+	}))
+}
+
+func TestCloseHeapColumn(t *testing.T) {
+	c := NewCollection()
+	assert.NoError(t, c.CreateColumn("age", ForInt()))
+	idx := c.InsertObject(Object{"age": 35})
+	assert.NoError(t, c.Close())
+
+	// The columns held on the heap keep their values once closed
+	assert.Equal(t, 1, c.Count())
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		age, ok := r.Int("age")
+		assert.True(t, ok)
+		assert.Equal(t, 35, age)
+		return nil
+	}))
+}
+
+func TestAllocatorInvalid(t *testing.T) {
+	alloc := new(fakeAllocator)
+	c := NewCollection()
+	defer c.Close()
+
+	assert.Error(t, c.CreateColumn("name", ForString(), WithAllocator(alloc)))
+	assert.Error(t, c.CreateColumn("age", ForInt(), WithAllocator(alloc), WithMapping("age.col")))
+
+	// The memory is freed once the column is dropped
+	assert.NoError(t, c.CreateColumn("age", ForInt(), WithAllocator(alloc)))
+	assert.Equal(t, 1, alloc.live())
+	assert.NoError(t, c.DropColumn("age"))
+	assert.Equal(t, 0, alloc.live())
+}
+
+// fakeAllocator allocates on the heap, keeping track of the live buffers
+type fakeAllocator struct {
+	lock    sync.Mutex
+	buffers map[*byte]int
+}
+
+func (a *fakeAllocator) Alloc(size int) ([]byte, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.buffers == nil {
+		a.buffers = make(map[*byte]int)
+	}
+
+	buffer := make([]byte, size)
+	a.buffers[&buffer[0]] = size
+	return buffer, nil
+}
+
+func (a *fakeAllocator) Free(buffer []byte) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.buffers, &buffer[0])
+	return nil
+}
+
+func (a *fakeAllocator) live() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return len(a.buffers)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// Append merges the rows of the other collection into this one, column by column rather than
+// row by row. For example, this can roll a set of hourly collections into a daily one. The
+// schemas are reconciled first: the columns which only exist in the other collection are
+// created, while a column which exists in both collections with a different type results in
+// an error before anything is appended. The indexes of this collection are updated, but the
+// indexes which only exist in the other collection are not created.
+//
+// If both collections have the same primary key column, the rows whose key already exists
+// are replaced by the rows of the other collection, while the remaining rows are inserted.
+// The rows are appended chunk by chunk, so the concurrent readers may observe a partially
+// appended collection.
+func (c *Collection) Append(other *Collection) error {
+	if other == c {
+		return fmt.Errorf("column: unable to append a collection into itself")
+	}
+
+	if err := c.reconcile(other); err != nil {
+		return err
+	}
+
+	// The rows are matched by their primary key, if both collections share it
+	var key *columnKey
+	if c.pk != nil && other.pk != nil && c.pk.name == other.pk.name {
+		key = other.pk
+	}
+
+	for chunk, n := 0, other.chunks(); chunk < n; chunk++ {
+		if err := c.appendChunk(other, commit.Chunk(chunk), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcile creates the columns of the other collection which do not exist in this one, after
+// making sure that the columns which exist in both have the same type.
+func (c *Collection) reconcile(other *Collection) error {
+	if err := other.cols.RangeUntil(func(src *column) error {
+		if dst, ok := c.cols.Load(src.name); ok && !src.IsIndex() && reflect.TypeOf(dst.Column) != reflect.TypeOf(src.Column) {
+			return fmt.Errorf("column: unable to append column '%s' of type %T into %T", src.name, src.Column, dst.Column)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// The expiration columns of the values are created along with the columns with a TTL
+	return other.cols.RangeUntil(func(src *column) error {
+		if _, ok := c.cols.Load(src.name); ok || src.IsIndex() || src.opts.expiryOf != "" {
+			return nil
+		}
+
+		empty, ok := src.Column.(Factory)
+		if !ok {
+			return fmt.Errorf("column: unable to append column '%s' of type %T", src.name, src.Column)
+		}
+
+		return c.CreateColumn(src.name, empty.MakeEmpty(), src.opts.copyTo)
+	})
+}
+
+// appendChunk appends the rows of a chunk of the other collection in a single transaction. The
+// values are copied while holding the read lock of the chunk, and committed once released.
+func (c *Collection) appendChunk(other *Collection, chunk commit.Chunk, key *columnKey) error {
+	return c.Query(func(txn *Txn) error {
+		txn.merged = true // Copied as they are, without coalescing
+		return other.readChunk(chunk, func(_ uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
+			offset := chunk.Min()
+
+			// Map every row of the chunk onto the row with the same key or onto a new row
+			target := make([]uint32, chunkSize)
+			replaced := make([]uint32, 0, 64)
+			inserted := make([]uint32, 0, fill.Count())
+			fill.Range(func(x uint32) {
+				if key != nil {
+					if k, ok := key.LoadString(offset + x); ok {
+						if at, ok := c.pk.OffsetOf(k); ok {
+							target[x] = at
+							replaced = append(replaced, at)
+							return
+						}
+					}
+				}
+				inserted = append(inserted, x)
+			})
+
+			if len(inserted) == 0 && len(replaced) == 0 {
+				return nil
+			}
+
+			// Reserve the indices of the new rows at once
+			reserved := make([]uint32, len(inserted))
+			c.nextMany(reserved)
+			markers := txn.bufferFor(rowColumn)
+			for i, x := range inserted {
+				target[x] = reserved[i]
+				markers.PutOperation(commit.Insert, reserved[i])
+			}
+
+			// Copy the values column by column, onto the target rows
+			buffer := c.txns.acquirePage("")
+			defer c.txns.releasePage(buffer)
+			return other.cols.RangeUntil(func(src *column) error {
+				if _, ok := txn.columnAt(src.name); !ok || !src.Snapshot(chunk, buffer) {
+					return nil
+				}
+
+				// Clear the values of the replaced rows, so that they are replaced entirely
+				dst := txn.bufferFor(src.name)
+				if key == nil || src.name != key.name {
+					for _, at := range replaced {
+						dst.PutOperation(commit.Delete, at)
+					}
+				}
+
+				txn.reader.Seek(buffer)
+				for txn.reader.Next() {
+					if x := txn.reader.IndexAtChunk(); fill.Contains(x) {
+						dst.PutFrom(target[x], txn.reader)
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppend(t *testing.T) {
+	daily := NewCollection()
+	daily.CreateColumn("name", ForString())
+	daily.CreateColumn("age", ForInt())
+	daily.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() >= 50
+	})
+
+	// Roll a few hourly collections into the daily one, the last one with an extra column
+	for hour := 0; hour < 3; hour++ {
+		hourly := NewCollection()
+		hourly.CreateColumn("name", ForString())
+		hourly.CreateColumn("age", ForInt())
+		if hour == 2 {
+			hourly.CreateColumn("city", ForEnum())
+		}
+
+		for i := 0; i < 20000; i++ {
+			hourly.Insert(func(r Row) error {
+				r.SetString("name", fmt.Sprintf("Roman %d", hour))
+				r.SetInt("age", i%100)
+				if hour == 2 {
+					r.SetEnum("city", "London")
+				}
+				return nil
+			})
+		}
+
+		assert.NoError(t, daily.Append(hourly))
+		hourly.Close()
+	}
+
+	assert.Equal(t, 60000, daily.Count())
+	assert.NoError(t, daily.Query(func(txn *Txn) error {
+		assert.Equal(t, 30000, txn.With("old").Count())
+		return nil
+	}))
+	assert.NoError(t, daily.Query(func(txn *Txn) error {
+		assert.Equal(t, 20000, txn.With("city").Count())
+		return nil
+	}))
+	assert.NoError(t, daily.Query(func(txn *Txn) error {
+		assert.Equal(t, 20000, txn.WithString("name", func(v string) bool {
+			return v == "Roman 1"
+		}).Count())
+		return nil
+	}))
+}
+
+func TestAppendByKey(t *testing.T) {
+	newCollection := func() *Collection {
+		c := NewCollection()
+		c.CreateColumn("key", ForKey())
+		c.CreateColumn("age", ForInt())
+		c.CreateColumn("name", ForString())
+		return c
+	}
+
+	target := newCollection()
+	target.InsertObject(Object{"key": "a", "age": 10, "name": "Alice"})
+
+	source := newCollection()
+	source.InsertObject(Object{"key": "a", "age": 20})
+	source.InsertObject(Object{"key": "b", "age": 30})
+
+	// The row with the same key is replaced entirely
+	assert.NoError(t, target.Append(source))
+	assert.Equal(t, 2, target.Count())
+	assert.NoError(t, target.QueryKey("a", func(r Row) error {
+		age, _ := r.Int("age")
+		_, hasName := r.String("name")
+		assert.Equal(t, 20, age)
+		assert.False(t, hasName)
+		return nil
+	}))
+	assert.NoError(t, target.QueryKey("b", func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 30, age)
+		return nil
+	}))
+}
+
+func TestAppendInvalid(t *testing.T) {
+	target := NewCollection()
+	target.CreateColumn("age", ForInt())
+	target.Insert(func(r Row) error {
+		r.SetInt("age", 10)
+		return nil
+	})
+
+	source := NewCollection()
+	source.CreateColumn("age", ForString())
+	source.CreateColumn("name", ForString())
+	source.Insert(func(r Row) error {
+		r.SetString("age", "ten")
+		return nil
+	})
+
+	// Nothing is appended when the types do not match
+	assert.Error(t, target.Append(target))
+	assert.Error(t, target.Append(source))
+	assert.Equal(t, 1, target.Count())
+	_, hasName := target.cols.Load("name")
+	assert.False(t, hasName)
+}
@@ -0,0 +1,958 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// The identifiers of the Arrow message headers and types which are written
+const (
+	arrowSchema      = 1 // MessageHeader.Schema
+	arrowRecordBatch = 3 // MessageHeader.RecordBatch
+	arrowInt         = 2 // Type.Int
+	arrowFloat       = 3 // Type.FloatingPoint
+	arrowBinary      = 4 // Type.Binary
+	arrowUtf8        = 5 // Type.Utf8
+	arrowBool        = 6 // Type.Bool
+	arrowVersion     = 4 // MetadataVersion.V5
+)
+
+// WriteArrowStream writes the rows selected by the transaction into the destination in the
+// Arrow IPC streaming format, so that they can be read by the Arrow implementations such as
+// pyarrow.ipc.open_stream(). The stream starts with the schema of the columns, followed by
+// one record batch per chunk of the collection and the end-of-stream marker. The rows which
+// do not have a value are written as nulls.
+//
+// If no columns are specified, all of the columns which can be represented in Arrow are
+// written, namely the boolean, numeric, string and bytes columns.
+func (txn *Txn) WriteArrowStream(dst io.Writer, columns ...string) error {
+	fields, err := txn.arrowFields(columns)
+	if err != nil {
+		return err
+	}
+
+	if err := writeArrowMessage(dst, arrowSchema, encodeArrowSchema(fields), nil); err != nil {
+		return err
+	}
+
+	// Write a record batch for every chunk which has any of the selected rows
+	txn.resolve()
+	rows := make([]uint32, 0, 64)
+	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if err != nil {
+			return
+		}
+
+		offset := chunk.Min()
+		rows = rows[:0]
+		index.Range(func(x uint32) {
+			rows = append(rows, offset+x)
+		})
+
+		if len(rows) > 0 {
+			meta, body := encodeArrowBatch(fields, rows)
+			err = writeArrowMessage(dst, arrowRecordBatch, meta, body)
+		}
+	})
+
+	switch {
+	case err != nil:
+		return err
+	case txn.ctx.Err() != nil:
+		return txn.ctx.Err()
+	default:
+		_, err := dst.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+		return err
+	}
+}
+
+// --------------------------- Fields ----------------------------
+
+// arrowField represents a column which is written into an Arrow stream
+type arrowField struct {
+	column *column // The column to read the values from
+	kind   uint8   // The identifier of the Arrow type
+	width  int     // The width of the values, in bits
+	signed bool    // Whether the integers are signed
+}
+
+// arrowFields returns the fields for the specified columns, or for all of the columns which
+// can be represented in Arrow if none are specified.
+func (txn *Txn) arrowFields(columns []string) ([]arrowField, error) {
+	strict := len(columns) > 0
+	if !strict {
+		columns = txn.owner.Columns()
+	}
+
+	fields := make([]arrowField, 0, len(columns))
+	for _, name := range columns {
+		column, ok := txn.columnAt(name)
+		if !ok {
+			return nil, fmt.Errorf("column: unable to write arrow stream, column '%v' does not exist", name)
+		}
+
+		field, ok := arrowFieldOf(column)
+		switch {
+		case ok:
+			fields = append(fields, field)
+		case strict:
+			return nil, fmt.Errorf("column: unable to write arrow stream, column '%v' is not supported", name)
+		}
+	}
+	return fields, nil
+}
+
+// arrowFieldOf returns the Arrow field for a column, if its values can be represented
+func arrowFieldOf(c *column) (arrowField, bool) {
+	if _, ok := c.Column.(*columnBytes); ok {
+		return arrowField{column: c, kind: arrowBinary}, true
+	}
+
+	switch kindOf(c.Column) {
+	case reflect.Bool:
+		return arrowField{column: c, kind: arrowBool, width: 1}, true
+	case reflect.String:
+		return arrowField{column: c, kind: arrowUtf8}, c.IsTextual()
+	case reflect.Int, reflect.Int64:
+		return arrowField{column: c, kind: arrowInt, width: 64, signed: true}, c.IsNumeric()
+	case reflect.Int32:
+		return arrowField{column: c, kind: arrowInt, width: 32, signed: true}, c.IsNumeric()
+	case reflect.Int16:
+		return arrowField{column: c, kind: arrowInt, width: 16, signed: true}, c.IsNumeric()
+	case reflect.Int8:
+		return arrowField{column: c, kind: arrowInt, width: 8, signed: true}, c.IsNumeric()
+	case reflect.Uint, reflect.Uint64:
+		return arrowField{column: c, kind: arrowInt, width: 64}, c.IsNumeric()
+	case reflect.Uint32:
+		return arrowField{column: c, kind: arrowInt, width: 32}, c.IsNumeric()
+	case reflect.Uint16:
+		return arrowField{column: c, kind: arrowInt, width: 16}, c.IsNumeric()
+	case reflect.Uint8:
+		return arrowField{column: c, kind: arrowInt, width: 8}, c.IsNumeric()
+	case reflect.Float32:
+		return arrowField{column: c, kind: arrowFloat, width: 32}, c.IsNumeric()
+	case reflect.Float64:
+		return arrowField{column: c, kind: arrowFloat, width: 64}, c.IsNumeric()
+	default:
+		return arrowField{}, false
+	}
+}
+
+// encodeArrowSchema encodes the metadata of the schema message
+func encodeArrowSchema(fields []arrowField) func(*flatBuilder) int {
+	items := make([]func(*flatBuilder) int, 0, len(fields))
+	for i := range fields {
+		items = append(items, fields[i].encode)
+	}
+
+	return func(b *flatBuilder) int {
+		return b.table(
+			flatScalar(2, 0), // Endianness.Little
+			flatRef(func(b *flatBuilder) int { return b.tables(items) }),
+		)
+	}
+}
+
+// encode encodes the field as a table of the schema
+func (f *arrowField) encode(b *flatBuilder) int {
+	return b.table(
+		flatRef(func(b *flatBuilder) int { return b.string(f.column.name) }),
+		flatScalar(1, 1), // Nullable
+		flatScalar(1, uint64(f.kind)),
+		flatRef(f.encodeType),
+		flatField{}, // No dictionary
+		flatRef(func(b *flatBuilder) int { return b.tables(nil) }),
+	)
+}
+
+// encodeType encodes the type of the field
+func (f *arrowField) encodeType(b *flatBuilder) int {
+	switch f.kind {
+	case arrowInt:
+		signed := uint64(0)
+		if f.signed {
+			signed = 1
+		}
+		return b.table(flatScalar(4, uint64(f.width)), flatScalar(1, signed))
+	case arrowFloat:
+		precision := uint64(2) // Precision.DOUBLE
+		if f.width == 32 {
+			precision = 1 // Precision.SINGLE
+		}
+		return b.table(flatScalar(2, precision))
+	default:
+		return b.table()
+	}
+}
+
+// --------------------------- Record Batch ----------------------------
+
+// encodeArrowBatch encodes the values of the fields for the rows into a record batch, and
+// returns its metadata along with its body.
+func encodeArrowBatch(fields []arrowField, rows []uint32) (func(*flatBuilder) int, []byte) {
+	var body []byte
+	nodes := make([]int64, 0, 2*len(fields))
+	buffers := make([]int64, 0, 6*len(fields))
+	push := func(buffer []byte) {
+		buffers = append(buffers, int64(len(body)), int64(len(buffer)))
+		body = append(body, buffer...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	for i := range fields {
+		validity, values, data, nulls := fields[i].encodeValues(rows)
+		nodes = append(nodes, int64(len(rows)), int64(nulls))
+		if nulls == 0 {
+			validity = nil
+		}
+
+		push(validity)
+		push(values)
+		if fields[i].kind == arrowUtf8 || fields[i].kind == arrowBinary {
+			push(data)
+		}
+	}
+
+	return func(b *flatBuilder) int {
+		return b.table(
+			flatScalar(8, uint64(len(rows))),
+			flatRef(func(b *flatBuilder) int { return b.structs(nodes) }),
+			flatRef(func(b *flatBuilder) int { return b.structs(buffers) }),
+		)
+	}, body
+}
+
+// encodeValues encodes the values of the rows into the validity bitmap, the values and, for
+// the variable-length types, the data which the values point to.
+func (f *arrowField) encodeValues(rows []uint32) (validity, values, data []byte, nulls int) {
+	validity = make([]byte, (len(rows)+7)/8)
+	switch f.kind {
+	case arrowBool:
+		values = make([]byte, (len(rows)+7)/8)
+	case arrowUtf8, arrowBinary:
+		values = make([]byte, 4*(len(rows)+1))
+	default:
+		values = make([]byte, len(rows)*f.width/8)
+	}
+
+	for i, idx := range rows {
+		ok := false
+		switch f.kind {
+		case arrowBool:
+			var v interface{}
+			if v, ok = f.column.Value(idx); ok && v.(bool) {
+				values[i/8] |= 1 << (i % 8)
+			}
+		case arrowUtf8:
+			var v string
+			v, ok = f.column.Column.(Textual).LoadString(idx)
+			data = append(data, v...)
+			binary.LittleEndian.PutUint32(values[4*(i+1):], uint32(len(data)))
+		case arrowBinary:
+			var v interface{}
+			if v, ok = f.column.Value(idx); ok {
+				data = append(data, v.([]byte)...)
+			}
+			binary.LittleEndian.PutUint32(values[4*(i+1):], uint32(len(data)))
+		case arrowFloat:
+			var v float64
+			v, ok = f.column.Column.(Numeric).LoadFloat64(idx)
+			if f.width == 32 {
+				binary.LittleEndian.PutUint32(values[4*i:], math.Float32bits(float32(v)))
+			} else {
+				binary.LittleEndian.PutUint64(values[8*i:], math.Float64bits(v))
+			}
+		case arrowInt:
+			var v uint64
+			if f.signed {
+				var n int64
+				n, ok = f.column.Column.(Numeric).LoadInt64(idx)
+				v = uint64(n)
+			} else {
+				v, ok = f.column.Column.(Numeric).LoadUint64(idx)
+			}
+			putUintN(values[i*f.width/8:], f.width, v)
+		}
+
+		if ok {
+			validity[i/8] |= 1 << (i % 8)
+		} else {
+			nulls++
+		}
+	}
+	return
+}
+
+// putUintN writes the lower bits of an integer, in little endian
+func putUintN(dst []byte, width int, v uint64) {
+	switch width {
+	case 8:
+		dst[0] = byte(v)
+	case 16:
+		binary.LittleEndian.PutUint16(dst, uint16(v))
+	case 32:
+		binary.LittleEndian.PutUint32(dst, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(dst, v)
+	}
+}
+
+// writeArrowMessage writes an encapsulated message, with its metadata padded so that the
+// body which follows it is aligned to 8 bytes.
+func writeArrowMessage(dst io.Writer, kind uint8, header func(*flatBuilder) int, body []byte) error {
+	var b flatBuilder
+	meta := b.finish(func(b *flatBuilder) int {
+		return b.table(
+			flatScalar(2, arrowVersion),
+			flatScalar(1, uint64(kind)),
+			flatRef(header),
+			flatScalar(8, uint64(len(body))),
+		)
+	})
+
+	for len(meta)%8 != 0 {
+		meta = append(meta, 0)
+	}
+
+	prefix := make([]byte, 8)
+	binary.LittleEndian.PutUint32(prefix[0:4], 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:8], uint32(len(meta)))
+	for _, v := range [][]byte{prefix, meta, body} {
+		if _, err := dst.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------- Reading ----------------------------
+
+// errArrowInvalid is returned when a message of an Arrow stream is malformed
+var errArrowInvalid = errors.New("column: invalid arrow message")
+
+// InsertArrowStream reads the record batches of a stream in the Arrow IPC streaming format,
+// such as the one written by WriteArrowStream() or by pyarrow.ipc.new_stream(), and inserts
+// their rows column by column, in the same way as InsertColumns(). The fields of the schema
+// are mapped onto the columns of the same name, and the null values are left empty. The
+// boolean, integer, floating-point, string and binary types are supported, while the
+// dictionary-encoded and the compressed record batches are rejected.
+func (txn *Txn) InsertArrowStream(src io.Reader) ([]uint32, error) {
+	var fields []arrowColumn
+	var indices []uint32
+	for {
+		kind, header, body, err := readArrowMessage(src)
+		switch {
+		case err == io.EOF:
+			return indices, nil
+		case err != nil:
+			return nil, fmt.Errorf("column: unable to read arrow stream, %w", err)
+		}
+
+		switch kind {
+		case arrowSchema:
+			if fields, err = decodeArrowSchema(header); err != nil {
+				return nil, err
+			}
+		case arrowRecordBatch:
+			if fields == nil {
+				return nil, fmt.Errorf("column: unable to read arrow stream, record batch before the schema")
+			}
+
+			batch, err := decodeArrowBatch(fields, header, body)
+			if err != nil {
+				return nil, err
+			}
+
+			inserted, err := txn.InsertColumns(batch)
+			if err != nil {
+				return nil, err
+			}
+			indices = append(indices, inserted...)
+		default:
+			return nil, fmt.Errorf("column: unable to read arrow stream, message type %d is not supported", kind)
+		}
+	}
+}
+
+// readArrowMessage reads an encapsulated message of a stream and returns its type, its header
+// and its body. It returns io.EOF once the end-of-stream marker, or the end of the stream,
+// is reached.
+func readArrowMessage(src io.Reader) (uint8, flatView, []byte, error) {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		return 0, flatView{}, nil, err
+	}
+
+	// The continuation marker precedes the size, except in the streams of older versions
+	size := binary.LittleEndian.Uint32(prefix)
+	if size == 0xffffffff {
+		if _, err := io.ReadFull(src, prefix); err != nil {
+			return 0, flatView{}, nil, io.ErrUnexpectedEOF
+		}
+		size = binary.LittleEndian.Uint32(prefix)
+	}
+
+	if size == 0 {
+		return 0, flatView{}, nil, io.EOF
+	}
+
+	meta, err := readArrowBytes(src, uint64(size))
+	if err != nil {
+		return 0, flatView{}, nil, err
+	}
+
+	root := (&flatReader{buf: meta}).root()
+	kind, header := uint8(root.scalar(1, 1)), root.table(2)
+	body, err := readArrowBytes(src, root.scalar(3, 8))
+	switch {
+	case err != nil:
+		return 0, flatView{}, nil, err
+	case root.r.invalid:
+		return 0, flatView{}, nil, errArrowInvalid
+	default:
+		return kind, header, body, nil
+	}
+}
+
+// readArrowBytes reads the specified number of bytes, without allocating them upfront since
+// the size comes from the stream itself
+func readArrowBytes(src io.Reader, size uint64) ([]byte, error) {
+	if size > math.MaxInt32 {
+		return nil, errArrowInvalid
+	}
+
+	var out bytes.Buffer
+	n, err := out.ReadFrom(io.LimitReader(src, int64(size)))
+	switch {
+	case err != nil:
+		return nil, err
+	case uint64(n) != size:
+		return nil, io.ErrUnexpectedEOF
+	default:
+		return out.Bytes(), nil
+	}
+}
+
+// arrowColumn represents a field of a stream which is read
+type arrowColumn struct {
+	name   string // The name of the field
+	kind   uint8  // The identifier of the Arrow type
+	width  int    // The width of the values, in bits
+	signed bool   // Whether the integers are signed
+}
+
+// decodeArrowSchema decodes the fields of a schema message
+func decodeArrowSchema(header flatView) ([]arrowColumn, error) {
+	if header.r == nil {
+		return nil, errArrowInvalid
+	}
+
+	items := header.tables(1)
+	fields := make([]arrowColumn, 0, len(items))
+	for _, item := range items {
+		field := arrowColumn{
+			name: item.string(0),
+			kind: uint8(item.scalar(2, 1)),
+		}
+
+		typ := item.table(3)
+		switch field.kind {
+		case arrowInt:
+			field.width = int(typ.scalar(0, 4))
+			field.signed = typ.scalar(1, 1) == 1
+		case arrowFloat:
+			switch typ.scalar(0, 2) {
+			case 1: // Precision.SINGLE
+				field.width = 32
+			case 2: // Precision.DOUBLE
+				field.width = 64
+			}
+		case arrowBool, arrowUtf8, arrowBinary:
+		default:
+			return nil, fmt.Errorf("column: unable to read arrow stream, type %d of field '%s' is not supported", field.kind, field.name)
+		}
+
+		switch {
+		case item.has(4):
+			return nil, fmt.Errorf("column: unable to read arrow stream, field '%s' is dictionary-encoded", field.name)
+		case (field.kind == arrowInt || field.kind == arrowFloat) && !isArrowWidth(field.width):
+			return nil, fmt.Errorf("column: unable to read arrow stream, field '%s' has an unsupported width", field.name)
+		}
+		fields = append(fields, field)
+	}
+
+	if header.r.invalid {
+		return nil, errArrowInvalid
+	}
+	return fields, nil
+}
+
+// isArrowWidth returns whether the width of the numbers is supported
+func isArrowWidth(width int) bool {
+	return width == 8 || width == 16 || width == 32 || width == 64
+}
+
+// decodeArrowBatch decodes the values of a record batch into a slice per field
+func decodeArrowBatch(fields []arrowColumn, header flatView, body []byte) (map[string]any, error) {
+	switch {
+	case header.r == nil:
+		return nil, errArrowInvalid
+	case header.has(3):
+		return nil, fmt.Errorf("column: unable to read arrow stream, compressed record batches are not supported")
+	}
+
+	rows := int(header.scalar(0, 8))
+	nodes, buffers := header.structs(1), header.structs(2)
+	if header.r.invalid || rows < 0 || rows > 8*len(body)+8 || len(nodes) < 2*len(fields) {
+		return nil, errArrowInvalid
+	}
+
+	// Slice the next buffer of the body
+	next := 0
+	buffer := func() ([]byte, bool) {
+		if 2*next+1 >= len(buffers) {
+			return nil, false
+		}
+
+		offset, size := buffers[2*next], buffers[2*next+1]
+		next++
+		if offset < 0 || size < 0 || offset+size > int64(len(body)) {
+			return nil, false
+		}
+		return body[offset : offset+size], true
+	}
+
+	batch := make(map[string]any, len(fields))
+	for i, field := range fields {
+		if int(nodes[2*i]) != rows {
+			return nil, errArrowInvalid
+		}
+
+		validity, ok1 := buffer()
+		values, ok2 := buffer()
+		var data []byte
+		ok3 := true
+		if field.kind == arrowUtf8 || field.kind == arrowBinary {
+			data, ok3 = buffer()
+		}
+
+		if !ok1 || !ok2 || !ok3 {
+			return nil, errArrowInvalid
+		}
+
+		if nodes[2*i+1] == 0 {
+			validity = nil
+		}
+
+		column, err := field.decodeValues(rows, validity, values, data)
+		if err != nil {
+			return nil, err
+		}
+		batch[field.name] = column
+	}
+	return batch, nil
+}
+
+// decodeValues decodes the values of a field into a slice of their type, or into a slice of
+// boxed values if any of them is null.
+func (f *arrowColumn) decodeValues(rows int, validity, values, data []byte) (any, error) {
+	if validity != nil && len(validity) < (rows+7)/8 {
+		return nil, errArrowInvalid
+	}
+
+	switch f.kind {
+	case arrowBool:
+		if len(values) < (rows+7)/8 {
+			return nil, errArrowInvalid
+		}
+	case arrowUtf8, arrowBinary:
+		if len(values) < 4*(rows+1) {
+			return nil, errArrowInvalid
+		}
+	default:
+		if len(values) < rows*f.width/8 {
+			return nil, errArrowInvalid
+		}
+	}
+
+	out := make([]any, rows)
+	for i := 0; i < rows; i++ {
+		if validity != nil && validity[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+
+		switch f.kind {
+		case arrowBool:
+			out[i] = values[i/8]&(1<<(i%8)) != 0
+		case arrowUtf8, arrowBinary:
+			lo := binary.LittleEndian.Uint32(values[4*i:])
+			hi := binary.LittleEndian.Uint32(values[4*i+4:])
+			if lo > hi || int(hi) > len(data) {
+				return nil, errArrowInvalid
+			}
+
+			if f.kind == arrowUtf8 {
+				out[i] = string(data[lo:hi])
+			} else {
+				out[i] = append([]byte(nil), data[lo:hi]...)
+			}
+		case arrowFloat:
+			if f.width == 32 {
+				out[i] = math.Float32frombits(binary.LittleEndian.Uint32(values[4*i:]))
+			} else {
+				out[i] = math.Float64frombits(binary.LittleEndian.Uint64(values[8*i:]))
+			}
+		case arrowInt:
+			out[i] = f.decodeInt(values[i*f.width/8:])
+		}
+	}
+
+	if validity != nil {
+		return out, nil
+	}
+	return unboxArrow(f, out), nil
+}
+
+// decodeInt decodes an integer of the width of the field
+func (f *arrowColumn) decodeInt(b []byte) any {
+	var v uint64
+	switch f.width {
+	case 8:
+		v = uint64(b[0])
+	case 16:
+		v = uint64(binary.LittleEndian.Uint16(b))
+	case 32:
+		v = uint64(binary.LittleEndian.Uint32(b))
+	default:
+		v = binary.LittleEndian.Uint64(b)
+	}
+
+	if !f.signed {
+		return v
+	}
+
+	// Extend the sign of the narrower integers
+	shift := 64 - f.width
+	return int64(v<<shift) >> shift
+}
+
+// unboxArrow converts the values of a field without nulls into a slice of their type, so that
+// they can be inserted without any per-value conversion
+func unboxArrow(f *arrowColumn, values []any) any {
+	switch {
+	case f.kind == arrowBool:
+		return unboxAll[bool](values)
+	case f.kind == arrowUtf8:
+		return unboxAll[string](values)
+	case f.kind == arrowFloat && f.width == 32:
+		return unboxAll[float32](values)
+	case f.kind == arrowFloat:
+		return unboxAll[float64](values)
+	case f.kind == arrowInt && f.signed:
+		return unboxAll[int64](values)
+	case f.kind == arrowInt:
+		return unboxAll[uint64](values)
+	default:
+		return values
+	}
+}
+
+// unboxAll converts a slice of boxed values into a slice of their type
+func unboxAll[T any](values []any) []T {
+	out := make([]T, len(values))
+	for i, v := range values {
+		out[i] = v.(T)
+	}
+	return out
+}
+
+// --------------------------- Flatbuffers ----------------------------
+
+// flatBuilder represents a minimal builder of flatbuffers, as used by the Arrow metadata. The
+// buffer is written front to back, so the referenced objects follow the tables which point
+// to them while the vtables precede their tables.
+type flatBuilder struct {
+	buf []byte
+}
+
+// flatField represents a field of a table, either a scalar or a reference to an object. A
+// zero value represents an absent field.
+type flatField struct {
+	size  int                    // The size of the scalar, in bytes
+	value uint64                 // The value of the scalar
+	ref   func(*flatBuilder) int // The function which writes the referenced object
+}
+
+// flatScalar returns a scalar field of the specified size
+func flatScalar(size int, value uint64) flatField {
+	return flatField{size: size, value: value}
+}
+
+// flatRef returns a field which references an object, written by the function
+func flatRef(fn func(*flatBuilder) int) flatField {
+	return flatField{size: 4, ref: fn}
+}
+
+// finish writes the buffer with the root table and returns it
+func (b *flatBuilder) finish(root func(*flatBuilder) int) []byte {
+	b.buf = append(b.buf[:0], 0, 0, 0, 0)
+	at := root(b)
+	binary.LittleEndian.PutUint32(b.buf, uint32(at))
+	return b.buf
+}
+
+// table writes a table along with its vtable, then the objects it references, and returns
+// the position of the table.
+func (b *flatBuilder) table(fields ...flatField) int {
+	offsets := make([]int, len(fields))
+	size := 4 // The offset to the vtable
+	for _, width := range []int{8, 4, 2, 1} {
+		for i, f := range fields {
+			if f.size == width {
+				size = (size + width - 1) / width * width
+				offsets[i] = size
+				size += width
+			}
+		}
+	}
+
+	// Write the vtable, followed by the table aligned so that its fields are aligned
+	b.pad(2)
+	vtable := len(b.buf)
+	b.uint16(uint16(4 + 2*len(fields)))
+	b.uint16(uint16(size))
+	for _, offset := range offsets {
+		b.uint16(uint16(offset))
+	}
+
+	b.pad(8)
+	table := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[table:], uint32(table-vtable))
+	for i, f := range fields {
+		if f.size > 0 && f.ref == nil {
+			putUintN(b.buf[table+offsets[i]:], 8*f.size, f.value)
+		}
+	}
+
+	// Write the referenced objects and point to them, the buffer may grow meanwhile
+	for i, f := range fields {
+		if f.ref != nil {
+			at := table + offsets[i]
+			to := f.ref(b)
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(to-at))
+		}
+	}
+	return table
+}
+
+// tables writes a vector of tables and returns its position
+func (b *flatBuilder) tables(items []func(*flatBuilder) int) int {
+	b.pad(4)
+	vector := len(b.buf)
+	b.uint32(uint32(len(items)))
+	b.buf = append(b.buf, make([]byte, 4*len(items))...)
+	for i, fn := range items {
+		at := vector + 4 + 4*i
+		to := fn(b)
+		binary.LittleEndian.PutUint32(b.buf[at:], uint32(to-at))
+	}
+	return vector
+}
+
+// structs writes a vector of structs made of two 64-bit integers and returns its position
+func (b *flatBuilder) structs(values []int64) int {
+	for (len(b.buf)+4)%8 != 0 {
+		b.buf = append(b.buf, 0)
+	}
+
+	vector := len(b.buf)
+	b.uint32(uint32(len(values) / 2))
+	for _, v := range values {
+		b.uint64(uint64(v))
+	}
+	return vector
+}
+
+// string writes a null-terminated string and returns its position
+func (b *flatBuilder) string(v string) int {
+	b.pad(4)
+	at := len(b.buf)
+	b.uint32(uint32(len(v)))
+	b.buf = append(b.buf, v...)
+	b.buf = append(b.buf, 0)
+	return at
+}
+
+// uint16 appends a 16-bit integer
+func (b *flatBuilder) uint16(v uint16) {
+	b.buf = append(b.buf, byte(v), byte(v>>8))
+}
+
+// uint32 appends a 32-bit integer
+func (b *flatBuilder) uint32(v uint32) {
+	b.buf = append(b.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// uint64 appends a 64-bit integer
+func (b *flatBuilder) uint64(v uint64) {
+	b.uint32(uint32(v))
+	b.uint32(uint32(v >> 32))
+}
+
+// pad pads the buffer with zeros until it is aligned
+func (b *flatBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// --------------------------- Flatbuffers Reader ----------------------------
+
+// flatReader represents a flatbuffer being read. The reads outside of the buffer return zero
+// values and mark the buffer as invalid, so that the malformed messages are rejected rather
+// than causing a panic.
+type flatReader struct {
+	buf     []byte
+	invalid bool
+}
+
+// flatView represents a table of a flatbuffer being read
+type flatView struct {
+	r   *flatReader
+	pos int
+}
+
+// root returns the root table of the buffer
+func (r *flatReader) root() flatView {
+	return flatView{r: r, pos: int(r.uint(0, 4))}
+}
+
+// uint reads an integer of the specified size, in little endian
+func (r *flatReader) uint(at, size int) uint64 {
+	if at < 0 || at+size > len(r.buf) {
+		r.invalid = true
+		return 0
+	}
+
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(r.buf[at+i])
+	}
+	return v
+}
+
+// field returns the position of a field, if present
+func (t flatView) field(slot int) (int, bool) {
+	if t.r == nil {
+		return 0, false
+	}
+
+	vtable := t.pos - int(int32(t.r.uint(t.pos, 4)))
+	if 4+2*slot >= int(t.r.uint(vtable, 2)) {
+		return 0, false
+	}
+
+	offset := int(t.r.uint(vtable+4+2*slot, 2))
+	return t.pos + offset, offset != 0
+}
+
+// has returns whether a field is present
+func (t flatView) has(slot int) bool {
+	_, ok := t.field(slot)
+	return ok
+}
+
+// scalar reads a scalar field, or zero if absent
+func (t flatView) scalar(slot, size int) uint64 {
+	if at, ok := t.field(slot); ok {
+		return t.r.uint(at, size)
+	}
+	return 0
+}
+
+// deref returns the position of the object referenced by a field
+func (t flatView) deref(slot int) (int, bool) {
+	at, ok := t.field(slot)
+	if !ok {
+		return 0, false
+	}
+	return at + int(t.r.uint(at, 4)), true
+}
+
+// table reads a table field, which is empty if absent
+func (t flatView) table(slot int) flatView {
+	if at, ok := t.deref(slot); ok {
+		return flatView{r: t.r, pos: at}
+	}
+	return flatView{}
+}
+
+// string reads a string field
+func (t flatView) string(slot int) string {
+	at, ok := t.deref(slot)
+	if !ok {
+		return ""
+	}
+
+	size := int(t.r.uint(at, 4))
+	if size < 0 || at+4+size > len(t.r.buf) {
+		t.r.invalid = true
+		return ""
+	}
+	return string(t.r.buf[at+4 : at+4+size])
+}
+
+// tables reads a vector of tables
+func (t flatView) tables(slot int) []flatView {
+	at, ok := t.deref(slot)
+	if !ok {
+		return nil
+	}
+
+	n := int(t.r.uint(at, 4))
+	if n < 0 || at+4+4*n > len(t.r.buf) {
+		t.r.invalid = true
+		return nil
+	}
+
+	out := make([]flatView, 0, n)
+	for i := 0; i < n; i++ {
+		item := at + 4 + 4*i
+		out = append(out, flatView{r: t.r, pos: item + int(t.r.uint(item, 4))})
+	}
+	return out
+}
+
+// structs reads a vector of structs made of two 64-bit integers
+func (t flatView) structs(slot int) []int64 {
+	at, ok := t.deref(slot)
+	if !ok {
+		return nil
+	}
+
+	n := 2 * int(t.r.uint(at, 4))
+	if n < 0 || at+4+8*n > len(t.r.buf) {
+		t.r.invalid = true
+		return nil
+	}
+
+	out := make([]int64, n)
+	for i := range out {
+		out[i] = int64(t.r.uint(at+4+8*i, 8))
+	}
+	return out
+}
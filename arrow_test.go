@@ -0,0 +1,362 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteArrowStream(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt32())
+	coll.CreateColumn("balance", ForFloat64())
+	coll.CreateColumn("active", ForBool())
+	coll.CreateColumn("avatar", ForBytes())
+	coll.CreateColumn("profile", ForJSON())
+	coll.InsertObject(Object{"name": "Roman", "age": int32(35), "balance": 10.5, "active": true, "avatar": []byte{1, 2}})
+	coll.InsertObject(Object{"name": "Merlin", "balance": 20.0, "avatar": []byte{3}})
+	coll.InsertObject(Object{"name": "Arthur", "age": int32(30), "balance": 30.0, "active": true})
+
+	var buffer bytes.Buffer
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		return txn.WithFloat("balance", func(v float64) bool {
+			return v < 25
+		}).WriteArrowStream(&buffer)
+	}))
+
+	messages := readArrowStream(t, buffer.Bytes())
+	assert.Len(t, messages, 2)
+
+	// The schema lists the supported columns, in order
+	schema := messages[0].header
+	assert.Equal(t, uint64(arrowSchema), messages[0].kind)
+	fields := schema.tables(1)
+	assert.Len(t, fields, 5)
+	for i, expect := range []struct {
+		name string
+		kind uint64
+	}{{"name", arrowUtf8}, {"age", arrowInt}, {"balance", arrowFloat}, {"active", arrowBool}, {"avatar", arrowBinary}} {
+		assert.Equal(t, expect.name, fields[i].string(0))
+		assert.Equal(t, expect.kind, fields[i].scalar(2, 1))
+	}
+	assert.Equal(t, uint64(32), fields[1].table(3).scalar(0, 4))
+	assert.Equal(t, uint64(1), fields[1].table(3).scalar(1, 1))
+	assert.Equal(t, uint64(2), fields[2].table(3).scalar(0, 2))
+
+	// The record batch holds the selected rows
+	batch := messages[1]
+	assert.Equal(t, uint64(arrowRecordBatch), batch.kind)
+	assert.Equal(t, uint64(2), batch.header.scalar(0, 8))
+	nodes := batch.header.structs(1)
+	assert.Equal(t, []int64{2, 0, 2, 1, 2, 0, 2, 1, 2, 0}, nodes)
+
+	buffers := batch.header.structs(2)
+	body := func(i int) []byte {
+		return batch.body[buffers[2*i] : buffers[2*i]+buffers[2*i+1]]
+	}
+
+	// name: validity, offsets and data
+	assert.Empty(t, body(0))
+	assert.Equal(t, []byte{0, 0, 0, 0, 5, 0, 0, 0, 11, 0, 0, 0}, body(1))
+	assert.Equal(t, "RomanMerlin", string(body(2)))
+
+	// age: validity and values
+	assert.Equal(t, []byte{0b01}, body(3))
+	assert.Equal(t, uint32(35), binary.LittleEndian.Uint32(body(4)))
+
+	// balance: validity and values
+	assert.Empty(t, body(5))
+	assert.Equal(t, 20.0, math.Float64frombits(binary.LittleEndian.Uint64(body(6)[8:])))
+
+	// active: validity and values
+	assert.Equal(t, []byte{0b01}, body(7))
+	assert.Equal(t, []byte{0b01}, body(8))
+
+	// avatar: validity, offsets and data
+	assert.Empty(t, body(9))
+	assert.Equal(t, []byte{0, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0}, body(10))
+	assert.Equal(t, []byte{1, 2, 3}, body(11))
+}
+
+func TestWriteArrowStreamColumns(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("profile", ForJSON())
+	coll.Query(func(txn *Txn) error {
+		for i := 0; i < chunkSize+10; i++ {
+			txn.InsertObject(Object{"name": "Roman"})
+		}
+		return nil
+	})
+
+	// Every chunk is written as a separate record batch
+	var buffer bytes.Buffer
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer, "name")
+	}))
+
+	messages := readArrowStream(t, buffer.Bytes())
+	assert.Len(t, messages, 3)
+	assert.Equal(t, uint64(chunkSize), messages[1].header.scalar(0, 8))
+	assert.Equal(t, uint64(10), messages[2].header.scalar(0, 8))
+
+	// The columns must exist and be supported
+	assert.Error(t, coll.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer, "profile")
+	}))
+	assert.Error(t, coll.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer, "missing")
+	}))
+}
+
+func TestInsertArrowStream(t *testing.T) {
+	source := NewCollection()
+	source.CreateColumn("name", ForString())
+	source.CreateColumn("age", ForInt16())
+	source.CreateColumn("score", ForUint64())
+	source.CreateColumn("balance", ForFloat32())
+	source.CreateColumn("active", ForBool())
+	source.CreateColumn("avatar", ForBytes())
+	source.Query(func(txn *Txn) error {
+		for i := 0; i < chunkSize+10; i++ {
+			txn.InsertObject(Object{"name": "Roman", "age": int16(-i), "score": uint64(i), "balance": 1.5, "active": i%2 == 0})
+		}
+		txn.InsertObject(Object{"name": "Merlin", "avatar": []byte{1, 2}})
+		return nil
+	})
+
+	var buffer bytes.Buffer
+	assert.NoError(t, source.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer)
+	}))
+
+	// Read the stream back into an empty collection with the same columns
+	target := NewCollection()
+	target.CreateColumn("name", ForString())
+	target.CreateColumn("age", ForInt32())
+	target.CreateColumn("score", ForUint64())
+	target.CreateColumn("balance", ForFloat64())
+	target.CreateColumn("active", ForBool())
+	target.CreateColumn("avatar", ForBytes())
+	indices, err := target.InsertArrowStream(bytes.NewReader(buffer.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, indices, chunkSize+11)
+	assert.Equal(t, chunkSize+11, target.Count())
+
+	assert.NoError(t, target.QueryAt(indices[15], func(r Row) error {
+		name, _ := r.String("name")
+		age, _ := r.Int32("age")
+		score, _ := r.Uint64("score")
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, "Roman", name)
+		assert.Equal(t, int32(-15), age)
+		assert.Equal(t, uint64(15), score)
+		assert.Equal(t, 1.5, balance)
+		assert.False(t, r.Bool("active"))
+		return nil
+	}))
+
+	// The null values are left empty
+	assert.NoError(t, target.QueryAt(indices[chunkSize+10], func(r Row) error {
+		_, ok := r.Int32("age")
+		assert.False(t, ok)
+		avatar, _ := r.Bytes("avatar")
+		assert.Equal(t, []byte{1, 2}, avatar)
+		return nil
+	}))
+}
+
+func TestInsertArrowStreamInvalid(t *testing.T) {
+	source := NewCollection()
+	source.CreateColumn("name", ForString())
+	source.CreateColumn("age", ForInt32())
+	source.InsertObject(Object{"name": "Roman", "age": int32(35)})
+	source.InsertObject(Object{"name": "Merlin"})
+
+	var buffer bytes.Buffer
+	assert.NoError(t, source.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer)
+	}))
+
+	// The fields must have a column of a matching type
+	target := NewCollection()
+	target.CreateColumn("name", ForString())
+	_, err := target.InsertArrowStream(bytes.NewReader(buffer.Bytes()))
+	assert.Error(t, err)
+
+	target.CreateColumn("age", ForString())
+	_, err = target.InsertArrowStream(bytes.NewReader(buffer.Bytes()))
+	assert.Error(t, err)
+	assert.Equal(t, 0, target.Count())
+
+	// The truncated or corrupted streams are rejected, without inserting anything
+	target = NewCollection()
+	target.CreateColumn("name", ForString())
+	target.CreateColumn("age", ForInt32())
+	stream := buffer.Bytes()
+	for i := 1; i < len(stream)-8; i++ {
+		target.InsertArrowStream(bytes.NewReader(stream[:i]))
+		assert.Equal(t, 0, target.Count())
+
+		corrupted := append([]byte{}, stream...)
+		corrupted[i] ^= 0xff
+		assert.NotPanics(t, func() {
+			source.InsertArrowStream(bytes.NewReader(corrupted))
+		})
+	}
+}
+
+func FuzzInsertArrowStream(f *testing.F) {
+	source := NewCollection()
+	source.CreateColumn("name", ForString())
+	source.CreateColumn("age", ForInt32())
+	source.InsertObject(Object{"name": "Roman", "age": int32(35)})
+
+	var buffer bytes.Buffer
+	assert.NoError(f, source.Query(func(txn *Txn) error {
+		return txn.WriteArrowStream(&buffer)
+	}))
+
+	// The messages without a header, which used to crash the reader
+	schema := buffer.Bytes()[:len(buffer.Bytes())-8]
+	schema = schema[:8+binary.LittleEndian.Uint32(schema[4:])]
+	f.Add(buffer.Bytes())
+	f.Add(headerlessArrowMessage(arrowSchema))
+	f.Add(append(append([]byte{}, schema...), headerlessArrowMessage(arrowRecordBatch)...))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		target := NewCollection()
+		target.CreateColumn("name", ForString())
+		target.CreateColumn("age", ForInt32())
+		target.InsertArrowStream(bytes.NewReader(data))
+	})
+}
+
+// headerlessArrowMessage encodes a message of the specified type, without any header
+func headerlessArrowMessage(kind uint8) []byte {
+	meta := []byte{
+		12, 0, 0, 0, // The offset of the root table
+		8, 0, 8, 0, 0, 0, 4, 0, // The vtable, with the type of the message only
+		8, 0, 0, 0, kind, 0, 0, 0, // The root table
+	}
+
+	out := []byte{0xff, 0xff, 0xff, 0xff, byte(len(meta)), 0, 0, 0}
+	return append(out, meta...)
+}
+
+// --------------------------- Reader ----------------------------
+
+// arrowMessage represents a decoded message of an arrow stream
+type arrowMessage struct {
+	kind   uint64
+	header flatTable
+	body   []byte
+}
+
+// readArrowStream reads the encapsulated messages of a stream, up to the end-of-stream marker
+func readArrowStream(t *testing.T, data []byte) (out []arrowMessage) {
+	for {
+		assert.Equal(t, uint32(0xffffffff), binary.LittleEndian.Uint32(data))
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if size == 0 {
+			assert.Len(t, data, 8)
+			return
+		}
+
+		assert.Zero(t, size%8)
+		meta := data[8 : 8+size]
+		root := flatTable{buf: meta, pos: int(binary.LittleEndian.Uint32(meta))}
+		assert.Equal(t, uint64(arrowVersion), root.scalar(0, 2))
+
+		length := int(root.scalar(3, 8))
+		out = append(out, arrowMessage{
+			kind:   root.scalar(1, 1),
+			header: root.table(2),
+			body:   data[8+size : 8+size+length],
+		})
+		data = data[8+size+length:]
+	}
+}
+
+// flatTable represents a table of a flatbuffer
+type flatTable struct {
+	buf []byte
+	pos int
+}
+
+// field returns the position of a field, if present
+func (t flatTable) field(slot int) (int, bool) {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*slot >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0, false
+	}
+
+	offset := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*slot:]))
+	return t.pos + offset, offset != 0
+}
+
+// scalar reads a scalar field, which must be aligned to its size
+func (t flatTable) scalar(slot, size int) uint64 {
+	at, ok := t.field(slot)
+	if !ok {
+		return 0
+	}
+
+	if at%size != 0 {
+		panic("misaligned scalar")
+	}
+
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(t.buf[at+i])
+	}
+	return v
+}
+
+// deref returns the position of the object referenced by a field
+func (t flatTable) deref(slot int) int {
+	at, _ := t.field(slot)
+	return at + int(binary.LittleEndian.Uint32(t.buf[at:]))
+}
+
+// table reads a table field
+func (t flatTable) table(slot int) flatTable {
+	return flatTable{buf: t.buf, pos: t.deref(slot)}
+}
+
+// string reads a string field
+func (t flatTable) string(slot int) string {
+	at := t.deref(slot)
+	size := int(binary.LittleEndian.Uint32(t.buf[at:]))
+	return string(t.buf[at+4 : at+4+size])
+}
+
+// tables reads a vector of tables
+func (t flatTable) tables(slot int) (out []flatTable) {
+	at := t.deref(slot)
+	for i := 0; i < int(binary.LittleEndian.Uint32(t.buf[at:])); i++ {
+		item := at + 4 + 4*i
+		out = append(out, flatTable{buf: t.buf, pos: item + int(binary.LittleEndian.Uint32(t.buf[item:]))})
+	}
+	return
+}
+
+// structs reads a vector of structs made of 64-bit integers
+func (t flatTable) structs(slot int) (out []int64) {
+	at := t.deref(slot)
+	if (at+4)%8 != 0 {
+		panic("misaligned structs")
+	}
+
+	for i := 0; i < 2*int(binary.LittleEndian.Uint32(t.buf[at:])); i++ {
+		out = append(out, int64(binary.LittleEndian.Uint64(t.buf[at+4+8*i:])))
+	}
+	return
+}
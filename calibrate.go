@@ -0,0 +1,115 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelindar/bitmap"
+)
+
+// calibrationTime is the minimum duration of the measurement of each operation
+const calibrationTime = 5 * time.Millisecond
+
+// CostModel represents the costs of the operations considered by the query planner, as
+// measured on the current hardware and data.
+type CostModel struct {
+	Bitmap float64 // The cost of intersecting a bitmap, in nanoseconds per 64 rows
+	Typed  float64 // The cost of a typed filter which reads the values directly, in nanoseconds per row
+	Value  float64 // The cost of a generic filter which boxes the values, in nanoseconds per row
+}
+
+// Calibrate measures the costs of the operations of the query planner on the rows of the
+// collection and stores them, so that the planner uses them from then on. Once calibrated,
+// the planner orders the value filters by their cost per eliminated row, and an equality
+// filter scans the remaining rows rather than intersecting the hash index when there are
+// only a few of them left. The returned model can be persisted and restored with
+// SetCostModel, in order to avoid measuring it again.
+func (c *Collection) Calibrate() (CostModel, error) {
+	if c.Count() == 0 {
+		return CostModel{}, fmt.Errorf("column: unable to calibrate an empty collection")
+	}
+
+	// Pick the columns on which the filters are measured
+	var typed, value string
+	c.cols.Range(func(column *column) {
+		if column.IsIndex() || column.name == expireColumn {
+			return
+		}
+
+		if value == "" {
+			value = column.name
+		}
+		if typed == "" && (column.IsNumeric() || column.IsTextual()) {
+			typed = column.name
+		}
+	})
+
+	if value == "" {
+		return CostModel{}, fmt.Errorf("column: unable to calibrate a collection without columns")
+	}
+
+	model := CostModel{
+		Bitmap: c.measureBitmap(),
+		Value: c.measure(func(txn *Txn) *Txn {
+			return txn.WithValue(value, func(any) bool { return true })
+		}),
+	}
+
+	model.Typed = model.Value
+	if typed != "" {
+		model.Typed = c.measure(func(txn *Txn) *Txn {
+			if col, _ := c.cols.Load(typed); col.IsNumeric() {
+				return txn.WithFloat(typed, func(float64) bool { return true })
+			}
+			return txn.WithString(typed, func(string) bool { return true })
+		})
+	}
+
+	c.SetCostModel(model)
+	return model, nil
+}
+
+// SetCostModel sets the costs of the operations used by the query planner, for example the
+// ones previously measured by Calibrate.
+func (c *Collection) SetCostModel(model CostModel) {
+	c.costs.Store(model)
+	c.plans.Clear()
+}
+
+// costModel returns the calibrated cost model, if any
+func (c *Collection) costModel() (CostModel, bool) {
+	model, ok := c.costs.Load().(CostModel)
+	return model, ok
+}
+
+// measure measures the cost of a filter, in nanoseconds per row
+func (c *Collection) measure(fn func(txn *Txn) *Txn) float64 {
+	rows, start := 0, time.Now()
+	for time.Since(start) < calibrationTime {
+		c.View(func(txn *Txn) error {
+			rows += txn.Count()
+			fn(txn).Count()
+			return nil
+		})
+	}
+	return float64(time.Since(start).Nanoseconds()) / float64(rows)
+}
+
+// measureBitmap measures the cost of intersecting the fill list, in nanoseconds per 64 rows
+func (c *Collection) measureBitmap() float64 {
+	var fill, index bitmap.Bitmap
+	c.lock.RLock()
+	c.fill.Clone(&fill)
+	c.lock.RUnlock()
+
+	words, start := 0, time.Now()
+	for time.Since(start) < calibrationTime {
+		fill.Clone(&index)
+		index.And(fill)
+		words += len(fill)
+	}
+	return float64(time.Since(start).Nanoseconds()) / float64(words)
+}
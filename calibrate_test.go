@@ -0,0 +1,117 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalibrate(t *testing.T) {
+	players := loadPlayers(500)
+	defer players.Close()
+
+	_, ok := players.costModel()
+	assert.False(t, ok)
+
+	model, err := players.Calibrate()
+	assert.NoError(t, err)
+	assert.Greater(t, model.Bitmap, 0.0)
+	assert.Greater(t, model.Typed, 0.0)
+	assert.Greater(t, model.Value, 0.0)
+
+	stored, ok := players.costModel()
+	assert.True(t, ok)
+	assert.Equal(t, model, stored)
+
+	// The queries should return the same results once calibrated
+	players.Query(func(txn *Txn) error {
+		count := txn.WithValue("race", func(v any) bool {
+			return v == "human"
+		}).WithFloat("age", func(v float64) bool {
+			return v > 30
+		}).Count()
+		assert.Greater(t, count, 0)
+		return nil
+	})
+}
+
+func TestCalibrateEmpty(t *testing.T) {
+	c := NewCollection()
+	_, err := c.Calibrate()
+	assert.Error(t, err)
+
+	c.InsertObject(Object{})
+	_, err = c.Calibrate()
+	assert.Error(t, err)
+}
+
+func TestCostModelRank(t *testing.T) {
+	model := CostModel{Bitmap: 1, Typed: 1, Value: 10}
+
+	// A cheap but unselective filter should go after an expensive but selective one
+	cheap := model.rank(costTyped, 1000, 999)
+	selective := model.rank(costValue, 1000, 1)
+	assert.Less(t, selective, cheap)
+
+	// For the same selectivity, the cheaper one goes first
+	assert.Less(t, model.rank(costTyped, 1000, 500), model.rank(costValue, 1000, 500))
+}
+
+func TestPlanWithCostModel(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("age", ForInt())
+	for i := 0; i < 100; i++ {
+		c.InsertObject(Object{"name": "Roman", "age": i})
+	}
+
+	// The value filter is far more selective, hence applied first despite its cost
+	c.SetCostModel(CostModel{Bitmap: 1, Typed: 1, Value: 2})
+	for i := 0; i < 2; i++ {
+		c.Query(func(txn *Txn) error {
+			assert.Equal(t, 1, txn.WithString("name", func(v string) bool {
+				return true
+			}).WithValue("age", func(v any) bool {
+				return v == 42
+			}).Count())
+			return nil
+		})
+	}
+
+	c.plans.lock.RLock()
+	defer c.plans.lock.RUnlock()
+	assert.Len(t, c.plans.plans, 1)
+	for _, p := range c.plans.plans {
+		assert.Equal(t, []int{1, 0}, p.order)
+	}
+}
+
+func TestWithEqualCostModel(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("class", ForString())
+	c.CreateColumn("age", ForInt())
+	assert.NoError(t, c.CreateHashIndex("class"))
+	for i := 0; i < 1000; i++ {
+		c.InsertObject(Object{"class": []string{"mage", "rogue"}[i%2], "age": i})
+	}
+
+	// Scanning a few rows is cheaper than intersecting the index
+	c.SetCostModel(CostModel{Bitmap: 1000, Typed: 1, Value: 1})
+	c.Query(func(txn *Txn) error {
+		txn.WithInt("age", func(v int64) bool { return v < 10 }).Count()
+		assert.True(t, txn.cheaperToScan())
+		assert.Equal(t, 5, txn.WithEqual("class", "mage").Count())
+		return nil
+	})
+
+	// Intersecting the index is cheaper than scanning all of the rows
+	c.SetCostModel(CostModel{Bitmap: 1, Typed: 1, Value: 1000})
+	c.Query(func(txn *Txn) error {
+		assert.False(t, txn.cheaperToScan())
+		assert.Equal(t, 500, txn.WithEqual("class", "mage").Count())
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/iostream"
+	"github.com/klauspost/compress/s2"
+)
+
+// Catalog represents a set of named collections which can be snapshotted and restored
+// together, in a mutually consistent state.
+type Catalog struct {
+	lock    sync.RWMutex           // The lock to protect the registry
+	barrier sync.RWMutex           // The commit barrier shared by all of the collections
+	colls   map[string]*Collection // The collections of the catalog
+}
+
+// NewCatalog creates a new, empty catalog of collections.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		colls: make(map[string]*Collection, 4),
+	}
+}
+
+// CreateCollection creates a new collection with the specified name and adds it to the catalog.
+func (c *Catalog) CreateCollection(name string, opts ...Options) (*Collection, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.colls[name]; ok {
+		return nil, fmt.Errorf("column: unable to create collection '%s', already exists", name)
+	}
+
+	collection := NewCollection(opts...)
+	collection.barrier = &c.barrier
+	c.colls[name] = collection
+	return collection, nil
+}
+
+// Collection returns a collection with the specified name, if it exists.
+func (c *Catalog) Collection(name string) (*Collection, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	collection, ok := c.colls[name]
+	return collection, ok
+}
+
+// Close closes all of the collections in the catalog.
+func (c *Catalog) Close() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, collection := range c.colls {
+		collection.Close()
+	}
+	return nil
+}
+
+// Names returns the sorted names of the collections in the catalog.
+func (c *Catalog) Names() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.names()
+}
+
+// names returns the sorted names of the collections in the catalog.
+func (c *Catalog) names() []string {
+	names := make([]string, 0, len(c.colls))
+	for name := range c.colls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// --------------------------- Snapshotting ---------------------------
+
+// Snapshot writes a snapshot of all of the collections into the underlying writer. The state
+// of every collection is captured at the same logical commit point, identified by a commit
+// sequence number. While the state is being captured, commits of all collections of this
+// catalog are paused, and are resumed before the snapshot is written out.
+func (c *Catalog) Snapshot(dst io.Writer) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	// Capture the state of every collection at the same commit point
+	names := c.names()
+	states := make([]*bytes.Buffer, len(names))
+	c.barrier.Lock()
+	cut := commit.Next()
+	for i, name := range names {
+		states[i] = bytes.NewBuffer(nil)
+		if _, err := c.colls[name].writeState(states[i]); err != nil {
+			c.barrier.Unlock()
+			return err
+		}
+	}
+	c.barrier.Unlock()
+
+	// Write the captured states
+	writer := iostream.NewWriter(s2.NewWriter(dst))
+	if err := writer.WriteUvarint(cut); err != nil {
+		return err
+	}
+
+	if err := writer.WriteRange(len(names), func(i int, w *iostream.Writer) error {
+		if err := w.WriteString(names[i]); err != nil {
+			return err
+		}
+		return w.WriteBytes(states[i].Bytes())
+	}); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Restore restores all of the collections from the underlying snapshot reader and returns
+// the commit sequence number at which the snapshot was taken. The collections must already
+// be created in the catalog with the appropriate schema.
+func (c *Catalog) Restore(src io.Reader) (uint64, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	reader := iostream.NewReader(s2.NewReader(src))
+	cut, err := reader.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	return cut, reader.ReadRange(func(i int, r *iostream.Reader) error {
+		name, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+
+		state, err := r.ReadBytes()
+		if err != nil {
+			return err
+		}
+
+		collection, ok := c.colls[name]
+		if !ok {
+			return fmt.Errorf("column: unable to restore collection '%s', does not exist", name)
+		}
+
+		_, err = collection.readState(bytes.NewReader(state))
+		return err
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogSnapshot(t *testing.T) {
+	input := newCatalog(t)
+	defer input.Close()
+
+	orders, _ := input.Collection("orders")
+	stock, _ := input.Collection("inventory")
+	for i := 0; i < 100; i++ {
+		orders.InsertObject(Object{"item": "apple", "qty": 1})
+		stock.InsertObject(Object{"item": "apple", "qty": 10})
+	}
+
+	// Snapshot the entire catalog
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(buffer))
+
+	// Restore into a catalog with the same schema
+	output := newCatalog(t)
+	defer output.Close()
+	cut, err := output.Restore(buffer)
+	assert.NoError(t, err)
+	assert.NotZero(t, cut)
+
+	orders, _ = output.Collection("orders")
+	stock, _ = output.Collection("inventory")
+	assert.Equal(t, 100, orders.Count())
+	assert.Equal(t, 100, stock.Count())
+	stock.Query(func(txn *Txn) error {
+		assert.Equal(t, 1000, txn.Int("qty").Sum())
+		return nil
+	})
+}
+
+func TestCatalogRestoreMissing(t *testing.T) {
+	input := newCatalog(t)
+	defer input.Close()
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(buffer))
+
+	output := NewCatalog()
+	_, err := output.Restore(buffer)
+	assert.Error(t, err)
+}
+
+func TestCatalogDuplicate(t *testing.T) {
+	catalog := newCatalog(t)
+	defer catalog.Close()
+
+	_, err := catalog.CreateCollection("orders")
+	assert.Error(t, err)
+
+	_, ok := catalog.Collection("invalid")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"inventory", "orders"}, catalog.Names())
+}
+
+// newCatalog creates a new catalog with orders and inventory collections
+func newCatalog(t *testing.T) *Catalog {
+	catalog := NewCatalog()
+	for _, name := range []string{"orders", "inventory"} {
+		collection, err := catalog.CreateCollection(name)
+		assert.NoError(t, err)
+		collection.CreateColumn("item", ForEnum())
+		collection.CreateColumn("qty", ForInt())
+	}
+	return catalog
+}
@@ -0,0 +1,376 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	// ErrCheck is returned when a transaction is not committed, because one of the values it
+	// writes does not satisfy the check constraint of its column.
+	ErrCheck = errors.New("column: check constraint violated")
+
+	// ErrUnique is returned when a transaction is not committed, because one of the values it
+	// writes is already held by another row of a column with a unique constraint.
+	ErrUnique = errors.New("column: unique constraint violated")
+)
+
+// CreateCheck creates a check constraint on a column, which is a predicate every value
+// written into the column must satisfy. The values are checked when the transaction commits
+// and if any of them does not satisfy the predicate, the entire transaction is rolled back
+// and an error wrapping ErrCheck, which names the column and the row, is returned. The
+// existing values are checked as well and the constraint is not created if any of them
+// violates it. Several checks can be created on the same column.
+//
+// The predicate receives the value as it would be stored, for example an int64 for a column
+// created with ForInt64(). The increments are checked against the value of the row at the
+// time of the commit, but a concurrent increment of the same row may still be applied in
+// between. Deleting a value is always allowed.
+func (c *Collection) CreateCheck(columnName string, fn func(v interface{}) bool) error {
+	if fn == nil {
+		return fmt.Errorf("column: check must specify a predicate")
+	}
+
+	return c.lockAll(func() error {
+		column, ok := c.cols.Load(columnName)
+		if !ok {
+			return fmt.Errorf("column: unable to create check, column '%v' does not exist", columnName)
+		}
+
+		if column.IsIndex() {
+			return fmt.Errorf("column: unable to create check, column '%v' is an index", columnName)
+		}
+
+		// Make sure the existing values satisfy the check
+		for chunk := commit.Chunk(0); int(chunk) < c.chunks(); chunk++ {
+			var err error
+			column.Index(chunk).Range(func(x uint32) {
+				idx := chunk.Min() + x
+				if v, ok := column.Value(idx); ok && err == nil && !fn(v) {
+					err = violation(columnName, idx, v)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		c.checks.add(columnName, fn)
+		return nil
+	})
+}
+
+// CreateUnique creates a unique constraint on a column, so that no two rows hold the same
+// value. A hash index is created on the column, unless it already has one, in order to look
+// up the rows holding a value. The existing values must be unique for the constraint to be
+// created, and dropping the hash index drops the constraint.
+//
+// The constraint is checked when the transaction commits, over the values the rows end up
+// with rather than after every operation. Hence, the values of two rows can be swapped within
+// a transaction, and a value can be moved from a row which is deleted to another one.
+func (c *Collection) CreateUnique(columnName string) error {
+	if _, ok := c.cols.Load(hashIndexOf(columnName)); !ok {
+		if err := c.CreateHashIndex(columnName); err != nil {
+			return err
+		}
+	}
+
+	return c.lockAll(func() error {
+		index, ok := c.cols.Load(hashIndexOf(columnName))
+		if !ok {
+			return fmt.Errorf("column: unable to create unique constraint, column '%v' has no hash index", columnName)
+		}
+
+		// Make sure the existing values are unique
+		hash := index.Column.(*columnHash)
+		hash.lock.RLock()
+		defer hash.lock.RUnlock()
+		for key, rows := range hash.rows {
+			if rows.Count() > 1 {
+				idx, _ := rows.Max()
+				return duplicate(columnName, idx, key)
+			}
+		}
+
+		c.checks.addUnique(columnName)
+		return nil
+	})
+}
+
+// violation returns the error for a value which violates a check constraint
+func violation(columnName string, idx uint32, value any) error {
+	return fmt.Errorf("%w, value %v of column '%v' at row %d", ErrCheck, value, columnName, idx)
+}
+
+// duplicate returns the error for a value which violates a unique constraint
+func duplicate(columnName string, idx uint32, value any) error {
+	return fmt.Errorf("%w, value %v of column '%v' at row %d", ErrUnique, value, columnName, idx)
+}
+
+// checks represents the check constraints of the columns of a collection
+type checks struct {
+	lock   sync.RWMutex                          // The lock to protect the rules
+	count  int32                                 // The number of check constraints
+	unique int32                                 // The number of unique constraints
+	rules  map[string][]func(v interface{}) bool // The check constraints, by column name
+	keys   map[string]bool                       // The columns with a unique constraint
+}
+
+// add adds a check constraint to a column
+func (c *checks) add(columnName string, fn func(v interface{}) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.rules == nil {
+		c.rules = make(map[string][]func(v interface{}) bool, 4)
+	}
+
+	c.rules[columnName] = append(c.rules[columnName], fn)
+	atomic.AddInt32(&c.count, 1)
+}
+
+// addUnique adds a unique constraint to a column
+func (c *checks) addUnique(columnName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]bool, 4)
+	}
+
+	if !c.keys[columnName] {
+		c.keys[columnName] = true
+		atomic.AddInt32(&c.unique, 1)
+	}
+}
+
+// hasUnique returns whether any of the columns has a unique constraint
+func (c *checks) hasUnique() bool {
+	return atomic.LoadInt32(&c.unique) > 0
+}
+
+// drop removes the check and unique constraints of a column
+func (c *checks) drop(columnName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	atomic.AddInt32(&c.count, -int32(len(c.rules[columnName])))
+	delete(c.rules, columnName)
+	c.dropUnique(columnName)
+}
+
+// dropUnique removes the unique constraint of a column, the lock must be held
+func (c *checks) dropUnique(columnName string) {
+	if c.keys[columnName] {
+		atomic.AddInt32(&c.unique, -1)
+		delete(c.keys, columnName)
+	}
+}
+
+// rename moves the check constraints of a column to its new name
+func (c *checks) rename(columnName, newName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if rules, ok := c.rules[columnName]; ok {
+		c.rules[newName] = rules
+		delete(c.rules, columnName)
+	}
+	if c.keys[columnName] {
+		c.keys[newName] = true
+		delete(c.keys, columnName)
+	}
+}
+
+// checkValues checks whether the values written by the transaction satisfy the check
+// constraints of their columns, in which case nil is returned. Since a row can be updated
+// several times by a transaction, only the value it ends up with is checked.
+func (txn *Txn) checkValues() (err error) {
+	checks := &txn.owner.checks
+	if atomic.LoadInt32(&checks.count) == 0 {
+		return txn.checkUnique()
+	}
+
+	checks.lock.RLock()
+	for _, u := range txn.updates {
+		rules := checks.rules[u.Column]
+		column, ok := txn.owner.cols.Load(u.Column)
+		if len(rules) == 0 || !ok || u.IsEmpty() {
+			continue
+		}
+
+		u.RangeChunks(func(chunk commit.Chunk) {
+			if err != nil {
+				return
+			}
+
+			rows, written := txn.pendingValues(u, column, chunk)
+			for _, idx := range rows {
+				if v := written[idx]; v.ok {
+					for _, fn := range rules {
+						if err == nil && !fn(v.value) {
+							err = violation(u.Column, idx, v.value)
+						}
+					}
+				}
+			}
+		})
+
+		if err != nil {
+			break
+		}
+	}
+
+	checks.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	return txn.checkUnique()
+}
+
+// checkUnique checks whether the values written by the transaction into the columns with a
+// unique constraint are held by no other row, in which case nil is returned. Only the values
+// the rows end up with are checked, and the rows which are written or deleted by the
+// transaction do not hold their current value anymore.
+func (txn *Txn) checkUnique() (err error) {
+	checks := &txn.owner.checks
+	if !checks.hasUnique() {
+		return nil
+	}
+
+	checks.lock.RLock()
+	defer checks.lock.RUnlock()
+
+	var deleted bitmap.Bitmap
+	if markers, ok := txn.findMarkers(); ok {
+		markers.RangeChunks(func(chunk commit.Chunk) {
+			txn.reader.Range(markers, chunk, func(r *commit.Reader) {
+				for r.Next() {
+					if r.Type == commit.Delete {
+						deleted.Set(r.Index())
+					}
+				}
+			})
+		})
+	}
+
+	for _, u := range txn.updates {
+		column, ok := txn.owner.cols.Load(u.Column)
+		index, indexed := txn.owner.cols.Load(hashIndexOf(u.Column))
+		if !checks.keys[u.Column] || !ok || !indexed || u.IsEmpty() {
+			continue
+		}
+
+		// Compute the values the rows end up with, across all of the chunks
+		var rows []uint32
+		var written bitmap.Bitmap
+		values := make(map[uint32]pendingValue, 8)
+		visited := make(map[commit.Chunk]bool, 2)
+		u.RangeChunks(func(chunk commit.Chunk) {
+			if visited[chunk] {
+				return
+			}
+
+			visited[chunk] = true
+			chunkRows, chunkValues := txn.pendingValues(u, column, chunk)
+			for _, idx := range chunkRows {
+				if !written.Contains(idx) {
+					written.Set(idx)
+					rows = append(rows, idx)
+				}
+				values[idx] = chunkValues[idx]
+			}
+		})
+
+		// Every value must be held by a single row, once the transaction is committed
+		hash := index.Column.(*columnHash)
+		owners := make(map[any]uint32, len(rows))
+		var holders bitmap.Bitmap
+		for _, idx := range rows {
+			v := values[idx]
+			if !v.ok || deleted.Contains(idx) {
+				continue
+			}
+
+			key := hashKey(v.value)
+			if _, ok := owners[key]; ok {
+				return duplicate(u.Column, idx, v.value)
+			}
+
+			owners[key] = idx
+			hash.Lookup(key, &holders)
+			holders.AndNot(written)
+			holders.AndNot(deleted)
+			if holders.Count() > 0 {
+				return duplicate(u.Column, idx, v.value)
+			}
+		}
+	}
+	return nil
+}
+
+// serialized returns whether the transaction must be committed while holding the verify lock
+// of the collection, so that its reads or unique values are not modified concurrently.
+func (txn *Txn) serialized() bool {
+	return len(txn.reads) > 0 || (txn.owner.checks.hasUnique() && txn.hasUpdates())
+}
+
+// pendingValues computes the values which the rows of a chunk end up with once the updates
+// of the column are applied, in the order the rows were first written.
+func (txn *Txn) pendingValues(u *commit.Buffer, column *column, chunk commit.Chunk) ([]uint32, map[uint32]pendingValue) {
+	rows, written := make([]uint32, 0, 8), make(map[uint32]pendingValue, 8)
+	txn.owner.slock.RLock(uint(chunk))
+	txn.owner.thaw(chunk)
+	txn.reader.Range(u, chunk, func(r *commit.Reader) {
+		for r.Next() {
+			prev, ok := written[r.Index()]
+			if !ok {
+				rows = append(rows, r.Index())
+				prev.value, _ = column.Value(r.Index())
+			}
+			written[r.Index()] = column.nextValue(r, prev.value)
+		}
+	})
+	txn.owner.slock.RUnlock(uint(chunk))
+	return rows, written
+}
+
+// pendingValue represents a value written by a transaction which is yet to be committed
+type pendingValue struct {
+	value any  // The value written, or the previous one if it was deleted
+	ok    bool // Whether the row has a value
+}
+
+// nextValue decodes the value written into the column by the current operation of the
+// reader, given the value previously written at its row for the increments.
+func (c *column) nextValue(r *commit.Reader, prev any) pendingValue {
+	switch col := c.Column.(type) {
+	case *columnBool:
+		return pendingValue{value: r.Type == commit.PutTrue, ok: true}
+	case interface{ nextValue(*commit.Reader, any) any }:
+		if r.Type == commit.Delete {
+			return pendingValue{value: prev}
+		}
+		return pendingValue{value: col.nextValue(r, prev), ok: true}
+	case *columnAny:
+		if r.Type == commit.Delete {
+			return pendingValue{}
+		}
+
+		_, value := decodeAny(r.Bytes())
+		return pendingValue{value: value, ok: true}
+	default:
+		switch {
+		case r.Type == commit.Delete:
+			return pendingValue{}
+		case c.IsTextual():
+			return pendingValue{value: r.String(), ok: true}
+		default:
+			return pendingValue{value: r.Bytes(), ok: true}
+		}
+	}
+}
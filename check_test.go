@@ -0,0 +1,238 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("age", ForInt64())
+	assert.NoError(t, c.CreateCheck("age", func(v interface{}) bool {
+		age := v.(int64)
+		return age >= 0 && age <= 150
+	}))
+	assert.NoError(t, c.CreateCheck("name", func(v interface{}) bool {
+		return v.(string) != ""
+	}))
+
+	idx, err := c.Insert(func(r Row) error {
+		r.SetString("name", "Merlin")
+		r.SetInt64("age", 101)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Inserting an invalid row fails the entire transaction
+	_, err = c.Insert(func(r Row) error {
+		r.SetString("name", "Roman")
+		r.SetInt64("age", -1)
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrCheck))
+	assert.Contains(t, err.Error(), "'age' at row 1")
+	assert.Equal(t, 1, c.Count())
+
+	// Updating a value with an invalid one leaves the row untouched
+	err = c.Query(func(txn *Txn) error {
+		return txn.QueryAt(idx, func(r Row) error {
+			r.SetString("name", "Merlin the Wise")
+			r.SetInt64("age", 200)
+			return nil
+		})
+	})
+	assert.True(t, errors.Is(err, ErrCheck))
+	assert.Equal(t, Object{"name": "Merlin", "age": int64(101)}, objectAt(c, idx))
+
+	// The increments are checked against the stored value
+	assert.True(t, errors.Is(c.QueryAt(idx, func(r Row) error {
+		r.txn.Int64("age").Add(50)
+		return nil
+	}), ErrCheck))
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		r.txn.Int64("age").Add(60)
+		r.txn.Int64("age").Add(-20)
+		return nil
+	}))
+	assert.Equal(t, Object{"name": "Merlin", "age": int64(141)}, objectAt(c, idx))
+
+	// Deleting a value is always allowed
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		r.SetInt64("age", 1000)
+		r.SetAny("age", nil)
+		return nil
+	}))
+	assert.Equal(t, Object{"name": "Merlin"}, objectAt(c, idx))
+}
+
+func TestCheckTypes(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("active", ForBool())
+	c.CreateColumn("class", ForEnum())
+	c.CreateColumn("meta", ForAny())
+	c.CreateColumn("score", ForUint16())
+	assert.NoError(t, c.CreateCheck("active", func(v interface{}) bool { return v.(bool) }))
+	assert.NoError(t, c.CreateCheck("class", func(v interface{}) bool { return v.(string) != "rogue" }))
+	assert.NoError(t, c.CreateCheck("meta", func(v interface{}) bool { return v != "secret" }))
+	assert.NoError(t, c.CreateCheck("score", func(v interface{}) bool { return v.(uint16) < 65535 }))
+
+	for _, obj := range []Object{
+		{"active": false},
+		{"class": "rogue"},
+		{"meta": "secret"},
+		{"score": 70000},
+	} {
+		_, err := c.Insert(func(r Row) error {
+			for k, v := range obj {
+				r.SetAny(k, v)
+			}
+			return nil
+		})
+		assert.True(t, errors.Is(err, ErrCheck), obj)
+	}
+
+	_, err := c.Insert(func(r Row) error {
+		r.SetBool("active", true)
+		r.SetEnum("class", "mage")
+		r.SetAny("meta", "public")
+		r.SetUint16("score", 100)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c.Count())
+
+	// Saturating additions are checked against the clamped value
+	assert.True(t, errors.Is(c.QueryAt(0, func(r Row) error {
+		r.txn.Uint16("score").AddSaturate(65535)
+		return nil
+	}), ErrCheck))
+}
+
+func TestCheckCreate(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("age", ForInt())
+	c.CreateIndex("old", "age", func(r Reader) bool { return r.Int() > 100 })
+	c.InsertObject(Object{"age": 101})
+	c.InsertObject(Object{"age": -5})
+
+	assert.Error(t, c.CreateCheck("age", nil))
+	assert.Error(t, c.CreateCheck("missing", func(interface{}) bool { return true }))
+	assert.Error(t, c.CreateCheck("old", func(interface{}) bool { return true }))
+
+	// The existing values must satisfy the check
+	err := c.CreateCheck("age", func(v interface{}) bool { return v.(int) >= 0 })
+	assert.True(t, errors.Is(err, ErrCheck))
+	assert.Contains(t, err.Error(), "value -5 of column 'age' at row 1")
+
+	// Once fixed, the check can be created and follows the column when renamed
+	assert.NoError(t, c.QueryAt(1, func(r Row) error {
+		r.SetInt("age", 5)
+		return nil
+	}))
+	assert.NoError(t, c.CreateCheck("age", func(v interface{}) bool { return v.(int) >= 0 }))
+	assert.NoError(t, c.RenameColumn("age", "years"))
+	assert.True(t, errors.Is(c.QueryAt(1, func(r Row) error {
+		r.SetInt("years", -1)
+		return nil
+	}), ErrCheck))
+
+	// Once dropped, the column has no checks anymore
+	assert.NoError(t, c.DropColumn("years"))
+	c.CreateColumn("years", ForInt())
+	assert.NoError(t, c.QueryAt(1, func(r Row) error {
+		r.SetInt("years", -1)
+		return nil
+	}))
+}
+
+// objectAt returns the values of a row, as an object
+func objectAt(c *Collection, idx uint32) (object Object) {
+	c.ReadAt(idx, func(v Selector) {
+		object = v.ToObject()
+	})
+	return
+}
+
+func TestUnique(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("slot", ForInt())
+	assert.NoError(t, c.CreateUnique("name"))
+	assert.NoError(t, c.CreateUnique("slot"))
+
+	c.InsertObject(Object{"name": "Roman", "slot": 1})
+	c.InsertObject(Object{"name": "Merlin", "slot": 2})
+
+	// Inserting a duplicate value fails the entire transaction
+	_, err := c.InsertStrict(Object{"name": "Roman", "slot": 3})
+	assert.True(t, errors.Is(err, ErrUnique))
+	assert.Contains(t, err.Error(), "'name' at row 2")
+	assert.Equal(t, 2, c.Count())
+
+	// Writing the same value twice within a transaction fails as well
+	assert.ErrorIs(t, c.Query(func(txn *Txn) error {
+		txn.InsertObject(Object{"name": "Arthur", "slot": 3})
+		txn.InsertObject(Object{"name": "Lancelot", "slot": 3})
+		return nil
+	}), ErrUnique)
+	assert.Equal(t, 2, c.Count())
+
+	// Swap the slots through a temporary value, which is checked only at commit
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		slot := txn.Int("slot")
+		assert.NoError(t, txn.QueryAt(0, func(r Row) error {
+			r.SetInt("slot", 99)
+			return nil
+		}))
+		assert.NoError(t, txn.QueryAt(1, func(r Row) error {
+			r.SetInt("slot", 1)
+			return nil
+		}))
+		return txn.QueryAt(0, func(r Row) error {
+			slot.Set(2)
+			return nil
+		})
+	}))
+
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		v, _ := r.Int("slot")
+		assert.Equal(t, 2, v)
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(1, func(r Row) error {
+		v, _ := r.Int("slot")
+		assert.Equal(t, 1, v)
+		return nil
+	}))
+
+	// A value released by a deleted row can be reused within the same transaction
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		txn.DeleteAt(0)
+		_, err := txn.InsertObject(Object{"name": "Roman", "slot": 2})
+		return err
+	}))
+	assert.Equal(t, 2, c.Count())
+
+	// Dropping the hash index drops the constraint
+	assert.NoError(t, c.DropIndex(hashIndexOf("slot")))
+	_, err = c.InsertStrict(Object{"name": "Arthur", "slot": 1})
+	assert.NoError(t, err)
+	assert.False(t, c.checks.hasUnique() && c.checks.keys["slot"])
+}
+
+func TestUniqueExisting(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.InsertObject(Object{"name": "Roman"})
+	c.InsertObject(Object{"name": "Roman"})
+
+	// The existing values must be unique
+	assert.ErrorIs(t, c.CreateUnique("name"), ErrUnique)
+	assert.Error(t, c.CreateUnique("missing"))
+}
@@ -0,0 +1,32 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import "time"
+
+// Clock represents a source of time, used to compute the expiration of the rows and values
+// with a time-to-live and to decide which of them have expired. A custom clock allows the
+// expiration to be tested deterministically, without waiting for the time to pass.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock returns the options of a collection which uses the specified clock for the
+// time-to-live of its rows and values, instead of the system clock.
+func WithClock(clock Clock) Options {
+	return Options{Clock: clock}
+}
+
+// systemClock represents the clock of the system
+type systemClock struct{}
+
+// Now returns the current local time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns the current time of the clock of the collection
+func (c *Collection) now() time.Time {
+	return c.opts.Clock.Now()
+}
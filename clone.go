@@ -0,0 +1,90 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Clone creates a logically independent copy of the collection, with the same columns,
+// indexes and rows. The chunks of values are shared by both collections until either of
+// them modifies a chunk, at which point the chunk is copied. This makes a clone cheap to
+// create, for example to run a what-if simulation over a point-in-time fork of the state.
+//
+// The commits are held back while the collection is being cloned. The clone does not
+// inherit the commit logger, the changefeeds, the thresholds, the eviction policy or the
+// instrumentation of the collection.
+func (c *Collection) Clone() (*Collection, error) {
+	clone, err := c.fork()
+	if err != nil {
+		return nil, err
+	}
+
+	// If the updates are coalesced, periodically commit the ones held back
+	clone.cols.Range(func(column *column) {
+		if column.coalesce != nil {
+			clone.startCoalesce(column)
+		}
+	})
+	return clone, nil
+}
+
+// fork creates a copy of the collection which shares the chunks of values with it. The
+// commits are held back while the collection is copied, so that the copy never observes a
+// partially applied commit.
+func (c *Collection) fork() (*Collection, error) {
+	clone := NewCollection(Options{
+		Capacity:            c.opts.Capacity,
+		CleanupInterval:     c.opts.CleanupInterval,
+		CompactionThreshold: c.opts.CompactionThreshold,
+		Clock:               c.opts.Clock,
+		ColdAfter:           c.opts.ColdAfter,
+	})
+
+	c.forks.Lock()
+	defer c.forks.Unlock()
+	if err := c.lockAll(func() error {
+		if err := c.cols.CopyTo(clone.cols, clone.cloneColumn); err != nil {
+			return err
+		}
+
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		clone.lock.Lock()
+		defer clone.lock.Unlock()
+		clone.fill = c.fill.Clone(nil)
+		clone.commits = append(make([]uint64, 0, cap(c.commits)), c.commits...)
+		atomic.StoreUint64(&clone.count, atomic.LoadUint64(&c.count))
+		atomic.StoreUint64(&clone.last, atomic.LoadUint64(&c.last))
+		return nil
+	}); err != nil {
+		clone.Close()
+		return nil, err
+	}
+	return clone, nil
+}
+
+// cloneColumn creates a copy of a column for this collection. The thresholds are not copied,
+// since their handlers belong to the original collection.
+func (c *Collection) cloneColumn(src *column) (*column, error) {
+	if _, ok := src.Column.(*columnThreshold); ok {
+		return nil, nil
+	}
+
+	source, ok := src.Column.(Cloner)
+	if !ok {
+		return nil, fmt.Errorf("column: unable to clone column '%s' of type %T", src.name, src.Column)
+	}
+
+	src.lock.RLock()
+	created := columnFor(src.name, source.Clone(), src.opts)
+	src.lock.RUnlock()
+
+	// If necessary, keep track of the primary key column
+	if pk, ok := created.Column.(*columnKey); ok {
+		c.pk = pk
+	}
+	return created, nil
+}
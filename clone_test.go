@@ -0,0 +1,122 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	players := loadPlayers(500)
+	players.CreateColumn("data", ForAny())
+	players.CreateColumn("series", ForSeries())
+	assert.NoError(t, players.CreateHashIndex("race"))
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		data, series := txn.Any("data"), txn.Series("series")
+		return txn.Range(func(idx uint32) {
+			data.Set(int(idx))
+			series.Set(float64(idx))
+		})
+	}))
+
+	stateOf := func(c *Collection) (state [6]int) {
+		for i, fn := range []func(txn *Txn) int{
+			func(txn *Txn) int { return txn.Count() },
+			func(txn *Txn) int { return txn.With("dwarf").Count() },
+			func(txn *Txn) int { return txn.With("old").Count() },
+			func(txn *Txn) int { return int(txn.Float64("age").Sum()) },
+			func(txn *Txn) int { return txn.With("series").Count() },
+			func(txn *Txn) int { return txn.WithEqual("race", "elf").Count() },
+		} {
+			assert.NoError(t, c.Query(func(txn *Txn) error {
+				state[i] = fn(txn)
+				return nil
+			}))
+		}
+		return
+	}
+
+	clone, err := players.Clone()
+	assert.NoError(t, err)
+	defer clone.Close()
+
+	original := stateOf(players)
+	assert.Equal(t, original, stateOf(clone))
+
+	// Modify the clone, the original remains intact
+	assert.NoError(t, clone.Query(func(txn *Txn) error {
+		age, data := txn.Float64("age"), txn.Any("data")
+		return txn.With("human").Range(func(idx uint32) {
+			age.Set(10)
+			data.Set("human")
+		})
+	}))
+	assert.NoError(t, clone.Query(func(txn *Txn) error {
+		txn.With("dwarf").DeleteAll()
+		return nil
+	}))
+	clone.InsertObject(Object{"serial": "merlin", "race": "elf", "age": 101.0, "series": 1.0})
+
+	assert.Equal(t, original, stateOf(players))
+	_, found := players.pk.OffsetOf("merlin")
+	assert.False(t, found)
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		data := txn.Any("data")
+		return txn.With("human").Range(func(idx uint32) {
+			v, _ := data.Get()
+			assert.Equal(t, int(idx), v)
+		})
+	}))
+
+	modified := stateOf(clone)
+	assert.Equal(t, original[0]-original[1]+1, modified[0])
+	assert.Equal(t, 0, modified[1])
+	assert.NotEqual(t, original[3], modified[3])
+	assert.Equal(t, original[5]+1, modified[5])
+	assert.NoError(t, clone.QueryKey("merlin", func(r Row) error {
+		age, _ := r.Float64("age")
+		assert.Equal(t, 101.0, age)
+		return nil
+	}))
+
+	// Modify the original, the clone remains intact
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		txn.With("elf").DeleteAll()
+		return nil
+	}))
+	assert.Equal(t, 0, stateOf(players)[5])
+	assert.Equal(t, modified, stateOf(clone))
+}
+
+func TestCloneThreshold(t *testing.T) {
+	var fired int32
+	sensors := NewCollection()
+	sensors.CreateColumn("temperature", ForFloat64())
+	assert.NoError(t, sensors.OnThreshold("temperature", func(v float64) bool {
+		return v > 50
+	}, func(idx uint32, v float64) {
+		atomic.AddInt32(&fired, 1)
+	}))
+
+	idx := sensors.InsertObject(Object{"temperature": 20.0})
+	clone, err := sensors.Clone()
+	assert.NoError(t, err)
+	defer clone.Close()
+
+	// The thresholds of the original collection are not copied
+	assert.NoError(t, clone.QueryAt(idx, func(r Row) error {
+		r.SetFloat64("temperature", 60)
+		return nil
+	}))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fired))
+
+	assert.NoError(t, sensors.QueryAt(idx, func(r Row) error {
+		r.SetFloat64("temperature", 60)
+		return nil
+	}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// WithCoalesce specifies a window within which the successive updates of the same cell are
+// coalesced. The first update of a cell is committed right away, while the following ones
+// within the window are held back and only the latest of them is committed once the window
+// closes. This reduces the number of commits (and changefeed entries) for the values which
+// are updated thousands of times per second, such as telemetry, at the expense of readers
+// observing the latest value up to one window late. Increments are never coalesced.
+func WithCoalesce(window time.Duration) ColumnOption {
+	return func(o *columnOptions) {
+		o.Coalesce = window
+	}
+}
+
+// coalescer holds back the successive updates of the cells of a column
+type coalescer struct {
+	lock    sync.Mutex
+	recent  bitmap.Bitmap             // The cells updated within the current window
+	pending map[uint32]*commit.Buffer // The latest update held back for every cell
+	reader  *commit.Reader            // The reader of the pending updates
+	due     int64                     // The time at which the current window closes
+}
+
+// newCoalescer creates a new coalescer for a column
+func newCoalescer() *coalescer {
+	return &coalescer{
+		pending: make(map[uint32]*commit.Buffer, 64),
+		reader:  commit.NewReader(),
+	}
+}
+
+// hold keeps the current operation of the reader as the pending update of its cell
+func (c *coalescer) hold(column string, r *commit.Reader) {
+	buffer, ok := c.pending[r.Index()]
+	if !ok {
+		buffer = commit.NewBuffer(16)
+		c.pending[r.Index()] = buffer
+	}
+
+	buffer.Reset(column)
+	buffer.PutFrom(r.Index(), r)
+}
+
+// release writes the pending update of the cell into the destination and forgets it
+func (c *coalescer) release(idx uint32, dst *commit.Buffer) {
+	if buffer, ok := c.pending[idx]; ok {
+		c.reader.Seek(buffer)
+		for c.reader.Next() {
+			dst.PutFrom(idx, c.reader)
+		}
+		delete(c.pending, idx)
+	}
+}
+
+// apply filters the operations of the buffer into the destination, holding back the updates
+// of the cells which were already updated within the current window.
+func (c *coalescer) apply(src, dst *commit.Buffer, r *commit.Reader) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	r.Seek(src)
+	for r.Next() {
+		idx := r.Index()
+		switch {
+		case r.Type == commit.Add || r.Type == commit.Saturate:
+			c.release(idx, dst) // The increment applies on top of the held back value
+			dst.PutFrom(idx, r)
+		case c.recent.Contains(idx):
+			c.hold(src.Column, r)
+		default:
+			c.recent.Set(idx)
+			dst.PutFrom(idx, r)
+		}
+	}
+}
+
+// forget discards the state of the cells of the deleted rows
+func (c *coalescer) forget(deleted bitmap.Bitmap) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.recent.AndNot(deleted)
+	deleted.Range(func(idx uint32) {
+		delete(c.pending, idx)
+	})
+}
+
+// flush closes the current window and writes the pending updates of the rows which still
+// exist into the destination. These cells remain throttled for the next window.
+func (c *coalescer) flush(dst *commit.Buffer, exists func(idx uint32) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.recent.Clear()
+	for idx := range c.pending {
+		if exists(idx) {
+			c.recent.Set(idx)
+			c.release(idx, dst)
+		}
+	}
+
+	// Start over, so the memory of the cells which are no longer updated can be reclaimed
+	c.pending = make(map[uint32]*commit.Buffer, len(c.pending))
+}
+
+// --------------------------- Transaction ----------------------------
+
+// coalesce holds back the successive updates of the cells of the columns with a coalescing
+// window, unless the updates were already coalesced (e.g. when replaying a commit).
+func (txn *Txn) coalesce() {
+	if txn.merged {
+		return
+	}
+
+	var deleted bitmap.Bitmap
+	for i, u := range txn.updates {
+		if u.IsEmpty() {
+			continue
+		}
+
+		// Collect the deleted rows, so that their held back updates are discarded
+		if u.Column == rowColumn {
+			txn.reader.Seek(u)
+			for txn.reader.Next() {
+				if txn.reader.Type == commit.Delete {
+					deleted.Set(txn.reader.Index())
+				}
+			}
+			continue
+		}
+
+		column, ok := txn.columnAt(u.Column)
+		if !ok || column.coalesce == nil {
+			continue
+		}
+
+		// Replace the buffer with the updates which were not held back
+		out := txn.owner.txns.acquirePage(u.Column)
+		column.coalesce.apply(u, out, txn.reader)
+		txn.owner.txns.releasePage(u)
+		txn.updates[i] = out
+	}
+
+	if len(deleted) > 0 {
+		txn.owner.cols.Range(func(column *column) {
+			if column.coalesce != nil {
+				column.coalesce.forget(deleted)
+			}
+		})
+	}
+}
+
+// flushCoalesced commits the updates of a column which were held back during the window
+func (c *Collection) flushCoalesced(column *column) {
+	c.Query(func(txn *Txn) error {
+		txn.merged = true
+		column.coalesce.flush(txn.bufferFor(column.name), func(idx uint32) bool {
+			c.lock.RLock()
+			defer c.lock.RUnlock()
+			return c.fill.Contains(idx)
+		})
+		return nil
+	})
+}
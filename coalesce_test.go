@@ -0,0 +1,117 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalesce(t *testing.T) {
+	w := make(commit.Channel, 1024)
+	c := NewCollection(Options{
+		Writer: w,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("value", ForInt(), WithCoalesce(50*time.Millisecond)))
+
+	// The first write of the cell is committed right away
+	idx := c.InsertObject(Object{"value": 0})
+	assert.Equal(t, 0, valueAt(c, idx))
+	<-w
+
+	// The successive updates are held back
+	for i := 1; i <= 100; i++ {
+		assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+			r.SetInt("value", i)
+			return nil
+		}))
+	}
+	assert.Less(t, valueAt(c, idx), 100)
+
+	// Only the latest value is committed once the window closes
+	assert.Eventually(t, func() bool {
+		return valueAt(c, idx) == 100
+	}, time.Second, 5*time.Millisecond)
+	assert.LessOrEqual(t, len(w), 3)
+}
+
+func TestCoalesceAdd(t *testing.T) {
+	c := NewCollection(Options{
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("value", ForInt(), WithCoalesce(time.Hour))
+
+	idx := c.InsertObject(Object{"value": 1})
+	c.QueryAt(idx, func(r Row) error {
+		r.SetInt("value", 10)
+		return nil
+	})
+	assert.Equal(t, 1, valueAt(c, idx))
+
+	// The increment applies on top of the value held back
+	c.QueryAt(idx, func(r Row) error {
+		r.AddInt("value", 5)
+		return nil
+	})
+	assert.Equal(t, 15, valueAt(c, idx))
+}
+
+func TestCoalesceDelete(t *testing.T) {
+	c := NewCollection(Options{
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("value", ForInt(), WithCoalesce(time.Hour))
+	column, _ := c.cols.Load("value")
+
+	idx := c.InsertObject(Object{"value": 1})
+	c.QueryAt(idx, func(r Row) error {
+		r.SetInt("value", 10)
+		return nil
+	})
+	assert.Len(t, column.coalesce.pending, 1)
+
+	// Deleting the row discards the updates held back
+	c.DeleteAt(idx)
+	assert.Len(t, column.coalesce.pending, 0)
+	assert.False(t, column.coalesce.recent.Contains(idx))
+
+	// Flushing does not resurrect the value
+	c.flushCoalesced(column)
+	assert.False(t, column.Contains(idx))
+}
+
+func TestCoalesceReplay(t *testing.T) {
+	c := NewCollection(Options{
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("value", ForInt(), WithCoalesce(time.Hour))
+
+	// The replayed commits are applied as they are
+	idx := c.InsertObject(Object{"value": 1})
+	update := commit.NewBuffer(16)
+	update.Reset("value")
+	update.PutInt(idx, 42)
+	assert.NoError(t, c.Replay(commit.Commit{
+		Chunk:   commit.ChunkAt(idx),
+		Updates: []*commit.Buffer{update},
+	}))
+	assert.Equal(t, 42, valueAt(c, idx))
+}
+
+// valueAt reads the "value" column at the specified index
+func valueAt(c *Collection, idx uint32) (v int) {
+	c.QueryAt(idx, func(r Row) error {
+		v, _ = r.Int("value")
+		return nil
+	})
+	return
+}
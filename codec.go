@@ -0,0 +1,94 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kelindar/column/commit"
+)
+
+// Codec represents a compression scheme which can be plugged into a column in order to
+// compress its data in the snapshots and, with the ColdAfter option of the collection, the
+// chunks held in memory which were not modified for a while. A codec compresses the bytes
+// of a chunk as a block.
+type Codec = commit.Codec
+
+// codecs represents a registry of codecs by their name
+var codecs = struct {
+	sync.RWMutex
+	registry map[string]Codec
+}{
+	registry: map[string]Codec{
+		"s2":     commit.S2,
+		"snappy": commit.Snappy,
+		"zstd":   commit.Zstd,
+	},
+}
+
+// RegisterCodec registers a codec with a specified name, so that it can be used by the
+// columns when writing a snapshot and recognized when restoring it. The name must be unique.
+func RegisterCodec(name string, codec Codec) error {
+	if name == "" || codec == nil {
+		return fmt.Errorf("column: register codec must specify name and codec")
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, exists := codecs.registry[name]; exists {
+		return fmt.Errorf("column: unable to register codec '%s', already exists", name)
+	}
+
+	codecs.registry[name] = codec
+	return nil
+}
+
+// codecOf loads a registered codec by its name
+func codecOf(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	codec, ok := codecs.registry[name]
+	return codec, ok
+}
+
+// --------------------------- Column Options ----------------------------
+
+// ColumnOption represents an option which can be specified when creating a column.
+type ColumnOption func(*columnOptions)
+
+// columnOptions represents a set of options of a column
+type columnOptions struct {
+	Codec     string        // The name of the codec to use (optional)
+	TTL       time.Duration // The time-to-live of the values written to the column (optional)
+	Coalesce  time.Duration // The window within which the updates of a cell are coalesced (optional)
+	Mapping   string        // The path of the file backing the values of the column (optional)
+	Allocator Allocator     // The allocator of the memory holding the values of the column (optional)
+	expiryOf  string        // The name of the column whose expiration this column holds, if any
+}
+
+// copyTo copies the options into the destination, this is used when copying a schema
+func (o columnOptions) copyTo(dst *columnOptions) {
+	*dst = o
+}
+
+// WithTTL specifies the time-to-live of the values of the column. Every value written to
+// the column expires once this duration has elapsed since the write, at which point the
+// value is removed from the row while the row itself is retained. The expiration times are
+// stored in an auxiliary column, named after the column with an "#expire" suffix.
+func WithTTL(ttl time.Duration) ColumnOption {
+	return func(o *columnOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithCodec specifies the name of a registered codec which should be used to compress the
+// data of the column in the snapshots. If the ColdAfter option of the collection is set, the
+// cold chunks of the numeric columns are also compressed in memory with the same codec.
+func WithCodec(name string) ColumnOption {
+	return func(o *columnOptions) {
+		o.Codec = name
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterCodec(t *testing.T) {
+	assert.Error(t, RegisterCodec("", nil))
+	assert.Error(t, RegisterCodec("s2", new(reverseCodec)))
+	assert.NoError(t, RegisterCodec("test-register", new(reverseCodec)))
+
+	codec, ok := codecOf("test-register")
+	assert.True(t, ok)
+	assert.NotNil(t, codec)
+}
+
+func TestCreateColumnInvalidCodec(t *testing.T) {
+	col := NewCollection()
+	defer col.Close()
+	assert.Error(t, col.CreateColumn("name", ForString(), WithCodec("invalid")))
+}
+
+func TestSnapshotWithCodec(t *testing.T) {
+	RegisterCodec("test-reverse", new(reverseCodec))
+	newCodecs := func() *Collection {
+		out := NewCollection()
+		out.CreateColumn("name", ForString(), WithCodec("s2"))
+		out.CreateColumn("age", ForInt(), WithCodec("test-reverse"))
+		return out
+	}
+
+	input := newCodecs()
+	defer input.Close()
+	for i := 0; i < 1000; i++ {
+		input.InsertObject(Object{"name": "Roman", "age": i})
+	}
+
+	// Write a snapshot into a buffer
+	buffer := bytes.NewBuffer(nil)
+	_, err := input.writeState(buffer)
+	assert.NoError(t, err)
+
+	// Restore the collection from the snapshot
+	output := newCodecs()
+	defer output.Close()
+	_, err = output.readState(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, output.Count())
+	assert.NoError(t, output.QueryAt(999, func(r Row) error {
+		name, _ := r.String("name")
+		age, _ := r.Int("age")
+		assert.Equal(t, "Roman", name)
+		assert.Equal(t, 999, age)
+		return nil
+	}))
+}
+
+func TestSnapshotCompression(t *testing.T) {
+	keys := &commit.Keyring{
+		Current: "v1",
+		Keys:    map[string][]byte{"v1": bytes.Repeat([]byte{7}, 32)},
+	}
+
+	for _, codec := range []commit.Codec{commit.S2, commit.Snappy, commit.Zstd} {
+		for _, encryption := range []commit.KeyProvider{nil, keys} {
+			options := Options{Compression: codec, Encryption: encryption}
+			newCollection := func() *Collection {
+				out := NewCollection(options)
+				out.CreateColumn("name", ForString())
+				return out
+			}
+
+			input := newCollection()
+			for i := 0; i < 500; i++ {
+				input.InsertObject(Object{"name": "Roman"})
+			}
+
+			buffer := bytes.NewBuffer(nil)
+			assert.NoError(t, input.Snapshot(buffer))
+			input.Close()
+
+			// Followed by the commits recorded while the snapshot was in progress
+			recorded := commit.OpenWith(buffer, input.logOptions())
+			assert.NoError(t, recorded.Append(commit.Commit{ID: commit.Next()}))
+
+			output := newCollection()
+			assert.NoError(t, output.Restore(buffer))
+			assert.Equal(t, 500, output.Count())
+			output.Close()
+		}
+	}
+}
+
+func TestRestoreVersion1(t *testing.T) {
+	src, err := os.Open("fixtures/players.bin")
+	assert.NoError(t, err)
+	defer src.Close()
+
+	output := newEmpty(500)
+	defer output.Close()
+	assert.NoError(t, output.Restore(src))
+	assert.NotZero(t, output.Count())
+}
+
+// reverseCodec is a codec which simply reverses the bytes
+type reverseCodec struct{}
+
+func (c *reverseCodec) Encode(dst, src []byte) []byte {
+	for i := len(src) - 1; i >= 0; i-- {
+		dst = append(dst, src[i])
+	}
+	return dst
+}
+
+func (c *reverseCodec) Decode(dst, src []byte) ([]byte, error) {
+	return c.Encode(dst, src), nil
+}
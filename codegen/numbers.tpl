@@ -27,6 +27,9 @@ func make{{.Name}}s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddTo{{.Name}}(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateTo{{.Name}}(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -51,6 +54,23 @@ func (s {{.Type}}Writer) Add(delta {{.Type}}) {
 	s.writer.Add{{.Name}}(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of {{.Type}} instead of overflowing
+func (s {{.Type}}Writer) AddSaturate(delta {{.Type}}) {
+	s.writer.AddSaturate{{.Name}}(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow {{.Type}}, in which case ErrOverflow is returned instead
+func (s {{.Type}}Writer) AddChecked(delta {{.Type}}) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturate{{.Name}}(s.txn.cursor, delta)
+	return nil
+}
+
 // {{.Name}} returns a read-write accessor for {{.Type}} column
 func (txn *Txn) {{.Name}}(columnName string) {{.Type}}Writer {
 	return {{.Type}}Writer{
@@ -59,4 +79,23 @@ func (txn *Txn) {{.Name}}(columnName string) {{.Type}}Writer {
 	}
 }
 
+// Range{{.Name}} iterates over the {{.Type}} values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) Range{{.Name}}(columnName string, fn func(idx uint32, v {{.Type}})) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// Apply{{.Name}} updates every {{.Type}} value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) Apply{{.Name}}(columnName string, fn func(v {{.Type}}) {{.Type}}) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// {{.Name}}s invokes the callback with a read-only view of the raw {{.Type}} values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) {{.Name}}s(columnName string, fn func(offset uint32, values []{{.Type}}, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 {{ end }}
\ No newline at end of file
@@ -0,0 +1,175 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kelindar/column/commit"
+)
+
+// freezer represents a column which is able to compress the values of a chunk with a codec
+// while the chunk is cold, and to decompress them before the chunk is accessed again.
+type freezer interface {
+	freeze(chunk commit.Chunk, codec Codec) bool
+	thaw(chunk commit.Chunk, codec Codec) error
+}
+
+// coldChunks represents the chunks of a collection whose values are compressed, since they
+// were not modified for a while. A chunk is compressed while exclusively latched and is
+// decompressed by the first reader or writer which latches it afterwards.
+type coldChunks struct {
+	pass  sync.Mutex   // The lock which serializes the passes of the vacuum
+	lock  sync.RWMutex // The lock which guards the state of the chunks
+	count int32        // The number of compressed chunks, read atomically
+	state []int64      // For every chunk, -1 if compressed or the time it was last decompressed
+	seen  []uint64     // The commit of every chunk, as of the last pass of the vacuum
+	since []int64      // The time since which every chunk was not modified
+}
+
+// compress compresses the values of the chunks which were not modified, nor decompressed, for
+// the duration specified by the ColdAfter option. Only the columns with a codec are compressed.
+func (c *Collection) compress() {
+	after := int64(c.opts.ColdAfter)
+	if after <= 0 || !c.hasFreezer() {
+		return
+	}
+
+	c.lock.RLock()
+	commits := append([]uint64(nil), c.commits...)
+	c.lock.RUnlock()
+
+	cold := &c.cold
+	cold.pass.Lock()
+	defer cold.pass.Unlock()
+
+	now := c.now().UnixNano()
+	for chunk := commit.Chunk(0); int(chunk) < len(commits); chunk++ {
+		if int(chunk) == len(cold.seen) {
+			cold.lock.Lock()
+			cold.seen = append(cold.seen, commits[chunk])
+			cold.since = append(cold.since, now)
+			cold.state = append(cold.state, 0)
+			cold.lock.Unlock()
+			continue
+		}
+
+		if commits[chunk] != cold.seen[chunk] {
+			cold.seen[chunk], cold.since[chunk] = commits[chunk], now
+			continue
+		}
+
+		// Compress the chunk while no transaction can read or write into it
+		c.slock.Lock(uint(chunk))
+		cold.lock.Lock()
+		if last := cold.state[chunk]; last >= 0 && now-maxInt64(last, cold.since[chunk]) >= after && !c.isEmpty(chunk) {
+			c.freeze(chunk)
+		}
+		cold.lock.Unlock()
+		c.slock.Unlock(uint(chunk))
+	}
+}
+
+// freeze compresses the values of a chunk in every column with a codec. The caller must hold
+// the exclusive latch of the chunk and the lock of the cold chunks.
+func (c *Collection) freeze(chunk commit.Chunk) {
+	frozen := false
+	c.cols.Range(func(column *column) {
+		if f, codec, ok := column.freezer(); ok {
+			column.lock.RLock()
+			frozen = f.freeze(chunk, codec) || frozen
+			column.lock.RUnlock()
+		}
+	})
+
+	if frozen {
+		c.cold.state[chunk] = -1
+		atomic.AddInt32(&c.cold.count, 1)
+	}
+}
+
+// thaw decompresses the values of a chunk, if it was compressed. This must be called by every
+// reader and writer of the values once the chunk is latched, before accessing them.
+func (c *Collection) thaw(chunk commit.Chunk) {
+	cold := &c.cold
+	if atomic.LoadInt32(&cold.count) == 0 {
+		return
+	}
+
+	cold.lock.RLock()
+	frozen := int(chunk) < len(cold.state) && cold.state[chunk] < 0
+	cold.lock.RUnlock()
+	if !frozen {
+		return
+	}
+
+	cold.lock.Lock()
+	defer cold.lock.Unlock()
+	if cold.state[chunk] >= 0 {
+		return // Decompressed concurrently
+	}
+
+	c.cols.Range(func(column *column) {
+		if f, codec, ok := column.freezer(); ok {
+			column.lock.RLock()
+			err := f.thaw(chunk, codec)
+			column.lock.RUnlock()
+			if err != nil {
+				panic(fmt.Errorf("column: unable to decompress chunk %d of column '%s', %w", chunk, column.name, err))
+			}
+		}
+	})
+
+	cold.state[chunk] = c.now().UnixNano()
+	atomic.AddInt32(&cold.count, -1)
+}
+
+// thawAll decompresses all of the chunks. The caller must hold all of the latches.
+func (c *Collection) thawAll() {
+	if atomic.LoadInt32(&c.cold.count) == 0 {
+		return
+	}
+
+	c.cold.lock.RLock()
+	chunks := len(c.cold.state)
+	c.cold.lock.RUnlock()
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		c.thaw(chunk)
+	}
+}
+
+// hasFreezer returns whether any of the columns can be compressed with a codec
+func (c *Collection) hasFreezer() (ok bool) {
+	c.cols.Range(func(column *column) {
+		if _, _, can := column.freezer(); can {
+			ok = true
+		}
+	})
+	return
+}
+
+// freezer returns the column as a freezer along with its codec, if it has one
+func (c *column) freezer() (freezer, Codec, bool) {
+	if c.opts.Codec == "" {
+		return nil, nil, false
+	}
+
+	f, ok := c.Column.(freezer)
+	if !ok {
+		return nil, nil, false
+	}
+
+	codec, ok := codecOf(c.opts.Codec)
+	return f, codec, ok
+}
+
+// maxInt64 returns the largest of the two values
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && !wasm
+
+package column
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColdChunks(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := NewCollection(Options{Clock: clock, ColdAfter: time.Minute})
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("age", ForInt64(), WithCodec("zstd")))
+	assert.NoError(t, c.CreateColumn("name", ForString()))
+	var sum int64
+	for i := 0; i < 2*chunkSize; i++ {
+		c.InsertObject(Object{"age": int64(i % 10), "name": "Roman"})
+		sum += int64(i % 10)
+	}
+
+	age, _ := c.cols.Load("age")
+	before := age.sizeOf(0)
+
+	// The chunks are only compressed once they were not modified for long enough
+	c.compress()
+	c.compress()
+	assert.Equal(t, int32(0), c.cold.count)
+	clock.Add(2 * time.Minute)
+	c.compress()
+	assert.Equal(t, int32(2), c.cold.count)
+	assert.Less(t, age.sizeOf(0), before/10)
+
+	// The values are decompressed on read
+	assert.NoError(t, c.QueryAt(chunkSize+5, func(r Row) error {
+		v, ok := r.Int64("age")
+		assert.True(t, ok)
+		assert.Equal(t, int64((chunkSize+5)%10), v)
+		return nil
+	}))
+	assert.Equal(t, int32(1), c.cold.count)
+
+	// The values are decompressed on write
+	assert.NoError(t, c.QueryAt(3, func(r Row) error {
+		r.SetInt64("age", 100)
+		return nil
+	}))
+	assert.Equal(t, int32(0), c.cold.count)
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, sum+100-3, txn.Int64("age").Sum())
+		return nil
+	}))
+
+	// The decompressed chunks are compressed again once they are cold
+	c.compress()
+	clock.Add(2 * time.Minute)
+	c.compress()
+	assert.Equal(t, int32(2), c.cold.count)
+
+	// The snapshot restores the values, and the clone can be modified on its own
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	defer clone.Close()
+	assert.Equal(t, int32(0), c.cold.count)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, c.Snapshot(buffer))
+	other := NewCollection()
+	defer other.Close()
+	assert.NoError(t, other.CreateColumn("age", ForInt64()))
+	assert.NoError(t, other.CreateColumn("name", ForString()))
+	assert.NoError(t, other.Restore(buffer))
+	assert.NoError(t, other.QueryAt(3, func(r Row) error {
+		v, _ := r.Int64("age")
+		assert.Equal(t, int64(100), v)
+		return nil
+	}))
+}
+
+func TestColdChunksDisabled(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := NewCollection(Options{Clock: clock})
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("age", ForInt64(), WithCodec("zstd")))
+	c.InsertObject(Object{"age": 1})
+
+	// Without the option, nothing is compressed
+	c.compress()
+	clock.Add(time.Hour)
+	c.compress()
+	assert.Equal(t, int32(0), c.cold.count)
+}
+
+func TestChunksFreeze(t *testing.T) {
+	var s chunks[float64]
+	s.Grow(0)
+	_, data := s.chunkFor(0)
+	data[1] = 1.5
+
+	assert.True(t, s.freeze(0, commit.S2))
+	assert.Nil(t, s[0].data)
+	assert.False(t, s.freeze(0, commit.S2))
+	assert.NoError(t, s.thaw(0, commit.S2))
+	_, data = s.chunkAt(0)
+	assert.Equal(t, 1.5, data[1])
+	assert.Len(t, data, chunkSize)
+
+	// The shared chunks are left as they are
+	s.share()
+	assert.False(t, s.freeze(0, commit.S2))
+}
@@ -6,6 +6,7 @@ package column
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/bits"
 	"reflect"
 	"sync"
@@ -14,7 +15,6 @@ import (
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
-	"github.com/kelindar/smutex"
 )
 
 // Object represents a single object
@@ -28,9 +28,10 @@ const (
 // Collection represents a collection of objects in a columnar format
 type Collection struct {
 	count   uint64             // The current count of elements
+	expiry  int64              // The time of the next vacuum, when done without a goroutine
 	txns    *txnPool           // The transaction pool
 	lock    sync.RWMutex       // The mutex to guard the fill-list
-	slock   *smutex.SMutex128  // The sharded mutex for the collection
+	slock   *latches           // The sharded latches of the chunks
 	cols    columns            // The map of columns
 	fill    bitmap.Bitmap      // The fill-list
 	opts    Options            // The options configured
@@ -39,21 +40,52 @@ type Collection struct {
 	pk      *columnKey         // The primary key column
 	cancel  context.CancelFunc // The cancellation function for the context
 	commits []uint64           // The array of commit IDs for corresponding chunk
+	feeds   atomic.Value       // The changefeeds of the replicas and subscribers
+	ctx     context.Context    // The context of the collection, cancelled on close
+	barrier *sync.RWMutex      // The commit barrier of the owning catalog (optional)
+	plans   planCache          // The cache of query plans
+	forks   sync.RWMutex       // The lock which holds back the commits while the collection is copied
+	cold    coldChunks         // The chunks whose values are compressed while they are cold
+	evict   evictors           // The callbacks invoked before evicting the expired rows
+	hooks   listeners          // The callbacks invoked once the transactions commit or roll back
+	alerts  alerts             // The thresholds watched on the numeric columns
+	verify  sync.Mutex         // The lock to validate the tracked transactions one at a time
+	metrics Metrics            // The instrumentation hooks (optional)
+	costs   atomic.Value       // The calibrated cost model of the planner (optional)
+	tracer  Tracer             // The tracer of the transactions (optional)
+	last    uint64             // The ID of the most recent commit
+	seq     uint64             // The sequence number of the last commit appended to the logger
+	seqLock sync.Mutex         // The lock to append the commits to the logger in sequence
+	frozen  uint32             // Whether the collection rejects the writes
+	tasks   sync.WaitGroup     // The background goroutines, waited for on close
+	locks   rowLocks           // The rows locked by the transactions
+	checks  checks             // The check constraints of the columns
 }
 
 // Options represents the options for a collection.
 type Options struct {
-	Capacity int           // The initial capacity when creating columns
-	Writer   commit.Logger // The writer for the commit log (optional)
-	Vacuum   time.Duration // The interval at which the vacuum of expired entries will be done
+	Capacity            int                // The initial capacity when creating columns
+	Writer              commit.Logger      // The writer for the commit log (optional)
+	Vacuum              time.Duration      // Deprecated: use CleanupInterval instead
+	CleanupInterval     time.Duration      // The interval at which the expired rows are purged (default: 1s)
+	CompactionThreshold float64            // The fraction of unused values beyond which a column is compacted (default: 0.5)
+	Clock               Clock              // The clock used for the time-to-live (default: system clock)
+	Metrics             Metrics            // The hooks which receive the instrumentation (optional)
+	Tracer              Tracer             // The tracer of the transactions, such as a slow-query log (optional)
+	Encryption          commit.KeyProvider // The keys which encrypt the snapshots with AES-GCM (optional)
+	Compression         commit.Codec       // The codec which compresses the snapshots (default: s2 stream)
+	Dynamic             bool               // Whether the unknown keys of the inserted objects create columns (default: false)
+	ColdAfter           time.Duration      // The duration after which the unmodified chunks of the columns with a codec are compressed (optional)
 }
 
 // NewCollection creates a new columnar collection.
 func NewCollection(opts ...Options) *Collection {
 	options := Options{
-		Capacity: 1024,
-		Vacuum:   1 * time.Second,
-		Writer:   nil,
+		Capacity:            1024,
+		CleanupInterval:     1 * time.Second,
+		CompactionThreshold: 0.5,
+		Clock:               systemClock{},
+		Writer:              nil,
 	}
 
 	// Merge options together
@@ -62,28 +94,59 @@ func NewCollection(opts ...Options) *Collection {
 			options.Capacity = o.Capacity
 		}
 		if o.Vacuum > 0 {
-			options.Vacuum = o.Vacuum
+			options.CleanupInterval = o.Vacuum
+		}
+		if o.CleanupInterval > 0 {
+			options.CleanupInterval = o.CleanupInterval
+		}
+		if o.CompactionThreshold > 0 {
+			options.CompactionThreshold = o.CompactionThreshold
 		}
 		if o.Writer != nil {
 			options.Writer = o.Writer
 		}
+		if o.Clock != nil {
+			options.Clock = o.Clock
+		}
+		if o.Metrics != nil {
+			options.Metrics = o.Metrics
+		}
+		if o.Tracer != nil {
+			options.Tracer = o.Tracer
+		}
+		if o.Encryption != nil {
+			options.Encryption = o.Encryption
+		}
+		if o.Compression != nil {
+			options.Compression = o.Compression
+		}
+		if o.Dynamic {
+			options.Dynamic = true
+		}
+		if o.ColdAfter > 0 {
+			options.ColdAfter = o.ColdAfter
+		}
 	}
 
 	// Create a new collection
+	options.Vacuum = options.CleanupInterval
 	ctx, cancel := context.WithCancel(context.Background())
 	store := &Collection{
-		cols:   makeColumns(8),
-		txns:   newTxnPool(),
-		opts:   options,
-		slock:  new(smutex.SMutex128),
-		fill:   make(bitmap.Bitmap, 0, options.Capacity>>6),
-		logger: options.Writer,
-		cancel: cancel,
+		cols:    makeColumns(8),
+		txns:    newTxnPool(),
+		opts:    options,
+		slock:   new(latches),
+		fill:    make(bitmap.Bitmap, 0, options.Capacity>>6),
+		logger:  options.Writer,
+		metrics: options.Metrics,
+		tracer:  options.Tracer,
+		cancel:  cancel,
+		ctx:     ctx,
 	}
 
-	// Create an expiration column and start the cleanup goroutine
+	// Create an expiration column and start the cleanup
 	store.CreateColumn(expireColumn, ForInt64())
-	go store.vacuum(ctx, options.Vacuum)
+	store.startVacuum(ctx, options.CleanupInterval)
 	return store
 }
 
@@ -97,6 +160,17 @@ func (c *Collection) next() uint32 {
 	return idx
 }
 
+// nextMany reserves a set of free indices in the collection at once, atomically.
+func (c *Collection) nextMany(dst []uint32) {
+	c.lock.Lock()
+	for i := range dst {
+		idx := c.findFreeIndex(atomic.AddUint64(&c.count, 1))
+		c.fill.Set(idx)
+		dst[i] = idx
+	}
+	c.lock.Unlock()
+}
+
 // findFreeIndex finds a free index for insertion
 func (c *Collection) findFreeIndex(count uint64) uint32 {
 	fillSize := len(c.fill)
@@ -119,7 +193,8 @@ func (c *Collection) findFreeIndex(count uint64) uint32 {
 	return idx
 }
 
-// InsertObject adds an object to a collection and returns the allocated index.
+// InsertObject adds an object to a collection and returns the allocated index. The keys of
+// the object without a corresponding column are ignored, see InsertStrict to reject them.
 func (c *Collection) InsertObject(obj Object) (index uint32) {
 	c.Query(func(txn *Txn) error {
 		index, _ = txn.InsertObject(obj)
@@ -128,6 +203,53 @@ func (c *Collection) InsertObject(obj Object) (index uint32) {
 	return
 }
 
+// InsertStrict adds an object to a collection and returns the allocated index. If any of the
+// keys of the object has no corresponding column, nothing is inserted and an error wrapping
+// ErrMissingColumns, which lists the keys, is returned.
+func (c *Collection) InsertStrict(obj Object) (index uint32, err error) {
+	err = c.Query(func(txn *Txn) (innerErr error) {
+		index, innerErr = txn.InsertStrict(obj)
+		return
+	})
+	return
+}
+
+// InsertMany adds a set of objects to a collection in a single transaction and returns
+// the allocated indices. The keys of the objects without a corresponding column are not
+// inserted, while the objects are, and an error wrapping ErrMissingColumns is returned.
+func (c *Collection) InsertMany(objects []Object) (indices []uint32, err error) {
+	var insertErr error
+	if err = c.Query(func(txn *Txn) error {
+		indices, insertErr = txn.InsertMany(objects)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return indices, insertErr
+}
+
+// InsertColumns adds a batch of rows given column by column, where each value of the batch
+// is a slice holding the values of that column for every row, and returns the allocated
+// indices.
+func (c *Collection) InsertColumns(batch map[string]any) (indices []uint32, err error) {
+	err = c.Query(func(txn *Txn) error {
+		indices, err = txn.InsertColumns(batch)
+		return err
+	})
+	return
+}
+
+// InsertArrowStream reads the record batches of a stream in the Arrow IPC streaming format
+// and inserts their rows in a single transaction, returning the allocated indices.
+func (c *Collection) InsertArrowStream(src io.Reader) (indices []uint32, err error) {
+	err = c.Query(func(txn *Txn) error {
+		indices, err = txn.InsertArrowStream(src)
+		return err
+	})
+	return
+}
+
 // InsertObjectWithTTL adds an object to a collection, sets the expiration time
 // based on the specified time-to-live and returns the allocated index.
 func (c *Collection) InsertObjectWithTTL(obj Object, ttl time.Duration) (index uint32) {
@@ -167,11 +289,163 @@ func (c *Collection) DeleteAt(idx uint32) (deleted bool) {
 	return
 }
 
+// Truncate removes all of the rows of the collection, while keeping its columns, indexes and
+// constraints. Rather than filtering and deleting the rows one by one, the fill list of every
+// chunk is deleted at once and the memory of the emptied chunks is then released. Since the
+// deletion is committed like any other transaction, the commit log, the replicas and the
+// commit listeners observe the deleted rows.
+func (c *Collection) Truncate() error {
+	err := c.Query(func(txn *Txn) error {
+		txn.deleteSelected()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.release()
+	return nil
+}
+
+// Reset removes all of the rows of the collection along with all of its columns, indexes and
+// constraints, so that the collection can be used again with a different schema.
+func (c *Collection) Reset() error {
+	if err := c.Truncate(); err != nil {
+		return err
+	}
+
+	for _, columnName := range c.Columns() {
+		if err := c.DropColumn(columnName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Count returns the total number of elements in the collection.
 func (c *Collection) Count() (count int) {
 	return int(atomic.LoadUint64(&c.count))
 }
 
+// CountWith returns the number of rows which are part of all of the specified indexes, or
+// which have a value in all of the specified columns, just like txn.With(...).Count() would.
+// The bitmaps are intersected and counted in small blocks, chunk by chunk, without creating a
+// transaction nor copying the fill list of the collection.
+func (c *Collection) CountWith(columns ...string) int {
+	return c.countWith(columns, false)
+}
+
+// Any returns whether any row is part of all of the specified indexes, or has a value in all
+// of the specified columns. It stops at the first block containing a matching row.
+func (c *Collection) Any(columns ...string) bool {
+	return c.countWith(columns, true) > 0
+}
+
+// countWith counts the rows of the fill list which are also part of the indexes of all of the
+// columns. If stopAtFirst is set, it stops as soon as a matching row is found.
+func (c *Collection) countWith(columnNames []string, stopAtFirst bool) (count int) {
+	var buffer [4]*column
+	columns := buffer[:0]
+	for _, name := range columnNames {
+		column, ok := c.cols.Load(name)
+		if !ok {
+			return 0 // No rows match a missing column
+		}
+		columns = append(columns, column)
+	}
+
+	for chunk, n := commit.Chunk(0), commit.Chunk(c.chunks()); chunk < n; chunk++ {
+		if count += c.countWithChunk(chunk, columns, stopAtFirst); stopAtFirst && count > 0 {
+			return
+		}
+	}
+	return
+}
+
+// countWithChunk counts the matching rows of a single chunk while holding its read lock. The
+// bitmaps are intersected in a small buffer on the stack, so nothing needs to be allocated.
+func (c *Collection) countWithChunk(chunk commit.Chunk, columns []*column, stopAtFirst bool) (count int) {
+	c.slock.RLock(uint(chunk))
+	defer c.slock.RUnlock(uint(chunk))
+	c.thaw(chunk)
+
+	var indexes [4]bitmap.Bitmap
+	others := indexes[:0]
+	for _, column := range columns {
+		others = append(others, column.Index(chunk))
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var buffer [256]uint64
+	fill := chunk.OfBitmap(c.fill)
+	for offset := 0; offset < len(fill); offset += len(buffer) {
+		block := bitmap.Bitmap(buffer[:copy(buffer[:], fill[offset:])])
+		for _, index := range others {
+			if offset >= len(index) {
+				return // Nothing left to intersect with in this chunk
+			}
+
+			block.And(index[offset:])
+		}
+
+		if count += block.Count(); stopAtFirst && count > 0 {
+			return
+		}
+	}
+	return
+}
+
+// LastCommit returns the ID of the most recent commit applied to the collection, or zero if
+// nothing was committed yet. The commit IDs are monotonically increasing, hence a consumer of
+// the commit log which has processed this commit can later resume from the next one.
+func (c *Collection) LastCommit() uint64 {
+	return atomic.LoadUint64(&c.last)
+}
+
+// advance moves the ID of the most recent commit forward, if the specified one is newer
+func (c *Collection) advance(commitID uint64) {
+	for {
+		last := atomic.LoadUint64(&c.last)
+		if commitID <= last || atomic.CompareAndSwapUint64(&c.last, last, commitID) {
+			return
+		}
+	}
+}
+
+// Columns returns the names of the columns in the collection, in the order of their creation.
+// The indexes and the expiration column are not included.
+func (c *Collection) Columns() []string {
+	names := make([]string, 0, c.cols.Count())
+	c.cols.Range(func(column *column) {
+		if !column.IsIndex() && column.name != expireColumn && column.opts.expiryOf == "" {
+			names = append(names, column.name)
+		}
+	})
+	return names
+}
+
+// KindOf returns the kind of the values stored in a column, or reflect.Interface if the column
+// can store values of any type. This can be used to convert the loosely typed values, such as
+// the numbers decoded from JSON, into the type expected by the column.
+func (c *Collection) KindOf(columnName string) (reflect.Kind, bool) {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return reflect.Invalid, false
+	}
+
+	return kindOf(column.Column), true
+}
+
+// PrimaryKey returns the name of the primary key column, if the collection has one.
+func (c *Collection) PrimaryKey() (string, bool) {
+	if c.pk == nil {
+		return "", false
+	}
+	return c.pk.name, true
+}
+
 // createColumnKey attempts to create a primary key column
 func (c *Collection) createColumnKey(columnName string, column *columnKey) error {
 	if c.pk != nil {
@@ -198,14 +472,100 @@ func (c *Collection) CreateColumnsOf(object Object) error {
 	return nil
 }
 
+// createColumnsFor creates the columns for the keys of the objects without one, with their
+// type inferred from the first value. The nil values are skipped, since their type is unknown,
+// while the maps, slices and structs are stored as JSON documents.
+func (c *Collection) createColumnsFor(objects ...Object) error {
+	for _, object := range objects {
+		for k, v := range object {
+			if _, ok := c.cols.Load(k); ok || v == nil {
+				continue
+			}
+
+			column, err := inferColumn(v)
+			if err != nil {
+				return fmt.Errorf("column: unable to create column '%s', %w", k, err)
+			}
+
+			// A concurrent transaction might have created the same column meanwhile
+			if err := c.CreateColumn(k, column); err != nil {
+				if _, ok := c.cols.Load(k); !ok {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inferColumn creates a column which is able to store the value
+func inferColumn(value any) (Column, error) {
+	if _, ok := value.([]byte); ok {
+		return ForBytes(), nil
+	}
+
+	switch kind := reflect.TypeOf(value).Kind(); kind {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return ForJSON(), nil
+	default:
+		return ForKind(kind)
+	}
+}
+
 // CreateColumn creates a column of a specified type and adds it to the collection.
-func (c *Collection) CreateColumn(columnName string, column Column) error {
+func (c *Collection) CreateColumn(columnName string, column Column, opts ...ColumnOption) error {
 	if _, ok := c.cols.Load(columnName); ok {
 		return fmt.Errorf("column: unable to create column '%s', already exists", columnName)
 	}
 
+	// Apply the options of the column
+	options := columnOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if _, ok := codecOf(options.Codec); options.Codec != "" && !ok {
+		return fmt.Errorf("column: unable to create column '%s', codec '%s' does not exist", columnName, options.Codec)
+	}
+
+	if v, ok := column.(validator); ok {
+		if err := v.validate(); err != nil {
+			return fmt.Errorf("column: unable to create column '%s', %w", columnName, err)
+		}
+	}
+
+	// If the values expire, create the column which holds their expiration times
+	if options.TTL > 0 {
+		if err := c.createExpiry(columnName); err != nil {
+			return err
+		}
+	}
+
+	// If the values are memory-mapped, the chunks already present in the file are loaded
+	switch {
+	case options.Mapping != "" && options.Allocator != nil:
+		return fmt.Errorf("column: unable to create column '%s', a mapped column can not use an allocator", columnName)
+	case options.Mapping != "":
+		if err := mapColumn(column, options.Mapping); err != nil {
+			return fmt.Errorf("column: unable to create column '%s', %w", columnName, err)
+		}
+	case options.Allocator != nil:
+		if err := allocateColumn(column, options.Allocator); err != nil {
+			return fmt.Errorf("column: unable to create column '%s', %w", columnName, err)
+		}
+	}
+
 	column.Grow(uint32(c.opts.Capacity))
-	c.cols.Store(columnName, columnFor(columnName, column))
+	created := columnFor(columnName, column, options)
+	c.cols.Store(columnName, created)
+	if options.Mapping != "" {
+		c.restoreRows(column)
+	}
+
+	// If the updates are coalesced, periodically commit the ones held back
+	if created.coalesce != nil {
+		c.startCoalesce(created)
+	}
 
 	// If necessary, create a primary key column
 	if pk, ok := column.(*columnKey); ok {
@@ -214,10 +574,159 @@ func (c *Collection) CreateColumn(columnName string, column Column) error {
 	return nil
 }
 
-// DropColumn removes the column (or an index) with the specified name. If the column with this
+// DropColumn removes the column (or an index) with the specified name, along with all of the
+// indexes which depend on it, so that their memory can be reclaimed. If the column with this
 // name does not exist, this operation is a no-op.
-func (c *Collection) DropColumn(columnName string) {
-	c.cols.DeleteColumn(columnName)
+func (c *Collection) DropColumn(columnName string) error {
+	if columnName == expireColumn {
+		return fmt.Errorf("column: unable to drop column '%s', it is required for expiration", columnName)
+	}
+
+	return c.lockAll(func() error {
+		columns, ok := c.cols.LoadWithIndex(columnName)
+		if !ok {
+			return nil
+		}
+
+		// If this is an index, remove it from the column it depends on
+		if index, ok := columns[0].Column.(computed); ok {
+			c.cols.DeleteIndex(index.Column(), columnName)
+		}
+
+		// Remove all of the indexes depending on the column
+		for _, index := range columns[1:] {
+			c.cols.DeleteColumn(index.name)
+		}
+
+		if columns[0].Column == c.pk {
+			c.pk = nil
+		}
+
+		if columns[0].opts.TTL > 0 {
+			c.cols.DeleteColumn(expiryOf(columnName))
+		}
+
+		c.cols.DeleteColumn(columnName)
+		c.checks.drop(columnName)
+
+		// Free the memory of the values, if it is not managed by the garbage collector
+		if m, ok := columns[0].Column.(offHeap); ok {
+			return m.free()
+		}
+		return nil
+	})
+}
+
+// RenameColumn renames an existing column, keeping its values and its indexes intact. The
+// transactions which are in-flight and still refer to the old name will not be able to
+// update the column once the rename is complete.
+func (c *Collection) RenameColumn(columnName, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("column: unable to rename column '%s', name must be specified", columnName)
+	}
+
+	return c.lockAll(func() error {
+		if _, ok := c.cols.Load(newName); ok {
+			return fmt.Errorf("column: unable to rename column '%s', '%s' already exists", columnName, newName)
+		}
+
+		columns, ok := c.cols.LoadWithIndex(columnName)
+		if !ok {
+			return fmt.Errorf("column: unable to rename column '%s', does not exist", columnName)
+		}
+
+		// Update the column and let its indexes know about the new target
+		columns[0].name = newName
+		for _, index := range columns[1:] {
+			switch idx := index.Column.(type) {
+			case *columnIndex:
+				idx.name = newName
+			case *columnThreshold:
+				idx.name = newName
+			case *columnHash:
+				idx.name = newName
+				index.name = hashIndexOf(newName)
+				c.cols.Rename(hashIndexOf(columnName), index.name)
+			}
+		}
+
+		// The primary key column is also known by its name
+		if pk, ok := columns[0].Column.(*columnKey); ok {
+			pk.name = newName
+		}
+
+		c.cols.Rename(columnName, newName)
+		c.checks.rename(columnName, newName)
+		return nil
+	})
+}
+
+// MigrateColumn replaces an existing column with a new one, backfilling it by applying the
+// transform function on every value of the existing column. The function must return a
+// value of a type supported by the new column, or nil to leave the value empty. Commits
+// are blocked during the migration, so the swap happens atomically and no update is lost.
+func (c *Collection) MigrateColumn(columnName string, column Column, fn func(v any) any) error {
+	if _, ok := column.(*columnKey); ok {
+		return fmt.Errorf("column: unable to migrate column '%s', key columns are not supported", columnName)
+	}
+
+	return c.lockAll(func() error {
+		columns, ok := c.cols.LoadWithIndex(columnName)
+		switch {
+		case !ok:
+			return fmt.Errorf("column: unable to migrate column '%s', does not exist", columnName)
+		case columns[0].IsIndex():
+			return fmt.Errorf("column: unable to migrate column '%s', it is an index", columnName)
+		case columns[0].Column == c.pk:
+			return fmt.Errorf("column: unable to migrate column '%s', it is a primary key", columnName)
+		}
+
+		// Backfill the new column, chunk by chunk, from the values of the existing one
+		source := columns[0]
+		target := columnFor(columnName, column, source.opts)
+		target.Grow(uint32(c.opts.Capacity))
+		for _, index := range columns[1:] {
+			switch idx := index.Column.(type) {
+			case *columnIndex:
+				idx.fill.Clear()
+			case *columnHash:
+				if err := idx.reset(column); err != nil {
+					return err
+				}
+			}
+		}
+
+		chunks := c.chunks()
+		buffer := commit.NewBuffer(chunkSize)
+		reader := commit.NewReader()
+		for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+			c.lock.RLock()
+			fill := chunk.OfBitmap(c.fill)
+			c.lock.RUnlock()
+
+			offset := chunk.Min()
+			target.Grow(chunk.Max())
+			buffer.Reset(columnName)
+			fill.Range(func(x uint32) {
+				if v, ok := source.Value(offset + x); ok {
+					if value := fn(v); value != nil {
+						target.PutAny(buffer, offset+x, value)
+					}
+				}
+			})
+
+			// Apply on the new column and rebuild the indexes on top of the new values
+			reader.Seek(buffer)
+			target.Apply(chunk, reader)
+			for _, index := range columns[1:] {
+				reader.Seek(buffer)
+				index.Apply(chunk, reader)
+			}
+		}
+
+		c.cols.Store(columnName, target)
+		return nil
+	})
 }
 
 // CreateIndex creates an index column with a specified name which depends on a given
@@ -257,6 +766,46 @@ func (c *Collection) CreateIndex(indexName, columnName string, fn func(r Reader)
 	return nil
 }
 
+// CreateHashIndex creates a hash index on a column, which keeps track of the rows holding
+// each distinct value of the column. This makes equality lookups using WithEqual() cheap,
+// since the matching rows are found directly instead of scanning the column. Only the
+// textual and numeric columns are supported.
+func (c *Collection) CreateHashIndex(columnName string) error {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return fmt.Errorf("column: unable to create hash index, column '%v' does not exist", columnName)
+	}
+
+	indexName := hashIndexOf(columnName)
+	if _, ok := c.cols.Load(indexName); ok {
+		return fmt.Errorf("column: unable to create hash index, column '%v' already has one", columnName)
+	}
+
+	index, err := newHashIndex(columnName, column.Column)
+	if err != nil {
+		return err
+	}
+
+	// Register the index and fill it with the existing values, while holding back the commits
+	return c.lockAll(func() error {
+		index.Grow(uint32(c.opts.Capacity))
+		c.cols.Store(indexName, index)
+		c.cols.Store(columnName, column, index)
+
+		chunks := c.chunks()
+		buffer := commit.NewBuffer(c.Count())
+		reader := commit.NewReader()
+		for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+			index.Grow(chunk.Max())
+			if column.Snapshot(chunk, buffer) {
+				reader.Seek(buffer)
+				index.Apply(chunk, reader)
+			}
+		}
+		return nil
+	})
+}
+
 // DropIndex removes the index column with the specified name. If the index with this
 // name does not exist, this operation is a no-op.
 func (c *Collection) DropIndex(indexName string) error {
@@ -273,6 +822,13 @@ func (c *Collection) DropIndex(indexName string) error {
 	columnName := column.Column.(computed).Column()
 	c.cols.DeleteIndex(columnName, indexName)
 	c.cols.DeleteColumn(indexName)
+
+	// The unique constraint of the column relies on its hash index
+	if indexName == hashIndexOf(columnName) {
+		c.checks.lock.Lock()
+		c.checks.dropUnique(columnName)
+		c.checks.lock.Unlock()
+	}
 	return nil
 }
 
@@ -292,31 +848,107 @@ func (c *Collection) QueryKey(key string, fn func(Row) error) error {
 	})
 }
 
+// Replace inserts the object at the specified key or replaces the existing row with it. Only
+// the columns whose values have changed are updated, and the missing ones are deleted.
+func (c *Collection) Replace(key string, obj Object) (index uint32, err error) {
+	err = c.Query(func(txn *Txn) (innerErr error) {
+		index, innerErr = txn.Replace(key, obj)
+		return
+	})
+	return
+}
+
 // Query creates a transaction which allows for filtering and iteration over the
 // columns in this collection. It also allows for individual rows to be modified or
 // deleted during iteration (range), but the actual operations will be queued and
-// executed after the iteration.
+// executed after the iteration. The transaction is committed if the function returns
+// nil and rolled back if it returns an error or panics, in which case the panic is
+// propagated to the caller once the transaction is released.
+//
+// The rows are read chunk by chunk, each under a shared lock, while the commits apply
+// their changes chunk by chunk, each under a latch. A query therefore never
+// observes a partially applied commit within a chunk, nor the rows deleted since it
+// started, but it may observe a commit on some chunks and not on others. Use View()
+// when a stable snapshot of the entire collection is required.
 func (c *Collection) Query(fn func(txn *Txn) error) error {
+	return c.QueryContext(context.Background(), fn)
+}
+
+// QueryContext creates a transaction bound to the context. The iterations over the result
+// set check the context periodically and stop once it is cancelled, in which case the
+// transaction is rolled back and the error of the context is returned.
+func (c *Collection) QueryContext(ctx context.Context, fn func(txn *Txn) error) (err error) {
+	c.vacuumIfDue()
 	txn := c.txns.acquire(c)
+	txn.ctx = ctx
+	defer c.txns.release(txn)
+	defer c.hooks.notify(txn) // Once the locks are released
+	if c.tracer != nil {
+		txn.trace = new(QueryTrace)
+		defer func(start time.Time) {
+			c.traceQuery(txn, start, err)
+		}(time.Now())
+	}
+	if c.metrics != nil {
+		defer func(start time.Time) {
+			c.observeQuery(txn, start, err)
+		}(time.Now())
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			txn.rollback()
+			panic(r)
+		}
+	}()
 
 	// Execute the query and keep the error for later
 	if err := fn(txn); err != nil {
+		err = txn.journaled(err)
 		txn.rollback()
-		c.txns.release(txn)
 		return err
 	}
 
+	// If the context was cancelled meanwhile, the result might be incomplete
+	if err := ctx.Err(); err != nil {
+		err = txn.journaled(err)
+		txn.rollback()
+		return err
+	}
+
+	// A collection reconstructed from its history can not be modified
+	if atomic.LoadUint32(&c.frozen) == 1 && txn.hasUpdates() {
+		txn.rollback()
+		return errReadOnly
+	}
+
 	// Now that the iteration has finished, we can range over the pending action
 	// queue and apply all of the actions that were requested by the Selector.
-	txn.commit()
-	c.txns.release(txn)
-	return nil
+	return txn.commit()
 }
 
-// Close closes the collection and clears up all of the resources.
-func (c *Collection) Close() error {
+// Close closes the collection and clears up all of the resources. It stops the background
+// cleanup and waits for it to finish, hence it must not be called from within the callbacks
+// invoked by the cleanup, such as the eviction policy. The files backing the memory-mapped
+// columns are unmapped and the memory of the allocated columns is freed, so these columns can
+// no longer be used.
+func (c *Collection) Close() (err error) {
 	c.cancel()
-	return nil
+	c.tasks.Wait()
+
+	// Free the memory-mapped columns and the ones allocated outside of the heap, once none of
+	// the commits is in progress
+	c.lockAll(func() error {
+		c.cols.Range(func(column *column) {
+			if m, ok := column.Column.(offHeap); ok {
+				if e := m.free(); e != nil && err == nil {
+					err = e
+				}
+			}
+		})
+		return nil
+	})
+	return
 }
 
 // vacuum cleans up the expired objects on a specified interval.
@@ -328,29 +960,97 @@ func (c *Collection) vacuum(ctx context.Context, interval time.Duration) {
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			now := time.Now().UnixNano()
-			c.Query(func(txn *Txn) error {
-				expire := txn.Int64(expireColumn)
-				return txn.With(expireColumn).Range(func(idx uint32) {
-					if expirateAt, ok := expire.Get(); ok && expirateAt != 0 && now >= expirateAt {
-						txn.DeleteAt(idx)
-					}
-				})
+			c.expire(c.now().UnixNano())
+			c.release()
+			c.compact()
+			c.compress()
+			c.observeMemory()
+		}
+	}
+}
+
+// expire deletes all of the objects which have expired at the specified time.
+func (c *Collection) expire(now int64) {
+	defer c.expireValues(now)
+	c.Query(func(txn *Txn) error {
+		expire := txn.Int64(expireColumn)
+		return txn.With(expireColumn).Range(func(idx uint32) {
+			if expirateAt, ok := expire.Get(); ok && expirateAt != 0 && now >= expirateAt {
+				switch c.evict.decide(Row{txn}) {
+				case Evict:
+					txn.DeleteAt(idx)
+				case Retain:
+					expire.Set(0)
+				}
+			}
+		})
+	})
+}
+
+// release releases the memory of the chunks which no longer contain any rows. Since the
+// values are stored in chunks of 16K rows, deleting a range of rows lets the collection
+// shrink, and the memory of a chunk is allocated again once a row is inserted into it.
+func (c *Collection) release() {
+	c.lock.RLock()
+	chunks := len(c.commits)
+	c.lock.RUnlock()
+
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		if !c.isEmpty(chunk) {
+			continue
+		}
+
+		// Check again while no transaction can read or write into the chunk
+		c.slock.Lock(uint(chunk))
+		c.thaw(chunk)
+		if c.isEmpty(chunk) {
+			c.cols.Range(func(column *column) {
+				column.release(chunk)
 			})
 		}
+		c.slock.Unlock(uint(chunk))
 	}
 }
 
+// compact compacts the columns whose fraction of unused values exceeds the compaction
+// threshold. The transactions are held back while a column is being compacted.
+func (c *Collection) compact() {
+	threshold := c.opts.CompactionThreshold
+	c.cols.Range(func(column *column) {
+		if r, ok := column.Column.(Compacter); ok && r.Fragmented(threshold) {
+			c.lockAll(func() error {
+				column.compact(threshold)
+				return nil
+			})
+		}
+	})
+}
+
+// isEmpty returns whether a chunk contains no rows, including the rows reserved by the
+// pending inserts.
+func (c *Collection) isEmpty(chunk commit.Chunk) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, blk := range chunk.OfBitmap(c.fill) {
+		if blk != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // --------------------------- column registry ---------------------------
 
 // columns represents a concurrent column registry.
 type columns struct {
-	cols *atomic.Value
+	cols    *atomic.Value
+	version *uint64 // The schema version, incremented on every change
 }
 
 func makeColumns(capacity int) columns {
 	data := columns{
-		cols: &atomic.Value{},
+		cols:    &atomic.Value{},
+		version: new(uint64),
 	}
 
 	data.cols.Store(make([]columnEntry, 0, capacity))
@@ -363,6 +1063,11 @@ type columnEntry struct {
 	cols []*column // The columns and its computed
 }
 
+// Version returns the current version of the schema
+func (c *columns) Version() uint64 {
+	return atomic.LoadUint64(c.version)
+}
+
 // Count returns the number of columns, excluding indexes.
 func (c *columns) Count() (count int) {
 	cols := c.cols.Load().([]columnEntry)
@@ -435,6 +1140,7 @@ func (c *columns) Store(columnName string, main *column, index ...*column) {
 			columns[i].cols = append(columns[i].cols, index...)
 		}
 		c.cols.Store(columns)
+		atomic.AddUint64(c.version, 1)
 
 		return
 	}
@@ -447,6 +1153,65 @@ func (c *columns) Store(columnName string, main *column, index ...*column) {
 		cols: value,
 	})
 	c.cols.Store(columns)
+	atomic.AddUint64(c.version, 1)
+}
+
+// CopyTo replaces the columns of the destination registry with copies of these columns,
+// along with their computed columns. The columns which are copied as nil are skipped.
+func (c *columns) CopyTo(dst columns, copyOf func(*column) (*column, error)) error {
+	copies := make(map[*column]*column, 8)
+	columns := c.cols.Load().([]columnEntry)
+	for _, v := range columns {
+		for _, src := range v.cols {
+			if _, ok := copies[src]; ok {
+				continue
+			}
+
+			copied, err := copyOf(src)
+			if err != nil {
+				return err
+			}
+			copies[src] = copied
+		}
+	}
+
+	// Rebuild the entries, referring to the copied columns
+	entries := make([]columnEntry, 0, cap(columns))
+	for _, v := range columns {
+		if copies[v.cols[0]] == nil {
+			continue
+		}
+
+		cols := make([]*column, 0, len(v.cols))
+		for _, src := range v.cols {
+			if copied := copies[src]; copied != nil {
+				cols = append(cols, copied)
+			}
+		}
+
+		entries = append(entries, columnEntry{
+			name: v.name,
+			cols: cols,
+		})
+	}
+
+	dst.cols.Store(entries)
+	atomic.AddUint64(dst.version, 1)
+	return nil
+}
+
+// Rename renames a column entry in the registry.
+func (c *columns) Rename(columnName, newName string) {
+	columns := c.cols.Load().([]columnEntry)
+	renamed := make([]columnEntry, 0, cap(columns))
+	for _, v := range columns {
+		if v.name == columnName {
+			v.name = newName
+		}
+		renamed = append(renamed, v)
+	}
+	c.cols.Store(renamed)
+	atomic.AddUint64(c.version, 1)
 }
 
 // DeleteColumn deletes a column from the registry.
@@ -459,6 +1224,7 @@ func (c *columns) DeleteColumn(columnName string) {
 		}
 	}
 	c.cols.Store(filtered)
+	atomic.AddUint64(c.version, 1)
 }
 
 // Delete deletes a column from the registry.
@@ -482,4 +1248,5 @@ func (c *columns) DeleteIndex(columnName, indexName string) {
 	}
 
 	c.cols.Store(columns)
+	atomic.AddUint64(c.version, 1)
 }
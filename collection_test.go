@@ -4,11 +4,14 @@
 package column
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -73,6 +76,18 @@ func BenchmarkCollection(b *testing.B) {
 		assert.NotEmpty(b, name)
 	})
 
+	b.Run("read-at", func(b *testing.B) {
+		name := ""
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			players.ReadAt(20, func(v Selector) {
+				name, _ = v.Enum("name")
+			})
+		}
+		assert.NotEmpty(b, name)
+	})
+
 	b.Run("scan", func(b *testing.B) {
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -90,6 +105,30 @@ func BenchmarkCollection(b *testing.B) {
 		}
 	})
 
+	b.Run("scan-float", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			players.Query(func(txn *Txn) error {
+				txn.WithFloat("balance", func(v float64) bool {
+					return v > 2500
+				}).Count()
+				return nil
+			})
+		}
+	})
+
+	b.Run("scan-float-greater", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			players.Query(func(txn *Txn) error {
+				txn.WithFloatGreater("balance", 2500).Count()
+				return nil
+			})
+		}
+	})
+
 	b.Run("count", func(b *testing.B) {
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -101,6 +140,25 @@ func BenchmarkCollection(b *testing.B) {
 		}
 	})
 
+	b.Run("count-with", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			players.CountWith("human", "mage", "old")
+		}
+	})
+
+	b.Run("count-without", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			players.Query(func(txn *Txn) error {
+				txn.Without("human", "mage", "old").Count()
+				return nil
+			})
+		}
+	})
+
 	b.Run("range", func(b *testing.B) {
 		count, name := 0, ""
 		b.ReportAllocs()
@@ -284,6 +342,17 @@ func TestCollection(t *testing.T) {
 	}
 }
 
+func TestColumnNames(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+	coll.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() > 50
+	})
+
+	assert.Equal(t, []string{"name", "age"}, coll.Columns())
+}
+
 func TestDropColumn(t *testing.T) {
 	obj := Object{
 		"wallet": 5000,
@@ -298,11 +367,124 @@ func TestDropColumn(t *testing.T) {
 	assert.Equal(t, uint32(0), col.InsertObject(obj))
 	assert.Equal(t, uint32(1), col.InsertObject(obj))
 
-	col.DropColumn("rich")
+	assert.NoError(t, col.DropColumn("rich"))
 	col.Query(func(txn *Txn) error {
 		assert.Equal(t, 0, txn.With("rich").Count())
 		return nil
 	})
+
+	// The index should no longer be maintained
+	columns, _ := col.cols.LoadWithIndex("wallet")
+	assert.Len(t, columns, 1)
+}
+
+func TestDropColumnWithIndex(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("id", ForKey())
+	col.CreateColumn("wallet", ForFloat64())
+	assert.NoError(t, col.CreateIndex("rich", "wallet", func(r Reader) bool {
+		return r.Float() > 100
+	}))
+
+	col.InsertObject(Object{"id": "a", "wallet": 5000.0})
+	assert.NoError(t, col.DropColumn("wallet"))
+	assert.NoError(t, col.DropColumn("id"))
+	assert.NoError(t, col.DropColumn("unknown"))
+	assert.Error(t, col.DropColumn(expireColumn))
+
+	_, ok := col.cols.Load("rich")
+	assert.False(t, ok)
+	assert.Nil(t, col.pk)
+	assert.Equal(t, 1, col.cols.Count())
+
+	// Updates to the dropped column are simply ignored
+	assert.NoError(t, col.QueryAt(0, func(r Row) error {
+		r.txn.bufferFor("wallet").PutFloat64(0, 10)
+		return nil
+	}))
+}
+
+func TestRenameColumn(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	assert.NoError(t, col.CreateColumn("age", ForInt()))
+	assert.NoError(t, col.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() >= 30
+	}))
+
+	col.InsertObject(Object{"name": "Roman", "age": 35})
+	col.InsertObject(Object{"name": "Alex", "age": 20})
+
+	// Rename the indexed column
+	assert.NoError(t, col.RenameColumn("age", "years"))
+	assert.Error(t, col.RenameColumn("age", "years"))
+	assert.Error(t, col.RenameColumn("name", "years"))
+	assert.Error(t, col.RenameColumn("name", ""))
+
+	// Update using the new name, index should still be maintained
+	assert.NoError(t, col.QueryAt(1, func(r Row) error {
+		r.SetInt("years", 40)
+		return nil
+	}))
+
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.With("old").Count())
+		assert.Equal(t, 2, txn.With("years").Count())
+		assert.Equal(t, 0, txn.With("age").Count())
+		return nil
+	}))
+
+	// Snapshot should use the new name
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, col.Snapshot(buffer))
+	other := NewCollection()
+	other.CreateColumn("name", ForString())
+	other.CreateColumn("years", ForInt())
+	assert.NoError(t, other.Restore(buffer))
+	assert.NoError(t, other.QueryAt(0, func(r Row) error {
+		v, ok := r.Int("years")
+		assert.True(t, ok)
+		assert.Equal(t, 35, v)
+		return nil
+	}))
+}
+
+func TestMigrateColumn(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("age", ForString()))
+	assert.NoError(t, col.CreateIndex("known", "age", func(r Reader) bool {
+		return len(r.String()) > 0
+	}))
+
+	col.InsertObject(Object{"age": "35"})
+	col.InsertObject(Object{"age": "20"})
+	col.InsertObject(Object{"age": "n/a"})
+
+	// Convert the strings into proper integers
+	assert.NoError(t, col.MigrateColumn("age", ForInt(), func(v any) any {
+		if age, err := strconv.Atoi(v.(string)); err == nil {
+			return age
+		}
+		return nil
+	}))
+
+	assert.NoError(t, col.QueryAt(0, func(r Row) error {
+		age, ok := r.Int("age")
+		assert.True(t, ok)
+		assert.Equal(t, 35, age)
+		return nil
+	}))
+
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.With("age").Count())
+		assert.Equal(t, 2, txn.With("known").Count())
+		return nil
+	}))
+
+	// Invalid migrations
+	assert.Error(t, col.MigrateColumn("xxx", ForInt(), func(v any) any { return v }))
+	assert.Error(t, col.MigrateColumn("known", ForInt(), func(v any) any { return v }))
+	assert.Error(t, col.MigrateColumn("age", ForKey(), func(v any) any { return v }))
 }
 
 func TestInsertObject(t *testing.T) {
@@ -526,6 +708,168 @@ func TestInsert(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestInsertMany(t *testing.T) {
+	data := loadFixture("players.json")
+	col := newEmpty(len(data))
+	defer col.Close()
+
+	indices, err := col.InsertMany(data)
+	assert.ErrorIs(t, err, ErrMissingColumns) // The location has no column
+	assert.Len(t, indices, len(data))
+	assert.Equal(t, len(data), col.Count())
+	assert.NoError(t, col.QueryAt(indices[10], func(r Row) error {
+		name, ok := r.Enum("name")
+		assert.True(t, ok)
+		assert.Equal(t, data[10]["name"], name)
+		return nil
+	}))
+
+	// Indexes should also be computed
+	col.Query(func(txn *Txn) error {
+		assert.Equal(t, 138, txn.With("human").Count())
+		return nil
+	})
+}
+
+func TestInsertManyGrowsOnce(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	defer col.Close()
+
+	objects := make([]Object, 50000)
+	for i := range objects {
+		objects[i] = Object{"name": "Roman"}
+	}
+
+	// The columns are grown up to the last index before the values are appended
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		indices, err := txn.InsertMany(objects)
+		assert.NoError(t, err)
+		assert.Len(t, col.commits, int(commit.ChunkAt(indices[len(indices)-1])+1))
+		return err
+	}))
+	assert.Equal(t, 50000, col.Count())
+}
+
+func TestInsertManyUnknownColumn(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	defer col.Close()
+
+	indices, err := col.InsertMany([]Object{{"name": "A", "x": 1}, {"y": 2}, {"name": "C"}})
+	assert.ErrorIs(t, err, ErrMissingColumns)
+	assert.Len(t, indices, 3)
+	assert.Equal(t, 3, col.Count())
+	assert.NoError(t, col.QueryAt(2, func(r Row) error {
+		name, ok := r.String("name")
+		assert.True(t, ok)
+		assert.Equal(t, "C", name)
+		return nil
+	}))
+}
+
+func TestInsertInvalidValue(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	defer col.Close()
+
+	// The values of a mismatching type are rejected before any row is reserved
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		_, err := txn.InsertObject(Object{"name": "Roman", "age": "35"})
+		assert.Error(t, err)
+		return nil
+	}))
+
+	indices, err := col.InsertMany([]Object{{"name": "A", "age": 1}, {"name": "B", "age": "B"}})
+	assert.Error(t, err)
+	assert.Empty(t, indices)
+	assert.Equal(t, 0, col.Count())
+
+	// The numbers of other types are converted
+	_, err = col.InsertMany([]Object{{"name": "A", "age": 1.5}, {"name": "B", "age": uint8(2)}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, col.Count())
+}
+
+func TestInsertColumns(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt32())
+	col.CreateColumn("balance", ForFloat64())
+	col.CreateColumn("active", ForBool())
+	col.CreateColumn("class", ForEnum())
+	defer col.Close()
+
+	indices, err := col.InsertColumns(map[string]any{
+		"name":    []string{"A", "B", "C"},
+		"age":     []int32{10, 20, 30},
+		"balance": []float64{1.5, 2.5, 3.5},
+		"active":  []bool{true, false, true},
+		"class":   []any{"mage", nil, "rogue"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{0, 1, 2}, indices)
+	assert.Equal(t, 3, col.Count())
+
+	assert.NoError(t, col.QueryAt(1, func(r Row) error {
+		name, _ := r.String("name")
+		age, _ := r.Int32("age")
+		balance, _ := r.Float64("balance")
+		_, hasClass := r.Enum("class")
+		assert.Equal(t, "B", name)
+		assert.Equal(t, int32(20), age)
+		assert.Equal(t, 2.5, balance)
+		assert.False(t, r.Bool("active"))
+		assert.False(t, hasClass)
+		return nil
+	}))
+
+	col.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.With("active").Count())
+		return nil
+	})
+}
+
+func TestInsertColumnsInvalid(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	defer col.Close()
+
+	_, err := col.InsertColumns(map[string]any{"xxx": []string{"A"}})
+	assert.Error(t, err)
+
+	_, err = col.InsertColumns(map[string]any{"name": []byte("A")})
+	assert.Error(t, err)
+
+	_, err = col.InsertColumns(map[string]any{
+		"name": []string{"A", "B"},
+		"age":  []int{1},
+	})
+	assert.Error(t, err)
+
+	// The values of a mismatching type are rejected before any row is reserved
+	_, err = col.InsertColumns(map[string]any{"age": []string{"A"}})
+	assert.Error(t, err)
+	_, err = col.InsertColumns(map[string]any{"name": []float64{1}})
+	assert.Error(t, err)
+	_, err = col.InsertColumns(map[string]any{
+		"name": []string{"A", "B"},
+		"age":  []any{1, "B"},
+	})
+	assert.Error(t, err)
+
+	indices, err := col.InsertColumns(map[string]any{})
+	assert.NoError(t, err)
+	assert.Empty(t, indices)
+	assert.Equal(t, 0, col.Count())
+
+	indices, err = col.InsertColumns(map[string]any{"age": []any{1, 2.5, nil}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{0, 1, 2}, indices)
+}
+
 func TestInsertWithTTL(t *testing.T) {
 	c := NewCollection()
 	c.CreateColumn("name", ForString())
@@ -563,6 +907,98 @@ func TestCreateColumnsOfDuplicate(t *testing.T) {
 	assert.Error(t, col.CreateColumnsOf(obj))
 }
 
+func TestCreateColumnsDynamic(t *testing.T) {
+	col := NewCollection(Options{Dynamic: true})
+	col.CreateColumn("name", ForString())
+
+	idx := col.InsertObject(Object{
+		"name":    "Roman",
+		"age":     35,
+		"balance": 10.5,
+		"active":  true,
+		"avatar":  []byte{1, 2, 3},
+		"tags":    []string{"mage", "elf"},
+	})
+
+	for name, kind := range map[string]reflect.Kind{
+		"age":     reflect.Int,
+		"balance": reflect.Float64,
+		"active":  reflect.Bool,
+	} {
+		actual, ok := col.KindOf(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, kind, actual, name)
+	}
+
+	assert.NoError(t, col.QueryAt(idx, func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 35, age)
+		avatar, _ := r.Bytes("avatar")
+		assert.Equal(t, []byte{1, 2, 3}, avatar)
+		tags, _ := r.Any("tags")
+		assert.JSONEq(t, `["mage","elf"]`, string(tags.(json.RawMessage)))
+		return nil
+	}))
+
+	// The strict insertion and the batches create the columns as well
+	_, err := col.InsertStrict(Object{"name": "Merlin", "class": "wizard"})
+	assert.NoError(t, err)
+	col.InsertMany([]Object{{"name": "Arthur", "guild": "knights"}})
+	assert.Equal(t, 3, col.Count())
+	for _, name := range []string{"class", "guild"} {
+		kind, ok := col.KindOf(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, reflect.String, kind, name)
+	}
+
+	// The values of an unsupported type can not create a column
+	_, err = col.InsertStrict(Object{"name": "Lancelot", "ratio": complex64(1)})
+	assert.Error(t, err)
+	assert.Equal(t, 3, col.Count())
+}
+
+func TestTruncate(t *testing.T) {
+	players := loadPlayers(50000)
+	assert.Equal(t, 50000, players.Count())
+
+	var deleted int
+	players.OnCommit(func(c Commit) {
+		deleted += c.Deleted
+	})
+
+	columns := players.Columns()
+	assert.NoError(t, players.Truncate())
+	assert.Equal(t, 0, players.Count())
+	assert.Equal(t, 50000, deleted)
+	assert.Equal(t, columns, players.Columns())
+
+	// The columns and the indexes remain usable
+	players.InsertObject(Object{"name": "Roman", "race": "human", "class": "mage", "age": 35})
+	assert.Equal(t, 1, players.Count())
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.With("human", "mage").Count())
+		assert.Equal(t, 0, txn.With("elf").Count())
+		return nil
+	}))
+
+	// Truncating an empty collection is a no-op
+	empty := NewCollection()
+	assert.NoError(t, empty.Truncate())
+	assert.Equal(t, 0, empty.Count())
+}
+
+func TestReset(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.Reset())
+	assert.Equal(t, 0, players.Count())
+	assert.Empty(t, players.Columns())
+
+	// A different schema can be created
+	assert.NoError(t, players.CreateColumn("name", ForEnum()))
+	players.InsertObject(Object{"name": "Roman"})
+	assert.Equal(t, 1, players.Count())
+}
+
 func TestFindFreeIndex(t *testing.T) {
 	col := NewCollection()
 	assert.NoError(t, col.CreateColumn("name", ForString()))
@@ -697,3 +1133,291 @@ func loadFixture(name string) []Object {
 
 	return data
 }
+
+func TestLastCommit(t *testing.T) {
+	w := make(commit.Channel, 1024)
+	c := NewCollection(Options{
+		Writer: w,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("name", ForString())
+	assert.Equal(t, uint64(0), c.LastCommit())
+
+	// Every commit moves the last commit ID forward
+	c.InsertObject(Object{"name": "Roman"})
+	first := c.LastCommit()
+	assert.NotZero(t, first)
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		txn.Annotate([]byte("request-42"))
+		_, err := txn.InsertObject(Object{"name": "Merlin"})
+		return err
+	}))
+	assert.Greater(t, c.LastCommit(), first)
+
+	// The commit log receives the IDs and the metadata
+	assert.Equal(t, first, (<-w).ID)
+	last := <-w
+	assert.Equal(t, c.LastCommit(), last.ID)
+	assert.Equal(t, []byte("request-42"), last.Meta)
+}
+
+func TestCountWith(t *testing.T) {
+	players := loadPlayers(500)
+	defer players.Close()
+
+	// Must match the count of the equivalent transaction
+	for _, columns := range [][]string{
+		{"human"},
+		{"human", "mage"},
+		{"human", "mage", "old"},
+		{"active", "rogue"},
+		{"name"},
+		{},
+	} {
+		players.Query(func(txn *Txn) error {
+			expect := txn.With(columns...).Count()
+			assert.Equal(t, expect, players.CountWith(columns...), columns)
+			assert.Equal(t, expect > 0, players.Any(columns...), columns)
+			return nil
+		})
+	}
+
+	// Deleted rows are no longer counted
+	before := players.CountWith("human")
+	players.Query(func(txn *Txn) error {
+		return txn.With("human").Range(func(idx uint32) {
+			if idx%2 == 0 {
+				txn.DeleteAt(idx)
+			}
+		})
+	})
+	assert.Less(t, players.CountWith("human"), before)
+
+	// Missing columns match nothing
+	assert.Equal(t, 0, players.CountWith("human", "invalid"))
+	assert.False(t, players.Any("invalid"))
+}
+
+func TestReplace(t *testing.T) {
+	w := make(commit.Channel, 1024)
+	c := NewCollection(Options{
+		Writer: w,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("id", ForKey())
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("age", ForInt())
+	c.CreateColumn("class", ForEnum())
+
+	// Insert a new row, since the key does not exist
+	idx, err := c.Replace("merlin", Object{"name": "Merlin", "age": 100, "class": "mage"})
+	assert.NoError(t, err)
+	<-w
+
+	// Replace the row, only the age was changed and the class is removed
+	at, err := c.Replace("merlin", Object{"name": "Merlin", "age": 101})
+	assert.NoError(t, err)
+	assert.Equal(t, idx, at)
+
+	change := <-w
+	columns := make([]string, 0, len(change.Updates))
+	for _, u := range change.Updates {
+		columns = append(columns, u.Column)
+	}
+	assert.ElementsMatch(t, []string{"age", "class"}, columns)
+
+	assert.NoError(t, c.QueryKey("merlin", func(r Row) error {
+		name, _ := r.String("name")
+		age, _ := r.Int("age")
+		_, hasClass := r.Enum("class")
+		assert.Equal(t, "Merlin", name)
+		assert.Equal(t, 101, age)
+		assert.False(t, hasClass)
+		return nil
+	}))
+
+	// Replacing with the same object does not change anything
+	_, err = c.Replace("merlin", Object{"name": "Merlin", "age": int64(101)})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(w))
+	assert.Equal(t, 1, c.Count())
+}
+
+func TestReplaceFailure(t *testing.T) {
+	c := NewCollection()
+	defer c.Close()
+	c.CreateColumn("id", ForKey())
+	c.CreateColumn("name", ForString())
+
+	_, err := c.Replace("merlin", Object{"name": "Merlin"})
+	assert.NoError(t, err)
+
+	// The key is not written when the object could not be inserted
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		_, err := txn.Replace("arthur", Object{"name": "Arthur", "unknown": 1})
+		assert.Error(t, err)
+		return nil
+	}))
+
+	_, exists := c.pk.OffsetOf("arthur")
+	assert.False(t, exists)
+	assert.NoError(t, c.QueryKey("merlin", func(r Row) error {
+		name, _ := r.String("name")
+		assert.Equal(t, "Merlin", name)
+		return nil
+	}))
+}
+
+func TestReplaceNoKey(t *testing.T) {
+	c := NewCollection()
+	_, err := c.Replace("merlin", Object{"name": "Merlin"})
+	assert.Error(t, err)
+}
+
+func TestRelease(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("class", ForEnum())
+	c.CreateColumn("age", ForFloat64())
+	c.CreateColumn("data", ForAny())
+	c.CreateColumn("series", ForSeries())
+
+	objects := make([]Object, 0, 2*chunkSize)
+	for i := 0; i < 2*chunkSize; i++ {
+		objects = append(objects, Object{
+			"name":   "Roman",
+			"class":  "mage",
+			"age":    float64(i),
+			"data":   i,
+			"series": float64(i),
+		})
+	}
+	c.InsertMany(objects)
+
+	// Delete every row of the first chunk
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+			if idx < chunkSize {
+				txn.DeleteAt(idx)
+			}
+		})
+	}))
+
+	// Only the memory of the empty chunk is released, the fill lists are kept
+	before := make(map[string]int)
+	c.cols.Range(func(column *column) {
+		before[column.name] = column.sizeOf(1)
+	})
+
+	c.release()
+	c.cols.Range(func(column *column) {
+		assert.Equal(t, chunkSize/8, column.sizeOf(0), column.name)
+		assert.Equal(t, before[column.name], column.sizeOf(1), column.name)
+	})
+	assert.Equal(t, chunkSize, c.Count())
+
+	// The chunk can be written into again
+	idx := c.InsertObject(Object{"name": "Merlin", "class": "mage", "age": 101.0, "data": "x", "series": 1.0})
+	assert.Equal(t, uint32(0), idx)
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		name, _ := r.String("name")
+		class, _ := r.Enum("class")
+		age, _ := r.Float64("age")
+		data, _ := r.Any("data")
+		series, _ := r.Float64("series")
+		assert.Equal(t, "Merlin", name)
+		assert.Equal(t, "mage", class)
+		assert.Equal(t, 101.0, age)
+		assert.Equal(t, "x", data)
+		assert.Equal(t, 1.0, series)
+		return nil
+	}))
+
+	// The remaining rows are still there
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, chunkSize+1, txn.Count())
+		assert.Equal(t, float64(chunkSize*(3*chunkSize-1)/2+101), txn.Float64("age").Sum())
+		return nil
+	}))
+}
+
+func TestCompact(t *testing.T) {
+	c := NewCollection(Options{CleanupInterval: time.Hour})
+	c.CreateColumn("class", ForEnum())
+	for i := 0; i < 100; i++ {
+		c.InsertObject(Object{"class": fmt.Sprintf("class-%d", i)})
+	}
+
+	enum := func() *columnEnum {
+		column, _ := c.cols.Load("class")
+		return column.Column.(*columnEnum)
+	}
+
+	// Delete most of the rows, leaving their strings unused
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+			if idx >= 20 {
+				txn.DeleteAt(idx)
+			}
+		})
+	}))
+
+	assert.True(t, enum().Fragmented(0.5))
+	c.compact()
+	assert.Len(t, enum().data, 20)
+	assert.False(t, enum().Fragmented(0.5))
+
+	// The remaining values are still readable and can be filtered on
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		class := txn.Enum("class")
+		return txn.Range(func(idx uint32) {
+			v, ok := class.Get()
+			assert.True(t, ok)
+			assert.Equal(t, fmt.Sprintf("class-%d", idx), v)
+		})
+	}))
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.WithValue("class", func(v interface{}) bool {
+			return v == "class-7"
+		}).Count())
+		return nil
+	}))
+
+	// The existing strings are reused, and new ones can be added
+	c.InsertObject(Object{"class": "class-7"})
+	c.InsertObject(Object{"class": "mage"})
+	assert.Len(t, enum().data, 21)
+}
+
+func TestCompactBelowThreshold(t *testing.T) {
+	c := NewCollection(Options{CleanupInterval: time.Hour, CompactionThreshold: 0.9})
+	c.CreateColumn("class", ForEnum())
+	for i := 0; i < 10; i++ {
+		c.InsertObject(Object{"class": fmt.Sprintf("class-%d", i)})
+	}
+
+	c.DeleteAt(0)
+	c.DeleteAt(1)
+	c.compact()
+	column, _ := c.cols.Load("class")
+	assert.Len(t, column.Column.(*columnEnum).data, 10)
+}
+
+func TestCleanupOptions(t *testing.T) {
+	c := NewCollection(Options{Vacuum: time.Minute})
+	assert.Equal(t, time.Minute, c.opts.CleanupInterval)
+	assert.Equal(t, 0.5, c.opts.CompactionThreshold)
+	assert.NoError(t, c.Close())
+
+	c = NewCollection(Options{Vacuum: time.Minute, CleanupInterval: time.Hour, CompactionThreshold: 0.25})
+	assert.Equal(t, time.Hour, c.opts.CleanupInterval)
+	assert.Equal(t, 0.25, c.opts.CompactionThreshold)
+
+	// Closing waits for the background goroutines to stop
+	c.CreateColumn("score", ForFloat64(), WithCoalesce(time.Millisecond))
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+}
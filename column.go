@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"unsafe"
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
@@ -36,7 +37,20 @@ func typeOf(column Column) (typ columnType) {
 
 // --------------------------- Contracts ----------------------------
 
-// Column represents a column implementation
+// Column represents a column implementation. Besides the built-in columns, any type which
+// implements this interface can be registered with CreateColumn(). The collection takes care
+// of the synchronization: the reads happen while the chunk is read-locked and the writes while
+// it is write-locked, so an implementation does not need any locking of its own.
+//
+// Grow is called before any value is written at an index, so that the column can allocate the
+// storage up to and including it. Apply applies the operations of a commit which concern a
+// single chunk, reading them with Next() until it returns false. The values are written with
+// the commit.Put operation, in the encoding of Buffer.PutAny() unless the column is an Encoder,
+// and a deleted value or a deleted row comes as a commit.Delete operation. The operations the
+// column does not support, such as the insertions of the rows, must be ignored. Index returns
+// the bitmap of the rows of a chunk which have a value, in the offsets of the chunk, and must
+// not be modified by the caller. Snapshot writes every value of a chunk into the buffer, so that
+// they can be restored by Apply().
 type Column interface {
 	Grow(idx uint32)
 	Apply(commit.Chunk, *commit.Reader)
@@ -46,7 +60,9 @@ type Column interface {
 	Snapshot(chunk commit.Chunk, dst *commit.Buffer)
 }
 
-// Numeric represents a column that stores numbers.
+// Numeric represents a column that stores numbers. The filters are applied on a chunk, and
+// must remove from the index the rows which do not have a value or do not match the predicate.
+// The numeric columns can be used with WithFloat(), WithInt() and WithUint().
 type Numeric interface {
 	Column
 	LoadFloat64(uint32) (float64, bool)
@@ -57,31 +73,87 @@ type Numeric interface {
 	FilterInt64(commit.Chunk, bitmap.Bitmap, func(v int64) bool)
 }
 
-// Textual represents a column that stores strings.
+// Textual represents a column that stores strings. The textual columns can be used with
+// WithString().
 type Textual interface {
 	Column
 	LoadString(uint32) (string, bool)
 	FilterString(commit.Chunk, bitmap.Bitmap, func(v string) bool)
 }
 
+// Encoder represents a column which encodes the values written into the commit buffer by
+// itself, for example in order to preserve their type. A nil value should be encoded as a
+// commit.Delete operation, and the values which can not be encoded rejected with a panic.
+type Encoder interface {
+	Encode(dst *commit.Buffer, idx uint32, value any)
+}
+
+// Sizer represents a column which can estimate the memory used by a chunk, in bytes, as
+// reported by Stats().
+type Sizer interface {
+	SizeOf(chunk commit.Chunk) int
+}
+
+// Releaser represents a column which is able to release the memory of an empty chunk. The
+// memory is allocated again once a value is written into the chunk.
+type Releaser interface {
+	Release(chunk commit.Chunk)
+}
+
+// Compacter represents a column which is able to reclaim the memory of the values which are
+// no longer used by any of the rows, such as the strings of an enum dictionary. Compact is
+// called while the entire collection is locked, only if the column is Fragmented().
+type Compacter interface {
+	Fragmented(threshold float64) bool
+	Compact(threshold float64)
+}
+
+// Cloner represents a column which is able to create a copy of itself, sharing the chunks
+// of values with the copy until either of them modifies a chunk. This is required in order
+// to Clone() a collection.
+type Cloner interface {
+	Clone() Column
+}
+
+// Factory represents a column which is able to create a new, empty column of the same
+// type and configuration. This is used when a collection schema needs to be copied, for
+// example by a sharded collection or a replica.
+type Factory interface {
+	MakeEmpty() Column
+}
+
+// validator represents a column whose constructor may be given invalid arguments, which are
+// reported as an error when the column is created.
+type validator interface {
+	validate() error
+}
+
 // --------------------------- Constructors ----------------------------
 
 // Various column constructor functions for a specific types.
 var (
-	ForString  = makeStrings
-	ForFloat32 = makeFloat32s
-	ForFloat64 = makeFloat64s
-	ForInt     = makeInts
-	ForInt16   = makeInt16s
-	ForInt32   = makeInt32s
-	ForInt64   = makeInt64s
-	ForUint    = makeUints
-	ForUint16  = makeUint16s
-	ForUint32  = makeUint32s
-	ForUint64  = makeUint64s
-	ForBool    = makeBools
-	ForEnum    = makeEnum
-	ForKey     = makeKey
+	ForString     = makeStrings
+	ForFloat32    = makeFloat32s
+	ForFloat64    = makeFloat64s
+	ForInt        = makeInts
+	ForInt16      = makeInt16s
+	ForInt32      = makeInt32s
+	ForInt64      = makeInt64s
+	ForUint       = makeUints
+	ForUint16     = makeUint16s
+	ForUint32     = makeUint32s
+	ForUint64     = makeUint64s
+	ForBool       = makeBools
+	ForEnum       = makeEnum
+	ForKey        = makeKey
+	ForSeries     = makeSeries
+	ForAny        = makeAny
+	ForPoint      = makePoints
+	ForUUID       = makeUUIDs
+	ForBytes      = makeBytes
+	ForJSON       = makeJSON
+	ForDecimal    = makeDecimals
+	ForCompressed = makeCompressed
 )
 
 // ForKind creates a new column instance for a specified reflect.Kind
@@ -116,28 +188,55 @@ func ForKind(kind reflect.Kind) (Column, error) {
 	}
 }
 
+// kindOf returns the kind of the values stored in a column, or reflect.Interface if the column
+// can store values of any type.
+func kindOf(column Column) reflect.Kind {
+	switch c := column.(type) {
+	case *columnBool, *columnIndex:
+		return reflect.Bool
+	case *columnSeries:
+		return reflect.Float64
+	case interface{ valueKind() reflect.Kind }:
+		return c.valueKind()
+	case Textual:
+		return reflect.String
+	default:
+		return reflect.Interface
+	}
+}
+
 // --------------------------- Column ----------------------------
 
 // column represents a column wrapper that synchronizes operations
 type column struct {
+	version uint64 // The version of the column, incremented on every commit which modifies it
 	Column
-	lock sync.RWMutex // The lock to protect the entire column
-	kind columnType   // The type of the colum
-	name string       // The name of the column
+	lock sync.RWMutex  // The lock to protect the entire column
+	kind columnType    // The type of the colum
+	name string        // The name of the column
+	opts columnOptions // The options of the column
+
+	coalesce *coalescer // The coalescer of the updates, if a coalescing window is set
 }
 
 // columnFor creates a synchronized column for a column implementation
-func columnFor(name string, v Column) *column {
-	return &column{
+func columnFor(name string, v Column, opts columnOptions) *column {
+	c := &column{
 		kind:   typeOf(v),
 		name:   name,
+		opts:   opts,
 		Column: v,
 	}
+
+	if opts.Coalesce > 0 {
+		c.coalesce = newCoalescer()
+	}
+	return c
 }
 
 // IsIndex returns whether the column is an index
 func (c *column) IsIndex() bool {
-	_, ok := c.Column.(*columnIndex)
+	_, ok := c.Column.(computed)
 	return ok
 }
 
@@ -151,6 +250,18 @@ func (c *column) IsTextual() bool {
 	return (c.kind & typeTextual) == typeTextual
 }
 
+// IsEncoder checks whether a column encodes its own values in the commit buffer.
+func (c *column) IsEncoder() bool {
+	_, ok := c.Column.(Encoder)
+	return ok
+}
+
+// isNative returns whether a slice of values is of the type of the values of a numeric
+// column, in which case the values can be appended without any conversion.
+func (c *column) isNative(values any) bool {
+	return c.IsNumeric() && reflect.TypeOf(values).Elem().Kind() == kindOf(c.Column)
+}
+
 // Grow grows the size of the column
 func (c *column) Grow(idx uint32) {
 	c.lock.Lock()
@@ -168,6 +279,37 @@ func (c *column) Apply(chunk commit.Chunk, r *commit.Reader) {
 	c.Column.Apply(chunk, r)
 }
 
+// sizeOf returns the approximate memory used by a chunk of the column, in bytes. The caller
+// must hold the shard lock of the chunk.
+func (c *column) sizeOf(chunk commit.Chunk) int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if s, ok := c.Column.(Sizer); ok {
+		return s.SizeOf(chunk)
+	}
+	return 0
+}
+
+// release releases the memory of an empty chunk of the column. The caller must hold the
+// exclusive shard lock of the chunk.
+func (c *column) release(chunk commit.Chunk) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if r, ok := c.Column.(Releaser); ok {
+		r.Release(chunk)
+	}
+}
+
+// compact reclaims the memory of the unused values of the column, if their fraction exceeds
+// the threshold. The caller must hold all of the shard locks.
+func (c *column) compact(threshold float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if r, ok := c.Column.(Compacter); ok {
+		r.Compact(threshold)
+	}
+}
+
 // Index loads the appropriate column index for a given chunk
 func (c *column) Index(chunk commit.Chunk) bitmap.Bitmap {
 	c.lock.RLock()
@@ -186,6 +328,17 @@ func (c *column) Snapshot(chunk commit.Chunk, buffer *commit.Buffer) bool {
 	return true
 }
 
+// PutAny writes a value into the update buffer of the column, using the encoding of the
+// column if it has its own.
+func (c *column) PutAny(dst *commit.Buffer, idx uint32, value any) {
+	if enc, ok := c.Column.(Encoder); ok {
+		enc.Encode(dst, idx, value)
+		return
+	}
+
+	dst.PutAny(commit.Put, idx, value)
+}
+
 // Value retrieves a value at a specified index
 func (c *column) Value(idx uint32) (v interface{}, ok bool) {
 	v, ok = c.Column.Value(idx)
@@ -221,18 +374,22 @@ func anyReaderFor(txn *Txn, columnName string) anyReader {
 // anyWriter represents read-write accessor for any column type
 type anyWriter struct {
 	anyReader
+	column *column
 	writer *commit.Buffer
 }
 
 // Set sets the value at the current transaction cursor
 func (s anyWriter) Set(value any) {
-	s.writer.PutAny(commit.Put, *s.cursor, value)
+	s.column.PutAny(s.writer, *s.cursor, value)
 }
 
 // Any returns a column accessor
 func (txn *Txn) Any(columnName string) anyWriter {
+	reader := anyReaderFor(txn, columnName)
+	column, _ := txn.columnAt(columnName)
 	return anyWriter{
-		anyReader: anyReaderFor(txn, columnName),
+		anyReader: reader,
+		column:    column,
 		writer:    txn.bufferFor(columnName),
 	}
 }
@@ -241,8 +398,10 @@ func (txn *Txn) Any(columnName string) anyWriter {
 
 // Chunks represents a chunked array storage
 type chunks[T any] []struct {
-	fill bitmap.Bitmap // The fill-list
-	data []T           // The actual values
+	fill   bitmap.Bitmap // The fill-list
+	data   []T           // The actual values
+	packed []byte        // The values compressed with a codec, while the chunk is cold
+	shared bool          // Whether the chunk is shared with a clone
 }
 
 // chunkAt loads the fill and data list at a particular chunk
@@ -252,20 +411,113 @@ func (s chunks[T]) chunkAt(chunk commit.Chunk) (bitmap.Bitmap, []T) {
 	return fill, data
 }
 
+// chunkFor loads the fill and data list at a particular chunk for writing, allocating
+// the data list if it was previously released, or copying the chunk if it is shared.
+func (s chunks[T]) chunkFor(chunk commit.Chunk) (bitmap.Bitmap, []T) {
+	switch {
+	case s[chunk].data == nil:
+		s[chunk].data = make([]T, chunkSize)
+	case s[chunk].shared:
+		s[chunk].data = append(make([]T, 0, chunkSize), s[chunk].data...)
+	}
+
+	if s[chunk].shared {
+		s[chunk].fill = s[chunk].fill.Clone(nil)
+		s[chunk].shared = false
+	}
+	return s.chunkAt(chunk)
+}
+
+// share marks every chunk as shared and returns a copy of the chunk list, referring to
+// the same chunks. Either of the lists copies a chunk before modifying it.
+func (s chunks[T]) share() chunks[T] {
+	for i := range s {
+		s[i].shared = true
+	}
+
+	out := make(chunks[T], len(s), cap(s))
+	copy(out, s)
+	return out
+}
+
+// Release releases the data list of an empty chunk
+func (s chunks[T]) Release(chunk commit.Chunk) {
+	if int(chunk) < len(s) {
+		s[chunk].data = nil
+		s[chunk].packed = nil
+	}
+}
+
+// freeze compresses the data list of a chunk with the codec and releases it. The chunks which
+// are shared with a clone are left as they are.
+func (s chunks[T]) freeze(chunk commit.Chunk, codec Codec) bool {
+	if int(chunk) >= len(s) || s[chunk].data == nil || s[chunk].shared {
+		return false
+	}
+
+	raw := sliceBytes(s[chunk].data)
+	if raw == nil {
+		return false
+	}
+
+	// The codecs may allocate the output upfront, hence copy it to release the excess memory
+	packed := codec.Encode(nil, raw)
+	s[chunk].packed = append(make([]byte, 0, len(packed)), packed...)
+	s[chunk].data = nil
+	return true
+}
+
+// thaw decompresses the data list of a chunk, if it was compressed with the codec
+func (s chunks[T]) thaw(chunk commit.Chunk, codec Codec) error {
+	if int(chunk) >= len(s) || s[chunk].packed == nil {
+		return nil
+	}
+
+	raw, err := codec.Decode(nil, s[chunk].packed)
+	if err != nil {
+		return err
+	}
+
+	data := make([]T, chunkSize)
+	if copy(sliceBytes(data), raw) != len(raw) {
+		return fmt.Errorf("column: decompressed chunk is larger than expected")
+	}
+
+	s[chunk].data, s[chunk].packed = data, nil
+	return nil
+}
+
+// SizeOf returns the approximate memory used by a chunk, in bytes
+func (s chunks[T]) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(s) {
+		return 0
+	}
+
+	var zero T
+	return len(s[chunk].fill)*8 + cap(s[chunk].data)*int(unsafe.Sizeof(zero)) + cap(s[chunk].packed)
+}
+
 // Grow grows a segment list
 func (s *chunks[T]) Grow(idx uint32) {
 	chunk := int(commit.ChunkAt(idx))
 	for i := len(*s); i <= chunk; i++ {
-		*s = append(*s, struct {
-			fill bitmap.Bitmap
-			data []T
-		}{
-			fill: make(bitmap.Bitmap, chunkSize/64),
-			data: make([]T, chunkSize),
-		})
+		s.push(make(bitmap.Bitmap, chunkSize/64), make([]T, chunkSize))
 	}
 }
 
+// push appends a chunk with the specified fill and data lists
+func (s *chunks[T]) push(fill bitmap.Bitmap, data []T) {
+	*s = append(*s, struct {
+		fill   bitmap.Bitmap
+		data   []T
+		packed []byte
+		shared bool
+	}{
+		fill: fill,
+		data: data,
+	})
+}
+
 // Index returns the fill list for the segment
 func (s chunks[T]) Index(chunk commit.Chunk) (fill bitmap.Bitmap) {
 	if int(chunk) < len(s) {
@@ -0,0 +1,364 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// kindCount is the number of different kinds which can be counted by the column
+const kindCount = int(reflect.UnsafePointer) + 1
+
+// --------------------------- Any ----------------------------
+
+// columnAny represents a column which accepts values of heterogeneous types. Along with each
+// value, its kind is recorded so that the values can be filtered by their type and the number
+// of values of each type can be reported. Booleans, numbers, strings and byte slices are kept
+// as they are, while the composite values such as maps, slices and structs are stored encoded
+// as JSON, and read back in their generic form (map[string]any, []any, float64, ...).
+type columnAny struct {
+	chunks []anyChunk
+}
+
+// anyChunk represents a single chunk of the column
+type anyChunk struct {
+	fill   bitmap.Bitmap  // The fill-list
+	data   []any          // The actual values
+	kinds  []reflect.Kind // The kinds of the values
+	count  [kindCount]int // The number of values of each kind
+	shared bool           // Whether the chunk is shared with a clone
+}
+
+// makeAny creates a new column which accepts values of any supported type
+func makeAny() Column {
+	return &columnAny{
+		chunks: make([]anyChunk, 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnAny) MakeEmpty() Column {
+	return makeAny()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnAny) Clone() Column {
+	for i := range c.chunks {
+		c.chunks[i].shared = true
+	}
+
+	chunks := make([]anyChunk, len(c.chunks), cap(c.chunks))
+	copy(chunks, c.chunks)
+	return &columnAny{
+		chunks: chunks,
+	}
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnAny) Grow(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		c.chunks = append(c.chunks, anyChunk{
+			fill:  make(bitmap.Bitmap, chunkSize/64),
+			data:  make([]any, chunkSize),
+			kinds: make([]reflect.Kind, chunkSize),
+		})
+	}
+}
+
+// SizeOf returns the approximate memory used by a chunk, excluding the boxed values
+func (c *columnAny) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(c.chunks) {
+		return 0
+	}
+
+	s := &c.chunks[chunk]
+	return len(s.fill)*8 + cap(s.data)*16 + cap(s.kinds)
+}
+
+// Release releases the values and the kinds of an empty chunk
+func (c *columnAny) Release(chunk commit.Chunk) {
+	if int(chunk) < len(c.chunks) {
+		c.chunks[chunk].data = nil
+		c.chunks[chunk].kinds = nil
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnAny) Apply(chunk commit.Chunk, r *commit.Reader) {
+	s := &c.chunks[chunk]
+	switch {
+	case s.data == nil:
+		s.data = make([]any, chunkSize)
+		s.kinds = make([]reflect.Kind, chunkSize)
+	case s.shared:
+		s.data = append(make([]any, 0, chunkSize), s.data...)
+		s.kinds = append(make([]reflect.Kind, 0, chunkSize), s.kinds...)
+	}
+
+	if s.shared {
+		s.fill = s.fill.Clone(nil)
+		s.shared = false
+	}
+
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			kind, value := decodeAny(r.Bytes())
+			if s.fill.Contains(offset) {
+				s.count[s.kinds[offset]]--
+			}
+
+			s.fill.Set(offset)
+			s.data[offset] = value
+			s.kinds[offset] = kind
+			s.count[kind]++
+		case commit.Delete:
+			if s.fill.Contains(offset) {
+				s.count[s.kinds[offset]]--
+				s.fill.Remove(offset)
+				s.data[offset] = nil
+				s.kinds[offset] = reflect.Invalid
+			}
+		}
+	}
+}
+
+// Value retrieves a value at a specified index
+func (c *columnAny) Value(idx uint32) (any, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		return c.chunks[chunk].data[index], true
+	}
+	return nil, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnAny) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Index returns the fill list for the column
+func (c *columnAny) Index(chunk commit.Chunk) (fill bitmap.Bitmap) {
+	if int(chunk) < len(c.chunks) {
+		fill = c.chunks[chunk].fill
+	}
+	return
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnAny) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	s := &c.chunks[chunk]
+	s.fill.Range(func(x uint32) {
+		c.Encode(dst, chunk.Min()+x, s.data[x])
+	})
+}
+
+// Encode writes a value into the buffer, along with its kind
+func (c *columnAny) Encode(dst *commit.Buffer, idx uint32, value any) {
+	if value == nil {
+		dst.PutOperation(commit.Delete, idx)
+		return
+	}
+
+	dst.PutBytes(commit.Put, idx, encodeAny(value))
+}
+
+// FilterKind filters down the values to the ones of the specified kind
+func (c *columnAny) FilterKind(chunk commit.Chunk, index bitmap.Bitmap, kind reflect.Kind) {
+	if int(chunk) >= len(c.chunks) {
+		index.Clear()
+		return
+	}
+
+	s := &c.chunks[chunk]
+	index.And(s.fill)
+	index.Filter(func(idx uint32) bool {
+		return s.kinds[idx] == kind
+	})
+}
+
+// --------------------------- Encoding ----------------------------
+
+// encodeAny encodes a value of a supported type, prefixed by its kind
+func encodeAny(value any) []byte {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return []byte{byte(reflect.Bool), 1}
+		}
+		return []byte{byte(reflect.Bool), 0}
+	case int:
+		return encodeUint(reflect.Int, uint64(v))
+	case int8:
+		return encodeUint(reflect.Int8, uint64(v))
+	case int16:
+		return encodeUint(reflect.Int16, uint64(v))
+	case int32:
+		return encodeUint(reflect.Int32, uint64(v))
+	case int64:
+		return encodeUint(reflect.Int64, uint64(v))
+	case uint:
+		return encodeUint(reflect.Uint, uint64(v))
+	case uint8:
+		return encodeUint(reflect.Uint8, uint64(v))
+	case uint16:
+		return encodeUint(reflect.Uint16, uint64(v))
+	case uint32:
+		return encodeUint(reflect.Uint32, uint64(v))
+	case uint64:
+		return encodeUint(reflect.Uint64, v)
+	case float32:
+		return encodeUint(reflect.Float32, uint64(math.Float32bits(v)))
+	case float64:
+		return encodeUint(reflect.Float64, math.Float64bits(v))
+	case string:
+		return append([]byte{byte(reflect.String)}, v...)
+	case []byte:
+		return append([]byte{byte(reflect.Slice)}, v...)
+	default:
+		return encodeJSON(value)
+	}
+}
+
+// kindJSON flags the kind of a composite value which is encoded as JSON
+const kindJSON = 0x80
+
+// encodeJSON encodes a composite value as JSON, prefixed by its flagged kind
+func encodeJSON(value any) []byte {
+	out, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Errorf("column: unsupported type (%T), %w", value, err))
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	return append([]byte{byte(kind) | kindJSON}, out...)
+}
+
+// encodeUint encodes a fixed-size number, prefixed by its kind
+func encodeUint(kind reflect.Kind, v uint64) []byte {
+	out := make([]byte, 9)
+	out[0] = byte(kind)
+	binary.BigEndian.PutUint64(out[1:], v)
+	return out
+}
+
+// decodeAny decodes a value previously encoded with encodeAny
+func decodeAny(b []byte) (reflect.Kind, any) {
+	if b[0]&kindJSON != 0 {
+		var v any
+		json.Unmarshal(b[1:], &v)
+		return reflect.Kind(b[0] &^ kindJSON), v
+	}
+
+	kind, b := reflect.Kind(b[0]), b[1:]
+	switch kind {
+	case reflect.Bool:
+		return kind, b[0] == 1
+	case reflect.String:
+		return kind, string(b)
+	case reflect.Slice:
+		return kind, append([]byte(nil), b...)
+	}
+
+	v := binary.BigEndian.Uint64(b)
+	switch kind {
+	case reflect.Int:
+		return kind, int(v)
+	case reflect.Int8:
+		return kind, int8(v)
+	case reflect.Int16:
+		return kind, int16(v)
+	case reflect.Int32:
+		return kind, int32(v)
+	case reflect.Int64:
+		return kind, int64(v)
+	case reflect.Uint:
+		return kind, uint(v)
+	case reflect.Uint8:
+		return kind, uint8(v)
+	case reflect.Uint16:
+		return kind, uint16(v)
+	case reflect.Uint32:
+		return kind, uint32(v)
+	case reflect.Float32:
+		return kind, math.Float32frombits(uint32(v))
+	case reflect.Float64:
+		return kind, math.Float64frombits(v)
+	default: // reflect.Uint64
+		return reflect.Uint64, v
+	}
+}
+
+// --------------------------- Type Stats ----------------------------
+
+// WithType filters down the values of a column created with ForAny, keeping only the ones
+// of the specified kind. Byte slices are of the reflect.Slice kind.
+func (txn *Txn) WithType(column string, kind reflect.Kind) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(column)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	reader, ok := c.Column.(*columnAny)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, "WithType", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		reader.FilterKind(chunk, index, kind)
+	})
+	return txn
+}
+
+// TypesOf returns the number of values of each kind stored in a column created with ForAny.
+func (c *Collection) TypesOf(columnName string) (map[reflect.Kind]int, error) {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return nil, fmt.Errorf("column: column '%s' does not exist", columnName)
+	}
+
+	reader, ok := column.Column.(*columnAny)
+	if !ok {
+		return nil, fmt.Errorf("column: column '%s' is not of type any", columnName)
+	}
+
+	// Sum up the counts of every chunk, each one under its own lock
+	var count [kindCount]int
+	for chunk := 0; ; chunk++ {
+		c.slock.RLock(uint(chunk))
+		column.lock.RLock()
+		if chunk >= len(reader.chunks) {
+			column.lock.RUnlock()
+			c.slock.RUnlock(uint(chunk))
+			break
+		}
+
+		for kind, n := range reader.chunks[chunk].count {
+			count[kind] += n
+		}
+		column.lock.RUnlock()
+		c.slock.RUnlock(uint(chunk))
+	}
+
+	types := make(map[reflect.Kind]int, 4)
+	for kind, n := range count {
+		if n > 0 {
+			types[reflect.Kind(kind)] = n
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,175 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnyEncoding(t *testing.T) {
+	for _, v := range []any{
+		true, false, "hello", []byte("world"),
+		int(-1), int8(-2), int16(-3), int32(-4), int64(-5),
+		uint(1), uint8(2), uint16(3), uint32(4), uint64(5),
+		float32(1.5), float64(2.5),
+	} {
+		kind, out := decodeAny(encodeAny(v))
+		assert.Equal(t, reflect.TypeOf(v).Kind(), kind)
+		assert.Equal(t, v, out)
+	}
+
+	// The composite values are read back in their generic JSON form
+	for _, tc := range []struct {
+		in   any
+		kind reflect.Kind
+		out  any
+	}{
+		{in: map[string]int{"a": 1}, kind: reflect.Map, out: map[string]any{"a": 1.0}},
+		{in: []string{"a", "b"}, kind: reflect.Slice, out: []any{"a", "b"}},
+		{in: [2]int{1, 2}, kind: reflect.Array, out: []any{1.0, 2.0}},
+		{in: struct{ Name string }{"Roman"}, kind: reflect.Struct, out: map[string]any{"Name": "Roman"}},
+	} {
+		kind, out := decodeAny(encodeAny(tc.in))
+		assert.Equal(t, tc.kind, kind)
+		assert.Equal(t, tc.out, out)
+	}
+
+	assert.Panics(t, func() {
+		encodeAny(make(chan int))
+	})
+}
+
+func TestAnyColumn(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("value", ForAny())
+	coll.InsertObject(Object{"value": "hello"})
+	coll.InsertObject(Object{"value": 42})
+	coll.InsertObject(Object{"value": 3.14})
+	coll.InsertObject(Object{"value": "world"})
+	coll.InsertObject(Object{"value": true})
+	coll.InsertObject(Object{"other": 1})
+
+	types, err := coll.TypesOf("value")
+	assert.NoError(t, err)
+	assert.Equal(t, map[reflect.Kind]int{
+		reflect.String:  2,
+		reflect.Int:     1,
+		reflect.Float64: 1,
+		reflect.Bool:    1,
+	}, types)
+
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.WithType("value", reflect.String).Count())
+		assert.Equal(t, 0, txn.WithType("value", reflect.Int).Count())
+		return nil
+	})
+
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithType("invalid", reflect.Int).Count())
+		return nil
+	})
+
+	// Overwrite the values, the stats should follow
+	coll.Query(func(txn *Txn) error {
+		value := txn.Any("value")
+		return txn.WithType("value", reflect.String).Range(func(idx uint32) {
+			value.Set(int64(1))
+		})
+	})
+
+	types, err = coll.TypesOf("value")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, types[reflect.Int64])
+	assert.Equal(t, 0, types[reflect.String])
+
+	// Delete a row, the stats should follow
+	coll.DeleteAt(1)
+	types, err = coll.TypesOf("value")
+	assert.NoError(t, err)
+	assert.Equal(t, map[reflect.Kind]int{
+		reflect.Int64:   2,
+		reflect.Float64: 1,
+		reflect.Bool:    1,
+	}, types)
+
+	coll.QueryAt(0, func(r Row) error {
+		v, ok := r.Any("value")
+		assert.True(t, ok)
+		assert.Equal(t, int64(1), v)
+		return nil
+	})
+}
+
+func TestAnyColumnComposite(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("value", ForAny())
+	idx := coll.InsertObject(Object{"value": map[string]any{"class": "mage", "level": 10}})
+	coll.InsertObject(Object{"value": []int{1, 2, 3}})
+
+	types, err := coll.TypesOf("value")
+	assert.NoError(t, err)
+	assert.Equal(t, map[reflect.Kind]int{
+		reflect.Map:   1,
+		reflect.Slice: 1,
+	}, types)
+
+	assert.NoError(t, coll.QueryAt(idx, func(r Row) error {
+		v, ok := r.Any("value")
+		assert.True(t, ok)
+		assert.Equal(t, map[string]any{"class": "mage", "level": 10.0}, v)
+		return nil
+	}))
+}
+
+func TestAnyColumnInvalid(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+
+	_, err := coll.TypesOf("name")
+	assert.Error(t, err)
+
+	_, err = coll.TypesOf("invalid")
+	assert.Error(t, err)
+
+	coll.InsertObject(Object{"name": "Roman"})
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithType("name", reflect.String).Count())
+		return nil
+	})
+}
+
+func TestAnyColumnSnapshot(t *testing.T) {
+	input := NewCollection()
+	input.CreateColumn("value", ForAny())
+	input.InsertColumns(map[string]any{
+		"value": []any{"hello", 42, nil, []byte("raw")},
+	})
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(buffer))
+
+	output := NewCollection()
+	output.CreateColumn("value", ForAny())
+	assert.NoError(t, output.Restore(buffer))
+
+	types, err := output.TypesOf("value")
+	assert.NoError(t, err)
+	assert.Equal(t, map[reflect.Kind]int{
+		reflect.String: 1,
+		reflect.Int:    1,
+		reflect.Slice:  1,
+	}, types)
+
+	output.Query(func(txn *Txn) error {
+		value := txn.Any("value")
+		return txn.WithType("value", reflect.Slice).Range(func(idx uint32) {
+			v, _ := value.Get()
+			assert.Equal(t, []byte("raw"), v)
+		})
+	})
+}
@@ -22,11 +22,28 @@ func makeBools() Column {
 	}
 }
 
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnBool) MakeEmpty() Column {
+	return makeBools()
+}
+
+// Clone creates a copy of the column
+func (c *columnBool) Clone() Column {
+	return &columnBool{
+		data: c.data.Clone(nil),
+	}
+}
+
 // Grow grows the size of the column until we have enough to store
 func (c *columnBool) Grow(idx uint32) {
 	c.data.Grow(idx)
 }
 
+// SizeOf returns the memory used by a chunk, in bytes
+func (c *columnBool) SizeOf(chunk commit.Chunk) int {
+	return len(chunk.OfBitmap(c.data)) * 8
+}
+
 // Apply applies a set of operations to the column.
 func (c *columnBool) Apply(chunk commit.Chunk, r *commit.Reader) {
 	for r.Next() {
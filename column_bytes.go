@@ -0,0 +1,297 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- Bytes ----------------------------
+
+// columnBytes represents a column of variable-length byte slices. The values of each chunk
+// are appended into an arena, so they do not need to be allocated one by one, and the arena
+// of a chunk is compacted once most of its bytes are no longer used.
+type columnBytes struct {
+	garbage uint64 // The number of bytes in the arenas which are no longer used
+	size    uint64 // The number of bytes in the arenas
+	chunks  []bytesChunk
+}
+
+// bytesChunk represents a single chunk of the byte slice column
+type bytesChunk struct {
+	fill    bitmap.Bitmap // The fill-list
+	offset  []uint32      // The offset of each value in the arena
+	length  []uint32      // The length of each value
+	arena   []byte        // The bytes of the values, in the order they were written
+	garbage int           // The number of bytes in the arena which are no longer used
+	shared  bool          // Whether the chunk is shared with a clone
+}
+
+// makeBytes creates a new column of byte slices
+func makeBytes() Column {
+	return &columnBytes{
+		chunks: make([]bytesChunk, 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnBytes) MakeEmpty() Column {
+	return makeBytes()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnBytes) Clone() Column {
+	for i := range c.chunks {
+		c.chunks[i].shared = true
+	}
+
+	chunks := make([]bytesChunk, len(c.chunks), cap(c.chunks))
+	copy(chunks, c.chunks)
+	return &columnBytes{
+		garbage: atomic.LoadUint64(&c.garbage),
+		size:    atomic.LoadUint64(&c.size),
+		chunks:  chunks,
+	}
+}
+
+// SizeOf returns the approximate memory used by a chunk, including its arena
+func (c *columnBytes) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(c.chunks) {
+		return 0
+	}
+
+	s := &c.chunks[chunk]
+	return len(s.fill)*8 + cap(s.offset)*4 + cap(s.length)*4 + cap(s.arena)
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnBytes) Grow(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		c.chunks = append(c.chunks, bytesChunk{
+			fill: make(bitmap.Bitmap, chunkSize/64),
+		})
+	}
+}
+
+// Release releases the offsets and the arena of an empty chunk
+func (c *columnBytes) Release(chunk commit.Chunk) {
+	if int(chunk) < len(c.chunks) {
+		s := &c.chunks[chunk]
+		atomic.AddUint64(&c.garbage, ^uint64(s.garbage-1))
+		atomic.AddUint64(&c.size, ^uint64(len(s.arena)-1))
+		s.offset, s.length, s.arena, s.garbage = nil, nil, nil, 0
+	}
+}
+
+// chunkFor loads a chunk for writing, allocating its offsets if they were previously released
+// or copying the chunk if it is shared. The arena of a shared chunk is not copied, but its
+// capacity is limited so that appending to it never overwrites the bytes of the clone.
+func (c *columnBytes) chunkFor(chunk commit.Chunk) *bytesChunk {
+	s := &c.chunks[chunk]
+	switch {
+	case s.offset == nil:
+		s.offset = make([]uint32, chunkSize)
+		s.length = make([]uint32, chunkSize)
+	case s.shared:
+		s.offset = append(make([]uint32, 0, chunkSize), s.offset...)
+		s.length = append(make([]uint32, 0, chunkSize), s.length...)
+		s.arena = s.arena[:len(s.arena):len(s.arena)]
+	}
+
+	if s.shared {
+		s.fill = s.fill.Clone(nil)
+		s.shared = false
+	}
+	return s
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnBytes) Apply(chunk commit.Chunk, r *commit.Reader) {
+	s := c.chunkFor(chunk)
+	garbage, size := s.garbage, len(s.arena)
+	for r.Next() {
+		s.apply(r)
+	}
+
+	c.track(s, garbage, size)
+}
+
+// track accounts for the bytes appended to the arena of a chunk and the bytes which are no
+// longer used, given their number before the operations were applied.
+func (c *columnBytes) track(s *bytesChunk, garbage, size int) {
+	atomic.AddUint64(&c.garbage, uint64(s.garbage-garbage))
+	atomic.AddUint64(&c.size, uint64(len(s.arena)-size))
+}
+
+// apply applies the current operation of the reader to the chunk
+func (s *bytesChunk) apply(r *commit.Reader) {
+	offset := r.IndexAtChunk()
+	switch r.Type {
+	case commit.Put:
+		if s.fill.Contains(offset) {
+			s.garbage += int(s.length[offset])
+		}
+
+		value := r.Bytes()
+		s.fill.Set(offset)
+		s.offset[offset] = uint32(len(s.arena))
+		s.length[offset] = uint32(len(value))
+		s.arena = append(s.arena, value...)
+	case commit.Delete:
+		if s.fill.Contains(offset) {
+			s.garbage += int(s.length[offset])
+			s.fill.Remove(offset)
+		}
+	}
+}
+
+// stringAt returns the value at an offset of the chunk as a string, without copying it. This
+// is safe since the bytes of the arena are never modified once they have been written.
+func (s *bytesChunk) stringAt(x uint32) string {
+	return toString(s.arena[s.offset[x] : s.offset[x]+s.length[x]])
+}
+
+// Value retrieves a value at a specified index
+func (c *columnBytes) Value(idx uint32) (interface{}, bool) {
+	if v, ok := c.LoadBytes(idx); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// LoadBytes retrieves a byte slice at a specified index. The returned slice refers to the
+// arena of the chunk and must not be modified.
+func (c *columnBytes) LoadBytes(idx uint32) ([]byte, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) >= len(c.chunks) || !c.chunks[chunk].fill.Contains(index) {
+		return nil, false
+	}
+
+	s := &c.chunks[chunk]
+	from, until := s.offset[index], s.offset[index]+s.length[index]
+	return s.arena[from:until:until], true
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnBytes) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Index returns the fill list for the column
+func (c *columnBytes) Index(chunk commit.Chunk) bitmap.Bitmap {
+	if int(chunk) < len(c.chunks) {
+		return c.chunks[chunk].fill
+	}
+	return nil
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnBytes) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	c.chunks[chunk].fill.Range(func(x uint32) {
+		v, _ := c.LoadBytes(chunk.Min() + x)
+		dst.PutBytes(commit.Put, chunk.Min()+x, v)
+	})
+}
+
+// Encode writes a byte slice into the commit buffer. The strings are written as their bytes,
+// a nil value deletes the value and the values of other types are rejected with a panic.
+func (c *columnBytes) Encode(dst *commit.Buffer, idx uint32, value any) {
+	switch v := value.(type) {
+	case nil:
+		dst.PutOperation(commit.Delete, idx)
+	case []byte:
+		dst.PutBytes(commit.Put, idx, v)
+	case string:
+		dst.PutString(commit.Put, idx, v)
+	default:
+		panic(fmt.Errorf("column: unable to write %T into a bytes column", value))
+	}
+}
+
+// Fragmented returns whether the fraction of the unused bytes in the arenas exceeds the
+// threshold.
+func (c *columnBytes) Fragmented(threshold float64) bool {
+	garbage := atomic.LoadUint64(&c.garbage)
+	return garbage > 0 && float64(garbage) >= threshold*float64(atomic.LoadUint64(&c.size))
+}
+
+// Compact rebuilds the arenas of the chunks in which the fraction of the unused bytes exceeds
+// the threshold, keeping only the used ones. The caller must hold all of the shard locks.
+func (c *columnBytes) Compact(threshold float64) {
+	for chunk := range c.chunks {
+		s := &c.chunks[chunk]
+		if s.garbage == 0 || float64(s.garbage) < threshold*float64(len(s.arena)) {
+			continue
+		}
+
+		s = c.chunkFor(commit.Chunk(chunk))
+		arena := make([]byte, 0, len(s.arena)-s.garbage)
+		s.fill.Range(func(x uint32) {
+			from, until := s.offset[x], s.offset[x]+s.length[x]
+			s.offset[x] = uint32(len(arena))
+			arena = append(arena, s.arena[from:until]...)
+		})
+
+		atomic.AddUint64(&c.garbage, ^uint64(s.garbage-1))
+		atomic.AddUint64(&c.size, ^uint64(len(s.arena)-len(arena)-1))
+		s.arena, s.garbage = arena, 0
+	}
+}
+
+// --------------------------- Accessors ----------------------------
+
+// bytesReader represents a read-only accessor for byte slices
+type bytesReader struct {
+	cursor *uint32
+	reader *columnBytes
+}
+
+// Get loads the value at the current transaction cursor. The returned slice must not be
+// modified.
+func (s bytesReader) Get() ([]byte, bool) {
+	return s.reader.LoadBytes(*s.cursor)
+}
+
+// bytesReaderFor creates a new byte slice reader
+func bytesReaderFor(txn *Txn, columnName string) bytesReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnBytes)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type bytes", columnName))
+	}
+
+	return bytesReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// bytesWriter represents read-write accessor for byte slices
+type bytesWriter struct {
+	bytesReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s bytesWriter) Set(value []byte) {
+	s.writer.PutBytes(commit.Put, *s.cursor, value)
+}
+
+// Bytes returns a byte slice column accessor
+func (txn *Txn) Bytes(columnName string) bytesWriter {
+	return bytesWriter{
+		bytesReader: bytesReaderFor(txn, columnName),
+		writer:      txn.bufferFor(columnName),
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesColumn(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("payload", ForBytes())
+	c.InsertObject(Object{"payload": []byte("hello")})
+	c.InsertObject(Object{"payload": "world"})
+	c.InsertObject(Object{"payload": []byte{}})
+	c.InsertObject(Object{})
+
+	assert.Equal(t, []byte("hello"), payloadAt(c, 0))
+	assert.Equal(t, []byte("world"), payloadAt(c, 1))
+	assert.Equal(t, []byte{}, payloadAt(c, 2))
+	assert.Nil(t, payloadAt(c, 3))
+
+	// Update and delete values through the accessors
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		payload := txn.Bytes("payload")
+		return txn.Range(func(idx uint32) {
+			switch idx {
+			case 0:
+				payload.Set([]byte("hello, world"))
+			case 1:
+				txn.Any("payload").Set(nil)
+			case 3:
+				payload.Set([]byte("!"))
+			}
+		})
+	}))
+
+	assert.Equal(t, []byte("hello, world"), payloadAt(c, 0))
+	assert.Nil(t, payloadAt(c, 1))
+	assert.Equal(t, []byte("!"), payloadAt(c, 3))
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		v, ok := r.Bytes("payload")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("hello, world"), v)
+
+		value, ok := r.Any("payload")
+		assert.True(t, ok)
+		assert.Equal(t, v, value)
+		return nil
+	}))
+
+	// Appending to a value read must not overwrite the arena
+	assert.NoError(t, c.QueryAt(2, func(r Row) error {
+		v, _ := r.Bytes("payload")
+		_ = append(v, "overflow"...)
+		return nil
+	}))
+	assert.Equal(t, []byte("!"), payloadAt(c, 3))
+
+	assert.Panics(t, func() {
+		c.InsertObject(Object{"payload": 42})
+	})
+}
+
+func TestBytesCompact(t *testing.T) {
+	c := NewCollection(Options{CleanupInterval: time.Hour})
+	c.CreateColumn("payload", ForBytes())
+	for i := 0; i < 100; i++ {
+		c.InsertObject(Object{"payload": []byte(fmt.Sprintf("payload-%03d", i))})
+	}
+
+	column := func() *columnBytes {
+		column, _ := c.cols.Load("payload")
+		return column.Column.(*columnBytes)
+	}
+
+	// Overwrite most of the values, leaving their bytes unused in the arena
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		payload := txn.Bytes("payload")
+		return txn.Range(func(idx uint32) {
+			if idx >= 20 {
+				payload.Set([]byte("x"))
+			}
+		})
+	}))
+
+	assert.True(t, column().Fragmented(0.5))
+	size := column().SizeOf(0)
+	c.compact()
+	assert.False(t, column().Fragmented(0.5))
+	assert.Less(t, column().SizeOf(0), size)
+	assert.Equal(t, uint64(0), column().garbage)
+	assert.Equal(t, uint64(20*11+80), column().size)
+
+	for i := 0; i < 100; i++ {
+		expect := []byte("x")
+		if i < 20 {
+			expect = []byte(fmt.Sprintf("payload-%03d", i))
+		}
+		assert.Equal(t, expect, payloadAt(c, uint32(i)))
+	}
+}
+
+func TestBytesCloneAndSnapshot(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("payload", ForBytes())
+	c.InsertObject(Object{"payload": []byte("a")})
+	c.InsertObject(Object{"payload": []byte("b")})
+
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+
+	// The values appended to either of the collections must not affect the other
+	clone.InsertObject(Object{"payload": []byte("clone")})
+	c.InsertObject(Object{"payload": []byte("original")})
+	assert.NoError(t, clone.QueryAt(0, func(r Row) error {
+		r.SetBytes("payload", []byte("changed"))
+		return nil
+	}))
+
+	assert.Equal(t, []byte("a"), payloadAt(c, 0))
+	assert.Equal(t, []byte("original"), payloadAt(c, 2))
+	assert.Equal(t, []byte("changed"), payloadAt(clone, 0))
+	assert.Equal(t, []byte("clone"), payloadAt(clone, 2))
+
+	// The values are restored from a snapshot
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, c.Snapshot(buffer))
+	other := NewCollection()
+	other.CreateColumn("payload", ForBytes())
+	assert.NoError(t, other.Restore(buffer))
+	assert.Equal(t, 3, other.Count())
+	assert.Equal(t, []byte("b"), payloadAt(other, 1))
+	assert.Equal(t, []byte("original"), payloadAt(other, 2))
+}
+
+// payloadAt reads the payload of a row, through a selector
+func payloadAt(c *Collection, idx uint32) (payload []byte) {
+	c.ReadAt(idx, func(v Selector) {
+		payload, _ = v.Bytes("payload")
+	})
+	return
+}
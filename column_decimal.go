@@ -0,0 +1,452 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	// ErrInexact is returned when a decimal value can not be represented exactly at the scale
+	// of a column, which would otherwise require it to be rounded.
+	ErrInexact = errors.New("column: decimal value can not be represented exactly")
+)
+
+// maxScale is the largest scale of a decimal, so that 10^scale fits into an int64
+const maxScale = 18
+
+// Decimal represents a fixed-point decimal number, stored as an integer number of units
+// where each unit is 10^-scale. For example, 12.34 is 1234 units at a scale of 2.
+type Decimal struct {
+	units int64
+	scale uint8
+}
+
+// NewDecimal creates a new decimal from a number of units at the specified scale, such that
+// NewDecimal(1234, 2) is 12.34. The scale must not exceed 18.
+func NewDecimal(units int64, scale uint8) Decimal {
+	if scale > maxScale {
+		panic(fmt.Errorf("column: invalid decimal scale %d, must not exceed %d", scale, maxScale))
+	}
+	return Decimal{units: units, scale: scale}
+}
+
+// ParseDecimal parses a decimal such as "-12.34", with a scale equal to the number of digits
+// of its fraction. Exponents are not supported.
+func ParseDecimal(s string) (Decimal, error) {
+	digits, fraction, _ := strings.Cut(s, ".")
+	if len(fraction) > maxScale {
+		return Decimal{}, fmt.Errorf("column: unable to parse decimal '%s', too many fractional digits", s)
+	}
+
+	if strings.ContainsAny(fraction, "+-") || digits == "" || digits == "-" || digits == "+" {
+		return Decimal{}, fmt.Errorf("column: unable to parse decimal '%s'", s)
+	}
+
+	units, err := strconv.ParseInt(digits+fraction, 10, 64)
+	switch {
+	case errors.Is(err, strconv.ErrRange):
+		return Decimal{}, fmt.Errorf("%w, decimal '%s'", ErrOverflow, s)
+	case err != nil:
+		return Decimal{}, fmt.Errorf("column: unable to parse decimal '%s'", s)
+	default:
+		return Decimal{units: units, scale: uint8(len(fraction))}, nil
+	}
+}
+
+// DecimalOf converts a rational number into a decimal at the specified scale. It returns
+// ErrInexact if the number has more fractional digits than the scale allows, and ErrOverflow
+// if it does not fit in the range of the decimal.
+func DecimalOf(r *big.Rat, scale uint8) (Decimal, error) {
+	if scale > maxScale {
+		return Decimal{}, fmt.Errorf("column: invalid decimal scale %d, must not exceed %d", scale, maxScale)
+	}
+
+	units := new(big.Int).Mul(r.Num(), big.NewInt(pow10[scale]))
+	units, remainder := units.QuoRem(units, r.Denom(), new(big.Int))
+	switch {
+	case remainder.Sign() != 0:
+		return Decimal{}, fmt.Errorf("%w at a scale of %d, value %s", ErrInexact, scale, r.RatString())
+	case !units.IsInt64():
+		return Decimal{}, fmt.Errorf("%w, value %s", ErrOverflow, r.RatString())
+	default:
+		return Decimal{units: units.Int64(), scale: scale}, nil
+	}
+}
+
+// Units returns the number of units of the decimal, each unit being 10^-scale
+func (d Decimal) Units() int64 {
+	return d.units
+}
+
+// Scale returns the number of fractional digits of the decimal
+func (d Decimal) Scale() uint8 {
+	return d.scale
+}
+
+// Rat returns the decimal as a rational number
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(d.units), big.NewInt(pow10[d.scale]))
+}
+
+// Rescale converts the decimal to another scale. It returns ErrInexact if the decimal has
+// more fractional digits than the scale allows, and ErrOverflow if it does not fit anymore.
+func (d Decimal) Rescale(scale uint8) (Decimal, error) {
+	switch {
+	case scale > maxScale:
+		return Decimal{}, fmt.Errorf("column: invalid decimal scale %d, must not exceed %d", scale, maxScale)
+	case scale < d.scale:
+		factor := pow10[d.scale-scale]
+		if d.units%factor != 0 {
+			return Decimal{}, fmt.Errorf("%w at a scale of %d, value %s", ErrInexact, scale, d)
+		}
+		return Decimal{units: d.units / factor, scale: scale}, nil
+	default:
+		factor := pow10[scale-d.scale]
+		if d.units > math.MaxInt64/factor || d.units < math.MinInt64/factor {
+			return Decimal{}, fmt.Errorf("%w, value %s", ErrOverflow, d)
+		}
+		return Decimal{units: d.units * factor, scale: scale}, nil
+	}
+}
+
+// Cmp compares the decimal with another one, regardless of their scales, and returns -1, 0
+// or +1 depending on whether it is smaller, equal or greater.
+func (d Decimal) Cmp(other Decimal) int {
+	if d.scale == other.scale {
+		switch {
+		case d.units < other.units:
+			return -1
+		case d.units > other.units:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return d.Rat().Cmp(other.Rat())
+}
+
+// String returns the decimal with all of the digits of its scale, such as "-12.30"
+func (d Decimal) String() string {
+	abs := uint64(d.units)
+	if d.units < 0 {
+		abs = -abs
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+
+	if d.units < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// pow10 contains the powers of ten which fit into an int64
+var pow10 = func() (out [maxScale + 1]int64) {
+	out[0] = 1
+	for i := 1; i < len(out); i++ {
+		out[i] = out[i-1] * 10
+	}
+	return
+}()
+
+// decimalOf converts a value into a decimal at the specified scale. The integers are whole
+// numbers, while the floating-point numbers are rejected since they are not exact.
+func decimalOf(value any, scale uint8) (Decimal, error) {
+	switch v := value.(type) {
+	case Decimal:
+		return v.Rescale(scale)
+	case *big.Rat:
+		return DecimalOf(v, scale)
+	case big.Rat:
+		return DecimalOf(&v, scale)
+	case string:
+		d, err := ParseDecimal(v)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return d.Rescale(scale)
+	case int, int8, int16, int32, int64:
+		return Decimal{units: reflect.ValueOf(v).Int()}.Rescale(scale)
+	case uint, uint8, uint16, uint32, uint64:
+		u := reflect.ValueOf(v).Uint()
+		if u > math.MaxInt64 {
+			return Decimal{}, fmt.Errorf("%w, value %d", ErrOverflow, u)
+		}
+		return Decimal{units: int64(u)}.Rescale(scale)
+	default:
+		return Decimal{}, fmt.Errorf("column: unable to convert %T into a decimal", value)
+	}
+}
+
+// --------------------------- Decimal Column ----------------------------
+
+// columnDecimal represents a column of fixed-point decimals, all of them at the scale of the
+// column and stored as their number of units. The additions on the units are exact.
+type columnDecimal struct {
+	chunks[int64]
+	scale uint8
+}
+
+// makeDecimals creates a new column of decimals at the specified scale, which is the number
+// of fractional digits of the values and must not exceed 18.
+func makeDecimals(scale uint8) Column {
+	if scale > maxScale {
+		panic(fmt.Errorf("column: invalid decimal scale %d, must not exceed %d", scale, maxScale))
+	}
+
+	return &columnDecimal{
+		chunks: make(chunks[int64], 0, 4),
+		scale:  scale,
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnDecimal) MakeEmpty() Column {
+	return makeDecimals(c.scale)
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnDecimal) Clone() Column {
+	return &columnDecimal{
+		chunks: c.chunks.share(),
+		scale:  c.scale,
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnDecimal) Apply(chunk commit.Chunk, r *commit.Reader) {
+	fill, data := c.chunkFor(chunk)
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			fill.Set(offset)
+			data[offset] = r.Int64()
+		case commit.Add:
+			fill.Set(offset)
+			data[offset] = r.AddToInt64(data[offset])
+		case commit.Saturate:
+			fill.Set(offset)
+			data[offset] = r.AddSaturateToInt64(data[offset])
+		case commit.Delete:
+			fill.Remove(offset)
+		}
+	}
+}
+
+// Value retrieves a value at a specified index
+func (c *columnDecimal) Value(idx uint32) (interface{}, bool) {
+	if v, ok := c.LoadDecimal(idx); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// LoadDecimal retrieves a decimal at a specified index
+func (c *columnDecimal) LoadDecimal(idx uint32) (Decimal, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		return Decimal{units: c.chunks[chunk].data[index], scale: c.scale}, true
+	}
+	return Decimal{}, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnDecimal) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnDecimal) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	fill, data := c.chunkAt(chunk)
+	fill.Range(func(x uint32) {
+		dst.PutInt64(chunk.Min()+x, data[x])
+	})
+}
+
+// Encode writes a decimal into the commit buffer. The values can be written as decimals,
+// rational numbers, strings or integers, but never as floating-point numbers. A nil value
+// deletes the decimal and the values which can not be represented exactly at the scale of
+// the column are rejected with a panic.
+func (c *columnDecimal) Encode(dst *commit.Buffer, idx uint32, value any) {
+	if value == nil {
+		dst.PutOperation(commit.Delete, idx)
+		return
+	}
+
+	d, err := decimalOf(value, c.scale)
+	if err != nil {
+		panic(err)
+	}
+
+	dst.PutInt64(idx, d.units)
+}
+
+// nextValue returns the decimal which the current operation of the reader stores, given the
+// value previously stored at its row (if any).
+func (c *columnDecimal) nextValue(r *commit.Reader, prev any) any {
+	current, _ := prev.(Decimal)
+	switch r.Type {
+	case commit.Add:
+		return Decimal{units: current.units + r.Int64(), scale: c.scale}
+	case commit.Saturate:
+		return Decimal{units: r.AddSaturateToInt64(current.units), scale: c.scale}
+	default:
+		return Decimal{units: r.Int64(), scale: c.scale}
+	}
+}
+
+// filterDecimals filters down the rows whose decimal satisfies the predicate
+func (c *columnDecimal) filterDecimals(chunk commit.Chunk, index bitmap.Bitmap, predicate func(Decimal) bool) {
+	if int(chunk) >= len(c.chunks) || c.chunks[chunk].data == nil {
+		index.Clear()
+		return
+	}
+
+	fill, data := c.chunkAt(chunk)
+	index.And(fill)
+	index.Filter(func(x uint32) bool {
+		return predicate(Decimal{units: data[x], scale: c.scale})
+	})
+}
+
+// WithDecimal filters down the rows whose decimal, in the specified column, satisfies the
+// predicate. The decimals are all at the scale of the column.
+func (txn *Txn) WithDecimal(columnName string, predicate func(v Decimal) bool) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(columnName)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	decimals, ok := c.Column.(*columnDecimal)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, "WithDecimal", columnName, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		decimals.filterDecimals(chunk, index, predicate)
+	})
+	return txn
+}
+
+// --------------------------- Accessors ----------------------------
+
+// decimalReader represents a read-only accessor for decimals
+type decimalReader struct {
+	reader *columnDecimal
+	txn    *Txn
+}
+
+// Get loads the value at the current transaction cursor
+func (s decimalReader) Get() (Decimal, bool) {
+	return s.reader.LoadDecimal(s.txn.cursor)
+}
+
+// Sum computes the exact sum of the decimals selected by this transaction. It returns
+// ErrOverflow if the sum does not fit in the range of the decimal.
+func (s decimalReader) Sum() (Decimal, error) {
+	var sum int64
+	var overflow bool
+	s.txn.resolve()
+	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) >= len(s.reader.chunks) || s.reader.chunks[chunk].data == nil {
+			return
+		}
+
+		fill, data := s.reader.chunkAt(chunk)
+		index.Range(func(x uint32) {
+			if fill.Contains(x) {
+				overflow = overflow || overflows(sum, data[x])
+				sum += data[x]
+			}
+		})
+	})
+
+	if overflow {
+		return Decimal{}, ErrOverflow
+	}
+	return Decimal{units: sum, scale: s.reader.scale}, nil
+}
+
+// decimalReaderFor creates a new decimal reader
+func decimalReaderFor(txn *Txn, columnName string) decimalReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnDecimal)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type decimal", columnName))
+	}
+
+	return decimalReader{
+		reader: reader,
+		txn:    txn,
+	}
+}
+
+// decimalWriter represents read-write accessor for decimals
+type decimalWriter struct {
+	decimalReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor. It returns ErrInexact if the value
+// can not be represented exactly at the scale of the column.
+func (s decimalWriter) Set(value Decimal) error {
+	d, err := value.Rescale(s.reader.scale)
+	if err != nil {
+		return err
+	}
+
+	s.writer.PutInt64(s.txn.cursor, d.units)
+	return nil
+}
+
+// Add atomically adds a delta to the value at the current transaction cursor. It returns
+// ErrInexact if the delta can not be represented exactly at the scale of the column, and
+// ErrOverflow if the result would not fit in the range of the decimal.
+func (s decimalWriter) Add(delta Decimal) error {
+	d, err := delta.Rescale(s.reader.scale)
+	if err != nil {
+		return err
+	}
+
+	if current, _ := s.Get(); overflows(current.units, d.units) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateInt64(s.txn.cursor, d.units)
+	return nil
+}
+
+// Decimal returns a decimal column accessor
+func (txn *Txn) Decimal(columnName string) decimalWriter {
+	return decimalWriter{
+		decimalReader: decimalReaderFor(txn, columnName),
+		writer:        txn.bufferFor(columnName),
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for input, expect := range map[string]Decimal{
+		"0":         {units: 0},
+		"12.34":     {units: 1234, scale: 2},
+		"-12.30":    {units: -1230, scale: 2},
+		"+0.005":    {units: 5, scale: 3},
+		"-0.5":      {units: -5, scale: 1},
+		"100.":      {units: 100},
+		"922337203": {units: 922337203},
+	} {
+		d, err := ParseDecimal(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expect, d, input)
+	}
+
+	for _, input := range []string{"", ".5", "-", "1.2.3", "1.-5", "abc", "1e5", "0.1234567890123456789"} {
+		_, err := ParseDecimal(input)
+		assert.Error(t, err, input)
+	}
+
+	_, err := ParseDecimal("92233720368547758.08")
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestDecimalString(t *testing.T) {
+	for expect, d := range map[string]Decimal{
+		"0":                     NewDecimal(0, 0),
+		"0.00":                  NewDecimal(0, 2),
+		"12.34":                 NewDecimal(1234, 2),
+		"-0.05":                 NewDecimal(-5, 2),
+		"-92233720368547.75808": NewDecimal(math.MinInt64, 5),
+	} {
+		assert.Equal(t, expect, d.String())
+	}
+
+	assert.Panics(t, func() {
+		NewDecimal(1, 19)
+	})
+}
+
+func TestDecimalConversions(t *testing.T) {
+	d, err := DecimalOf(big.NewRat(1, 8), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.125", d.String())
+	assert.Equal(t, big.NewRat(1, 8), d.Rat())
+
+	_, err = DecimalOf(big.NewRat(1, 3), 18)
+	assert.ErrorIs(t, err, ErrInexact)
+	_, err = DecimalOf(new(big.Rat).SetInt64(math.MaxInt64), 1)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = DecimalOf(big.NewRat(1, 2), 19)
+	assert.Error(t, err)
+
+	// Rescaling is exact, or fails
+	up, err := NewDecimal(1234, 2).Rescale(4)
+	assert.NoError(t, err)
+	assert.Equal(t, NewDecimal(123400, 4), up)
+	down, err := up.Rescale(2)
+	assert.NoError(t, err)
+	assert.Equal(t, NewDecimal(1234, 2), down)
+	_, err = up.Rescale(1)
+	assert.ErrorIs(t, err, ErrInexact)
+	_, err = NewDecimal(math.MaxInt64/10, 0).Rescale(2)
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	assert.Equal(t, 0, NewDecimal(1230, 3).Cmp(NewDecimal(123, 2)))
+	assert.Equal(t, -1, NewDecimal(-1, 2).Cmp(NewDecimal(0, 0)))
+	assert.Equal(t, 1, NewDecimal(2, 1).Cmp(NewDecimal(1, 1)))
+}
+
+func TestDecimalColumn(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("amount", ForDecimal(2))
+	c.InsertObject(Object{"amount": NewDecimal(1050, 2)})
+	c.InsertObject(Object{"amount": "0.1"})
+	c.InsertObject(Object{"amount": big.NewRat(1, 4)})
+	c.InsertObject(Object{"amount": 3})
+	c.InsertObject(Object{})
+
+	assert.Equal(t, "10.50", amountAt(c, 0))
+	assert.Equal(t, "0.10", amountAt(c, 1))
+	assert.Equal(t, "0.25", amountAt(c, 2))
+	assert.Equal(t, "3.00", amountAt(c, 3))
+	assert.Equal(t, "", amountAt(c, 4))
+
+	// The values which are not exact are rejected
+	assert.Panics(t, func() {
+		c.InsertObject(Object{"amount": 0.1})
+	})
+	assert.Panics(t, func() {
+		c.InsertObject(Object{"amount": "0.001"})
+	})
+
+	// Adding ten cents ten times is exact, unlike floating-point numbers
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, c.QueryAt(1, func(r Row) error {
+			return r.AddDecimal("amount", NewDecimal(1, 1))
+		}))
+	}
+	assert.Equal(t, "1.10", amountAt(c, 1))
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		amount := txn.Decimal("amount")
+		return txn.Range(func(idx uint32) {
+			switch idx {
+			case 0:
+				assert.ErrorIs(t, amount.Set(NewDecimal(1, 3)), ErrInexact)
+				assert.NoError(t, amount.Add(NewDecimal(-50, 2)))
+			case 3:
+				assert.ErrorIs(t, amount.Add(NewDecimal(math.MaxInt64, 2)), ErrOverflow)
+			case 4:
+				assert.NoError(t, amount.Set(NewDecimal(1, 0)))
+			}
+		})
+	}))
+
+	assert.Equal(t, "10.00", amountAt(c, 0))
+	assert.Equal(t, "1.00", amountAt(c, 4))
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		sum, err := txn.Decimal("amount").Sum()
+		assert.NoError(t, err)
+		assert.Equal(t, "15.35", sum.String())
+
+		count := txn.WithDecimal("amount", func(v Decimal) bool {
+			return v.Cmp(NewDecimal(1, 0)) > 0
+		}).Count()
+		assert.Equal(t, 3, count)
+		return nil
+	}))
+}
+
+func TestDecimalSumOverflow(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("amount", ForDecimal(0))
+	c.InsertObject(Object{"amount": int64(math.MaxInt64)})
+	c.InsertObject(Object{"amount": 1})
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		_, err := txn.Decimal("amount").Sum()
+		assert.ErrorIs(t, err, ErrOverflow)
+		return nil
+	}))
+}
+
+func TestDecimalSnapshot(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("amount", ForDecimal(4))
+	c.InsertObject(Object{"amount": "1234.5678"})
+	c.InsertObject(Object{"amount": "-0.0001"})
+
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, clone.QueryAt(0, func(r Row) error {
+		return r.SetDecimal("amount", NewDecimal(1, 0))
+	}))
+	assert.Equal(t, "1234.5678", amountAt(c, 0))
+	assert.Equal(t, "1.0000", amountAt(clone, 0))
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, c.Snapshot(buffer))
+	other := NewCollection()
+	other.CreateColumn("amount", ForDecimal(4))
+	assert.NoError(t, other.Restore(buffer))
+	assert.Equal(t, "1234.5678", amountAt(other, 0))
+	assert.Equal(t, "-0.0001", amountAt(other, 1))
+}
+
+// amountAt reads the amount of a row, through a selector
+func amountAt(c *Collection, idx uint32) (amount string) {
+	c.ReadAt(idx, func(v Selector) {
+		if d, ok := v.Decimal("amount"); ok {
+			amount = d.String()
+		}
+	})
+	return
+}
@@ -0,0 +1,304 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- Hash Index ----------------------------
+
+// columnHash represents a hash index which maps every distinct value of the target column
+// to the set of rows holding that value, so that equality lookups do not need to scan.
+type columnHash struct {
+	lock sync.RWMutex               // The lock to protect the index across chunks
+	fill bitmap.Bitmap              // The rows which have a value
+	keys []any                      // The current key of every row
+	rows map[any]bitmap.Bitmap      // The rows of every key
+	key  func(r *commit.Reader) any // The function which reads a key from the commit
+	name string                     // The name of the target column
+}
+
+// newHashIndex creates a new hash index column for the target column.
+func newHashIndex(columnName string, target Column) (*column, error) {
+	key, ok := hashKeyOf(target)
+	if !ok {
+		return nil, fmt.Errorf("column: unable to create hash index, column '%s' is of unsupported type %T", columnName, target)
+	}
+
+	return columnFor(hashIndexOf(columnName), &columnHash{
+		fill: make(bitmap.Bitmap, 0, 4),
+		keys: make([]any, 0, 64),
+		rows: make(map[any]bitmap.Bitmap, 64),
+		key:  key,
+		name: columnName,
+	}, columnOptions{}), nil
+}
+
+// hashIndexOf returns the name of the hash index of a column
+func hashIndexOf(columnName string) string {
+	return columnName + "#hash"
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnHash) Grow(idx uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fill.Grow(idx)
+	for len(c.keys) <= int(idx) {
+		c.keys = append(c.keys, nil)
+	}
+}
+
+// Clone creates a copy of the index
+func (c *columnHash) Clone() Column {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	rows := make(map[any]bitmap.Bitmap, len(c.rows))
+	for k, v := range c.rows {
+		rows[k] = v.Clone(nil)
+	}
+
+	return &columnHash{
+		fill: c.fill.Clone(nil),
+		keys: append(make([]any, 0, cap(c.keys)), c.keys...),
+		rows: rows,
+		key:  c.key,
+		name: c.name,
+	}
+}
+
+// Column returns the target name of the column on which this index should apply.
+func (c *columnHash) Column() string {
+	return c.name
+}
+
+// SizeOf returns the approximate memory used by a chunk, excluding the sets of rows per key
+func (c *columnHash) SizeOf(chunk commit.Chunk) int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	keys := 0
+	if lo, hi := int(chunk.Min()), int(chunk.Max())+1; lo < len(c.keys) {
+		if hi > len(c.keys) {
+			hi = len(c.keys)
+		}
+		keys = hi - lo
+	}
+	return len(chunk.OfBitmap(c.fill))*8 + keys*16
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnHash) Apply(chunk commit.Chunk, r *commit.Reader) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for r.Next() {
+		switch r.Type {
+		case commit.Put, commit.Add, commit.Saturate:
+			c.remove(r.Index())
+			c.insert(r.Index(), c.key(r))
+		case commit.Delete:
+			c.remove(r.Index())
+		}
+	}
+}
+
+// insert adds the row to the set of rows of the key
+func (c *columnHash) insert(idx uint32, key any) {
+	rows := c.rows[key]
+	rows.Set(idx)
+	c.rows[key] = rows
+	c.fill.Set(idx)
+	c.keys[idx] = key
+}
+
+// remove removes the row from the set of rows of its current key
+func (c *columnHash) remove(idx uint32) {
+	if !c.fill.Contains(idx) {
+		return
+	}
+
+	key := c.keys[idx]
+	rows := c.rows[key]
+	rows.Remove(idx)
+	if _, ok := rows.Min(); ok {
+		c.rows[key] = rows
+	} else {
+		delete(c.rows, key)
+	}
+
+	c.fill.Remove(idx)
+	c.keys[idx] = nil
+}
+
+// reset clears the index and changes the function which reads the keys, this is used when
+// the target column is replaced by a column of a different type.
+func (c *columnHash) reset(target Column) error {
+	key, ok := hashKeyOf(target)
+	if !ok {
+		return fmt.Errorf("column: unable to update hash index, unsupported type %T", target)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fill.Clear()
+	c.rows = make(map[any]bitmap.Bitmap, 64)
+	for i := range c.keys {
+		c.keys[i] = nil
+	}
+
+	c.key = key
+	return nil
+}
+
+// Value retrieves a value at a specified index.
+func (c *columnHash) Value(idx uint32) (any, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.fill.Contains(idx) {
+		return c.keys[idx], true
+	}
+	return nil, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnHash) Contains(idx uint32) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.fill.Contains(idx)
+}
+
+// Index returns the fill list for the column
+func (c *columnHash) Index(chunk commit.Chunk) bitmap.Bitmap {
+	return chunk.OfBitmap(c.fill)
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnHash) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	dst.PutBitmap(commit.PutTrue, chunk, c.fill)
+}
+
+// Lookup copies the set of rows which hold the specified value into the destination.
+func (c *columnHash) Lookup(value any, dst *bitmap.Bitmap) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if rows, ok := c.rows[hashKey(value)]; ok {
+		rows.Clone(dst)
+		return
+	}
+
+	dst.Clear()
+}
+
+// --------------------------- Keys ----------------------------
+
+// hashKeyOf returns a function which reads the value of a column from the commit reader in a
+// normalized form, so that it can be used as a key of the hash index.
+func hashKeyOf(column Column) (func(r *commit.Reader) any, bool) {
+	switch column.(type) {
+	case Textual:
+		return func(r *commit.Reader) any { return string(r.Bytes()) }, true
+	case *numericColumn[int]:
+		return func(r *commit.Reader) any { return int64(r.Int()) }, true
+	case *numericColumn[int16]:
+		return func(r *commit.Reader) any { return int64(r.Int16()) }, true
+	case *numericColumn[int32]:
+		return func(r *commit.Reader) any { return int64(r.Int32()) }, true
+	case *numericColumn[int64]:
+		return func(r *commit.Reader) any { return r.Int64() }, true
+	case *numericColumn[uint]:
+		return func(r *commit.Reader) any { return uint64(r.Uint()) }, true
+	case *numericColumn[uint16]:
+		return func(r *commit.Reader) any { return uint64(r.Uint16()) }, true
+	case *numericColumn[uint32]:
+		return func(r *commit.Reader) any { return uint64(r.Uint32()) }, true
+	case *numericColumn[uint64]:
+		return func(r *commit.Reader) any { return r.Uint64() }, true
+	case *numericColumn[float32]:
+		return func(r *commit.Reader) any { return float64(r.Float32()) }, true
+	case *numericColumn[float64]:
+		return func(r *commit.Reader) any { return r.Float64() }, true
+	default:
+		return nil, false
+	}
+}
+
+// hashKey normalizes a value the same way as the keys of the hash index, so that a value
+// of any integer type can be used to look up an integer column, for example.
+func hashKey(value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	default:
+		return value
+	}
+}
+
+// --------------------------- Transaction ----------------------------
+
+// cheaperToScan returns whether scanning the values of the remaining rows is cheaper than
+// intersecting them with a hash index, according to the calibrated cost model.
+func (txn *Txn) cheaperToScan() bool {
+	model, ok := txn.owner.costModel()
+	if !ok {
+		return false
+	}
+
+	candidates := float64(txn.index.Count())
+	return candidates*model.Value < float64(len(txn.index))*model.Bitmap
+}
+
+// WithEqual filters down the rows to the ones where the value of the column is equal to the
+// specified value. If the column has a hash index, the rows are looked up directly instead
+// of scanning the values of the column.
+func (txn *Txn) WithEqual(column string, value any) *Txn {
+	txn.initialize()
+	columns, ok := txn.owner.cols.LoadWithIndex(column)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	// If there is a hash index, use it for the lookup unless scanning the few remaining rows
+	// is cheaper according to the calibrated cost model, or unless the hints say otherwise
+	preferred := txn.prefers(hintIndex, column)
+	for _, c := range columns[1:] {
+		if index, ok := c.Column.(*columnHash); ok && !txn.prefers(hintScan, column) && (preferred || !txn.cheaperToScan()) {
+			done := txn.traceStep("WithEqual", column)
+			var rows bitmap.Bitmap
+			index.Lookup(value, &rows)
+			txn.index.And(rows)
+			done()
+			return txn
+		}
+	}
+
+	// Otherwise, scan the values of the column
+	key := hashKey(value)
+	return txn.WithValue(column, func(v any) bool {
+		return hashKey(v) == key
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashIndex(t *testing.T) {
+	users := NewCollection()
+	users.CreateColumn("email", ForString())
+	users.CreateColumn("age", ForInt32())
+	for i := 0; i < 100; i++ {
+		users.InsertObject(Object{
+			"email": fmt.Sprintf("user%d@example.com", i%10),
+			"age":   int32(i % 7),
+		})
+	}
+
+	// Index after the fact, the existing values should be indexed
+	assert.NoError(t, users.CreateHashIndex("email"))
+	assert.NoError(t, users.CreateHashIndex("age"))
+	assert.Error(t, users.CreateHashIndex("email"))
+	assert.Error(t, users.CreateHashIndex("invalid"))
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.WithEqual("email", "user3@example.com").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 15, txn.WithEqual("age", 0).Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.
+			WithEqual("email", "user3@example.com").
+			WithEqual("age", int64(3)).Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithEqual("email", "nobody@example.com").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithEqual("invalid", "x").Count())
+		return nil
+	})
+}
+
+func TestHashIndexUpdate(t *testing.T) {
+	users := NewCollection()
+	users.CreateColumn("email", ForEnum())
+	users.CreateColumn("score", ForFloat64())
+	assert.NoError(t, users.CreateHashIndex("email"))
+	assert.NoError(t, users.CreateHashIndex("score"))
+	for i := 0; i < 10; i++ {
+		users.InsertObject(Object{"email": "a@b.c", "score": 1.0})
+	}
+
+	// Update some of the values
+	users.Query(func(txn *Txn) error {
+		email := txn.Enum("email")
+		score := txn.Float64("score")
+		return txn.Range(func(idx uint32) {
+			if idx%2 == 0 {
+				email.Set("x@y.z")
+				score.Add(1)
+			}
+		})
+	})
+
+	// Delete one of the rows
+	users.DeleteAt(0)
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 5, txn.WithEqual("email", "a@b.c").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 4, txn.WithEqual("email", "x@y.z").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 4, txn.WithEqual("score", float32(2)).Count())
+		return nil
+	})
+}
+
+func TestHashIndexScan(t *testing.T) {
+	users := NewCollection()
+	users.CreateColumn("email", ForString())
+	users.CreateColumn("age", ForInt())
+	users.InsertObject(Object{"email": "a@b.c", "age": 10})
+	users.InsertObject(Object{"email": "x@y.z", "age": 20})
+
+	// Without a hash index, the same filters should scan the values
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.WithEqual("email", "a@b.c").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.WithEqual("age", int16(20)).Count())
+		return nil
+	})
+}
+
+func TestHashIndexSchema(t *testing.T) {
+	users := NewCollection()
+	users.CreateColumn("email", ForString())
+	users.CreateColumn("active", ForBool())
+	assert.Error(t, users.CreateHashIndex("active"))
+	assert.NoError(t, users.CreateHashIndex("email"))
+	users.InsertObject(Object{"email": "a@b.c"})
+
+	// Renaming the column should keep its hash index
+	assert.NoError(t, users.RenameColumn("email", "mail"))
+	users.InsertObject(Object{"mail": "a@b.c"})
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.WithEqual("mail", "a@b.c").Count())
+		return nil
+	})
+
+	// Migrating the column should rebuild its hash index
+	assert.NoError(t, users.MigrateColumn("mail", ForEnum(), func(v any) any {
+		return "z@z.z"
+	}))
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithEqual("mail", "a@b.c").Count())
+		return nil
+	})
+
+	users.Query(func(txn *Txn) error {
+		assert.Equal(t, 2, txn.WithEqual("mail", "z@z.z").Count())
+		return nil
+	})
+
+	// Dropping the column should drop its hash index
+	assert.NoError(t, users.DropColumn("mail"))
+	_, ok := users.cols.Load(hashIndexOf("mail"))
+	assert.False(t, ok)
+}
@@ -44,7 +44,7 @@ func newIndex(indexName, columnName string, rule func(Reader) bool) *column {
 		fill: make(bitmap.Bitmap, 0, 4),
 		name: columnName,
 		rule: rule,
-	})
+	}, columnOptions{})
 }
 
 // Grow grows the size of the column until we have enough to store
@@ -52,11 +52,25 @@ func (c *columnIndex) Grow(idx uint32) {
 	c.fill.Grow(idx)
 }
 
+// Clone creates a copy of the index
+func (c *columnIndex) Clone() Column {
+	return &columnIndex{
+		fill: c.fill.Clone(nil),
+		name: c.name,
+		rule: c.rule,
+	}
+}
+
 // Column returns the target name of the column on which this index should apply.
 func (c *columnIndex) Column() string {
 	return c.name
 }
 
+// SizeOf returns the memory used by a chunk, in bytes
+func (c *columnIndex) SizeOf(chunk commit.Chunk) int {
+	return len(chunk.OfBitmap(c.fill)) * 8
+}
+
 // Apply applies a set of operations to the column.
 func (c *columnIndex) Apply(chunk commit.Chunk, r *commit.Reader) {
 
@@ -65,7 +79,7 @@ func (c *columnIndex) Apply(chunk commit.Chunk, r *commit.Reader) {
 	// on the actual column.
 	for r.Next() {
 		switch r.Type {
-		case commit.Put, commit.Add:
+		case commit.Put, commit.Add, commit.Saturate:
 			if c.rule(r) {
 				c.fill.Set(uint32(r.Offset))
 			} else {
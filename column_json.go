@@ -0,0 +1,365 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- JSON Path ----------------------------
+
+// jsonPath represents a parsed path into a JSON document, such as "$.user.plans[0]"
+type jsonPath []pathStep
+
+// pathStep represents a single step of a path, either a field of an object or an element of
+// an array
+type pathStep struct {
+	field string // The name of the field, if not an element
+	index int    // The index of the element, or -1 for a field
+}
+
+// parsePath parses a path made of the root "$" followed by fields (".name") and elements
+// of arrays ("[0]").
+func parsePath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("column: invalid json path '%s', must start with '$'", path)
+	}
+
+	var steps jsonPath
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("column: invalid json path '%s', empty field", path)
+			}
+
+			steps = append(steps, pathStep{field: rest[1:end], index: -1})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("column: invalid json path '%s', missing ']'", path)
+			}
+
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("column: invalid json path '%s', invalid index '%s'", path, rest[1:end])
+			}
+
+			steps = append(steps, pathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("column: invalid json path '%s', unexpected '%c'", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// Find decodes the document and returns the value at the path, if any
+func (p jsonPath) Find(document []byte) (any, bool) {
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return nil, false
+	}
+
+	for _, step := range p {
+		switch v := value.(type) {
+		case map[string]any:
+			if step.index >= 0 {
+				return nil, false
+			}
+
+			field, ok := v[step.field]
+			if !ok {
+				return nil, false
+			}
+			value = field
+		case []any:
+			if step.index < 0 || step.index >= len(v) {
+				return nil, false
+			}
+			value = v[step.index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// --------------------------- JSON Column ----------------------------
+
+// columnJSON represents a column of JSON documents, stored as their raw bytes. The values at
+// the indexed paths are extracted the first time a chunk is filtered on them, and are kept
+// up to date as the documents of the chunk are written.
+type columnJSON struct {
+	columnBytes
+	paths map[string]jsonPath // The paths which are indexed
+	index sync.Map            // The extracted values of the indexed paths, by chunk
+	err   error               // The error of parsing the indexed paths, if any
+}
+
+// pathKey represents the key of the index of a path for a chunk
+type pathKey struct {
+	chunk commit.Chunk
+	path  string
+}
+
+// pathIndex represents the values at a path, extracted from the documents of a chunk
+type pathIndex struct {
+	once   sync.Once     // Extracts the values once, when first filtered on
+	found  bitmap.Bitmap // The rows whose document has a value at the path
+	values []any         // The values at the path
+}
+
+// makeJSON creates a new column of JSON documents. The specified paths are indexed, so the
+// filters on them do not need to decode the documents. The paths which can not be parsed
+// are reported when the column is created.
+func makeJSON(indexed ...string) Column {
+	column := &columnJSON{
+		columnBytes: columnBytes{
+			chunks: make([]bytesChunk, 0, 4),
+		},
+		paths: make(map[string]jsonPath, len(indexed)),
+	}
+
+	for _, p := range indexed {
+		path, err := parsePath(p)
+		if err != nil {
+			column.err = err
+			break
+		}
+		column.paths[p] = path
+	}
+	return column
+}
+
+// validate returns the error of parsing the indexed paths, if any
+func (c *columnJSON) validate() error {
+	return c.err
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnJSON) MakeEmpty() Column {
+	return &columnJSON{
+		columnBytes: columnBytes{
+			chunks: make([]bytesChunk, 0, 4),
+		},
+		paths: c.paths,
+	}
+}
+
+// Clone creates a copy of the column, sharing the documents until they are modified. The
+// indexes of the paths are not shared and will be extracted again.
+func (c *columnJSON) Clone() Column {
+	return &columnJSON{
+		columnBytes: *c.columnBytes.Clone().(*columnBytes),
+		paths:       c.paths,
+	}
+}
+
+// Release releases the documents and the indexes of an empty chunk
+func (c *columnJSON) Release(chunk commit.Chunk) {
+	c.columnBytes.Release(chunk)
+	for p := range c.paths {
+		c.index.Delete(pathKey{chunk: chunk, path: p})
+	}
+}
+
+// Apply applies a set of operations to the column, updating the indexes of the paths which
+// were already extracted for the chunk.
+func (c *columnJSON) Apply(chunk commit.Chunk, r *commit.Reader) {
+	c.columnBytes.Apply(chunk, r)
+	for p, path := range c.paths {
+		v, ok := c.index.Load(pathKey{chunk: chunk, path: p})
+		if !ok || v.(*pathIndex).values == nil {
+			continue
+		}
+
+		index := v.(*pathIndex)
+		r.Rewind()
+		for r.Next() {
+			offset := r.IndexAtChunk()
+			index.found.Remove(offset)
+			index.values[offset] = nil
+			if document, ok := c.LoadBytes(r.Index()); ok {
+				if value, ok := path.Find(document); ok {
+					index.found.Set(offset)
+					index.values[offset] = value
+				}
+			}
+		}
+	}
+}
+
+// Value retrieves a value at a specified index
+func (c *columnJSON) Value(idx uint32) (interface{}, bool) {
+	if v, ok := c.LoadBytes(idx); ok {
+		return json.RawMessage(v), true
+	}
+	return nil, false
+}
+
+// Encode writes a JSON document into the commit buffer. The raw documents, as bytes or as a
+// string, must be valid JSON while any other value is encoded with json.Marshal(). A nil
+// value deletes the document and the invalid documents are rejected with a panic.
+func (c *columnJSON) Encode(dst *commit.Buffer, idx uint32, value any) {
+	var document []byte
+	switch v := value.(type) {
+	case nil:
+		dst.PutOperation(commit.Delete, idx)
+		return
+	case json.RawMessage:
+		document = v
+	case []byte:
+		document = v
+	case string:
+		document = []byte(v)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			panic(fmt.Errorf("column: unable to encode %T as json, %w", value, err))
+		}
+		document = encoded
+	}
+
+	if !json.Valid(document) {
+		panic(fmt.Errorf("column: unable to write an invalid json document"))
+	}
+	dst.PutBytes(commit.Put, idx, document)
+}
+
+// filterPath filters down the rows whose document has a value at the path which satisfies
+// the predicate. If the path is indexed, the extracted values are used.
+func (c *columnJSON) filterPath(chunk commit.Chunk, index bitmap.Bitmap, p string, path jsonPath, predicate func(v any) bool) {
+	if int(chunk) >= len(c.chunks) || c.chunks[chunk].offset == nil {
+		index.Clear()
+		return
+	}
+
+	index.And(c.chunks[chunk].fill)
+	if _, ok := c.paths[p]; !ok {
+		offset := chunk.Min()
+		index.Filter(func(x uint32) bool {
+			document, _ := c.LoadBytes(offset + x)
+			value, ok := path.Find(document)
+			return ok && predicate(value)
+		})
+		return
+	}
+
+	extracted := c.extract(chunk, p, path)
+	index.And(extracted.found)
+	index.Filter(func(x uint32) bool {
+		return predicate(extracted.values[x])
+	})
+}
+
+// extract returns the index of a path for a chunk, extracting the values from the documents
+// if this was not done yet. The caller must hold the shard lock of the chunk.
+func (c *columnJSON) extract(chunk commit.Chunk, p string, path jsonPath) *pathIndex {
+	v, _ := c.index.LoadOrStore(pathKey{chunk: chunk, path: p}, new(pathIndex))
+	index := v.(*pathIndex)
+	index.once.Do(func() {
+		found := make(bitmap.Bitmap, chunkSize/64)
+		values := make([]any, chunkSize)
+		c.chunks[chunk].fill.Range(func(x uint32) {
+			document, _ := c.LoadBytes(chunk.Min() + x)
+			if value, ok := path.Find(document); ok {
+				found.Set(x)
+				values[x] = value
+			}
+		})
+		index.found, index.values = found, values
+	})
+	return index
+}
+
+// WithJSONPath filters down the rows whose JSON document, in the specified column, has a
+// value at the path which satisfies the predicate. The path starts with the root "$" and is
+// followed by the fields of the objects and the elements of the arrays, for example
+// "$.user.plans[0].name". The values are decoded as with json.Unmarshal(), so the numbers
+// are float64. If the path is invalid, or the column is not a JSON column, no row matches.
+func (txn *Txn) WithJSONPath(columnName, path string, predicate func(v interface{}) bool) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(columnName)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	documents, ok := c.Column.(*columnJSON)
+	steps, err := parsePath(path)
+	if !ok || err != nil {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costValue, "WithJSONPath", columnName, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		documents.filterPath(chunk, index, path, steps, predicate)
+	})
+	return txn
+}
+
+// --------------------------- Accessors ----------------------------
+
+// jsonReader represents a read-only accessor for JSON documents
+type jsonReader struct {
+	cursor *uint32
+	reader *columnJSON
+}
+
+// Get loads the value at the current transaction cursor. The returned document must not be
+// modified.
+func (s jsonReader) Get() (json.RawMessage, bool) {
+	return s.reader.LoadBytes(*s.cursor)
+}
+
+// jsonReaderFor creates a new JSON reader
+func jsonReaderFor(txn *Txn, columnName string) jsonReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnJSON)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type json", columnName))
+	}
+
+	return jsonReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// jsonWriter represents read-write accessor for JSON documents
+type jsonWriter struct {
+	jsonReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor. The value is either a raw document,
+// which must be valid, or any other value which is encoded with json.Marshal().
+func (s jsonWriter) Set(value any) {
+	s.reader.Encode(s.writer, *s.cursor, value)
+}
+
+// JSON returns a JSON column accessor
+func (txn *Txn) JSON(columnName string) jsonWriter {
+	return jsonWriter{
+		jsonReader: jsonReaderFor(txn, columnName),
+		writer:     txn.bufferFor(columnName),
+	}
+}
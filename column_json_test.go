@@ -0,0 +1,137 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePath(t *testing.T) {
+	path, err := parsePath("$.user.plans[1].name")
+	assert.NoError(t, err)
+	assert.Equal(t, jsonPath{
+		{field: "user", index: -1},
+		{field: "plans", index: -1},
+		{index: 1},
+		{field: "name", index: -1},
+	}, path)
+
+	root, err := parsePath("$")
+	assert.NoError(t, err)
+	assert.Empty(t, root)
+
+	for _, invalid := range []string{"", "user", "$.", "$..user", "$[", "$[x]", "$[-1]", "$user"} {
+		_, err := parsePath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestJSONPathFind(t *testing.T) {
+	document := []byte(`{"user":{"plan":"pro","seats":3,"tags":["a","b"],"trial":null}}`)
+	for path, expect := range map[string]any{
+		"$.user.plan":    "pro",
+		"$.user.seats":   3.0,
+		"$.user.tags[1]": "b",
+		"$.user.trial":   nil,
+	} {
+		p, _ := parsePath(path)
+		value, ok := p.Find(document)
+		assert.True(t, ok, path)
+		assert.Equal(t, expect, value, path)
+	}
+
+	for _, path := range []string{"$.user.missing", "$.user.tags[2]", "$.user[0]", "$.user.plan.name", "$.user.tags.a"} {
+		p, _ := parsePath(path)
+		_, ok := p.Find(document)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestJSONColumn(t *testing.T) {
+	for _, indexed := range [][]string{nil, {"$.user.plan", "$.user.seats"}} {
+		c := NewCollection()
+		c.CreateColumn("payload", ForJSON(indexed...))
+		c.InsertObject(Object{"payload": `{"user":{"plan":"pro","seats":3}}`})
+		c.InsertObject(Object{"payload": []byte(`{"user":{"plan":"free","seats":1}}`)})
+		c.InsertObject(Object{"payload": map[string]any{"user": map[string]any{"plan": "pro", "seats": 10}}})
+		c.InsertObject(Object{"payload": json.RawMessage(`{"user":{}}`)})
+		c.InsertObject(Object{})
+
+		assert.Equal(t, 2, countPath(c, "$.user.plan", "pro"))
+		assert.Equal(t, 1, countPath(c, "$.user.plan", "free"))
+		assert.Equal(t, 1, countPath(c, "$.user.seats", 10.0))
+		assert.Equal(t, 0, countPath(c, "$.user.plan.name", "pro"))
+		assert.Equal(t, 0, countPath(c, "invalid", "pro"))
+
+		// The indexes are kept up to date as the documents are written
+		assert.NoError(t, c.Query(func(txn *Txn) error {
+			payload := txn.JSON("payload")
+			return txn.Range(func(idx uint32) {
+				switch idx {
+				case 0:
+					payload.Set(map[string]any{"user": map[string]any{"plan": "free"}})
+				case 2:
+					txn.Any("payload").Set(nil)
+				case 4:
+					payload.Set(`{"user":{"plan":"pro"}}`)
+				}
+			})
+		}))
+
+		assert.Equal(t, 1, countPath(c, "$.user.plan", "pro"))
+		assert.Equal(t, 2, countPath(c, "$.user.plan", "free"))
+		assert.Equal(t, 0, countPath(c, "$.user.seats", 10.0))
+		assert.NoError(t, c.QueryAt(4, func(r Row) error {
+			document, ok := r.JSON("payload")
+			assert.True(t, ok)
+			assert.JSONEq(t, `{"user":{"plan":"pro"}}`, string(document))
+			return nil
+		}))
+
+		// The invalid documents are rejected
+		assert.Panics(t, func() {
+			c.InsertObject(Object{"payload": `{"user":`})
+		})
+		assert.Panics(t, func() {
+			c.InsertObject(Object{"payload": func() {}})
+		})
+	}
+}
+
+func TestJSONColumnClone(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("payload", ForJSON("$.plan"))
+	c.InsertObject(Object{"payload": `{"plan":"pro"}`})
+	assert.Equal(t, 1, countPath(c, "$.plan", "pro"))
+
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, clone.QueryAt(0, func(r Row) error {
+		r.SetJSON("payload", `{"plan":"free"}`)
+		return nil
+	}))
+
+	assert.Equal(t, 1, countPath(c, "$.plan", "pro"))
+	assert.Equal(t, 0, countPath(clone, "$.plan", "pro"))
+	assert.Equal(t, 1, countPath(clone, "$.plan", "free"))
+
+	// The invalid paths are reported when the column is created
+	assert.Error(t, c.CreateColumn("invalid", ForJSON("plan")))
+	_, exists := c.cols.Load("invalid")
+	assert.False(t, exists)
+}
+
+// countPath counts the rows whose document has the value at the path
+func countPath(c *Collection, path string, value any) (count int) {
+	c.Query(func(txn *Txn) error {
+		count = txn.WithJSONPath("payload", path, func(v interface{}) bool {
+			return v == value
+		}).Count()
+		return nil
+	})
+	return
+}
@@ -23,37 +23,67 @@ type columnKey struct {
 // makeKey creates a new primary key column
 func makeKey() Column {
 	return &columnKey{
-		seek: make(map[string]uint32, 64),
-		columnString: columnString{
-			chunks: make(chunks[string], 0, 4),
-		},
+		seek:         make(map[string]uint32, 64),
+		columnString: *makeStrings().(*columnString),
 	}
 }
 
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnKey) MakeEmpty() Column {
+	return makeKey()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnKey) Clone() Column {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	seek := make(map[string]uint32, len(c.seek))
+	for k, v := range c.seek {
+		seek[k] = v
+	}
+
+	return &columnKey{
+		name:         c.name,
+		seek:         seek,
+		columnString: *c.columnString.Clone().(*columnString),
+	}
+}
+
+// SizeOf returns the approximate memory used by a chunk, including the lookup table
+func (c *columnKey) SizeOf(chunk commit.Chunk) int {
+	size := c.columnString.SizeOf(chunk)
+	if int(chunk) < len(c.data.chunks) {
+		s := &c.data.chunks[chunk]
+		s.fill.Range(func(x uint32) {
+			size += 20 + int(s.length[x]) // The key header, the offset and the copy of the key
+		})
+	}
+	return size
+}
+
 // Apply applies a set of operations to the column.
 func (c *columnKey) Apply(chunk commit.Chunk, r *commit.Reader) {
-	fill, data := c.chunkAt(chunk)
-	from := chunk.Min()
+	s := c.data.chunkFor(chunk)
+	garbage, size := s.garbage, len(s.arena)
 
+	c.lock.Lock()
 	for r.Next() {
-		offset := r.Offset - int32(from)
+		offset := r.IndexAtChunk()
 		switch r.Type {
 		case commit.Put:
-			value := string(r.Bytes())
-
-			fill[offset>>6] |= 1 << (offset & 0x3f)
-			data[offset] = value
-			c.lock.Lock()
-			c.seek[value] = uint32(r.Offset)
-			c.lock.Unlock()
-
+			c.seek[string(r.Bytes())] = uint32(r.Offset)
 		case commit.Delete:
-			fill.Remove(uint32(offset))
-			c.lock.Lock()
-			delete(c.seek, string(data[offset]))
-			c.lock.Unlock()
+			if s.fill.Contains(offset) {
+				delete(c.seek, s.stringAt(offset))
+			}
 		}
+
+		s.apply(r)
 	}
+
+	c.lock.Unlock()
+	c.data.track(s, garbage, size)
 }
 
 // OffsetOf returns the offset for a particular value
@@ -8,6 +8,7 @@ import (
 	"github.com/kelindar/column/commit"
 )
 
+
 // --------------------------- Int ----------------------------
 
 // makeInts creates a new vector for ints
@@ -26,6 +27,9 @@ func makeInts() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToInt(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToInt(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -50,6 +54,23 @@ func (s intWriter) Add(delta int) {
 	s.writer.AddInt(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of int instead of overflowing
+func (s intWriter) AddSaturate(delta int) {
+	s.writer.AddSaturateInt(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow int, in which case ErrOverflow is returned instead
+func (s intWriter) AddChecked(delta int) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateInt(s.txn.cursor, delta)
+	return nil
+}
+
 // Int returns a read-write accessor for int column
 func (txn *Txn) Int(columnName string) intWriter {
 	return intWriter{
@@ -58,6 +79,25 @@ func (txn *Txn) Int(columnName string) intWriter {
 	}
 }
 
+// RangeInt iterates over the int values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeInt(columnName string, fn func(idx uint32, v int)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyInt updates every int value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyInt(columnName string, fn func(v int) int) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Ints invokes the callback with a read-only view of the raw int values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Ints(columnName string, fn func(offset uint32, values []int, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Int16 ----------------------------
 
@@ -77,6 +117,9 @@ func makeInt16s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToInt16(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToInt16(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -101,6 +144,23 @@ func (s int16Writer) Add(delta int16) {
 	s.writer.AddInt16(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of int16 instead of overflowing
+func (s int16Writer) AddSaturate(delta int16) {
+	s.writer.AddSaturateInt16(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow int16, in which case ErrOverflow is returned instead
+func (s int16Writer) AddChecked(delta int16) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateInt16(s.txn.cursor, delta)
+	return nil
+}
+
 // Int16 returns a read-write accessor for int16 column
 func (txn *Txn) Int16(columnName string) int16Writer {
 	return int16Writer{
@@ -109,6 +169,25 @@ func (txn *Txn) Int16(columnName string) int16Writer {
 	}
 }
 
+// RangeInt16 iterates over the int16 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeInt16(columnName string, fn func(idx uint32, v int16)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyInt16 updates every int16 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyInt16(columnName string, fn func(v int16) int16) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Int16s invokes the callback with a read-only view of the raw int16 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Int16s(columnName string, fn func(offset uint32, values []int16, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Int32 ----------------------------
 
@@ -128,6 +207,9 @@ func makeInt32s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToInt32(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToInt32(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -152,6 +234,23 @@ func (s int32Writer) Add(delta int32) {
 	s.writer.AddInt32(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of int32 instead of overflowing
+func (s int32Writer) AddSaturate(delta int32) {
+	s.writer.AddSaturateInt32(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow int32, in which case ErrOverflow is returned instead
+func (s int32Writer) AddChecked(delta int32) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateInt32(s.txn.cursor, delta)
+	return nil
+}
+
 // Int32 returns a read-write accessor for int32 column
 func (txn *Txn) Int32(columnName string) int32Writer {
 	return int32Writer{
@@ -160,6 +259,25 @@ func (txn *Txn) Int32(columnName string) int32Writer {
 	}
 }
 
+// RangeInt32 iterates over the int32 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeInt32(columnName string, fn func(idx uint32, v int32)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyInt32 updates every int32 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyInt32(columnName string, fn func(v int32) int32) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Int32s invokes the callback with a read-only view of the raw int32 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Int32s(columnName string, fn func(offset uint32, values []int32, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Int64 ----------------------------
 
@@ -179,6 +297,9 @@ func makeInt64s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToInt64(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToInt64(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -203,6 +324,23 @@ func (s int64Writer) Add(delta int64) {
 	s.writer.AddInt64(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of int64 instead of overflowing
+func (s int64Writer) AddSaturate(delta int64) {
+	s.writer.AddSaturateInt64(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow int64, in which case ErrOverflow is returned instead
+func (s int64Writer) AddChecked(delta int64) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateInt64(s.txn.cursor, delta)
+	return nil
+}
+
 // Int64 returns a read-write accessor for int64 column
 func (txn *Txn) Int64(columnName string) int64Writer {
 	return int64Writer{
@@ -211,6 +349,25 @@ func (txn *Txn) Int64(columnName string) int64Writer {
 	}
 }
 
+// RangeInt64 iterates over the int64 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeInt64(columnName string, fn func(idx uint32, v int64)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyInt64 updates every int64 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyInt64(columnName string, fn func(v int64) int64) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Int64s invokes the callback with a read-only view of the raw int64 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Int64s(columnName string, fn func(offset uint32, values []int64, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Uint ----------------------------
 
@@ -230,6 +387,9 @@ func makeUints() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToUint(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToUint(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -254,6 +414,23 @@ func (s uintWriter) Add(delta uint) {
 	s.writer.AddUint(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of uint instead of overflowing
+func (s uintWriter) AddSaturate(delta uint) {
+	s.writer.AddSaturateUint(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow uint, in which case ErrOverflow is returned instead
+func (s uintWriter) AddChecked(delta uint) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateUint(s.txn.cursor, delta)
+	return nil
+}
+
 // Uint returns a read-write accessor for uint column
 func (txn *Txn) Uint(columnName string) uintWriter {
 	return uintWriter{
@@ -262,6 +439,25 @@ func (txn *Txn) Uint(columnName string) uintWriter {
 	}
 }
 
+// RangeUint iterates over the uint values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeUint(columnName string, fn func(idx uint32, v uint)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyUint updates every uint value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyUint(columnName string, fn func(v uint) uint) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Uints invokes the callback with a read-only view of the raw uint values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Uints(columnName string, fn func(offset uint32, values []uint, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Uint16 ----------------------------
 
@@ -281,6 +477,9 @@ func makeUint16s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToUint16(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToUint16(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -305,6 +504,23 @@ func (s uint16Writer) Add(delta uint16) {
 	s.writer.AddUint16(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of uint16 instead of overflowing
+func (s uint16Writer) AddSaturate(delta uint16) {
+	s.writer.AddSaturateUint16(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow uint16, in which case ErrOverflow is returned instead
+func (s uint16Writer) AddChecked(delta uint16) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateUint16(s.txn.cursor, delta)
+	return nil
+}
+
 // Uint16 returns a read-write accessor for uint16 column
 func (txn *Txn) Uint16(columnName string) uint16Writer {
 	return uint16Writer{
@@ -313,6 +529,25 @@ func (txn *Txn) Uint16(columnName string) uint16Writer {
 	}
 }
 
+// RangeUint16 iterates over the uint16 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeUint16(columnName string, fn func(idx uint32, v uint16)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyUint16 updates every uint16 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyUint16(columnName string, fn func(v uint16) uint16) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Uint16s invokes the callback with a read-only view of the raw uint16 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Uint16s(columnName string, fn func(offset uint32, values []uint16, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Uint32 ----------------------------
 
@@ -332,6 +567,9 @@ func makeUint32s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToUint32(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToUint32(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -356,6 +594,23 @@ func (s uint32Writer) Add(delta uint32) {
 	s.writer.AddUint32(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of uint32 instead of overflowing
+func (s uint32Writer) AddSaturate(delta uint32) {
+	s.writer.AddSaturateUint32(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow uint32, in which case ErrOverflow is returned instead
+func (s uint32Writer) AddChecked(delta uint32) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateUint32(s.txn.cursor, delta)
+	return nil
+}
+
 // Uint32 returns a read-write accessor for uint32 column
 func (txn *Txn) Uint32(columnName string) uint32Writer {
 	return uint32Writer{
@@ -364,6 +619,25 @@ func (txn *Txn) Uint32(columnName string) uint32Writer {
 	}
 }
 
+// RangeUint32 iterates over the uint32 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeUint32(columnName string, fn func(idx uint32, v uint32)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyUint32 updates every uint32 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyUint32(columnName string, fn func(v uint32) uint32) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Uint32s invokes the callback with a read-only view of the raw uint32 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Uint32s(columnName string, fn func(offset uint32, values []uint32, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Uint64 ----------------------------
 
@@ -383,6 +657,9 @@ func makeUint64s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToUint64(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToUint64(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -407,6 +684,23 @@ func (s uint64Writer) Add(delta uint64) {
 	s.writer.AddUint64(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of uint64 instead of overflowing
+func (s uint64Writer) AddSaturate(delta uint64) {
+	s.writer.AddSaturateUint64(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow uint64, in which case ErrOverflow is returned instead
+func (s uint64Writer) AddChecked(delta uint64) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateUint64(s.txn.cursor, delta)
+	return nil
+}
+
 // Uint64 returns a read-write accessor for uint64 column
 func (txn *Txn) Uint64(columnName string) uint64Writer {
 	return uint64Writer{
@@ -415,6 +709,25 @@ func (txn *Txn) Uint64(columnName string) uint64Writer {
 	}
 }
 
+// RangeUint64 iterates over the uint64 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeUint64(columnName string, fn func(idx uint32, v uint64)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyUint64 updates every uint64 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyUint64(columnName string, fn func(v uint64) uint64) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Uint64s invokes the callback with a read-only view of the raw uint64 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Uint64s(columnName string, fn func(offset uint32, values []uint64, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Float32 ----------------------------
 
@@ -434,6 +747,9 @@ func makeFloat32s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToFloat32(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToFloat32(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -458,6 +774,23 @@ func (s float32Writer) Add(delta float32) {
 	s.writer.AddFloat32(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of float32 instead of overflowing
+func (s float32Writer) AddSaturate(delta float32) {
+	s.writer.AddSaturateFloat32(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow float32, in which case ErrOverflow is returned instead
+func (s float32Writer) AddChecked(delta float32) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateFloat32(s.txn.cursor, delta)
+	return nil
+}
+
 // Float32 returns a read-write accessor for float32 column
 func (txn *Txn) Float32(columnName string) float32Writer {
 	return float32Writer{
@@ -466,6 +799,25 @@ func (txn *Txn) Float32(columnName string) float32Writer {
 	}
 }
 
+// RangeFloat32 iterates over the float32 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeFloat32(columnName string, fn func(idx uint32, v float32)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyFloat32 updates every float32 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyFloat32(columnName string, fn func(v float32) float32) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Float32s invokes the callback with a read-only view of the raw float32 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Float32s(columnName string, fn func(offset uint32, values []float32, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
 
 // --------------------------- Float64 ----------------------------
 
@@ -485,6 +837,9 @@ func makeFloat64s() Column {
 				case commit.Add:
 					fill[offset>>6] |= 1 << (offset & 0x3f)
 					data[offset] = r.AddToFloat64(data[offset])
+				case commit.Saturate:
+					fill[offset>>6] |= 1 << (offset & 0x3f)
+					data[offset] = r.AddSaturateToFloat64(data[offset])
 				case commit.Delete:
 					fill.Remove(offset)
 				}
@@ -509,6 +864,23 @@ func (s float64Writer) Add(delta float64) {
 	s.writer.AddFloat64(s.txn.cursor, delta)
 }
 
+// AddSaturate atomically adds a delta to the value at the current transaction cursor, clamping
+// the result to the range of float64 instead of overflowing
+func (s float64Writer) AddSaturate(delta float64) {
+	s.writer.AddSaturateFloat64(s.txn.cursor, delta)
+}
+
+// AddChecked atomically adds a delta to the value at the current transaction cursor, unless
+// the result would overflow float64, in which case ErrOverflow is returned instead
+func (s float64Writer) AddChecked(delta float64) error {
+	if current, _ := s.Get(); overflows(current, delta) {
+		return ErrOverflow
+	}
+
+	s.writer.AddSaturateFloat64(s.txn.cursor, delta)
+	return nil
+}
+
 // Float64 returns a read-write accessor for float64 column
 func (txn *Txn) Float64(columnName string) float64Writer {
 	return float64Writer{
@@ -516,3 +888,23 @@ func (txn *Txn) Float64(columnName string) float64Writer {
 		writer:        txn.bufferFor(columnName),
 	}
 }
+
+// RangeFloat64 iterates over the float64 values of the column for the rows selected by this
+// transaction, reading directly from the typed column storage without any boxing.
+func (txn *Txn) RangeFloat64(columnName string, fn func(idx uint32, v float64)) error {
+	return rangeNumbers(txn, columnName, fn)
+}
+
+// ApplyFloat64 updates every float64 value of the column for the rows selected by this
+// transaction, by applying the function on the current value in a single pass.
+func (txn *Txn) ApplyFloat64(columnName string, fn func(v float64) float64) error {
+	return applyNumbers(txn, columnName, fn)
+}
+
+// Float64s invokes the callback with a read-only view of the raw float64 values of the column
+// and its fill list, chunk by chunk, for custom vectorized computations. The views are not
+// copied, so they must not be modified nor retained once the callback returns.
+func (c *Collection) Float64s(columnName string, fn func(offset uint32, values []float64, fill bitmap.Bitmap)) error {
+	return viewNumbers(c, columnName, fn)
+}
+
@@ -1,208 +1,684 @@
-package column
-
-import (
-	"fmt"
-
-	"github.com/kelindar/bitmap"
-	"github.com/kelindar/column/commit"
-	"github.com/kelindar/simd"
-)
-
-// readNumber is a helper function for point reads
-func readNumber[T simd.Number](txn *Txn, columnName string) (value T, found bool) {
-	if column, ok := txn.columnAt(columnName); ok {
-		if rdr, ok := column.Column.(*numericColumn[T]); ok {
-			value, found = rdr.load(txn.cursor)
-		}
-	}
-	return
-}
-
-// --------------------------- Generic Column ----------------------------
-
-// numericColumn represents a numeric column
-type numericColumn[T simd.Number] struct {
-	chunks[T]
-	write func(*commit.Buffer, uint32, T)
-	apply func(*commit.Reader, bitmap.Bitmap, []T)
-}
-
-// makeNumeric creates a new vector for simd.Numbers
-func makeNumeric[T simd.Number](
-	write func(*commit.Buffer, uint32, T),
-	apply func(*commit.Reader, bitmap.Bitmap, []T),
-) *numericColumn[T] {
-	return &numericColumn[T]{
-		chunks: make(chunks[T], 0, 4),
-		write:  write,
-		apply:  apply,
-	}
-}
-
-// --------------------------- Accessors ----------------------------
-
-// Contains checks whether the column has a value at a specified index.
-func (c *numericColumn[T]) Contains(idx uint32) bool {
-	chunk := commit.ChunkAt(idx)
-	return c.chunks[chunk].fill.Contains(idx - chunk.Min())
-}
-
-// load retrieves a float64 value at a specified index
-func (c *numericColumn[T]) load(idx uint32) (v T, ok bool) {
-	chunk := commit.ChunkAt(idx)
-	index := idx - chunk.Min()
-	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
-		v, ok = c.chunks[chunk].data[index], true
-	}
-	return
-}
-
-// Value retrieves a value at a specified index
-func (c *numericColumn[T]) Value(idx uint32) (any, bool) {
-	return c.load(idx)
-}
-
-// LoadFloat64 retrieves a float64 value at a specified index
-func (c *numericColumn[T]) LoadFloat64(idx uint32) (float64, bool) {
-	v, ok := c.load(idx)
-	return float64(v), ok
-}
-
-// LoadInt64 retrieves an int64 value at a specified index
-func (c *numericColumn[T]) LoadInt64(idx uint32) (int64, bool) {
-	v, ok := c.load(idx)
-	return int64(v), ok
-}
-
-// LoadUint64 retrieves an uint64 value at a specified index
-func (c *numericColumn[T]) LoadUint64(idx uint32) (uint64, bool) {
-	v, ok := c.load(idx)
-	return uint64(v), ok
-}
-
-// --------------------------- Filtering ----------------------------
-
-// filterNumbers filters down the values based on the specified predicate.
-func filterNumbers[T, C simd.Number](column *numericColumn[T], chunk commit.Chunk, index bitmap.Bitmap, predicate func(C) bool) {
-	if int(chunk) < len(column.chunks) {
-		fill, data := column.chunkAt(chunk)
-		index.And(fill)
-		index.Filter(func(idx uint32) bool {
-			return predicate(C(data[idx]))
-		})
-	}
-}
-
-// FilterFloat64 filters down the values based on the specified predicate.
-func (c *numericColumn[T]) FilterFloat64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(float64) bool) {
-	filterNumbers(c, chunk, index, predicate)
-}
-
-// FilterInt64 filters down the values based on the specified predicate.
-func (c *numericColumn[T]) FilterInt64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(int64) bool) {
-	filterNumbers(c, chunk, index, predicate)
-}
-
-// FilterUint64 filters down the values based on the specified predicate.
-func (c *numericColumn[T]) FilterUint64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(uint64) bool) {
-	filterNumbers(c, chunk, index, predicate)
-}
-
-// --------------------------- Apply & Snapshot ----------------------------
-
-// Apply applies a set of operations to the column.
-func (c *numericColumn[T]) Apply(chunk commit.Chunk, r *commit.Reader) {
-	fill, data := c.chunkAt(chunk)
-	c.apply(r, fill, data)
-}
-
-// Snapshot writes the entire column into the specified destination buffer
-func (c *numericColumn[T]) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
-	fill, data := c.chunkAt(chunk)
-	fill.Range(func(x uint32) {
-		c.write(dst, chunk.Min()+x, data[x])
-	})
-}
-
-// --------------------------- Reader/Writer ----------------------------
-
-// numericReader represents a read-only accessor for simd.Numbers
-type numericReader[T simd.Number] struct {
-	reader *numericColumn[T]
-	txn    *Txn
-}
-
-// Get loads the value at the current transaction cursor
-func (s numericReader[T]) Get() (T, bool) {
-	return s.reader.load(s.txn.cursor)
-}
-
-// Sum computes a sum of the column values selected by this transaction
-func (s numericReader[T]) Sum() (sum T) {
-	s.txn.initialize()
-	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
-		if int(chunk) < len(s.reader.chunks) {
-			sum += bitmap.Sum(s.reader.chunks[chunk].data, index)
-		}
-	})
-	return sum
-}
-
-// Avg computes an arithmetic mean of the column values selected by this transaction
-func (s numericReader[T]) Avg() float64 {
-	sum, ct := T(0), 0
-	s.txn.initialize()
-	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
-		if int(chunk) < len(s.reader.chunks) {
-			sum += bitmap.Sum(s.reader.chunks[chunk].data, index)
-			ct += index.Count()
-		}
-	})
-	return float64(sum) / float64(ct)
-}
-
-// Min finds the smallest value from the column values selected by this transaction
-func (s numericReader[T]) Min() (min T, ok bool) {
-	s.txn.initialize()
-	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
-		if int(chunk) < len(s.reader.chunks) {
-			if v, hit := bitmap.Min(s.reader.chunks[chunk].data, index); hit && (v < min || !ok) {
-				min = v
-				ok = true
-			}
-		}
-	})
-	return
-}
-
-// Max finds the largest value from the column values selected by this transaction
-func (s numericReader[T]) Max() (max T, ok bool) {
-	s.txn.initialize()
-	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
-		if int(chunk) < len(s.reader.chunks) {
-			if v, hit := bitmap.Max(s.reader.chunks[chunk].data, index); hit && (v > max || !ok) {
-				max = v
-				ok = true
-			}
-		}
-	})
-	return
-}
-
-// numericReaderFor creates a new numeric reader
-func numericReaderFor[T simd.Number](txn *Txn, columnName string) numericReader[T] {
-	column, ok := txn.columnAt(columnName)
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
-	}
-
-	reader, ok := column.Column.(*numericColumn[T])
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' is not of type %T", columnName, float64(0)))
-	}
-
-	return numericReader[T]{
-		reader: reader,
-		txn:    txn,
-	}
-}
+package column
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"unsafe"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/simd"
+)
+
+var (
+	// ErrOverflow is returned when a checked addition would overflow the type of the column.
+	ErrOverflow = errors.New("column: numeric overflow")
+)
+
+// readNumber is a helper function for point reads
+func readNumber[T simd.Number](txn *Txn, columnName string) (value T, found bool) {
+	if column, ok := txn.columnAt(columnName); ok {
+		switch rdr := column.Column.(type) {
+		case *numericColumn[T]:
+			value, found = rdr.load(txn.cursor)
+		case Numeric:
+			v, ok := rdr.LoadFloat64(txn.cursor)
+			value, found = T(v), ok
+		}
+	}
+	return
+}
+
+// --------------------------- Generic Column ----------------------------
+
+// numericColumn represents a numeric column
+type numericColumn[T simd.Number] struct {
+	chunks[T]
+	write func(*commit.Buffer, uint32, T)
+	apply func(*commit.Reader, bitmap.Bitmap, []T)
+	file  *mappedFile // The file backing the values, if the column is memory-mapped
+	alloc Allocator   // The allocator of the values, if they are not on the Go heap
+}
+
+// valueKind returns the kind of the values stored in the column
+func (c *numericColumn[T]) valueKind() reflect.Kind {
+	var zero T
+	return reflect.TypeOf(zero).Kind()
+}
+
+// makeNumeric creates a new vector for simd.Numbers
+func makeNumeric[T simd.Number](
+	write func(*commit.Buffer, uint32, T),
+	apply func(*commit.Reader, bitmap.Bitmap, []T),
+) *numericColumn[T] {
+	return &numericColumn[T]{
+		chunks: make(chunks[T], 0, 4),
+		write:  write,
+		apply:  apply,
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *numericColumn[T]) MakeEmpty() Column {
+	return makeNumeric(c.write, c.apply)
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified. Since the
+// values of a memory-mapped column are modified in place and the ones allocated by an allocator
+// are freed explicitly, they are copied right away instead.
+func (c *numericColumn[T]) Clone() Column {
+	if c.file == nil && c.alloc == nil {
+		return &numericColumn[T]{
+			chunks: c.chunks.share(),
+			write:  c.write,
+			apply:  c.apply,
+		}
+	}
+
+	clone := makeNumeric(c.write, c.apply)
+	clone.alloc = c.alloc
+	for i := range c.chunks {
+		fill, data := c.chunkAt(commit.Chunk(i))
+		if data != nil {
+			data = append(clone.allocate()[:0], data...)
+		}
+
+		clone.chunks.push(fill.Clone(nil), data)
+	}
+	return clone
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *numericColumn[T]) Grow(idx uint32) {
+	switch {
+	case c.file != nil:
+		c.growMapped(idx)
+	case c.alloc != nil:
+		for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+			c.chunks.push(make(bitmap.Bitmap, chunkSize/64), c.allocate())
+		}
+	default:
+		c.chunks.Grow(idx)
+	}
+}
+
+// Release releases the data list of an empty chunk, unless it is memory-mapped
+func (c *numericColumn[T]) Release(chunk commit.Chunk) {
+	switch {
+	case c.file != nil || int(chunk) >= len(c.chunks):
+	case c.alloc != nil:
+		if err := c.release(c.chunks[chunk].data); err == nil {
+			c.chunks[chunk].data = nil
+		}
+	default:
+		c.chunks.Release(chunk)
+	}
+}
+
+// freeze compresses the values of a chunk with the codec, unless they are memory-mapped or
+// allocated by an allocator
+func (c *numericColumn[T]) freeze(chunk commit.Chunk, codec Codec) bool {
+	if c.file != nil || c.alloc != nil {
+		return false
+	}
+	return c.chunks.freeze(chunk, codec)
+}
+
+// --------------------------- Accessors ----------------------------
+
+// Contains checks whether the column has a value at a specified index.
+func (c *numericColumn[T]) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return c.chunks[chunk].fill.Contains(idx - chunk.Min())
+}
+
+// load retrieves a float64 value at a specified index
+func (c *numericColumn[T]) load(idx uint32) (v T, ok bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		v, ok = c.chunks[chunk].data[index], true
+	}
+	return
+}
+
+// Value retrieves a value at a specified index
+func (c *numericColumn[T]) Value(idx uint32) (any, bool) {
+	return c.load(idx)
+}
+
+// LoadFloat64 retrieves a float64 value at a specified index
+func (c *numericColumn[T]) LoadFloat64(idx uint32) (float64, bool) {
+	v, ok := c.load(idx)
+	return float64(v), ok
+}
+
+// LoadInt64 retrieves an int64 value at a specified index
+func (c *numericColumn[T]) LoadInt64(idx uint32) (int64, bool) {
+	v, ok := c.load(idx)
+	return int64(v), ok
+}
+
+// LoadUint64 retrieves an uint64 value at a specified index
+func (c *numericColumn[T]) LoadUint64(idx uint32) (uint64, bool) {
+	v, ok := c.load(idx)
+	return uint64(v), ok
+}
+
+// Encode writes a value into the buffer, converting it to the type of the column. The values
+// of the other numeric types are converted predictably: the fractions are truncated and the
+// values out of the range of the column type are clamped to the closest bound. A nil value
+// deletes the value and the values which are not numbers are rejected with a panic.
+func (c *numericColumn[T]) Encode(dst *commit.Buffer, idx uint32, value any) {
+	if value == nil {
+		dst.PutOperation(commit.Delete, idx)
+		return
+	}
+
+	v, ok := convertNumber[T](value)
+	if !ok {
+		panic(fmt.Errorf("column: unable to write %T into a column of %T", value, v))
+	}
+
+	c.write(dst, idx, v)
+}
+
+// --------------------------- Conversion ----------------------------
+
+// convertNumber converts a number of any type into T, truncating the fractions and clamping
+// the values to the range of T. It returns false if the value is not a number.
+func convertNumber[T simd.Number](value any) (T, bool) {
+	switch v := value.(type) {
+	case T:
+		return v, true
+	case int:
+		return fromInt64[T](int64(v)), true
+	case int8:
+		return fromInt64[T](int64(v)), true
+	case int16:
+		return fromInt64[T](int64(v)), true
+	case int32:
+		return fromInt64[T](int64(v)), true
+	case int64:
+		return fromInt64[T](v), true
+	case uint:
+		return fromUint64[T](uint64(v)), true
+	case uint8:
+		return fromUint64[T](uint64(v)), true
+	case uint16:
+		return fromUint64[T](uint64(v)), true
+	case uint32:
+		return fromUint64[T](uint64(v)), true
+	case uint64:
+		return fromUint64[T](v), true
+	case float32:
+		return fromFloat64[T](float64(v)), true
+	case float64:
+		return fromFloat64[T](v), true
+	default:
+		return 0, false
+	}
+}
+
+// numberType returns whether T is a floating-point type, whether it is signed and the maximum
+// value of T if it is an integer type.
+func numberType[T simd.Number]() (float, signed bool, max uint64) {
+	var zero T
+	size := uint64(unsafe.Sizeof(zero)) * 8
+	float, signed = T(1)/2 != 0, zero-1 < 0
+	max = math.MaxUint64 >> (64 - size)
+	if signed {
+		max >>= 1
+	}
+	return
+}
+
+// fromInt64 converts a signed integer into T, clamping it to the range of T
+func fromInt64[T simd.Number](v int64) T {
+	float, signed, max := numberType[T]()
+	switch {
+	case float:
+		return T(v)
+	case v < 0 && !signed:
+		return 0
+	case v < 0 && v < -int64(max)-1:
+		return T(-int64(max) - 1)
+	case v > 0 && uint64(v) > max:
+		return T(max)
+	default:
+		return T(v)
+	}
+}
+
+// fromUint64 converts an unsigned integer into T, clamping it to the range of T
+func fromUint64[T simd.Number](v uint64) T {
+	if float, _, max := numberType[T](); !float && v > max {
+		return T(max)
+	}
+	return T(v)
+}
+
+// fromFloat64 converts a floating-point number into T, truncating its fraction and clamping
+// it to the range of T. NaN is converted to zero for the integer types.
+func fromFloat64[T simd.Number](v float64) T {
+	float, signed, max := numberType[T]()
+	switch {
+	case float:
+		return T(v)
+	case math.IsNaN(v):
+		return 0
+	case v >= float64(max):
+		return T(max)
+	case signed && v <= -float64(max)-1:
+		return fromInt64[T](-int64(max) - 1)
+	case signed:
+		return T(int64(v))
+	case v <= 0:
+		return 0
+	default:
+		return T(uint64(v))
+	}
+}
+
+// overflows returns whether adding the delta to the value overflows the range of T. For the
+// floating-point types, this is whether a finite sum would become infinite.
+func overflows[T simd.Number](value, delta T) bool {
+	sum := value + delta
+	switch float, signed, _ := numberType[T](); {
+	case float:
+		return math.IsInf(float64(sum), 0) && !math.IsInf(float64(value), 0) && !math.IsInf(float64(delta), 0)
+	case signed:
+		return (delta > 0 && sum < value) || (delta < 0 && sum > value)
+	default:
+		return sum < value
+	}
+}
+
+// nextValue returns the value which the current operation of the reader stores, given the
+// value previously stored at its row (if any). This decodes the operation without applying it.
+func (c *numericColumn[T]) nextValue(r *commit.Reader, prev any) any {
+	var value T
+	switch v := any(&value).(type) {
+	case *int:
+		*v = r.Int()
+	case *int16:
+		*v = r.Int16()
+	case *int32:
+		*v = r.Int32()
+	case *int64:
+		*v = r.Int64()
+	case *uint:
+		*v = r.Uint()
+	case *uint16:
+		*v = r.Uint16()
+	case *uint32:
+		*v = r.Uint32()
+	case *uint64:
+		*v = r.Uint64()
+	case *float32:
+		*v = r.Float32()
+	case *float64:
+		*v = r.Float64()
+	}
+
+	current, _ := prev.(T)
+	switch {
+	case r.Type == commit.Saturate && overflows(current, value) && value > 0:
+		return fromFloat64[T](math.Inf(1))
+	case r.Type == commit.Saturate && overflows(current, value):
+		return fromFloat64[T](math.Inf(-1))
+	case r.Type == commit.Add || r.Type == commit.Saturate:
+		return current + value
+	default:
+		return value
+	}
+}
+
+// --------------------------- Filtering ----------------------------
+
+// filterNumbers filters down the values based on the specified predicate.
+func filterNumbers[T, C simd.Number](column *numericColumn[T], chunk commit.Chunk, index bitmap.Bitmap, predicate func(C) bool) {
+	if int(chunk) < len(column.chunks) {
+		fill, data := column.chunkAt(chunk)
+		index.And(fill)
+		index.Filter(func(idx uint32) bool {
+			return predicate(C(data[idx]))
+		})
+	}
+}
+
+// FilterFloat64 filters down the values based on the specified predicate.
+func (c *numericColumn[T]) FilterFloat64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(float64) bool) {
+	filterNumbers(c, chunk, index, predicate)
+}
+
+// FilterInt64 filters down the values based on the specified predicate.
+func (c *numericColumn[T]) FilterInt64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(int64) bool) {
+	filterNumbers(c, chunk, index, predicate)
+}
+
+// FilterUint64 filters down the values based on the specified predicate.
+func (c *numericColumn[T]) FilterUint64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(uint64) bool) {
+	filterNumbers(c, chunk, index, predicate)
+}
+
+// --------------------------- Comparison ----------------------------
+
+// comparison represents the operator of a comparison filter
+type comparison uint8
+
+// Operators of the comparison filters
+const (
+	compareEqual comparison = iota
+	compareLess
+	compareGreater
+	compareBetween
+)
+
+// comparer represents a column which is able to compare its values against constants by
+// scanning the raw values, without calling a predicate for each one of them. The upper
+// bound is only used by the range comparisons.
+type comparer interface {
+	compareFloat64(chunk commit.Chunk, index bitmap.Bitmap, op comparison, value, upper float64)
+	compareInt64(chunk commit.Chunk, index bitmap.Bitmap, op comparison, value, upper int64)
+}
+
+// compareFloat64 filters down the values which compare to the specified float64
+func (c *numericColumn[T]) compareFloat64(chunk commit.Chunk, index bitmap.Bitmap, op comparison, value, upper float64) {
+	compareNumbers(c, chunk, index, op, value, upper)
+}
+
+// compareInt64 filters down the values which compare to the specified int64
+func (c *numericColumn[T]) compareInt64(chunk commit.Chunk, index bitmap.Bitmap, op comparison, value, upper int64) {
+	compareNumbers(c, chunk, index, op, value, upper)
+}
+
+// compareNumbers filters down the values which compare to the specified value. Each word of
+// the index covers 64 rows, hence the corresponding 64 values are compared in a branch-free
+// loop and packed into a mask, which is then applied onto the word.
+func compareNumbers[T, C simd.Number](column *numericColumn[T], chunk commit.Chunk, index bitmap.Bitmap, op comparison, value, upper C) {
+	if int(chunk) >= len(column.chunks) {
+		index.Clear()
+		return
+	}
+
+	fill, data := column.chunkAt(chunk)
+	for blkAt, blk := range index {
+		if blk &= fill[blkAt]; blk != 0 {
+			values := data[blkAt<<6 : blkAt<<6+64]
+			switch op {
+			case compareEqual:
+				blk &= equalMask(values, value)
+			case compareLess:
+				blk &= lessMask(values, value)
+			case compareGreater:
+				blk &= greaterMask(values, value)
+			case compareBetween:
+				blk &= betweenMask(values, value, upper)
+			}
+		}
+		index[blkAt] = blk
+	}
+}
+
+// equalMask returns a mask of the values which are equal to the specified one
+func equalMask[T, C simd.Number](values []T, value C) uint64 {
+	var flags [64]byte
+	values = values[:64]
+	for i := range flags {
+		flags[i] = b2b(C(values[i]) == value)
+	}
+	return pack(&flags)
+}
+
+// lessMask returns a mask of the values which are less than the specified one
+func lessMask[T, C simd.Number](values []T, value C) uint64 {
+	var flags [64]byte
+	values = values[:64]
+	for i := range flags {
+		flags[i] = b2b(C(values[i]) < value)
+	}
+	return pack(&flags)
+}
+
+// greaterMask returns a mask of the values which are greater than the specified one
+func greaterMask[T, C simd.Number](values []T, value C) uint64 {
+	var flags [64]byte
+	values = values[:64]
+	for i := range flags {
+		flags[i] = b2b(C(values[i]) > value)
+	}
+	return pack(&flags)
+}
+
+// betweenMask returns a mask of the values which are within the specified bounds, inclusive
+func betweenMask[T, C simd.Number](values []T, lower, upper C) uint64 {
+	var flags [64]byte
+	values = values[:64]
+	for i := range flags {
+		v := C(values[i])
+		flags[i] = b2b(v >= lower) & b2b(v <= upper)
+	}
+	return pack(&flags)
+}
+
+// b2b converts a boolean into a byte, which the compiler does without branching
+func b2b(b bool) (v byte) {
+	if b {
+		v = 1
+	}
+	return
+}
+
+// pack packs 64 flags, each being either 0 or 1, into the bits of a mask. Each group of 8
+// flags is read as a single word and multiplied so that all of its flags are gathered in
+// the top byte of the product.
+func pack(flags *[64]byte) (mask uint64) {
+	for i := 0; i < 64; i += 8 {
+		mask |= (binary.LittleEndian.Uint64(flags[i:]) * 0x0102040810204080 >> 56) << i
+	}
+	return
+}
+
+// matches evaluates a comparison of two values, for the columns which are not comparers
+func matches[C simd.Number](op comparison, v, value, upper C) bool {
+	switch op {
+	case compareEqual:
+		return v == value
+	case compareLess:
+		return v < value
+	case compareGreater:
+		return v > value
+	default:
+		return v >= value && v <= upper
+	}
+}
+
+// numericColumnOf loads a numeric column of a specific type for the transaction
+func numericColumnOf[T simd.Number](txn *Txn, columnName string) (*numericColumn[T], error) {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		return nil, fmt.Errorf("column: column '%s' does not exist", columnName)
+	}
+
+	reader, ok := column.Column.(*numericColumn[T])
+	if !ok {
+		return nil, fmt.Errorf("column: column '%s' is not of type %T", columnName, T(0))
+	}
+	return reader, nil
+}
+
+// rangeNumbers iterates over the values of a numeric column for the rows selected by the
+// transaction, skipping the rows which do not have a value in the column.
+func rangeNumbers[T simd.Number](txn *Txn, columnName string, fn func(idx uint32, v T)) error {
+	reader, err := numericColumnOf[T](txn, columnName)
+	if err != nil {
+		return err
+	}
+
+	txn.resolve()
+	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) >= len(reader.chunks) {
+			return
+		}
+
+		fill, data := reader.chunkAt(chunk)
+		offset := chunk.Min()
+		for blkAt, blk := range index {
+			blk &= fill[blkAt]
+			for blk != 0 {
+				x := uint32(blkAt<<6 + bits.TrailingZeros64(blk))
+				txn.cursor = offset + x
+				fn(offset+x, data[x])
+				blk &= blk - 1
+			}
+		}
+	})
+	return txn.ctx.Err()
+}
+
+// applyNumbers updates the values of a numeric column for the rows selected by the
+// transaction, by applying the function on the current value in a single pass.
+func applyNumbers[T simd.Number](txn *Txn, columnName string, fn func(v T) T) error {
+	reader, err := numericColumnOf[T](txn, columnName)
+	if err != nil {
+		return err
+	}
+
+	buffer := txn.bufferFor(columnName)
+	return rangeNumbers(txn, columnName, func(idx uint32, v T) {
+		reader.write(buffer, idx, fn(v))
+	})
+}
+
+// viewNumbers invokes the callback with the values of a numeric column and their fill list,
+// chunk by chunk, while each chunk is read-locked. The values are not copied.
+func viewNumbers[T simd.Number](c *Collection, columnName string, fn func(offset uint32, values []T, fill bitmap.Bitmap)) error {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return fmt.Errorf("column: column '%s' does not exist", columnName)
+	}
+
+	reader, ok := column.Column.(*numericColumn[T])
+	if !ok {
+		return fmt.Errorf("column: column '%s' is not of type %T", columnName, T(0))
+	}
+
+	for chunk, n := commit.Chunk(0), commit.Chunk(c.chunks()); chunk < n; chunk++ {
+		c.readChunk(chunk, func(_ uint64, chunk commit.Chunk, _ bitmap.Bitmap) error {
+			if int(chunk) < len(reader.chunks) && reader.chunks[chunk].data != nil {
+				fill, data := reader.chunkAt(chunk)
+				fn(chunk.Min(), data, fill)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// --------------------------- Apply & Snapshot ----------------------------
+
+// Apply applies a set of operations to the column.
+func (c *numericColumn[T]) Apply(chunk commit.Chunk, r *commit.Reader) {
+	if c.alloc != nil && c.chunks[chunk].data == nil {
+		c.chunks[chunk].data = c.allocate()
+	}
+
+	fill, data := c.chunkFor(chunk)
+	c.apply(r, fill, data)
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *numericColumn[T]) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	fill, data := c.chunkAt(chunk)
+	fill.Range(func(x uint32) {
+		c.write(dst, chunk.Min()+x, data[x])
+	})
+}
+
+// --------------------------- Reader/Writer ----------------------------
+
+// numericReader represents a read-only accessor for simd.Numbers
+type numericReader[T simd.Number] struct {
+	reader *numericColumn[T]
+	txn    *Txn
+}
+
+// Get loads the value at the current transaction cursor
+func (s numericReader[T]) Get() (T, bool) {
+	return s.reader.load(s.txn.cursor)
+}
+
+// Sum computes a sum of the column values selected by this transaction
+func (s numericReader[T]) Sum() (sum T) {
+	s.txn.resolve()
+	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) < len(s.reader.chunks) {
+			sum += bitmap.Sum(s.reader.chunks[chunk].data, index)
+		}
+	})
+	return sum
+}
+
+// Avg computes an arithmetic mean of the column values selected by this transaction
+func (s numericReader[T]) Avg() float64 {
+	sum, ct := T(0), 0
+	s.txn.resolve()
+	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) < len(s.reader.chunks) {
+			sum += bitmap.Sum(s.reader.chunks[chunk].data, index)
+			ct += index.Count()
+		}
+	})
+	return float64(sum) / float64(ct)
+}
+
+// Min finds the smallest value from the column values selected by this transaction
+func (s numericReader[T]) Min() (min T, ok bool) {
+	s.txn.resolve()
+	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) < len(s.reader.chunks) {
+			if v, hit := bitmap.Min(s.reader.chunks[chunk].data, index); hit && (v < min || !ok) {
+				min = v
+				ok = true
+			}
+		}
+	})
+	return
+}
+
+// Max finds the largest value from the column values selected by this transaction
+func (s numericReader[T]) Max() (max T, ok bool) {
+	s.txn.resolve()
+	s.txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if int(chunk) < len(s.reader.chunks) {
+			if v, hit := bitmap.Max(s.reader.chunks[chunk].data, index); hit && (v > max || !ok) {
+				max = v
+				ok = true
+			}
+		}
+	})
+	return
+}
+
+// numericReaderFor creates a new numeric reader
+func numericReaderFor[T simd.Number](txn *Txn, columnName string) numericReader[T] {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*numericColumn[T])
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type %T", columnName, float64(0)))
+	}
+
+	return numericReader[T]{
+		reader: reader,
+		txn:    txn,
+	}
+}
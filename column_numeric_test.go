@@ -0,0 +1,166 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericCoercion(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("int16", ForInt16())
+	c.CreateColumn("int64", ForInt64())
+	c.CreateColumn("uint32", ForUint32())
+	c.CreateColumn("float32", ForFloat32())
+
+	// Values of other numeric types are converted, truncated and clamped
+	idx := c.InsertObject(Object{
+		"int16":   100000,
+		"int64":   1.7,
+		"uint32":  -5,
+		"float32": int64(42),
+	})
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		i16, _ := r.Int16("int16")
+		i64, _ := r.Int64("int64")
+		u32, _ := r.Uint32("uint32")
+		f32, _ := r.Float32("float32")
+		assert.Equal(t, int16(math.MaxInt16), i16)
+		assert.Equal(t, int64(1), i64)
+		assert.Equal(t, uint32(0), u32)
+		assert.Equal(t, float32(42), f32)
+		return nil
+	}))
+
+	// The values set through the untyped accessors are converted as well
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		r.SetAny("int16", float64(-1e9))
+		r.SetAny("int64", uint64(math.MaxUint64))
+		r.SetAny("uint32", int8(7))
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		i16, _ := r.Int16("int16")
+		i64, _ := r.Int64("int64")
+		u32, _ := r.Uint32("uint32")
+		assert.Equal(t, int16(math.MinInt16), i16)
+		assert.Equal(t, int64(math.MaxInt64), i64)
+		assert.Equal(t, uint32(7), u32)
+		return nil
+	}))
+
+	// The values which are not numbers are rejected
+	assert.Panics(t, func() {
+		c.QueryAt(idx, func(r Row) error {
+			r.SetAny("int64", "42")
+			return nil
+		})
+	})
+}
+
+func TestNumericCoercionColumns(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("int16", ForInt16())
+	c.CreateColumn("float64", ForFloat64())
+
+	_, err := c.InsertColumns(map[string]any{
+		"int16":   []int{1, 70000},
+		"float64": []float64{1.5, 2.5},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 4.0, txn.Float64("float64").Sum())
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(1, func(r Row) error {
+		v, _ := r.Int16("int16")
+		assert.Equal(t, int16(math.MaxInt16), v)
+		return nil
+	}))
+}
+
+func TestConvertNumber(t *testing.T) {
+	for _, tc := range []struct {
+		value  any
+		int16  int16
+		uint16 uint16
+		int64  int64
+		uint64 uint64
+	}{
+		{value: 42, int16: 42, uint16: 42, int64: 42, uint64: 42},
+		{value: -42, int16: -42, uint16: 0, int64: -42, uint64: 0},
+		{value: 1e20, int16: math.MaxInt16, uint16: math.MaxUint16, int64: math.MaxInt64, uint64: math.MaxUint64},
+		{value: -1e20, int16: math.MinInt16, uint16: 0, int64: math.MinInt64, uint64: 0},
+		{value: math.NaN(), int16: 0, uint16: 0, int64: 0, uint64: 0},
+		{value: -2.9, int16: -2, uint16: 0, int64: -2, uint64: 0},
+		{value: uint64(math.MaxUint64), int16: math.MaxInt16, uint16: math.MaxUint16, int64: math.MaxInt64, uint64: math.MaxUint64},
+		{value: int64(math.MinInt64), int16: math.MinInt16, uint16: 0, int64: math.MinInt64, uint64: 0},
+	} {
+		i16, ok := convertNumber[int16](tc.value)
+		assert.True(t, ok)
+		assert.Equal(t, tc.int16, i16, tc.value)
+		u16, _ := convertNumber[uint16](tc.value)
+		assert.Equal(t, tc.uint16, u16, tc.value)
+		i64, _ := convertNumber[int64](tc.value)
+		assert.Equal(t, tc.int64, i64, tc.value)
+		u64, _ := convertNumber[uint64](tc.value)
+		assert.Equal(t, tc.uint64, u64, tc.value)
+	}
+
+	_, ok := convertNumber[int]("42")
+	assert.False(t, ok)
+}
+
+func TestAddSaturate(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("score", ForInt16())
+	c.CreateColumn("count", ForUint32())
+	c.CreateIndex("high", "score", func(r Reader) bool {
+		return r.Int() == math.MaxInt16
+	})
+	idx := c.InsertObject(Object{"score": int16(math.MaxInt16 - 10), "count": uint32(5)})
+
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		r.txn.Int16("score").AddSaturate(100)
+		r.txn.Uint32("count").AddSaturate(math.MaxUint32)
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		score, _ := r.Int16("score")
+		count, _ := r.Uint32("count")
+		assert.Equal(t, int16(math.MaxInt16), score)
+		assert.Equal(t, uint32(math.MaxUint32), count)
+		return nil
+	}))
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.With("high").Count())
+		return nil
+	}))
+}
+
+func TestAddChecked(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("score", ForInt16())
+	c.CreateColumn("ratio", ForFloat64())
+	idx := c.InsertObject(Object{"score": int16(math.MaxInt16 - 10), "ratio": math.MaxFloat64})
+
+	assert.Equal(t, ErrOverflow, c.QueryAt(idx, func(r Row) error {
+		return r.txn.Int16("score").AddChecked(11)
+	}))
+	assert.Equal(t, ErrOverflow, c.QueryAt(idx, func(r Row) error {
+		return r.txn.Float64("ratio").AddChecked(math.MaxFloat64)
+	}))
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		return r.txn.Int16("score").AddChecked(10)
+	}))
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		score, _ := r.Int16("score")
+		assert.Equal(t, int16(math.MaxInt16), score)
+		return nil
+	}))
+}
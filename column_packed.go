@@ -0,0 +1,502 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"math/bits"
+	"reflect"
+	"sort"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// Compression represents the encoding of the values of a compressed integer column
+type Compression uint8
+
+// Various compressions of the integer columns
+const (
+	CompressAuto       Compression = iota // Picks the smallest encoding for every chunk
+	CompressBitPacking                    // Frame-of-reference with bit-packing
+	CompressRunLength                     // Run-length encoding
+)
+
+// String returns the name of the compression
+func (c Compression) String() string {
+	switch c {
+	case CompressAuto:
+		return "auto"
+	case CompressBitPacking:
+		return "bit-packing"
+	case CompressRunLength:
+		return "run-length"
+	default:
+		return fmt.Sprintf("compression(%d)", uint8(c))
+	}
+}
+
+// --------------------------- Compressed ----------------------------
+
+var _ Numeric = new(columnPacked)
+
+// columnPacked represents an int64 column, where the values of each chunk are compressed. With
+// bit-packing, each value is stored as its difference with the smallest value of the chunk, using
+// only as many bits as the largest difference needs. With run-length encoding, the repeated
+// values are stored once along with the number of repetitions.
+type columnPacked struct {
+	mode   Compression
+	chunks []packedChunk
+}
+
+// packedChunk represents a single chunk of the compressed column
+type packedChunk struct {
+	fill   bitmap.Bitmap // The fill-list
+	count  int           // The number of values, in the order of the fill-list
+	rle    bool          // Whether the values are run-length encoded
+	base   int64         // The frame of reference of the bit-packed values
+	width  int           // The number of bits of each bit-packed value
+	packed []uint64      // The bit-packed values
+	runs   []int64       // The value of each run
+	ends   []uint32      // The rank following the last value of each run
+	shared bool          // Whether the chunk is shared with a clone
+}
+
+// makeCompressed creates a new compressed int64 column
+func makeCompressed(compression Compression) Column {
+	return &columnPacked{
+		mode:   compression,
+		chunks: make([]packedChunk, 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnPacked) MakeEmpty() Column {
+	return makeCompressed(c.mode)
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnPacked) Clone() Column {
+	for i := range c.chunks {
+		c.chunks[i].shared = true
+	}
+
+	chunks := make([]packedChunk, len(c.chunks), cap(c.chunks))
+	copy(chunks, c.chunks)
+	return &columnPacked{
+		mode:   c.mode,
+		chunks: chunks,
+	}
+}
+
+// SizeOf returns the memory used by a chunk, in bytes
+func (c *columnPacked) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(c.chunks) {
+		return 0
+	}
+
+	s := &c.chunks[chunk]
+	return len(s.fill)*8 + cap(s.packed)*8 + cap(s.runs)*8 + cap(s.ends)*4
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnPacked) Grow(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		c.chunks = append(c.chunks, packedChunk{
+			fill: make(bitmap.Bitmap, chunkSize/64),
+		})
+	}
+}
+
+// Release releases the encoded values of an empty chunk
+func (c *columnPacked) Release(chunk commit.Chunk) {
+	if int(chunk) < len(c.chunks) {
+		c.chunks[chunk].encode(nil, c.mode)
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnPacked) Apply(chunk commit.Chunk, r *commit.Reader) {
+	s := &c.chunks[chunk]
+	if s.shared {
+		s.fill = s.fill.Clone(nil)
+		s.packed = append([]uint64(nil), s.packed...)
+		s.runs = append([]int64(nil), s.runs...)
+		s.ends = append([]uint32(nil), s.ends...)
+		s.shared = false
+	}
+
+	// If we only append new values at the end of the chunk which fit in the current encoding,
+	// we can simply keep on encoding without touching the values that are already there. The
+	// insertions of the rows are also applied to every column, but do not carry any value.
+	last, hasLast := s.fill.Max()
+	appendOnly := true
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch {
+		case r.Type == commit.Insert:
+			continue
+		case r.Type != commit.Put || (hasLast && offset <= last) || !s.fits(r.Int64(), c.mode):
+			appendOnly = false
+		}
+		if !appendOnly {
+			break
+		}
+		last, hasLast = offset, true
+	}
+
+	r.Rewind()
+	if appendOnly {
+		for r.Next() {
+			if r.Type == commit.Put {
+				s.fill.Set(r.IndexAtChunk())
+				s.append(r.Int64())
+			}
+		}
+		return
+	}
+
+	// Otherwise, decode the entire chunk, apply the operations and encode it back
+	values := make([]int64, chunkSize)
+	s.Range(func(x uint32, v int64) {
+		values[x] = v
+	})
+
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			s.fill.Set(offset)
+			values[offset] = r.Int64()
+		case commit.Add:
+			s.fill.Set(offset)
+			values[offset] += r.Int64()
+		case commit.Delete:
+			s.fill.Remove(offset)
+		}
+	}
+
+	dense := make([]int64, 0, s.fill.Count())
+	s.fill.Range(func(x uint32) {
+		dense = append(dense, values[x])
+	})
+	s.encode(dense, c.mode)
+}
+
+// Encode writes a value into the buffer, converting it to an int64 as the numeric columns do.
+// A nil value deletes the value and the values which are not numbers are rejected with a panic.
+func (c *columnPacked) Encode(dst *commit.Buffer, idx uint32, value any) {
+	if value == nil {
+		dst.PutOperation(commit.Delete, idx)
+		return
+	}
+
+	v, ok := convertNumber[int64](value)
+	if !ok {
+		panic(fmt.Errorf("column: unable to write %T into a column of int64", value))
+	}
+
+	dst.PutInt64(idx, v)
+}
+
+// valueKind returns the kind of the values stored in the column
+func (c *columnPacked) valueKind() reflect.Kind {
+	return reflect.Int64
+}
+
+// Value retrieves a value at a specified index
+func (c *columnPacked) Value(idx uint32) (interface{}, bool) {
+	return c.LoadInt64(idx)
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnPacked) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Index returns the fill list for the column
+func (c *columnPacked) Index(chunk commit.Chunk) bitmap.Bitmap {
+	if int(chunk) < len(c.chunks) {
+		return c.chunks[chunk].fill
+	}
+	return nil
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnPacked) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	offset := chunk.Min()
+	c.chunks[chunk].Range(func(x uint32, v int64) {
+		dst.PutInt64(offset+x, v)
+	})
+}
+
+// LoadInt64 retrieves an int64 value at a specified index. The bit-packed values are read
+// directly, while the run containing the value is searched for with run-length encoding.
+func (c *columnPacked) LoadInt64(idx uint32) (int64, bool) {
+	if !c.Contains(idx) {
+		return 0, false
+	}
+
+	chunk := commit.ChunkAt(idx)
+	s := &c.chunks[chunk]
+	return s.at(s.fill.CountTo(idx - chunk.Min())), true
+}
+
+// LoadFloat64 retrieves a float64 value at a specified index
+func (c *columnPacked) LoadFloat64(idx uint32) (float64, bool) {
+	v, ok := c.LoadInt64(idx)
+	return float64(v), ok
+}
+
+// LoadUint64 retrieves an uint64 value at a specified index
+func (c *columnPacked) LoadUint64(idx uint32) (uint64, bool) {
+	v, ok := c.LoadInt64(idx)
+	return uint64(v), ok
+}
+
+// FilterInt64 filters down the values based on the specified predicate. With run-length
+// encoding, the predicate is only evaluated once per run.
+func (c *columnPacked) FilterInt64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(int64) bool) {
+	if int(chunk) >= len(c.chunks) {
+		return
+	}
+
+	s := &c.chunks[chunk]
+	index.And(s.fill)
+
+	var last int64
+	var match, matched bool
+	s.Range(func(x uint32, v int64) {
+		if !matched || v != last {
+			last, match, matched = v, predicate(v), true
+		}
+
+		if !match {
+			index[x>>6] &^= 1 << (x & 0x3f)
+		}
+	})
+}
+
+// FilterFloat64 filters down the values based on the specified predicate.
+func (c *columnPacked) FilterFloat64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(float64) bool) {
+	c.FilterInt64(chunk, index, func(v int64) bool {
+		return predicate(float64(v))
+	})
+}
+
+// FilterUint64 filters down the values based on the specified predicate.
+func (c *columnPacked) FilterUint64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(uint64) bool) {
+	c.FilterInt64(chunk, index, func(v int64) bool {
+		return predicate(uint64(v))
+	})
+}
+
+// --------------------------- Encoding ----------------------------
+
+// encode replaces the values of the chunk with the specified ones, in the order of the
+// fill-list. With automatic compression, the smallest of the two encodings is picked.
+func (s *packedChunk) encode(values []int64, mode Compression) {
+	s.count = len(values)
+	s.packed, s.runs, s.ends = nil, nil, nil
+	if len(values) == 0 {
+		s.rle, s.base, s.width = mode == CompressRunLength, 0, 0
+		return
+	}
+
+	lo, hi, runs := values[0], values[0], 1
+	for i, v := range values {
+		switch {
+		case v < lo:
+			lo = v
+		case v > hi:
+			hi = v
+		}
+		if i > 0 && v != values[i-1] {
+			runs++
+		}
+	}
+
+	width := bits.Len64(uint64(hi) - uint64(lo))
+	switch mode {
+	case CompressRunLength:
+		s.rle = true
+	case CompressBitPacking:
+		s.rle = false
+	default:
+		s.rle = runs*12 < (len(values)*width+63)/64*8
+	}
+
+	if s.rle {
+		s.runs = make([]int64, 0, runs)
+		s.ends = make([]uint32, 0, runs)
+		for _, v := range values {
+			s.appendRun(v)
+		}
+		return
+	}
+
+	s.base, s.width = lo, width
+	s.packed = make([]uint64, (len(values)*width+63)/64)
+	for i, v := range values {
+		s.pack(i, uint64(v)-uint64(lo))
+	}
+}
+
+// fits returns whether a value can be appended at the end of the chunk without changing its
+// encoding. With automatic compression, new runs are only added while the average length of
+// the runs remains above two values, after which the encoding needs to be picked again.
+func (s *packedChunk) fits(v int64, mode Compression) bool {
+	switch {
+	case s.count == 0:
+		return false
+	case s.rle:
+		return mode != CompressAuto || v == s.runs[len(s.runs)-1] || len(s.runs)*2 < s.count
+	default:
+		return v >= s.base && (s.width == 64 || uint64(v)-uint64(s.base) < 1<<s.width)
+	}
+}
+
+// append appends a value at the end of the chunk, which must fit in its encoding
+func (s *packedChunk) append(v int64) {
+	if s.rle {
+		s.count++
+		s.appendRun(v)
+		return
+	}
+
+	if size := (s.count + 1) * s.width; (size+63)/64 > len(s.packed) {
+		s.packed = append(s.packed, 0)
+	}
+
+	s.pack(s.count, uint64(v)-uint64(s.base))
+	s.count++
+}
+
+// appendRun extends the last run with a value, or starts a new run if the value differs
+func (s *packedChunk) appendRun(v int64) {
+	if n := len(s.runs); n > 0 && s.runs[n-1] == v {
+		s.ends[n-1]++
+		return
+	}
+
+	end := uint32(1)
+	if n := len(s.ends); n > 0 {
+		end = s.ends[n-1] + 1
+	}
+
+	s.runs = append(s.runs, v)
+	s.ends = append(s.ends, end)
+}
+
+// pack writes the bit-packed delta at the specified rank
+func (s *packedChunk) pack(rank int, delta uint64) {
+	if s.width == 0 {
+		return
+	}
+
+	head := rank * s.width
+	word, shift := head>>6, head&63
+	s.packed[word] |= delta << shift
+	if shift+s.width > 64 {
+		s.packed[word+1] |= delta >> (64 - shift)
+	}
+}
+
+// at returns the value at the specified rank of the chunk
+func (s *packedChunk) at(rank int) int64 {
+	if s.rle {
+		i := sort.Search(len(s.ends), func(i int) bool {
+			return int(s.ends[i]) > rank
+		})
+		return s.runs[i]
+	}
+
+	if s.width == 0 {
+		return s.base
+	}
+
+	head := rank * s.width
+	word, shift := head>>6, head&63
+	delta := s.packed[word] >> shift
+	if shift+s.width > 64 {
+		delta |= s.packed[word+1] << (64 - shift)
+	}
+
+	return int64(uint64(s.base) + delta&(1<<s.width-1))
+}
+
+// Range sequentially decodes the values of the chunk, along with their offsets.
+func (s *packedChunk) Range(fn func(x uint32, v int64)) {
+	rank, run := 0, 0
+	s.fill.Range(func(x uint32) {
+		if !s.rle {
+			fn(x, s.at(rank))
+			rank++
+			return
+		}
+
+		for uint32(rank) >= s.ends[run] {
+			run++
+		}
+
+		fn(x, s.runs[run])
+		rank++
+	})
+}
+
+// --------------------------- Accessor ----------------------------
+
+// packedReader represents a read-only accessor for compressed values
+type packedReader struct {
+	cursor *uint32
+	reader *columnPacked
+}
+
+// Get loads the value at the current transaction cursor
+func (s packedReader) Get() (int64, bool) {
+	return s.reader.LoadInt64(*s.cursor)
+}
+
+// packedReaderFor creates a new compressed column reader
+func packedReaderFor(txn *Txn, columnName string) packedReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnPacked)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type compressed", columnName))
+	}
+
+	return packedReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// packedWriter represents read-write accessor for compressed values
+type packedWriter struct {
+	packedReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s packedWriter) Set(value int64) {
+	s.writer.PutInt64(*s.cursor, value)
+}
+
+// Add atomically adds a delta to the value at the current transaction cursor
+func (s packedWriter) Add(delta int64) {
+	s.writer.AddInt64(*s.cursor, delta)
+}
+
+// Compressed returns a compressed integer column accessor
+func (txn *Txn) Compressed(columnName string) packedWriter {
+	return packedWriter{
+		packedReader: packedReaderFor(txn, columnName),
+		writer:       txn.bufferFor(columnName),
+	}
+}
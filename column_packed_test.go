@@ -0,0 +1,146 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackedChunk(t *testing.T) {
+	inputs := [][]int64{
+		{},
+		{7},
+		{5, 5, 5, 5},
+		{-3, 0, 1000, -3, 12},
+		{math.MinInt64, math.MaxInt64, 0, -1},
+		{1, 1, 1, 2, 2, 3, 3, 3, 3, 1},
+	}
+
+	for _, mode := range []Compression{CompressAuto, CompressBitPacking, CompressRunLength} {
+		for _, input := range inputs {
+			s := packedChunk{}
+			s.encode(input, mode)
+			assert.Equal(t, len(input), s.count)
+			for i, v := range input {
+				assert.Equal(t, v, s.at(i), mode.String())
+			}
+		}
+	}
+
+	// Runs of equal values are smaller with run-length encoding
+	runs := make([]int64, 1000)
+	s := packedChunk{}
+	s.encode(runs, CompressAuto)
+	assert.False(t, s.rle)
+	assert.Equal(t, 0, s.width)
+
+	for i := range runs {
+		runs[i] = int64(i / 100 * 1000)
+	}
+	s.encode(runs, CompressAuto)
+	assert.True(t, s.rle)
+	assert.Len(t, s.runs, 10)
+}
+
+func TestCompressed(t *testing.T) {
+	for _, mode := range []Compression{CompressAuto, CompressBitPacking, CompressRunLength} {
+		col := NewCollection()
+		assert.NoError(t, col.CreateColumn("status", ForCompressed(mode)))
+		for i := 0; i < 40000; i++ {
+			col.InsertObject(Object{"status": 1000 + i/500})
+		}
+
+		// Values are decompressed transparently on read
+		assert.NoError(t, col.QueryAt(20999, func(r Row) error {
+			v, ok := r.txn.Compressed("status").Get()
+			assert.True(t, ok)
+			assert.Equal(t, int64(1041), v)
+
+			n, ok := r.Int64("status")
+			assert.True(t, ok)
+			assert.Equal(t, int64(1041), n)
+			return nil
+		}))
+
+		// Updates and deletes in the middle of the chunks re-encode them
+		assert.NoError(t, col.QueryAt(10, func(r Row) error {
+			r.txn.Compressed("status").Set(math.MaxInt64)
+			return nil
+		}))
+		assert.NoError(t, col.QueryAt(11, func(r Row) error {
+			r.txn.Compressed("status").Add(5)
+			return nil
+		}))
+		col.DeleteAt(12)
+
+		assert.NoError(t, col.Query(func(txn *Txn) error {
+			status := txn.Compressed("status")
+			for idx, expect := range map[uint32]int64{9: 1000, 10: math.MaxInt64, 11: 1005, 13: 1000} {
+				assert.NoError(t, txn.QueryAt(idx, func(r Row) error {
+					v, ok := status.Get()
+					assert.True(t, ok)
+					assert.Equal(t, expect, v, mode.String())
+					return nil
+				}))
+			}
+			return nil
+		}))
+
+		// Filters are evaluated on the decompressed values
+		assert.NoError(t, col.Query(func(txn *Txn) error {
+			assert.Equal(t, 500, txn.WithInt("status", func(v int64) bool {
+				return v == 1050
+			}).Count())
+			return nil
+		}))
+		assert.NoError(t, col.Query(func(txn *Txn) error {
+			assert.Equal(t, 1, txn.WithInt("status", func(v int64) bool {
+				return v == math.MaxInt64
+			}).Count())
+			return nil
+		}))
+	}
+}
+
+func TestCompressedSize(t *testing.T) {
+	plain := NewCollection()
+	plain.CreateColumn("v", ForInt64())
+	packed := NewCollection()
+	packed.CreateColumn("v", ForCompressed(CompressBitPacking))
+	for i := 0; i < 10000; i++ {
+		plain.InsertObject(Object{"v": i % 16})
+		packed.InsertObject(Object{"v": i % 16})
+	}
+
+	// Only 4 bits per value should be needed
+	plainCol, _ := plain.cols.Load("v")
+	packedCol, _ := packed.cols.Load("v")
+	assert.Less(t, packedCol.Column.(Sizer).SizeOf(0)*8, plainCol.Column.(Sizer).SizeOf(0))
+
+	column := packedCol.Column.(*columnPacked)
+	assert.Equal(t, 4, column.chunks[0].width)
+}
+
+func TestCompressedClone(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("v", ForCompressed(CompressAuto))
+	for i := 0; i < 100; i++ {
+		col.InsertObject(Object{"v": i})
+	}
+
+	column, _ := col.cols.Load("v")
+	clone := column.Column.(Cloner).Clone().(*columnPacked)
+	assert.NoError(t, col.QueryAt(5, func(r Row) error {
+		r.txn.Compressed("v").Set(-5)
+		return nil
+	}))
+
+	v, _ := clone.LoadInt64(5)
+	assert.Equal(t, int64(5), v)
+	v, _ = column.Column.(*columnPacked).LoadInt64(5)
+	assert.Equal(t, int64(-5), v)
+}
@@ -0,0 +1,479 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+const (
+	earthRadius = 6371008.8 // The mean radius of the Earth, in meters
+	cellSize    = 0.01      // The size of the cells of the spatial index, in degrees
+	cellCount   = 36000     // The number of cells around the Earth, along a parallel
+)
+
+// Point represents a geographic location, in degrees
+type Point struct {
+	Lat float64 // The latitude, between -90 and 90
+	Lon float64 // The longitude, between -180 and 180
+}
+
+// Rect represents a geographic bounding box, from its south-west corner to its north-east
+// corner. If the longitude of the south-west corner is greater than the one of the north-east
+// corner, the box crosses the antimeridian.
+type Rect struct {
+	Min Point // The south-west corner
+	Max Point // The north-east corner
+}
+
+// Contains returns whether the point is within the bounding box, edges included
+func (r Rect) Contains(p Point) bool {
+	if p.Lat < r.Min.Lat || p.Lat > r.Max.Lat {
+		return false
+	}
+
+	if r.Min.Lon <= r.Max.Lon {
+		return p.Lon >= r.Min.Lon && p.Lon <= r.Max.Lon
+	}
+	return p.Lon >= r.Min.Lon || p.Lon <= r.Max.Lon
+}
+
+// split splits the bounding box at the antimeridian, if it crosses it
+func (r Rect) split() []Rect {
+	if r.Min.Lon <= r.Max.Lon {
+		return []Rect{r}
+	}
+
+	return []Rect{
+		{Min: r.Min, Max: Point{Lat: r.Max.Lat, Lon: 180}},
+		{Min: Point{Lat: r.Min.Lat, Lon: -180}, Max: r.Max},
+	}
+}
+
+// distance returns the great-circle distance between two points, in meters
+func distance(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat, dLon := lat2-lat1, (b.Lon-a.Lon)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// boundsOf returns the bounding box of a circle, which may cross the antimeridian
+func boundsOf(center Point, meters float64) Rect {
+	dLat := meters / earthRadius * 180 / math.Pi
+	minLat, maxLat := center.Lat-dLat, center.Lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return Rect{ // The circle contains a pole
+			Min: Point{Lat: math.Max(minLat, -90), Lon: -180},
+			Max: Point{Lat: math.Min(maxLat, 90), Lon: 180},
+		}
+	}
+
+	// Compute the extent of the longitudes at the latitude of the center
+	dLon := math.Asin(math.Min(1, math.Sin(dLat*math.Pi/180)/math.Cos(center.Lat*math.Pi/180))) * 180 / math.Pi
+	if dLon >= 180 {
+		return Rect{Min: Point{Lat: minLat, Lon: -180}, Max: Point{Lat: maxLat, Lon: 180}}
+	}
+
+	return Rect{
+		Min: Point{Lat: minLat, Lon: wrapLon(center.Lon - dLon)},
+		Max: Point{Lat: maxLat, Lon: wrapLon(center.Lon + dLon)},
+	}
+}
+
+// wrapLon wraps a longitude into the range between -180 and 180
+func wrapLon(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	default:
+		return lon
+	}
+}
+
+// --------------------------- Spatial Cells ----------------------------
+
+// cellOf returns the cell of the spatial index which contains the point
+func cellOf(p Point) uint32 {
+	row, col := cellAt(p.Lat+90, cellCount/2), cellAt(p.Lon+180, cellCount)
+	return row<<16 | col
+}
+
+// cellAt returns the position of the cell along an axis, clamped to the number of cells
+func cellAt(degrees float64, count uint32) uint32 {
+	switch x := degrees / cellSize; {
+	case x <= 0 || math.IsNaN(x):
+		return 0
+	case x >= float64(count):
+		return count - 1
+	default:
+		return uint32(x)
+	}
+}
+
+// cellRange represents a range of the cells of the spatial index, bounds included
+type cellRange struct {
+	row0, row1 uint32
+	col0, col1 uint32
+}
+
+// cellsOf returns the ranges of the cells covering the bounding box
+func cellsOf(rect Rect) (ranges []cellRange, count int) {
+	for _, r := range rect.split() {
+		if !(r.Min.Lat <= r.Max.Lat) {
+			continue // Empty, or not a number
+		}
+
+		min, max := cellOf(r.Min), cellOf(r.Max)
+		cells := cellRange{
+			row0: min >> 16, row1: max >> 16,
+			col0: min & 0xffff, col1: max & 0xffff,
+		}
+
+		ranges = append(ranges, cells)
+		count += int(cells.row1-cells.row0+1) * int(cells.col1-cells.col0+1)
+	}
+	return
+}
+
+// --------------------------- Point Column ----------------------------
+
+// columnPoint represents a column of geographic locations, indexed in a grid of cells so that
+// the rows within a bounding box or a radius can be found without scanning every value.
+type columnPoint struct {
+	chunks []pointChunk
+}
+
+// pointChunk represents a single chunk of the point column
+type pointChunk struct {
+	fill   bitmap.Bitmap            // The fill-list
+	data   []Point                  // The actual values
+	cells  map[uint32]bitmap.Bitmap // The rows of the chunk, by cell of the spatial index
+	shared bool                     // Whether the chunk is shared with a clone
+}
+
+// makePoints creates a new column of geographic locations
+func makePoints() Column {
+	return &columnPoint{
+		chunks: make([]pointChunk, 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnPoint) MakeEmpty() Column {
+	return makePoints()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnPoint) Clone() Column {
+	for i := range c.chunks {
+		c.chunks[i].shared = true
+	}
+
+	chunks := make([]pointChunk, len(c.chunks), cap(c.chunks))
+	copy(chunks, c.chunks)
+	return &columnPoint{
+		chunks: chunks,
+	}
+}
+
+// SizeOf returns the approximate memory used by a chunk, including its spatial index
+func (c *columnPoint) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(c.chunks) {
+		return 0
+	}
+
+	s := &c.chunks[chunk]
+	size := len(s.fill)*8 + cap(s.data)*16
+	for _, rows := range s.cells {
+		size += 4 + len(rows)*8
+	}
+	return size
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnPoint) Grow(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		c.chunks = append(c.chunks, pointChunk{
+			fill: make(bitmap.Bitmap, chunkSize/64),
+		})
+	}
+}
+
+// Release releases the values and the spatial index of an empty chunk
+func (c *columnPoint) Release(chunk commit.Chunk) {
+	if int(chunk) < len(c.chunks) {
+		c.chunks[chunk].data = nil
+		c.chunks[chunk].cells = nil
+	}
+}
+
+// chunkFor loads a chunk for writing, allocating its values if they were previously released
+// or copying the chunk if it is shared.
+func (c *columnPoint) chunkFor(chunk commit.Chunk) *pointChunk {
+	s := &c.chunks[chunk]
+	switch {
+	case s.data == nil:
+		s.data = make([]Point, chunkSize)
+		s.cells = make(map[uint32]bitmap.Bitmap, 16)
+	case s.shared:
+		s.data = append(make([]Point, 0, chunkSize), s.data...)
+		cells := make(map[uint32]bitmap.Bitmap, len(s.cells))
+		for cell, rows := range s.cells {
+			cells[cell] = rows.Clone(nil)
+		}
+		s.cells = cells
+	}
+
+	if s.shared {
+		s.fill = s.fill.Clone(nil)
+		s.shared = false
+	}
+	return s
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnPoint) Apply(chunk commit.Chunk, r *commit.Reader) {
+	s := c.chunkFor(chunk)
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			if s.fill.Contains(offset) {
+				s.unindex(offset)
+			}
+
+			value := decodePoint(r.Bytes())
+			cell := cellOf(value)
+			rows := s.cells[cell]
+			rows.Set(offset)
+			s.cells[cell] = rows
+			s.fill.Set(offset)
+			s.data[offset] = value
+		case commit.Delete:
+			if s.fill.Contains(offset) {
+				s.unindex(offset)
+				s.fill.Remove(offset)
+			}
+		}
+	}
+}
+
+// unindex removes a row from the cell of its current value
+func (s *pointChunk) unindex(offset uint32) {
+	cell := cellOf(s.data[offset])
+	rows := s.cells[cell]
+	rows.Remove(offset)
+	if rows.Count() == 0 {
+		delete(s.cells, cell)
+	}
+}
+
+// Value retrieves a value at a specified index
+func (c *columnPoint) Value(idx uint32) (interface{}, bool) {
+	return c.LoadPoint(idx)
+}
+
+// LoadPoint retrieves a point at a specified index
+func (c *columnPoint) LoadPoint(idx uint32) (Point, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		return c.chunks[chunk].data[index], true
+	}
+	return Point{}, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnPoint) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Index returns the fill list for the column
+func (c *columnPoint) Index(chunk commit.Chunk) bitmap.Bitmap {
+	if int(chunk) < len(c.chunks) {
+		return c.chunks[chunk].fill
+	}
+	return nil
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnPoint) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	var buffer [16]byte
+	s := &c.chunks[chunk]
+	s.fill.Range(func(x uint32) {
+		dst.PutBytes(commit.Put, chunk.Min()+x, encodePoint(buffer[:], s.data[x]))
+	})
+}
+
+// Encode writes a point into the commit buffer. A nil value deletes the point and the values
+// of other types are rejected with a panic.
+func (c *columnPoint) Encode(dst *commit.Buffer, idx uint32, value any) {
+	switch v := value.(type) {
+	case nil:
+		dst.PutOperation(commit.Delete, idx)
+	case Point:
+		var buffer [16]byte
+		dst.PutBytes(commit.Put, idx, encodePoint(buffer[:], v))
+	default:
+		panic(fmt.Errorf("column: unable to write %T into a point column", value))
+	}
+}
+
+// filter filters down the rows whose point is within the bounding box and satisfies the
+// predicate. The candidates are first found in the cells covering the bounding box.
+func (c *columnPoint) filter(chunk commit.Chunk, index bitmap.Bitmap, rect Rect, predicate func(Point) bool) {
+	if int(chunk) >= len(c.chunks) || c.chunks[chunk].data == nil {
+		index.Clear()
+		return
+	}
+
+	// Either look up the covering cells, or go through the cells of the chunk if fewer
+	s := &c.chunks[chunk]
+	ranges, count := cellsOf(rect)
+	candidates := make(bitmap.Bitmap, chunkSize/64)
+	if count <= len(s.cells) {
+		for _, r := range ranges {
+			for row := r.row0; row <= r.row1; row++ {
+				for col := r.col0; col <= r.col1; col++ {
+					if rows, ok := s.cells[row<<16|col]; ok {
+						candidates.Or(rows)
+					}
+				}
+			}
+		}
+	} else {
+		for cell, rows := range s.cells {
+			row, col := cell>>16, cell&0xffff
+			for _, r := range ranges {
+				if row >= r.row0 && row <= r.row1 && col >= r.col0 && col <= r.col1 {
+					candidates.Or(rows)
+					break
+				}
+			}
+		}
+	}
+
+	index.And(candidates)
+	index.Filter(func(x uint32) bool {
+		return predicate(s.data[x])
+	})
+}
+
+// encodePoint encodes a point into the buffer of 16 bytes
+func encodePoint(dst []byte, p Point) []byte {
+	binary.BigEndian.PutUint64(dst[0:8], math.Float64bits(p.Lat))
+	binary.BigEndian.PutUint64(dst[8:16], math.Float64bits(p.Lon))
+	return dst[:16]
+}
+
+// decodePoint decodes a point previously encoded with encodePoint
+func decodePoint(b []byte) Point {
+	if len(b) < 16 {
+		return Point{}
+	}
+
+	return Point{
+		Lat: math.Float64frombits(binary.BigEndian.Uint64(b[0:8])),
+		Lon: math.Float64frombits(binary.BigEndian.Uint64(b[8:16])),
+	}
+}
+
+// --------------------------- Filters ----------------------------
+
+// WithinRadius filters down the rows whose point, in the specified column, is within the
+// distance in meters from the center, following the great-circle distance. The rows are
+// looked up in the spatial index of the column, so only the values near the center are read.
+func (txn *Txn) WithinRadius(columnName string, lat, lon, meters float64) *Txn {
+	center := Point{Lat: lat, Lon: lon}
+	return txn.withinRect("WithinRadius", columnName, boundsOf(center, meters), func(p Point) bool {
+		return distance(center, p) <= meters
+	})
+}
+
+// WithinBox filters down the rows whose point, in the specified column, is within the
+// bounding box, edges included. The rows are looked up in the spatial index of the column.
+func (txn *Txn) WithinBox(columnName string, rect Rect) *Txn {
+	return txn.withinRect("WithinBox", columnName, rect, rect.Contains)
+}
+
+// withinRect filters down the rows whose point is within the bounding box and satisfies
+// the predicate
+func (txn *Txn) withinRect(op, columnName string, rect Rect, predicate func(Point) bool) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(columnName)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	points, ok := c.Column.(*columnPoint)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, op, columnName, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		points.filter(chunk, index, rect, predicate)
+	})
+	return txn
+}
+
+// --------------------------- Accessors ----------------------------
+
+// pointReader represents a read-only accessor for points
+type pointReader struct {
+	cursor *uint32
+	reader *columnPoint
+}
+
+// Get loads the value at the current transaction cursor
+func (s pointReader) Get() (Point, bool) {
+	return s.reader.LoadPoint(*s.cursor)
+}
+
+// pointReaderFor creates a new point reader
+func pointReaderFor(txn *Txn, columnName string) pointReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnPoint)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type point", columnName))
+	}
+
+	return pointReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// pointWriter represents read-write accessor for points
+type pointWriter struct {
+	pointReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s pointWriter) Set(value Point) {
+	s.reader.Encode(s.writer, *s.cursor, value)
+}
+
+// Point returns a point column accessor
+func (txn *Txn) Point(columnName string) pointWriter {
+	return pointWriter{
+		pointReader: pointReaderFor(txn, columnName),
+		writer:      txn.bufferFor(columnName),
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointColumn(t *testing.T) {
+	c := newVehicles()
+	assert.Equal(t, []string{"eiffel", "louvre"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 5000)
+	}))
+	assert.Equal(t, []string{"big-ben", "eiffel", "louvre"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 350000)
+	}))
+	assert.Equal(t, []string{"big-ben"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinBox("position", Rect{
+			Min: Point{Lat: 51, Lon: -1},
+			Max: Point{Lat: 52, Lon: 1},
+		})
+	}))
+
+	// The bounding boxes and the circles may cross the antimeridian
+	assert.Equal(t, []string{"fiji", "samoa"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinBox("position", Rect{
+			Min: Point{Lat: -20, Lon: 170},
+			Max: Point{Lat: -10, Lon: -170},
+		})
+	}))
+	assert.Equal(t, []string{"fiji"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", -17, -179.5, 300000)
+	}))
+
+	// Moving a vehicle updates the spatial index
+	assert.NoError(t, c.QueryKey("louvre", func(r Row) error {
+		r.SetPoint("position", Point{Lat: 51.5007, Lon: -0.1246})
+		return nil
+	}))
+	assert.Equal(t, []string{"eiffel"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 5000)
+	}))
+	assert.Equal(t, []string{"big-ben", "louvre"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 51.5, -0.12, 1000)
+	}))
+
+	// Deleting the point or the row removes it from the spatial index
+	assert.NoError(t, c.QueryKey("big-ben", func(r Row) error {
+		r.SetAny("position", nil)
+		return nil
+	}))
+	idx, _ := c.pk.OffsetOf("louvre")
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		txn.DeleteAt(idx)
+		return nil
+	}))
+	assert.Empty(t, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 51.5, -0.12, 1000)
+	}))
+
+	// Filters on columns which are missing, or are not points, match nothing
+	assert.Empty(t, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("missing", 0, 0, 1e7)
+	}))
+	assert.Empty(t, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("name", 0, 0, 1e7)
+	}))
+}
+
+func TestPointAccessors(t *testing.T) {
+	c := newVehicles()
+	assert.NoError(t, c.QueryKey("eiffel", func(r Row) error {
+		p, ok := r.Point("position")
+		assert.True(t, ok)
+		assert.Equal(t, Point{Lat: 48.8584, Lon: 2.2945}, p)
+
+		v, ok := r.Any("position")
+		assert.True(t, ok)
+		assert.Equal(t, p, v)
+		return nil
+	}))
+
+	assert.Panics(t, func() {
+		c.Insert(func(r Row) error {
+			r.SetAny("position", "48.8584,2.2945")
+			return nil
+		})
+	})
+}
+
+func TestPointCloneAndSnapshot(t *testing.T) {
+	c := newVehicles()
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+
+	// Moving a vehicle in the clone does not affect the original collection
+	assert.NoError(t, clone.QueryKey("eiffel", func(r Row) error {
+		r.SetPoint("position", Point{Lat: 0, Lon: 0})
+		return nil
+	}))
+	assert.Equal(t, []string{"eiffel", "louvre"}, namesOf(c, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 5000)
+	}))
+	assert.Equal(t, []string{"louvre"}, namesOf(clone, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 5000)
+	}))
+
+	// The points and their spatial index are restored from a snapshot
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, c.Snapshot(buffer))
+	other := NewCollection()
+	other.CreateColumn("name", ForKey())
+	other.CreateColumn("position", ForPoint())
+	assert.NoError(t, other.Restore(buffer))
+	assert.Equal(t, []string{"eiffel", "louvre"}, namesOf(other, func(txn *Txn) *Txn {
+		return txn.WithinRadius("position", 48.8566, 2.3522, 5000)
+	}))
+}
+
+func TestDistance(t *testing.T) {
+	paris, london := Point{Lat: 48.8566, Lon: 2.3522}, Point{Lat: 51.5074, Lon: -0.1278}
+	assert.InDelta(t, 343500, distance(paris, london), 1000)
+	assert.Equal(t, 0.0, distance(paris, paris))
+
+	// The bounding box of a circle around a pole spans all of the longitudes
+	rect := boundsOf(Point{Lat: 89.9, Lon: 10}, 50000)
+	assert.Equal(t, -180.0, rect.Min.Lon)
+	assert.Equal(t, 180.0, rect.Max.Lon)
+	assert.Equal(t, 90.0, rect.Max.Lat)
+}
+
+// newVehicles creates a collection of vehicles, keyed by name
+func newVehicles() *Collection {
+	c := NewCollection()
+	c.CreateColumn("name", ForKey())
+	c.CreateColumn("position", ForPoint())
+	for name, position := range map[string]Point{
+		"eiffel":  {Lat: 48.8584, Lon: 2.2945},
+		"louvre":  {Lat: 48.8606, Lon: 2.3376},
+		"big-ben": {Lat: 51.5007, Lon: -0.1246},
+		"fiji":    {Lat: -17.7134, Lon: 178.0650},
+		"samoa":   {Lat: -13.7590, Lon: -172.1046},
+	} {
+		c.InsertObject(Object{
+			"name":     name,
+			"position": position,
+		})
+	}
+	return c
+}
+
+// namesOf returns the sorted names of the rows matching the filter
+func namesOf(c *Collection, filter func(txn *Txn) *Txn) (names []string) {
+	c.Query(func(txn *Txn) error {
+		name := txn.Key()
+		return filter(txn).Range(func(idx uint32) {
+			v, _ := name.Get()
+			names = append(names, v)
+		})
+	})
+	sort.Strings(names)
+	return
+}
@@ -0,0 +1,422 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- Series ----------------------------
+
+var _ Numeric = new(columnSeries)
+
+// columnSeries represents a float64 time-series column, where the values of each chunk are
+// compressed using Gorilla-style XOR encoding. This works best when the values are appended
+// in order and change slowly, as it is the case for most of the sensor data.
+type columnSeries struct {
+	chunks []seriesChunk
+}
+
+// seriesChunk represents a single chunk of the series
+type seriesChunk struct {
+	fill   bitmap.Bitmap // The fill-list
+	data   xorStream     // The encoded values, in the order of the fill-list
+	shared bool          // Whether the chunk is shared with a clone
+}
+
+// makeSeries creates a new float64 time-series column
+func makeSeries() Column {
+	return &columnSeries{
+		chunks: make([]seriesChunk, 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnSeries) MakeEmpty() Column {
+	return makeSeries()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnSeries) Clone() Column {
+	for i := range c.chunks {
+		c.chunks[i].shared = true
+	}
+
+	chunks := make([]seriesChunk, len(c.chunks), cap(c.chunks))
+	copy(chunks, c.chunks)
+	return &columnSeries{
+		chunks: chunks,
+	}
+}
+
+// SizeOf returns the memory used by a chunk, in bytes
+func (c *columnSeries) SizeOf(chunk commit.Chunk) int {
+	if int(chunk) >= len(c.chunks) {
+		return 0
+	}
+
+	s := &c.chunks[chunk]
+	return len(s.fill)*8 + cap(s.data.data)
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnSeries) Grow(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		c.chunks = append(c.chunks, seriesChunk{
+			fill: make(bitmap.Bitmap, chunkSize/64),
+		})
+	}
+}
+
+// Release releases the encoded values of an empty chunk
+func (c *columnSeries) Release(chunk commit.Chunk) {
+	if int(chunk) < len(c.chunks) {
+		c.chunks[chunk].data = xorStream{}
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnSeries) Apply(chunk commit.Chunk, r *commit.Reader) {
+	s := &c.chunks[chunk]
+	if s.shared {
+		s.fill = s.fill.Clone(nil)
+		s.data.data = append([]byte(nil), s.data.data...)
+		s.shared = false
+	}
+
+	// If we only append new values at the end of the chunk, we can simply keep on encoding
+	// without touching the values that are already there.
+	last, hasLast := s.fill.Max()
+	appendOnly := true
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		if r.Type != commit.Put || (hasLast && offset <= last) {
+			appendOnly = false
+			break
+		}
+		last, hasLast = offset, true
+	}
+
+	r.Rewind()
+	if appendOnly {
+		for r.Next() {
+			s.fill.Set(r.IndexAtChunk())
+			s.data.Append(r.Float())
+		}
+		return
+	}
+
+	// Otherwise, decode the entire chunk, apply the operations and encode it back
+	values := make([]float64, chunkSize)
+	s.Range(func(x uint32, v float64) {
+		values[x] = v
+	})
+
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			s.fill.Set(offset)
+			values[offset] = r.Float()
+		case commit.Add:
+			s.fill.Set(offset)
+			values[offset] += r.Float()
+		case commit.Delete:
+			s.fill.Remove(offset)
+		}
+	}
+
+	s.data = xorStream{}
+	s.fill.Range(func(x uint32) {
+		s.data.Append(values[x])
+	})
+}
+
+// Value retrieves a value at a specified index
+func (c *columnSeries) Value(idx uint32) (interface{}, bool) {
+	return c.LoadFloat64(idx)
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnSeries) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Index returns the fill list for the column
+func (c *columnSeries) Index(chunk commit.Chunk) bitmap.Bitmap {
+	if int(chunk) < len(c.chunks) {
+		return c.chunks[chunk].fill
+	}
+	return nil
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnSeries) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	offset := chunk.Min()
+	c.chunks[chunk].Range(func(x uint32, v float64) {
+		dst.PutFloat64(offset+x, v)
+	})
+}
+
+// LoadFloat64 retrieves a float64 value at a specified index. Since the values are
+// encoded, this requires decoding the chunk up until the requested value.
+func (c *columnSeries) LoadFloat64(idx uint32) (float64, bool) {
+	if !c.Contains(idx) {
+		return 0, false
+	}
+
+	chunk := commit.ChunkAt(idx)
+	s := c.chunks[chunk]
+	rank := s.fill.CountTo(idx - chunk.Min())
+	reader := s.data.Reader()
+	for i := 0; i < rank; i++ {
+		reader.Next()
+	}
+	return reader.Next()
+}
+
+// LoadInt64 retrieves an int64 value at a specified index
+func (c *columnSeries) LoadInt64(idx uint32) (int64, bool) {
+	v, ok := c.LoadFloat64(idx)
+	return int64(v), ok
+}
+
+// LoadUint64 retrieves an uint64 value at a specified index
+func (c *columnSeries) LoadUint64(idx uint32) (uint64, bool) {
+	v, ok := c.LoadFloat64(idx)
+	return uint64(v), ok
+}
+
+// FilterFloat64 filters down the values based on the specified predicate.
+func (c *columnSeries) FilterFloat64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(float64) bool) {
+	if int(chunk) >= len(c.chunks) {
+		return
+	}
+
+	s := c.chunks[chunk]
+	index.And(s.fill)
+	s.Range(func(x uint32, v float64) {
+		if index.Contains(x) && !predicate(v) {
+			index[x>>6] &^= 1 << (x & 0x3f)
+		}
+	})
+}
+
+// FilterInt64 filters down the values based on the specified predicate.
+func (c *columnSeries) FilterInt64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(int64) bool) {
+	c.FilterFloat64(chunk, index, func(v float64) bool {
+		return predicate(int64(v))
+	})
+}
+
+// FilterUint64 filters down the values based on the specified predicate.
+func (c *columnSeries) FilterUint64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(uint64) bool) {
+	c.FilterFloat64(chunk, index, func(v float64) bool {
+		return predicate(uint64(v))
+	})
+}
+
+// Range sequentially decodes the values of the chunk, along with their offsets.
+func (s *seriesChunk) Range(fn func(x uint32, v float64)) {
+	reader := s.data.Reader()
+	s.fill.Range(func(x uint32) {
+		v, _ := reader.Next()
+		fn(x, v)
+	})
+}
+
+// --------------------------- XOR Encoding ----------------------------
+
+// xorStream represents a stream of float64 values, encoded using XOR compression as
+// described in the Gorilla paper. The state of the encoder is retained, so that new
+// values can be appended at any time.
+type xorStream struct {
+	data     []byte // The encoded bits
+	size     int    // The number of bits written
+	count    int    // The number of values written
+	last     uint64 // The last value written
+	leading  uint8  // The leading zeros of the last meaningful block
+	trailing uint8  // The trailing zeros of the last meaningful block
+}
+
+// Append encodes and appends a value at the end of the stream
+func (s *xorStream) Append(value float64) {
+	v := math.Float64bits(value)
+	s.count++
+	if s.count == 1 {
+		s.writeBits(v, 64)
+		s.last = v
+		s.leading = math.MaxUint8
+		return
+	}
+
+	// If the value has not changed, a single bit is enough
+	xor := v ^ s.last
+	s.last = v
+	if xor == 0 {
+		s.writeBits(0, 1)
+		return
+	}
+
+	leading := uint8(bits.LeadingZeros64(xor))
+	trailing := uint8(bits.TrailingZeros64(xor))
+	if leading > 31 {
+		leading = 31
+	}
+
+	// If the meaningful bits fit within the previous block, reuse it
+	s.writeBits(1, 1)
+	if s.leading != math.MaxUint8 && leading >= s.leading && trailing >= s.trailing {
+		s.writeBits(0, 1)
+		s.writeBits(xor>>s.trailing, 64-int(s.leading)-int(s.trailing))
+		return
+	}
+
+	// Otherwise, write a new block with its size
+	significant := 64 - int(leading) - int(trailing)
+	s.leading, s.trailing = leading, trailing
+	s.writeBits(1, 1)
+	s.writeBits(uint64(leading), 5)
+	s.writeBits(uint64(significant), 6) // 64 wraps to 0
+	s.writeBits(xor>>trailing, significant)
+}
+
+// writeBits writes the n lowest bits of the value, most significant bit first
+func (s *xorStream) writeBits(v uint64, n int) {
+	for n > 0 {
+		if s.size&7 == 0 {
+			s.data = append(s.data, 0)
+		}
+
+		free := 8 - s.size&7
+		take := free
+		if n < take {
+			take = n
+		}
+
+		s.data[len(s.data)-1] |= byte((v>>(n-take))&(1<<take-1)) << (free - take)
+		s.size += take
+		n -= take
+	}
+}
+
+// Reader returns a sequential reader for the stream
+func (s *xorStream) Reader() xorReader {
+	return xorReader{src: s}
+}
+
+// xorReader represents a sequential decoder of the XOR stream
+type xorReader struct {
+	src      *xorStream // The stream to decode
+	head     int        // The current bit position
+	read     int        // The number of values read
+	last     uint64     // The last value decoded
+	leading  uint8      // The leading zeros of the current block
+	trailing uint8      // The trailing zeros of the current block
+}
+
+// Next decodes the next value of the stream
+func (r *xorReader) Next() (float64, bool) {
+	if r.read >= r.src.count {
+		return 0, false
+	}
+
+	r.read++
+	switch {
+	case r.read == 1:
+		r.last = r.readBits(64)
+	case r.readBits(1) == 1:
+		if r.readBits(1) == 1 {
+			r.leading = uint8(r.readBits(5))
+			significant := uint8(r.readBits(6))
+			if significant == 0 {
+				significant = 64
+			}
+			r.trailing = 64 - r.leading - significant
+		}
+
+		significant := 64 - int(r.leading) - int(r.trailing)
+		r.last ^= r.readBits(significant) << r.trailing
+	}
+
+	return math.Float64frombits(r.last), true
+}
+
+// readBits reads n bits from the stream, most significant bit first
+func (r *xorReader) readBits(n int) (v uint64) {
+	for n > 0 {
+		avail := 8 - r.head&7
+		take := avail
+		if n < take {
+			take = n
+		}
+
+		b := uint64(r.src.data[r.head>>3] >> (avail - take))
+		v = v<<take | b&(1<<take-1)
+		r.head += take
+		n -= take
+	}
+	return
+}
+
+// --------------------------- Accessor ----------------------------
+
+// seriesReader represents a read-only accessor for series values
+type seriesReader struct {
+	cursor *uint32
+	reader *columnSeries
+}
+
+// Get loads the value at the current transaction cursor
+func (s seriesReader) Get() (float64, bool) {
+	return s.reader.LoadFloat64(*s.cursor)
+}
+
+// seriesReaderFor creates a new series reader
+func seriesReaderFor(txn *Txn, columnName string) seriesReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnSeries)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type series", columnName))
+	}
+
+	return seriesReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// seriesWriter represents read-write accessor for series values
+type seriesWriter struct {
+	seriesReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s seriesWriter) Set(value float64) {
+	s.writer.PutFloat64(*s.cursor, value)
+}
+
+// Add atomically adds a delta to the value at the current transaction cursor
+func (s seriesWriter) Add(delta float64) {
+	s.writer.AddFloat64(*s.cursor, delta)
+}
+
+// Series returns a series column accessor
+func (txn *Txn) Series(columnName string) seriesWriter {
+	return seriesWriter{
+		seriesReader: seriesReaderFor(txn, columnName),
+		writer:       txn.bufferFor(columnName),
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXORStream(t *testing.T) {
+	input := []float64{0, 1, 1, -1, 12.5, 12.75, math.MaxFloat64, math.SmallestNonzeroFloat64, math.Inf(-1), 3}
+	stream := xorStream{}
+	for _, v := range input {
+		stream.Append(v)
+	}
+
+	reader := stream.Reader()
+	for _, expect := range input {
+		v, ok := reader.Next()
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+
+	_, ok := reader.Next()
+	assert.False(t, ok)
+}
+
+func TestSeriesCompression(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("temp", ForSeries()))
+	for i := 0; i < 1000; i++ {
+		col.Insert(func(r Row) error {
+			r.txn.Series("temp").Set(20 + float64(i%10)*0.25)
+			return nil
+		})
+	}
+
+	column, _ := col.cols.Load("temp")
+	series := column.Column.(*columnSeries)
+	assert.Less(t, len(series.chunks[0].data.data), 1000*4)
+
+	// Read back the values
+	assert.NoError(t, col.QueryAt(999, func(r Row) error {
+		v, ok := r.txn.Series("temp").Get()
+		assert.True(t, ok)
+		assert.Equal(t, 22.25, v)
+		return nil
+	}))
+
+	// Row accessor should also work
+	assert.NoError(t, col.QueryAt(5, func(r Row) error {
+		v, ok := r.Float64("temp")
+		assert.True(t, ok)
+		assert.Equal(t, 21.25, v)
+		return nil
+	}))
+}
+
+func TestSeriesUpdate(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("temp", ForSeries()))
+	for i := 0; i < 100; i++ {
+		col.Insert(func(r Row) error {
+			r.txn.Series("temp").Set(float64(i))
+			return nil
+		})
+	}
+
+	// Update and delete in the middle of the chunk
+	assert.NoError(t, col.QueryAt(10, func(r Row) error {
+		r.txn.Series("temp").Add(0.5)
+		return nil
+	}))
+	assert.True(t, col.DeleteAt(20))
+
+	assert.Equal(t, 99, col.Count())
+	assert.NoError(t, col.QueryAt(10, func(r Row) error {
+		v, _ := r.txn.Series("temp").Get()
+		assert.Equal(t, 10.5, v)
+		return nil
+	}))
+	assert.NoError(t, col.QueryAt(21, func(r Row) error {
+		v, _ := r.txn.Series("temp").Get()
+		assert.Equal(t, 21.0, v)
+		return nil
+	}))
+
+	// Filter on the series
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.WithFloat("temp", func(v float64) bool {
+			return v >= 90
+		}).Count())
+		return nil
+	}))
+}
+
+func TestSeriesSnapshot(t *testing.T) {
+	input := NewCollection()
+	assert.NoError(t, input.CreateColumn("temp", ForSeries()))
+	for i := 0; i < 100; i++ {
+		input.Insert(func(r Row) error {
+			r.txn.Series("temp").Set(float64(i) * 1.5)
+			return nil
+		})
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(buffer))
+
+	output := NewCollection()
+	assert.NoError(t, output.CreateColumn("temp", ForSeries()))
+	assert.NoError(t, output.Restore(buffer))
+	assert.NoError(t, output.QueryAt(42, func(r Row) error {
+		v, ok := r.txn.Series("temp").Get()
+		assert.True(t, ok)
+		assert.Equal(t, 63.0, v)
+		return nil
+	}))
+}
+
+func TestSeriesInvalidType(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	assert.Panics(t, func() {
+		col.Query(func(txn *Txn) error {
+			txn.Series("name")
+			return nil
+		})
+	})
+}
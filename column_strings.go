@@ -1,305 +1,437 @@
-// Copyright (c) Roman Atachiants and contributors. All rights reserved.
-// Licensed under the MIT license. See LICENSE file in the project root for details.
-
-package column
-
-import (
-	"fmt"
-	"math"
-
-	"github.com/kelindar/bitmap"
-	"github.com/kelindar/column/commit"
-	"github.com/kelindar/intmap"
-	"github.com/zeebo/xxh3"
-)
-
-// --------------------------- Enum ----------------------------
-
-var _ Textual = new(columnEnum)
-
-// columnEnum represents a string column
-type columnEnum struct {
-	chunks[uint32]
-	seek *intmap.Sync // The hash->location table
-	data []string     // The string data
-}
-
-// makeEnum creates a new column
-func makeEnum() Column {
-	return &columnEnum{
-		chunks: make(chunks[uint32], 0, 4),
-		seek:   intmap.NewSync(64, .95),
-		data:   make([]string, 0, 64),
-	}
-}
-
-// Apply applies a set of operations to the column.
-func (c *columnEnum) Apply(chunk commit.Chunk, r *commit.Reader) {
-	fill, locs := c.chunkAt(chunk)
-	for r.Next() {
-		offset := r.IndexAtChunk()
-		switch r.Type {
-		case commit.Put:
-			fill[offset>>6] |= 1 << (offset & 0x3f)
-			locs[offset] = c.findOrAdd(r.Bytes())
-		case commit.Delete:
-			fill.Remove(offset)
-			// TODO: remove unused strings, need some reference counting for that
-			// and can proably be done during vacuum() instead
-		}
-	}
-}
-
-// Search for the string or adds it and returns the offset
-func (c *columnEnum) findOrAdd(v []byte) uint32 {
-	target := uint32(xxh3.Hash(v))
-	at, _ := c.seek.LoadOrStore(target, func() uint32 {
-		c.data = append(c.data, string(v))
-		return uint32(len(c.data)) - 1
-	})
-	return at
-}
-
-// readAt reads a string at a location
-func (c *columnEnum) readAt(at uint32) string {
-	return c.data[at]
-}
-
-// Value retrieves a value at a specified index
-func (c *columnEnum) Value(idx uint32) (v interface{}, ok bool) {
-	return c.LoadString(idx)
-}
-
-// LoadString retrieves a value at a specified index
-func (c *columnEnum) LoadString(idx uint32) (v string, ok bool) {
-	chunk := commit.ChunkAt(idx)
-	index := idx - chunk.Min()
-	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
-		v, ok = c.readAt(c.chunks[chunk].data[index]), true
-	}
-	return
-}
-
-// FilterString filters down the values based on the specified predicate. The column for
-// this filter must be a string.
-func (c *columnEnum) FilterString(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v string) bool) {
-	if int(chunk) >= len(c.chunks) {
-		return
-	}
-
-	fill, locs := c.chunkAt(chunk)
-	cache := struct {
-		index uint32 // Last seen offset
-		value bool   // Last evaluated predicate
-	}{
-		index: math.MaxUint32,
-		value: false,
-	}
-
-	// Do a quick ellimination of elements which are NOT contained in this column, this
-	// allows us not to check contains during the filter itself
-	index.And(fill)
-
-	// Filters down the strings, if strings repeat we avoid reading every time by
-	// caching the last seen index/value combination.
-	index.Filter(func(idx uint32) bool {
-		if at := locs[idx]; at != cache.index {
-			cache.index = at
-			cache.value = predicate(c.readAt(at))
-			return cache.value
-		}
-
-		// The value is cached, avoid evaluating it
-		return cache.value
-	})
-}
-
-// Contains checks whether the column has a value at a specified index.
-func (c *columnEnum) Contains(idx uint32) bool {
-	chunk := commit.ChunkAt(idx)
-	return c.chunks[chunk].fill.Contains(idx - chunk.Min())
-}
-
-// Snapshot writes the entire column into the specified destination buffer
-func (c *columnEnum) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
-	fill, locs := c.chunkAt(chunk)
-	fill.Range(func(idx uint32) {
-		dst.PutString(commit.Put, idx, c.readAt(locs[idx]))
-	})
-}
-
-// enumReader represents a read-only accessor for enum strings
-type enumReader struct {
-	cursor *uint32
-	reader *columnEnum
-}
-
-// Get loads the value at the current transaction cursor
-func (s enumReader) Get() (string, bool) {
-	return s.reader.LoadString(*s.cursor)
-}
-
-// enumReaderFor creates a new enum string reader
-func enumReaderFor(txn *Txn, columnName string) enumReader {
-	column, ok := txn.columnAt(columnName)
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
-	}
-
-	reader, ok := column.Column.(*columnEnum)
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' is not of type string", columnName))
-	}
-
-	return enumReader{
-		cursor: &txn.cursor,
-		reader: reader,
-	}
-}
-
-// slice accessor for enums
-type enumSlice struct {
-	enumReader
-	writer *commit.Buffer
-}
-
-// Set sets the value at the current transaction cursor
-func (s enumSlice) Set(value string) {
-	s.writer.PutString(commit.Put, *s.cursor, value)
-}
-
-// Enum returns a enumerable column accessor
-func (txn *Txn) Enum(columnName string) enumSlice {
-	return enumSlice{
-		enumReader: enumReaderFor(txn, columnName),
-		writer:     txn.bufferFor(columnName),
-	}
-}
-
-// --------------------------- String ----------------------------
-
-var _ Textual = new(columnString)
-
-// columnString represents a string column
-type columnString struct {
-	chunks[string]
-}
-
-// makeString creates a new string column
-func makeStrings() Column {
-	return &columnString{
-		chunks: make(chunks[string], 0, 4),
-	}
-}
-
-// Apply applies a set of operations to the column.
-func (c *columnString) Apply(chunk commit.Chunk, r *commit.Reader) {
-	fill, data := c.chunkAt(chunk)
-	from := chunk.Min()
-
-	// Update the values of the column, for this one we can only process stores
-	for r.Next() {
-		offset := r.Offset - int32(from)
-		switch r.Type {
-		case commit.Put:
-			fill[offset>>6] |= 1 << (offset & 0x3f)
-			data[offset] = string(r.Bytes())
-		case commit.Delete:
-			fill.Remove(uint32(offset))
-		}
-	}
-}
-
-// Value retrieves a value at a specified index
-func (c *columnString) Value(idx uint32) (v interface{}, ok bool) {
-	chunk := commit.ChunkAt(idx)
-	index := idx - chunk.Min()
-
-	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
-		v, ok = c.chunks[chunk].data[index], true
-	}
-	return
-}
-
-// Contains checks whether the column has a value at a specified index.
-func (c *columnString) Contains(idx uint32) bool {
-	chunk := commit.ChunkAt(idx)
-	index := idx - chunk.Min()
-	return c.chunks[chunk].fill.Contains(index)
-
-}
-
-// LoadString retrieves a value at a specified index
-func (c *columnString) LoadString(idx uint32) (string, bool) {
-	v, has := c.Value(idx)
-	s, ok := v.(string)
-	return s, has && ok
-}
-
-// FilterString filters down the values based on the specified predicate. The column for
-// this filter must be a string.
-func (c *columnString) FilterString(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v string) bool) {
-	if int(chunk) < len(c.chunks) {
-		fill, data := c.chunkAt(chunk)
-		index.And(fill)
-		index.Filter(func(idx uint32) bool {
-			return predicate(data[idx])
-		})
-	}
-}
-
-// Snapshot writes the entire column into the specified destination buffer
-func (c *columnString) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
-	fill, data := c.chunkAt(chunk)
-	fill.Range(func(x uint32) {
-		dst.PutString(commit.Put, chunk.Min()+x, data[x])
-	})
-}
-
-// stringReader represents a read-only accessor for strings
-type stringReader struct {
-	cursor *uint32
-	reader *columnString
-}
-
-// Get loads the value at the current transaction cursor
-func (s stringReader) Get() (string, bool) {
-	return s.reader.LoadString(*s.cursor)
-}
-
-// stringReaderFor creates a new string reader
-func stringReaderFor(txn *Txn, columnName string) stringReader {
-	column, ok := txn.columnAt(columnName)
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
-	}
-
-	reader, ok := column.Column.(*columnString)
-	if !ok {
-		panic(fmt.Errorf("column: column '%s' is not of type string", columnName))
-	}
-
-	return stringReader{
-		cursor: &txn.cursor,
-		reader: reader,
-	}
-}
-
-// stringWriter represents read-write accessor for strings
-type stringWriter struct {
-	stringReader
-	writer *commit.Buffer
-}
-
-// Set sets the value at the current transaction cursor
-func (s stringWriter) Set(value string) {
-	s.writer.PutString(commit.Put, *s.cursor, value)
-}
-
-// String returns a string column accessor
-func (txn *Txn) String(columnName string) stringWriter {
-	return stringWriter{
-		stringReader: stringReaderFor(txn, columnName),
-		writer:       txn.bufferFor(columnName),
-	}
-}
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/intmap"
+	"github.com/zeebo/xxh3"
+)
+
+// --------------------------- Enum ----------------------------
+
+var _ Textual = new(columnEnum)
+
+// columnEnum represents a string column
+type columnEnum struct {
+	removed uint64 // The number of values removed since the last compaction
+	chunks[uint32]
+	seek *intmap.Sync // The hash->location table
+	lock sync.RWMutex // The lock which guards the string data, shared by all of the chunks
+	data []string     // The string data
+}
+
+// makeEnum creates a new column
+func makeEnum() Column {
+	return &columnEnum{
+		chunks: make(chunks[uint32], 0, 4),
+		seek:   intmap.NewSync(64, .95),
+		data:   make([]string, 0, 64),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnEnum) MakeEmpty() Column {
+	return makeEnum()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified. Since
+// the strings are only ever appended, the copy refers to the same strings.
+func (c *columnEnum) Clone() Column {
+	seek := intmap.NewSync(64, .95)
+	c.seek.Range(func(hash, at uint32) bool {
+		seek.Store(hash, at)
+		return true
+	})
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return &columnEnum{
+		removed: atomic.LoadUint64(&c.removed),
+		chunks:  c.chunks.share(),
+		seek:    seek,
+		data:    c.data[:len(c.data):len(c.data)],
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnEnum) Apply(chunk commit.Chunk, r *commit.Reader) {
+	fill, locs := c.chunkFor(chunk)
+	removed := uint64(0)
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			if fill.Contains(offset) {
+				removed++
+			}
+
+			fill[offset>>6] |= 1 << (offset & 0x3f)
+			locs[offset] = c.findOrAdd(r.Bytes())
+		case commit.Delete:
+			if fill.Contains(offset) {
+				removed++
+			}
+
+			// The strings which are no longer used are removed during the compaction
+			fill.Remove(offset)
+		}
+	}
+
+	if removed > 0 {
+		atomic.AddUint64(&c.removed, removed)
+	}
+}
+
+// Fragmented returns whether the fraction of the unused strings may exceed the threshold.
+// Since every removed value leaves at most one string unused, this is an upper bound.
+func (c *columnEnum) Fragmented(threshold float64) bool {
+	removed := atomic.LoadUint64(&c.removed)
+	return removed > 0 && float64(removed) >= threshold*float64(c.seek.Count())
+}
+
+// Compact removes the unused strings if their fraction exceeds the threshold, and updates
+// the locations of the values accordingly. The caller must hold all of the shard locks.
+func (c *columnEnum) Compact(threshold float64) {
+	var used bitmap.Bitmap
+	for chunk := range c.chunks {
+		fill, locs := c.chunkAt(commit.Chunk(chunk))
+		fill.Range(func(idx uint32) {
+			used.Set(locs[idx])
+		})
+	}
+
+	// Keep track of the exact number of unused strings, as the next upper bound
+	unused := len(c.data) - used.Count()
+	atomic.StoreUint64(&c.removed, uint64(unused))
+	if unused == 0 || float64(unused) < threshold*float64(len(c.data)) {
+		return
+	}
+
+	// Rebuild the strings, keeping only the used ones
+	remap := make([]uint32, len(c.data))
+	data := make([]string, 0, len(c.data)-unused)
+	seek := intmap.NewSync(64, .95)
+	used.Range(func(at uint32) {
+		remap[at] = uint32(len(data))
+		seek.Store(uint32(xxh3.HashString(c.data[at])), remap[at])
+		data = append(data, c.data[at])
+	})
+
+	// Update the locations, copying the chunks which are shared with a clone
+	for chunk := range c.chunks {
+		if c.chunks[chunk].fill.Count() == 0 {
+			continue
+		}
+
+		fill, locs := c.chunkFor(commit.Chunk(chunk))
+		fill.Range(func(idx uint32) {
+			locs[idx] = remap[locs[idx]]
+		})
+	}
+
+	c.lock.Lock()
+	c.data, c.seek = data, seek
+	c.lock.Unlock()
+	atomic.StoreUint64(&c.removed, 0)
+}
+
+// Search for the string or adds it and returns the offset. Since the chunks are applied
+// concurrently, the strings are appended under the lock of the string data.
+func (c *columnEnum) findOrAdd(v []byte) uint32 {
+	target := uint32(xxh3.Hash(v))
+	at, _ := c.seek.LoadOrStore(target, func() uint32 {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.data = append(c.data, string(v))
+		return uint32(len(c.data)) - 1
+	})
+	return at
+}
+
+// readAt reads a string at a location
+func (c *columnEnum) readAt(at uint32) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.data[at]
+}
+
+// Value retrieves a value at a specified index
+func (c *columnEnum) Value(idx uint32) (v interface{}, ok bool) {
+	return c.LoadString(idx)
+}
+
+// LoadString retrieves a value at a specified index
+func (c *columnEnum) LoadString(idx uint32) (v string, ok bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		v, ok = c.readAt(c.chunks[chunk].data[index]), true
+	}
+	return
+}
+
+// FilterString filters down the values based on the specified predicate. The column for
+// this filter must be a string.
+func (c *columnEnum) FilterString(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v string) bool) {
+	if int(chunk) >= len(c.chunks) {
+		return
+	}
+
+	fill, locs := c.chunkAt(chunk)
+	cache := struct {
+		index uint32 // Last seen offset
+		value bool   // Last evaluated predicate
+	}{
+		index: math.MaxUint32,
+		value: false,
+	}
+
+	// Do a quick ellimination of elements which are NOT contained in this column, this
+	// allows us not to check contains during the filter itself
+	index.And(fill)
+
+	// Filters down the strings, if strings repeat we avoid reading every time by
+	// caching the last seen index/value combination.
+	index.Filter(func(idx uint32) bool {
+		if at := locs[idx]; at != cache.index {
+			cache.index = at
+			cache.value = predicate(c.readAt(at))
+			return cache.value
+		}
+
+		// The value is cached, avoid evaluating it
+		return cache.value
+	})
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnEnum) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return c.chunks[chunk].fill.Contains(idx - chunk.Min())
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnEnum) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	fill, locs := c.chunkAt(chunk)
+	fill.Range(func(idx uint32) {
+		dst.PutString(commit.Put, idx, c.readAt(locs[idx]))
+	})
+}
+
+// enumReader represents a read-only accessor for enum strings
+type enumReader struct {
+	cursor *uint32
+	reader *columnEnum
+}
+
+// Get loads the value at the current transaction cursor
+func (s enumReader) Get() (string, bool) {
+	return s.reader.LoadString(*s.cursor)
+}
+
+// enumReaderFor creates a new enum string reader
+func enumReaderFor(txn *Txn, columnName string) enumReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnEnum)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type string", columnName))
+	}
+
+	return enumReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// slice accessor for enums
+type enumSlice struct {
+	enumReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s enumSlice) Set(value string) {
+	s.writer.PutString(commit.Put, *s.cursor, value)
+}
+
+// Enum returns a enumerable column accessor
+func (txn *Txn) Enum(columnName string) enumSlice {
+	return enumSlice{
+		enumReader: enumReaderFor(txn, columnName),
+		writer:     txn.bufferFor(columnName),
+	}
+}
+
+// --------------------------- String ----------------------------
+
+var _ Textual = new(columnString)
+
+// columnString represents a string column. The strings of each chunk are appended into the
+// arena of a byte slice column, so that they are not scanned one by one by the garbage
+// collector, and the strings read refer to the arena rather than being copied.
+type columnString struct {
+	data columnBytes
+}
+
+// makeString creates a new string column
+func makeStrings() Column {
+	return &columnString{
+		data: columnBytes{
+			chunks: make([]bytesChunk, 0, 4),
+		},
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnString) MakeEmpty() Column {
+	return makeStrings()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnString) Clone() Column {
+	return &columnString{
+		data: *c.data.Clone().(*columnBytes),
+	}
+}
+
+// SizeOf returns the approximate memory used by a chunk, including its arena
+func (c *columnString) SizeOf(chunk commit.Chunk) int {
+	return c.data.SizeOf(chunk)
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnString) Grow(idx uint32) {
+	c.data.Grow(idx)
+}
+
+// Release releases the offsets and the arena of an empty chunk
+func (c *columnString) Release(chunk commit.Chunk) {
+	c.data.Release(chunk)
+}
+
+// Fragmented returns whether the fraction of the unused bytes in the arenas exceeds the
+// threshold.
+func (c *columnString) Fragmented(threshold float64) bool {
+	return c.data.Fragmented(threshold)
+}
+
+// Compact rebuilds the arenas in which the fraction of the unused bytes exceeds the threshold.
+// The strings previously read keep referring to the former arenas, which remain unchanged.
+func (c *columnString) Compact(threshold float64) {
+	c.data.Compact(threshold)
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnString) Apply(chunk commit.Chunk, r *commit.Reader) {
+	c.data.Apply(chunk, r)
+}
+
+// Value retrieves a value at a specified index
+func (c *columnString) Value(idx uint32) (v interface{}, ok bool) {
+	if s, ok := c.LoadString(idx); ok {
+		return s, true
+	}
+	return nil, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnString) Contains(idx uint32) bool {
+	return c.data.Contains(idx)
+}
+
+// Index returns the fill list for the column
+func (c *columnString) Index(chunk commit.Chunk) bitmap.Bitmap {
+	return c.data.Index(chunk)
+}
+
+// LoadString retrieves a value at a specified index. The string refers to the arena of the
+// chunk, which is never modified once written, so it remains valid after the value changes.
+func (c *columnString) LoadString(idx uint32) (string, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) >= len(c.data.chunks) || !c.data.chunks[chunk].fill.Contains(index) {
+		return "", false
+	}
+
+	return c.data.chunks[chunk].stringAt(index), true
+}
+
+// FilterString filters down the values based on the specified predicate. The column for
+// this filter must be a string.
+func (c *columnString) FilterString(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v string) bool) {
+	if int(chunk) < len(c.data.chunks) {
+		s := &c.data.chunks[chunk]
+		index.And(s.fill)
+		index.Filter(func(idx uint32) bool {
+			return predicate(s.stringAt(idx))
+		})
+	}
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnString) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	s := &c.data.chunks[chunk]
+	s.fill.Range(func(x uint32) {
+		dst.PutString(commit.Put, chunk.Min()+x, s.stringAt(x))
+	})
+}
+
+// stringReader represents a read-only accessor for strings
+type stringReader struct {
+	cursor *uint32
+	reader *columnString
+}
+
+// Get loads the value at the current transaction cursor
+func (s stringReader) Get() (string, bool) {
+	return s.reader.LoadString(*s.cursor)
+}
+
+// stringReaderFor creates a new string reader
+func stringReaderFor(txn *Txn, columnName string) stringReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnString)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type string", columnName))
+	}
+
+	return stringReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// stringWriter represents read-write accessor for strings
+type stringWriter struct {
+	stringReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s stringWriter) Set(value string) {
+	s.writer.PutString(commit.Put, *s.cursor, value)
+}
+
+// String returns a string column accessor
+func (txn *Txn) String(columnName string) stringWriter {
+	return stringWriter{
+		stringReader: stringReaderFor(txn, columnName),
+		writer:       txn.bufferFor(columnName),
+	}
+}
@@ -4,15 +4,67 @@
 package column
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
 	"github.com/stretchr/testify/assert"
 )
 
+func BenchmarkCompare(b *testing.B) {
+	coll := NewCollection()
+	coll.CreateColumn("value", ForFloat64())
+	coll.Query(func(txn *Txn) error {
+		for i := 0; i < 1000000; i++ {
+			txn.Insert(func(r Row) error {
+				r.SetFloat64("value", rand.Float64())
+				return nil
+			})
+		}
+		return nil
+	})
+
+	b.Run("predicate", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			coll.Query(func(txn *Txn) error {
+				txn.WithFloat("value", func(v float64) bool {
+					return v > 0.5
+				}).Count()
+				return nil
+			})
+		}
+	})
+
+	b.Run("greater", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			coll.Query(func(txn *Txn) error {
+				txn.WithFloatGreater("value", 0.5).Count()
+				return nil
+			})
+		}
+	})
+
+	b.Run("between", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			coll.Query(func(txn *Txn) error {
+				txn.WithFloatBetween("value", 0.25, 0.75).Count()
+				return nil
+			})
+		}
+	})
+}
+
 func TestColumns(t *testing.T) {
 	tests := []struct {
 		column Column
@@ -31,6 +83,9 @@ func TestColumns(t *testing.T) {
 		{column: ForUint64(), value: uint64(99)},
 		{column: ForFloat32(), value: float32(99.5)},
 		{column: ForFloat64(), value: float64(99.5)},
+		{column: ForSeries(), value: float64(99.5)},
+		{column: ForAny(), value: int64(99)},
+		{column: ForAny(), value: "test"},
 	}
 
 	for _, tc := range tests {
@@ -207,6 +262,11 @@ func TestFromKind(t *testing.T) {
 func applyChanges(column Column, updates ...Update) {
 	buf := commit.NewBuffer(10)
 	for _, u := range updates {
+		if enc, ok := column.(Encoder); ok && u.Type == commit.Put {
+			enc.Encode(buf, u.Index, u.Value)
+			continue
+		}
+
 		buf.PutAny(u.Type, u.Index, u.Value)
 	}
 
@@ -244,6 +304,63 @@ func TestForString(t *testing.T) {
 	})
 }
 
+func TestStringArena(t *testing.T) {
+	coll := NewCollection(Options{CleanupInterval: time.Hour})
+	coll.CreateColumn("id", ForKey())
+	coll.CreateColumn("name", ForString())
+	for i := 0; i < 100; i++ {
+		coll.InsertObject(Object{"id": fmt.Sprintf("key-%03d", i), "name": fmt.Sprintf("name-%03d", i)})
+	}
+
+	column := func() *columnString {
+		column, _ := coll.cols.Load("name")
+		return column.Column.(*columnString)
+	}
+
+	// The strings read remain unchanged once the values are overwritten
+	var before string
+	assert.NoError(t, coll.QueryAt(5, func(r Row) error {
+		before, _ = r.String("name")
+		return nil
+	}))
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		name := txn.String("name")
+		return txn.Range(func(idx uint32) {
+			if idx >= 2 {
+				name.Set("x")
+			}
+		})
+	}))
+
+	assert.Equal(t, "name-005", before)
+	assert.True(t, column().Fragmented(0.5))
+	coll.compact()
+	assert.False(t, column().Fragmented(0.5))
+	assert.Equal(t, "name-005", before)
+
+	// The values and the keys are read back from the compacted arenas
+	assert.NoError(t, coll.QueryKey("key-001", func(r Row) error {
+		name, _ := r.String("name")
+		assert.Equal(t, "name-001", name)
+		return nil
+	}))
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 98, txn.WithString("name", func(v string) bool {
+			return v == "x"
+		}).Count())
+		return nil
+	}))
+
+	// The deleted keys are removed from the lookup table
+	assert.True(t, coll.DeleteAt(50))
+	_, ok := coll.pk.OffsetOf("key-050")
+	assert.False(t, ok)
+	idx, ok := coll.pk.OffsetOf("key-051")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(51), idx)
+}
+
 func TestForKindInvalid(t *testing.T) {
 	c, err := ForKind(reflect.Invalid)
 	assert.Nil(t, c)
@@ -435,6 +552,59 @@ func TestBooleanAccessor(t *testing.T) {
 
 }
 
+func TestBooleanFilters(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("active", ForBool())
+	col.CreateColumn("admin", ForBool())
+	for i := 0; i < 100; i++ {
+		col.InsertObject(Object{"active": i%2 == 0, "admin": i%5 == 0})
+	}
+
+	// The values are stored as a single bit per row
+	column, _ := col.cols.Load("active")
+	assert.Equal(t, chunkSize/8, column.sizeOf(0))
+
+	count := func(fn func(txn *Txn) *Txn) (n int) {
+		col.Query(func(txn *Txn) error {
+			n = fn(txn).Count()
+			return nil
+		})
+		return
+	}
+
+	assert.Equal(t, 50, count(func(txn *Txn) *Txn { return txn.With("active") }))
+	assert.Equal(t, 10, count(func(txn *Txn) *Txn { return txn.With("active", "admin") }))
+	assert.Equal(t, 40, count(func(txn *Txn) *Txn { return txn.With("active").Without("admin") }))
+	assert.Equal(t, 60, count(func(txn *Txn) *Txn { return txn.Union("active", "admin") }))
+
+	// Setting a value to false removes the row from the bitmap
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		return txn.With("admin").Range(func(idx uint32) {
+			txn.Bool("active").Set(false)
+		})
+	}))
+	assert.NoError(t, col.QueryAt(1, func(r Row) error {
+		r.SetBool("active", true)
+		return nil
+	}))
+
+	assert.Equal(t, 41, count(func(txn *Txn) *Txn { return txn.With("active") }))
+	assert.Equal(t, 0, count(func(txn *Txn) *Txn { return txn.With("active", "admin") }))
+
+	// The value set with a row cursor within a transaction is filtered once committed
+	assert.NoError(t, col.Query(func(txn *Txn) error {
+		return txn.QueryAt(5, func(r Row) error {
+			r.SetBool("active", true)
+			return nil
+		})
+	}))
+
+	isActive := func(v interface{}) bool { return v.(bool) }
+	assert.Equal(t, 42, count(func(txn *Txn) *Txn { return txn.With("active") }))
+	assert.Equal(t, 42, count(func(txn *Txn) *Txn { return txn.WithValue("active", isActive) }))
+	assert.Equal(t, 1, count(func(txn *Txn) *Txn { return txn.With("active", "admin") }))
+}
+
 func TestColumnNotFound(t *testing.T) {
 	col := NewCollection()
 	assert.NoError(t, col.CreateColumn("name", ForString()))
@@ -505,3 +675,245 @@ func invoke(any interface{}, name string, args ...interface{}) []reflect.Value {
 
 	return reflect.ValueOf(any).MethodByName(name).Call(inputs)
 }
+
+func TestKindOf(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("id", ForKey())
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("class", ForEnum())
+	coll.CreateColumn("age", ForInt16())
+	coll.CreateColumn("balance", ForFloat64())
+	coll.CreateColumn("active", ForBool())
+	coll.CreateColumn("temp", ForSeries())
+	coll.CreateColumn("meta", ForAny())
+	coll.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() > 50
+	})
+
+	for name, kind := range map[string]reflect.Kind{
+		"id":      reflect.String,
+		"name":    reflect.String,
+		"class":   reflect.String,
+		"age":     reflect.Int16,
+		"balance": reflect.Float64,
+		"active":  reflect.Bool,
+		"temp":    reflect.Float64,
+		"meta":    reflect.Interface,
+		"old":     reflect.Bool,
+	} {
+		v, ok := coll.KindOf(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, kind, v, name)
+	}
+
+	_, ok := coll.KindOf("invalid")
+	assert.False(t, ok)
+}
+
+func TestCompareMask(t *testing.T) {
+	values := make([]int32, 64)
+	for i := range values {
+		values[i] = int32(rand.Intn(20) - 10)
+	}
+
+	var equal, less, greater, between uint64
+	for i, v := range values {
+		if v == 3 {
+			equal |= 1 << i
+		}
+		if v < 3 {
+			less |= 1 << i
+		}
+		if v > 3 {
+			greater |= 1 << i
+		}
+		if v >= -2 && v <= 5 {
+			between |= 1 << i
+		}
+	}
+
+	assert.Equal(t, equal, equalMask(values, float64(3)))
+	assert.Equal(t, less, lessMask(values, int64(3)))
+	assert.Equal(t, greater, greaterMask(values, int64(3)))
+	assert.Equal(t, between, betweenMask(values, float64(-2), float64(5)))
+}
+
+func TestNumericViews(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("age", ForInt64())
+	coll.CreateColumn("name", ForString())
+	for i := 0; i < 20000; i++ {
+		coll.InsertObject(Object{"age": int64(i % 100)})
+	}
+	coll.DeleteAt(99)
+
+	// Sum up the values directly from the views
+	var sum int64
+	var offsets []uint32
+	assert.NoError(t, coll.Int64s("age", func(offset uint32, values []int64, fill bitmap.Bitmap) {
+		offsets = append(offsets, offset)
+		fill.Range(func(x uint32) {
+			sum += values[x]
+		})
+	}))
+	assert.Equal(t, []uint32{0, 16384}, offsets)
+	assert.Equal(t, int64(200*(99*100/2)-99), sum)
+
+	assert.Error(t, coll.Float64s("age", func(uint32, []float64, bitmap.Bitmap) {}))
+	assert.Error(t, coll.Int64s("name", func(uint32, []int64, bitmap.Bitmap) {}))
+	assert.Error(t, coll.Int64s("missing", func(uint32, []int64, bitmap.Bitmap) {}))
+}
+
+func TestCustomColumn(t *testing.T) {
+	c := NewCollection()
+	assert.NoError(t, c.CreateColumn("score", newSparseInts()))
+	for i := 0; i < 100; i++ {
+		object := Object{}
+		if i%10 == 0 {
+			object["score"] = i
+		}
+		c.InsertObject(object)
+	}
+
+	count := func(c *Collection) (n int) {
+		c.Query(func(txn *Txn) error {
+			n = txn.WithInt("score", func(v int64) bool {
+				return v >= 50
+			}).Count()
+			return nil
+		})
+		return
+	}
+
+	// The custom column is filtered as any other numeric column
+	assert.Equal(t, 5, count(c))
+	assert.True(t, c.DeleteAt(90))
+	assert.Equal(t, 4, count(c))
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		r.SetAny("score", 1000)
+		return nil
+	}))
+	assert.Equal(t, 5, count(c))
+
+	// The collection can be cloned and copied, since the column is a cloner and a factory
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	clone.DeleteAt(0)
+	assert.Equal(t, 5, count(c))
+	assert.Equal(t, 4, count(clone))
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, c.Snapshot(buffer))
+	other := NewCollection()
+	other.CreateColumn("score", newSparseInts())
+	assert.NoError(t, other.Restore(buffer))
+	assert.Equal(t, 5, count(other))
+
+	for _, s := range c.Stats() {
+		if s.Name == "score" {
+			assert.Equal(t, 9, s.Count)
+			assert.Equal(t, 9*12, s.Bytes)
+		}
+	}
+}
+
+// sparseInts is a custom column, implemented with the exported contracts only, which stores
+// a few integers in a map rather than in dense chunks
+type sparseInts struct {
+	fill   bitmap.Bitmap
+	values map[uint32]int64
+}
+
+func newSparseInts() *sparseInts {
+	return &sparseInts{values: make(map[uint32]int64)}
+}
+
+func (c *sparseInts) Grow(idx uint32) {
+	c.fill.Grow(idx)
+}
+
+func (c *sparseInts) Apply(chunk commit.Chunk, r *commit.Reader) {
+	for r.Next() {
+		switch r.Type {
+		case commit.Put:
+			c.fill.Set(r.Index())
+			c.values[r.Index()] = r.Int64()
+		case commit.Delete:
+			c.fill.Remove(r.Index())
+			delete(c.values, r.Index())
+		}
+	}
+}
+
+func (c *sparseInts) Value(idx uint32) (interface{}, bool) {
+	v, ok := c.values[idx]
+	return v, ok
+}
+
+func (c *sparseInts) Contains(idx uint32) bool {
+	return c.fill.Contains(idx)
+}
+
+func (c *sparseInts) Index(chunk commit.Chunk) bitmap.Bitmap {
+	return chunk.OfBitmap(c.fill)
+}
+
+func (c *sparseInts) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	chunk.OfBitmap(c.fill).Range(func(x uint32) {
+		dst.PutInt64(chunk.Min()+x, c.values[chunk.Min()+x])
+	})
+}
+
+func (c *sparseInts) LoadInt64(idx uint32) (int64, bool) {
+	v, ok := c.values[idx]
+	return v, ok
+}
+
+func (c *sparseInts) LoadFloat64(idx uint32) (float64, bool) {
+	v, ok := c.values[idx]
+	return float64(v), ok
+}
+
+func (c *sparseInts) LoadUint64(idx uint32) (uint64, bool) {
+	v, ok := c.values[idx]
+	return uint64(v), ok
+}
+
+func (c *sparseInts) FilterInt64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v int64) bool) {
+	index.And(chunk.OfBitmap(c.fill))
+	index.Filter(func(x uint32) bool {
+		return predicate(c.values[chunk.Min()+x])
+	})
+}
+
+func (c *sparseInts) FilterFloat64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v float64) bool) {
+	c.FilterInt64(chunk, index, func(v int64) bool { return predicate(float64(v)) })
+}
+
+func (c *sparseInts) FilterUint64(chunk commit.Chunk, index bitmap.Bitmap, predicate func(v uint64) bool) {
+	c.FilterInt64(chunk, index, func(v int64) bool { return predicate(uint64(v)) })
+}
+
+func (c *sparseInts) SizeOf(chunk commit.Chunk) int {
+	return chunk.OfBitmap(c.fill).Count() * 12
+}
+
+func (c *sparseInts) Clone() Column {
+	clone := newSparseInts()
+	clone.fill = c.fill.Clone(nil)
+	for k, v := range c.values {
+		clone.values[k] = v
+	}
+	return clone
+}
+
+func (c *sparseInts) MakeEmpty() Column {
+	return newSparseInts()
+}
+
+var (
+	_ Numeric = new(sparseInts)
+	_ Sizer   = new(sparseInts)
+	_ Cloner  = new(sparseInts)
+	_ Factory = new(sparseInts)
+)
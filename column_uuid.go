@@ -0,0 +1,241 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// UUID represents a universally unique identifier, stored as 16 bytes
+type UUID [16]byte
+
+// ParseUUID parses a UUID in its canonical form, such as "f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+// or as 32 hexadecimal digits without the hyphens.
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		s = strings.ReplaceAll(s, "-", "")
+	}
+
+	if len(s) != 32 {
+		return uuid, fmt.Errorf("column: unable to parse uuid '%s', invalid length", s)
+	}
+
+	if _, err := hex.Decode(uuid[:], []byte(s)); err != nil {
+		return uuid, fmt.Errorf("column: unable to parse uuid '%s', %w", s, err)
+	}
+	return uuid, nil
+}
+
+// String returns the canonical form of the UUID
+func (u UUID) String() string {
+	var dst [36]byte
+	hex.Encode(dst[0:8], u[0:4])
+	hex.Encode(dst[9:13], u[4:6])
+	hex.Encode(dst[14:18], u[6:8])
+	hex.Encode(dst[19:23], u[8:10])
+	hex.Encode(dst[24:36], u[10:16])
+	dst[8], dst[13], dst[18], dst[23] = '-', '-', '-', '-'
+	return string(dst[:])
+}
+
+// uuidOf converts a value into a UUID, parsing it if it is a string
+func uuidOf(value any) (UUID, error) {
+	switch v := value.(type) {
+	case UUID:
+		return v, nil
+	case [16]byte:
+		return UUID(v), nil
+	case []byte:
+		if len(v) != 16 {
+			return UUID{}, fmt.Errorf("column: unable to convert %d bytes into a uuid", len(v))
+		}
+		return *(*UUID)(v), nil
+	case string:
+		return ParseUUID(v)
+	default:
+		return UUID{}, fmt.Errorf("column: unable to convert %T into a uuid", value)
+	}
+}
+
+// --------------------------- UUID Column ----------------------------
+
+// columnUUID represents a column of UUIDs, stored as fixed-width arrays of 16 bytes
+type columnUUID struct {
+	chunks[UUID]
+}
+
+// makeUUIDs creates a new column of UUIDs
+func makeUUIDs() Column {
+	return &columnUUID{
+		chunks: make(chunks[UUID], 0, 4),
+	}
+}
+
+// MakeEmpty creates a new, empty column of the same type
+func (c *columnUUID) MakeEmpty() Column {
+	return makeUUIDs()
+}
+
+// Clone creates a copy of the column, sharing the chunks until they are modified
+func (c *columnUUID) Clone() Column {
+	return &columnUUID{
+		chunks: c.chunks.share(),
+	}
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnUUID) Apply(chunk commit.Chunk, r *commit.Reader) {
+	fill, data := c.chunkFor(chunk)
+	for r.Next() {
+		offset := r.IndexAtChunk()
+		switch r.Type {
+		case commit.Put:
+			fill.Set(offset)
+			copy(data[offset][:], r.Bytes())
+		case commit.Delete:
+			fill.Remove(offset)
+		}
+	}
+}
+
+// Value retrieves a value at a specified index
+func (c *columnUUID) Value(idx uint32) (interface{}, bool) {
+	if v, ok := c.LoadUUID(idx); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// LoadUUID retrieves a UUID at a specified index
+func (c *columnUUID) LoadUUID(idx uint32) (UUID, bool) {
+	chunk := commit.ChunkAt(idx)
+	index := idx - chunk.Min()
+	if int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(index) {
+		return c.chunks[chunk].data[index], true
+	}
+	return UUID{}, false
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnUUID) Contains(idx uint32) bool {
+	chunk := commit.ChunkAt(idx)
+	return int(chunk) < len(c.chunks) && c.chunks[chunk].fill.Contains(idx-chunk.Min())
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnUUID) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	fill, data := c.chunkAt(chunk)
+	fill.Range(func(x uint32) {
+		dst.PutBytes(commit.Put, chunk.Min()+x, data[x][:])
+	})
+}
+
+// Encode writes a UUID into the commit buffer. The UUIDs can be written as an array or a
+// slice of 16 bytes, or as a string in their canonical form. A nil value deletes the UUID
+// and the values which can not be converted are rejected with a panic.
+func (c *columnUUID) Encode(dst *commit.Buffer, idx uint32, value any) {
+	if value == nil {
+		dst.PutOperation(commit.Delete, idx)
+		return
+	}
+
+	uuid, err := uuidOf(value)
+	if err != nil {
+		panic(err)
+	}
+
+	dst.PutBytes(commit.Put, idx, uuid[:])
+}
+
+// filterEqual filters down the rows whose UUID is equal to the specified one
+func (c *columnUUID) filterEqual(chunk commit.Chunk, index bitmap.Bitmap, value UUID) {
+	if int(chunk) >= len(c.chunks) || c.chunks[chunk].data == nil {
+		index.Clear()
+		return
+	}
+
+	fill, data := c.chunkAt(chunk)
+	index.And(fill)
+	index.Filter(func(x uint32) bool {
+		return data[x] == value
+	})
+}
+
+// WithUUID filters down the rows whose UUID, in the specified column, is equal to the
+// specified one. The UUIDs are compared directly in the column, without any conversion.
+func (txn *Txn) WithUUID(columnName string, value UUID) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(columnName)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	uuids, ok := c.Column.(*columnUUID)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, "WithUUID", columnName, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		uuids.filterEqual(chunk, index, value)
+	})
+	return txn
+}
+
+// --------------------------- Accessors ----------------------------
+
+// uuidReader represents a read-only accessor for UUIDs
+type uuidReader struct {
+	cursor *uint32
+	reader *columnUUID
+}
+
+// Get loads the value at the current transaction cursor
+func (s uuidReader) Get() (UUID, bool) {
+	return s.reader.LoadUUID(*s.cursor)
+}
+
+// uuidReaderFor creates a new UUID reader
+func uuidReaderFor(txn *Txn, columnName string) uuidReader {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' does not exist", columnName))
+	}
+
+	reader, ok := column.Column.(*columnUUID)
+	if !ok {
+		panic(fmt.Errorf("column: column '%s' is not of type uuid", columnName))
+	}
+
+	return uuidReader{
+		cursor: &txn.cursor,
+		reader: reader,
+	}
+}
+
+// uuidWriter represents read-write accessor for UUIDs
+type uuidWriter struct {
+	uuidReader
+	writer *commit.Buffer
+}
+
+// Set sets the value at the current transaction cursor
+func (s uuidWriter) Set(value UUID) {
+	s.writer.PutBytes(commit.Put, *s.cursor, value[:])
+}
+
+// UUID returns a UUID column accessor
+func (txn *Txn) UUID(columnName string) uuidWriter {
+	return uuidWriter{
+		uuidReader: uuidReaderFor(txn, columnName),
+		writer:     txn.bufferFor(columnName),
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUUID(t *testing.T) {
+	uuid, err := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	assert.NoError(t, err)
+	assert.Equal(t, "f81d4fae-7dec-11d0-a765-00a0c91e6bf6", uuid.String())
+	assert.Equal(t, byte(0xf8), uuid[0])
+	assert.Equal(t, byte(0xf6), uuid[15])
+
+	other, err := ParseUUID("F81D4FAE7DEC11D0A76500A0C91E6BF6")
+	assert.NoError(t, err)
+	assert.Equal(t, uuid, other)
+
+	for _, invalid := range []string{"", "f81d4fae-7dec-11d0-a765", "f81d4fae-7dec-11d0-a765-00a0c91e6bfz"} {
+		_, err := ParseUUID(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestUUIDColumn(t *testing.T) {
+	first, _ := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	second, _ := ParseUUID("9b2e6a4c-1f0d-4c8e-8a3b-5d7f0e2c1a99")
+
+	c := NewCollection()
+	c.CreateColumn("id", ForUUID())
+	c.CreateColumn("name", ForString())
+	c.InsertObject(Object{"id": first, "name": "first"})
+	c.InsertObject(Object{"id": second.String(), "name": "second"})
+	c.InsertObject(Object{"id": second[:], "name": "third"})
+	c.InsertObject(Object{"name": "none"})
+
+	// The UUIDs can be filtered by equality
+	assert.Equal(t, 1, countUUID(c, "id", first))
+	assert.Equal(t, 2, countUUID(c, "id", second))
+	assert.Equal(t, 0, countUUID(c, "id", UUID{}))
+	assert.Equal(t, 0, countUUID(c, "name", first))
+	assert.Equal(t, 0, countUUID(c, "missing", first))
+
+	// The UUIDs can be read and updated with the accessors
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		v, ok := r.UUID("id")
+		assert.True(t, ok)
+		assert.Equal(t, first, v)
+
+		r.SetUUID("id", second)
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(3, func(r Row) error {
+		_, ok := r.UUID("id")
+		assert.False(t, ok)
+
+		v, ok := r.Any("id")
+		assert.False(t, ok)
+		assert.Nil(t, v)
+		return nil
+	}))
+	assert.Equal(t, 3, countUUID(c, "id", second))
+
+	// The values which are not UUIDs are rejected
+	assert.Panics(t, func() {
+		c.InsertObject(Object{"id": "not-a-uuid"})
+	})
+	assert.Panics(t, func() {
+		c.InsertObject(Object{"id": 42})
+	})
+}
+
+func TestUUIDClone(t *testing.T) {
+	uuid, _ := ParseUUID("f81d4fae-7dec-11d0-a765-00a0c91e6bf6")
+	c := NewCollection()
+	c.CreateColumn("id", ForUUID())
+	c.InsertObject(Object{"id": uuid})
+
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, clone.QueryAt(0, func(r Row) error {
+		r.SetUUID("id", UUID{})
+		return nil
+	}))
+
+	assert.Equal(t, 1, countUUID(c, "id", uuid))
+	assert.Equal(t, 0, countUUID(clone, "id", uuid))
+}
+
+// countUUID counts the rows with the specified UUID
+func countUUID(c *Collection, columnName string, value UUID) (count int) {
+	c.Query(func(txn *Txn) error {
+		count = txn.WithUUID(columnName, value).Count()
+		return nil
+	})
+	return
+}
@@ -32,6 +32,7 @@ const (
 	PutTrue  OpType = 2 // PutTrue is a combination of Put+True for boolean values
 	Put      OpType = 2 // Put stores a value regardless of a previous value
 	Add      OpType = 3 // Add increments the current stored value by the amount
+	Saturate OpType = 4 // Saturate increments the current stored value, clamped to the bounds of its type
 )
 
 // --------------------------- Delta log ----------------------------
@@ -86,6 +87,63 @@ func (b *Buffer) Reset(column string) {
 	b.Column = column
 }
 
+// Mark represents a position in a buffer, which the buffer can be truncated back to.
+type Mark struct {
+	size   int   // The size of the buffer
+	chunks int   // The number of chunk headers
+	last   int32 // The last offset written
+	chunk  Chunk // The current chunk
+}
+
+// Mark returns the current position of the buffer, so that the operations appended from
+// now on can be discarded with Truncate.
+func (b *Buffer) Mark() Mark {
+	return Mark{
+		size:   len(b.buffer),
+		chunks: len(b.chunks),
+		last:   b.last,
+		chunk:  b.chunk,
+	}
+}
+
+// Truncate discards the operations which were appended since the position was marked.
+func (b *Buffer) Truncate(m Mark) {
+	if m.size > len(b.buffer) || m.chunks > len(b.chunks) {
+		return // Not a position of this buffer
+	}
+
+	b.buffer = b.buffer[:m.size]
+	b.chunks = b.chunks[:m.chunks]
+	b.last = m.last
+	b.chunk = m.chunk
+}
+
+// Since returns a buffer with the operations which were appended since the position was
+// marked. The returned buffer shares the memory of this one and must not be written to.
+func (b *Buffer) Since(m Mark) *Buffer {
+	out := &Buffer{
+		Column: b.Column,
+		buffer: b.buffer[m.size:],
+		last:   b.last,
+		chunk:  b.chunk,
+	}
+
+	// The operations might continue the chunk which was current at the mark
+	if m.chunks > 0 && len(b.buffer) > m.size && (len(b.chunks) == m.chunks || int(b.chunks[m.chunks].Start) > m.size) {
+		out.chunks = append(out.chunks, header{
+			Chunk: m.chunk,
+			Start: 0,
+			Value: uint32(m.last),
+		})
+	}
+
+	for _, h := range b.chunks[m.chunks:] {
+		h.Start -= uint32(m.size)
+		out.chunks = append(out.chunks, h)
+	}
+	return out
+}
+
 // IsEmpty returns whether the buffer is empty or not.
 func (b *Buffer) IsEmpty() bool {
 	return len(b.buffer) == 0
@@ -247,6 +305,58 @@ func (b *Buffer) AddFloat32(idx uint32, value float32) {
 	b.writeUint32(Add, idx, math.Float32bits(value))
 }
 
+// --------------------------- Saturating Additions ----------------------------
+
+// AddSaturateInt appends a saturating addition of int value.
+func (b *Buffer) AddSaturateInt(idx uint32, value int) {
+	b.writeUint64(Saturate, idx, uint64(value))
+}
+
+// AddSaturateInt16 appends a saturating addition of int16 value.
+func (b *Buffer) AddSaturateInt16(idx uint32, value int16) {
+	b.writeUint16(Saturate, idx, uint16(value))
+}
+
+// AddSaturateInt32 appends a saturating addition of int32 value.
+func (b *Buffer) AddSaturateInt32(idx uint32, value int32) {
+	b.writeUint32(Saturate, idx, uint32(value))
+}
+
+// AddSaturateInt64 appends a saturating addition of int64 value.
+func (b *Buffer) AddSaturateInt64(idx uint32, value int64) {
+	b.writeUint64(Saturate, idx, uint64(value))
+}
+
+// AddSaturateUint appends a saturating addition of uint value.
+func (b *Buffer) AddSaturateUint(idx uint32, value uint) {
+	b.writeUint64(Saturate, idx, uint64(value))
+}
+
+// AddSaturateUint16 appends a saturating addition of uint16 value.
+func (b *Buffer) AddSaturateUint16(idx uint32, value uint16) {
+	b.writeUint16(Saturate, idx, value)
+}
+
+// AddSaturateUint32 appends a saturating addition of uint32 value.
+func (b *Buffer) AddSaturateUint32(idx uint32, value uint32) {
+	b.writeUint32(Saturate, idx, value)
+}
+
+// AddSaturateUint64 appends a saturating addition of uint64 value.
+func (b *Buffer) AddSaturateUint64(idx uint32, value uint64) {
+	b.writeUint64(Saturate, idx, value)
+}
+
+// AddSaturateFloat32 appends a saturating addition of float32 value.
+func (b *Buffer) AddSaturateFloat32(idx uint32, value float32) {
+	b.writeUint32(Saturate, idx, math.Float32bits(value))
+}
+
+// AddSaturateFloat64 appends a saturating addition of float64 value.
+func (b *Buffer) AddSaturateFloat64(idx uint32, value float64) {
+	b.writeUint64(Saturate, idx, math.Float64bits(value))
+}
+
 // AddNumber appends an addition of float64 value.
 func (b *Buffer) AddNumber(idx uint32, value float64) {
 	b.writeUint64(Add, idx, math.Float64bits(value))
@@ -306,6 +416,28 @@ func (b *Buffer) PutString(op OpType, idx uint32, value string) {
 	b.PutBytes(op, idx, toBytes(value))
 }
 
+// PutFrom copies the current operation of the reader, along with its value, onto the buffer
+// at the specified index.
+func (b *Buffer) PutFrom(idx uint32, r *Reader) {
+	value := r.buffer[r.i0:r.i1]
+	if r.kind&isString != 0 {
+		b.PutBytes(r.Type, idx, value)
+		return
+	}
+
+	head := r.kind &^ isNext
+	delta := b.writeChunk(idx)
+	if delta == 1 {
+		head |= isNext
+	}
+
+	b.buffer = append(b.buffer, head)
+	b.buffer = append(b.buffer, value...)
+	if delta != 1 {
+		b.writeOffset(uint32(delta))
+	}
+}
+
 // PutBitmap iterates over the bitmap values and appends an operation for each bit set to one
 func (b *Buffer) PutBitmap(op OpType, chunk Chunk, value bitmap.Bitmap) {
 	chunk.Range(value, func(idx uint32) {
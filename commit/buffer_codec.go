@@ -6,8 +6,6 @@ package commit
 import (
 	"encoding/binary"
 	"io"
-	"reflect"
-	"unsafe"
 
 	"github.com/kelindar/iostream"
 )
@@ -93,15 +91,3 @@ func readChunksFrom(r *iostream.Reader) ([]header, error) {
 	}
 	return v, nil
 }
-
-// toBytes converts a string to a byte slice without allocating.
-func toBytes(v string) (b []byte) {
-	strHeader := (*reflect.StringHeader)(unsafe.Pointer(&v))
-	byteHeader := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	byteHeader.Data = strHeader.Data
-
-	l := len(v)
-	byteHeader.Len = l
-	byteHeader.Cap = l
-	return
-}
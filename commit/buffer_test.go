@@ -270,6 +270,61 @@ func TestBufferClone(t *testing.T) {
 	assert.EqualValues(t, buf, cloned)
 }
 
+func TestBufferMark(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutInt16(10, 100)
+	buf.PutInt16(70000, 200)
+	mark := buf.Mark()
+	expect := buf.Clone()
+
+	// Continue the current chunk, then switch to other chunks
+	buf.PutInt16(70001, 300)
+	buf.PutInt16(20, 400)
+	buf.PutString(Put, 70005, "hello")
+
+	// The operations since the mark are read in their chunks
+	since := buf.Since(mark)
+	r := NewReader()
+	var offsets []uint32
+	for _, chunk := range []Chunk{4, 0} {
+		r.Range(since, chunk, func(r *Reader) {
+			for r.Next() {
+				offsets = append(offsets, r.Index())
+			}
+		})
+	}
+	assert.Equal(t, []uint32{70001, 70005, 20}, offsets)
+
+	// Once truncated, the buffer is as it was at the mark
+	buf.Truncate(mark)
+	assert.EqualValues(t, expect.buffer, buf.buffer)
+	assert.EqualValues(t, expect.chunks, buf.chunks)
+
+	buf.PutInt16(70001, 300)
+	r.Seek(buf)
+	offsets = offsets[:0]
+	for r.Next() {
+		offsets = append(offsets, r.Index())
+	}
+	assert.Equal(t, []uint32{10, 70000, 70001}, offsets)
+
+	// A mark which starts a new chunk
+	mark = buf.Mark()
+	buf.PutInt16(5, 500)
+	since = buf.Since(mark)
+	assert.Equal(t, 1, len(since.chunks))
+	r.Seek(since)
+	assert.True(t, r.Next())
+
+	// A mark of an empty buffer
+	empty := NewBuffer(0)
+	mark = empty.Mark()
+	empty.PutInt16(5, 500)
+	assert.Equal(t, 1, len(empty.Since(mark).chunks))
+	empty.Truncate(mark)
+	assert.True(t, empty.IsEmpty())
+}
+
 func TestPutNil(t *testing.T) {
 	buf := NewBuffer(0)
 	buf.PutAny(PutTrue, 0, nil)
@@ -290,6 +345,26 @@ func TestPutBitmap(t *testing.T) {
 	assert.Equal(t, Insert, r.Type)
 }
 
+func TestPutFrom(t *testing.T) {
+	input := NewBuffer(0)
+	input.PutInt16(10, 100)
+	input.PutString(Put, 20, "hello")
+	input.AddInt64(21, 5)
+	input.PutBool(70000, true)
+	input.PutOperation(Delete, 30)
+
+	// Copy every operation, one by one
+	output := NewBuffer(0)
+	r := NewReader()
+	r.Seek(input)
+	for r.Next() {
+		output.PutFrom(r.Index(), r)
+	}
+
+	assert.Equal(t, input.buffer, output.buffer)
+	assert.Equal(t, input.chunks, output.chunks)
+}
+
 func TestBufferWriteTo(t *testing.T) {
 	input := NewBuffer(0)
 	input.Column = "test"
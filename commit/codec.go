@@ -0,0 +1,230 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+var errInvalidBlock = errors.New("commit: unable to decompress, the block is invalid")
+
+// maxBlock is the maximum size of the uncompressed data of a block
+const maxBlock = 1 << 20
+
+// Codec represents a compression scheme of blocks of data. The destination of Encode and
+// Decode is an optional buffer which can be reused for the result, and can be nil.
+type Codec interface {
+	Encode(dst, src []byte) []byte
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+// Built-in codecs which can be used to compress the blocks of data
+var (
+	S2     Codec = codecFunc{encode: s2.Encode, decode: s2.Decode}
+	Snappy Codec = codecFunc{encode: snappy.Encode, decode: snappy.Decode}
+	Zstd   Codec = newZstd()
+)
+
+// codecFunc represents a codec implemented by a pair of functions
+type codecFunc struct {
+	encode func(dst, src []byte) []byte
+	decode func(dst, src []byte) ([]byte, error)
+}
+
+// Encode encodes the source into the destination
+func (c codecFunc) Encode(dst, src []byte) []byte {
+	return c.encode(dst, src)
+}
+
+// Decode decodes the source into the destination
+func (c codecFunc) Decode(dst, src []byte) ([]byte, error) {
+	return c.decode(dst, src)
+}
+
+// zstdCodec represents a codec which uses zstandard, its encoder and decoder are created on
+// first use and are safe for concurrent use when encoding and decoding entire blocks.
+type zstdCodec struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// newZstd creates a new zstandard codec
+func newZstd() *zstdCodec {
+	return new(zstdCodec)
+}
+
+// init creates the encoder and the decoder, the codec being unusable without them
+func (c *zstdCodec) init() {
+	c.once.Do(func() {
+		var err error
+		if c.encoder, err = zstd.NewWriter(nil); err == nil {
+			c.decoder, err = zstd.NewReader(nil)
+		}
+		if err != nil {
+			panic(fmt.Errorf("commit: unable to create the zstd codec, %w", err))
+		}
+	})
+}
+
+// Encode encodes the source into the destination
+func (c *zstdCodec) Encode(dst, src []byte) []byte {
+	c.init()
+	return c.encoder.EncodeAll(src, dst[:0])
+}
+
+// Decode decodes the source into the destination
+func (c *zstdCodec) Decode(dst, src []byte) ([]byte, error) {
+	c.init()
+	return c.decoder.DecodeAll(src, dst[:0])
+}
+
+// --------------------------- Compressor ----------------------------
+
+// Compressor represents a writer which compresses the data written into it with a codec. The
+// data is buffered and written in blocks, each prefixed with its size, so that the reader
+// never reads beyond the end of the stream.
+type Compressor struct {
+	dst     io.Writer // The destination of the compressed blocks
+	codec   Codec     // The codec of the blocks
+	buffer  []byte    // The uncompressed data of the current block
+	encoded []byte    // The buffer of the compressed data
+	block   []byte    // The buffer of the block, along with its size
+}
+
+// NewCompressor creates a writer which compresses the data with the codec and writes it into
+// the destination. The writer must be closed once all of the data has been written, so that
+// the reader knows where the stream ends.
+func NewCompressor(dst io.Writer, codec Codec) *Compressor {
+	return &Compressor{
+		dst:   dst,
+		codec: codec,
+	}
+}
+
+// Write buffers the data and writes the blocks which are full
+func (w *Compressor) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		n := minInt(len(p)-written, maxBlock-len(w.buffer))
+		w.buffer = append(w.buffer, p[written:written+n]...)
+		written += n
+
+		if len(w.buffer) == maxBlock {
+			if err := w.Flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Flush compresses the buffered data and writes it as a block into the destination
+func (w *Compressor) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	w.encoded = w.codec.Encode(w.encoded[:0], w.buffer)
+	w.buffer = w.buffer[:0]
+	return w.writeBlock(w.encoded)
+}
+
+// Close flushes the buffered data and writes the end of the stream. It does not close the
+// destination.
+func (w *Compressor) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return w.writeBlock(nil)
+}
+
+// writeBlock writes a block prefixed with its size, an empty block ends the stream
+func (w *Compressor) writeBlock(encoded []byte) error {
+	w.block = append(w.block[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(w.block, uint32(len(encoded)))
+	w.block = append(w.block, encoded...)
+	_, err := w.dst.Write(w.block)
+	return err
+}
+
+// --------------------------- Decompressor ----------------------------
+
+// Decompressor represents a reader which decompresses the data written by a Compressor.
+type Decompressor struct {
+	src     io.Reader // The source of the compressed blocks
+	codec   Codec     // The codec of the blocks
+	block   []byte    // The buffer of the compressed block
+	buffer  []byte    // The buffer of the decompressed block
+	pending []byte    // The decompressed data which was not read yet
+	done    bool      // Whether the end of the stream was read
+}
+
+// NewDecompressor creates a reader which decompresses the data of the source with the codec.
+// The reader stops right after the end of the stream, so that the source can be read further.
+func NewDecompressor(src io.Reader, codec Codec) *Decompressor {
+	return &Decompressor{
+		src:   src,
+		codec: codec,
+	}
+}
+
+// Read reads the decompressed data
+func (r *Decompressor) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next reads and decompresses the next block of the source
+func (r *Decompressor) next() error {
+	var head [4]byte
+	if _, err := io.ReadFull(r.src, head[:]); err != nil {
+		return err // io.EOF if the source has ended, since the commit logs are never closed
+	}
+
+	size := binary.BigEndian.Uint32(head[:])
+	switch {
+	case size == 0:
+		r.done = true
+		return nil
+	case size > uint32(s2.MaxEncodedLen(maxBlock)):
+		return errInvalidBlock
+	}
+
+	if cap(r.block) < int(size) {
+		r.block = make([]byte, size)
+	}
+
+	r.block = r.block[:size]
+	if _, err := io.ReadFull(r.src, r.block); err != nil {
+		return unexpected(err)
+	}
+
+	decoded, err := r.codec.Decode(r.buffer[:0], r.block)
+	if err != nil {
+		return errInvalidBlock
+	}
+
+	r.buffer = decoded
+	r.pending = decoded
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCodecs = map[string]Codec{
+	"s2":     S2,
+	"snappy": Snappy,
+	"zstd":   Zstd,
+}
+
+func compressAll(t *testing.T, codec Codec, input []byte) []byte {
+	buffer := bytes.NewBuffer(nil)
+	w := NewCompressor(buffer, codec)
+	_, err := w.Write(input)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buffer.Bytes()
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for name, codec := range testCodecs {
+		for _, size := range []int{0, 1, 100, maxBlock, maxBlock*2 + 7} {
+			input := bytes.Repeat([]byte("hello world "), size/12+1)[:size]
+			output := compressAll(t, codec, input)
+			assert.Less(t, len(output), len(input)/2+32, name)
+
+			// The reader stops at the end of the stream
+			src := bytes.NewReader(append(output, "trailer"...))
+			plain, err := io.ReadAll(NewDecompressor(src, codec))
+			assert.NoError(t, err, name)
+			assert.Equal(t, input, append([]byte{}, plain...), name)
+
+			rest, err := io.ReadAll(src)
+			assert.NoError(t, err)
+			assert.Equal(t, "trailer", string(rest))
+		}
+	}
+}
+
+func TestCodecInvalid(t *testing.T) {
+	output := compressAll(t, Zstd, []byte("hello world"))
+
+	// Truncated blocks
+	for i := 1; i < len(output)-4; i++ {
+		_, err := io.ReadAll(NewDecompressor(bytes.NewReader(output[:i]), Zstd))
+		assert.Error(t, err)
+	}
+
+	// Corrupted blocks
+	corrupted := append([]byte{}, output...)
+	corrupted[6] ^= 0xff
+	_, err := io.ReadAll(NewDecompressor(bytes.NewReader(corrupted), Zstd))
+	assert.Equal(t, errInvalidBlock, err)
+
+	// Blocks which are too large
+	_, err = io.ReadAll(NewDecompressor(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), Zstd))
+	assert.Equal(t, errInvalidBlock, err)
+}
+
+func TestCodecFlush(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewCompressor(buffer, Snappy)
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, 0, buffer.Len())
+
+	_, err := w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, buffer.Len())
+	assert.NoError(t, w.Flush())
+	assert.NotZero(t, buffer.Len())
+}
+
+func TestLogWithCodec(t *testing.T) {
+	for name, codec := range testCodecs {
+		for _, keys := range []KeyProvider{nil, newKeyring("k1")} {
+			options := LogOptions{Codec: codec, Encryption: keys}
+			buffer := bytes.NewBuffer(nil)
+			logger := OpenWith(buffer, options)
+			assert.NoError(t, logger.Append(newCommit(1)))
+			assert.NoError(t, logger.Append(newCommit(2)))
+
+			var arr []uint64
+			assert.NoError(t, OpenWith(buffer, options).Range(func(commit Commit) error {
+				arr = append(arr, commit.ID)
+				return nil
+			}), name)
+			assert.Equal(t, []uint64{1, 2}, arr, name)
+		}
+	}
+}
@@ -16,9 +16,27 @@ import (
 
 var id uint64 = uint64(time.Now().UnixNano())
 
-// Next returns the next commit ID
+// Next returns the next commit ID. The IDs follow the wall clock, in nanoseconds since the
+// epoch, while remaining strictly increasing. Hence, the ID of a commit can be compared
+// with a point in time, for example to reconstruct the state of a collection at that time.
 func Next() uint64 {
-	return atomic.AddUint64(&id, 1)
+	for {
+		last := atomic.LoadUint64(&id)
+		next := last + 1
+		if now := uint64(time.Now().UnixNano()); now > next {
+			next = now
+		}
+
+		if atomic.CompareAndSwapUint64(&id, last, next) {
+			return next
+		}
+	}
+}
+
+// At returns the commit ID which corresponds to a point in time. Every commit with a lower
+// or equal ID was made at or before that time.
+func At(t time.Time) uint64 {
+	return uint64(t.UnixNano())
 }
 
 // --------------------------- Chunk ----------------------------
@@ -72,17 +90,31 @@ func min(v1, v2 int32) int32 {
 
 // --------------------------- Commit ----------------------------
 
+// The flags set on the encoded chunk number when the commit carries the optional fields, so
+// that the commits encoded without them can still be read back.
+const (
+	metaFlag = 1 << 32 // The commit carries metadata
+	seqFlag  = 1 << 33 // The commit carries a sequence number
+)
+
 // Commit represents an individual transaction commit. If multiple chunks are committed
 // in the same transaction, it would result in multiple commits per transaction.
 type Commit struct {
 	ID      uint64    // The commit ID
 	Chunk   Chunk     // The chunk number
 	Updates []*Buffer // The update buffers
+	Meta    []byte    // The metadata supplied by the caller of the transaction (optional)
+	Seq     uint64    // The sequence number of the commit in the commit log of its collection
 }
 
 // Clone clones a commit into a new one
 func (c *Commit) Clone() (clone Commit) {
+	clone.ID = c.ID
 	clone.Chunk = c.Chunk
+	clone.Seq = c.Seq
+	if len(c.Meta) > 0 {
+		clone.Meta = append([]byte(nil), c.Meta...)
+	}
 	for _, u := range c.Updates {
 		if len(u.buffer) > 0 {
 			clone.Updates = append(clone.Updates, u.Clone())
@@ -96,8 +128,15 @@ func (c *Commit) Clone() (clone Commit) {
 func (c *Commit) WriteTo(dst io.Writer) (int64, error) {
 	w := iostream.NewWriter(dst)
 
-	// Write the chunk ID
-	if err := w.WriteUvarint(uint64(c.Chunk)); err != nil {
+	// Write the chunk ID, flagged if the metadata follows
+	chunk := uint64(c.Chunk)
+	if len(c.Meta) > 0 {
+		chunk |= metaFlag
+	}
+	if c.Seq > 0 {
+		chunk |= seqFlag
+	}
+	if err := w.WriteUvarint(chunk); err != nil {
 		return w.Offset(), err
 	}
 
@@ -106,6 +145,20 @@ func (c *Commit) WriteTo(dst io.Writer) (int64, error) {
 		return w.Offset(), err
 	}
 
+	// Write the sequence number, if any
+	if c.Seq > 0 {
+		if err := w.WriteUvarint(c.Seq); err != nil {
+			return w.Offset(), err
+		}
+	}
+
+	// Write the metadata, if any
+	if len(c.Meta) > 0 {
+		if err := w.WriteBytes(c.Meta); err != nil {
+			return w.Offset(), err
+		}
+	}
+
 	// Write all of the columns for the current chunk
 	reader := NewReader()
 	if err := w.WriteRange(len(c.Updates), func(i int, w *iostream.Writer) error {
@@ -156,8 +209,9 @@ func (c *Commit) ReadFrom(src io.Reader) (int64, error) {
 	r := iostream.NewReader(src)
 
 	// Read chunk ID
-	chunk, err := r.ReadUvarint()
-	c.Chunk = Chunk(chunk)
+	flagged, err := r.ReadUvarint()
+	chunk := Chunk(flagged &^ (metaFlag | seqFlag))
+	c.Chunk = chunk
 	if err != nil {
 		return r.Offset(), err
 	}
@@ -167,6 +221,22 @@ func (c *Commit) ReadFrom(src io.Reader) (int64, error) {
 		return r.Offset(), err
 	}
 
+	// Read the sequence number, if any
+	c.Seq = 0
+	if flagged&seqFlag != 0 {
+		if c.Seq, err = r.ReadUvarint(); err != nil {
+			return r.Offset(), err
+		}
+	}
+
+	// Read the metadata, if any
+	c.Meta = nil
+	if flagged&metaFlag != 0 {
+		if c.Meta, err = r.ReadBytes(); err != nil {
+			return r.Offset(), err
+		}
+	}
+
 	// Read each update buffer in the commit
 	if err := r.ReadRange(func(i int, r *iostream.Reader) error {
 		buffer := NewBuffer(256)
@@ -182,7 +252,7 @@ func (c *Commit) ReadFrom(src io.Reader) (int64, error) {
 		buffer.Reset(column)
 		r.ReadRange(func(i int, r *iostream.Reader) error {
 			header := header{
-				Chunk: Chunk(chunk),
+				Chunk: chunk,
 			}
 
 			// Previous offset and index in the byte array
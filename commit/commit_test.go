@@ -9,6 +9,7 @@ import (
 	"io"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/kelindar/bitmap"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +36,7 @@ func BenchmarkColumn(b *testing.B) {
 
 func TestCommitClone(t *testing.T) {
 	commit := Commit{
+		ID: 42,
 		Updates: []*Buffer{{
 			buffer: []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f},
 			chunks: []header{{
@@ -47,6 +49,18 @@ func TestCommitClone(t *testing.T) {
 	assert.EqualValues(t, commit, clone)
 }
 
+func TestNext(t *testing.T) {
+	before := At(time.Now())
+	last := Next()
+	for i := 0; i < 1000; i++ {
+		next := Next()
+		assert.Greater(t, next, last)
+		last = next
+	}
+
+	assert.Greater(t, last, before)
+}
+
 func TestWriterChannel(t *testing.T) {
 	w := make(Channel, 1)
 	w.Append(Commit{
@@ -174,6 +188,30 @@ func TestCommitCodec(t *testing.T) {
 	assert.Equal(t, []int64{20, 1, 21, 2, 40, 4, 41, 5, 60, 7, 61, 8}, updates)
 }
 
+func TestCommitCodecWithMeta(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	input := Commit{
+		ID:      Next(),
+		Chunk:   3,
+		Updates: []*Buffer{newInterleaved("a")},
+		Meta:    []byte("request-42"),
+		Seq:     7,
+	}
+
+	n, err := input.WriteTo(buffer)
+	assert.NoError(t, err)
+
+	output := Commit{}
+	m, err := output.ReadFrom(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, n, m)
+	assert.Equal(t, input.ID, output.ID)
+	assert.Equal(t, Chunk(3), output.Chunk)
+	assert.Equal(t, []byte("request-42"), output.Meta)
+	assert.Equal(t, uint64(7), output.Seq)
+	assert.Equal(t, []byte("request-42"), output.Clone().Meta)
+}
+
 // newInterleaved creates a new interleaved buffer
 func newInterleaved(columnName string) *Buffer {
 	buf := NewBuffer(10)
@@ -0,0 +1,301 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	errCorrupted = errors.New("commit: unable to decrypt, the stream is corrupted")
+	errNoHeader  = errors.New("commit: unable to decrypt, the stream has no header")
+)
+
+// Tags of the records of an encrypted stream
+const (
+	tagHeader = 'H' // The header of a stream, identifying its key
+	tagData   = 'D' // A frame of encrypted data
+	tagFinal  = 'F' // The last frame of a stream, which is followed by other data
+)
+
+// maxFrame is the maximum size of the plaintext of a frame
+const maxFrame = 1 << 20
+
+// KeyProvider represents a provider of the keys used to encrypt the snapshots and the commit
+// logs with AES-GCM. Every key has an identifier which is written along with the encrypted
+// data, so that the keys can be rotated: the new data is encrypted with the current key, while
+// the data encrypted with a previous key remains readable as long as its key is provided.
+type KeyProvider interface {
+	CurrentKey() (id string, key []byte, err error)
+	Key(id string) ([]byte, error)
+}
+
+// Keyring represents a static set of keys of 16, 24 or 32 bytes, for AES-128, AES-192 or
+// AES-256 respectively. The data is encrypted with the current key.
+type Keyring struct {
+	Current string            // The identifier of the key used for encryption
+	Keys    map[string][]byte // The keys by their identifier
+}
+
+// CurrentKey returns the key used for encryption
+func (k *Keyring) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key returns the key with the specified identifier
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("commit: encryption key '%s' does not exist", id)
+	}
+	return key, nil
+}
+
+// newAEAD creates an AES-GCM cipher for the key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// --------------------------- Encrypter ----------------------------
+
+// Encrypter represents a writer which encrypts the data written into it with AES-GCM. Each
+// write is sealed into one or more frames, authenticated along with their position in the
+// stream, so that the frames can neither be modified nor reordered.
+type Encrypter struct {
+	dst    io.Writer   // The destination of the encrypted frames
+	aead   cipher.AEAD // The cipher of the current key
+	stream [16]byte    // The random identifier of the stream
+	seq    uint64      // The sequence number of the next frame
+	buffer []byte      // The buffer of the frames
+}
+
+// NewEncrypter creates a writer which encrypts the data with the current key of the provider
+// and writes it into the destination. The writer must be closed once all of the data has been
+// written, so that the reader knows where the stream ends.
+func NewEncrypter(dst io.Writer, keys KeyProvider) (*Encrypter, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Encrypter{dst: dst, aead: aead}
+	if _, err := rand.Read(w.stream[:]); err != nil {
+		return nil, err
+	}
+
+	// Write the header with the identifier of the key and of the stream
+	header := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(id)+len(w.stream))
+	header[0] = tagHeader
+	header = header[:1+binary.PutUvarint(header[1:], uint64(len(id)))]
+	header = append(append(header, id...), w.stream[:]...)
+	if _, err := dst.Write(header); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write encrypts the data and writes it into the destination
+func (w *Encrypter) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		n := minInt(len(p)-written, maxFrame)
+		if err := w.seal(tagData, p[written:written+n]); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(p), nil
+}
+
+// Close writes the last frame of the stream. It does not close the destination.
+func (w *Encrypter) Close() error {
+	return w.seal(tagFinal, nil)
+}
+
+// seal encrypts a frame and writes it into the destination
+func (w *Encrypter) seal(tag byte, plaintext []byte) error {
+	size := w.aead.NonceSize() + len(plaintext) + w.aead.Overhead()
+	w.buffer = append(w.buffer[:0], make([]byte, 5+w.aead.NonceSize())...)
+	w.buffer[0] = tag
+	binary.BigEndian.PutUint32(w.buffer[1:5], uint32(size))
+
+	nonce := w.buffer[5:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	w.buffer = w.aead.Seal(w.buffer, nonce, plaintext, frameData(w.stream, w.seq, tag))
+	w.seq++
+	_, err := w.dst.Write(w.buffer)
+	return err
+}
+
+// frameData returns the additional data authenticated along with a frame
+func frameData(stream [16]byte, seq uint64, tag byte) []byte {
+	var data [25]byte
+	copy(data[:], stream[:])
+	binary.BigEndian.PutUint64(data[16:], seq)
+	data[24] = tag
+	return data[:]
+}
+
+// --------------------------- Decrypter ----------------------------
+
+// Decrypter represents a reader which decrypts the data written by an Encrypter. Several
+// encrypted streams can be appended one after the other, as long as none of them is closed.
+type Decrypter struct {
+	src     io.Reader   // The source of the encrypted frames
+	keys    KeyProvider // The provider of the keys
+	aead    cipher.AEAD // The cipher of the current stream
+	stream  [16]byte    // The random identifier of the current stream
+	seq     uint64      // The sequence number of the next frame
+	frame   []byte      // The buffer of the current frame
+	pending []byte      // The decrypted data which was not read yet
+	done    bool        // Whether the last frame was read
+}
+
+// NewDecrypter creates a reader which decrypts the data of the source. The reader stops right
+// after the last frame of the stream, so that the source can be read further.
+func NewDecrypter(src io.Reader, keys KeyProvider) *Decrypter {
+	return &Decrypter{
+		src:  src,
+		keys: keys,
+	}
+}
+
+// Read reads the decrypted data
+func (r *Decrypter) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next reads the next record of the source
+func (r *Decrypter) next() error {
+	var head [5]byte
+	if _, err := io.ReadFull(r.src, head[:1]); err != nil {
+		return err // io.EOF if the source has ended
+	}
+
+	switch head[0] {
+	case tagHeader:
+		return r.readHeader()
+	case tagData, tagFinal:
+		if r.aead == nil {
+			return errNoHeader
+		}
+	default:
+		return errCorrupted
+	}
+
+	// Read the frame and decrypt it
+	if _, err := io.ReadFull(r.src, head[1:]); err != nil {
+		return unexpected(err)
+	}
+
+	size := int(binary.BigEndian.Uint32(head[1:]))
+	if size < r.aead.NonceSize()+r.aead.Overhead() || size > r.aead.NonceSize()+maxFrame+r.aead.Overhead() {
+		return errCorrupted
+	}
+
+	if cap(r.frame) < size {
+		r.frame = make([]byte, size)
+	}
+
+	r.frame = r.frame[:size]
+	if _, err := io.ReadFull(r.src, r.frame); err != nil {
+		return unexpected(err)
+	}
+
+	nonce, sealed := r.frame[:r.aead.NonceSize()], r.frame[r.aead.NonceSize():]
+	plaintext, err := r.aead.Open(sealed[:0], nonce, sealed, frameData(r.stream, r.seq, head[0]))
+	if err != nil {
+		return errCorrupted
+	}
+
+	r.seq++
+	r.pending = plaintext
+	r.done = head[0] == tagFinal
+	return nil
+}
+
+// readHeader reads the header of a stream and loads its key
+func (r *Decrypter) readHeader() error {
+	size, err := binary.ReadUvarint(byteReader{r.src})
+	if err != nil || size > 1024 {
+		return errCorrupted
+	}
+
+	id := make([]byte, size)
+	if _, err := io.ReadFull(r.src, id); err != nil {
+		return unexpected(err)
+	}
+
+	if _, err := io.ReadFull(r.src, r.stream[:]); err != nil {
+		return unexpected(err)
+	}
+
+	key, err := r.keys.Key(string(id))
+	if err != nil {
+		return err
+	}
+
+	r.seq = 0
+	r.aead, err = newAEAD(key)
+	return err
+}
+
+// unexpected converts the end of the source within a record into an error
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// byteReader reads the source one byte at a time, without reading ahead
+type byteReader struct {
+	io.Reader
+}
+
+// ReadByte reads a single byte
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], unexpected(err)
+}
+
+// minInt returns the minimum of two integers
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
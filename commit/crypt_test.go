@@ -0,0 +1,140 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeyring(current string) *Keyring {
+	return &Keyring{
+		Current: current,
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+			"k2": bytes.Repeat([]byte{2}, 16),
+		},
+	}
+}
+
+func encrypt(t *testing.T, keys KeyProvider, input []byte) []byte {
+	buffer := bytes.NewBuffer(nil)
+	w, err := NewEncrypter(buffer, keys)
+	assert.NoError(t, err)
+	_, err = w.Write(input)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buffer.Bytes()
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 100, maxFrame, maxFrame*2 + 7} {
+		input := bytes.Repeat([]byte("secret"), size/6+1)[:size]
+		output := encrypt(t, newKeyring("k1"), input)
+		if size > 0 {
+			// The output is randomized and does not contain the input in the clear
+			assert.NotEqual(t, output, encrypt(t, newKeyring("k1"), input))
+			for i := 0; i < 6 && i+16 <= size; i++ {
+				assert.False(t, bytes.Contains(output, input[i:i+16]))
+			}
+		}
+
+		plain, err := io.ReadAll(NewDecrypter(bytes.NewReader(output), newKeyring("k1")))
+		assert.NoError(t, err)
+		assert.Equal(t, input, append([]byte{}, plain...))
+	}
+}
+
+func TestEncryptKeyRotation(t *testing.T) {
+	old := encrypt(t, newKeyring("k1"), []byte("hello"))
+
+	// The data encrypted with a previous key remains readable
+	plain, err := io.ReadAll(NewDecrypter(bytes.NewReader(old), newKeyring("k2")))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(plain))
+
+	// But not once the key is removed
+	keys := newKeyring("k2")
+	delete(keys.Keys, "k1")
+	_, err = io.ReadAll(NewDecrypter(bytes.NewReader(old), keys))
+	assert.Error(t, err)
+
+	// Or if the current key does not exist
+	_, err = NewEncrypter(bytes.NewBuffer(nil), newKeyring("k3"))
+	assert.Error(t, err)
+}
+
+func TestEncryptTampering(t *testing.T) {
+	output := encrypt(t, newKeyring("k1"), []byte("hello world"))
+	for i := 0; i < len(output); i++ {
+		tampered := append([]byte{}, output...)
+		tampered[i] ^= 0xff
+
+		_, err := io.ReadAll(NewDecrypter(bytes.NewReader(tampered), newKeyring("k1")))
+		assert.Error(t, err)
+	}
+
+	// Streams truncated within a record are rejected as well
+	header, frame := 1+1+2+16, 5+12+len("hello world")+16
+	for i := 1; i < len(output); i++ {
+		if i == header || i == header+frame {
+			continue
+		}
+
+		_, err := io.ReadAll(NewDecrypter(bytes.NewReader(output[:i]), newKeyring("k1")))
+		assert.Error(t, err)
+	}
+}
+
+func TestDecryptStopsAtFinal(t *testing.T) {
+	output := encrypt(t, newKeyring("k1"), []byte("hello"))
+	src := bytes.NewReader(append(output, "trailer"...))
+
+	plain, err := io.ReadAll(NewDecrypter(src, newKeyring("k1")))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(plain))
+
+	rest, err := io.ReadAll(src)
+	assert.NoError(t, err)
+	assert.Equal(t, "trailer", string(rest))
+}
+
+func TestDecryptNoHeader(t *testing.T) {
+	_, err := io.ReadAll(NewDecrypter(bytes.NewReader([]byte{tagData, 0, 0, 0, 0}), newKeyring("k1")))
+	assert.Equal(t, errNoHeader, err)
+
+	_, err = io.ReadAll(NewDecrypter(bytes.NewReader([]byte{'X'}), newKeyring("k1")))
+	assert.Equal(t, errCorrupted, err)
+}
+
+func TestLogEncrypted(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	logger := OpenEncrypted(buffer, newKeyring("k1"))
+	assert.NoError(t, logger.Append(newCommit(1)))
+	assert.NoError(t, logger.Append(newCommit(2)))
+
+	// Logs written with different keys can be appended one after the other
+	other := OpenEncrypted(buffer, newKeyring("k2"))
+	assert.NoError(t, other.Append(newCommit(3)))
+
+	var arr []uint64
+	assert.NoError(t, OpenEncrypted(buffer, newKeyring("k1")).Range(func(commit Commit) error {
+		arr = append(arr, commit.ID)
+		return nil
+	}))
+	assert.Equal(t, []uint64{1, 2, 3}, arr)
+
+	// A read-only log can not be appended to
+	assert.Error(t, OpenEncrypted(bytes.NewReader(nil), newKeyring("k1")).Append(newCommit(4)))
+}
+
+func TestLogOpenTempEncrypted(t *testing.T) {
+	logger, err := OpenTempWith(LogOptions{Encryption: newKeyring("k1")})
+	assert.NoError(t, err)
+	defer logger.Close()
+	assert.NotEmpty(t, logger.Name())
+}
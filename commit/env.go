@@ -0,0 +1,28 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && !wasm
+
+package commit
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// toBytes converts a string to a byte slice without allocating.
+func toBytes(v string) (b []byte) {
+	strHeader := (*reflect.StringHeader)(unsafe.Pointer(&v))
+	byteHeader := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	byteHeader.Data = strHeader.Data
+
+	l := len(v)
+	byteHeader.Len = l
+	byteHeader.Cap = l
+	return
+}
+
+// toString converts a byte slice to a string without allocating.
+func toString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
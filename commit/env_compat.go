@@ -0,0 +1,17 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build tinygo || wasm
+
+package commit
+
+// toBytes converts a string to a byte slice. On WASM and TinyGo this copies the string
+// instead of relying on the memory layout of the runtime.
+func toBytes(v string) []byte {
+	return []byte(v)
+}
+
+// toString converts a byte slice to a string, copying it.
+func toString(b []byte) string {
+	return string(b)
+}
@@ -4,6 +4,7 @@
 package commit
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"sync"
@@ -37,25 +38,67 @@ func (w Channel) Append(commit Commit) error {
 // Log represents a commit log that can be used to write the changes to the collection
 // during a snapshot. It also supports reading a commit log back.
 type Log struct {
-	lock   sync.Mutex
-	source io.Reader
-	writer *iostream.Writer
-	reader *iostream.Reader
+	lock    sync.Mutex
+	source  io.Reader
+	writer  *iostream.Writer
+	reader  *iostream.Reader
+	options LogOptions
+}
+
+// LogOptions represents the options of a commit log
+type LogOptions struct {
+	Codec      Codec       // The codec compressing the commits (default: s2 stream)
+	Encryption KeyProvider // The keys encrypting the commits with AES-GCM (optional)
 }
 
 // Open opens a commit log stream for both read and write.
 func Open(source io.Reader) *Log {
+	return OpenWith(source, LogOptions{})
+}
+
+// OpenEncrypted opens a commit log stream for both read and write, where the commits are
+// encrypted with AES-GCM using the keys of the provider.
+func OpenEncrypted(source io.Reader, keys KeyProvider) *Log {
+	return OpenWith(source, LogOptions{Encryption: keys})
+}
+
+// OpenWith opens a commit log stream for both read and write, where the commits are
+// compressed with the codec and optionally encrypted, as specified by the options.
+func OpenWith(source io.Reader, options LogOptions) *Log {
+	src := source
+	if options.Encryption != nil {
+		src = NewDecrypter(source, options.Encryption)
+	}
+
 	log := &Log{
-		source: source,
-		reader: iostream.NewReader(s2.NewReader(source)),
+		source:  source,
+		reader:  iostream.NewReader(decompress(src, options.Codec)),
+		options: options,
 	}
 
-	if rw, ok := source.(io.Writer); ok {
-		log.writer = iostream.NewWriter(s2.NewWriter(rw))
+	// The encrypted stream starts with a header, which is written along with the first commit
+	if rw, ok := source.(io.Writer); ok && options.Encryption == nil {
+		log.writer = iostream.NewWriter(compress(rw, options.Codec))
 	}
 	return log
 }
 
+// compress creates a writer which compresses the stream with the codec
+func compress(dst io.Writer, codec Codec) io.Writer {
+	if codec == nil {
+		return s2.NewWriter(dst)
+	}
+	return NewCompressor(dst, codec)
+}
+
+// decompress creates a reader which decompresses the stream with the codec
+func decompress(src io.Reader, codec Codec) io.Reader {
+	if codec == nil {
+		return s2.NewReader(src)
+	}
+	return NewDecompressor(src, codec)
+}
+
 // OpenFile opens a specified commit log file in a read/write mode. If
 // the file does not exist, it will create it.
 func OpenFile(filename string) (*Log, error) {
@@ -67,6 +110,17 @@ func OpenTemp() (*Log, error) {
 	return openFile(os.CreateTemp("", "column_*.log"))
 }
 
+// OpenTempWith opens a temporary commit log file with read/write permissions, where the
+// commits are compressed and encrypted as specified by the options.
+func OpenTempWith(options LogOptions) (*Log, error) {
+	file, err := os.CreateTemp("", "column_*.log")
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenWith(file, options), nil
+}
+
 // openFile opens a file or returns the error provided
 func openFile(file *os.File, err error) (*Log, error) {
 	if err != nil {
@@ -81,6 +135,13 @@ func (l *Log) Append(commit Commit) (err error) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
+	// The encrypted stream starts with a header, which is written along with the first commit
+	if l.writer == nil && l.options.Encryption != nil {
+		if err = l.openEncrypter(); err != nil {
+			return err
+		}
+	}
+
 	// Write the commit into the stream
 	if _, err = commit.WriteTo(l.writer); err == nil {
 		err = l.writer.Flush()
@@ -88,6 +149,22 @@ func (l *Log) Append(commit Commit) (err error) {
 	return
 }
 
+// openEncrypter opens the writer of an encrypted log
+func (l *Log) openEncrypter() error {
+	rw, ok := l.source.(io.Writer)
+	if !ok {
+		return fmt.Errorf("commit: unable to append, the log is read-only")
+	}
+
+	encrypter, err := NewEncrypter(rw, l.options.Encryption)
+	if err != nil {
+		return err
+	}
+
+	l.writer = iostream.NewWriter(compress(encrypter, l.options.Codec))
+	return nil
+}
+
 // Range iterates over all the commits in the log and calls the provided
 // callback function on each of them. If the callback returns an error, the
 // iteration will stop.
@@ -6,7 +6,6 @@ package commit
 import (
 	"encoding/binary"
 	"math"
-	"unsafe"
 )
 
 // Reader represnts a commit log reader (iterator).
@@ -14,6 +13,7 @@ type Reader struct {
 	head   int    // The read position
 	i0, i1 int    // The value start and end
 	Type   OpType // The current operation type
+	kind   byte   // The header of the current operation
 	buffer []byte // The log slice
 	Offset int32  // The current offset
 	start  int32  // The start offset
@@ -142,8 +142,7 @@ func (r *Reader) Float() float64 {
 
 // String reads a string value.
 func (r *Reader) String() string {
-	b := r.buffer[r.i0:r.i1]
-	return *(*string)(unsafe.Pointer(&b))
+	return toString(r.buffer[r.i0:r.i1])
 }
 
 // Bool reads a boolean value.
@@ -213,10 +212,19 @@ func (r *Reader) SwapBool(b bool) {
 
 // --------------------------- Value Increment ----------------------------
 
+// asPut turns the current operation into a put, once its value was swapped with the
+// result of an increment. This way, the buffer can be replayed or replicated without
+// incrementing the value twice.
+func (r *Reader) asPut() {
+	r.buffer[r.i0-1] = r.buffer[r.i0-1]&0xf0 | byte(Put)
+	r.Type = Put
+}
+
 // AddToInt adds and swaps a int value with a new one.
 func (r *Reader) AddToInt(value int) int {
 	value += r.Int()
 	r.SwapInt(value)
+	r.asPut()
 	return value
 }
 
@@ -224,6 +232,7 @@ func (r *Reader) AddToInt(value int) int {
 func (r *Reader) AddToInt16(value int16) int16 {
 	value += r.Int16()
 	r.SwapInt16(value)
+	r.asPut()
 	return value
 }
 
@@ -231,6 +240,7 @@ func (r *Reader) AddToInt16(value int16) int16 {
 func (r *Reader) AddToInt32(value int32) int32 {
 	value += r.Int32()
 	r.SwapInt32(value)
+	r.asPut()
 	return value
 }
 
@@ -238,6 +248,7 @@ func (r *Reader) AddToInt32(value int32) int32 {
 func (r *Reader) AddToInt64(value int64) int64 {
 	value += r.Int64()
 	r.SwapInt64(value)
+	r.asPut()
 	return value
 }
 
@@ -245,6 +256,7 @@ func (r *Reader) AddToInt64(value int64) int64 {
 func (r *Reader) AddToUint(value uint) uint {
 	value += r.Uint()
 	r.SwapUint(value)
+	r.asPut()
 	return value
 }
 
@@ -252,6 +264,7 @@ func (r *Reader) AddToUint(value uint) uint {
 func (r *Reader) AddToUint16(value uint16) uint16 {
 	value += r.Uint16()
 	r.SwapUint16(value)
+	r.asPut()
 	return value
 }
 
@@ -259,6 +272,7 @@ func (r *Reader) AddToUint16(value uint16) uint16 {
 func (r *Reader) AddToUint32(value uint32) uint32 {
 	value += r.Uint32()
 	r.SwapUint32(value)
+	r.asPut()
 	return value
 }
 
@@ -266,6 +280,7 @@ func (r *Reader) AddToUint32(value uint32) uint32 {
 func (r *Reader) AddToUint64(value uint64) uint64 {
 	value += r.Uint64()
 	r.SwapUint64(value)
+	r.asPut()
 	return value
 }
 
@@ -273,6 +288,7 @@ func (r *Reader) AddToUint64(value uint64) uint64 {
 func (r *Reader) AddToFloat32(value float32) float32 {
 	value += r.Float32()
 	r.SwapFloat32(value)
+	r.asPut()
 	return value
 }
 
@@ -280,9 +296,121 @@ func (r *Reader) AddToFloat32(value float32) float32 {
 func (r *Reader) AddToFloat64(value float64) float64 {
 	value += r.Float64()
 	r.SwapFloat64(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToInt adds and swaps a int value with a new one, clamping the result to the
+// range of int.
+func (r *Reader) AddSaturateToInt(value int) int {
+	value = addSigned(r.Int(), value, math.MinInt, math.MaxInt)
+	r.SwapInt(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToInt16 adds and swaps a int16 value with a new one, clamping the result to the
+// range of int16.
+func (r *Reader) AddSaturateToInt16(value int16) int16 {
+	value = addSigned(r.Int16(), value, math.MinInt16, math.MaxInt16)
+	r.SwapInt16(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToInt32 adds and swaps a int32 value with a new one, clamping the result to the
+// range of int32.
+func (r *Reader) AddSaturateToInt32(value int32) int32 {
+	value = addSigned(r.Int32(), value, math.MinInt32, math.MaxInt32)
+	r.SwapInt32(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToInt64 adds and swaps a int64 value with a new one, clamping the result to the
+// range of int64.
+func (r *Reader) AddSaturateToInt64(value int64) int64 {
+	value = addSigned(r.Int64(), value, math.MinInt64, math.MaxInt64)
+	r.SwapInt64(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToUint adds and swaps a uint value with a new one, clamping the result to the
+// range of uint.
+func (r *Reader) AddSaturateToUint(value uint) uint {
+	value = addUnsigned(r.Uint(), value)
+	r.SwapUint(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToUint16 adds and swaps a uint16 value with a new one, clamping the result to the
+// range of uint16.
+func (r *Reader) AddSaturateToUint16(value uint16) uint16 {
+	value = addUnsigned(r.Uint16(), value)
+	r.SwapUint16(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToUint32 adds and swaps a uint32 value with a new one, clamping the result to the
+// range of uint32.
+func (r *Reader) AddSaturateToUint32(value uint32) uint32 {
+	value = addUnsigned(r.Uint32(), value)
+	r.SwapUint32(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToUint64 adds and swaps a uint64 value with a new one, clamping the result to the
+// range of uint64.
+func (r *Reader) AddSaturateToUint64(value uint64) uint64 {
+	value = addUnsigned(r.Uint64(), value)
+	r.SwapUint64(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToFloat32 adds and swaps a float32 value with a new one. Since the floating-point
+// additions saturate at the infinities, this is equivalent to AddToFloat32().
+func (r *Reader) AddSaturateToFloat32(value float32) float32 {
+	value += r.Float32()
+	r.SwapFloat32(value)
+	r.asPut()
+	return value
+}
+
+// AddSaturateToFloat64 adds and swaps a float64 value with a new one. Since the floating-point
+// additions saturate at the infinities, this is equivalent to AddToFloat64().
+func (r *Reader) AddSaturateToFloat64(value float64) float64 {
+	value += r.Float64()
+	r.SwapFloat64(value)
+	r.asPut()
 	return value
 }
 
+// addSigned adds two signed integers, clamping the result to the specified bounds
+func addSigned[T int | int16 | int32 | int64](value, delta, min, max T) T {
+	sum := value + delta
+	switch {
+	case delta > 0 && sum < value:
+		return max
+	case delta < 0 && sum > value:
+		return min
+	default:
+		return sum
+	}
+}
+
+// addUnsigned adds two unsigned integers, clamping the result to the maximum value
+func addUnsigned[T uint | uint16 | uint32 | uint64](value, delta T) T {
+	if sum := value + delta; sum >= value {
+		return sum
+	}
+	return ^T(0)
+}
+
 // --------------------------- Chunk Iterator ----------------------------
 
 // Range iterates over parts of the buffer which match the specified chunk.
@@ -403,6 +531,7 @@ func (r *Reader) readFixed(v byte) {
 	r.head += size
 	r.i1 = r.head
 	r.Type = OpType(v & 0xf)
+	r.kind = v
 }
 
 // readString reads the operation type and the value at the current position.
@@ -413,4 +542,5 @@ func (r *Reader) readString(v byte) {
 	r.head += size
 	r.i1 = r.head
 	r.Type = OpType(v & 0xf)
+	r.kind = v
 }
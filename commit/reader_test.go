@@ -4,6 +4,7 @@
 package commit
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -212,6 +213,79 @@ func TestAddTo(t *testing.T) {
 	assert.Equal(t, 150, int(r.AddToUint(50)))
 }
 
+func TestAddToAsPut(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutAny(Add, 10, int64(5))
+	buf.PutAny(Add, 20, float64(5))
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, int64(15), r.AddToInt64(10))
+	assert.True(t, r.Next())
+	assert.Equal(t, float64(15), r.AddToFloat64(10))
+
+	// Replaying the buffer must not increment the values again
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, uint32(10), r.Index())
+	assert.Equal(t, int64(15), r.Int64())
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, uint32(20), r.Index())
+	assert.Equal(t, float64(15), r.Float64())
+	assert.False(t, r.Next())
+}
+
+func TestAddSaturateTo(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.AddSaturateInt16(10, 100)
+	buf.AddSaturateInt16(20, -100)
+	buf.AddSaturateInt32(30, 100)
+	buf.AddSaturateInt64(40, 100)
+	buf.AddSaturateInt(50, 100)
+	buf.AddSaturateUint16(60, 100)
+	buf.AddSaturateUint32(70, 100)
+	buf.AddSaturateUint64(80, 100)
+	buf.AddSaturateUint(90, 100)
+	buf.AddSaturateFloat32(100, 100)
+	buf.AddSaturateFloat64(110, 100)
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Saturate, r.Type)
+	assert.Equal(t, int16(math.MaxInt16), r.AddSaturateToInt16(math.MaxInt16-10))
+	assert.True(t, r.Next())
+	assert.Equal(t, int16(math.MinInt16), r.AddSaturateToInt16(math.MinInt16+10))
+	assert.True(t, r.Next())
+	assert.Equal(t, int32(math.MaxInt32), r.AddSaturateToInt32(math.MaxInt32-10))
+	assert.True(t, r.Next())
+	assert.Equal(t, int64(150), r.AddSaturateToInt64(50))
+	assert.True(t, r.Next())
+	assert.Equal(t, math.MaxInt, r.AddSaturateToInt(math.MaxInt))
+	assert.True(t, r.Next())
+	assert.Equal(t, uint16(math.MaxUint16), r.AddSaturateToUint16(math.MaxUint16-10))
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(150), r.AddSaturateToUint32(50))
+	assert.True(t, r.Next())
+	assert.Equal(t, uint64(math.MaxUint64), r.AddSaturateToUint64(math.MaxUint64))
+	assert.True(t, r.Next())
+	assert.Equal(t, uint(math.MaxUint), r.AddSaturateToUint(math.MaxUint-1))
+	assert.True(t, r.Next())
+	assert.Equal(t, float32(150), r.AddSaturateToFloat32(50))
+	assert.True(t, r.Next())
+	assert.Equal(t, float64(150), r.AddSaturateToFloat64(50))
+	assert.False(t, r.Next())
+
+	// Replaying the buffer must not increment the values again
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, int16(math.MaxInt16), r.Int16())
+}
+
 func TestWriteUnsupported(t *testing.T) {
 	assert.Panics(t, func() {
 		buf := NewBuffer(0)
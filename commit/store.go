@@ -0,0 +1,237 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kelindar/iostream"
+)
+
+// LogStore represents a durable storage of the commits, such as a write-ahead log. Since
+// it is also a Logger, it can be used as the writer of a collection, while the commits it
+// holds can be replayed in order to recover the state of the collection. Custom stores, for
+// example backed by an append-only log of a cloud provider, can implement this contract.
+type LogStore interface {
+	Logger
+
+	// Range iterates over the commits whose ID is greater or equal to the specified one, in
+	// the order they were appended. If the callback returns an error, the iteration stops.
+	Range(commitID uint64, fn func(Commit) error) error
+
+	// Truncate removes all of the commits whose ID is lower than the specified one, for
+	// example once a snapshot containing them was taken.
+	Truncate(commitID uint64) error
+}
+
+var _ LogStore = new(MemoryStore)
+var _ LogStore = new(FileStore)
+
+// --------------------------- Memory Store ----------------------------
+
+// MemoryStore represents an in-memory log store, which is mostly useful for testing.
+type MemoryStore struct {
+	lock    sync.RWMutex
+	commits []Commit
+}
+
+// NewMemoryStore creates a new, empty in-memory log store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		commits: make([]Commit, 0, 64),
+	}
+}
+
+// Append clones the commit and appends it to the store
+func (s *MemoryStore) Append(commit Commit) error {
+	clone := commit.Clone()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commits = append(s.commits, clone)
+	return nil
+}
+
+// Range iterates over the commits whose ID is greater or equal to the specified one. The
+// callback receives a copy of every commit, since the commits are typically replayed and
+// their buffers recycled afterwards.
+func (s *MemoryStore) Range(commitID uint64, fn func(Commit) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, commit := range s.commits {
+		if commit.ID < commitID {
+			continue
+		}
+
+		if err := fn(commit.Clone()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Truncate removes all of the commits whose ID is lower than the specified one
+func (s *MemoryStore) Truncate(commitID uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	retained := s.commits[:0]
+	for _, commit := range s.commits {
+		if commit.ID >= commitID {
+			retained = append(retained, commit)
+		}
+	}
+
+	for i := len(retained); i < len(s.commits); i++ {
+		s.commits[i] = Commit{}
+	}
+
+	s.commits = retained
+	return nil
+}
+
+// --------------------------- File Store ----------------------------
+
+// FileStore represents a log store backed by a single file on the local disk. Every commit
+// is written into the file as soon as it is appended, while truncating the log rewrites the
+// file with the retained commits only.
+type FileStore struct {
+	lock sync.Mutex
+	file *os.File
+	name string
+}
+
+// OpenFileStore opens a log store backed by the specified file. If the file does not exist,
+// it will create it.
+func OpenFileStore(filename string) (*FileStore, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		file: file,
+		name: filename,
+	}, nil
+}
+
+// Append writes the commit at the end of the file
+func (s *FileStore) Append(commit Commit) error {
+	var buffer bytes.Buffer
+	if _, err := commit.WriteTo(&buffer); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.file.Write(buffer.Bytes())
+	return err
+}
+
+// Range iterates over the commits whose ID is greater or equal to the specified one
+func (s *FileStore) Range(commitID uint64, fn func(Commit) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return rangeFile(s.name, func(commit Commit) error {
+		if commit.ID < commitID {
+			return nil
+		}
+		return fn(commit)
+	})
+}
+
+// Truncate removes all of the commits whose ID is lower than the specified one, by writing
+// the retained commits into a temporary file which then replaces the log.
+func (s *FileStore) Truncate(commitID uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	temp, err := os.CreateTemp(filepath.Dir(s.name), filepath.Base(s.name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	// Copy the retained commits into the temporary file
+	writer := bufio.NewWriter(temp)
+	if err := rangeFile(s.name, func(commit Commit) error {
+		if commit.ID < commitID {
+			return nil
+		}
+
+		_, err := commit.WriteTo(writer)
+		return err
+	}); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	// Replace the log with the temporary file and reopen it
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), s.name); err != nil {
+		return err
+	}
+
+	s.file, err = os.OpenFile(s.name, os.O_RDWR|os.O_APPEND, os.ModePerm)
+	return err
+}
+
+// Sync commits the contents of the file to the stable storage.
+func (s *FileStore) Sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Sync()
+}
+
+// Name returns the name of the underlying file.
+func (s *FileStore) Name() string {
+	return s.name
+}
+
+// Close closes the underlying file.
+func (s *FileStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
+
+// rangeFile reads all of the commits of a file, in order
+func rangeFile(filename string, fn func(Commit) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+	reader := iostream.NewReader(bufio.NewReader(file))
+	for {
+		var commit Commit
+		_, err := commit.ReadFrom(reader)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if err := fn(commit); err != nil {
+			return err
+		}
+	}
+}
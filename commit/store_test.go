@@ -0,0 +1,101 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogStores(t *testing.T) {
+	file, err := OpenFileStore(filepath.Join(t.TempDir(), "commits.log"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	for name, store := range map[string]LogStore{
+		"memory": NewMemoryStore(),
+		"file":   file,
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := 1; i <= 5; i++ {
+				assert.NoError(t, store.Append(newCommit(i)))
+			}
+
+			assert.Equal(t, []uint64{1, 2, 3, 4, 5}, idsOf(t, store, 0))
+			assert.Equal(t, []uint64{3, 4, 5}, idsOf(t, store, 3))
+
+			// Truncate the log and keep appending
+			assert.NoError(t, store.Truncate(4))
+			assert.NoError(t, store.Append(newCommit(6)))
+			assert.Equal(t, []uint64{4, 5, 6}, idsOf(t, store, 0))
+
+			// Stop the iteration early
+			assert.Equal(t, io.ErrShortBuffer, store.Range(0, func(Commit) error {
+				return io.ErrShortBuffer
+			}))
+
+			// The updates must be preserved, even if recycled by the callback
+			store.Range(6, func(commit Commit) error {
+				commit.Updates[0].Reset("")
+				return nil
+			})
+			store.Range(6, func(commit Commit) error {
+				assert.Equal(t, encode(newCommit(6)), encode(commit))
+				return nil
+			})
+		})
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "commits.log")
+	store, err := OpenFileStore(name)
+	assert.NoError(t, err)
+	assert.Equal(t, name, store.Name())
+	assert.NoError(t, store.Append(newCommit(1)))
+	assert.NoError(t, store.Append(newCommit(2)))
+	assert.NoError(t, store.Sync())
+	assert.NoError(t, store.Close())
+
+	// The commits should survive reopening the file
+	store, err = OpenFileStore(name)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.NoError(t, store.Append(newCommit(3)))
+	assert.Equal(t, []uint64{1, 2, 3}, idsOf(t, store, 0))
+}
+
+func TestFileStoreFailures(t *testing.T) {
+	_, err := OpenFileStore(filepath.Join(t.TempDir(), "missing", "commits.log"))
+	assert.Error(t, err)
+
+	// Remove the file from under the store
+	name := filepath.Join(t.TempDir(), "commits.log")
+	store, err := OpenFileStore(name)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.NoError(t, store.Append(newCommit(1)))
+	assert.NoError(t, os.Remove(name))
+	assert.Error(t, store.Range(0, func(Commit) error { return nil }))
+	assert.Error(t, store.Truncate(1))
+}
+
+func encode(commit Commit) []byte {
+	buffer := bytes.NewBuffer(nil)
+	commit.WriteTo(buffer)
+	return buffer.Bytes()
+}
+
+func idsOf(t *testing.T, store LogStore, from uint64) (ids []uint64) {
+	assert.NoError(t, store.Range(from, func(commit Commit) error {
+		ids = append(ids, commit.ID)
+		return nil
+	}))
+	return
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrConflict is returned when a tracked transaction is not committed, because the indexes
+	// it has read were modified by a concurrent transaction in the meantime.
+	ErrConflict = errors.New("column: transaction conflicts with a concurrent commit")
+)
+
+// Track enables the tracking of the index membership reads of the transaction, in order to
+// prevent write skew. The indexes read by the subsequent With(), Without() and Union() filters
+// are recorded, and once the query function completes, the transaction is only committed if
+// none of them was modified by a concurrent commit. Otherwise, it is rolled back and the query
+// returns ErrConflict, so it can be retried.
+//
+// This allows invariants such as "at most one active session per user" to hold even when two
+// concurrent transactions each observe zero matches before inserting. The commits of tracked
+// transactions are validated one at a time, hence the conflicting writes must themselves be
+// made by tracked transactions in order to be always detected.
+func (txn *Txn) Track() *Txn {
+	txn.tracked = true
+	return txn
+}
+
+// readVersion represents the version of a column, as observed by a tracked transaction
+type readVersion struct {
+	column  *column
+	version uint64
+}
+
+// track records the version of the column read by the transaction, if tracking is enabled
+func (txn *Txn) track(column *column) {
+	if !txn.tracked {
+		return
+	}
+
+	for _, r := range txn.reads {
+		if r.column == column {
+			return // Only the first read matters
+		}
+	}
+
+	txn.reads = append(txn.reads, readVersion{
+		column:  column,
+		version: atomic.LoadUint64(&column.version),
+	})
+}
+
+// validate checks whether any of the columns read by the transaction was modified or replaced
+// since it was read. The caller must hold the validation lock of the collection.
+func (txn *Txn) validate() error {
+	for _, r := range txn.reads {
+		current, ok := txn.owner.cols.Load(r.column.name)
+		if !ok || current != r.column || atomic.LoadUint64(&current.version) != r.version {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+// touch increments the versions of the columns modified by the transaction, along with their
+// computed indexes. If any rows were inserted or deleted, all of the columns are touched.
+func (txn *Txn) touch(changedRows bool) {
+	if changedRows {
+		txn.owner.cols.Range(func(column *column) {
+			atomic.AddUint64(&column.version, 1)
+		})
+		return
+	}
+
+	for _, u := range txn.updates {
+		if u.IsEmpty() || u.Column == rowColumn {
+			continue
+		}
+
+		columns, _ := txn.owner.cols.LoadWithIndex(u.Column)
+		for _, column := range columns {
+			atomic.AddUint64(&column.version, 1)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackConflict(t *testing.T) {
+	coll := newSessions()
+
+	// Both transactions observe no active session, the second one commits first
+	err := coll.Query(func(txn *Txn) error {
+		if txn.Track().With("active").Count() > 0 {
+			return nil
+		}
+
+		assert.NoError(t, coll.Query(func(txn *Txn) error {
+			assert.Equal(t, 0, txn.Track().With("active").Count())
+			_, err := txn.InsertObject(Object{"user": "roman", "active": true})
+			return err
+		}))
+
+		_, err := txn.InsertObject(Object{"user": "roman", "active": true})
+		return err
+	})
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, 1, coll.Count())
+}
+
+func TestTrackNoConflict(t *testing.T) {
+	coll := newSessions()
+
+	// A concurrent commit which does not touch the index read should not conflict
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.Track().With("active").Count())
+		assert.NoError(t, coll.Query(func(txn *Txn) error {
+			txn.Int("logins").Set(1)
+			return txn.Range(func(idx uint32) {})
+		}))
+
+		_, err := txn.InsertObject(Object{"user": "roman", "active": true})
+		return err
+	}))
+
+	// Without tracking, the write skew is not detected
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.With("active").Count())
+		coll.InsertObject(Object{"user": "roman", "active": true})
+		return nil
+	}))
+	assert.Equal(t, 2, coll.Count())
+}
+
+func TestTrackDropColumn(t *testing.T) {
+	coll := newSessions()
+	err := coll.Query(func(txn *Txn) error {
+		txn.Track().Without("active")
+		assert.NoError(t, coll.DropColumn("active"))
+		_, err := txn.InsertObject(Object{"user": "roman"})
+		return err
+	})
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, 0, coll.Count())
+}
+
+func TestTrackConcurrent(t *testing.T) {
+	coll := newSessions()
+
+	// Only one of the concurrent writers should ever manage to open a session
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := coll.Query(func(txn *Txn) error {
+					if txn.Track().With("active").Count() > 0 {
+						return nil
+					}
+
+					_, err := txn.InsertObject(Object{"user": "roman", "active": true})
+					return err
+				})
+				if err != ErrConflict {
+					assert.NoError(t, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 1, coll.Count())
+}
+
+// newSessions creates a collection of user sessions
+func newSessions() *Collection {
+	coll := NewCollection()
+	coll.CreateColumn("user", ForString())
+	coll.CreateColumn("active", ForBool())
+	coll.CreateColumn("logins", ForInt())
+	return coll
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && !wasm
+
+package column
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- Vacuum ----------------------------
+
+// startVacuum starts the background goroutine which periodically cleans up the expired
+// objects, until the context is cancelled.
+func (c *Collection) startVacuum(ctx context.Context, interval time.Duration) {
+	c.tasks.Add(1)
+	go func() {
+		defer c.tasks.Done()
+		c.vacuum(ctx, interval)
+	}()
+}
+
+// startCoalesce starts the background goroutine which periodically commits the updates held
+// back by the coalescer of a column, until the context is cancelled or the column is dropped.
+func (c *Collection) startCoalesce(column *column) {
+	c.tasks.Add(1)
+	go func() {
+		defer c.tasks.Done()
+		ticker := time.NewTicker(column.opts.Coalesce)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				if current, ok := c.cols.Load(column.name); !ok || current != column {
+					return
+				}
+
+				c.flushCoalesced(column)
+			}
+		}
+	}()
+}
+
+// vacuumIfDue is a no-op, since the cleanup is done by the background goroutine.
+func (c *Collection) vacuumIfDue() {}
+
+// --------------------------- Recorder ----------------------------
+
+// recorderOpen opens a recorder for commits while the snapshot is in progress
+func (c *Collection) recorderOpen() (log *commit.Log, err error) {
+	if log, err = c.recorderLog(); err == nil {
+		dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
+		ptr := unsafe.Pointer(log)
+		if !atomic.CompareAndSwapPointer(dst, nil, ptr) {
+			return nil, fmt.Errorf("column: unable to snapshot, another one might be in progress")
+		}
+	}
+	return
+}
+
+// recorderClose closes the pending commit recorder and deletes the file
+func (c *Collection) recorderClose() {
+	if _, ok := c.isSnapshotting(); ok {
+		dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
+		atomic.StorePointer(dst, nil)
+	}
+}
+
+// isSnapshotting loads a currently used commit log for a pending snapshot
+func (c *Collection) isSnapshotting() (*commit.Log, bool) {
+	dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
+	ptr := atomic.LoadPointer(dst)
+	if ptr == nil {
+		return nil, false
+	}
+
+	return (*commit.Log)(ptr), true
+}
+
+// --------------------------- Conversions ----------------------------
+
+// toString converts a byte slice to a string without copying it. The bytes must not be
+// modified afterwards.
+func toString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// castSlice reinterprets the memory of a byte slice as a slice of n values, without copying
+// it. The byte slice must be aligned and large enough to hold the values.
+func castSlice[T any](b []byte, n int) []T {
+	return unsafe.Slice((*T)(unsafe.Pointer(&b[0])), n)
+}
+
+// sliceBytes reinterprets the memory of a slice of values as bytes, without copying it. The
+// values must not contain any pointers.
+func sliceBytes[T any](data []T) []byte {
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&data[0])), len(data)*int(unsafe.Sizeof(zero)))
+}
+
+// allocSlice allocates the memory of n values with the allocator.
+func allocSlice[T any](alloc Allocator, n int) ([]T, error) {
+	var zero T
+	buffer, err := alloc.Alloc(n * int(unsafe.Sizeof(zero)))
+	if err != nil {
+		return nil, err
+	}
+
+	return castSlice[T](buffer, n), nil
+}
+
+// freeSlice frees the memory of values which was allocated with allocSlice.
+func freeSlice[T any](alloc Allocator, data []T) error {
+	return alloc.Free(sliceBytes(data))
+}
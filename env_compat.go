@@ -0,0 +1,126 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build tinygo || wasm
+
+package column
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/column/commit"
+)
+
+// --------------------------- Vacuum ----------------------------
+
+// startVacuum schedules the first cleanup of the expired objects. On WASM and TinyGo
+// there is no background goroutine, the cleanup is done lazily by the queries instead.
+func (c *Collection) startVacuum(ctx context.Context, interval time.Duration) {
+	atomic.StoreInt64(&c.expiry, time.Now().Add(interval).UnixNano())
+}
+
+// startCoalesce schedules the first commit of the updates held back by the coalescer of a
+// column. On WASM and TinyGo, these are committed lazily by the queries instead.
+func (c *Collection) startCoalesce(column *column) {
+	atomic.StoreInt64(&column.coalesce.due, time.Now().Add(column.opts.Coalesce).UnixNano())
+}
+
+// coalesceIfDue commits the updates held back by the coalescers whose window has elapsed
+func (c *Collection) coalesceIfDue(now int64) {
+	c.cols.Range(func(column *column) {
+		if column.coalesce == nil {
+			return
+		}
+
+		due := atomic.LoadInt64(&column.coalesce.due)
+		if now >= due && atomic.CompareAndSwapInt64(&column.coalesce.due, due, now+int64(column.opts.Coalesce)) {
+			c.flushCoalesced(column)
+		}
+	})
+}
+
+// vacuumIfDue cleans up the expired objects if the vacuum interval has elapsed since the
+// last cleanup. Only one of the concurrent callers performs the cleanup.
+func (c *Collection) vacuumIfDue() {
+	now := time.Now().UnixNano()
+	c.coalesceIfDue(now)
+	next := atomic.LoadInt64(&c.expiry)
+	if now < next || c.ctx.Err() != nil {
+		return
+	}
+
+	if atomic.CompareAndSwapInt64(&c.expiry, next, now+int64(c.opts.CleanupInterval)) {
+		c.expire(c.now().UnixNano())
+		c.release()
+		c.compact()
+		c.compress()
+		c.observeMemory()
+	}
+}
+
+// --------------------------- Recorder ----------------------------
+
+// recorderOpen opens a recorder for commits while the snapshot is in progress
+func (c *Collection) recorderOpen() (*commit.Log, error) {
+	log, err := c.recorderLog()
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.record != nil {
+		return nil, fmt.Errorf("column: unable to snapshot, another one might be in progress")
+	}
+
+	c.record = log
+	return log, nil
+}
+
+// recorderClose closes the pending commit recorder and deletes the file
+func (c *Collection) recorderClose() {
+	c.lock.Lock()
+	c.record = nil
+	c.lock.Unlock()
+}
+
+// isSnapshotting loads a currently used commit log for a pending snapshot
+func (c *Collection) isSnapshotting() (*commit.Log, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.record, c.record != nil
+}
+
+// --------------------------- Conversions ----------------------------
+
+// toString converts a byte slice to a string. On WASM and TinyGo this copies the bytes
+// instead of relying on the memory layout of the runtime.
+func toString(b []byte) string {
+	return string(b)
+}
+
+// castSlice is not supported on WASM and TinyGo, where the memory of the columns can be
+// neither mapped nor reinterpreted.
+func castSlice[T any](b []byte, n int) []T {
+	panic(errMappingUnsupported)
+}
+
+// sliceBytes is not supported on WASM and TinyGo, hence the chunks of values are never
+// compressed in memory.
+func sliceBytes[T any](data []T) []byte {
+	return nil
+}
+
+// allocSlice allocates the memory of n values. On WASM and TinyGo the values are always kept
+// on the Go heap, since the memory of the allocator cannot be reinterpreted.
+func allocSlice[T any](alloc Allocator, n int) ([]T, error) {
+	return make([]T, n), nil
+}
+
+// freeSlice is a no-op, since the values are kept on the Go heap.
+func freeSlice[T any](alloc Allocator, data []T) error {
+	return nil
+}
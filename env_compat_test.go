@@ -0,0 +1,31 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build tinygo || wasm
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVacuumIfDue(t *testing.T) {
+	col := NewCollection(Options{
+		Vacuum: time.Millisecond,
+	})
+	defer col.Close()
+
+	col.CreateColumn("name", ForString())
+	col.InsertObjectWithTTL(Object{"name": "Roman"}, time.Microsecond)
+	assert.Equal(t, 1, col.Count())
+
+	// The next query after the interval should clean up
+	time.Sleep(5 * time.Millisecond)
+	col.Query(func(txn *Txn) error {
+		return nil
+	})
+	assert.Equal(t, 0, col.Count())
+}
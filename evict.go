@@ -0,0 +1,50 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+)
+
+// Eviction represents the decision taken by an eviction callback for an expired row.
+type Eviction uint8
+
+// Various eviction decisions
+const (
+	Evict  Eviction = iota // Remove the row from the collection
+	Retain                 // Keep the row and clear its expiration time
+	Defer                  // Keep the row and consider it again on the next vacuum
+)
+
+// OnEvict registers a callback which is invoked for every expired row right before it is
+// removed from the collection. The callback can read the values of the row, for example in
+// order to persist them elsewhere, and decides whether the row is actually evicted. Several
+// callbacks can be registered, they are invoked in the order of registration until one of
+// them vetoes the eviction by returning a decision other than Evict.
+//
+// The callback is invoked within the vacuum transaction, hence it may update the row (for
+// example, extend its expiration time), but must not query the collection itself.
+func (c *Collection) OnEvict(fn func(r Row) Eviction) {
+	c.evict.lock.Lock()
+	defer c.evict.lock.Unlock()
+	c.evict.hooks = append(c.evict.hooks, fn)
+}
+
+// evictors represents a set of the registered eviction callbacks
+type evictors struct {
+	lock  sync.RWMutex         // The lock to protect the callbacks
+	hooks []func(Row) Eviction // The callbacks, in order of registration
+}
+
+// decide invokes the eviction callbacks for a row, in order, and returns the decision.
+func (e *evictors) decide(r Row) Eviction {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	for _, fn := range e.hooks {
+		if decision := fn(r); decision != Evict {
+			return decision
+		}
+	}
+	return Evict
+}
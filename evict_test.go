@@ -0,0 +1,82 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnEvict(t *testing.T) {
+	coll := NewCollection(Options{Vacuum: time.Hour})
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("vip", ForBool())
+	coll.InsertObjectWithTTL(Object{"name": "Roman"}, time.Millisecond)
+	coll.InsertObjectWithTTL(Object{"name": "Merlin", "vip": true}, time.Millisecond)
+	coll.InsertObjectWithTTL(Object{"name": "Arthur"}, time.Millisecond)
+	coll.InsertObject(Object{"name": "Lancelot"})
+
+	// Persist the evicted rows and keep the VIPs
+	var evicted []string
+	coll.OnEvict(func(r Row) Eviction {
+		if r.Bool("vip") {
+			return Retain
+		}
+		return Evict
+	})
+	coll.OnEvict(func(r Row) Eviction {
+		name, _ := r.String("name")
+		if name == "Arthur" {
+			return Defer
+		}
+
+		evicted = append(evicted, name)
+		return Evict
+	})
+
+	coll.expire(time.Now().Add(time.Second).UnixNano())
+	assert.Equal(t, []string{"Roman"}, evicted)
+	assert.Equal(t, 3, coll.Count())
+
+	// The retained row should no longer expire, while the deferred one is considered again
+	evicted = evicted[:0]
+	coll.expire(time.Now().Add(time.Second).UnixNano())
+	assert.Empty(t, evicted)
+	assert.Equal(t, 3, coll.Count())
+
+	coll.QueryAt(1, func(r Row) error {
+		expireAt, _ := r.Int64(expireColumn)
+		assert.Zero(t, expireAt)
+		return nil
+	})
+
+	coll.QueryAt(2, func(r Row) error {
+		expireAt, _ := r.Int64(expireColumn)
+		assert.NotZero(t, expireAt)
+		return nil
+	})
+}
+
+func TestOnEvictExtend(t *testing.T) {
+	coll := NewCollection(Options{Vacuum: time.Hour})
+	coll.CreateColumn("name", ForString())
+	coll.InsertObjectWithTTL(Object{"name": "Roman"}, time.Millisecond)
+
+	// Extend the expiration time instead of evicting
+	deadline := time.Now().Add(time.Hour).UnixNano()
+	coll.OnEvict(func(r Row) Eviction {
+		r.SetInt64(expireColumn, deadline)
+		return Defer
+	})
+
+	coll.expire(time.Now().Add(time.Second).UnixNano())
+	assert.Equal(t, 1, coll.Count())
+	coll.QueryAt(0, func(r Row) error {
+		expireAt, _ := r.Int64(expireColumn)
+		assert.Equal(t, deadline, expireAt)
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kelindar/bitmap"
+)
+
+// QueryPlan represents the explanation of the filters applied to a transaction
+type QueryPlan struct {
+	Steps  []PlanStep // The filter steps, in the order they were applied
+	Rows   int        // The number of rows selected by the filters
+	Cached bool       // Whether the deferred filters followed a cached plan
+	traced int        // The number of steps of the trace which were explained
+	order  []int      // The order in which the deferred filters were first applied
+}
+
+// PlanStep represents a single filter step of an explained transaction, along with the
+// selectivity which was estimated before the step was applied.
+type PlanStep struct {
+	TraceStep
+	Deferred bool    // Whether the filter was deferred and ordered by the query planner
+	Estimate float64 // The estimated fraction of the rows kept, or -1 if unknown
+}
+
+// Explain applies the pending filters of the transaction and returns the plan which was
+// followed, with the estimated and the actual selectivity of every step and the time spent
+// on it. The rows are not iterated over, so no callbacks are executed. The deferred filters
+// are listed in the order they were first applied, although the planner reorders them after
+// the first chunk when it has no cached plan for them yet.
+//
+// The filters on bitmaps, such as With(), are applied as they are chained and are only
+// explained if the explanation was already started. Hence, Explain() should be called once
+// before the chain of filters, and once again after it to retrieve the complete plan.
+func (txn *Txn) Explain() *QueryPlan {
+	txn.initialize()
+	if txn.explain == nil {
+		txn.explain = new(QueryPlan)
+		if txn.trace == nil {
+			txn.trace = new(QueryTrace)
+		}
+	}
+
+	// Explain the filters which were applied since, which are all applied eagerly
+	plan := txn.explain
+	for _, step := range txn.trace.Steps[plan.traced:] {
+		plan.Steps = append(plan.Steps, PlanStep{
+			TraceStep: step,
+			Estimate:  txn.estimate(step),
+		})
+	}
+
+	// Apply the deferred filters, following the plan chosen by the query planner
+	if deferred := len(txn.filters); deferred > 0 {
+		key := planKey(txn.filters)
+		cached, ok := txn.owner.plans.plan(key, txn.owner.cols.Version(), txn.owner.Count())
+		plan.Cached = ok && deferred > 1
+
+		offset := len(txn.trace.Steps)
+		plan.order = nil
+		txn.resolve()
+		steps := txn.trace.Steps[offset : offset+deferred]
+		if len(plan.order) != deferred {
+			plan.order = []int{0} // A single filter is applied without a plan
+		}
+
+		for _, i := range plan.order {
+			step := PlanStep{TraceStep: steps[i], Deferred: true, Estimate: -1}
+			if ok && i < len(cached.selectivity) {
+				step.Estimate = cached.selectivity[i]
+			}
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+
+	plan.traced = len(txn.trace.Steps)
+	plan.Rows = txn.index.Count()
+	return plan
+}
+
+// explained records the order in which the deferred filters are applied, if the plan of the
+// transaction is being explained
+func (txn *Txn) explained(order []int) {
+	if txn.explain != nil {
+		txn.explain.order = append(txn.explain.order[:0], order...)
+	}
+}
+
+// estimate estimates the fraction of the rows kept by a bitmap filter, assuming that the
+// index of the column is independent from the rows selected beforehand.
+func (txn *Txn) estimate(step TraceStep) float64 {
+	column, ok := txn.columnAt(step.Column)
+	total := txn.owner.Count()
+	if !ok || total == 0 || !column.IsIndex() {
+		return -1
+	}
+
+	var rows int
+	txn.rangeReadPair(column, func(_, src bitmap.Bitmap) {
+		rows += src.Count()
+	})
+
+	selectivity := float64(rows) / float64(total)
+	if selectivity > 1 {
+		selectivity = 1
+	}
+
+	switch step.Operation {
+	case "With":
+		return selectivity
+	case "Without":
+		return 1 - selectivity
+	default:
+		return -1
+	}
+}
+
+// String returns a human-readable representation of the plan
+func (p *QueryPlan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "plan selects %d rows in %d steps", p.Rows, len(p.Steps))
+	if p.Cached {
+		sb.WriteString(", following a cached plan")
+	}
+
+	for i, s := range p.Steps {
+		estimate := "unknown"
+		if s.Estimate >= 0 {
+			estimate = fmt.Sprintf("%.1f%%", s.Estimate*100)
+		}
+
+		kind := "bitmap"
+		if s.Deferred {
+			kind = "deferred"
+		}
+
+		fmt.Fprintf(&sb, "\n  %d. %s(%s), %s: %d -> %d rows (%.1f%%, estimated %s) in %v",
+			i+1, s.Operation, s.Column, kind, s.Input, s.Output, s.Selectivity()*100, estimate, s.Duration)
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	players := loadPlayers(500)
+	explain := func() (plan *QueryPlan) {
+		players.Query(func(txn *Txn) error {
+			txn.Explain()
+			txn.With("human").Without("mage").WithString("class", func(v string) bool {
+				return v == "rogue"
+			}).WithFloat("balance", func(v float64) bool {
+				return v > 3000
+			})
+
+			plan = txn.Explain()
+			assert.Equal(t, plan.Rows, txn.Count())
+			return nil
+		})
+		return
+	}
+
+	// The first plan is made while the filters are applied
+	plan := explain()
+	assert.False(t, plan.Cached)
+	assert.Len(t, plan.Steps, 4)
+	assert.Equal(t, "With", plan.Steps[0].Operation)
+	assert.Equal(t, "Without", plan.Steps[1].Operation)
+	assert.False(t, plan.Steps[0].Deferred)
+	assert.True(t, plan.Steps[2].Deferred)
+	assert.True(t, plan.Steps[3].Deferred)
+	assert.Equal(t, -1.0, plan.Steps[2].Estimate)
+	assert.InDelta(t, plan.Steps[0].Selectivity(), plan.Steps[0].Estimate, 0.001)
+	assert.Equal(t, plan.Steps[1].Output, plan.Steps[2].Input)
+
+	// The next one follows the cached plan, with the estimates it measured
+	plan = explain()
+	assert.True(t, plan.Cached)
+	for _, step := range plan.Steps[2:] {
+		assert.True(t, step.Deferred)
+		assert.GreaterOrEqual(t, step.Estimate, 0.0)
+	}
+	assert.Contains(t, plan.String(), "following a cached plan")
+	assert.Contains(t, plan.String(), "1. With(human), bitmap")
+}
+
+func TestExplainHints(t *testing.T) {
+	players := loadPlayers(500)
+	explain := func(hints ...Hint) (plan *QueryPlan) {
+		players.Query(func(txn *Txn) error {
+			plan = txn.Hint(hints...).WithString("class", func(v string) bool {
+				return true
+			}).WithString("race", func(v string) bool {
+				return v == "elf"
+			}).Explain()
+			return nil
+		})
+		return
+	}
+
+	// The most selective filter goes first, unless the hint says otherwise
+	explain()
+	assert.Equal(t, "race", explain().Steps[0].Column)
+	assert.Equal(t, "class", explain(PreferIndex("class")).Steps[0].Column)
+}
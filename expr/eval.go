@@ -0,0 +1,266 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package expr
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// lookupFn represents a function which reads the value of a column
+type lookupFn = func(columnName string) (any, bool)
+
+// node represents a node of the syntax tree
+type node interface {
+	eval(lookup lookupFn) any
+}
+
+// Various kinds of nodes
+type (
+	literal    struct{ value any }
+	columnNode struct{ name string }
+	notNode    struct{ inner node }
+	andNode    struct{ left, right node }
+	orNode     struct{ left, right node }
+	arithNode  struct {
+		op          string
+		left, right node
+	}
+	compareNode struct {
+		op          string
+		left, right node
+	}
+	callNode struct {
+		fn   function
+		args []node
+	}
+)
+
+func (n literal) eval(lookup lookupFn) any {
+	return n.value
+}
+
+func (n columnNode) eval(lookup lookupFn) any {
+	if v, ok := lookup(n.name); ok {
+		return normalize(v)
+	}
+	return nil
+}
+
+func (n notNode) eval(lookup lookupFn) any {
+	return !isTrue(n.inner.eval(lookup))
+}
+
+func (n andNode) eval(lookup lookupFn) any {
+	return isTrue(n.left.eval(lookup)) && isTrue(n.right.eval(lookup))
+}
+
+func (n orNode) eval(lookup lookupFn) any {
+	return isTrue(n.left.eval(lookup)) || isTrue(n.right.eval(lookup))
+}
+
+func (n arithNode) eval(lookup lookupFn) any {
+	left, right := n.left.eval(lookup), n.right.eval(lookup)
+	if a, ok := left.(string); ok && n.op == "+" {
+		if b, ok := right.(string); ok {
+			return a + b
+		}
+	}
+
+	a, ok1 := left.(float64)
+	b, ok2 := right.(float64)
+	if !ok1 || !ok2 {
+		return nil
+	}
+
+	switch n.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return nil
+		}
+		return a / b
+	case "%":
+		if b == 0 {
+			return nil
+		}
+		return math.Mod(a, b)
+	default:
+		return nil
+	}
+}
+
+func (n compareNode) eval(lookup lookupFn) any {
+	left, right := n.left.eval(lookup), n.right.eval(lookup)
+	switch {
+	case n.op == "==":
+		return left == right
+	case n.op == "!=":
+		return left != right
+	case left == nil || right == nil:
+		return false
+	}
+
+	switch a := left.(type) {
+	case float64:
+		if b, ok := right.(float64); ok {
+			return compare(a, b, n.op)
+		}
+	case string:
+		if b, ok := right.(string); ok {
+			return compare(a, b, n.op)
+		}
+	}
+	return false
+}
+
+func (n callNode) eval(lookup lookupFn) any {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(lookup)
+	}
+	return n.fn.call(args)
+}
+
+// compare compares two ordered values with the operator
+func compare[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return false
+	}
+}
+
+// isTrue returns whether the value is the boolean true
+func isTrue(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// normalize converts the value of a column into one of the types of the expressions
+func normalize(v any) any {
+	switch v := v.(type) {
+	case float64, string, bool:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case []byte:
+		return string(v)
+	default:
+		return nil
+	}
+}
+
+// --------------------------- Functions ----------------------------
+
+// function represents a built-in function with its number of arguments, where a negative
+// maximum means that the function is variadic.
+type function struct {
+	min, max int
+	call     func(args []any) any
+}
+
+// functions represents the set of built-in functions
+var functions = map[string]function{
+	"abs":      {1, 1, unary(math.Abs)},
+	"ceil":     {1, 1, unary(math.Ceil)},
+	"floor":    {1, 1, unary(math.Floor)},
+	"round":    {1, 1, unary(math.Round)},
+	"sqrt":     {1, 1, unary(math.Sqrt)},
+	"min":      {1, -1, fold(math.Min)},
+	"max":      {1, -1, fold(math.Max)},
+	"len":      {1, 1, text(func(s string) any { return float64(utf8.RuneCountInString(s)) })},
+	"lower":    {1, 1, text(func(s string) any { return strings.ToLower(s) })},
+	"upper":    {1, 1, text(func(s string) any { return strings.ToUpper(s) })},
+	"contains": {2, 2, contains},
+	"coalesce": {1, -1, coalesce},
+}
+
+// unary creates a function of a single number
+func unary(fn func(float64) float64) func([]any) any {
+	return func(args []any) any {
+		if v, ok := args[0].(float64); ok {
+			return fn(v)
+		}
+		return nil
+	}
+}
+
+// fold creates a function which folds several numbers into one
+func fold(fn func(a, b float64) float64) func([]any) any {
+	return func(args []any) any {
+		out, ok := args[0].(float64)
+		for _, arg := range args[1:] {
+			v, isNumber := arg.(float64)
+			if !ok || !isNumber {
+				return nil
+			}
+			out = fn(out, v)
+		}
+
+		if !ok {
+			return nil
+		}
+		return out
+	}
+}
+
+// text creates a function of a single string
+func text(fn func(string) any) func([]any) any {
+	return func(args []any) any {
+		if v, ok := args[0].(string); ok {
+			return fn(v)
+		}
+		return nil
+	}
+}
+
+// contains returns whether the first string contains the second one
+func contains(args []any) any {
+	s, ok1 := args[0].(string)
+	sub, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	return strings.Contains(s, sub)
+}
+
+// coalesce returns the first value which is not null
+func coalesce(args []any) any {
+	for _, arg := range args {
+		if arg != nil {
+			return arg
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package expr provides a small expression language to define computed values and filters at
+// runtime, for example when they are received through an HTTP frontend. An expression such as
+//
+//	weight / (height * height) > 25 && !retired
+//
+// combines the column values, the number, string (single or double-quoted), boolean and null
+// literals with the arithmetic (+, -, *, /, %), comparison (==, !=, <, <=, >, >=) and logical
+// (&&, ||, ! or and, or, not) operators, and the functions abs, ceil, floor, round, sqrt, min,
+// max, len, lower, upper, contains and coalesce. The column names which are not identifiers
+// can be backquoted. All numbers are evaluated as float64 values, while the value of a missing
+// column, or of an invalid operation such as a division by zero, is null.
+package expr
+
+import (
+	"fmt"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column"
+)
+
+// Expression represents a parsed expression, which can be evaluated concurrently.
+type Expression struct {
+	source  string   // The source text of the expression
+	root    node     // The root of the syntax tree
+	columns []string // The names of the columns referenced by the expression
+}
+
+// Parse parses the source text of an expression.
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, columns: make(map[string]bool)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{
+		source:  source,
+		root:    root,
+		columns: p.order,
+	}, nil
+}
+
+// MustParse parses the source text of an expression and panics if it is invalid.
+func MustParse(source string) *Expression {
+	expr, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+// String returns the source text of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Columns returns the names of the columns referenced by the expression
+func (e *Expression) Columns() []string {
+	return e.columns
+}
+
+// Validate checks that all of the columns referenced by the expression exist in the collection.
+func (e *Expression) Validate(c *column.Collection) error {
+	for _, name := range e.columns {
+		if _, ok := c.KindOf(name); !ok {
+			return fmt.Errorf("column: column '%s' does not exist", name)
+		}
+	}
+	return nil
+}
+
+// Eval evaluates the expression, reading the values of the columns with the lookup function.
+// The result is either a float64, a string, a bool or nil.
+func (e *Expression) Eval(lookup func(columnName string) (any, bool)) any {
+	return e.root.eval(lookup)
+}
+
+// Match evaluates the expression and returns whether the result is true.
+func (e *Expression) Match(lookup func(columnName string) (any, bool)) bool {
+	return isTrue(e.root.eval(lookup))
+}
+
+// Compute evaluates the expression on the row the selector is pointing at.
+func (e *Expression) Compute(row column.Selector) any {
+	return e.root.eval(row.Any)
+}
+
+// Filter filters down the transaction to the rows for which the expression is true. The columns
+// referenced by the expression must exist in the collection, see Validate(). Since every row is
+// evaluated, the cheaper filters should be applied onto the transaction first.
+func (e *Expression) Filter(txn *column.Txn) error {
+	readers := make(map[string]interface{ Get() (any, bool) }, len(e.columns))
+	for _, name := range e.columns {
+		readers[name] = txn.Any(name)
+	}
+
+	lookup := func(columnName string) (any, bool) {
+		return readers[columnName].Get()
+	}
+
+	var matches bitmap.Bitmap
+	if err := txn.Range(func(idx uint32) {
+		if e.Match(lookup) {
+			matches.Set(idx)
+		}
+	}); err != nil {
+		return err
+	}
+
+	txn.WithBitmap(matches)
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/kelindar/column"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	values := map[string]any{
+		"age":    int64(30),
+		"weight": float32(80),
+		"height": 2.0,
+		"name":   "Merlin",
+		"active": true,
+	}
+
+	lookup := func(columnName string) (any, bool) {
+		v, ok := values[columnName]
+		return v, ok
+	}
+
+	tests := []struct {
+		source string
+		expect any
+	}{
+		{"age + 2 * 5", 40.0},
+		{"(age + 2) * -5", -160.0},
+		{"weight / (height * height)", 20.0},
+		{"age % 7", 2.0},
+		{"age / 0", nil},
+		{"missing + 1", nil},
+		{"age > 25 && active", true},
+		{"age > 25 and not active", false},
+		{"age >= 30 || missing", true},
+		{"!(name == 'Merlin')", false},
+		{"name = \"Merlin\" and age != 31", true},
+		{"name < 'Morgana'", true},
+		{"missing == null", true},
+		{"age == null", false},
+		{"missing > 1", false},
+		{"'Hello ' + name", "Hello Merlin"},
+		{"upper(name) + lower('X')", "MERLINx"},
+		{"len(name)", 6.0},
+		{"contains(name, 'rl')", true},
+		{"min(age, 10, 20) + max(1, 2)", 12.0},
+		{"abs(-2.5) + round(1.4) + floor(1.9) + ceil(1.1) + sqrt(16)", 10.5},
+		{"coalesce(missing, age)", 30.0},
+		{"`age` + 1", 31.0},
+	}
+
+	for _, tc := range tests {
+		expr, err := Parse(tc.source)
+		assert.NoError(t, err, tc.source)
+		assert.Equal(t, tc.expect, expr.Eval(lookup), tc.source)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"age >",
+		"(age + 1",
+		"age + 1)",
+		"'unterminated",
+		"age ^ 2",
+		"unknown(age)",
+		"abs(1, 2)",
+		"min()",
+		"age > 1 > 2",
+	} {
+		_, err := Parse(source)
+		assert.Error(t, err, source)
+	}
+
+	assert.Panics(t, func() {
+		MustParse("age >")
+	})
+}
+
+func TestColumns(t *testing.T) {
+	expr := MustParse("weight / (height * height) > 25 && weight > 0 && `first name` != ''")
+	assert.Equal(t, []string{"weight", "height", "first name"}, expr.Columns())
+	assert.Equal(t, "weight / (height * height) > 25 && weight > 0 && `first name` != ''", expr.String())
+}
+
+func TestFilter(t *testing.T) {
+	players := loadPlayers()
+	filter := MustParse("weight / (height * height) > 25 && class != 'mage'")
+	assert.NoError(t, filter.Validate(players))
+	assert.Error(t, MustParse("missing > 1").Validate(players))
+
+	var names []string
+	assert.NoError(t, players.Query(func(txn *column.Txn) error {
+		if err := filter.Filter(txn.With("active")); err != nil {
+			return err
+		}
+
+		name := txn.String("name")
+		return txn.Range(func(idx uint32) {
+			v, _ := name.Get()
+			names = append(names, v)
+		})
+	}))
+	assert.Equal(t, []string{"Arthur"}, names)
+}
+
+func TestCompute(t *testing.T) {
+	players := loadPlayers()
+	bmi := MustParse("round(weight / (height * height))")
+
+	var values []any
+	assert.NoError(t, players.SelectAt([]uint32{0, 1, 2}, func(v column.Selector) {
+		values = append(values, bmi.Compute(v))
+	}))
+	assert.Equal(t, []any{23.0, 31.0, 22.0}, values)
+}
+
+// loadPlayers loads a small collection of players
+func loadPlayers() *column.Collection {
+	players := column.NewCollection()
+	players.CreateColumn("name", column.ForString())
+	players.CreateColumn("class", column.ForEnum())
+	players.CreateColumn("weight", column.ForFloat64())
+	players.CreateColumn("height", column.ForFloat64())
+	players.CreateColumn("active", column.ForBool())
+	players.InsertMany([]column.Object{
+		{"name": "Roman", "class": "rogue", "weight": 75.0, "height": 1.8, "active": false},
+		{"name": "Arthur", "class": "knight", "weight": 100.0, "height": 1.8, "active": true},
+		{"name": "Merlin", "class": "mage", "weight": 70.0, "height": 1.8, "active": true},
+		{"name": "Morgana", "class": "mage", "weight": 90.0, "height": 1.7, "active": true},
+	})
+	return players
+}
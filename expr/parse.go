@@ -0,0 +1,353 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind represents a kind of a lexical token
+type tokenKind uint8
+
+// Various kinds of tokens
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+// token represents a single lexical token of the expression
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+}
+
+// words represents the set of reserved words, which are case-insensitive
+var words = map[string]string{
+	"and": "&&", "or": "||", "not": "!", "true": "true", "false": "false", "null": "null",
+}
+
+// symbols represents the set of operators, the longest ones first
+var symbols = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "<", ">", "=", "!", "+", "-", "*", "/", "%", "(", ")", ",",
+}
+
+// tokenize splits the expression into a sequence of tokens, terminated by an EOF token.
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0, 16)
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isLetter(c):
+			start := i
+			for i < len(source) && (isLetter(source[i]) || isDigit(source[i])) {
+				i++
+			}
+
+			word := source[start:i]
+			if symbol, ok := words[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{kind: tokenSymbol, text: symbol, start: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: word, start: start})
+			}
+
+		case isDigit(c) || c == '.' && i+1 < len(source) && isDigit(source[i+1]):
+			start := i
+			for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], start: start})
+
+		case c == '\'' || c == '"' || c == '`':
+			text, next, err := readQuoted(source, i)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := tokenString
+			if c == '`' {
+				kind = tokenIdent
+			}
+			tokens = append(tokens, token{kind: kind, text: text, start: i})
+			i = next
+
+		default:
+			symbol := ""
+			for _, s := range symbols {
+				if strings.HasPrefix(source[i:], s) {
+					symbol = s
+					break
+				}
+			}
+
+			if symbol == "" {
+				return nil, fmt.Errorf("column: unexpected character '%c' at position %d", c, i)
+			}
+
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, start: i})
+			i += len(symbol)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, start: len(source)}), nil
+}
+
+// readQuoted reads a quoted string or identifier starting at the specified position, where
+// the quote character is escaped by doubling it.
+func readQuoted(source string, start int) (string, int, error) {
+	quote := source[start]
+	var out strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		if source[i] != quote {
+			out.WriteByte(source[i])
+			continue
+		}
+
+		if i+1 < len(source) && source[i+1] == quote {
+			out.WriteByte(quote)
+			i++
+			continue
+		}
+
+		return out.String(), i + 1, nil
+	}
+
+	return "", 0, fmt.Errorf("column: unterminated quote at position %d", start)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// --------------------------- Parser ----------------------------
+
+// parser represents a recursive descent parser of the expressions
+type parser struct {
+	tokens  []token
+	pos     int
+	columns map[string]bool // The set of referenced columns
+	order   []string        // The referenced columns, in order of appearance
+}
+
+// parse parses the entire expression
+func (p *parser) parse() (node, error) {
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected input")
+	}
+	return root, nil
+}
+
+// parseOr parses a disjunction of conjunctions
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right node
+		if right, err = p.parseAnd(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseAnd parses a conjunction of negations
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept("&&") {
+		var right node
+		if right, err = p.parseNot(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseNot parses a negation or a comparison
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		inner, err := p.parseNot()
+		return notNode{inner}, err
+	}
+	return p.parseCompare()
+}
+
+// parseCompare parses a comparison of two sums
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "="} {
+		if p.accept(op) {
+			right, err := p.parseSum()
+			if op == "=" {
+				op = "=="
+			}
+			return compareNode{op, left, right}, err
+		}
+	}
+	return left, nil
+}
+
+// parseSum parses an addition or a subtraction of products
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	for err == nil {
+		op := p.peek().text
+		if p.peek().kind != tokenSymbol || (op != "+" && op != "-") {
+			break
+		}
+
+		p.next()
+		var right node
+		if right, err = p.parseProduct(); err == nil {
+			left = arithNode{op, left, right}
+		}
+	}
+	return left, err
+}
+
+// parseProduct parses a multiplication, a division or a modulo of unary expressions
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op := p.peek().text
+		if p.peek().kind != tokenSymbol || (op != "*" && op != "/" && op != "%") {
+			break
+		}
+
+		p.next()
+		var right node
+		if right, err = p.parseUnary(); err == nil {
+			left = arithNode{op, left, right}
+		}
+	}
+	return left, err
+}
+
+// parseUnary parses a negative number or a primary expression
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("-") {
+		inner, err := p.parseUnary()
+		return arithNode{"-", literal{0.0}, inner}, err
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a literal, a column, a function call or a parenthesized expression
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok, "invalid number")
+		}
+		return literal{v}, nil
+
+	case tok.kind == tokenString:
+		return literal{tok.text}, nil
+
+	case tok.kind == tokenSymbol && (tok.text == "true" || tok.text == "false"):
+		return literal{tok.text == "true"}, nil
+
+	case tok.kind == tokenSymbol && tok.text == "null":
+		return literal{nil}, nil
+
+	case tok.kind == tokenSymbol && tok.text == "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorAt(p.peek(), "expected ')'")
+		}
+		return inner, nil
+
+	case tok.kind == tokenIdent && p.accept("("):
+		return p.parseCall(tok)
+
+	case tok.kind == tokenIdent:
+		if !p.columns[tok.text] {
+			p.columns[tok.text] = true
+			p.order = append(p.order, tok.text)
+		}
+		return columnNode{tok.text}, nil
+	}
+
+	return nil, p.errorAt(tok, "expected a value")
+}
+
+// parseCall parses the arguments of a function call
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorAt(name, "unknown function")
+	}
+
+	call := callNode{fn: fn}
+	for !p.accept(")") {
+		if len(call.args) > 0 && !p.accept(",") {
+			return nil, p.errorAt(p.peek(), "expected ',' or ')'")
+		}
+
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+	}
+
+	if len(call.args) < fn.min || fn.max >= 0 && len(call.args) > fn.max {
+		return nil, p.errorAt(name, "invalid number of arguments")
+	}
+	return call, nil
+}
+
+// --------------------------- Tokens ----------------------------
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(symbol string) bool {
+	if tok := p.peek(); tok.kind == tokenSymbol && tok.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// errorAt returns a syntax error at the position of the token
+func (p *parser) errorAt(tok token, message string) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("column: syntax error at end of expression, %s", message)
+	}
+	return fmt.Errorf("column: syntax error at position %d near '%s', %s", tok.start, tok.text, message)
+}
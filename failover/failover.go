@@ -0,0 +1,260 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package failover provides a small, lease-based coordination helper for an active/passive
+// deployment, where the processes share a commit log. The process which holds the lease is
+// active and writes into the collection and the log, while the other processes are passive
+// read replicas which follow the log. Once the lease of the active process expires, one of
+// the passive processes acquires it, replays the tail of the log and is promoted.
+//
+// There is no quorum involved, the consistency relies on the lease provider granting the lease
+// to at most one process at a time and on the time-based commit IDs being ordered across the
+// processes.
+package failover
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+)
+
+// Lease represents a pluggable lock provider which grants an exclusive lease with an expiry,
+// for example backed by a database row or a key in a distributed key-value store.
+type Lease interface {
+
+	// Acquire acquires the lease for the owner, or renews it if the owner already holds it,
+	// and returns whether the owner holds the lease for the duration of the TTL.
+	Acquire(owner string, ttl time.Duration) (bool, error)
+
+	// Release releases the lease, if it is held by the owner.
+	Release(owner string) error
+}
+
+// Role represents the role of a node
+type Role uint32
+
+// Various roles of a node
+const (
+	Passive Role = iota // The node follows the log and must not be written to
+	Active              // The node holds the lease and accepts the writes
+)
+
+// String returns the name of the role
+func (r Role) String() string {
+	if r == Active {
+		return "active"
+	}
+	return "passive"
+}
+
+// Options represents the options of a node
+type Options struct {
+	ID       string          // The unique identifier of the node
+	Lease    Lease           // The provider of the lease
+	Store    commit.LogStore // The commit log shared by the nodes
+	TTL      time.Duration   // The duration of the lease (default: 10s)
+	Interval time.Duration   // The interval at which the lease is renewed and the log is followed (default: TTL / 3)
+	OnChange func(Role)      // The callback invoked when the role of the node changes (optional)
+}
+
+// Node represents a process participating in the failover. It also acts as the commit writer
+// of the collection, which appends the commits into the shared log while the node is active.
+type Node struct {
+	role uint32        // The current role of the node
+	last uint64        // The ID of the last commit written or replayed
+	lock sync.Mutex    // The lock to apply the log one step at a time
+	opts Options       // The options of the node
+	tail *tailingStore // The log store which keeps track of the replayed commits
+}
+
+// New creates a new node, which starts as passive. The node must be configured as the
+// writer of the collection, for example
+//
+//	node, err := failover.New(failover.Options{ID: "a", Lease: lease, Store: store})
+//	players := column.NewCollection(column.Options{
+//		Writer: node,
+//	})
+//
+//	go node.Run(ctx, players)
+func New(opts Options) (*Node, error) {
+	switch {
+	case opts.ID == "":
+		return nil, fmt.Errorf("column: failover node requires an identifier")
+	case opts.Lease == nil:
+		return nil, fmt.Errorf("column: failover node requires a lease")
+	case opts.Store == nil:
+		return nil, fmt.Errorf("column: failover node requires a log store")
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Second
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = opts.TTL / 3
+	}
+
+	node := &Node{opts: opts}
+	node.tail = &tailingStore{LogStore: opts.Store, last: &node.last}
+	return node, nil
+}
+
+// Role returns the current role of the node
+func (n *Node) Role() Role {
+	return Role(atomic.LoadUint32(&n.role))
+}
+
+// Append appends the commit into the shared log. The commits are rejected while the node is
+// passive, since only the active node may write.
+func (n *Node) Append(change commit.Commit) error {
+	if n.Role() != Active {
+		return fmt.Errorf("column: unable to append commit %d, node '%s' is passive", change.ID, n.opts.ID)
+	}
+
+	if err := n.opts.Store.Append(change); err != nil {
+		return err
+	}
+
+	n.observe(change.ID)
+	return nil
+}
+
+// Run acquires and renews the lease periodically, while following the log as long as the
+// node is passive. It blocks until the context is cancelled, then releases the lease.
+func (n *Node) Run(ctx context.Context, collection *column.Collection) error {
+	ticker := time.NewTicker(n.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		n.step(collection)
+		select {
+		case <-ctx.Done():
+			n.demote()
+			return n.opts.Lease.Release(n.opts.ID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// step acquires or renews the lease and changes the role of the node accordingly. A node
+// which is unable to confirm its lease steps down, so that two nodes are never active.
+func (n *Node) step(collection *column.Collection) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	acquired, err := n.opts.Lease.Acquire(n.opts.ID, n.opts.TTL)
+	switch {
+	case err != nil || !acquired:
+		n.demote()
+		if err != nil {
+			return err
+		}
+		return collection.ReplayFrom(n.tail, n.next())
+
+	case n.Role() == Passive:
+		if err := collection.ReplayFrom(n.tail, n.next()); err != nil {
+			return err // Retry once the tail of the log is readable
+		}
+
+		n.promote()
+	}
+	return nil
+}
+
+// promote makes the node active
+func (n *Node) promote() {
+	if atomic.CompareAndSwapUint32(&n.role, uint32(Passive), uint32(Active)) && n.opts.OnChange != nil {
+		n.opts.OnChange(Active)
+	}
+}
+
+// demote makes the node passive
+func (n *Node) demote() {
+	if atomic.CompareAndSwapUint32(&n.role, uint32(Active), uint32(Passive)) && n.opts.OnChange != nil {
+		n.opts.OnChange(Passive)
+	}
+}
+
+// next returns the ID from which the log should be followed
+func (n *Node) next() uint64 {
+	return atomic.LoadUint64(&n.last) + 1
+}
+
+// observe records the ID of a commit written or replayed
+func (n *Node) observe(id uint64) {
+	for {
+		last := atomic.LoadUint64(&n.last)
+		if id <= last || atomic.CompareAndSwapUint64(&n.last, last, id) {
+			return
+		}
+	}
+}
+
+// --------------------------- Tailing Store ----------------------------
+
+// tailingStore represents a log store which records the last commit ID it iterated over
+type tailingStore struct {
+	commit.LogStore
+	last *uint64
+}
+
+// Range iterates over the commits and records the last commit ID
+func (s *tailingStore) Range(commitID uint64, fn func(commit.Commit) error) error {
+	return s.LogStore.Range(commitID, func(change commit.Commit) error {
+		if err := fn(change); err != nil {
+			return err
+		}
+
+		for {
+			last := atomic.LoadUint64(s.last)
+			if change.ID <= last || atomic.CompareAndSwapUint64(s.last, last, change.ID) {
+				return nil
+			}
+		}
+	})
+}
+
+// --------------------------- Memory Lease ----------------------------
+
+// MemoryLease represents a lease held in memory, which coordinates the nodes within a single
+// process and is mostly useful for testing.
+type MemoryLease struct {
+	lock    sync.Mutex
+	owner   string
+	expires time.Time
+	now     func() time.Time
+}
+
+// NewMemoryLease creates a new in-memory lease
+func NewMemoryLease() *MemoryLease {
+	return &MemoryLease{now: time.Now}
+}
+
+// Acquire acquires the lease for the owner, or renews it if the owner already holds it
+func (l *MemoryLease) Acquire(owner string, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if l.owner != "" && l.owner != owner && now.Before(l.expires) {
+		return false, nil
+	}
+
+	l.owner = owner
+	l.expires = now.Add(ttl)
+	return true, nil
+}
+
+// Release releases the lease, if it is held by the owner
+func (l *MemoryLease) Release(owner string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.owner == owner {
+		l.owner = ""
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailover(t *testing.T) {
+	clock := time.Unix(0, 0)
+	lease := NewMemoryLease()
+	lease.now = func() time.Time { return clock }
+	store := commit.NewMemoryStore()
+
+	var roles []Role
+	a, players1 := newNode(t, "a", lease, store, func(r Role) { roles = append(roles, r) })
+	b, players2 := newNode(t, "b", lease, store, nil)
+
+	// The first node acquires the lease, the second one follows
+	assert.NoError(t, a.step(players1))
+	assert.NoError(t, b.step(players2))
+	assert.Equal(t, Active, a.Role())
+	assert.Equal(t, Passive, b.Role())
+
+	// Only the active node can write
+	players1.InsertObject(column.Object{"name": "Roman"})
+	players1.InsertObject(column.Object{"name": "Merlin"})
+	assert.NoError(t, b.step(players2))
+	assert.Equal(t, 2, players2.Count())
+	assert.Error(t, b.Append(commit.Commit{ID: 1}))
+
+	// Once the lease expires, the passive node replays the tail and is promoted
+	players1.InsertObject(column.Object{"name": "Arthur"})
+	clock = clock.Add(time.Minute)
+	assert.NoError(t, b.step(players2))
+	assert.Equal(t, Active, b.Role())
+	assert.Equal(t, 3, players2.Count())
+
+	// The former active node steps down and follows the new one
+	assert.NoError(t, a.step(players1))
+	assert.Equal(t, Passive, a.Role())
+	assert.Equal(t, []Role{Active, Passive}, roles)
+
+	players2.InsertObject(column.Object{"name": "Lancelot"})
+	assert.NoError(t, a.step(players1))
+	assert.Equal(t, 4, players1.Count())
+	assert.Equal(t, 4, players2.Count())
+}
+
+func TestRun(t *testing.T) {
+	lease := NewMemoryLease()
+	node, players := newNode(t, "a", lease, commit.NewMemoryStore(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- node.Run(ctx, players)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return node.Role() == Active
+	}, time.Second, time.Millisecond)
+
+	// Once stopped, the lease should be released
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, Passive, node.Role())
+	ok, err := lease.Acquire("b", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestLeaseError(t *testing.T) {
+	node, players := newNode(t, "a", failingLease{}, commit.NewMemoryStore(), nil)
+	node.promote()
+	assert.Error(t, node.step(players))
+	assert.Equal(t, Passive, node.Role())
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := New(Options{})
+	assert.Error(t, err)
+	_, err = New(Options{ID: "a"})
+	assert.Error(t, err)
+	_, err = New(Options{ID: "a", Lease: NewMemoryLease()})
+	assert.Error(t, err)
+}
+
+func TestRoleString(t *testing.T) {
+	assert.Equal(t, "active", Active.String())
+	assert.Equal(t, "passive", Passive.String())
+}
+
+// newNode creates a new node along with its collection
+func newNode(t *testing.T, id string, lease Lease, store commit.LogStore, onChange func(Role)) (*Node, *column.Collection) {
+	node, err := New(Options{
+		ID:       id,
+		Lease:    lease,
+		Store:    store,
+		TTL:      time.Second,
+		Interval: time.Millisecond,
+		OnChange: onChange,
+	})
+	assert.NoError(t, err)
+
+	players := column.NewCollection(column.Options{
+		Writer: node,
+	})
+	players.CreateColumn("name", column.ForString())
+	return node, players
+}
+
+// failingLease represents a lease provider which is unavailable
+type failingLease struct{}
+
+func (failingLease) Acquire(string, time.Duration) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func (failingLease) Release(string) error {
+	return nil
+}
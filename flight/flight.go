@@ -0,0 +1,321 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package flight serves the DoPut method of the Arrow Flight protocol over gRPC, so that the
+// external producers can push Arrow record batches into the collections of a source, such as
+// a catalog. The path of the flight descriptor names the collection, and the record batches
+// of the stream are appended column by column, in a single transaction per call. The other
+// methods of the Flight service are not implemented.
+//
+// The messages of the protocol are encoded by hand, in the same way as the Arrow streams of
+// the collections are, so that neither the Arrow nor the Flight libraries are required.
+package flight
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kelindar/column/sql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ServiceName is the name of the Arrow Flight gRPC service
+const ServiceName = "arrow.flight.protocol.FlightService"
+
+// Server represents the Arrow Flight service, which ingests the record batches pushed into
+// the collections of a source.
+type Server struct {
+	source sql.Source
+}
+
+// New creates a new Arrow Flight service for the collections of the source.
+func New(source sql.Source) *Server {
+	return &Server{source: source}
+}
+
+// Register registers the Flight service onto the gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{ DoPut(grpc.ServerStream) error })(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "DoPut",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(*Server).DoPut(stream)
+			},
+		}},
+		Metadata: "Flight.proto",
+	}, s)
+}
+
+// DoPut reads the record batches pushed by the client into the collection named by the path
+// of the flight descriptor, and replies with a single result once they are all inserted. The
+// metadata of the result holds the number of inserted rows, as a JSON object.
+func (s *Server) DoPut(stream grpc.ServerStream) error {
+	first := new(FlightData)
+	if err := stream.RecvMsg(first); err != nil {
+		return err
+	}
+
+	if first.Descriptor == nil || len(first.Descriptor.Path) != 1 {
+		return status.Error(codes.InvalidArgument, "column: descriptor must have the name of a collection as its path")
+	}
+
+	name := first.Descriptor.Path[0]
+	collection, ok := s.source.Collection(name)
+	if !ok {
+		return status.Errorf(codes.NotFound, "column: collection '%s' does not exist", name)
+	}
+
+	// Convert the messages to an Arrow stream as they are received, and insert it
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(copyStream(writer, stream, first))
+	}()
+
+	indices, err := collection.InsertArrowStream(reader)
+	reader.Close()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return stream.SendMsg(&PutResult{
+		AppMetadata: []byte(fmt.Sprintf(`{"count":%d}`, len(indices))),
+	})
+}
+
+// copyStream writes the messages received on the stream as encapsulated messages of an Arrow
+// stream, until the client is done sending.
+func copyStream(dst io.Writer, stream grpc.ServerStream, first *FlightData) error {
+	for msg := first; ; {
+		if err := writeMessage(dst, msg); err != nil {
+			return err
+		}
+
+		msg = new(FlightData)
+		switch err := stream.RecvMsg(msg); {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// writeMessage writes the header of a message, padded to 8 bytes, followed by its body
+func writeMessage(dst io.Writer, msg *FlightData) error {
+	if len(msg.DataHeader) == 0 {
+		return nil // Only carries the metadata
+	}
+
+	header := msg.DataHeader
+	if len(header)%8 != 0 {
+		header = append(header, make([]byte, 8-len(header)%8)...)
+	}
+
+	prefix := make([]byte, 8)
+	binary.LittleEndian.PutUint32(prefix[0:4], 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:8], uint32(len(header)))
+	for _, v := range [][]byte{prefix, header, msg.DataBody} {
+		if _, err := dst.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------- Messages ----------------------------
+
+// errInvalidMessage is returned when a message can not be decoded
+var errInvalidMessage = errors.New("column: invalid flight message")
+
+// FlightDescriptor represents the descriptor of a flight, which names the collection.
+type FlightDescriptor struct {
+	Type int32    // The type of the descriptor, 1 for a path and 2 for a command
+	Cmd  []byte   // The opaque command
+	Path []string // The path which identifies the flight
+}
+
+// FlightData represents a message of a flight, which carries an Arrow message with its body.
+type FlightData struct {
+	Descriptor  *FlightDescriptor // The descriptor, sent with the first message of a DoPut
+	DataHeader  []byte            // The header of the Arrow message, a flatbuffer
+	AppMetadata []byte            // The application-defined metadata
+	DataBody    []byte            // The body of the Arrow message
+}
+
+// Reset resets the message
+func (m *FlightData) Reset() { *m = FlightData{} }
+
+// String returns the description of the message
+func (m *FlightData) String() string {
+	return fmt.Sprintf("FlightData{header: %d bytes, body: %d bytes}", len(m.DataHeader), len(m.DataBody))
+}
+
+// ProtoMessage marks the type as a protobuf message
+func (*FlightData) ProtoMessage() {}
+
+// Marshal encodes the message in the protobuf wire format
+func (m *FlightData) Marshal() ([]byte, error) {
+	var out []byte
+	if d := m.Descriptor; d != nil {
+		var desc []byte
+		if d.Type != 0 {
+			desc = protowire.AppendTag(desc, 1, protowire.VarintType)
+			desc = protowire.AppendVarint(desc, uint64(d.Type))
+		}
+		desc = appendBytes(desc, 2, d.Cmd)
+		for _, path := range d.Path {
+			desc = protowire.AppendTag(desc, 3, protowire.BytesType)
+			desc = protowire.AppendString(desc, path)
+		}
+
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, desc)
+	}
+
+	out = appendBytes(out, 2, m.DataHeader)
+	out = appendBytes(out, 3, m.AppMetadata)
+	out = appendBytes(out, 1000, m.DataBody)
+	return out, nil
+}
+
+// Unmarshal decodes the message from the protobuf wire format
+func (m *FlightData) Unmarshal(b []byte) error {
+	m.Reset()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+
+		b = b[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var desc []byte
+			desc, n = protowire.ConsumeBytes(b)
+			m.Descriptor = new(FlightDescriptor)
+			if err := m.Descriptor.unmarshal(desc); err != nil {
+				return err
+			}
+		case num == 2 && typ == protowire.BytesType:
+			m.DataHeader, n = consumeBytes(b)
+		case num == 3 && typ == protowire.BytesType:
+			m.AppMetadata, n = consumeBytes(b)
+		case num == 1000 && typ == protowire.BytesType:
+			m.DataBody, n = consumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// unmarshal decodes the descriptor from the protobuf wire format
+func (d *FlightDescriptor) unmarshal(b []byte) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+
+		b = b[n:]
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			d.Type = int32(v)
+		case num == 2 && typ == protowire.BytesType:
+			d.Cmd, n = consumeBytes(b)
+		case num == 3 && typ == protowire.BytesType:
+			var path string
+			path, n = protowire.ConsumeString(b)
+			d.Path = append(d.Path, path)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// PutResult represents the reply of a DoPut call.
+type PutResult struct {
+	AppMetadata []byte // The application-defined metadata
+}
+
+// Reset resets the message
+func (m *PutResult) Reset() { *m = PutResult{} }
+
+// String returns the description of the message
+func (m *PutResult) String() string {
+	return fmt.Sprintf("PutResult{metadata: %q}", m.AppMetadata)
+}
+
+// ProtoMessage marks the type as a protobuf message
+func (*PutResult) ProtoMessage() {}
+
+// Marshal encodes the message in the protobuf wire format
+func (m *PutResult) Marshal() ([]byte, error) {
+	return appendBytes(nil, 1, m.AppMetadata), nil
+}
+
+// Unmarshal decodes the message from the protobuf wire format
+func (m *PutResult) Unmarshal(b []byte) error {
+	m.Reset()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			m.AppMetadata, n = consumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// appendBytes appends a length-delimited field, unless it is empty
+func appendBytes(dst []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return dst
+	}
+
+	dst = protowire.AppendTag(dst, num, protowire.BytesType)
+	return protowire.AppendBytes(dst, value)
+}
+
+// consumeBytes reads a length-delimited value and copies it, since the buffer it is decoded
+// from may be reused
+func consumeBytes(b []byte) ([]byte, int) {
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return nil, n
+	}
+	return append([]byte(nil), v...), n
+}
@@ -0,0 +1,177 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package flight
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/kelindar/column"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestDoPut(t *testing.T) {
+	catalog := newCatalog(t)
+	source := column.NewCollection()
+	source.CreateColumn("name", column.ForString())
+	source.CreateColumn("age", column.ForInt32())
+	for i := 0; i < 20000; i++ {
+		source.InsertObject(column.Object{"name": "Roman", "age": int32(i % 100)})
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, source.Query(func(txn *column.Txn) error {
+		return txn.WriteArrowStream(&buffer)
+	}))
+
+	client := newClient(t, catalog)
+	result, err := doPut(client, "players", splitStream(t, buffer.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":20000}`, string(result.AppMetadata))
+
+	players, _ := catalog.Collection("players")
+	assert.Equal(t, 20000, players.Count())
+	players.Query(func(txn *column.Txn) error {
+		assert.Equal(t, 200, txn.WithInt("age", func(v int64) bool {
+			return v == 42
+		}).Count())
+		return nil
+	})
+}
+
+func TestDoPutErrors(t *testing.T) {
+	client := newClient(t, newCatalog(t))
+
+	_, err := doPut(client, "", nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = doPut(client, "missing", nil)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = doPut(client, "players", []*FlightData{{DataHeader: []byte{1, 2, 3}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFlightDataMarshal(t *testing.T) {
+	input := &FlightData{
+		Descriptor:  &FlightDescriptor{Type: 1, Path: []string{"players"}},
+		DataHeader:  []byte{1, 2, 3},
+		AppMetadata: []byte("meta"),
+		DataBody:    []byte{4, 5},
+	}
+
+	encoded, err := input.Marshal()
+	assert.NoError(t, err)
+
+	output := new(FlightData)
+	assert.NoError(t, output.Unmarshal(encoded))
+	assert.Equal(t, input, output)
+	assert.Error(t, output.Unmarshal(encoded[:len(encoded)-1]))
+}
+
+// newCatalog creates a catalog with an empty collection of players
+func newCatalog(t *testing.T) *column.Catalog {
+	catalog := column.NewCatalog()
+	players, err := catalog.CreateCollection("players")
+	assert.NoError(t, err)
+	players.CreateColumn("name", column.ForString())
+	players.CreateColumn("age", column.ForInt32())
+	return catalog
+}
+
+// newClient serves the catalog over an in-memory listener and connects to it
+func newClient(t *testing.T, catalog *column.Catalog) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	New(catalog).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// doPut pushes the messages into the named collection and waits for the result
+func doPut(conn *grpc.ClientConn, name string, messages []*FlightData) (*PutResult, error) {
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{
+		StreamName:    "DoPut",
+		ServerStreams: true,
+		ClientStreams: true,
+	}, "/"+ServiceName+"/DoPut")
+	if err != nil {
+		return nil, err
+	}
+
+	first := &FlightData{Descriptor: &FlightDescriptor{Type: 1}}
+	if name != "" {
+		first.Descriptor.Path = []string{name}
+	}
+
+	if len(messages) > 0 {
+		first.DataHeader = messages[0].DataHeader
+		first.DataBody = messages[0].DataBody
+		messages = messages[1:]
+	}
+
+	for _, msg := range append([]*FlightData{first}, messages...) {
+		if err := stream.SendMsg(msg); err != nil {
+			break // The error is received below
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	result := new(PutResult)
+	if err := stream.RecvMsg(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// splitStream splits an Arrow stream into the flight messages, with the header of each
+// encapsulated message separate from its body
+func splitStream(t *testing.T, stream []byte) (out []*FlightData) {
+	for len(stream) >= 8 {
+		size := int(binary.LittleEndian.Uint32(stream[4:8]))
+		if size == 0 {
+			break // End of the stream
+		}
+
+		header := stream[8 : 8+size]
+		length := int(bodyLength(header))
+		out = append(out, &FlightData{
+			DataHeader: header,
+			DataBody:   stream[8+size : 8+size+length],
+		})
+		stream = stream[8+size+length:]
+	}
+
+	assert.NotEmpty(t, out)
+	return
+}
+
+// bodyLength reads the length of the body from the flatbuffer of a message
+func bodyLength(header []byte) int64 {
+	table := int(binary.LittleEndian.Uint32(header))
+	vtable := table - int(int32(binary.LittleEndian.Uint32(header[table:])))
+	offset := int(binary.LittleEndian.Uint16(header[vtable+4+2*3:]))
+	return int64(binary.LittleEndian.Uint64(header[table+offset:]))
+}
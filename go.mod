@@ -7,10 +7,15 @@ require (
 	github.com/kelindar/intmap v1.1.0
 	github.com/kelindar/iostream v1.3.0
 	github.com/kelindar/simd v1.1.2
-	github.com/kelindar/smutex v1.0.0
 	github.com/klauspost/compress v1.15.6
 	github.com/stretchr/testify v1.7.1
 	github.com/zeebo/xxh3 v1.0.2
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	github.com/google/go-cmp v0.5.6 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
 
 require (
@@ -0,0 +1,124 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/column/commit"
+)
+
+// History represents the retained history of a collection, from which its state at an
+// earlier point can be reconstructed. Typically, the commits are retained in a log store
+// which is truncated whenever a snapshot of the collection is taken periodically.
+type History struct {
+	Store    commit.LogStore // The retained commits, the writer of the collection by default
+	Snapshot io.Reader       // The most recent snapshot taken before the point (optional)
+}
+
+// AsOf reconstructs the state of the collection as it was right after the specified commit,
+// and returns it as a new, read-only collection with the same schema. The state is restored
+// from the snapshot of the history, if any, and the retained commits up to that point are
+// replayed on top of it. The snapshot must have been taken before the commit.
+func (c *Collection) AsOf(history History, commitID uint64) (*Collection, error) {
+	store := history.Store
+	if store == nil {
+		store, _ = c.opts.Writer.(commit.LogStore)
+	}
+
+	if store == nil {
+		return nil, fmt.Errorf("column: unable to reconstruct the collection, no log store")
+	}
+
+	past, err := c.cloneSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the snapshot first, since the commits older than it are no longer retained
+	var commits []uint64
+	if history.Snapshot != nil {
+		if commits, err = past.restoreUntil(history.Snapshot, commitID); err != nil {
+			past.Close()
+			return nil, err
+		}
+	}
+
+	// Replay the commits which are not part of the snapshot, up to the specified one
+	if err := past.replayStore(store, commits, commitID); err != nil {
+		past.Close()
+		return nil, err
+	}
+
+	atomic.StoreUint32(&past.frozen, 1)
+	return past, nil
+}
+
+// AsOfTime reconstructs the state of the collection as it was at the specified time, and
+// returns it as a new, read-only collection. See AsOf() for more details.
+func (c *Collection) AsOfTime(history History, t time.Time) (*Collection, error) {
+	return c.AsOf(history, commit.At(t))
+}
+
+// RestoreFrom restores the collection from a snapshot, and then replays the commits of the log
+// store which are not part of the snapshot, for example the commit log segments shipped since
+// the snapshot was taken. The replayed commits are not appended to the writer of the collection.
+// This operation should be called before any of transactions, right after initialization.
+func (c *Collection) RestoreFrom(snapshot io.Reader, store commit.LogStore) error {
+	var commits []uint64
+	if snapshot != nil {
+		var err error
+		if commits, err = c.restoreUntil(snapshot, math.MaxUint64); err != nil {
+			return err
+		}
+	}
+
+	return c.replayStore(store, commits, math.MaxUint64)
+}
+
+// replayStore replays the commits of the store up to the specified commit, skipping the ones
+// of the chunks which are already more recent in the collection.
+func (c *Collection) replayStore(store commit.LogStore, commits []uint64, commitID uint64) error {
+	return store.Range(0, func(change commit.Commit) error {
+		if change.ID > commitID || (int(change.Chunk) < len(commits) && change.ID <= commits[change.Chunk]) {
+			return nil
+		}
+		return c.replay(change, false)
+	})
+}
+
+// restoreUntil restores the collection from a snapshot, including the commits recorded while
+// the snapshot was taken, up to the specified commit. It returns the commit IDs of the chunks
+// in the snapshot.
+func (c *Collection) restoreUntil(snapshot io.Reader, commitID uint64) ([]uint64, error) {
+	commits, err := c.readSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, last := range commits {
+		if last > commitID {
+			return nil, fmt.Errorf("column: unable to reconstruct the collection at commit %d, the snapshot is more recent", commitID)
+		}
+	}
+
+	// Keep track of the recorded commits, so that they are not replayed again from the store
+	err = c.openLog(snapshot).Range(func(change commit.Commit) error {
+		if change.ID > commitID || (int(change.Chunk) < len(commits) && change.ID <= commits[change.Chunk]) {
+			return nil
+		}
+
+		for len(commits) <= int(change.Chunk) {
+			commits = append(commits, 0)
+		}
+
+		commits[change.Chunk] = change.ID
+		return c.replay(change, false)
+	})
+	return commits, err
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsOf(t *testing.T) {
+	store := commit.NewMemoryStore()
+	players := newHistory(store)
+	roman := players.InsertObject(Object{"name": "Roman", "balance": 100.0})
+	merlin := players.InsertObject(Object{"name": "Merlin", "balance": 200.0})
+	before := players.LastCommit()
+	at := time.Now()
+
+	// Modify the collection after the point in time
+	assert.NoError(t, players.QueryAt(roman, func(r Row) error {
+		r.SetFloat64("balance", 0)
+		return nil
+	}))
+	players.DeleteAt(merlin)
+	players.InsertObject(Object{"name": "Arthur", "balance": 300.0})
+
+	for _, past := range []func() (*Collection, error){
+		func() (*Collection, error) { return players.AsOf(History{}, before) },
+		func() (*Collection, error) { return players.AsOfTime(History{Store: store}, at) },
+	} {
+		past, err := past()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, past.Count())
+		assert.Equal(t, 300.0, balanceOf(past))
+		assert.NoError(t, past.QueryAt(merlin, func(r Row) error {
+			name, _ := r.String("name")
+			assert.Equal(t, "Merlin", name)
+			return nil
+		}))
+
+		// The past state can not be modified
+		assert.Equal(t, errReadOnly, past.Query(func(txn *Txn) error {
+			txn.InsertObject(Object{"name": "Lancelot"})
+			return nil
+		}))
+		assert.Equal(t, 2, past.Count())
+		past.Close()
+	}
+
+	// The present remains intact
+	assert.Equal(t, 2, players.Count())
+	assert.Equal(t, 300.0, balanceOf(players))
+}
+
+func TestAsOfSnapshot(t *testing.T) {
+	store := commit.NewMemoryStore()
+	players := newHistory(store)
+	players.InsertObject(Object{"name": "Roman", "balance": 100.0})
+	early := players.LastCommit()
+	players.InsertObject(Object{"name": "Merlin", "balance": 200.0})
+
+	// Take a snapshot and truncate the commits it contains
+	snapshot := bytes.NewBuffer(nil)
+	assert.NoError(t, players.Snapshot(snapshot))
+	assert.NoError(t, store.Truncate(players.LastCommit()+1))
+
+	players.InsertObject(Object{"name": "Arthur", "balance": 300.0})
+	middle := players.LastCommit()
+	players.InsertObject(Object{"name": "Lancelot", "balance": 400.0})
+
+	past, err := players.AsOf(History{Snapshot: bytes.NewReader(snapshot.Bytes())}, middle)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, past.Count())
+	assert.Equal(t, 600.0, balanceOf(past))
+	past.Close()
+
+	// The commits before the snapshot are no longer retained
+	_, err = players.AsOf(History{Snapshot: bytes.NewReader(snapshot.Bytes())}, early)
+	assert.Error(t, err)
+}
+
+func TestAsOfNoStore(t *testing.T) {
+	players := NewCollection()
+	_, err := players.AsOf(History{}, players.LastCommit())
+	assert.Error(t, err)
+}
+
+// newHistory creates a collection which retains its commits in the store
+func newHistory(store commit.LogStore) *Collection {
+	players := NewCollection(Options{Writer: store})
+	players.CreateColumn("name", ForString())
+	players.CreateColumn("balance", ForFloat64())
+	return players
+}
+
+// balanceOf returns the total balance of the players
+func balanceOf(players *Collection) (total float64) {
+	players.Query(func(txn *Txn) error {
+		total = txn.Float64("balance").Sum()
+		return nil
+	})
+	return
+}
+
+func TestRestoreFrom(t *testing.T) {
+	store := commit.NewMemoryStore()
+	players := newHistory(store)
+	players.InsertObject(Object{"name": "Roman", "balance": 100.0})
+
+	// Take a snapshot, then keep modifying the collection
+	snapshot := bytes.NewBuffer(nil)
+	assert.NoError(t, players.Snapshot(snapshot))
+	players.InsertObject(Object{"name": "Merlin", "balance": 200.0})
+	assert.NoError(t, players.QueryAt(0, func(r Row) error {
+		r.AddFloat64("balance", 50)
+		return nil
+	}))
+
+	// The commits which are part of the snapshot are not applied twice
+	output := newHistory(commit.NewMemoryStore())
+	assert.NoError(t, output.RestoreFrom(snapshot, store))
+	assert.Equal(t, 2, output.Count())
+	assert.Equal(t, 350.0, balanceOf(output))
+
+	// Without a snapshot, the entire store is replayed
+	output = newHistory(commit.NewMemoryStore())
+	assert.NoError(t, output.RestoreFrom(nil, store))
+	assert.Equal(t, 350.0, balanceOf(output))
+}
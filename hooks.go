@@ -0,0 +1,113 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+
+	"github.com/kelindar/column/commit"
+)
+
+// Commit represents a summary of the changes of a transaction, which is passed to the
+// commit and rollback listeners. On rollback, it summarizes the changes which were discarded.
+type Commit struct {
+	ID       uint64         // The ID of the most recent commit of the transaction, zero on rollback
+	Inserted int            // The number of rows inserted
+	Deleted  int            // The number of rows deleted
+	Updated  map[string]int // The number of values written or removed, per column
+	Meta     []byte         // The metadata attached to the transaction, if any
+}
+
+// OnCommit registers a callback which is invoked once a transaction which modified the
+// collection is committed, with a summary of its changes. This allows, for example, to
+// invalidate the external caches exactly when the data changes.
+//
+// The callback is invoked synchronously once the locks of the commit are released, hence
+// it may query the collection but should return quickly, since it holds back the caller.
+func (c *Collection) OnCommit(fn func(Commit)) {
+	c.hooks.lock.Lock()
+	defer c.hooks.lock.Unlock()
+	c.hooks.commit = append(c.hooks.commit, fn)
+}
+
+// OnRollback registers a callback which is invoked once a transaction with pending changes
+// is rolled back, with a summary of the changes which were discarded. The transactions
+// which were not about to modify the collection do not invoke the callback.
+func (c *Collection) OnRollback(fn func(Commit)) {
+	c.hooks.lock.Lock()
+	defer c.hooks.lock.Unlock()
+	c.hooks.rollback = append(c.hooks.rollback, fn)
+}
+
+// listeners represents a set of the registered commit and rollback callbacks
+type listeners struct {
+	lock     sync.RWMutex   // The lock to protect the callbacks
+	commit   []func(Commit) // The commit callbacks, in order of registration
+	rollback []func(Commit) // The rollback callbacks, in order of registration
+}
+
+// active returns whether any callback is registered
+func (l *listeners) active() bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return len(l.commit) > 0 || len(l.rollback) > 0
+}
+
+// notify invokes the callbacks with the summary of a transaction, if it was captured
+func (l *listeners) notify(txn *Txn) {
+	summary, committed := txn.summary, txn.applied
+	if summary == nil {
+		return
+	}
+
+	txn.summary = nil
+	l.lock.RLock()
+	hooks := l.rollback
+	if committed {
+		hooks = l.commit
+	}
+	l.lock.RUnlock()
+
+	for _, fn := range hooks {
+		fn(*summary)
+	}
+}
+
+// summarize captures the summary of the pending changes of the transaction, if there are
+// any listeners. It must be called before the transaction is reset.
+func (txn *Txn) summarize(committed bool) *Commit {
+	if !txn.hasUpdates() || !txn.owner.hooks.active() {
+		return nil
+	}
+
+	summary := &Commit{
+		Updated: make(map[string]int, len(txn.updates)),
+		Meta:    txn.meta,
+	}
+
+	for _, u := range txn.updates {
+		if u.IsEmpty() {
+			continue
+		}
+
+		u.RangeChunks(func(chunk commit.Chunk) {
+			txn.reader.Range(u, chunk, func(r *commit.Reader) {
+				for r.Next() {
+					switch {
+					case u.Column != rowColumn:
+						summary.Updated[u.Column]++
+					case r.Type == commit.Insert:
+						summary.Inserted++
+					case r.Type == commit.Delete:
+						summary.Deleted++
+					}
+				}
+			})
+		})
+	}
+
+	txn.summary = summary
+	txn.applied = committed
+	return summary
+}
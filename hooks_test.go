@@ -0,0 +1,146 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnCommit(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("balance", ForFloat64())
+
+	var commits []Commit
+	coll.OnCommit(func(c Commit) {
+		commits = append(commits, c)
+	})
+
+	// Insert a few rows
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		for i := 0; i < 3; i++ {
+			txn.InsertObject(Object{"name": fmt.Sprintf("player %d", i), "balance": 10.0})
+		}
+		return nil
+	}))
+
+	assert.Len(t, commits, 1)
+	assert.NotZero(t, commits[0].ID)
+	assert.Equal(t, 3, commits[0].Inserted)
+	assert.Equal(t, 0, commits[0].Deleted)
+	assert.Equal(t, map[string]int{"name": 3, "balance": 3}, commits[0].Updated)
+
+	// Update a column and delete a row
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		balance := txn.Float64("balance")
+		txn.Range(func(idx uint32) {
+			balance.Add(5)
+		})
+
+		txn.Annotate([]byte("tx"))
+		txn.DeleteAt(0)
+		return nil
+	}))
+
+	assert.Len(t, commits, 2)
+	assert.Greater(t, commits[1].ID, commits[0].ID)
+	assert.Equal(t, 0, commits[1].Inserted)
+	assert.Equal(t, 1, commits[1].Deleted)
+	assert.Equal(t, map[string]int{"balance": 3}, commits[1].Updated)
+	assert.Equal(t, []byte("tx"), commits[1].Meta)
+
+	// Read-only transactions do not invoke the listeners
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		return nil
+	}))
+	assert.Len(t, commits, 2)
+}
+
+func TestOnCommitQuery(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+
+	// The listener can query the collection, since the locks are released
+	var count int
+	coll.OnCommit(func(c Commit) {
+		count = coll.Count()
+	})
+
+	coll.InsertObject(Object{"name": "Roman"})
+	coll.InsertObject(Object{"name": "Merlin"})
+	assert.Equal(t, 2, count)
+}
+
+func TestOnRollback(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.InsertObject(Object{"name": "Roman"})
+
+	var commits, rollbacks []Commit
+	coll.OnCommit(func(c Commit) {
+		commits = append(commits, c)
+	})
+	coll.OnRollback(func(c Commit) {
+		rollbacks = append(rollbacks, c)
+	})
+
+	assert.Error(t, coll.Query(func(txn *Txn) error {
+		txn.InsertObject(Object{"name": "Merlin"})
+		txn.DeleteAt(0)
+		return fmt.Errorf("boom")
+	}))
+
+	assert.Empty(t, commits)
+	assert.Len(t, rollbacks, 1)
+	assert.Zero(t, rollbacks[0].ID)
+	assert.Equal(t, 1, rollbacks[0].Inserted)
+	assert.Equal(t, 1, rollbacks[0].Deleted)
+	assert.Equal(t, map[string]int{"name": 1}, rollbacks[0].Updated)
+	assert.Equal(t, 1, coll.Count())
+
+	// Transactions without any pending changes do not invoke the listeners
+	assert.Error(t, coll.Query(func(txn *Txn) error {
+		return fmt.Errorf("boom")
+	}))
+	assert.Len(t, rollbacks, 1)
+}
+
+func TestOnCommitAtomic(t *testing.T) {
+	players := NewCollection()
+	players.CreateColumn("name", ForString())
+	guilds := NewCollection()
+	guilds.CreateColumn("name", ForString())
+
+	var commits, rollbacks int
+	for _, c := range []*Collection{players, guilds} {
+		c.OnCommit(func(Commit) { commits++ })
+		c.OnRollback(func(Commit) { rollbacks++ })
+	}
+
+	assert.NoError(t, Atomic(func(tx *Tx) error {
+		tx.Query(players, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"name": "Roman"})
+			return err
+		})
+		return tx.Query(guilds, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"name": "Knights"})
+			return err
+		})
+	}))
+	assert.Equal(t, 2, commits)
+	assert.Equal(t, 0, rollbacks)
+
+	assert.Error(t, Atomic(func(tx *Tx) error {
+		tx.Query(players, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"name": "Merlin"})
+			return err
+		})
+		return fmt.Errorf("boom")
+	}))
+	assert.Equal(t, 2, commits)
+	assert.Equal(t, 1, rollbacks)
+}
@@ -0,0 +1,399 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/expr"
+)
+
+// Query represents the JSON filter of the query endpoint, for example
+//
+//	{
+//	  "where": [
+//	    {"column": "rogue"},
+//	    {"column": "age", "op": ">", "value": 30},
+//	    {"column": "class", "op": "in", "value": ["mage", "druid"]}
+//	  ],
+//	  "columns": ["name", "age"],
+//	  "limit": 10
+//	}
+//
+// All of the conditions must match. A condition without an operator selects the rows which
+// have a value in the column (or are part of the index), while the "not" operator selects the
+// ones which do not. The other operators are "=", "!=", "<", "<=", ">", ">=" and "in".
+//
+// In addition, the rows can be filtered with an expression and the query can define computed
+// fields, both written in the expression language of the expr package, for example
+//
+//	{
+//	  "filter": "age > 30 && contains(name, 'an')",
+//	  "computed": {"bmi": "weight / (height * height)"}
+//	}
+type Query struct {
+	Where    []Condition       `json:"where"`    // The conditions which must all match
+	Filter   string            `json:"filter"`   // The expression which must be true (optional)
+	Computed map[string]string `json:"computed"` // The expressions of the computed fields, by name
+	Columns  []string          `json:"columns"`  // The columns to return, all of them if empty
+	Limit    int               `json:"limit"`    // The maximum number of rows to return, unlimited if zero
+}
+
+// Condition represents a single condition of the filter
+type Condition struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  any    `json:"value"`
+}
+
+// execute executes the query against the collection and returns the matching rows along with
+// the total number of matches. The registered computed fields are returned along with the ones
+// of the query.
+func (q *Query) execute(c *column.Collection, fields computed) (rows []*Row, count int, err error) {
+	all := make(computed, len(fields)+len(q.Computed))
+	for name, field := range fields {
+		all[name] = field
+	}
+	for name, source := range q.Computed {
+		if all[name], err = parseField(c, name, source); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Select the columns and the computed fields to return
+	columns, selected := q.Columns, all
+	if len(columns) > 0 {
+		columns, selected = make([]string, 0, len(q.Columns)), make(computed)
+		for _, name := range q.Columns {
+			if field, ok := all[name]; ok {
+				selected[name] = field
+			} else {
+				columns = append(columns, name)
+			}
+		}
+	} else {
+		columns = c.Columns()
+	}
+
+	for _, name := range columns {
+		if _, ok := c.KindOf(name); !ok {
+			return nil, 0, fmt.Errorf("column: column '%s' does not exist", name)
+		}
+	}
+
+	filter, err := q.parseFilter(c)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var indexes []uint32
+	if err = c.View(func(txn *column.Txn) error {
+		if err := q.apply(txn, filter); err != nil {
+			return err
+		}
+
+		if err := txn.Range(func(idx uint32) {
+			if count++; q.Limit <= 0 || len(indexes) < q.Limit {
+				indexes = append(indexes, idx)
+			}
+		}); err != nil {
+			return err
+		}
+
+		rows = make([]*Row, 0, len(indexes))
+		return c.SelectAt(indexes, func(v column.Selector) {
+			rows = append(rows, readRow(v, columns, selected))
+		})
+	}); err != nil {
+		return nil, 0, err
+	}
+	return
+}
+
+// stream executes the query against the collection and writes the values of the matching
+// rows into the destination as an Arrow stream. The computed fields are not supported.
+func (q *Query) stream(dst io.Writer, c *column.Collection) error {
+	if len(q.Computed) > 0 || q.Limit > 0 {
+		return fmt.Errorf("column: computed fields and limit are not supported by arrow streams")
+	}
+
+	filter, err := q.parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	return c.View(func(txn *column.Txn) error {
+		if err := q.apply(txn, filter); err != nil {
+			return err
+		}
+		return txn.WriteArrowStream(dst, q.Columns...)
+	})
+}
+
+// parseFilter parses the filter expression of the query, if any
+func (q *Query) parseFilter(c *column.Collection) (*expr.Expression, error) {
+	if q.Filter == "" {
+		return nil, nil
+	}
+	return parseField(c, "filter", q.Filter)
+}
+
+// apply applies the conditions and the filter expression of the query onto the transaction
+func (q *Query) apply(txn *column.Txn, filter *expr.Expression) error {
+	for i := range q.Where {
+		if err := q.Where[i].apply(txn); err != nil {
+			return err
+		}
+	}
+
+	if filter != nil {
+		return filter.Filter(txn)
+	}
+	return nil
+}
+
+// apply applies the condition onto the transaction
+func (cond *Condition) apply(txn *column.Txn) error {
+	switch cond.Op {
+	case "":
+		txn.With(cond.Column)
+		return nil
+	case "not":
+		txn.Without(cond.Column)
+		return nil
+	case "in":
+		return cond.applyIn(txn)
+	case "=", "!=", "<", "<=", ">", ">=":
+		return cond.applyCompare(txn)
+	default:
+		return fmt.Errorf("column: unsupported operator '%s'", cond.Op)
+	}
+}
+
+// applyCompare applies a comparison of the column with the value onto the transaction
+func (cond *Condition) applyCompare(txn *column.Txn) error {
+	switch value := cond.Value.(type) {
+	case bool:
+		if cond.Op != "=" && cond.Op != "!=" {
+			return fmt.Errorf("column: unsupported operator '%s' for a boolean", cond.Op)
+		}
+
+		if (cond.Op == "=") == value {
+			txn.With(cond.Column)
+		} else {
+			txn.Without(cond.Column)
+		}
+
+	case string:
+		if cond.Op == "=" {
+			txn.WithEqual(cond.Column, value)
+			return nil
+		}
+
+		op := cond.Op
+		txn.WithString(cond.Column, func(v string) bool {
+			return compare(v, value, op)
+		})
+
+	case json.Number:
+		number, err := value.Float64()
+		if err != nil {
+			return fmt.Errorf("column: invalid number '%s'", value)
+		}
+
+		switch op := cond.Op; op {
+		case "=":
+			txn.WithFloatEqual(cond.Column, number)
+		case "<":
+			txn.WithFloatLess(cond.Column, number)
+		case ">":
+			txn.WithFloatGreater(cond.Column, number)
+		default:
+			txn.WithFloat(cond.Column, func(v float64) bool {
+				return compare(v, number, op)
+			})
+		}
+
+	default:
+		return fmt.Errorf("column: unsupported value '%v' for column '%s'", cond.Value, cond.Column)
+	}
+	return nil
+}
+
+// applyIn applies a membership test onto the transaction
+func (cond *Condition) applyIn(txn *column.Txn) error {
+	values, ok := cond.Value.([]any)
+	if !ok || len(values) == 0 {
+		return fmt.Errorf("column: operator 'in' requires an array of values")
+	}
+
+	texts := make(map[string]bool, len(values))
+	numbers := make(map[float64]bool, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			texts[v] = true
+		case json.Number:
+			number, err := v.Float64()
+			if err != nil {
+				return fmt.Errorf("column: invalid number '%s'", v)
+			}
+			numbers[number] = true
+		default:
+			return fmt.Errorf("column: unsupported value '%v' for column '%s'", v, cond.Column)
+		}
+	}
+
+	switch {
+	case len(texts) > 0 && len(numbers) > 0:
+		return fmt.Errorf("column: operator 'in' requires values of the same type")
+	case len(texts) > 0:
+		txn.WithString(cond.Column, func(v string) bool {
+			return texts[v]
+		})
+	default:
+		txn.WithFloat(cond.Column, func(v float64) bool {
+			return numbers[v]
+		})
+	}
+	return nil
+}
+
+// compare compares two ordered values with the operator
+func compare[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return false
+	}
+}
+
+// --------------------------- Computed ----------------------------
+
+// computed represents a set of computed fields, by name
+type computed map[string]*expr.Expression
+
+// parseField parses the expression of a computed field and validates it against the collection
+func parseField(c *column.Collection, name, source string) (*expr.Expression, error) {
+	field, err := expr.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("column: invalid expression '%s', %w", name, err)
+	}
+
+	if err := field.Validate(c); err != nil {
+		return nil, fmt.Errorf("column: invalid expression '%s', %w", name, err)
+	}
+	return field, nil
+}
+
+// --------------------------- Conversion ----------------------------
+
+// convertObject converts the decoded JSON values into the types of the columns
+func convertObject(c *column.Collection, values map[string]any) (column.Object, error) {
+	object := make(column.Object, len(values))
+	for name, value := range values {
+		kind, ok := c.KindOf(name)
+		if !ok {
+			return nil, fmt.Errorf("column: column '%s' does not exist", name)
+		}
+
+		v, err := convert(value, kind)
+		if err != nil {
+			return nil, fmt.Errorf("column: invalid value for column '%s', %w", name, err)
+		}
+		object[name] = v
+	}
+	return object, nil
+}
+
+// convert converts a decoded JSON value into the specified kind
+func convert(value any, kind reflect.Kind) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return convertNumber(v, kind)
+	case string:
+		if kind == reflect.String || kind == reflect.Interface {
+			return v, nil
+		}
+	case bool:
+		if kind == reflect.Bool || kind == reflect.Interface {
+			return v, nil
+		}
+	default:
+		if kind == reflect.Interface {
+			return value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("expected a value of kind %v", kind)
+}
+
+// convertNumber converts a JSON number into the specified numeric kind
+func convertNumber(v json.Number, kind reflect.Kind) (any, error) {
+	switch kind {
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(v), 10, bitsOf(kind))
+		switch kind {
+		case reflect.Int:
+			return int(n), err
+		case reflect.Int16:
+			return int16(n), err
+		case reflect.Int32:
+			return int32(n), err
+		default:
+			return n, err
+		}
+
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(string(v), 10, bitsOf(kind))
+		switch kind {
+		case reflect.Uint:
+			return uint(n), err
+		case reflect.Uint16:
+			return uint16(n), err
+		case reflect.Uint32:
+			return uint32(n), err
+		default:
+			return n, err
+		}
+
+	case reflect.Float32:
+		n, err := strconv.ParseFloat(string(v), 32)
+		return float32(n), err
+
+	case reflect.Float64, reflect.Interface:
+		return v.Float64()
+
+	default:
+		return nil, fmt.Errorf("expected a value of kind %v", kind)
+	}
+}
+
+// bitsOf returns the size of a numeric kind, in bits
+func bitsOf(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32:
+		return 32
+	default:
+		return 64
+	}
+}
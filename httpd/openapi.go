@@ -0,0 +1,314 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package httpd
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/kelindar/column"
+)
+
+// lister represents a source which is able to list the names of its collections, such as a
+// catalog or a set of tables.
+type lister interface {
+	Names() []string
+}
+
+// schema represents a JSON schema, or any other object of an OpenAPI document
+type schema = map[string]any
+
+// openapi writes the OpenAPI document describing the endpoints of the collections. If no
+// collection is specified, all of the collections of the source are described, provided
+// that the source is able to list them.
+func (s *Server) openapi(w http.ResponseWriter, names ...string) {
+	if len(names) == 0 {
+		source, ok := s.source.(lister)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("column: unable to list the collections"))
+			return
+		}
+		names = source.Names()
+	}
+
+	writeJSON(w, http.StatusOK, s.Document(names...))
+}
+
+// Document generates an OpenAPI 3.0 document which describes the endpoints of the specified
+// collections, along with the schema of their rows. The schema is derived from the columns
+// and the computed fields of each collection, at the time the document is generated. The
+// paths are relative to the mount point of the server.
+func (s *Server) Document(names ...string) map[string]any {
+	paths := make(schema)
+	schemas := schema{
+		"Error": schema{
+			"type": "object",
+			"properties": schema{
+				"error": schema{"type": "string"},
+			},
+		},
+		"Condition": schema{
+			"type":     "object",
+			"required": []string{"column"},
+			"properties": schema{
+				"column": schema{"type": "string", "description": "The name of a column or an index"},
+				"op":     schema{"type": "string", "enum": []string{"", "not", "=", "!=", "<", "<=", ">", ">=", "in"}},
+				"value":  schema{"description": "The value to compare with, or an array of values for the 'in' operator"},
+			},
+		},
+		"Query": schema{
+			"type": "object",
+			"properties": schema{
+				"where":    schema{"type": "array", "items": ref("Condition")},
+				"filter":   schema{"type": "string", "description": "An expression which must be true"},
+				"computed": schema{"type": "object", "additionalProperties": schema{"type": "string"}},
+				"columns":  schema{"type": "array", "items": schema{"type": "string"}},
+				"limit":    schema{"type": "integer", "minimum": 0},
+			},
+		},
+		"Computed": schema{
+			"type":     "object",
+			"required": []string{"expression"},
+			"properties": schema{
+				"expression": schema{"type": "string"},
+			},
+		},
+	}
+
+	for _, name := range names {
+		if c, ok := s.source.Collection(name); ok {
+			s.describe(name, c, paths, schemas)
+		}
+	}
+
+	return schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":   "column",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": schema{
+			"schemas": schemas,
+		},
+	}
+}
+
+// describe adds the paths and the schemas of a single collection to the document
+func (s *Server) describe(name string, c *column.Collection, paths, schemas schema) {
+	object, row := schemaName(name, "Object"), schemaName(name, "Row")
+	schemas[object] = objectOf(c)
+	schemas[row] = rowOf(c, s.fieldsOf(name))
+
+	failures := func(status ...int) schema {
+		out := make(schema, len(status))
+		for _, code := range status {
+			out[fmt.Sprint(code)] = response(http.StatusText(code), ref("Error"))
+		}
+		return out
+	}
+
+	with := func(out schema, status int, body schema) schema {
+		out[fmt.Sprint(status)] = response(http.StatusText(status), body)
+		return out
+	}
+
+	base := "/" + name
+	paths[base+"/rows"] = schema{
+		"post": schema{
+			"operationId": name + ".insert",
+			"summary":     "Inserts an object, or an array of objects",
+			"requestBody": body(schema{"oneOf": []any{
+				ref(object),
+				schema{"type": "array", "items": ref(object)},
+			}}),
+			"responses": with(failures(400), 201, schema{
+				"type": "object",
+				"properties": schema{
+					"indexes": schema{"type": "array", "items": schema{"type": "integer", "minimum": 0}},
+				},
+			}),
+		},
+	}
+
+	paths[base+"/rows/{index}"] = schema{
+		"parameters": []any{parameter("index", schema{"type": "integer", "minimum": 0})},
+		"get": schema{
+			"operationId": name + ".read",
+			"summary":     "Reads a row by its offset",
+			"responses":   with(failures(400, 404), 200, ref(row)),
+		},
+		"patch": schema{
+			"operationId": name + ".update",
+			"summary":     "Updates the values of a row by its offset",
+			"requestBody": body(ref(object)),
+			"responses":   with(failures(400, 404), 200, ref(row)),
+		},
+	}
+
+	if _, ok := c.PrimaryKey(); ok {
+		paths[base+"/keys/{key}"] = schema{
+			"parameters": []any{parameter("key", schema{"type": "string"})},
+			"get": schema{
+				"operationId": name + ".readKey",
+				"summary":     "Reads a row by its primary key",
+				"responses":   with(failures(404), 200, ref(row)),
+			},
+			"patch": schema{
+				"operationId": name + ".updateKey",
+				"summary":     "Updates the values of a row by its primary key",
+				"requestBody": body(ref(object)),
+				"responses":   with(failures(400, 404), 200, ref(row)),
+			},
+		}
+	}
+
+	paths[base+"/query"] = schema{
+		"post": schema{
+			"operationId": name + ".query",
+			"summary":     "Queries the rows with a JSON filter",
+			"requestBody": body(ref("Query")),
+			"responses": with(failures(400), 200, schema{
+				"type": "object",
+				"properties": schema{
+					"count": schema{"type": "integer"},
+					"rows":  schema{"type": "array", "items": ref(row)},
+				},
+			}),
+		},
+	}
+
+	paths[base+"/computed/{name}"] = schema{
+		"parameters": []any{parameter("name", schema{"type": "string"})},
+		"put": schema{
+			"operationId": name + ".compute",
+			"summary":     "Registers a computed field",
+			"requestBody": body(ref("Computed")),
+			"responses": with(failures(400), 200, schema{
+				"type": "object",
+				"properties": schema{
+					"name":       schema{"type": "string"},
+					"expression": schema{"type": "string"},
+				},
+			}),
+		},
+		"delete": schema{
+			"operationId": name + ".uncompute",
+			"summary":     "Removes a computed field",
+			"responses":   with(failures(404), 204, nil),
+		},
+	}
+}
+
+// objectOf returns the schema of the objects which can be written into the collection
+func objectOf(c *column.Collection) schema {
+	properties := make(schema)
+	for _, name := range c.Columns() {
+		kind, _ := c.KindOf(name)
+		properties[name] = typeOf(kind)
+	}
+
+	return schema{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// rowOf returns the schema of the rows read from the collection, along with the values of
+// the computed fields. The types of the computed fields are only known once evaluated.
+func rowOf(c *column.Collection, fields computed) schema {
+	values := objectOf(c)
+	properties := values["properties"].(schema)
+
+	for name, field := range fields {
+		properties[name] = schema{
+			"description": "Computed as " + field.String(),
+			"readOnly":    true,
+		}
+	}
+
+	return schema{
+		"type":     "object",
+		"required": []string{"index", "values"},
+		"properties": schema{
+			"index":  schema{"type": "integer", "minimum": 0},
+			"values": values,
+		},
+	}
+}
+
+// typeOf returns the schema of the values of a specific kind
+func typeOf(kind reflect.Kind) schema {
+	switch kind {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Int16, reflect.Int32:
+		return schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return schema{"type": "integer", "format": "int64"}
+	case reflect.Uint16, reflect.Uint32:
+		return schema{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Uint, reflect.Uint64:
+		return schema{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return schema{"type": "number", "format": "double"}
+	default:
+		return schema{}
+	}
+}
+
+// schemaName returns the name of a schema of a collection, replacing the characters which
+// are not allowed in the names of the components.
+func schemaName(collection, suffix string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, collection) + "." + suffix
+}
+
+// ref returns a reference to a schema of the document
+func ref(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+// body returns a JSON request body of the specified schema
+func body(content schema) schema {
+	return schema{
+		"required": true,
+		"content": schema{
+			"application/json": schema{"schema": content},
+		},
+	}
+}
+
+// parameter returns a required path parameter of the specified schema
+func parameter(name string, content schema) schema {
+	return schema{
+		"name":     name,
+		"in":       "path",
+		"required": true,
+		"schema":   content,
+	}
+}
+
+// response returns a JSON response of the specified schema, or an empty one
+func response(description string, content schema) schema {
+	out := schema{"description": description}
+	if content != nil {
+		out["content"] = schema{
+			"application/json": schema{"schema": content},
+		}
+	}
+	return out
+}
@@ -0,0 +1,414 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package httpd exposes the collections over a small REST API, which can be embedded into an
+// existing service. The following routes are served, relative to the mount point:
+//
+//	GET    /{collection}/rows/{index}     reads a row by its offset
+//	GET    /{collection}/keys/{key}       reads a row by its primary key
+//	PATCH  /{collection}/rows/{index}     updates the values of a row by its offset
+//	PATCH  /{collection}/keys/{key}       updates the values of a row by its primary key
+//	POST   /{collection}/rows             inserts an object, or an array of objects
+//	POST   /{collection}/query            queries the rows with a JSON filter
+//	PUT    /{collection}/computed/{name}  registers a computed field
+//	DELETE /{collection}/computed/{name}  removes a computed field
+//	GET    /{collection}/openapi.json     describes the endpoints of a collection
+//	GET    /openapi.json                  describes the endpoints of all of the collections
+//
+// The rows are encoded as JSON objects of the column values, and the values of the writes are
+// converted to the types of the columns. The computed fields are defined with an expression of
+// the expr package, such as {"expression": "weight / (height * height)"}, and are returned
+// along with the values of the columns. If the query accepts the Arrow streaming format, with
+// the "application/vnd.apache.arrow.stream" media type, the matching rows are streamed as Arrow
+// record batches instead, so that they can be consumed by BI tools and dataframes directly.
+// Likewise, the rows posted with this content type are read as Arrow record batches and
+// inserted column by column. The OpenAPI documents are generated from the schema
+// of the collections, so that the clients can be generated for them. Listing all of the
+// collections requires a source which is able to list them, such as a catalog.
+package httpd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/sql"
+)
+
+var (
+	errNotFound = errors.New("column: row not found")
+)
+
+// Server represents an HTTP handler which exposes the collections of a source.
+type Server struct {
+	source sql.Source
+	lock   sync.RWMutex        // The lock to protect the computed fields
+	fields map[string]computed // The computed fields, by collection
+}
+
+// New creates a new HTTP handler for the collections of the source, such as a catalog.
+func New(source sql.Source) *Server {
+	return &Server{
+		source: source,
+		fields: make(map[string]computed),
+	}
+}
+
+// Row represents a single row in the responses
+type Row struct {
+	Index  uint32         `json:"index"`
+	Values map[string]any `json:"values"`
+}
+
+// ServeHTTP serves an HTTP request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) == 1 && path[0] == "openapi.json" && r.Method == http.MethodGet {
+		s.openapi(w)
+		return
+	}
+
+	if len(path) < 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("column: route '%s' not found", r.URL.Path))
+		return
+	}
+
+	c, ok := s.source.Collection(path[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("column: collection '%s' does not exist", path[0]))
+		return
+	}
+
+	switch {
+	case len(path) == 2 && path[1] == "openapi.json" && r.Method == http.MethodGet:
+		s.openapi(w, path[0])
+	case len(path) == 2 && path[1] == "rows" && r.Method == http.MethodPost:
+		s.insert(w, r, c)
+	case len(path) == 2 && path[1] == "query" && r.Method == http.MethodPost:
+		s.query(w, r, c, path[0])
+	case len(path) == 3 && path[1] == "computed" && r.Method == http.MethodPut:
+		s.compute(w, r, c, path[0], path[2])
+	case len(path) == 3 && path[1] == "computed" && r.Method == http.MethodDelete:
+		s.uncompute(w, path[0], path[2])
+	case len(path) == 3 && (path[1] == "rows" || path[1] == "keys"):
+		idx, err := lookup(c, path[1], path[2])
+		switch {
+		case err == errNotFound:
+			writeError(w, http.StatusNotFound, err)
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err)
+		case r.Method == http.MethodGet:
+			s.read(w, c, path[0], idx)
+		case r.Method == http.MethodPatch:
+			s.update(w, r, c, path[0], idx)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("column: method '%s' not allowed", r.Method))
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("column: route '%s' not found", r.URL.Path))
+	}
+}
+
+// lookup resolves the offset of an existing row, either by its offset or its primary key
+func lookup(c *column.Collection, by, value string) (idx uint32, err error) {
+	found := false
+	fn := func(v column.Selector) {
+		idx, found = v.Index(), true
+	}
+
+	switch by {
+	case "keys":
+		err = c.SelectKeys([]string{value}, fn)
+	default:
+		offset, parseErr := strconv.ParseUint(value, 10, 32)
+		if parseErr != nil {
+			return 0, fmt.Errorf("column: invalid row index '%s'", value)
+		}
+		err = c.SelectAt([]uint32{uint32(offset)}, fn)
+	}
+
+	if err == nil && !found {
+		err = errNotFound
+	}
+	return
+}
+
+// read writes the row at the specified offset
+func (s *Server) read(w http.ResponseWriter, c *column.Collection, name string, idx uint32) {
+	var row *Row
+	fields := s.fieldsOf(name)
+	c.SelectAt([]uint32{idx}, func(v column.Selector) {
+		row = readRow(v, c.Columns(), fields)
+	})
+
+	if row == nil {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, row)
+}
+
+// update updates the values of the row at the specified offset
+func (s *Server) update(w http.ResponseWriter, r *http.Request, c *column.Collection, name string, idx uint32) {
+	var values map[string]any
+	if err := decode(r, &values); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	object, err := convertObject(c, values)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.QueryAt(idx, func(row column.Row) error {
+		for name, value := range object {
+			row.SetAny(name, value)
+		}
+		return nil
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.read(w, c, name, idx)
+}
+
+// insert inserts an object, or an array of objects into the collection
+func (s *Server) insert(w http.ResponseWriter, r *http.Request, c *column.Collection) {
+	if strings.Contains(r.Header.Get("Content-Type"), arrowStream) {
+		s.insertArrow(w, r, c)
+		return
+	}
+
+	var body json.RawMessage
+	if err := decode(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The body might be either a single object, or an array of objects
+	var values []map[string]any
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		if err := unmarshal(body, &values); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		values = make([]map[string]any, 1)
+		if err := unmarshal(body, &values[0]); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	objects := make([]column.Object, 0, len(values))
+	for _, v := range values {
+		object, err := convertObject(c, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		objects = append(objects, object)
+	}
+
+	indices, err := c.InsertMany(objects)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"indexes": indices,
+	})
+}
+
+// insertArrow inserts the record batches of an Arrow stream, column by column
+func (s *Server) insertArrow(w http.ResponseWriter, r *http.Request, c *column.Collection) {
+	indices, err := c.InsertArrowStream(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"indexes": indices,
+	})
+}
+
+// query queries the collection with the filter and writes the matching rows
+func (s *Server) query(w http.ResponseWriter, r *http.Request, c *column.Collection, name string) {
+	var q Query
+	if err := decode(r, &q); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Stream the rows as Arrow record batches, if the client accepts them
+	if strings.Contains(r.Header.Get("Accept"), arrowStream) {
+		out := &lazyWriter{w: w, contentType: arrowStream}
+		if err := q.stream(out, c); err != nil && !out.started {
+			writeError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	rows, count, err := q.execute(c, s.fieldsOf(name))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count": count,
+		"rows":  rows,
+	})
+}
+
+// compute registers a computed field of the collection, replacing the existing one
+func (s *Server) compute(w http.ResponseWriter, r *http.Request, c *column.Collection, collection, name string) {
+	var body struct {
+		Expression string `json:"expression"`
+	}
+	if err := decode(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := c.KindOf(name); ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("column: computed field '%s' conflicts with a column", name))
+		return
+	}
+
+	field, err := parseField(c, name, body.Expression)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Copy the fields on write, since the queries in progress may read them
+	s.lock.Lock()
+	fields := make(computed, len(s.fields[collection])+1)
+	for k, v := range s.fields[collection] {
+		fields[k] = v
+	}
+	fields[name] = field
+	s.fields[collection] = fields
+	s.lock.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":       name,
+		"expression": field.String(),
+	})
+}
+
+// uncompute removes a computed field of the collection
+func (s *Server) uncompute(w http.ResponseWriter, collection, name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.fields[collection][name]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("column: computed field '%s' does not exist", name))
+		return
+	}
+
+	fields := make(computed, len(s.fields[collection]))
+	for k, v := range s.fields[collection] {
+		if k != name {
+			fields[k] = v
+		}
+	}
+	s.fields[collection] = fields
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fieldsOf returns the computed fields of the collection
+func (s *Server) fieldsOf(collection string) computed {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.fields[collection]
+}
+
+// readRow reads the values of the specified columns and computed fields of the row
+func readRow(v column.Selector, columns []string, fields computed) *Row {
+	row := &Row{
+		Index:  v.Index(),
+		Values: make(map[string]any, len(columns)+len(fields)),
+	}
+
+	for _, name := range columns {
+		if value, ok := v.Any(name); ok {
+			row.Values[name] = value
+		}
+	}
+
+	for name, field := range fields {
+		if value := field.Compute(v); value != nil {
+			row.Values[name] = value
+		}
+	}
+	return row
+}
+
+// --------------------------- Encoding ----------------------------
+
+// decode decodes the JSON body of the request, preserving the numbers as json.Number
+func decode(r *http.Request, dst any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("column: invalid request body, %w", err)
+	}
+	return nil
+}
+
+// unmarshal decodes the JSON value, preserving the numbers as json.Number
+func unmarshal(data []byte, dst any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("column: invalid request body, %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes the value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// arrowStream is the media type of the Arrow streaming format
+const arrowStream = "application/vnd.apache.arrow.stream"
+
+// lazyWriter represents a response writer which only writes the headers once the body is
+// written, so that an error can still be written as a JSON response until then
+type lazyWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	started     bool
+}
+
+// Write writes the headers, if not written yet, followed by the data
+func (w *lazyWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.w.Header().Set("Content-Type", w.contentType)
+		w.w.WriteHeader(http.StatusOK)
+	}
+	return w.w.Write(data)
+}
+
+// writeError writes the error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
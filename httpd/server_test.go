@@ -0,0 +1,383 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package httpd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	server := New(sql.Tables{"players": loadPlayers()})
+
+	// Insert a single object and an array of objects
+	status, body := call(server, "POST", "/players/rows", `{"name": "Merlin", "age": 120, "score": 9.5, "active": true}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"indexes":[0]}`, body)
+
+	status, body = call(server, "POST", "/players/rows", `[{"name": "Arthur", "age": 30}, {"name": "Roman", "age": 18}]`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"indexes":[1,2]}`, body)
+
+	// Read by index and by key
+	status, body = call(server, "GET", "/players/rows/0", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":0,"values":{"active":true,"age":120,"name":"Merlin","score":9.5}}`, body)
+
+	status, body = call(server, "GET", "/users/keys/roman", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Update by index
+	status, body = call(server, "PATCH", "/players/rows/1", `{"age": 31, "active": true}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":1,"values":{"active":true,"age":31,"name":"Arthur"}}`, body)
+
+	// Query
+	status, body = call(server, "POST", "/players/query", `{
+		"where": [{"column": "active"}, {"column": "age", "op": "<", "value": 100}],
+		"columns": ["name"]
+	}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"count":1,"rows":[{"index":1,"values":{"name":"Arthur"}}]}`, body)
+}
+
+func TestServerKeys(t *testing.T) {
+	users := column.NewCollection()
+	users.CreateColumn("id", column.ForKey())
+	users.CreateColumn("email", column.ForString())
+	server := New(sql.Tables{"users": users})
+
+	status, _ := call(server, "POST", "/users/rows", `{"id": "roman", "email": "a@b.c"}`)
+	assert.Equal(t, http.StatusCreated, status)
+
+	status, body := call(server, "GET", "/users/keys/roman", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":0,"values":{"email":"a@b.c","id":"roman"}}`, body)
+
+	status, body = call(server, "PATCH", "/users/keys/roman", `{"email": "x@y.z"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":0,"values":{"email":"x@y.z","id":"roman"}}`, body)
+
+	status, _ = call(server, "GET", "/users/keys/merlin", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestServerQuery(t *testing.T) {
+	players := loadPlayers()
+	players.InsertMany([]column.Object{
+		{"name": "Merlin", "class": "mage", "age": 120, "active": true},
+		{"name": "Morgana", "class": "mage", "age": 25, "active": false},
+		{"name": "Roman", "class": "rogue", "age": 18, "active": false},
+	})
+
+	server := New(sql.Tables{"players": players})
+	tests := []struct {
+		filter string
+		expect []string
+	}{
+		{`{"where": [{"column": "class", "op": "=", "value": "mage"}]}`, []string{"Merlin", "Morgana"}},
+		{`{"where": [{"column": "class", "op": "!=", "value": "mage"}]}`, []string{"Roman"}},
+		{`{"where": [{"column": "class", "op": "in", "value": ["rogue", "mage"]}], "limit": 1}`, []string{"Merlin"}},
+		{`{"where": [{"column": "age", "op": "in", "value": [18, 25]}]}`, []string{"Morgana", "Roman"}},
+		{`{"where": [{"column": "active", "op": "=", "value": false}]}`, []string{"Morgana", "Roman"}},
+		{`{"where": [{"column": "active", "op": "!=", "value": false}]}`, []string{"Merlin"}},
+		{`{"where": [{"column": "active", "op": "not"}, {"column": "age", "op": ">=", "value": 25}]}`, []string{"Morgana"}},
+		{`{"where": [{"column": "age", "op": "<=", "value": 18}]}`, []string{"Roman"}},
+		{`{"where": [{"column": "age", "op": "=", "value": 120}]}`, []string{"Merlin"}},
+		{`{"where": [{"column": "age", "op": "!=", "value": 120}, {"column": "name", "op": ">", "value": "N"}]}`, []string{"Roman"}},
+	}
+
+	for _, tc := range tests {
+		status, body := call(server, "POST", "/players/query", tc.filter)
+		assert.Equal(t, http.StatusOK, status, tc.filter)
+
+		var out struct {
+			Rows []Row `json:"rows"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(body), &out))
+
+		var names []string
+		for _, row := range out.Rows {
+			names = append(names, row.Values["name"].(string))
+		}
+		assert.Equal(t, tc.expect, names, tc.filter)
+	}
+}
+
+func TestServerQueryArrow(t *testing.T) {
+	players := loadPlayers()
+	players.InsertMany([]column.Object{
+		{"name": "Merlin", "class": "mage", "age": 120, "active": true},
+		{"name": "Roman", "class": "rogue", "age": 18, "active": false},
+	})
+
+	query := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/players/query", strings.NewReader(body))
+		r.Header.Set("Accept", "application/vnd.apache.arrow.stream")
+		New(sql.Tables{"players": players}).ServeHTTP(w, r)
+		return w
+	}
+
+	// The response is the same stream as written by the transaction
+	var expect bytes.Buffer
+	players.Query(func(txn *column.Txn) error {
+		return txn.WithString("class", func(v string) bool {
+			return v == "mage"
+		}).WriteArrowStream(&expect, "name", "age")
+	})
+
+	w := query(`{"where": [{"column": "class", "op": "=", "value": "mage"}], "columns": ["name", "age"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.apache.arrow.stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, expect.Bytes(), w.Body.Bytes())
+
+	// The errors are reported until the stream starts
+	for _, body := range []string{
+		`{"columns": ["missing"]}`,
+		`{"where": [{"column": "class", "op": "=", "value": "mage"}], "limit": 1}`,
+		`{"computed": {"old": "age > 100"}}`,
+	} {
+		w := query(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "error", body)
+	}
+}
+
+func TestServerInsertArrow(t *testing.T) {
+	source := loadPlayers()
+	source.InsertMany([]column.Object{
+		{"name": "Merlin", "class": "mage", "age": 120, "active": true},
+		{"name": "Roman", "class": "rogue", "age": 18, "active": false},
+	})
+
+	var stream bytes.Buffer
+	source.Query(func(txn *column.Txn) error {
+		return txn.WriteArrowStream(&stream, "name", "age", "active")
+	})
+
+	insert := func(body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/players/rows", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/vnd.apache.arrow.stream")
+		New(sql.Tables{"players": loadPlayers()}).ServeHTTP(w, r)
+		return w
+	}
+
+	w := insert(stream.Bytes())
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"indexes":[0,1]}`, strings.TrimSpace(w.Body.String()))
+
+	w = insert(stream.Bytes()[:20])
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServerErrors(t *testing.T) {
+	server := New(sql.Tables{"players": loadPlayers()})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/players/rows", strings.NewReader(`{"name": "Merlin"}`)))
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/", "", http.StatusNotFound},
+		{"GET", "/players", "", http.StatusNotFound},
+		{"GET", "/invalid/rows/0", "", http.StatusNotFound},
+		{"GET", "/players/rows/1", "", http.StatusNotFound},
+		{"GET", "/players/rows/x", "", http.StatusBadRequest},
+		{"GET", "/players/keys/x", "", http.StatusBadRequest},
+		{"DELETE", "/players/rows/0", "", http.StatusMethodNotAllowed},
+		{"PATCH", "/players/rows/0", `{`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"invalid": 1}`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"age": "x"}`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"age": 1.5}`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"name": 1}`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"active": 1}`, http.StatusBadRequest},
+		{"PATCH", "/players/rows/0", `{"score": [1]}`, http.StatusBadRequest},
+		{"POST", "/players/rows", `[{"invalid": 1}]`, http.StatusBadRequest},
+		{"POST", "/players/rows", `[1]`, http.StatusBadRequest},
+		{"POST", "/players/rows", `1`, http.StatusBadRequest},
+		{"POST", "/players/rows", ``, http.StatusBadRequest},
+		{"POST", "/players/query", `{`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"columns": ["invalid"]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "~"}]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "<", "value": true}]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "<", "value": null}]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "in", "value": 1}]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "in", "value": [1, "a"]}]}`, http.StatusBadRequest},
+		{"POST", "/players/query", `{"where": [{"column": "age", "op": "in", "value": [true]}]}`, http.StatusBadRequest},
+	} {
+		status, _ := call(server, tc.method, tc.path, tc.body)
+		assert.Equal(t, tc.status, status, tc.method+" "+tc.path+" "+tc.body)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		input  any
+		kind   reflect.Kind
+		expect any
+	}{
+		{json.Number("1"), reflect.Int, int(1)},
+		{json.Number("1"), reflect.Int16, int16(1)},
+		{json.Number("1"), reflect.Int32, int32(1)},
+		{json.Number("1"), reflect.Int64, int64(1)},
+		{json.Number("1"), reflect.Uint, uint(1)},
+		{json.Number("1"), reflect.Uint16, uint16(1)},
+		{json.Number("1"), reflect.Uint32, uint32(1)},
+		{json.Number("1"), reflect.Uint64, uint64(1)},
+		{json.Number("1.5"), reflect.Float32, float32(1.5)},
+		{json.Number("1.5"), reflect.Float64, float64(1.5)},
+		{json.Number("1.5"), reflect.Interface, float64(1.5)},
+		{"a", reflect.String, "a"},
+		{"a", reflect.Interface, "a"},
+		{true, reflect.Bool, true},
+		{[]any{"a"}, reflect.Interface, []any{"a"}},
+	} {
+		v, err := convert(tc.input, tc.kind)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expect, v)
+	}
+
+	_, err := convert(json.Number("70000"), reflect.Int16)
+	assert.Error(t, err)
+	_, err = convert(json.Number("1"), reflect.String)
+	assert.Error(t, err)
+}
+
+// call performs an HTTP request against the handler
+func call(handler http.Handler, method, path, body string) (int, string) {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+// loadPlayers creates an empty collection of players
+func loadPlayers() *column.Collection {
+	players := column.NewCollection()
+	players.CreateColumn("name", column.ForString())
+	players.CreateColumn("class", column.ForEnum())
+	players.CreateColumn("age", column.ForInt())
+	players.CreateColumn("active", column.ForBool())
+	players.CreateColumn("score", column.ForFloat64())
+	return players
+}
+
+func TestServerComputed(t *testing.T) {
+	players := loadPlayers()
+	players.InsertMany([]column.Object{
+		{"name": "Merlin", "class": "mage", "age": 120, "score": 9.5},
+		{"name": "Morgana", "class": "mage", "age": 25, "score": 4.5},
+		{"name": "Roman", "class": "rogue", "age": 18},
+	})
+	server := New(sql.Tables{"players": players})
+
+	// Register a computed field, which is then returned with the rows
+	status, body := call(server, "PUT", "/players/computed/double", `{"expression": "score * 2"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"expression":"score * 2","name":"double"}`, body)
+
+	status, body = call(server, "GET", "/players/rows/0", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":0,"values":{"age":120,"class":"mage","double":19,"name":"Merlin","score":9.5}}`, body)
+
+	// Filter with an expression and define a computed field in the query
+	status, body = call(server, "POST", "/players/query", `{
+		"filter": "class == 'mage' && age < 100 || name == 'Roman'",
+		"computed": {"label": "upper(name) + ' the ' + class"},
+		"columns": ["label", "double"]
+	}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"count":2,"rows":[{"index":1,"values":{"double":9,"label":"MORGANA the mage"}},{"index":2,"values":{"label":"ROMAN the rogue"}}]}`, body)
+
+	// Remove the computed field
+	status, _ = call(server, "DELETE", "/players/computed/double", "")
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = call(server, "DELETE", "/players/computed/double", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = call(server, "GET", "/players/rows/2", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"index":2,"values":{"age":18,"class":"rogue","name":"Roman"}}`, body)
+
+	// Invalid computed fields and filters
+	for _, tc := range []struct{ method, path, body string }{
+		{"PUT", "/players/computed/age", `{"expression": "1"}`},
+		{"PUT", "/players/computed/x", `{"expression": "missing * 2"}`},
+		{"PUT", "/players/computed/x", `{"expression": "score *"}`},
+		{"POST", "/players/query", `{"filter": "age >"}`},
+		{"POST", "/players/query", `{"computed": {"x": "missing"}}`},
+	} {
+		status, _ := call(server, tc.method, tc.path, tc.body)
+		assert.Equal(t, http.StatusBadRequest, status, tc.body)
+	}
+}
+
+func TestServerOpenAPI(t *testing.T) {
+	users := column.NewCollection()
+	users.CreateColumn("id", column.ForKey())
+	users.CreateColumn("email", column.ForString())
+	server := New(sql.Tables{"players": loadPlayers(), "users": users})
+
+	status, _ := call(server, "PUT", "/players/computed/rank", `{"expression": "score * 10"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Describe all of the collections
+	status, body := call(server, "GET", "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	var doc struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/players/rows")
+	assert.Contains(t, doc.Paths, "/players/query")
+	assert.Contains(t, doc.Paths["/players/rows/{index}"], "patch")
+	assert.Contains(t, doc.Paths, "/users/keys/{key}")
+	assert.NotContains(t, doc.Paths, "/players/keys/{key}")
+
+	// The schema of the objects follows the columns
+	object := doc.Components.Schemas["players.Object"].Properties
+	assert.Equal(t, "string", object["name"]["type"])
+	assert.Equal(t, "int64", object["age"]["format"])
+	assert.Equal(t, "boolean", object["active"]["type"])
+	assert.Equal(t, "double", object["score"]["format"])
+	assert.NotContains(t, object, "rank")
+
+	// The rows also contain the computed fields
+	values := doc.Components.Schemas["players.Row"].Properties["values"]["properties"]
+	assert.Contains(t, values, "rank")
+
+	// Describe a single collection
+	status, body = call(server, "GET", "/users/openapi.json", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"/users/rows"`)
+	assert.NotContains(t, body, `"/players/rows"`)
+
+	// Listing requires a source which is able to list the collections
+	status, _ = call(New(source{}), "GET", "/openapi.json", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+// source represents a source which is not able to list its collections
+type source struct{}
+
+func (source) Collection(string) (*column.Collection, bool) {
+	return nil, false
+}
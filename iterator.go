@@ -0,0 +1,134 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"math/bits"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// Iterator represents a resumable cursor over the rows selected by a transaction which have
+// a value in a column, in the order of their offsets. Since its position is just an offset,
+// it can be saved once a page of rows is read and restored later by seeking an iterator of
+// another transaction, so that a large result set can be streamed in pages without holding a
+// transaction open in between.
+//
+// While the iterator is positioned at a row, the chunk of the row is read-locked, so that
+// the values of the row can be read with the accessors of the transaction. The lock is
+// released once the iterator is exhausted or closed, and at the latest when the transaction
+// commits.
+type Iterator struct {
+	txn    *Txn    // The transaction which selected the rows
+	column *column // The column whose values are iterated over
+	next   uint32  // The offset from which the next row is searched
+	locked bool    // Whether the chunk of the current row is read-locked
+	chunk  commit.Chunk
+}
+
+// Iterator creates a new iterator over the rows currently selected by the transaction which
+// have a value in the specified column. If the column does not exist, the iterator is empty.
+func (txn *Txn) Iterator(columnName string) *Iterator {
+	txn.resolve()
+	column, _ := txn.columnAt(columnName)
+	it := &Iterator{
+		txn:    txn,
+		column: column,
+	}
+
+	txn.iters = append(txn.iters, it)
+	return it
+}
+
+// Next moves the iterator to the next row and returns whether there is one. The cursor of
+// the transaction is set to the row, so that its values can be read or updated with the
+// accessors of the transaction. If the context of the transaction is cancelled, the
+// iteration stops.
+func (it *Iterator) Next() bool {
+	txn := it.txn
+	if it.column == nil || txn.ctx.Err() != nil {
+		it.Close()
+		return false
+	}
+
+	for limit := uint32(len(txn.index)) << 6; it.next < limit; {
+		chunk := commit.ChunkAt(it.next)
+		it.lock(chunk)
+
+		// Find the next row which is both selected and has a value in the column
+		index, fill := chunk.OfBitmap(txn.index), it.column.Index(chunk)
+		if x, ok := nextOf(index, fill, it.next-chunk.Min()); ok {
+			txn.cursor = chunk.Min() + x
+			it.next = txn.cursor + 1
+			return true
+		}
+
+		it.next = chunk.Max() + 1
+	}
+
+	it.Close()
+	return false
+}
+
+// Index returns the offset of the row the iterator is positioned at
+func (it *Iterator) Index() uint32 {
+	return it.txn.cursor
+}
+
+// Position returns the position of the iterator, which is the offset from which the next
+// row will be searched. It can be saved and later restored with Seek().
+func (it *Iterator) Position() uint32 {
+	return it.next
+}
+
+// Seek moves the iterator so that the next call to Next() returns the first row at or after
+// the specified offset. This can be used to restore a previously saved position.
+func (it *Iterator) Seek(idx uint32) {
+	it.next = idx
+}
+
+// Close releases the read lock held by the iterator, if any. The iterator can be used again
+// after it was closed, in which case it continues from its position.
+func (it *Iterator) Close() {
+	if it.locked {
+		it.txn.readUnlock(it.chunk, true)
+		it.locked = false
+	}
+}
+
+// lock read-locks the specified chunk, releasing the lock of the previous one if different
+func (it *Iterator) lock(chunk commit.Chunk) {
+	if it.locked && it.chunk == chunk {
+		return
+	}
+
+	it.Close()
+	it.chunk, it.locked = chunk, it.txn.readLock(chunk)
+}
+
+// closeIterators releases the read locks held by the iterators of the transaction, which is
+// necessary before the chunks can be locked for writing.
+func (txn *Txn) closeIterators() {
+	for _, it := range txn.iters {
+		it.Close()
+	}
+	txn.iters = txn.iters[:0]
+}
+
+// nextOf returns the first offset at or after the specified one which is contained in both
+// of the bitmaps.
+func nextOf(a, b bitmap.Bitmap, from uint32) (uint32, bool) {
+	for i := int(from >> 6); i < len(a) && i < len(b); i++ {
+		word := a[i] & b[i]
+		if i == int(from>>6) {
+			word &= ^uint64(0) << (from & 63)
+		}
+
+		if word != 0 {
+			return uint32(i<<6 + bits.TrailingZeros64(word)), true
+		}
+	}
+	return 0, false
+}
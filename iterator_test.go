@@ -0,0 +1,109 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+	for i := 0; i < 3*chunkSize; i++ {
+		object := Object{"name": fmt.Sprintf("user%d", i)}
+		if i%3 == 0 {
+			object["age"] = i
+		}
+		coll.InsertObject(object)
+	}
+
+	// Stream the rows with an age in pages, each one in a separate transaction
+	var ages []int
+	var position uint32
+	for done := false; !done; {
+		assert.NoError(t, coll.Query(func(txn *Txn) error {
+			it := txn.WithInt("age", func(v int64) bool {
+				return v%2 == 0
+			}).Iterator("age")
+			defer it.Close()
+
+			it.Seek(position)
+			age := txn.Int("age")
+			for page := 0; page < 1000; page++ {
+				if done = !it.Next(); done {
+					return nil
+				}
+
+				v, _ := age.Get()
+				assert.Equal(t, int(it.Index()), v)
+				ages = append(ages, v)
+			}
+
+			position = it.Position()
+			return nil
+		}))
+	}
+
+	assert.Len(t, ages, chunkSize/2)
+	for i, v := range ages {
+		assert.Equal(t, i*6, v)
+	}
+}
+
+func TestIteratorUpdate(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("balance", ForFloat64())
+	for i := 0; i < 100; i++ {
+		coll.InsertObject(Object{"balance": 10.0})
+	}
+
+	// The iterator does not need to be closed before the transaction commits
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		it, balance := txn.Iterator("balance"), txn.Float64("balance")
+		for it.Next() && it.Index() < 50 {
+			balance.Add(5)
+		}
+		return nil
+	}))
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 1250.0, txn.Float64("balance").Sum())
+		return nil
+	}))
+}
+
+func TestIteratorEmpty(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.InsertObject(Object{"name": "Roman"})
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.False(t, txn.Iterator("missing").Next())
+		assert.False(t, txn.With("name").WithValue("name", func(v interface{}) bool {
+			return false
+		}).Iterator("name").Next())
+		return nil
+	}))
+}
+
+func TestIteratorCancel(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.InsertObject(Object{"name": "Roman"})
+	coll.InsertObject(Object{"name": "Merlin"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, context.Canceled, coll.QueryContext(ctx, func(txn *Txn) error {
+		it := txn.Iterator("name")
+		assert.True(t, it.Next())
+		cancel()
+		assert.False(t, it.Next())
+		return nil
+	}))
+}
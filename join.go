@@ -0,0 +1,127 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kelindar/column/commit"
+)
+
+// joinPair represents the offsets of a pair of rows with equal values
+type joinPair struct {
+	left, right uint32
+}
+
+// Join performs an inner equality join between the rows selected by this transaction and the
+// rows of the other collection, matching the values of the left column of this collection
+// with the values of the right column of the other one. The callback is invoked for every
+// pair of matching rows, in the order of the rows of this transaction, and the iteration stops
+// early if it returns false. The selector of the left row can update or delete it within this
+// transaction, while the selector of the right row is read-only.
+//
+// This is a hash join: the values of the other collection are hashed first, so the smaller
+// of the two collections should preferably be the other one. The values are compared as with
+// WithEqual(), so the numbers of different types are equal if they have the same value, and
+// the rows which do not have a value are skipped.
+func (txn *Txn) Join(other *Collection, leftColumn, rightColumn string, fn func(left, right Selector) bool) error {
+	table, err := other.hashBy(rightColumn)
+	if err != nil {
+		return err
+	}
+
+	// Probe the table with the values of the rows selected by this transaction
+	column, ok := txn.columnAt(leftColumn)
+	if !ok {
+		return fmt.Errorf("column: unable to join, column '%s' does not exist", leftColumn)
+	}
+
+	var pairs []joinPair
+	if err := txn.Range(func(idx uint32) {
+		if v, ok := column.Value(idx); ok && reflect.TypeOf(hashKey(v)).Comparable() {
+			for _, match := range table[hashKey(v)] {
+				pairs = append(pairs, joinPair{left: idx, right: match})
+			}
+		}
+	}); err != nil {
+		return err
+	}
+
+	reader := other.txns.acquire(other)
+	defer other.txns.release(reader)
+	for _, pair := range pairs {
+		if !readPair(txn, reader, pair, fn) {
+			return nil
+		}
+	}
+	return txn.ctx.Err()
+}
+
+// hashBy builds a hash table of the offsets of the rows, by the value of the specified column
+func (c *Collection) hashBy(columnName string) (table map[any][]uint32, err error) {
+	err = c.View(func(txn *Txn) error {
+		column, ok := txn.columnAt(columnName)
+		if !ok {
+			return fmt.Errorf("column: unable to join, column '%s' does not exist", columnName)
+		}
+
+		var invalid error
+		table = make(map[any][]uint32)
+		if err := txn.Range(func(idx uint32) {
+			v, ok := column.Value(idx)
+			if !ok {
+				return
+			}
+
+			switch key := hashKey(v); {
+			case invalid != nil:
+			case !reflect.TypeOf(key).Comparable():
+				invalid = fmt.Errorf("column: unable to join on column '%s', %T is not comparable", columnName, v)
+			default:
+				table[key] = append(table[key], idx)
+			}
+		}); err != nil {
+			return err
+		}
+		return invalid
+	})
+	return
+}
+
+// readPair invokes the callback for a pair of rows while holding the read locks of both of
+// their chunks. It returns the result of the callback, or true if either row no longer exists.
+// For a join of a collection with itself, the shards are locked in ascending order and only
+// once if both rows belong to the same shard, since the read locks can not be recursive.
+func readPair(left, right *Txn, pair joinPair, fn func(left, right Selector) bool) bool {
+	if !left.owner.Exists(pair.left) || !right.owner.Exists(pair.right) {
+		return true
+	}
+
+	a, b := uint(commit.ChunkAt(pair.left)), uint(commit.ChunkAt(pair.right))
+	la, lb := left.owner.slock, right.owner.slock
+	switch {
+	case la != lb:
+		la.RLock(a)
+		lb.RLock(b)
+		defer la.RUnlock(a)
+		defer lb.RUnlock(b)
+	case a%128 == b%128:
+		la.RLock(a)
+		defer la.RUnlock(a)
+	case a%128 < b%128:
+		la.RLock(a)
+		la.RLock(b)
+		defer la.RUnlock(a)
+		defer la.RUnlock(b)
+	default:
+		la.RLock(b)
+		la.RLock(a)
+		defer la.RUnlock(b)
+		defer la.RUnlock(a)
+	}
+
+	left.cursor, right.cursor = pair.left, pair.right
+	return fn(Selector{row: Row{left}, writable: true}, Selector{row: Row{right}})
+}
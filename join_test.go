@@ -0,0 +1,141 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	customers := NewCollection()
+	customers.CreateColumn("id", ForKey())
+	customers.CreateColumn("name", ForString())
+	customers.CreateColumn("tier", ForEnum())
+	customers.InsertObject(Object{"id": "c1", "name": "Alice", "tier": "gold"})
+	customers.InsertObject(Object{"id": "c2", "name": "Bob", "tier": "silver"})
+	customers.InsertObject(Object{"id": "c3", "name": "Carol", "tier": "gold"})
+
+	orders := NewCollection()
+	orders.CreateColumn("customer", ForEnum())
+	orders.CreateColumn("amount", ForInt())
+	orders.CreateColumn("tier", ForEnum())
+	for i, customer := range []string{"c1", "c2", "c1", "c4", "c3", "c1"} {
+		orders.InsertObject(Object{"customer": customer, "amount": (i + 1) * 10})
+	}
+	orders.InsertObject(Object{"amount": 70})
+
+	// Each order matches its customer, and the orders of unknown customers are skipped
+	var joined []string
+	assert.NoError(t, orders.Query(func(txn *Txn) error {
+		return txn.Join(customers, "customer", "id", func(order, customer Selector) bool {
+			amount, _ := order.Int("amount")
+			name, _ := customer.String("name")
+			joined = append(joined, fmt.Sprintf("%s:%d", name, amount))
+			return true
+		})
+	}))
+	assert.Equal(t, []string{"Alice:10", "Bob:20", "Alice:30", "Carol:50", "Alice:60"}, joined)
+
+	// Only the rows selected by the transaction are joined, and the left rows can be updated
+	assert.NoError(t, orders.Query(func(txn *Txn) error {
+		return txn.WithInt("amount", func(v int64) bool {
+			return v >= 30
+		}).Join(customers, "customer", "id", func(order, customer Selector) bool {
+			tier, _ := customer.Enum("tier")
+			assert.NoError(t, order.Update(func(r Row) {
+				r.SetEnum("tier", tier)
+			}))
+			assert.Error(t, customer.Update(func(r Row) {}))
+			return true
+		})
+	}))
+
+	orders.Query(func(txn *Txn) error {
+		assert.Equal(t, 3, txn.WithValue("tier", func(v interface{}) bool {
+			return v == "gold"
+		}).Count())
+		return nil
+	})
+
+	// The iteration stops once the callback returns false
+	count := 0
+	assert.NoError(t, orders.Query(func(txn *Txn) error {
+		return txn.Join(customers, "customer", "id", func(_, _ Selector) bool {
+			count++
+			return count < 2
+		})
+	}))
+	assert.Equal(t, 2, count)
+}
+
+func TestJoinNumbers(t *testing.T) {
+	left := NewCollection()
+	left.CreateColumn("ref", ForInt16())
+	right := NewCollection()
+	right.CreateColumn("id", ForInt64())
+	for i := 0; i < 10; i++ {
+		left.InsertObject(Object{"ref": i % 3})
+		right.InsertObject(Object{"id": i})
+	}
+
+	// The numbers of different types are equal if they have the same value
+	count := 0
+	assert.NoError(t, left.Query(func(txn *Txn) error {
+		return txn.Join(right, "ref", "id", func(l, r Selector) bool {
+			ref, _ := l.Int16("ref")
+			id, _ := r.Int64("id")
+			assert.Equal(t, int64(ref), id)
+			count++
+			return true
+		})
+	}))
+	assert.Equal(t, 10, count)
+}
+
+func TestJoinSelf(t *testing.T) {
+	employees := NewCollection()
+	employees.CreateColumn("name", ForString())
+	employees.CreateColumn("manager", ForString())
+	for i := 0; i < 40000; i++ {
+		object := Object{"name": fmt.Sprintf("e%d", i)}
+		if i > 0 {
+			object["manager"] = fmt.Sprintf("e%d", i/2)
+		}
+		employees.InsertObject(object)
+	}
+
+	// Each employee but the first one has a manager, possibly in another chunk
+	count := 0
+	assert.NoError(t, employees.Query(func(txn *Txn) error {
+		return txn.Join(employees, "manager", "name", func(employee, manager Selector) bool {
+			assert.Equal(t, employee.Index()/2, manager.Index())
+			count++
+			return true
+		})
+	}))
+	assert.Equal(t, 39999, count)
+}
+
+func TestJoinInvalid(t *testing.T) {
+	left := NewCollection()
+	left.CreateColumn("ref", ForString())
+	left.InsertObject(Object{"ref": "a"})
+	right := NewCollection()
+	right.CreateColumn("id", ForString())
+	right.CreateColumn("payload", ForBytes())
+	right.CreateColumn("meta", ForJSON())
+	right.InsertObject(Object{"id": "a", "payload": []byte("a"), "meta": `{"a":1}`})
+
+	noop := func(_, _ Selector) bool { return true }
+	assert.NoError(t, left.Query(func(txn *Txn) error {
+		assert.Error(t, txn.Join(right, "invalid", "id", noop))
+		assert.Error(t, txn.Join(right, "ref", "invalid", noop))
+		assert.Error(t, txn.Join(right, "ref", "meta", noop))
+		assert.NoError(t, txn.Join(right, "ref", "payload", noop))
+		return nil
+	}))
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kelindar/column/commit"
+)
+
+// maxJournalOps is the maximum number of pending operations listed in a trace
+const maxJournalOps = 1000
+
+// Journal enables the journaling of the transaction, which records the filters applied on it
+// into a human-readable trace, available from Trace() along with the updates, inserts and
+// deletes which are pending. If the query fails, the trace is also attached to its error. This
+// is meant for debugging unexpected commit results, as it slows down the transaction.
+func (txn *Txn) Journal() *Txn {
+	if txn.journal == nil {
+		txn.journal = make([]string, 0, 16)
+	}
+	return txn
+}
+
+// Trace returns the human-readable trace of the transaction if the journaling is enabled, with
+// one line per filter applied so far, followed by one line per pending operation.
+func (txn *Txn) Trace() string {
+	if txn.journal == nil {
+		return ""
+	}
+
+	var out strings.Builder
+	for i, line := range txn.journal {
+		fmt.Fprintf(&out, "%d. %s\n", i+1, line)
+	}
+
+	// List the pending operations, which are only known at this point
+	count := len(txn.journal)
+	for _, buffer := range txn.updates {
+		kind, _ := txn.owner.KindOf(buffer.Column)
+		txn.reader.Seek(buffer)
+		for txn.reader.Next() {
+			if count++; count-len(txn.journal) <= maxJournalOps {
+				fmt.Fprintf(&out, "%d. %s\n", count, describeOp(buffer.Column, kind, txn.reader))
+			}
+		}
+	}
+
+	if skipped := count - len(txn.journal) - maxJournalOps; skipped > 0 {
+		fmt.Fprintf(&out, "... and %d more operations\n", skipped)
+	}
+	return out.String()
+}
+
+// note records a line into the journal, the caller is responsible to check whether the
+// journaling is enabled beforehand so that the arguments are not needlessly formatted
+func (txn *Txn) note(format string, args ...any) {
+	txn.journal = append(txn.journal, fmt.Sprintf(format, args...))
+}
+
+// journaled attaches the trace of the transaction to the error, if the journaling is enabled.
+// This must be called before the pending operations are rolled back.
+func (txn *Txn) journaled(err error) error {
+	if txn.journal == nil {
+		return err
+	}
+	return fmt.Errorf("%w, trace of the transaction:\n%s", err, txn.Trace())
+}
+
+// describeOp describes the current operation of the reader
+func describeOp(columnName string, kind reflect.Kind, r *commit.Reader) string {
+	if columnName == rowColumn {
+		switch r.Type {
+		case commit.Insert:
+			return fmt.Sprintf("Insert row %d", r.Index())
+		default:
+			return fmt.Sprintf("Delete row %d", r.Index())
+		}
+	}
+
+	switch {
+	case r.Type == commit.Delete && kind != reflect.Bool:
+		return fmt.Sprintf("Delete %s[%d]", columnName, r.Index())
+	case r.Type == commit.Add:
+		return fmt.Sprintf("Add %s[%d] += %v", columnName, r.Index(), valueOf(kind, r))
+	case r.Type == commit.Saturate:
+		return fmt.Sprintf("AddSaturate %s[%d] += %v", columnName, r.Index(), valueOf(kind, r))
+	default:
+		return fmt.Sprintf("Put %s[%d] = %v", columnName, r.Index(), valueOf(kind, r))
+	}
+}
+
+// valueOf decodes the value of the current operation, given the kind of its column
+func valueOf(kind reflect.Kind, r *commit.Reader) any {
+	switch kind {
+	case reflect.Bool:
+		return r.Bool()
+	case reflect.String:
+		return fmt.Sprintf("%q", r.String())
+	case reflect.Int16:
+		return r.Int16()
+	case reflect.Int32:
+		return r.Int32()
+	case reflect.Int, reflect.Int64:
+		return r.Int64()
+	case reflect.Uint16:
+		return r.Uint16()
+	case reflect.Uint32:
+		return r.Uint32()
+	case reflect.Uint, reflect.Uint64:
+		return r.Uint64()
+	case reflect.Float32:
+		return r.Float32()
+	case reflect.Float64:
+		return r.Float64()
+	default:
+		return fmt.Sprintf("<%d bytes>", len(r.Bytes()))
+	}
+}
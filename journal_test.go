@@ -0,0 +1,95 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	players := newJournalCollection()
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		assert.Empty(t, txn.Trace())
+
+		txn.Journal().With("active").WithFloat("age", func(v float64) bool {
+			return v >= 30
+		}).Range(func(idx uint32) {
+			txn.Float64("age").Add(1)
+			txn.String("name").Set("Merlin")
+		})
+		txn.DeleteAt(0)
+		txn.Bool("active").Set(false)
+		txn.Insert(func(r Row) error {
+			r.SetFloat64("age", 18)
+			return nil
+		})
+
+		assert.Equal(t, ""+
+			"1. With(active): 4 -> 3 rows\n"+
+			"2. WithFloat(age): deferred\n"+
+			"3. Resolved 1 deferred filters: 3 rows\n"+
+			"4. Add age[0] += 1\n"+
+			"5. Add age[1] += 1\n"+
+			"6. Add age[2] += 1\n"+
+			"7. Put age[4] = 18\n"+
+			"8. Put name[0] = \"Merlin\"\n"+
+			"9. Put name[1] = \"Merlin\"\n"+
+			"10. Put name[2] = \"Merlin\"\n"+
+			"11. Delete row 0\n"+
+			"12. Insert row 4\n"+
+			"13. Put active[2] = false\n", txn.Trace())
+		return nil
+	}))
+}
+
+func TestJournalError(t *testing.T) {
+	players := newJournalCollection()
+	errFailed := errors.New("failed")
+	err := players.Query(func(txn *Txn) error {
+		txn.Journal().DeleteAt(3)
+		return errFailed
+	})
+
+	assert.True(t, errors.Is(err, errFailed))
+	assert.Equal(t, "failed, trace of the transaction:\n1. Delete row 3\n", err.Error())
+	assert.Equal(t, 4, players.Count())
+
+	// The journal is not enabled on the next transaction
+	err = players.Query(func(txn *Txn) error {
+		txn.DeleteAt(3)
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+}
+
+func TestJournalLimit(t *testing.T) {
+	players := newJournalCollection()
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		txn.Journal().Range(func(idx uint32) {
+			for i := 0; i < 400; i++ {
+				txn.Float64("age").Add(1)
+			}
+		})
+		assert.Contains(t, txn.Trace(), "\n1000. Add age[2] += 1\n... and 600 more operations\n")
+		return nil
+	}))
+}
+
+// newJournalCollection creates a small collection of players
+func newJournalCollection() *Collection {
+	players := NewCollection()
+	players.CreateColumn("name", ForString())
+	players.CreateColumn("age", ForFloat64())
+	players.CreateColumn("active", ForBool())
+	players.InsertMany([]Object{
+		{"name": "Roman", "age": 35.0, "active": true},
+		{"name": "Arthur", "age": 30.0, "active": true},
+		{"name": "Lancelot", "age": 40.0, "active": true},
+		{"name": "Morgana", "age": 25.0, "active": false},
+	})
+	return players
+}
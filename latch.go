@@ -0,0 +1,181 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/zeebo/xxh3"
+)
+
+// latchShards is the number of shards of the latches, the chunks being mapped onto the
+// shards in a round-robin fashion.
+const latchShards = 128
+
+// latches represents the sharded latches which protect the chunks of a collection. Each
+// latch is either held by any number of readers, by any number of writers of disjoint sets
+// of columns, or by a single exclusive writer. The commits which only update the values take
+// the latches for the columns they update, so that the commits of different columns of the
+// same chunk are applied concurrently, while the commits which insert or delete rows take
+// them exclusively, since these touch every column.
+type latches struct {
+	shards [latchShards]latch
+}
+
+// RLock acquires the latch of a chunk for reading.
+func (l *latches) RLock(chunk uint) {
+	l.shards[chunk%latchShards].rlock()
+}
+
+// RUnlock releases the read latch of a chunk.
+func (l *latches) RUnlock(chunk uint) {
+	l.shards[chunk%latchShards].runlock()
+}
+
+// Lock acquires the latch of a chunk exclusively.
+func (l *latches) Lock(chunk uint) {
+	l.shards[chunk%latchShards].lock(allColumns)
+}
+
+// Unlock releases the exclusive latch of a chunk.
+func (l *latches) Unlock(chunk uint) {
+	l.shards[chunk%latchShards].unlock(allColumns)
+}
+
+// LockColumns acquires the latch of a chunk for writing the specified set of columns.
+func (l *latches) LockColumns(chunk uint, columns uint64) {
+	l.shards[chunk%latchShards].lock(columns)
+}
+
+// UnlockColumns releases the latch of a chunk held for writing the specified set of columns.
+func (l *latches) UnlockColumns(chunk uint, columns uint64) {
+	l.shards[chunk%latchShards].unlock(columns)
+}
+
+// --------------------------- Latch ----------------------------
+
+// allColumns is the set of columns held by an exclusive writer
+const allColumns = ^uint64(0)
+
+// latch represents the lock of a shard of the chunks. The readers and the writers take turns
+// like in a fair read-write lock: once a writer waits, the new readers wait for it, and once
+// it is done the waiting readers proceed before the next writers, so that neither starve.
+type latch struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	turn    bool   // Whether the writers have the next turn
+	readers int    // The number of active readers
+	writers int    // The number of active writers
+	columns uint64 // The set of columns held by the active writers
+	waiting [2]int // The number of waiting readers and writers
+	_       [32]byte
+}
+
+// rlock acquires the latch for reading
+func (l *latch) rlock() {
+	l.mu.Lock()
+	if l.cond.L == nil {
+		l.cond.L = &l.mu
+	}
+
+	for l.writers > 0 || (l.turn && l.waiting[1] > 0) {
+		if l.writers > 0 {
+			l.turn = false // Claim the next turn, so that the writers drain
+		}
+
+		l.waiting[0]++
+		l.cond.Wait()
+		l.waiting[0]--
+	}
+
+	l.readers++
+	l.mu.Unlock()
+}
+
+// runlock releases the latch held for reading
+func (l *latch) runlock() {
+	l.mu.Lock()
+	l.readers--
+	if l.readers == 0 && l.waiting[1] > 0 {
+		l.turn = true
+		l.cond.Broadcast()
+	}
+	l.mu.Unlock()
+}
+
+// lock acquires the latch for writing the set of columns
+func (l *latch) lock(columns uint64) {
+	l.mu.Lock()
+	if l.cond.L == nil {
+		l.cond.L = &l.mu
+	}
+
+	for l.readers > 0 || l.columns&columns != 0 || (!l.turn && l.waiting[0] > 0) {
+		if l.readers > 0 {
+			l.turn = true // Claim the next turn, so that the readers drain
+		}
+
+		l.waiting[1]++
+		l.cond.Wait()
+		l.waiting[1]--
+	}
+
+	l.writers++
+	l.columns |= columns
+	l.mu.Unlock()
+}
+
+// unlock releases the latch held for writing the set of columns
+func (l *latch) unlock(columns uint64) {
+	l.mu.Lock()
+	l.writers--
+	l.columns &^= columns
+	if l.writers == 0 && l.waiting[0] > 0 {
+		l.turn = false
+	}
+
+	if l.waiting[0] > 0 || l.waiting[1] > 0 {
+		l.cond.Broadcast()
+	}
+	l.mu.Unlock()
+}
+
+// --------------------------- Column Sets ----------------------------
+
+// latchOf returns the set of a single column, identified by the hash of its name. Distinct
+// columns may share the same bit, in which case their commits are simply serialized.
+func latchOf(columnName string) uint64 {
+	return 1 << (xxh3.HashString(columnName) % 64)
+}
+
+// latchColumns returns the set of columns the transaction writes to when it commits. If it
+// inserts or deletes any rows, every column is touched and the set contains all of them.
+func (txn *Txn) latchColumns(changedRows bool) (columns uint64) {
+	if changedRows {
+		return allColumns
+	}
+
+	for _, u := range txn.updates {
+		if !u.IsEmpty() {
+			columns |= latchOf(u.Column)
+		}
+	}
+
+	if columns == 0 {
+		return allColumns
+	}
+	return columns
+}
+
+// setCommit records the commit which last modified a chunk. Since the commits of different
+// columns may be applied to the same chunk concurrently, the commit ID only ever increases.
+func setCommit(dst *uint64, commitID uint64) {
+	for {
+		last := atomic.LoadUint64(dst)
+		if commitID <= last || atomic.CompareAndSwapUint64(dst, last, commitID) {
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatchColumns(t *testing.T) {
+	var l latches
+	a, b := uint64(1), uint64(2)
+
+	// The writers of disjoint columns do not wait for each other
+	l.LockColumns(0, a)
+	assert.True(t, finishes(func() { l.LockColumns(0, b) }))
+	l.UnlockColumns(0, b)
+
+	// The writers of the same column, the readers and the exclusive writers wait
+	for _, fn := range []func(){
+		func() { l.LockColumns(0, a|b); l.UnlockColumns(0, a|b) },
+		func() { l.RLock(0); l.RUnlock(0) },
+		func() { l.Lock(0); l.Unlock(0) },
+	} {
+		done := make(chan struct{})
+		go func(fn func()) {
+			fn()
+			close(done)
+		}(fn)
+
+		select {
+		case <-done:
+			assert.Fail(t, "expected to wait for the writer")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		l.UnlockColumns(0, a)
+		<-done
+		l.LockColumns(0, a)
+	}
+
+	// Other shards are not affected
+	assert.True(t, finishes(func() { l.Lock(1); l.Unlock(1) }))
+	l.UnlockColumns(0, a)
+}
+
+func TestLatchFairness(t *testing.T) {
+	var l latches
+	l.RLock(0)
+
+	// Once a writer waits, the new readers wait for it
+	var order []string
+	var lock sync.Mutex
+	record := func(s string) {
+		lock.Lock()
+		order = append(order, s)
+		lock.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l.Lock(0)
+		record("writer")
+		l.Unlock(0)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	go func() {
+		defer wg.Done()
+		l.RLock(0)
+		record("reader")
+		l.RUnlock(0)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	l.RUnlock(0)
+	wg.Wait()
+	assert.Equal(t, []string{"writer", "reader"}, order)
+}
+
+func TestCommitDisjointColumns(t *testing.T) {
+	position := &blockingColumn{
+		Column:  ForFloat64(),
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	coll := NewCollection()
+	coll.CreateColumn("position", position)
+	coll.CreateColumn("health", ForFloat64())
+	coll.InsertObject(Object{"health": 100.0})
+
+	// Start a commit of the position, which is held while being applied
+	position.block = true
+	write := func(columnName string, value float64) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			coll.Query(func(txn *Txn) error {
+				txn.bufferFor(columnName).PutFloat64(0, value)
+				return nil
+			})
+		}()
+		return done
+	}
+
+	first := write("position", 1)
+	<-position.entered
+
+	// The commit of another column of the same chunk proceeds, while the one of the same
+	// column waits for the first commit
+	assert.True(t, finishes(func() { <-write("health", 50) }))
+	second := write("position", 2)
+	select {
+	case <-second:
+		assert.Fail(t, "expected to wait for the first commit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	position.block = false
+	close(position.release)
+	<-first
+	<-second
+
+	assert.NoError(t, coll.QueryAt(0, func(r Row) error {
+		health, _ := r.Float64("health")
+		assert.Equal(t, 50.0, health)
+		value, _ := position.Value(0)
+		assert.Equal(t, 2.0, value)
+		return nil
+	}))
+}
+
+// blockingColumn represents a column whose updates are held until released
+type blockingColumn struct {
+	Column
+	block   bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+// Apply signals that the updates are being applied and waits until released
+func (c *blockingColumn) Apply(chunk commit.Chunk, r *commit.Reader) {
+	if c.block {
+		c.entered <- struct{}{}
+		<-c.release
+	}
+	c.Column.Apply(chunk, r)
+}
+
+// finishes returns whether the function returns within a second
+func finishes(fn func()) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	// ErrLocked is returned when a row can not be locked, or modified, because it is locked by
+	// a concurrent transaction.
+	ErrLocked = errors.New("column: row is locked by a concurrent transaction")
+)
+
+// rowLocks represents the rows locked by the transactions, until they commit or roll back
+type rowLocks struct {
+	count int32           // The number of rows currently locked
+	lock  sync.Mutex      // The mutex to guard the owners
+	owner map[uint32]*Txn // The transaction which holds the lock of each row
+}
+
+// Lock locks the row at the specified offset until the transaction commits or rolls back,
+// so that no concurrent transaction can modify it meanwhile. If the row is already locked by
+// a concurrent transaction, ErrLocked is returned immediately rather than waiting, so the
+// transaction can be retried. Locking a row twice within the same transaction is allowed.
+//
+// This allows a read-modify-write, such as updating the balance of an account, to be done
+// without any lost update. The commits of the transactions which modify a locked row are
+// rejected with ErrLocked, but a commit which was already in progress when the row was
+// locked is not, hence the writers of the row should lock it as well.
+func (txn *Txn) Lock(idx uint32) error {
+	locks := &txn.owner.locks
+	locks.lock.Lock()
+	defer locks.lock.Unlock()
+	return txn.lockRow(idx)
+}
+
+// LockAll locks all of the rows currently selected by the transaction, just like Lock(). If
+// any of the rows is locked by a concurrent transaction, none of them is locked by this call
+// and ErrLocked is returned.
+func (txn *Txn) LockAll() (err error) {
+	txn.resolve()
+	locks := &txn.owner.locks
+	locks.lock.Lock()
+	defer locks.lock.Unlock()
+
+	// Lock the rows one by one, and release the newly acquired ones on contention
+	start := len(txn.locked)
+	txn.index.Range(func(idx uint32) {
+		if err == nil {
+			err = txn.lockRow(idx)
+		}
+	})
+
+	if err != nil {
+		txn.unlockRows(txn.locked[start:])
+		txn.locked = txn.locked[:start]
+	}
+	return
+}
+
+// lockRow locks a single row. The caller must hold the mutex of the row locks.
+func (txn *Txn) lockRow(idx uint32) error {
+	locks := &txn.owner.locks
+	switch owner, ok := locks.owner[idx]; {
+	case !ok:
+		if locks.owner == nil {
+			locks.owner = make(map[uint32]*Txn, 16)
+		}
+
+		locks.owner[idx] = txn
+		txn.locked = append(txn.locked, idx)
+		atomic.AddInt32(&locks.count, 1)
+		return nil
+	case owner == txn:
+		return nil
+	default:
+		return ErrLocked
+	}
+}
+
+// unlockRows releases the locks of the specified rows. The caller must hold the mutex of
+// the row locks.
+func (txn *Txn) unlockRows(rows []uint32) {
+	locks := &txn.owner.locks
+	for _, idx := range rows {
+		delete(locks.owner, idx)
+	}
+	atomic.AddInt32(&locks.count, -int32(len(rows)))
+}
+
+// unlock releases all of the locks held by the transaction
+func (txn *Txn) unlock() {
+	if len(txn.locked) == 0 {
+		return
+	}
+
+	locks := &txn.owner.locks
+	locks.lock.Lock()
+	txn.unlockRows(txn.locked)
+	locks.lock.Unlock()
+	txn.locked = txn.locked[:0]
+}
+
+// checkLocks checks whether any of the rows modified by the transaction is locked by a
+// concurrent transaction, in which case ErrLocked is returned.
+func (txn *Txn) checkLocks() (err error) {
+	locks := &txn.owner.locks
+	if atomic.LoadInt32(&locks.count) == 0 {
+		return nil
+	}
+
+	locks.lock.Lock()
+	defer locks.lock.Unlock()
+	for _, u := range txn.updates {
+		u.RangeChunks(func(chunk commit.Chunk) {
+			txn.reader.Range(u, chunk, func(r *commit.Reader) {
+				for err == nil && r.Next() {
+					if owner, ok := locks.owner[r.Index()]; ok && owner != txn {
+						err = ErrLocked
+					}
+				}
+			})
+		})
+	}
+	return
+}
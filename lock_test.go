@@ -0,0 +1,161 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	accounts := newAccounts(2)
+	locked, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		accounts.Query(func(txn *Txn) error {
+			assert.NoError(t, txn.Lock(0))
+			assert.NoError(t, txn.Lock(0))
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	// Neither the lock can be acquired, nor the row modified
+	assert.Equal(t, ErrLocked, accounts.Query(func(txn *Txn) error {
+		return txn.Lock(0)
+	}))
+	assert.Equal(t, ErrLocked, accounts.QueryAt(0, func(r Row) error {
+		r.AddFloat64("balance", 10)
+		return nil
+	}))
+	assert.Equal(t, ErrLocked, accounts.Query(func(txn *Txn) error {
+		txn.DeleteAll()
+		return nil
+	}))
+
+	// The other rows are not locked
+	assert.NoError(t, accounts.QueryAt(1, func(r Row) error {
+		r.AddFloat64("balance", 10)
+		return nil
+	}))
+	assert.Equal(t, []float64{100, 110}, balancesOf(accounts))
+
+	// Once the transaction completes, the row is unlocked
+	close(release)
+	assert.Eventually(t, func() bool {
+		return accounts.Query(func(txn *Txn) error {
+			return txn.Lock(0)
+		}) == nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&accounts.locks.count))
+}
+
+func TestLockAll(t *testing.T) {
+	accounts := newAccounts(10)
+	locked, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		accounts.Query(func(txn *Txn) error {
+			assert.NoError(t, txn.Lock(5))
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	// None of the rows is locked if any of them is contended
+	assert.Equal(t, ErrLocked, accounts.Query(func(txn *Txn) error {
+		return txn.LockAll()
+	}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&accounts.locks.count))
+
+	assert.NoError(t, accounts.Query(func(txn *Txn) error {
+		return txn.WithValue("id", func(v interface{}) bool {
+			return v.(int) < 5
+		}).LockAll()
+	}))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return accounts.Query(func(txn *Txn) error {
+			return txn.LockAll()
+		}) == nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestLockTransfer(t *testing.T) {
+	accounts := newAccounts(2)
+
+	// Concurrently transfer money between the accounts, retrying on contention
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(from, to uint32) {
+			defer wg.Done()
+			for {
+				if err := accounts.Query(func(txn *Txn) error {
+					return transfer(txn, from, to, 1)
+				}); err != ErrLocked {
+					assert.NoError(t, err)
+					return
+				}
+			}
+		}(uint32(i%2), uint32(1-i%2))
+	}
+
+	wg.Wait()
+	assert.Equal(t, []float64{100, 100}, balancesOf(accounts))
+}
+
+// newAccounts creates a collection of accounts, each with a balance of 100
+func newAccounts(n int) *Collection {
+	accounts := NewCollection()
+	accounts.CreateColumn("id", ForInt())
+	accounts.CreateColumn("balance", ForFloat64())
+	for i := 0; i < n; i++ {
+		accounts.InsertObject(Object{"id": i, "balance": 100.0})
+	}
+	return accounts
+}
+
+// transfer moves an amount between two accounts with a read-modify-write
+func transfer(txn *Txn, from, to uint32, amount float64) error {
+	for _, idx := range []uint32{from, to} {
+		if err := txn.Lock(idx); err != nil {
+			return err
+		}
+	}
+
+	var balance [2]float64
+	for i, idx := range []uint32{from, to} {
+		txn.QueryAt(idx, func(r Row) error {
+			balance[i], _ = r.Float64("balance")
+			return nil
+		})
+	}
+
+	txn.QueryAt(from, func(r Row) error {
+		r.SetFloat64("balance", balance[0]-amount)
+		return nil
+	})
+	return txn.QueryAt(to, func(r Row) error {
+		r.SetFloat64("balance", balance[1]+amount)
+		return nil
+	})
+}
+
+// balancesOf returns the balances of the accounts, in the order of their offsets
+func balancesOf(accounts *Collection) (out []float64) {
+	accounts.Query(func(txn *Txn) error {
+		return txn.RangeFloat64("balance", func(idx uint32, v float64) {
+			out = append(out, v)
+		})
+	})
+	return
+}
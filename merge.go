@@ -0,0 +1,191 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/kelindar/column/commit"
+)
+
+// MergeSorted reads the rows of several collections in the global ascending order of a
+// common sort column, for example a timestamp shared by time-partitioned collections. The
+// optional filter is applied to every collection before reading, and the callback receives
+// the position of the source collection along with a selector for the row. The iteration
+// stops early if the callback returns false. The rows which do not have a value for the
+// sort column are skipped, and the ties are broken by the order of the collections.
+func MergeSorted(sortColumn string, sources []*Collection, filter func(txn *Txn), fn func(source int, v Selector) bool) error {
+	iter := make(mergeHeap, 0, len(sources))
+	for i, c := range sources {
+		rows, err := c.sortedBy(sortColumn, filter)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) > 0 {
+			iter = append(iter, &mergeCursor{source: i, rows: rows})
+		}
+	}
+
+	// Acquire a reader for each of the collections
+	readers := make([]*Txn, len(sources))
+	for i, c := range sources {
+		readers[i] = c.txns.acquire(c)
+		defer c.txns.release(readers[i])
+	}
+
+	// Perform the k-way merge of the sorted cursors
+	heap.Init(&iter)
+	for len(iter) > 0 {
+		cursor := iter[0]
+		if next := sources[cursor.source].readAt(readers[cursor.source], cursor.rows[0].idx, func(v Selector) bool {
+			return fn(cursor.source, v)
+		}); !next {
+			return nil
+		}
+
+		if cursor.rows = cursor.rows[1:]; len(cursor.rows) > 0 {
+			heap.Fix(&iter, 0)
+		} else {
+			heap.Pop(&iter)
+		}
+	}
+	return nil
+}
+
+// sortedBy returns the offsets of the rows which match the filter, sorted by the value of the
+// specified column.
+func (c *Collection) sortedBy(sortColumn string, filter func(txn *Txn)) (rows []sortedRow, err error) {
+	err = c.View(func(txn *Txn) error {
+		column, ok := txn.columnAt(sortColumn)
+		if !ok {
+			return fmt.Errorf("column: unable to merge, column '%s' does not exist", sortColumn)
+		}
+
+		if filter != nil {
+			filter(txn)
+		}
+
+		return txn.Range(func(idx uint32) {
+			if v, ok := column.Value(idx); ok {
+				rows = append(rows, sortedRow{idx: idx, key: hashKey(v)})
+			}
+		})
+	})
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessKey(rows[i].key, rows[j].key)
+	})
+	return
+}
+
+// readAt invokes the callback for the row at the specified offset while holding the read lock
+// of its chunk. It returns the result of the callback, or true if the row no longer exists.
+func (c *Collection) readAt(txn *Txn, idx uint32, fn func(Selector) bool) bool {
+	c.lock.RLock()
+	exists := c.fill.Contains(idx)
+	c.lock.RUnlock()
+	if !exists {
+		return true
+	}
+
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	defer c.slock.RUnlock(uint(chunk))
+	c.thaw(chunk)
+	txn.cursor = idx
+	return fn(Selector{row: Row{txn}})
+}
+
+// --------------------------- Merge Heap ----------------------------
+
+// sortedRow represents a row offset along with its sort key
+type sortedRow struct {
+	idx uint32
+	key any
+}
+
+// mergeCursor represents the remaining sorted rows of a single collection
+type mergeCursor struct {
+	source int
+	rows   []sortedRow
+}
+
+// mergeHeap represents a min-heap of cursors, ordered by the key of their current row
+type mergeHeap []*mergeCursor
+
+func (h mergeHeap) Len() int      { return len(h) }
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h mergeHeap) Less(i, j int) bool {
+	a, b := h[i].rows[0].key, h[j].rows[0].key
+	switch {
+	case lessKey(a, b):
+		return true
+	case lessKey(b, a):
+		return false
+	default:
+		return h[i].source < h[j].source
+	}
+}
+
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(*mergeCursor))
+}
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// lessKey compares two normalized keys. The keys of the same type are compared exactly,
+// while numbers of different types are compared as floating-point values.
+func lessKey(a, b any) bool {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return x < y
+		}
+	case uint64:
+		if y, ok := b.(uint64); ok {
+			return x < y
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x < y
+		}
+		return false
+	case bool:
+		if y, ok := b.(bool); ok {
+			return !x && y
+		}
+		return false
+	}
+
+	x, ok1 := floatOf(a)
+	y, ok2 := floatOf(b)
+	return ok1 && ok2 && x < y
+}
+
+// floatOf converts a normalized numeric key into a floating-point value
+func floatOf(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	default:
+		return 0, false
+	}
+}
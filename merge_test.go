@@ -0,0 +1,68 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSorted(t *testing.T) {
+	newPartition := func(times ...int64) *Collection {
+		coll := NewCollection()
+		coll.CreateColumn("time", ForInt64())
+		coll.CreateColumn("even", ForBool())
+		for _, v := range times {
+			coll.InsertObject(Object{"time": v, "even": v%2 == 0})
+		}
+		return coll
+	}
+
+	sources := []*Collection{
+		newPartition(5, 1, 9),
+		newPartition(2, 8, 4, 1),
+		newPartition(),
+		newPartition(7, 3, 6),
+	}
+
+	// Read everything in the global order
+	var times []int64
+	var from []int
+	assert.NoError(t, MergeSorted("time", sources, nil, func(source int, v Selector) bool {
+		time, _ := v.Int64("time")
+		times = append(times, time)
+		from = append(from, source)
+		return true
+	}))
+	assert.Equal(t, []int64{1, 1, 2, 3, 4, 5, 6, 7, 8, 9}, times)
+	assert.Equal(t, []int{0, 1, 1, 3, 1, 0, 3, 3, 1, 0}, from)
+
+	// Filter and stop early
+	times = times[:0]
+	assert.NoError(t, MergeSorted("time", sources, func(txn *Txn) {
+		txn.With("even")
+	}, func(source int, v Selector) bool {
+		time, _ := v.Int64("time")
+		times = append(times, time)
+		return len(times) < 3
+	}))
+	assert.Equal(t, []int64{2, 4, 6}, times)
+
+	// Missing sort column
+	assert.Error(t, MergeSorted("invalid", sources, nil, func(int, Selector) bool {
+		return true
+	}))
+}
+
+func TestLessKey(t *testing.T) {
+	assert.True(t, lessKey(int64(1), int64(2)))
+	assert.True(t, lessKey(uint64(1), uint64(2)))
+	assert.True(t, lessKey(1.5, 2.5))
+	assert.True(t, lessKey("a", "b"))
+	assert.True(t, lessKey(false, true))
+	assert.True(t, lessKey(int64(1), 1.5))
+	assert.False(t, lessKey(uint64(2), int64(1)))
+	assert.False(t, lessKey("a", int64(1)))
+}
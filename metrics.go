@@ -0,0 +1,62 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"time"
+
+	"github.com/kelindar/column/commit"
+)
+
+// Metrics represents a set of hooks which receive the instrumentation of a collection, for
+// example in order to export it into a monitoring system. The hooks are invoked synchronously
+// by the transactions, hence they must be cheap and safe for concurrent use.
+type Metrics interface {
+
+	// ObserveCommit is called for every committed transaction which modified the collection,
+	// with the number of rows it inserted and deleted.
+	ObserveCommit(inserts, deletes int)
+
+	// ObserveQuery is called once every transaction completes, with its duration, the number
+	// of rows it scanned and whether it was committed or rolled back.
+	ObserveQuery(duration time.Duration, scanned int, committed bool)
+
+	// ObserveMemory is called periodically for every column, with the approximate memory it
+	// uses, in bytes.
+	ObserveMemory(column string, bytes int)
+}
+
+// observeQuery reports the completion of a transaction, if the metrics are enabled
+func (c *Collection) observeQuery(txn *Txn, start time.Time, err error) {
+	if c.metrics != nil {
+		c.metrics.ObserveQuery(time.Since(start), txn.scanned, err == nil)
+	}
+}
+
+// observeMemory reports the approximate memory used by every column, if the metrics are enabled
+func (c *Collection) observeMemory() {
+	if c.metrics == nil {
+		return
+	}
+
+	c.lock.RLock()
+	chunks := len(c.commits)
+	c.lock.RUnlock()
+
+	// Compute the size of every column, chunk by chunk
+	sizes := make(map[string]int, 8)
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		c.slock.RLock(uint(chunk))
+		c.cold.lock.RLock() // The compressed chunks are measured as they are
+		c.cols.Range(func(column *column) {
+			sizes[column.name] += column.sizeOf(chunk)
+		})
+		c.cold.lock.RUnlock()
+		c.slock.RUnlock(uint(chunk))
+	}
+
+	c.cols.Range(func(column *column) {
+		c.metrics.ObserveMemory(column.name, sizes[column.name])
+	})
+}
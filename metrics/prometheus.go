@@ -0,0 +1,235 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package metrics provides adapters which export the instrumentation of the collections into
+// monitoring systems.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/column"
+)
+
+// Buckets represents the default upper bounds of the transaction duration histogram, in seconds
+var Buckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+
+// Prometheus represents a set of metrics of one or several collections, which are exposed in
+// the text format of Prometheus by serving them over HTTP, for example
+//
+//	metrics := metrics.NewPrometheus("column")
+//	players := column.NewCollection(column.Options{
+//		Metrics: metrics.For("players"),
+//	})
+//
+//	http.Handle("/metrics", metrics)
+type Prometheus struct {
+	lock        sync.Mutex
+	namespace   string
+	collections map[string]*collector
+}
+
+// NewPrometheus creates a new set of metrics, the names of which are prefixed by the namespace.
+func NewPrometheus(namespace string) *Prometheus {
+	return &Prometheus{
+		namespace:   namespace,
+		collections: make(map[string]*collector, 4),
+	}
+}
+
+// For returns the metrics of a collection with the specified name, creating them if necessary.
+func (p *Prometheus) For(collection string) column.Metrics {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if c, ok := p.collections[collection]; ok {
+		return c
+	}
+
+	c := newCollector(Buckets)
+	p.collections[collection] = c
+	return c
+}
+
+// ServeHTTP writes the metrics in the text exposition format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the text exposition format into the writer.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.lock.Lock()
+	names := make([]string, 0, len(p.collections))
+	for name := range p.collections {
+		names = append(names, name)
+	}
+	p.lock.Unlock()
+	sort.Strings(names)
+
+	out := &writer{w: bufio.NewWriter(w)}
+	counters := []struct {
+		name, help string
+		value      func(*collector) uint64
+	}{
+		{"commits_total", "The number of committed transactions which modified the collection.", func(c *collector) uint64 { return atomic.LoadUint64(&c.commits) }},
+		{"inserts_total", "The number of inserted rows.", func(c *collector) uint64 { return atomic.LoadUint64(&c.inserts) }},
+		{"deletes_total", "The number of deleted rows.", func(c *collector) uint64 { return atomic.LoadUint64(&c.deletes) }},
+		{"rows_scanned_total", "The number of rows scanned by the transactions.", func(c *collector) uint64 { return atomic.LoadUint64(&c.scanned) }},
+		{"rollbacks_total", "The number of transactions which were rolled back.", func(c *collector) uint64 { return atomic.LoadUint64(&c.rollbacks) }},
+	}
+
+	for _, m := range counters {
+		out.header(p.namespace+"_"+m.name, m.help, "counter")
+		for _, name := range names {
+			out.printf("%s_%s{collection=%q} %d\n", p.namespace, m.name, name, m.value(p.collector(name)))
+		}
+	}
+
+	// Write the histogram of the transaction durations
+	metric := p.namespace + "_transaction_duration_seconds"
+	out.header(metric, "The duration of the transactions.", "histogram")
+	for _, name := range names {
+		p.collector(name).duration.write(out, metric, name)
+	}
+
+	// Write the memory usage of the columns
+	metric = p.namespace + "_memory_bytes"
+	out.header(metric, "The approximate memory used by the columns.", "gauge")
+	for _, name := range names {
+		p.collector(name).memory.Range(func(k, v any) bool {
+			out.printf("%s{collection=%q,column=%q} %d\n", metric, name, k, atomic.LoadInt64(v.(*int64)))
+			return true
+		})
+	}
+
+	if out.err == nil {
+		out.err = out.w.Flush()
+	}
+	return out.n, out.err
+}
+
+// collector returns the metrics of a collection
+func (p *Prometheus) collector(name string) *collector {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.collections[name]
+}
+
+// --------------------------- Collector ----------------------------
+
+var _ column.Metrics = new(collector)
+
+// collector represents the metrics of a single collection
+type collector struct {
+	commits   uint64
+	inserts   uint64
+	deletes   uint64
+	scanned   uint64
+	rollbacks uint64
+	duration  *histogram
+	memory    sync.Map // The memory used by the columns, as *int64
+}
+
+// newCollector creates a new collector for a collection
+func newCollector(buckets []float64) *collector {
+	return &collector{
+		duration: newHistogram(buckets),
+	}
+}
+
+// ObserveCommit records a commit along with the number of inserted and deleted rows
+func (c *collector) ObserveCommit(inserts, deletes int) {
+	atomic.AddUint64(&c.commits, 1)
+	atomic.AddUint64(&c.inserts, uint64(inserts))
+	atomic.AddUint64(&c.deletes, uint64(deletes))
+}
+
+// ObserveQuery records the duration of a transaction and the number of rows it scanned
+func (c *collector) ObserveQuery(duration time.Duration, scanned int, committed bool) {
+	atomic.AddUint64(&c.scanned, uint64(scanned))
+	if !committed {
+		atomic.AddUint64(&c.rollbacks, 1)
+	}
+	c.duration.observe(duration.Seconds())
+}
+
+// ObserveMemory records the memory used by a column
+func (c *collector) ObserveMemory(column string, bytes int) {
+	v, _ := c.memory.LoadOrStore(column, new(int64))
+	atomic.StoreInt64(v.(*int64), int64(bytes))
+}
+
+// --------------------------- Histogram ----------------------------
+
+// histogram represents a cumulative histogram with fixed buckets
+type histogram struct {
+	bounds []float64 // The upper bounds of the buckets
+	counts []uint64  // The number of observations per bucket, the last one being +Inf
+	sum    uint64    // The sum of the observations, as float64 bits
+}
+
+// newHistogram creates a new histogram with the specified upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// observe records a single observation
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			return
+		}
+	}
+}
+
+// write writes the buckets, the sum and the count of the histogram
+func (h *histogram) write(out *writer, metric, collection string) {
+	count := uint64(0)
+	for i, bound := range h.bounds {
+		count += atomic.LoadUint64(&h.counts[i])
+		out.printf("%s_bucket{collection=%q,le=\"%g\"} %d\n", metric, collection, bound, count)
+	}
+
+	count += atomic.LoadUint64(&h.counts[len(h.bounds)])
+	out.printf("%s_bucket{collection=%q,le=\"+Inf\"} %d\n", metric, collection, count)
+	out.printf("%s_sum{collection=%q} %g\n", metric, collection, math.Float64frombits(atomic.LoadUint64(&h.sum)))
+	out.printf("%s_count{collection=%q} %d\n", metric, collection, count)
+}
+
+// --------------------------- Writer ----------------------------
+
+// writer writes the text format and keeps the first error
+type writer struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// header writes the help and the type of a metric
+func (w *writer) header(name, help, kind string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// printf writes a formatted line, unless a previous write failed
+func (w *writer) printf(format string, args ...any) {
+	if w.err == nil {
+		n, err := fmt.Fprintf(w.w, format, args...)
+		w.n += int64(n)
+		w.err = err
+	}
+}
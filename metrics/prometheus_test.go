@@ -0,0 +1,73 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus("column")
+	players := column.NewCollection(column.Options{
+		Metrics: p.For("players"),
+	})
+	defer players.Close()
+	players.CreateColumn("name", column.ForString())
+
+	players.InsertObject(column.Object{"name": "Roman"})
+	players.InsertObject(column.Object{"name": "Merlin"})
+	players.DeleteAt(0)
+	players.Query(func(txn *column.Txn) error {
+		return txn.Range(func(idx uint32) {})
+	})
+
+	// The same collection should return the same metrics
+	assert.Equal(t, p.For("players"), p.For("players"))
+	p.For("players").ObserveMemory("name", 1024)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	output := w.Body.String()
+
+	assert.Contains(t, output, "# TYPE column_commits_total counter\n")
+	assert.Contains(t, output, `column_commits_total{collection="players"} 3`)
+	assert.Contains(t, output, `column_inserts_total{collection="players"} 2`)
+	assert.Contains(t, output, `column_deletes_total{collection="players"} 1`)
+	assert.Contains(t, output, `column_rows_scanned_total{collection="players"} 1`)
+	assert.Contains(t, output, `column_rollbacks_total{collection="players"} 0`)
+	assert.Contains(t, output, "# TYPE column_transaction_duration_seconds histogram\n")
+	assert.Contains(t, output, `column_transaction_duration_seconds_bucket{collection="players",le="+Inf"} 4`)
+	assert.Contains(t, output, `column_transaction_duration_seconds_count{collection="players"} 4`)
+	assert.Contains(t, output, `column_memory_bytes{collection="players",column="name"} 1024`)
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{1, 2})
+	h.observe(0.5)
+	h.observe(1)
+	h.observe(1.5)
+	h.observe(5)
+
+	var buffer bytes.Buffer
+	c := newCollector([]float64{1, 2})
+	c.duration = h
+	c.ObserveQuery(3*time.Second, 10, false)
+
+	p := NewPrometheus("test")
+	p.collections["a"] = c
+	_, err := p.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), `test_transaction_duration_seconds_bucket{collection="a",le="1"} 2`)
+	assert.Contains(t, buffer.String(), `test_transaction_duration_seconds_bucket{collection="a",le="2"} 3`)
+	assert.Contains(t, buffer.String(), `test_transaction_duration_seconds_bucket{collection="a",le="+Inf"} 5`)
+	assert.Contains(t, buffer.String(), `test_transaction_duration_seconds_sum{collection="a"} 11`)
+	assert.Contains(t, buffer.String(), `test_rollbacks_total{collection="a"} 1`)
+	assert.Contains(t, buffer.String(), `test_rows_scanned_total{collection="a"} 10`)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := new(fakeMetrics)
+	c := NewCollection(Options{
+		Metrics: m,
+		Vacuum:  time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("age", ForInt())
+
+	// Insert and delete some rows
+	for i := 0; i < 10; i++ {
+		c.InsertObject(Object{"name": "Roman", "age": i})
+	}
+	c.DeleteAt(0)
+	c.DeleteAt(1)
+
+	// Scan the rows, then roll back
+	c.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {})
+	})
+	c.Query(func(txn *Txn) error {
+		return errors.New("rollback")
+	})
+
+	m.lock.Lock()
+	assert.Equal(t, 12, m.commits)
+	assert.Equal(t, 10, m.inserts)
+	assert.Equal(t, 2, m.deletes)
+	assert.Equal(t, 8, m.scanned)
+	assert.Equal(t, 1, m.rollbacks)
+	assert.Equal(t, 14, m.queries)
+	m.lock.Unlock()
+
+	// The memory usage is reported periodically by the vacuum
+	c.observeMemory()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	name, _ := c.cols.Load("name")
+	arena := cap(name.Column.(*columnString).data.chunks[0].arena)
+	assert.Equal(t, chunkSize*8+chunkSize/8+arena, m.memory["name"])
+	assert.GreaterOrEqual(t, arena, 10*5)
+	assert.Equal(t, chunkSize*8+chunkSize/8, m.memory["age"])
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.InsertObject(Object{"name": "Roman"})
+	c.observeMemory()
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {})
+	}))
+}
+
+// fakeMetrics records the observed metrics
+type fakeMetrics struct {
+	lock      sync.Mutex
+	commits   int
+	inserts   int
+	deletes   int
+	scanned   int
+	queries   int
+	rollbacks int
+	memory    map[string]int
+}
+
+func (m *fakeMetrics) ObserveCommit(inserts, deletes int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.commits++
+	m.inserts += inserts
+	m.deletes += deletes
+}
+
+func (m *fakeMetrics) ObserveQuery(duration time.Duration, scanned int, committed bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.queries++
+	m.scanned += scanned
+	if !committed {
+		m.rollbacks++
+	}
+}
+
+func (m *fakeMetrics) ObserveMemory(column string, bytes int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.memory == nil {
+		m.memory = make(map[string]int)
+	}
+	m.memory[column] = bytes
+}
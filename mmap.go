@@ -0,0 +1,220 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/kelindar/column/commit"
+)
+
+// errMappingUnsupported is returned when the memory-mapped files are not available
+var errMappingUnsupported = errors.New("column: memory-mapped columns are not supported on this platform")
+
+// mappingMagic identifies the files backing the memory-mapped columns
+var mappingMagic = []byte("COLMAP01")
+
+// WithMapping backs the values of a numeric column with a memory-mapped file at the specified
+// path, which is created if it does not exist. The operating system pages the values in and
+// out of memory as they are accessed, so that the column can be larger than the available
+// memory, and the values already present in the file are available as soon as the column is
+// created, along with their rows.
+//
+// The file is written in place and in the native byte order, it is not crash-consistent and
+// should not be shared between processes. The column must not be used once the collection
+// has been closed, which unmaps the file.
+func WithMapping(path string) ColumnOption {
+	return func(o *columnOptions) {
+		o.Mapping = path
+	}
+}
+
+// mappable represents a column whose values can be backed by a memory-mapped file
+type mappable interface {
+	mapTo(path string) error
+}
+
+// mapColumn backs the values of the column with a memory-mapped file
+func mapColumn(column Column, path string) error {
+	m, ok := column.(mappable)
+	switch {
+	case !mappingSupported:
+		return errMappingUnsupported
+	case !ok:
+		return fmt.Errorf("column: unable to map %T, its values are not of a fixed size", column)
+	}
+
+	return m.mapTo(path)
+}
+
+// restoreRows adds the rows which have a value in the column to the collection, this is used
+// once the values of a memory-mapped column have been loaded from its file.
+func (c *Collection) restoreRows(column Column) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for chunk := commit.Chunk(0); ; chunk++ {
+		index := column.Index(chunk)
+		if index == nil {
+			break
+		}
+
+		if index.Count() > 0 {
+			c.fill.Grow(chunk.Max())
+			fill := chunk.OfBitmap(c.fill)
+			fill.Or(index)
+		}
+	}
+
+	atomic.StoreUint64(&c.count, uint64(c.fill.Count()))
+}
+
+// --------------------------- Mapped File ----------------------------
+
+// mappedFile represents a file in which the chunks of a column are mapped. The first page holds
+// a header which describes the values, followed by the chunks. Each chunk starts with its
+// fill-list followed by the values, and is padded to a multiple of the page size.
+type mappedFile struct {
+	file    *os.File // The underlying file
+	header  []byte   // The expected header
+	stride  int      // The size of each chunk in the file
+	regions [][]byte // The mapped regions of the chunks
+}
+
+// openMapping opens or creates the file backing a column with the specified kind of values
+func openMapping(path string, kind reflect.Kind, size int) (*mappedFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	page := os.Getpagesize()
+	header := make([]byte, page)
+	copy(header, mappingMagic)
+	binary.LittleEndian.PutUint32(header[8:], uint32(kind))
+	binary.LittleEndian.PutUint32(header[12:], uint32(size))
+	binary.LittleEndian.PutUint32(header[16:], chunkSize)
+
+	m := &mappedFile{
+		file:   file,
+		header: header,
+		stride: (chunkSize/8 + chunkSize*size + page - 1) / page * page,
+	}
+
+	if err := m.validate(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// validate writes the header of an empty file, or checks the one of an existing file
+func (m *mappedFile) validate() error {
+	info, err := m.file.Stat()
+	switch {
+	case err != nil:
+		return err
+	case info.Size() == 0:
+		_, err := m.file.WriteAt(m.header, 0)
+		return err
+	}
+
+	existing := make([]byte, len(m.header))
+	if _, err := m.file.ReadAt(existing, 0); err != nil {
+		return fmt.Errorf("column: unable to read the header of '%s', %w", m.file.Name(), err)
+	}
+
+	if !bytes.Equal(existing, m.header) {
+		return fmt.Errorf("column: file '%s' does not contain values of the column type", m.file.Name())
+	}
+	return nil
+}
+
+// Count returns the number of chunks stored in the file
+func (m *mappedFile) Count() int {
+	info, err := m.file.Stat()
+	if err != nil {
+		return 0
+	}
+
+	return int((info.Size() - int64(len(m.header))) / int64(m.stride))
+}
+
+// Chunk maps the region of the specified chunk, extending the file if necessary
+func (m *mappedFile) Chunk(chunk int) ([]byte, error) {
+	offset := int64(len(m.header)) + int64(chunk)*int64(m.stride)
+	if m.Count() <= chunk {
+		if err := m.file.Truncate(offset + int64(m.stride)); err != nil {
+			return nil, err
+		}
+	}
+
+	region, err := mapRegion(m.file, offset, m.stride)
+	if err != nil {
+		return nil, err
+	}
+
+	m.regions = append(m.regions, region)
+	return region, nil
+}
+
+// Close unmaps all of the regions and closes the file
+func (m *mappedFile) Close() (err error) {
+	for _, region := range m.regions {
+		if e := unmapRegion(region); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	m.regions = nil
+	if e := m.file.Close(); e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// --------------------------- Numeric Mapping ----------------------------
+
+// mapTo backs the values of the column with a memory-mapped file, loading the chunks which
+// are already present in the file.
+func (c *numericColumn[T]) mapTo(path string) error {
+	if len(c.chunks) > 0 {
+		return fmt.Errorf("column: unable to map a column which already has values")
+	}
+
+	var zero T
+	file, err := openMapping(path, reflect.TypeOf(zero).Kind(), int(unsafe.Sizeof(zero)))
+	if err != nil {
+		return err
+	}
+
+	c.file = file
+	if count := file.Count(); count > 0 {
+		c.Grow(commit.Chunk(count - 1).Min())
+	}
+	return nil
+}
+
+// growMapped grows the column by mapping the regions of the new chunks
+func (c *numericColumn[T]) growMapped(idx uint32) {
+	for i := len(c.chunks); i <= int(commit.ChunkAt(idx)); i++ {
+		region, err := c.file.Chunk(i)
+		if err != nil {
+			panic(fmt.Errorf("column: unable to map chunk %d, %w", i, err))
+		}
+
+		values := region[chunkSize/8:]
+		c.chunks.push(
+			castSlice[uint64](region, chunkSize/64),
+			castSlice[T](values, chunkSize),
+		)
+	}
+}
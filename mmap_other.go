@@ -0,0 +1,34 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build tinygo || !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package column
+
+import (
+	"os"
+)
+
+// mappingSupported indicates whether the columns can be memory-mapped on this platform
+const mappingSupported = false
+
+// mapRegion is not supported on this platform
+func mapRegion(file *os.File, offset int64, size int) ([]byte, error) {
+	return nil, errMappingUnsupported
+}
+
+// unmapRegion is not supported on this platform
+func unmapRegion(region []byte) error {
+	return errMappingUnsupported
+}
+
+// allocAnonymous allocates the memory on the Go heap, as the anonymous memory maps are not
+// supported on this platform
+func allocAnonymous(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// freeAnonymous leaves the memory to the garbage collector
+func freeAnonymous(region []byte) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && (linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package column
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapping(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *Collection {
+		c := NewCollection()
+		assert.NoError(t, c.CreateColumn("age", ForInt64(), WithMapping(filepath.Join(dir, "age.col"))))
+		assert.NoError(t, c.CreateColumn("score", ForFloat32(), WithMapping(filepath.Join(dir, "score.col"))))
+		return c
+	}
+
+	c := open()
+	for i := 0; i < 20000; i++ {
+		c.InsertObject(Object{"age": i % 100, "score": float32(i) / 2})
+	}
+
+	assert.True(t, c.DeleteAt(5))
+	assert.NoError(t, c.QueryAt(6, func(r Row) error {
+		r.SetInt64("age", 600)
+		return nil
+	}))
+	assert.NoError(t, c.Close())
+
+	// The rows and their values are available as soon as the columns are mapped again
+	c = open()
+	defer c.Close()
+	assert.Equal(t, 19999, c.Count())
+	assert.NoError(t, c.QueryAt(6, func(r Row) error {
+		age, _ := r.Int64("age")
+		assert.Equal(t, int64(600), age)
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(19999, func(r Row) error {
+		score, _ := r.Float32("score")
+		assert.Equal(t, float32(9999.5), score)
+		return nil
+	}))
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 200, txn.WithInt("age", func(v int64) bool {
+			return v == 99
+		}).Count())
+		return nil
+	}))
+
+	// New rows are written to the files
+	idx := c.InsertObject(Object{"age": 1, "score": 1})
+	assert.Equal(t, 20000, c.Count())
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		age, _ := r.Int64("age")
+		assert.Equal(t, int64(1), age)
+		return nil
+	}))
+}
+
+func TestMappingClone(t *testing.T) {
+	c := NewCollection()
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("age", ForInt(), WithMapping(filepath.Join(t.TempDir(), "age.col"))))
+	c.InsertObject(Object{"age": 10})
+
+	// The clone keeps the values it was created with
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		r.SetInt("age", 20)
+		return nil
+	}))
+
+	assert.NoError(t, clone.QueryAt(0, func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 10, age)
+		return nil
+	}))
+	assert.NoError(t, c.QueryAt(0, func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 20, age)
+		return nil
+	}))
+}
+
+func TestMappingInvalid(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCollection()
+	defer c.Close()
+
+	assert.Error(t, c.CreateColumn("name", ForString(), WithMapping(filepath.Join(dir, "name.col"))))
+	assert.NoError(t, c.CreateColumn("age", ForInt64(), WithMapping(filepath.Join(dir, "age.col"))))
+	assert.Error(t, c.CreateColumn("other", ForInt32(), WithMapping(filepath.Join(dir, "age.col"))))
+	assert.Error(t, c.CreateColumn("nested", ForInt32(), WithMapping(dir)))
+
+	// A dropped column is unmapped and can be mapped again
+	assert.NoError(t, c.DropColumn("age"))
+	assert.NoError(t, c.CreateColumn("age", ForInt64(), WithMapping(filepath.Join(dir, "age.col"))))
+}
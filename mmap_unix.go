@@ -0,0 +1,34 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+//go:build !tinygo && (linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package column
+
+import (
+	"os"
+	"syscall"
+)
+
+// mappingSupported indicates whether the columns can be memory-mapped on this platform
+const mappingSupported = true
+
+// mapRegion maps a region of the file into memory, the changes are written back to the file
+func mapRegion(file *os.File, offset int64, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), offset, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmapRegion unmaps a region previously mapped
+func unmapRegion(region []byte) error {
+	return syscall.Munmap(region)
+}
+
+// allocAnonymous maps a region of anonymous memory, outside of the Go heap
+func allocAnonymous(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+// freeAnonymous unmaps a region of anonymous memory
+func freeAnonymous(region []byte) error {
+	return syscall.Munmap(region)
+}
@@ -0,0 +1,228 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// defaultBatchSize is the default number of rows inserted per transaction by LoadNDJSON()
+const defaultBatchSize = 10000
+
+// LoadOptions represents the options of LoadNDJSON()
+type LoadOptions struct {
+	BatchSize int                   // The number of rows inserted per transaction, 10000 by default
+	OnError   func(*LineError) bool // The callback for the invalid lines, which returns whether to keep loading
+}
+
+// LineError represents an error of a single line of a stream of newline-delimited JSON
+type LineError struct {
+	Line int    // The number of the line, starting at one
+	Err  error  // The reason why the line was not loaded
+	Data []byte // The content of the line
+}
+
+// Error returns the error message
+func (e *LineError) Error() string {
+	return fmt.Sprintf("column: unable to load line %d, %v", e.Line, e.Err)
+}
+
+// Unwrap returns the reason why the line was not loaded
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LoadNDJSON streams newline-delimited JSON from the reader and inserts one row per object,
+// so that the dumps which do not fit in memory can be loaded. The rows are inserted in large
+// transactions of BatchSize rows each, and the numbers are converted to the types of the
+// columns. The null values are skipped and the blank lines are ignored. If the collection is
+// Dynamic, the unknown keys create their columns, with the numbers stored as float64.
+//
+// A line which is not a valid object, or which has a key without a column or a value which
+// does not fit its column, is not loaded. It is passed to the OnError callback, if any, and the
+// loading stops unless the callback returns true. The number of rows inserted is returned,
+// along with the error which stopped the loading. The batches which were inserted remain.
+func (c *Collection) LoadNDJSON(r io.Reader, options ...LoadOptions) (int, error) {
+	opts := LoadOptions{BatchSize: defaultBatchSize}
+	if len(options) > 0 {
+		opts.OnError = options[0].OnError
+		if options[0].BatchSize > 0 {
+			opts.BatchSize = options[0].BatchSize
+		}
+	}
+
+	loaded, first := 0, 1
+	batch := make([]Object, 0, opts.BatchSize)
+	flush := func(last int) error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := c.Query(func(txn *Txn) error {
+			_, err := txn.InsertMany(batch)
+			return err
+		}); err != nil {
+			return fmt.Errorf("column: unable to load lines %d to %d, %w", first, last, err)
+		}
+
+		loaded += len(batch)
+		batch = batch[:0]
+		first = last + 1
+		return nil
+	}
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for line := 1; ; line++ {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return loaded, readErr
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			object, err := c.decodeLine(data)
+			switch {
+			case err == nil:
+				batch = append(batch, object)
+			case opts.OnError == nil:
+				return loaded, &LineError{Line: line, Err: err, Data: data}
+			case !opts.OnError(&LineError{Line: line, Err: err, Data: data}):
+				return loaded, errLoadStopped
+			}
+		}
+
+		// Insert the batch once it is full, or once the stream is done
+		switch {
+		case readErr == io.EOF:
+			return loaded, flush(line)
+		case len(batch) >= opts.BatchSize:
+			if err := flush(line); err != nil {
+				return loaded, err
+			}
+		}
+	}
+}
+
+// errLoadStopped is returned when the loading was stopped by the error callback
+var errLoadStopped = errors.New("column: loading stopped on an invalid line")
+
+// decodeLine decodes a line of newline-delimited JSON into an object with the values
+// converted to the types of the columns
+func (c *Collection) decodeLine(data []byte) (Object, error) {
+	var values map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	object := make(Object, len(values))
+	for name, value := range values {
+		if value == nil {
+			continue
+		}
+
+		kind, ok := c.KindOf(name)
+		switch {
+		case !ok && !c.opts.Dynamic:
+			return nil, fmt.Errorf("column '%s' does not exist", name)
+		case !ok:
+			kind = reflect.Interface
+		}
+
+		v, err := convertJSON(value, kind)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for column '%s', %w", name, err)
+		}
+		object[name] = v
+	}
+	return object, nil
+}
+
+// convertJSON converts a decoded JSON value into the specified kind
+func convertJSON(value any, kind reflect.Kind) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return parseNumber(v, kind)
+	case string:
+		if kind == reflect.String || kind == reflect.Interface {
+			return v, nil
+		}
+	case bool:
+		if kind == reflect.Bool || kind == reflect.Interface {
+			return v, nil
+		}
+	default:
+		if kind == reflect.Interface {
+			return value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("expected a value of kind %v", kind)
+}
+
+// parseNumber parses a JSON number into the specified numeric kind
+func parseNumber(v json.Number, kind reflect.Kind) (any, error) {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(v), 10, bitsOf(kind))
+		switch kind {
+		case reflect.Int:
+			return int(n), err
+		case reflect.Int8:
+			return int8(n), err
+		case reflect.Int16:
+			return int16(n), err
+		case reflect.Int32:
+			return int32(n), err
+		default:
+			return n, err
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(string(v), 10, bitsOf(kind))
+		switch kind {
+		case reflect.Uint:
+			return uint(n), err
+		case reflect.Uint8:
+			return uint8(n), err
+		case reflect.Uint16:
+			return uint16(n), err
+		case reflect.Uint32:
+			return uint32(n), err
+		default:
+			return n, err
+		}
+
+	case reflect.Float32:
+		n, err := strconv.ParseFloat(string(v), 32)
+		return float32(n), err
+
+	case reflect.Float64, reflect.Interface:
+		return v.Float64()
+
+	default:
+		return nil, fmt.Errorf("expected a value of kind %v", kind)
+	}
+}
+
+// bitsOf returns the size of a numeric kind, in bits
+func bitsOf(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int8, reflect.Uint8:
+		return 8
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32:
+		return 32
+	default:
+		return 64
+	}
+}
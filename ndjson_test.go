@@ -0,0 +1,138 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadNDJSON(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt32())
+	coll.CreateColumn("score", ForUint64())
+	coll.CreateColumn("balance", ForFloat64())
+	coll.CreateColumn("active", ForBool())
+	coll.CreateColumn("profile", ForJSON())
+
+	loaded, err := coll.LoadNDJSON(strings.NewReader(`
+		{"name": "Roman", "age": 35, "score": 18446744073709551615, "balance": 10.5, "active": true}
+
+		{"name": "Merlin", "age": null, "profile": {"class": "mage", "level": 10}}
+	`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	assert.Equal(t, 2, coll.Count())
+
+	// The numbers are converted to the types of the columns
+	assert.NoError(t, coll.QueryAt(0, func(r Row) error {
+		age, _ := r.Int32("age")
+		assert.Equal(t, int32(35), age)
+		score, _ := r.Uint64("score")
+		assert.Equal(t, uint64(18446744073709551615), score)
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, 10.5, balance)
+		return nil
+	}))
+
+	// The null values are skipped
+	assert.NoError(t, coll.QueryAt(1, func(r Row) error {
+		_, ok := r.Int32("age")
+		assert.False(t, ok)
+		profile, _ := r.Any("profile")
+		assert.JSONEq(t, `{"class": "mage", "level": 10}`, string(profile.(json.RawMessage)))
+		return nil
+	}))
+}
+
+func TestLoadNDJSONErrors(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt16())
+
+	input := strings.Join([]string{
+		`{"name": "Roman", "age": 35}`,
+		`{"name": "Merlin", "age": 40000}`,
+		`{"name": "Arthur", "class": "knight"}`,
+		`{"name": 42}`,
+		`not json`,
+		`{"name": "Lancelot"}`,
+	}, "\n")
+
+	// Without a callback, the first invalid line stops the loading
+	loaded, err := coll.LoadNDJSON(strings.NewReader(input))
+	assert.Equal(t, 0, loaded)
+	var lineErr *LineError
+	assert.True(t, errors.As(err, &lineErr))
+	assert.Equal(t, 2, lineErr.Line)
+	assert.Equal(t, `{"name": "Merlin", "age": 40000}`, string(lineErr.Data))
+	assert.Equal(t, 0, coll.Count())
+
+	// With a callback, every invalid line is reported
+	var lines []int
+	loaded, err = coll.LoadNDJSON(strings.NewReader(input), LoadOptions{
+		OnError: func(err *LineError) bool {
+			lines = append(lines, err.Line)
+			return true
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	assert.Equal(t, []int{2, 3, 4, 5}, lines)
+	assert.Equal(t, 2, coll.Count())
+
+	// The callback can stop the loading
+	_, err = coll.LoadNDJSON(strings.NewReader(input), LoadOptions{
+		OnError: func(err *LineError) bool { return false },
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 2, coll.Count())
+}
+
+func TestLoadNDJSONBatches(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("id", ForInt())
+	coll.CreateCheck("id", func(v interface{}) bool {
+		return v != 25
+	})
+
+	var input strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&input, "{\"id\": %d}\n", i)
+	}
+
+	// The batches are inserted separately, up to the one which failed
+	loaded, err := coll.LoadNDJSON(strings.NewReader(input.String()), LoadOptions{BatchSize: 10})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "lines 21 to 30")
+	assert.Equal(t, 20, loaded)
+	assert.Equal(t, 20, coll.Count())
+}
+
+func TestLoadNDJSONDynamic(t *testing.T) {
+	coll := NewCollection(Options{Dynamic: true})
+	loaded, err := coll.LoadNDJSON(strings.NewReader(
+		`{"name": "Roman", "age": 35, "active": true, "tags": ["mage"]}`,
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+
+	for name, kind := range map[string]reflect.Kind{
+		"name":   reflect.String,
+		"age":    reflect.Float64,
+		"active": reflect.Bool,
+		"tags":   reflect.Interface,
+	} {
+		actual, ok := coll.KindOf(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, kind, actual, name)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+
+	"github.com/kelindar/iostream"
+)
+
+// ToObject copies the values of the row the selector is pointing at into an object, keyed
+// by the name of their column. The indexes and the expiration times are not included, and
+// neither are the columns which have no value for the row.
+func (s Selector) ToObject() Object {
+	txn, idx := s.row.txn, s.row.txn.cursor
+	object := make(Object, 8)
+	txn.owner.cols.Range(func(column *column) {
+		if column.IsIndex() || column.name == expireColumn || column.opts.expiryOf != "" {
+			return
+		}
+
+		if v, ok := column.Value(idx); ok {
+			object[column.name] = v
+		}
+	})
+	return object
+}
+
+// --------------------------- Row Codec ----------------------------
+
+// valueKind represents the type of an encoded value
+type valueKind uint8
+
+const (
+	valueNil valueKind = iota
+	valueInt
+	valueInt16
+	valueInt32
+	valueInt64
+	valueUint
+	valueUint16
+	valueUint32
+	valueUint64
+	valueFloat32
+	valueFloat64
+	valueString
+	valueBool
+	valueBytes
+	valueGob
+)
+
+// EncodeRow encodes an object, such as the one returned by Selector.ToObject(), into a
+// compact binary representation which preserves the types of the values. The values of
+// the numeric, string, boolean and byte slice types are encoded natively, while the other
+// ones are encoded with gob and hence their types must be registered with gob.Register().
+func EncodeRow(object Object) ([]byte, error) {
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buffer bytes.Buffer
+	writer := iostream.NewWriter(&buffer)
+	if err := writer.WriteRange(len(keys), func(i int, w *iostream.Writer) error {
+		if err := w.WriteString(keys[i]); err != nil {
+			return err
+		}
+		return writeValue(w, object[keys[i]])
+	}); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// DecodeRow decodes an object previously encoded with EncodeRow(). The decoded values have
+// the same types as the encoded ones, so the object can be inserted into a collection.
+func DecodeRow(data []byte) (Object, error) {
+	object := make(Object, 8)
+	reader := iostream.NewReader(bytes.NewReader(data))
+	if err := reader.ReadRange(func(i int, r *iostream.Reader) error {
+		key, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+
+		object[key], err = readValue(r)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("column: unable to decode row, %w", err)
+	}
+
+	return object, nil
+}
+
+// writeValue writes a single value along with its type
+func writeValue(w *iostream.Writer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		return w.WriteUint8(uint8(valueNil))
+	case int:
+		return writeKind(w, valueInt, func() error { return w.WriteVarint(int64(v)) })
+	case int16:
+		return writeKind(w, valueInt16, func() error { return w.WriteVarint(int64(v)) })
+	case int32:
+		return writeKind(w, valueInt32, func() error { return w.WriteVarint(int64(v)) })
+	case int64:
+		return writeKind(w, valueInt64, func() error { return w.WriteVarint(v) })
+	case uint:
+		return writeKind(w, valueUint, func() error { return w.WriteUvarint(uint64(v)) })
+	case uint16:
+		return writeKind(w, valueUint16, func() error { return w.WriteUvarint(uint64(v)) })
+	case uint32:
+		return writeKind(w, valueUint32, func() error { return w.WriteUvarint(uint64(v)) })
+	case uint64:
+		return writeKind(w, valueUint64, func() error { return w.WriteUvarint(v) })
+	case float32:
+		return writeKind(w, valueFloat32, func() error { return w.WriteFloat32(v) })
+	case float64:
+		return writeKind(w, valueFloat64, func() error { return w.WriteFloat64(v) })
+	case string:
+		return writeKind(w, valueString, func() error { return w.WriteString(v) })
+	case bool:
+		return writeKind(w, valueBool, func() error { return w.WriteBool(v) })
+	case []byte:
+		return writeKind(w, valueBytes, func() error { return w.WriteBytes(v) })
+	default:
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).Encode(&value); err != nil {
+			return fmt.Errorf("column: unable to encode value of type %T, %w", value, err)
+		}
+		return writeKind(w, valueGob, func() error { return w.WriteBytes(buffer.Bytes()) })
+	}
+}
+
+// writeKind writes the type of a value, followed by the value itself
+func writeKind(w *iostream.Writer, kind valueKind, writeFn func() error) error {
+	if err := w.WriteUint8(uint8(kind)); err != nil {
+		return err
+	}
+	return writeFn()
+}
+
+// readValue reads a single value along with its type
+func readValue(r *iostream.Reader) (any, error) {
+	kind, err := r.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch valueKind(kind) {
+	case valueNil:
+		return nil, nil
+	case valueInt:
+		v, err := r.ReadVarint()
+		return int(v), err
+	case valueInt16:
+		v, err := r.ReadVarint()
+		return int16(v), err
+	case valueInt32:
+		v, err := r.ReadVarint()
+		return int32(v), err
+	case valueInt64:
+		return r.ReadVarint()
+	case valueUint:
+		v, err := r.ReadUvarint()
+		return uint(v), err
+	case valueUint16:
+		v, err := r.ReadUvarint()
+		return uint16(v), err
+	case valueUint32:
+		v, err := r.ReadUvarint()
+		return uint32(v), err
+	case valueUint64:
+		return r.ReadUvarint()
+	case valueFloat32:
+		return r.ReadFloat32()
+	case valueFloat64:
+		return r.ReadFloat64()
+	case valueString:
+		return r.ReadString()
+	case valueBool:
+		return r.ReadBool()
+	case valueBytes:
+		return r.ReadBytes()
+	case valueGob:
+		data, err := r.ReadBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+		return value, err
+	default:
+		return nil, fmt.Errorf("unknown value type %d", kind)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/gob"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToObject(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("id", ForKey())
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("class", ForEnum())
+	coll.CreateColumn("age", ForInt16())
+	coll.CreateColumn("balance", ForFloat64())
+	coll.CreateColumn("active", ForBool())
+	coll.CreateColumn("data", ForAny())
+	coll.CreateColumn("missing", ForString())
+	coll.CreateIndex("mage", "class", func(r Reader) bool {
+		return r.String() == "mage"
+	})
+
+	assert.NoError(t, coll.QueryKey("merlin", func(r Row) error {
+		r.SetString("name", "Merlin")
+		r.SetEnum("class", "mage")
+		r.SetInt16("age", 101)
+		r.SetFloat64("balance", 12.5)
+		r.SetBool("active", true)
+		r.SetAny("data", "staff")
+		return nil
+	}))
+
+	idx, _ := coll.pk.OffsetOf("merlin")
+	var object Object
+	assert.True(t, coll.ReadAt(idx, func(v Selector) {
+		object = v.ToObject()
+	}))
+
+	expect := Object{
+		"id":      "merlin",
+		"name":    "Merlin",
+		"class":   "mage",
+		"age":     int16(101),
+		"balance": 12.5,
+		"active":  true,
+		"data":    "staff",
+	}
+	assert.Equal(t, expect, object)
+
+	// The object can be shipped and decoded with the same types
+	encoded, err := EncodeRow(object)
+	assert.NoError(t, err)
+	decoded, err := DecodeRow(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, expect, decoded)
+
+	// The encoding is deterministic
+	again, err := EncodeRow(decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, encoded, again)
+}
+
+func TestEncodeRowTypes(t *testing.T) {
+	gob.Register(map[string]int{})
+	object := Object{
+		"int": int(-1), "int16": int16(-2), "int32": int32(-3), "int64": int64(-4),
+		"uint": uint(1), "uint16": uint16(2), "uint32": uint32(3), "uint64": uint64(4),
+		"float32": float32(1.5), "float64": 2.5, "string": "a", "bool": false,
+		"bytes": []byte("b"), "nil": nil, "map": map[string]int{"x": 1},
+	}
+
+	encoded, err := EncodeRow(object)
+	assert.NoError(t, err)
+	decoded, err := DecodeRow(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, object, decoded)
+}
+
+func TestDecodeRowInvalid(t *testing.T) {
+	encoded, err := EncodeRow(Object{"name": "Roman"})
+	assert.NoError(t, err)
+
+	_, err = DecodeRow(encoded[:len(encoded)-1])
+	assert.Error(t, err)
+
+	_, err = EncodeRow(Object{"fn": func() {}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,315 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// maxPlans is the maximum number of plans retained by the cache
+const maxPlans = 1024
+
+// Cost of the filters, the cheaper ones are applied first
+const (
+	costTyped = iota // Typed filter which reads the values directly
+	costValue        // Generic filter which boxes the values
+)
+
+// --------------------------- Filter ----------------------------
+
+// filter represents a pending value filter which is applied on a chunk
+type filter struct {
+	cost   uint8                             // The relative cost of the filter
+	op     string                            // The name of the operation, for tracing
+	column string                            // The column on which the filter is applied
+	fn     func(commit.Chunk, bitmap.Bitmap) // The filter function
+}
+
+// filter defers a value filter until the result set is actually needed. This way, the
+// bitmap filters are applied first and the values are only read for the rows which
+// survived them.
+func (txn *Txn) filter(cost uint8, op, column string, fn func(chunk commit.Chunk, index bitmap.Bitmap)) {
+	if txn.journal != nil {
+		txn.note("%s(%s): deferred", op, column)
+	}
+
+	txn.filters = append(txn.filters, filter{
+		cost:   cost,
+		op:     op,
+		column: column,
+		fn:     fn,
+	})
+}
+
+// resolve ensures that the transaction is initialized and that all of the pending
+// filters are applied, chunk by chunk, following the plan for this chain of filters.
+func (txn *Txn) resolve() {
+	txn.initialize()
+	if txn.trace != nil {
+		txn.traceFilters()
+	}
+
+	switch len(txn.filters) {
+	case 0:
+		return
+	case 1:
+		txn.rangeRead(txn.filters[0].fn)
+	default:
+		txn.execute()
+	}
+
+	if txn.journal != nil {
+		txn.note("Resolved %d deferred filters: %d rows", len(txn.filters), txn.index.Count())
+	}
+	txn.filters = txn.filters[:0]
+}
+
+// execute applies the pending filters using a cached plan. If there is no valid plan
+// for this chain of filters yet, a new one is made based on the cost and the observed
+// selectivity of each filter.
+func (txn *Txn) execute() {
+	key := planKey(txn.filters)
+	plans := &txn.owner.plans
+	version := txn.owner.cols.Version()
+	count := txn.owner.Count()
+	if order, ok := plans.Load(key, version, count); ok && len(order) == len(txn.filters) {
+		order = txn.applyHints(order)
+		txn.explained(order)
+		txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+			for _, i := range order {
+				txn.filters[i].fn(chunk, index)
+			}
+		})
+		return
+	}
+
+	// Start with the cheapest filters first
+	order := make([]int, len(txn.filters))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return txn.filters[order[i]].cost < txn.filters[order[j]].cost
+	})
+
+	// Apply the filters while measuring how many rows each one of them keeps. Once the first
+	// chunk with any rows is filtered, the remaining chunks follow the estimated selectivity,
+	// so that a poorly ordered chain is only slow on a single chunk.
+	input := make([]int, len(txn.filters))
+	output := make([]int, len(txn.filters))
+	applied := txn.applyHints(order)
+	sampled := false
+	txn.explained(applied)
+	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		for _, i := range applied {
+			input[i] += index.Count()
+			txn.filters[i].fn(chunk, index)
+			output[i] += index.Count()
+		}
+
+		if !sampled && input[applied[0]] > 0 {
+			sampled = true
+			txn.rank(order, input, output)
+			applied = txn.applyHints(order)
+		}
+	})
+
+	txn.rank(order, input, output)
+	selectivity := make([]float64, len(order))
+	for i := range selectivity {
+		selectivity[i] = float64(output[i]+1) / float64(input[i]+1)
+	}
+
+	plans.Store(key, &plan{
+		version:     version,
+		count:       count,
+		order:       order,
+		selectivity: selectivity,
+	})
+}
+
+// rank sorts the order of the filters by the number of rows they were measured to keep. The
+// most selective filters of the same cost are applied first and, if the costs were calibrated,
+// the filters with the lowest cost per eliminated row go first.
+func (txn *Txn) rank(order, input, output []int) {
+	model, calibrated := txn.owner.costModel()
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		switch {
+		case calibrated:
+			return model.rank(txn.filters[a].cost, input[a], output[a]) < model.rank(txn.filters[b].cost, input[b], output[b])
+		case txn.filters[a].cost != txn.filters[b].cost:
+			return txn.filters[a].cost < txn.filters[b].cost
+		default:
+			return output[a]*input[b] < output[b]*input[a]
+		}
+	})
+}
+
+// planKey computes the key of a chain of filters, using FNV-1a over the structure of
+// the chain. Filters which only differ by their predicates share the same key.
+func planKey(filters []filter) uint64 {
+	const prime = 1099511628211
+	key := uint64(14695981039346656037)
+	for _, f := range filters {
+		key = (key ^ uint64(f.cost)) * prime
+		for i := 0; i < len(f.column); i++ {
+			key = (key ^ uint64(f.column[i])) * prime
+		}
+		key = (key ^ 0xff) * prime
+	}
+	return key
+}
+
+// rank returns the cost of a filter per eliminated row, given the number of rows it was
+// applied on and the number of rows it kept.
+func (m CostModel) rank(cost uint8, input, output int) float64 {
+	perRow := m.Typed
+	if cost == costValue {
+		perRow = m.Value
+	}
+
+	eliminated := float64(input-output) + 1
+	return perRow * float64(input+1) / eliminated
+}
+
+// --------------------------- Hints ----------------------------
+
+// Kinds of the planner hints
+const (
+	hintIndex = iota // Prefer the index of the column
+	hintScan         // Prefer to scan the values of the column
+)
+
+// Hint represents a hint to the query planner, which overrides its estimates for a column
+type Hint struct {
+	kind   uint8  // The kind of the hint
+	column string // The column the hint applies to
+}
+
+// PreferIndex returns a hint which makes the planner apply the filters on the column before
+// any other filter, and look up the equal values with the hash index of the column even if
+// scanning the remaining rows is estimated to be cheaper.
+func PreferIndex(column string) Hint {
+	return Hint{kind: hintIndex, column: column}
+}
+
+// PreferScan returns a hint which makes the planner scan the values of the column when
+// filtering them, rather than looking them up with the hash index of the column.
+func PreferScan(column string) Hint {
+	return Hint{kind: hintScan, column: column}
+}
+
+// Hint gives hints to the query planner for the filters of the transaction. The hints apply to
+// the filters which are added afterwards, until the transaction completes.
+func (txn *Txn) Hint(hints ...Hint) *Txn {
+	txn.hints = append(txn.hints, hints...)
+	return txn
+}
+
+// prefers returns whether a hint of the kind was given for the column
+func (txn *Txn) prefers(kind uint8, column string) bool {
+	for _, h := range txn.hints {
+		if h.kind == kind && h.column == column {
+			return true
+		}
+	}
+	return false
+}
+
+// applyHints returns the order of the filters with the ones on a preferred index moved first,
+// keeping their relative order. The order is returned as is if there are no such filters.
+func (txn *Txn) applyHints(order []int) []int {
+	if len(txn.hints) == 0 {
+		return order
+	}
+
+	return txn.hintOrder(order, func(i int) string {
+		return txn.filters[i].column
+	})
+}
+
+// hintOrder moves the filters on a preferred index first, given the column of each filter
+func (txn *Txn) hintOrder(order []int, columnOf func(i int) string) []int {
+	hinted := make([]int, 0, len(order))
+	for _, i := range order {
+		if txn.prefers(hintIndex, columnOf(i)) {
+			hinted = append(hinted, i)
+		}
+	}
+
+	if len(hinted) == 0 {
+		return order
+	}
+
+	for _, i := range order {
+		if !txn.prefers(hintIndex, columnOf(i)) {
+			hinted = append(hinted, i)
+		}
+	}
+	return hinted
+}
+
+// --------------------------- Plan Cache ----------------------------
+
+// plan represents an execution plan for a chain of filters
+type plan struct {
+	version     uint64    // The schema version at which the plan was made
+	count       int       // The number of rows at which the plan was made
+	order       []int     // The order in which the filters should be applied
+	selectivity []float64 // The fraction of the rows kept by each filter, when the plan was made
+}
+
+// planCache represents a cache of execution plans, keyed by the structure of the chain
+type planCache struct {
+	lock  sync.RWMutex
+	plans map[uint64]*plan
+}
+
+// Load loads a plan for a specific key. The plan is invalid if the schema has changed
+// since, or if the number of rows has changed significantly.
+func (c *planCache) Load(key, version uint64, count int) ([]int, bool) {
+	if p, ok := c.plan(key, version, count); ok {
+		return p.order, true
+	}
+	return nil, false
+}
+
+// plan loads a valid plan for a specific key, along with its measured selectivity
+func (c *planCache) plan(key, version uint64, count int) (*plan, bool) {
+	c.lock.RLock()
+	p, ok := c.plans[key]
+	c.lock.RUnlock()
+
+	switch {
+	case !ok || p.version != version:
+		return nil, false
+	case count > 2*p.count+chunkSize || p.count > 2*count+chunkSize:
+		return nil, false
+	default:
+		return p, true
+	}
+}
+
+// Clear removes all of the plans from the cache
+func (c *planCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.plans = nil
+}
+
+// Store stores a plan in the cache, evicting all of the plans when the cache is full.
+func (c *planCache) Store(key uint64, p *plan) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.plans == nil || len(c.plans) >= maxPlans {
+		c.plans = make(map[uint64]*plan, 16)
+	}
+
+	c.plans[key] = p
+}
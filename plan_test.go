@@ -0,0 +1,152 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanCache(t *testing.T) {
+	players := loadPlayers(500)
+	query := func() (calls int) {
+		players.Query(func(txn *Txn) error {
+			txn.WithString("class", func(v string) bool {
+				calls++
+				return true
+			}).WithString("race", func(v string) bool {
+				calls++
+				return v == "elf"
+			}).Count()
+			return nil
+		})
+		return
+	}
+
+	// First run makes a plan, second one should use the most selective filter first
+	first := query()
+	second := query()
+	assert.Equal(t, 1, len(players.plans.plans))
+	assert.Less(t, second, first)
+
+	// Schema change should invalidate the plan
+	version := players.cols.Version()
+	assert.NoError(t, players.CreateColumn("new", ForString()))
+	assert.NotEqual(t, version, players.cols.Version())
+	_, ok := players.plans.Load(planKey([]filter{
+		{cost: costTyped, column: "class"},
+		{cost: costTyped, column: "race"},
+	}), players.cols.Version(), players.Count())
+	assert.False(t, ok)
+	assert.Equal(t, first, query())
+}
+
+func TestPlanCacheEviction(t *testing.T) {
+	cache := planCache{}
+	for i := 0; i < maxPlans; i++ {
+		cache.Store(uint64(i), &plan{order: []int{0}})
+	}
+
+	_, ok := cache.Load(1, 0, 0)
+	assert.True(t, ok)
+
+	cache.Store(maxPlans, &plan{order: []int{0}})
+	_, ok = cache.Load(1, 0, 0)
+	assert.False(t, ok)
+
+	// Significant change of the row count should invalidate the plan
+	cache.Store(1, &plan{count: 100})
+	_, ok = cache.Load(1, 0, 100000)
+	assert.False(t, ok)
+}
+
+func TestPlanFirstRun(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("common", ForInt64())
+	coll.CreateColumn("rare", ForInt64())
+	coll.Query(func(txn *Txn) error {
+		for i := 0; i < 3*chunkSize; i++ {
+			txn.InsertObject(Object{"common": int64(i), "rare": int64(i % 100)})
+		}
+		return nil
+	})
+
+	// The naive order applies the unselective filter first, but only on the first chunk
+	var common, rare int
+	coll.Query(func(txn *Txn) error {
+		txn.WithInt("common", func(v int64) bool {
+			common++
+			return true
+		}).WithInt("rare", func(v int64) bool {
+			rare++
+			return v == 0
+		}).Count()
+		return nil
+	})
+
+	assert.Equal(t, 3*chunkSize, rare)
+	assert.Less(t, common, chunkSize+chunkSize/10)
+}
+
+func TestPlanHints(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("class", ForString())
+	coll.CreateColumn("race", ForString())
+	for i := 0; i < 100; i++ {
+		coll.InsertObject(Object{"class": "mage", "race": []string{"elf", "human"}[i%2]})
+	}
+
+	query := func(hints ...Hint) (class, race int) {
+		coll.Query(func(txn *Txn) error {
+			txn.Hint(hints...).WithString("class", func(v string) bool {
+				class++
+				return true
+			}).WithString("race", func(v string) bool {
+				race++
+				return v == "elf"
+			}).Count()
+			return nil
+		})
+		return
+	}
+
+	// The plan applies the most selective filter first, unless the hint says otherwise
+	query()
+	class, race := query()
+	assert.Equal(t, 50, class)
+	assert.Equal(t, 100, race)
+
+	class, race = query(PreferIndex("class"))
+	assert.Equal(t, 100, class)
+	assert.Equal(t, 100, race)
+
+	// The hints are cleared once the transaction completes
+	class, _ = query()
+	assert.Equal(t, 50, class)
+}
+
+func TestPlanHintsEqual(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.CreateHashIndex("race"))
+
+	// With the hash index, the lookup is not deferred unless scanning is preferred
+	var indexed, scanned int
+	players.Query(func(txn *Txn) error {
+		txn.WithEqual("race", "elf")
+		assert.Len(t, txn.filters, 0)
+		indexed = txn.Count()
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		txn.Hint(PreferScan("race")).WithEqual("race", "elf")
+		assert.Len(t, txn.filters, 1)
+		scanned = txn.Count()
+		return nil
+	})
+
+	assert.NotZero(t, indexed)
+	assert.Equal(t, indexed, scanned)
+}
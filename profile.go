@@ -0,0 +1,120 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Profiler represents a tracer which aggregates the read amplification of the transactions,
+// that is how many rows and bytes were scanned by the filters compared to the number of rows
+// returned. The filters are aggregated by operation and column, so the most wasteful ones
+// can be identified and replaced, for example, by an index.
+type Profiler struct {
+	lock    sync.Mutex
+	profile Profile
+	filters map[filterKey]*FilterProfile
+}
+
+// Profile represents the read amplification aggregated by the profiler
+type Profile struct {
+	Queries  int             // The number of transactions profiled
+	Scanned  int             // The number of rows examined by the filters
+	Returned int             // The number of rows iterated over by the transactions
+	Bytes    int             // The approximate number of bytes read by the filters
+	Filters  []FilterProfile // The filters, from the most to the least bytes read
+}
+
+// Amplification returns the number of rows scanned per row returned
+func (p *Profile) Amplification() float64 {
+	if p.Returned == 0 {
+		return 0
+	}
+	return float64(p.Scanned) / float64(p.Returned)
+}
+
+// FilterProfile represents the aggregated work of a filter on a column
+type FilterProfile struct {
+	Operation string        // The name of the operation, such as "With" or "WithFloat"
+	Column    string        // The column or index on which the operation was applied
+	Count     int           // The number of times the filter was applied
+	Input     int           // The number of rows examined by the filter
+	Output    int           // The number of rows kept by the filter
+	Bytes     int           // The approximate number of bytes read by the filter
+	Duration  time.Duration // The time spent on the filter
+}
+
+// Selectivity returns the fraction of the rows which were kept by the filter
+func (f *FilterProfile) Selectivity() float64 {
+	if f.Input == 0 {
+		return 0
+	}
+	return float64(f.Output) / float64(f.Input)
+}
+
+// filterKey represents the key of a filter in the profile
+type filterKey struct {
+	operation, column string
+}
+
+// NewProfiler creates a new profiler, which should be configured as the tracer of one or
+// several collections.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		filters: make(map[filterKey]*FilterProfile, 8),
+	}
+}
+
+// TraceQuery aggregates the trace of a transaction
+func (p *Profiler) TraceQuery(trace *QueryTrace) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.profile.Queries++
+	p.profile.Returned += trace.Returned
+	for _, step := range trace.Steps {
+		p.profile.Scanned += step.Input
+		p.profile.Bytes += step.Bytes
+
+		key := filterKey{operation: step.Operation, column: step.Column}
+		filter, ok := p.filters[key]
+		if !ok {
+			filter = &FilterProfile{Operation: step.Operation, Column: step.Column}
+			p.filters[key] = filter
+		}
+
+		filter.Count++
+		filter.Input += step.Input
+		filter.Output += step.Output
+		filter.Bytes += step.Bytes
+		filter.Duration += step.Duration
+	}
+}
+
+// Profile returns the read amplification aggregated so far
+func (p *Profiler) Profile() Profile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	out := p.profile
+	out.Filters = make([]FilterProfile, 0, len(p.filters))
+	for _, filter := range p.filters {
+		out.Filters = append(out.Filters, *filter)
+	}
+
+	sort.Slice(out.Filters, func(i, j int) bool {
+		return out.Filters[i].Bytes > out.Filters[j].Bytes
+	})
+	return out
+}
+
+// Reset discards the read amplification aggregated so far
+func (p *Profiler) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.profile = Profile{}
+	p.filters = make(map[filterKey]*FilterProfile, 8)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	profiler := NewProfiler()
+	c := NewCollection(Options{
+		Tracer: profiler,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("rogue", ForBool())
+	c.CreateColumn("balance", ForFloat64())
+	for i := 0; i < 100; i++ {
+		c.InsertObject(Object{"rogue": i%2 == 0, "balance": float64(i)})
+	}
+
+	// Run the same query twice
+	profiler.Reset()
+	for i := 0; i < 2; i++ {
+		c.Query(func(txn *Txn) error {
+			return txn.With("rogue").WithFloat("balance", func(v float64) bool {
+				return v < 20
+			}).Range(func(idx uint32) {})
+		})
+	}
+
+	profile := profiler.Profile()
+	assert.Equal(t, 2, profile.Queries)
+	assert.Equal(t, 300, profile.Scanned)
+	assert.Equal(t, 20, profile.Returned)
+	assert.Equal(t, 15.0, profile.Amplification())
+	assert.Greater(t, profile.Bytes, 0)
+
+	// The filters are ordered by the bytes read
+	assert.Len(t, profile.Filters, 2)
+	assert.GreaterOrEqual(t, profile.Filters[0].Bytes, profile.Filters[1].Bytes)
+	for _, f := range profile.Filters {
+		assert.Equal(t, 2, f.Count)
+		switch f.Operation {
+		case "With":
+			assert.Equal(t, "rogue", f.Column)
+			assert.Equal(t, 200, f.Input)
+			assert.Equal(t, 0.5, f.Selectivity())
+		case "WithFloat":
+			assert.Equal(t, "balance", f.Column)
+			assert.Equal(t, 100, f.Input)
+			assert.Equal(t, 0.2, f.Selectivity())
+		default:
+			t.Fatalf("unexpected filter %s", f.Operation)
+		}
+	}
+
+	// Reset the profile
+	profiler.Reset()
+	assert.Equal(t, Profile{Filters: []FilterProfile{}}, profiler.Profile())
+}
+
+func TestProfileEmpty(t *testing.T) {
+	assert.Equal(t, 0.0, new(Profile).Amplification())
+	assert.Equal(t, 0.0, new(FilterProfile).Selectivity())
+}
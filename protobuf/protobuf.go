@@ -0,0 +1,255 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package protobuf maps the protobuf messages onto the rows of a collection, so that the
+// services which already speak protobuf do not need to map their messages to objects by hand.
+// The schema of a collection is created from a message descriptor, with one column per field
+// named after it, and the messages are inserted and read back as rows.
+//
+// The scalar fields are stored in the columns of the matching type, and the enums by the name
+// of their values. The messages, lists and maps are stored as JSON documents, in the canonical
+// JSON mapping of protobuf, so that they can still be filtered with WithJSONPath(). The fields
+// without presence, such as the scalars of proto3, are always stored, while the unset fields
+// with presence are stored as missing values.
+package protobuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/kelindar/column"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CreateColumns creates a column for every field of the message descriptor, named after the
+// field. The columns which already exist are left as is.
+func CreateColumns(c *column.Collection, desc protoreflect.MessageDescriptor) error {
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if _, ok := c.KindOf(string(fd.Name())); ok {
+			continue
+		}
+
+		if err := c.CreateColumn(string(fd.Name()), columnFor(fd)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnFor returns a column which can store the values of a field
+func columnFor(fd protoreflect.FieldDescriptor) column.Column {
+	if fd.IsList() || fd.IsMap() {
+		return column.ForJSON()
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return column.ForBool()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return column.ForInt32()
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return column.ForInt64()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return column.ForUint32()
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return column.ForUint64()
+	case protoreflect.FloatKind:
+		return column.ForFloat32()
+	case protoreflect.DoubleKind:
+		return column.ForFloat64()
+	case protoreflect.StringKind:
+		return column.ForString()
+	case protoreflect.BytesKind:
+		return column.ForBytes()
+	case protoreflect.EnumKind:
+		return column.ForEnum()
+	default:
+		return column.ForJSON()
+	}
+}
+
+// --------------------------- Encoding ----------------------------
+
+// Insert inserts the message as a new row and returns its offset. The collection must have
+// a column for every field which is set, for example created with CreateColumns().
+func Insert(c *column.Collection, msg proto.Message) (uint32, error) {
+	obj, err := ObjectOf(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.InsertStrict(obj)
+}
+
+// ObjectOf converts the fields of the message into an object, keyed by the names of the fields
+func ObjectOf(msg proto.Message) (column.Object, error) {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	obj := make(column.Object, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) && (fd.HasPresence() || fd.IsList() || fd.IsMap()) {
+			continue
+		}
+
+		value, err := encode(m, fd)
+		if err != nil {
+			return nil, err
+		}
+
+		obj[string(fd.Name())] = value
+	}
+	return obj, nil
+}
+
+// encode returns the value of a field, as stored in its column
+func encode(m protoreflect.Message, fd protoreflect.FieldDescriptor) (any, error) {
+	switch {
+	case fd.IsList() || fd.IsMap() || fd.Message() != nil:
+		return encodeJSON(m, fd)
+	case fd.Kind() == protoreflect.EnumKind:
+		number := m.Get(fd).Enum()
+		if value := fd.Enum().Values().ByNumber(number); value != nil {
+			return string(value.Name()), nil
+		}
+		return strconv.Itoa(int(number)), nil
+	default:
+		return m.Get(fd).Interface(), nil
+	}
+}
+
+// encodeJSON encodes the value of a composite field in the JSON mapping of protobuf. The field
+// is copied into an empty message which is then encoded, since only the messages themselves
+// can be encoded.
+func encodeJSON(m protoreflect.Message, fd protoreflect.FieldDescriptor) (any, error) {
+	tmp := m.New()
+	tmp.Set(fd, m.Get(fd))
+	encoded, err := protojson.Marshal(tmp.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("column: unable to encode field '%s', %w", fd.Name(), err)
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, fmt.Errorf("column: unable to encode field '%s', %w", fd.Name(), err)
+	}
+
+	if value, ok := document[fd.JSONName()]; ok {
+		return value, nil
+	}
+	return json.RawMessage("null"), nil
+}
+
+// --------------------------- Decoding ----------------------------
+
+// Read reads the row at the offset into the message, which is reset beforehand. The fields
+// without a column or without a value are left unset. It returns false if the row does not
+// exist.
+func Read(c *column.Collection, idx uint32, dst proto.Message) (found bool, err error) {
+	proto.Reset(dst)
+	m := dst.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	found = c.ReadAt(idx, func(row column.Selector) {
+		for i := 0; i < fields.Len() && err == nil; i++ {
+			fd := fields.Get(i)
+			if v, ok := row.Any(string(fd.Name())); ok {
+				err = decode(m, fd, v)
+			}
+		}
+	})
+	return
+}
+
+// Marshal reads the row at the offset into a message of the same type as the template and
+// encodes it in the protobuf wire format. It returns an error if the row does not exist.
+func Marshal(c *column.Collection, idx uint32, template proto.Message) ([]byte, error) {
+	msg := template.ProtoReflect().New().Interface()
+	found, err := Read(c, idx, msg)
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, fmt.Errorf("column: unable to marshal, row %d does not exist", idx)
+	default:
+		return proto.Marshal(msg)
+	}
+}
+
+// decode sets the field of the message from the value stored in its column
+func decode(m protoreflect.Message, fd protoreflect.FieldDescriptor, value any) error {
+	switch {
+	case fd.IsList() || fd.IsMap() || fd.Message() != nil:
+		return decodeJSON(m, fd, value)
+	case fd.Kind() == protoreflect.EnumKind:
+		name, _ := value.(string)
+		if v := fd.Enum().Values().ByName(protoreflect.Name(name)); v != nil {
+			m.Set(fd, protoreflect.ValueOfEnum(v.Number()))
+			return nil
+		}
+
+		number, err := strconv.Atoi(name)
+		if err != nil {
+			return fmt.Errorf("column: unable to decode field '%s', unknown enum value '%s'", fd.Name(), name)
+		}
+		m.Set(fd, protoreflect.ValueOfEnum(protoreflect.EnumNumber(number)))
+		return nil
+	case fd.Kind() == protoreflect.BytesKind:
+		b, _ := value.([]byte)
+		m.Set(fd, protoreflect.ValueOfBytes(append([]byte(nil), b...)))
+		return nil
+	default:
+		if !isKind(fd, value) {
+			return fmt.Errorf("column: unable to decode field '%s', unexpected %T", fd.Name(), value)
+		}
+
+		m.Set(fd, protoreflect.ValueOf(value))
+		return nil
+	}
+}
+
+// decodeJSON decodes the value of a composite field from its JSON mapping
+func decodeJSON(m protoreflect.Message, fd protoreflect.FieldDescriptor, value any) error {
+	document, err := json.Marshal(map[string]any{fd.JSONName(): value})
+	if err != nil {
+		return fmt.Errorf("column: unable to decode field '%s', %w", fd.Name(), err)
+	}
+
+	tmp := m.New()
+	if err := protojson.Unmarshal(document, tmp.Interface()); err != nil {
+		return fmt.Errorf("column: unable to decode field '%s', %w", fd.Name(), err)
+	}
+
+	if tmp.Has(fd) {
+		m.Set(fd, tmp.Get(fd))
+	}
+	return nil
+}
+
+// isKind returns whether the value has the Go type of the scalar field
+func isKind(fd protoreflect.FieldDescriptor, value any) bool {
+	switch value.(type) {
+	case bool:
+		return fd.Kind() == protoreflect.BoolKind
+	case int32:
+		return fd.Kind() == protoreflect.Int32Kind || fd.Kind() == protoreflect.Sint32Kind || fd.Kind() == protoreflect.Sfixed32Kind
+	case int64:
+		return fd.Kind() == protoreflect.Int64Kind || fd.Kind() == protoreflect.Sint64Kind || fd.Kind() == protoreflect.Sfixed64Kind
+	case uint32:
+		return fd.Kind() == protoreflect.Uint32Kind || fd.Kind() == protoreflect.Fixed32Kind
+	case uint64:
+		return fd.Kind() == protoreflect.Uint64Kind || fd.Kind() == protoreflect.Fixed64Kind
+	case float32:
+		return fd.Kind() == protoreflect.FloatKind
+	case float64:
+		return fd.Kind() == protoreflect.DoubleKind
+	case string:
+		return fd.Kind() == protoreflect.StringKind
+	default:
+		return false
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package protobuf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kelindar/column"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestCreateColumns(t *testing.T) {
+	desc := playerDescriptor(t)
+	players := column.NewCollection()
+	assert.NoError(t, CreateColumns(players, desc))
+	assert.NoError(t, CreateColumns(players, desc))
+
+	for name, kind := range map[string]reflect.Kind{
+		"name":    reflect.String,
+		"age":     reflect.Int32,
+		"balance": reflect.Float64,
+		"active":  reflect.Bool,
+		"role":    reflect.String,
+		"tags":    reflect.Interface,
+		"address": reflect.Interface,
+	} {
+		actual, ok := players.KindOf(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, kind, actual, name)
+	}
+}
+
+func TestInsertRead(t *testing.T) {
+	desc := playerDescriptor(t)
+	players := column.NewCollection()
+	assert.NoError(t, CreateColumns(players, desc))
+
+	// Insert a message with all of the fields set
+	msg := newPlayer(desc, map[string]any{
+		"name":    "Merlin",
+		"age":     int32(120),
+		"balance": 99.5,
+		"active":  true,
+		"avatar":  []byte{1, 2, 3},
+		"role":    protoreflect.EnumNumber(1),
+		"tags":    []string{"wizard", "mentor"},
+		"address": "Camelot",
+	})
+
+	idx, err := Insert(players, msg)
+	assert.NoError(t, err)
+
+	// The values can be queried as any other column
+	assert.NoError(t, players.QueryAt(idx, func(r column.Row) error {
+		role, _ := r.Enum("role")
+		assert.Equal(t, "ADMIN", role)
+
+		tags, _ := r.Any("tags")
+		assert.JSONEq(t, `["wizard", "mentor"]`, string(tags.(json.RawMessage)))
+		address, _ := r.Any("address")
+		assert.JSONEq(t, `{"city": "Camelot"}`, string(address.(json.RawMessage)))
+		return nil
+	}))
+
+	// Read the row back into a message
+	out := dynamicpb.NewMessage(desc)
+	found, err := Read(players, idx, out)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, proto.Equal(msg, out))
+
+	// Serialize the row in the wire format
+	encoded, err := Marshal(players, idx, msg)
+	assert.NoError(t, err)
+	decoded := dynamicpb.NewMessage(desc)
+	assert.NoError(t, proto.Unmarshal(encoded, decoded))
+	assert.True(t, proto.Equal(msg, decoded))
+
+	_, err = Marshal(players, 99, msg)
+	assert.Error(t, err)
+}
+
+func TestInsertDefaults(t *testing.T) {
+	desc := playerDescriptor(t)
+	players := column.NewCollection()
+	assert.NoError(t, CreateColumns(players, desc))
+
+	// The scalars without presence are stored even with their default value
+	idx, err := Insert(players, newPlayer(desc, map[string]any{"name": "Roman"}))
+	assert.NoError(t, err)
+	assert.NoError(t, players.QueryAt(idx, func(r column.Row) error {
+		age, ok := r.Int32("age")
+		assert.True(t, ok)
+		assert.Equal(t, int32(0), age)
+
+		_, ok = r.Any("tags")
+		assert.False(t, ok)
+		_, ok = r.Any("address")
+		assert.False(t, ok)
+		return nil
+	}))
+
+	// The fields without a column are rejected
+	strict := column.NewCollection()
+	strict.CreateColumn("name", column.ForString())
+	_, err = Insert(strict, newPlayer(desc, map[string]any{"name": "Roman"}))
+	assert.ErrorIs(t, err, column.ErrMissingColumns)
+}
+
+// playerDescriptor builds the descriptor of a message equivalent to
+//
+//	enum Role { MEMBER = 0; ADMIN = 1; }
+//	message Address { string city = 1; }
+//	message Player {
+//	  string name = 1; int32 age = 2; double balance = 3; bool active = 4;
+//	  bytes avatar = 5; Role role = 6; repeated string tags = 7; Address address = 8;
+//	}
+func playerDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     kind.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	tags := field("tags", 7, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("player.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Role"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("MEMBER"), Number: proto.Int32(0)},
+				{Name: proto.String("ADMIN"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Address"),
+			Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+		}, {
+			Name: proto.String("Player"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("age", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("balance", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+				field("active", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				field("avatar", 5, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+				field("role", 6, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Role"),
+				tags,
+				field("address", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Address"),
+			},
+		}},
+	}, nil)
+	assert.NoError(t, err)
+	return file.Messages().ByName("Player")
+}
+
+// newPlayer creates a player message with the specified fields set
+func newPlayer(desc protoreflect.MessageDescriptor, values map[string]any) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(desc)
+	for name, value := range values {
+		fd := desc.Fields().ByName(protoreflect.Name(name))
+		switch v := value.(type) {
+		case []string:
+			list := msg.Mutable(fd).List()
+			for _, s := range v {
+				list.Append(protoreflect.ValueOfString(s))
+			}
+		case protoreflect.EnumNumber:
+			msg.Set(fd, protoreflect.ValueOfEnum(v))
+		default:
+			if fd.Message() != nil {
+				address := msg.Mutable(fd).Message()
+				address.Set(fd.Message().Fields().ByName("city"), protoreflect.ValueOfString(v.(string)))
+				continue
+			}
+			msg.Set(fd, protoreflect.ValueOf(v))
+		}
+	}
+	return msg
+}
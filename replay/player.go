@@ -0,0 +1,146 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package replay
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/iostream"
+)
+
+// ErrStop can be returned by a hook in order to stop the replay without an error
+var ErrStop = errors.New("column: replay stopped")
+
+// Entry represents a recorded commit
+type Entry struct {
+	Seq    int           // The sequence number of the entry in the recording, starting at zero
+	Time   time.Time     // The time at which the commit was recorded
+	Commit commit.Commit // The recorded commit
+}
+
+// Options represents the options of a player
+type Options struct {
+	Speed  float64           // The speed relative to the recording, or zero to replay as fast as possible
+	Before func(Entry) error // The hook invoked before an entry is applied (optional)
+	After  func(Entry) error // The hook invoked after an entry is applied (optional)
+}
+
+// Player represents a player which replays a recording against a collection. The player is
+// also a clock which follows the time of the recording, so that a collection created with
+// it as its clock expires the rows with a time-to-live exactly as the recorded one did.
+type Player struct {
+	opts  Options                                          // The options of the player
+	now   int64                                            // The time of the current entry, in nanoseconds
+	sleep func(ctx context.Context, d time.Duration) error // The function which waits between the entries
+}
+
+// NewPlayer creates a new player, for example
+//
+//	player := replay.NewPlayer(replay.Options{Speed: 10})
+//	players := column.NewCollection(column.Options{
+//		Clock: player,
+//	})
+//
+//	err := player.Play(ctx, recording, players)
+func NewPlayer(opts Options) *Player {
+	return &Player{
+		opts:  opts,
+		sleep: sleep,
+	}
+}
+
+// Now returns the time of the entry being replayed
+func (p *Player) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&p.now))
+}
+
+// Play replays the recording from the source against the collection, applying the commits in
+// the order they were recorded. It stops at the end of the recording, when the context is
+// cancelled or when a hook returns an error.
+func (p *Player) Play(ctx context.Context, src io.Reader, collection *column.Collection) error {
+	err := Range(src, func(entry Entry) error {
+		if err := p.wait(ctx, entry); err != nil {
+			return err
+		}
+
+		if p.opts.Before != nil {
+			if err := p.opts.Before(entry); err != nil {
+				return err
+			}
+		}
+
+		if err := collection.Replay(entry.Commit); err != nil {
+			return err
+		}
+
+		if p.opts.After != nil {
+			return p.opts.After(entry)
+		}
+		return nil
+	})
+
+	if err == ErrStop {
+		return nil
+	}
+	return err
+}
+
+// Range iterates over the entries of a recording from the source, without applying them.
+func Range(src io.Reader, fn func(Entry) error) error {
+	reader := iostream.NewReader(bufio.NewReader(src))
+	for seq := 0; ; seq++ {
+		at, err := reader.ReadInt64()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		var change commit.Commit
+		if _, err := change.ReadFrom(reader); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		if err := fn(Entry{Seq: seq, Time: time.Unix(0, at), Commit: change}); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits for the time elapsed between the previous entry and the next one, scaled by the
+// speed of the player, and then moves the clock of the player forward.
+func (p *Player) wait(ctx context.Context, next Entry) error {
+	defer atomic.StoreInt64(&p.now, next.Time.UnixNano())
+	if next.Seq > 0 && p.opts.Speed > 0 {
+		if elapsed := next.Time.Sub(p.Now()); elapsed > 0 {
+			return p.sleep(ctx, time.Duration(float64(elapsed)/p.opts.Speed))
+		}
+	}
+
+	return ctx.Err()
+}
+
+// sleep waits for the duration, or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package replay provides a harness to reproduce the state of a collection deterministically.
+// A recorder captures every commit of a collection along with the time at which it happened,
+// for example in production, while a player replays the recording against a fresh collection
+// in the same order, at a configurable speed and with hooks invoked between the commits in
+// order to inspect the state or to stop right before an incident.
+package replay
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/iostream"
+)
+
+// Recorder represents a commit writer which records every commit of a collection along with
+// its timestamp, and optionally forwards it to another writer. It must be configured as the
+// writer of the collection, for example
+//
+//	recorder := replay.NewRecorder(file, nil)
+//	players := column.NewCollection(column.Options{
+//		Writer: recorder,
+//	})
+type Recorder struct {
+	lock   sync.Mutex       // The lock to write the entries one at a time
+	dst    io.Writer        // The destination of the recording
+	next   commit.Logger    // The writer which receives the commits afterwards (optional)
+	buffer bytes.Buffer     // The buffer of the current entry
+	now    func() time.Time // The source of the timestamps
+}
+
+// NewRecorder creates a recorder which writes the commits into the destination, and then
+// forwards them to the next writer, if specified.
+func NewRecorder(dst io.Writer, next commit.Logger) *Recorder {
+	return &Recorder{
+		dst:  dst,
+		next: next,
+		now:  time.Now,
+	}
+}
+
+// Append records the commit along with the current time and forwards it to the next writer.
+// Every entry is written into the destination at once, so that a recording which is cut short
+// still contains the complete entries.
+func (r *Recorder) Append(change commit.Commit) error {
+	if err := r.record(change); err != nil {
+		return err
+	}
+
+	if r.next != nil {
+		return r.next.Append(change)
+	}
+	return nil
+}
+
+// record writes an entry into the destination
+func (r *Recorder) record(change commit.Commit) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.buffer.Reset()
+	writer := iostream.NewWriter(&r.buffer)
+	if err := writer.WriteInt64(r.now().UnixNano()); err != nil {
+		return err
+	}
+
+	if _, err := change.WriteTo(writer); err != nil {
+		return err
+	}
+
+	_, err := r.dst.Write(r.buffer.Bytes())
+	return err
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	recording, store := record(t)
+
+	// Replay the recording, inspecting the state between the commits
+	var counts []int
+	var times []time.Time
+	player := NewPlayer(Options{})
+	players := newCollection(player)
+	assert.NoError(t, player.Play(context.Background(), bytes.NewReader(recording), players))
+	assert.Equal(t, 2, players.Count())
+
+	player = NewPlayer(Options{
+		Before: func(e Entry) error {
+			times = append(times, player.Now())
+			return nil
+		},
+		After: func(e Entry) error {
+			counts = append(counts, players.Count())
+			assert.Equal(t, e.Time, player.Now())
+			return nil
+		},
+	})
+	players = newCollection(player)
+	assert.NoError(t, player.Play(context.Background(), bytes.NewReader(recording), players))
+	assert.Equal(t, []int{1, 2, 3, 2}, counts)
+	assert.Equal(t, time.Unix(0, 0), times[0])
+	assert.Equal(t, time.Unix(100, 0), times[1])
+
+	// The commits were forwarded to the next writer as well
+	n := 0
+	assert.NoError(t, store.Range(0, func(commit.Commit) error { n++; return nil }))
+	assert.Equal(t, 4, n)
+}
+
+func TestReplayStop(t *testing.T) {
+	recording, _ := record(t)
+
+	// Stop right before the deletion
+	player := NewPlayer(Options{
+		Before: func(e Entry) error {
+			if e.Seq == 3 {
+				return ErrStop
+			}
+			return nil
+		},
+	})
+
+	players := newCollection(player)
+	assert.NoError(t, player.Play(context.Background(), bytes.NewReader(recording), players))
+	assert.Equal(t, 3, players.Count())
+
+	// Other errors are returned
+	failure := errors.New("failure")
+	player = NewPlayer(Options{After: func(e Entry) error { return failure }})
+	assert.Equal(t, failure, player.Play(context.Background(), bytes.NewReader(recording), newCollection(player)))
+
+	// Recordings which are cut short
+	player = NewPlayer(Options{})
+	err := player.Play(context.Background(), bytes.NewReader(recording[:len(recording)-1]), newCollection(player))
+	assert.Error(t, err)
+}
+
+func TestReplaySpeed(t *testing.T) {
+	recording, _ := record(t)
+
+	var waits []time.Duration
+	player := NewPlayer(Options{Speed: 10})
+	player.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	assert.NoError(t, player.Play(context.Background(), bytes.NewReader(recording), newCollection(player)))
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second}, waits)
+
+	// The replay stops once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	player = NewPlayer(Options{Speed: 1})
+	assert.Equal(t, context.Canceled, player.Play(ctx, bytes.NewReader(recording), newCollection(player)))
+	assert.Equal(t, context.Canceled, sleep(ctx, time.Hour))
+	assert.NoError(t, sleep(context.Background(), time.Millisecond))
+}
+
+// record records a few commits of a collection, 100 seconds apart except for the last one
+func record(t *testing.T) ([]byte, *commit.MemoryStore) {
+	buffer := bytes.NewBuffer(nil)
+	store := commit.NewMemoryStore()
+	recorder := NewRecorder(buffer, store)
+
+	clock := []time.Time{time.Unix(0, 0), time.Unix(100, 0), time.Unix(300, 0), time.Unix(300, 0)}
+	recorder.now = func() time.Time {
+		now := clock[0]
+		clock = clock[1:]
+		return now
+	}
+
+	players := column.NewCollection(column.Options{Writer: recorder})
+	players.CreateColumn("name", column.ForString())
+	defer players.Close()
+
+	players.InsertObject(column.Object{"name": "Roman"})
+	players.InsertObject(column.Object{"name": "Merlin"})
+	players.InsertObject(column.Object{"name": "Arthur"})
+	players.DeleteAt(1)
+	return buffer.Bytes(), store
+}
+
+// newCollection creates a fresh collection which follows the clock of the player
+func newCollection(player *Player) *column.Collection {
+	players := column.NewCollection(column.Options{Clock: player})
+	players.CreateColumn("name", column.ForString())
+	return players
+}
+
+// failingWriter represents a writer which always fails
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestRecorderFailure(t *testing.T) {
+	recorder := NewRecorder(failingWriter{}, nil)
+	assert.Error(t, recorder.Append(commit.Commit{ID: 1}))
+	assert.NoError(t, NewRecorder(io.Discard, nil).Append(commit.Commit{ID: 1}))
+}
@@ -0,0 +1,317 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/kelindar/column/commit"
+)
+
+// replicaQueue is the size of the changefeed queue of a replica. A replica which falls
+// behind by more than this number of commits is marked as stale and catches up with a
+// fresh snapshot, so that it never holds back the commits of the primary.
+const replicaQueue = 4096
+
+// ReplicaOptions represents the options of a replica.
+type ReplicaOptions struct {
+	Queue    int                   // The number of pending commits retained for the replica (default: 4096)
+	Progress func(ReplicaProgress) // The callback which is notified as the replica catches up (optional)
+}
+
+// ReplicaStage represents a stage of the synchronization of a replica
+type ReplicaStage uint8
+
+// Various stages of the synchronization of a replica
+const (
+	ReplicaLagging   ReplicaStage = iota // The replica fell behind the changefeed and needs a snapshot
+	ReplicaRestoring                     // The snapshot was taken and is being restored
+	ReplicaStreaming                     // The replica is up to date and follows the changefeed
+)
+
+// ReplicaProgress represents the progress of the synchronization of a replica
+type ReplicaProgress struct {
+	Stage ReplicaStage // The current stage of the synchronization
+	Bytes int          // The size of the snapshot being restored, in bytes
+	Err   error        // The error which prevented the snapshot from being restored, if any
+}
+
+// Replica creates a read-optimized copy of the collection with the same schema and data,
+// which is kept up to date asynchronously by following the changefeed of the collection.
+// This allows heavy analytical scans to run on the replica without contending with the
+// write path of the primary. The replica should be treated as read-only and closed once
+// it is no longer needed, which stops the replication.
+func (c *Collection) Replica() (*Collection, error) {
+	return c.ReplicaWith(ReplicaOptions{})
+}
+
+// ReplicaWith creates a replica of the collection with the specified options. The primary
+// never waits for the replica. Instead, once the queue is full, the commit is dropped and the
+// replica is marked as stale, after which it discards the pending commits, restores a fresh
+// snapshot of the primary and resumes streaming from there. The rows of the replica are reset
+// while the snapshot is restored, so the queries running on the replica meanwhile may observe
+// a partial state.
+func (c *Collection) ReplicaWith(opts ReplicaOptions) (*Collection, error) {
+	if opts.Queue <= 0 {
+		opts.Queue = replicaQueue
+	}
+
+	replica, err := c.cloneSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	// Subscribe to the changefeed before copying the state, so that no commit is missed
+	feed := &replicaFeed{
+		queue:  make(chan commit.Commit, opts.Queue),
+		resync: make(chan struct{}, 1),
+	}
+	c.subscribe(feed)
+
+	// Start draining the changefeed right away, since the commits need to be buffered while
+	// the state is being copied. Otherwise a full queue would block the committers.
+	ready := make(chan []uint64, 1)
+	go replica.follow(c, feed, ready, opts.Progress)
+
+	// Copy the current state of the collection into the replica
+	commits, err := replica.restoreFrom(c, opts.Progress)
+	if err != nil {
+		replica.Close()
+		return nil, err
+	}
+
+	ready <- commits
+	return replica, nil
+}
+
+// restoreFrom copies the current state of the primary collection into this one
+func (c *Collection) restoreFrom(primary *Collection, progress func(ReplicaProgress)) ([]uint64, error) {
+	buffer := bytes.NewBuffer(nil)
+	if _, err := primary.writeState(buffer); err != nil {
+		return nil, err
+	}
+
+	notify(progress, ReplicaProgress{Stage: ReplicaRestoring, Bytes: buffer.Len()})
+	return c.readState(buffer)
+}
+
+// catchUp resets the collection to a fresh snapshot of the primary, once it fell behind
+// the changefeed. It returns the commit IDs of the chunks in the snapshot.
+func (c *Collection) catchUp(primary *Collection, feed *replicaFeed, progress func(ReplicaProgress)) ([]uint64, error) {
+	notify(progress, ReplicaProgress{Stage: ReplicaLagging})
+
+	// Discard the pending commits, since they are part of the snapshot, and resume queueing
+	// the new commits before the snapshot is taken so that none of them is missed.
+	for drained := false; !drained; {
+		select {
+		case <-feed.queue:
+		default:
+			drained = true
+		}
+	}
+	atomic.StoreInt32(&feed.behind, 0)
+
+	// Remove all of the rows, and restore the snapshot
+	c.Query(func(txn *Txn) error {
+		txn.DeleteAll()
+		return nil
+	})
+	return c.restoreFrom(primary, progress)
+}
+
+// notify invokes the progress callback, if any
+func notify(progress func(ReplicaProgress), p ReplicaProgress) {
+	if progress != nil {
+		progress(p)
+	}
+}
+
+// follow applies the commits of the changefeed onto the collection, until the collection is
+// closed. The commits that were already part of the copied state are skipped. If the
+// collection falls behind the changefeed, it catches up by restoring a fresh snapshot.
+func (c *Collection) follow(primary *Collection, feed *replicaFeed, ready <-chan []uint64, progress func(ReplicaProgress)) {
+	defer primary.unsubscribe(feed)
+
+	// Buffer the commits until the state has been copied
+	var pending []commit.Commit
+	var commits []uint64
+	for commits == nil {
+		select {
+		case <-c.ctx.Done():
+			return
+		case change := <-feed.queue:
+			pending = append(pending, change)
+		case commits = <-ready:
+		}
+	}
+
+	// Only apply the commit if it is newer than the copied state of the chunk
+	apply := func(change commit.Commit) {
+		if int(change.Chunk) >= len(commits) || change.ID > commits[change.Chunk] {
+			c.Replay(change)
+		}
+	}
+
+	for _, change := range pending {
+		apply(change)
+	}
+
+	notify(progress, ReplicaProgress{Stage: ReplicaStreaming})
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case change := <-feed.queue:
+			apply(change)
+		case <-feed.resync:
+			restored, err := c.catchUp(primary, feed, progress)
+			if err != nil {
+				notify(progress, ReplicaProgress{Stage: ReplicaLagging, Err: err})
+				feed.retry()
+				continue
+			}
+
+			commits = restored
+			notify(progress, ReplicaProgress{Stage: ReplicaStreaming})
+		}
+	}
+}
+
+// cloneSchema creates a new, empty collection with the same columns and indexes.
+func (c *Collection) cloneSchema() (*Collection, error) {
+	clone := NewCollection(c.opts)
+	clone.logger = nil
+	clone.metrics = nil
+	clone.tracer = nil
+
+	// Create the columns first, since indexes depend on them
+	if err := c.cols.RangeUntil(func(column *column) error {
+		if column.IsIndex() {
+			return nil
+		}
+
+		if _, ok := clone.cols.Load(column.name); ok {
+			return nil // Already created, e.g. the expiration column
+		}
+
+		empty, ok := column.Column.(Factory)
+		if !ok {
+			return fmt.Errorf("column: unable to copy column '%s' of type %T", column.name, column.Column)
+		}
+
+		return clone.CreateColumn(column.name, empty.MakeEmpty(), column.opts.copyTo)
+	}); err != nil {
+		clone.Close()
+		return nil, err
+	}
+
+	// Create the indexes with the same rules
+	if err := c.cols.RangeUntil(func(column *column) error {
+		switch index := column.Column.(type) {
+		case *columnIndex:
+			return clone.CreateIndex(column.name, index.name, index.rule)
+		case *columnHash:
+			return clone.CreateHashIndex(index.name)
+		}
+		return nil
+	}); err != nil {
+		clone.Close()
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// --------------------------- Changefeed ----------------------------
+
+// replicaFeed represents a changefeed of a replica, which is unsubscribed once the replica
+// has been closed.
+type replicaFeed struct {
+	behind int32              // Whether the replica fell behind and the commits are discarded
+	queue  chan commit.Commit // The queue of pending commits
+	resync chan struct{}      // The channel signalled once the replica falls behind
+}
+
+// Append clones the commit and queues it for the replica, without ever blocking the commit.
+// If the queue is full, the commit is dropped and the replica is marked as stale, so that it
+// catches up with a fresh snapshot.
+func (f *replicaFeed) Append(change commit.Commit) error {
+	if atomic.LoadInt32(&f.behind) == 1 {
+		return nil // Will be part of the snapshot
+	}
+
+	select {
+	case f.queue <- change.Clone():
+	default:
+		if atomic.CompareAndSwapInt32(&f.behind, 0, 1) {
+			f.retry()
+		}
+	}
+	return nil
+}
+
+// retry marks the replica as lagging and signals it to restore a snapshot
+func (f *replicaFeed) retry() {
+	atomic.StoreInt32(&f.behind, 1)
+	select {
+	case f.resync <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe streams the commits of the collection into the logger as they are committed, until
+// the returned function is called. Unlike the logger of the options, it can be attached to a
+// running collection, for example to stream the changes to a remote subscriber. The logger is
+// invoked synchronously by the committer, hence it must not block, and it should clone the
+// commits it retains since their buffers are reused.
+func (c *Collection) Subscribe(logger commit.Logger) (unsubscribe func()) {
+	feed := &changefeed{logger}
+	c.subscribe(feed)
+	return func() {
+		c.unsubscribe(feed)
+	}
+}
+
+// changefeed represents a logger subscribed to the commits, which is wrapped so that the
+// loggers which are not comparable can still be unsubscribed
+type changefeed struct {
+	commit.Logger
+}
+
+// subscribe adds a changefeed to the collection
+func (c *Collection) subscribe(feed commit.Logger) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	feeds, _ := c.feeds.Load().([]commit.Logger)
+	updated := make([]commit.Logger, 0, len(feeds)+1)
+	updated = append(updated, feeds...)
+	updated = append(updated, feed)
+	c.feeds.Store(updated)
+}
+
+// unsubscribe removes a changefeed from the collection
+func (c *Collection) unsubscribe(feed commit.Logger) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	feeds, _ := c.feeds.Load().([]commit.Logger)
+	updated := make([]commit.Logger, 0, len(feeds))
+	for _, f := range feeds {
+		if f != feed {
+			updated = append(updated, f)
+		}
+	}
+	c.feeds.Store(updated)
+}
+
+// replicate streams the commit to all of the subscribed changefeeds
+func (c *Collection) replicate(change commit.Commit) {
+	feeds, _ := c.feeds.Load().([]commit.Logger)
+	for _, feed := range feeds {
+		feed.Append(change)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kelindar/column/commit"
+)
+
+// ErrGap is returned when a commit is missing from the commit stream followed by a replica.
+var ErrGap = errors.New("column: commit stream has a gap")
+
+// log appends the commit to the logger, along with the next sequence number of the collection.
+// The commits are appended one at a time, so that they are received in sequence.
+func (c *Collection) log(logger commit.Logger, change commit.Commit) error {
+	c.seqLock.Lock()
+	defer c.seqLock.Unlock()
+
+	c.seq++
+	change.Seq = c.seq
+	return logger.Append(change)
+}
+
+// Replica applies the commit stream of a primary collection, encoded by its commit logger,
+// onto a local collection. This allows a warm standby to be kept in a different process or
+// on a different machine, by following the commits read from a file, a channel or a network
+// connection. The commits are sequenced by the primary, so that a missing commit stops the
+// replication with ErrGap instead of the standby silently diverging from the primary.
+type Replica struct {
+	lock   sync.Mutex
+	target *Collection
+	last   uint64
+}
+
+// NewReplica creates a new replica which applies the commits onto the target collection. The
+// sequence number of the last commit already applied onto the target should be specified, or
+// zero if the first commit received should be applied regardless of its sequence number.
+func NewReplica(target *Collection, last uint64) *Replica {
+	return &Replica{
+		target: target,
+		last:   last,
+	}
+}
+
+// Last returns the sequence number of the last commit applied, from which the replication
+// can be resumed.
+func (r *Replica) Last() uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.last
+}
+
+// Apply applies a single commit of the primary onto the target collection. The commits which
+// were already applied are skipped, while a commit received out of sequence returns ErrGap.
+func (r *Replica) Apply(change commit.Commit) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch {
+	case change.Seq == 0:
+		return fmt.Errorf("column: unable to replicate commit %d, it was not sequenced", change.ID)
+	case r.last > 0 && change.Seq <= r.last:
+		return nil // Already applied
+	case r.last > 0 && change.Seq != r.last+1:
+		return fmt.Errorf("%w, expected commit #%d but received #%d", ErrGap, r.last+1, change.Seq)
+	}
+
+	if err := r.target.Replay(change); err != nil {
+		return err
+	}
+
+	r.last = change.Seq
+	return nil
+}
+
+// Consume applies the encoded commits read from the source until the end of the stream, or
+// until a commit fails to apply.
+func (r *Replica) Consume(src io.Reader) error {
+	return commit.Open(src).Range(r.Apply)
+}
+
+// Follow applies the commits received from the channel until the channel is closed, the
+// context is cancelled, or a commit fails to apply.
+func (r *Replica) Follow(ctx context.Context, src <-chan commit.Commit) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case change, ok := <-src:
+			if !ok {
+				return nil
+			}
+
+			if err := r.Apply(change); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaFollow(t *testing.T) {
+	w := make(commit.Channel, 1024)
+	primary := newStreamCollection(w)
+	defer primary.Close()
+	standby := newStreamCollection(nil)
+	defer standby.Close()
+
+	// Write into the primary
+	for i := 0; i < 100; i++ {
+		primary.InsertObject(Object{"name": "Roman", "age": i})
+	}
+	primary.Query(func(txn *Txn) error {
+		txn.WithInt("age", func(v int64) bool {
+			return v < 50
+		}).DeleteAll()
+		return nil
+	})
+	close(w)
+
+	// Follow the commits on the standby
+	replica := NewReplica(standby, 0)
+	assert.NoError(t, replica.Follow(context.Background(), w))
+	assert.Equal(t, uint64(101), replica.Last())
+	assert.Equal(t, 50, standby.Count())
+}
+
+func TestReplicaConsume(t *testing.T) {
+	stream := bytes.NewBuffer(nil)
+	primary := newStreamCollection(commit.Open(stream))
+	defer primary.Close()
+	standby := newStreamCollection(nil)
+	defer standby.Close()
+
+	for i := 0; i < 10; i++ {
+		primary.InsertObject(Object{"name": "Roman", "age": i})
+	}
+
+	// Consume the encoded stream, twice since the duplicates are skipped
+	replica := NewReplica(standby, 0)
+	encoded := stream.Bytes()
+	assert.NoError(t, replica.Consume(bytes.NewReader(encoded)))
+	assert.NoError(t, replica.Consume(bytes.NewReader(encoded)))
+	assert.Equal(t, uint64(10), replica.Last())
+	assert.Equal(t, 10, standby.Count())
+}
+
+func TestReplicaGap(t *testing.T) {
+	w := make(commit.Channel, 1024)
+	primary := newStreamCollection(w)
+	defer primary.Close()
+	standby := newStreamCollection(nil)
+	defer standby.Close()
+
+	for i := 0; i < 3; i++ {
+		primary.InsertObject(Object{"name": "Roman", "age": i})
+	}
+
+	replica := NewReplica(standby, 0)
+	assert.NoError(t, replica.Apply(<-w))
+	<-w // Lost
+
+	err := replica.Apply(<-w)
+	assert.True(t, errors.Is(err, ErrGap))
+	assert.Equal(t, uint64(1), replica.Last())
+	assert.Equal(t, 1, standby.Count())
+}
+
+func TestReplicaUnsequenced(t *testing.T) {
+	standby := newStreamCollection(nil)
+	defer standby.Close()
+
+	replica := NewReplica(standby, 0)
+	assert.Error(t, replica.Apply(commit.Commit{ID: 1}))
+}
+
+func TestReplicaFollowCancel(t *testing.T) {
+	standby := newStreamCollection(nil)
+	defer standby.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, NewReplica(standby, 0).Follow(ctx, nil))
+}
+
+// newStreamCollection creates a collection with the schema used by the replication tests
+func newStreamCollection(writer commit.Logger) *Collection {
+	c := NewCollection(Options{
+		Writer: writer,
+		Vacuum: time.Hour,
+	})
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("age", ForInt())
+	return c
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaOf(t *testing.T) {
+	primary := loadPlayers(500)
+	defer primary.Close()
+
+	replica, err := primary.Replica()
+	assert.NoError(t, err)
+	defer replica.Close()
+
+	// The replica should contain the copied state, including indexes
+	assert.Equal(t, 500, replica.Count())
+	replica.Query(func(txn *Txn) error {
+		assert.Equal(t, 138, txn.With("human").Count())
+		return nil
+	})
+
+	// Update the primary, the replica should eventually catch up
+	primary.Query(func(txn *Txn) error {
+		balance := txn.Float64("balance")
+		return txn.With("human").Range(func(idx uint32) {
+			balance.Set(1)
+		})
+	})
+	primary.DeleteAt(0)
+
+	assert.Eventually(t, func() bool {
+		sum := 0.0
+		replica.Query(func(txn *Txn) error {
+			sum = txn.With("human").Float64("balance").Sum()
+			return nil
+		})
+		return replica.Count() == 499 && sum == float64(primary.Count()-361)
+	}, time.Second, time.Millisecond)
+}
+
+func TestReplicaSnapshot(t *testing.T) {
+	primary := NewCollection()
+	primary.CreateColumn("name", ForString())
+	primary.CreateColumn("age", ForInt())
+	defer primary.Close()
+
+	// Hold the replica right after the initial copy, so that it falls behind
+	var lock sync.Mutex
+	var stages []ReplicaStage
+	resume := make(chan struct{})
+	replica, err := primary.ReplicaWith(ReplicaOptions{
+		Queue: 2,
+		Progress: func(p ReplicaProgress) {
+			lock.Lock()
+			stages = append(stages, p.Stage)
+			first := len(stages) == 2
+			lock.Unlock()
+			if first {
+				<-resume
+			}
+		},
+	})
+	assert.NoError(t, err)
+	defer replica.Close()
+
+	// The primary should never wait for the lagging replica
+	for i := 0; i < 100; i++ {
+		primary.InsertObject(Object{"name": "Roman", "age": i})
+	}
+	close(resume)
+
+	assert.Eventually(t, func() bool {
+		sum := 0
+		replica.Query(func(txn *Txn) error {
+			sum = txn.Int("age").Sum()
+			return nil
+		})
+		return replica.Count() == 100 && sum == 4950
+	}, time.Second, time.Millisecond)
+
+	// The replica should have restored a snapshot before resuming streaming
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(stages) == 5
+	}, time.Second, time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []ReplicaStage{
+		ReplicaRestoring, ReplicaStreaming,
+		ReplicaLagging, ReplicaRestoring, ReplicaStreaming,
+	}, stages)
+
+	// Subsequent commits should be streamed
+	primary.InsertObject(Object{"name": "Roman", "age": 100})
+	assert.Eventually(t, func() bool {
+		return replica.Count() == 101
+	}, time.Second, time.Millisecond)
+}
+
+func TestReplicaClose(t *testing.T) {
+	primary := NewCollection()
+	primary.CreateColumn("name", ForString())
+	defer primary.Close()
+
+	replica, err := primary.Replica()
+	assert.NoError(t, err)
+	assert.NoError(t, replica.Close())
+
+	// The primary should eventually drop the changefeed of the closed replica
+	assert.Eventually(t, func() bool {
+		feeds, _ := primary.feeds.Load().([]commit.Logger)
+		return len(feeds) == 0
+	}, time.Second, time.Millisecond)
+
+	primary.InsertObject(Object{"name": "Roman"})
+	assert.Equal(t, 0, replica.Count())
+}
+
+func TestSubscribe(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	defer coll.Close()
+
+	feed := make(commit.Channel, 10)
+	unsubscribe := coll.Subscribe(feed)
+	coll.InsertObject(Object{"name": "Roman"})
+
+	// The commit can be replayed onto another collection
+	change := <-feed
+	other := NewCollection()
+	other.CreateColumn("name", ForString())
+	assert.NoError(t, other.Replay(change))
+	assert.Equal(t, 1, other.Count())
+
+	// No more commits once unsubscribed
+	unsubscribe()
+	coll.InsertObject(Object{"name": "Merlin"})
+	assert.Len(t, feed, 0)
+}
+
+func TestReplicaUnsupportedColumn(t *testing.T) {
+	primary := NewCollection()
+	primary.CreateColumn("custom", &fixedColumn{})
+	defer primary.Close()
+
+	_, err := primary.Replica()
+	assert.Error(t, err)
+}
+
+// fixedColumn represents a column implementation which can not be copied
+type fixedColumn struct {
+	Column
+}
+
+func (c *fixedColumn) Grow(idx uint32) {}
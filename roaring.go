@@ -0,0 +1,263 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/kelindar/bitmap"
+)
+
+// Constants of the portable roaring serialization format, as specified by the RoaringFormatSpec
+// at https://github.com/RoaringBitmap/RoaringFormatSpec
+const (
+	roaringCookie      = 12347 // Cookie of a bitmap with run containers
+	roaringCookieNoRun = 12346 // Cookie of a bitmap without run containers
+	roaringMaxArray    = 4096  // Maximum cardinality of an array container
+	roaringNoOffset    = 4     // Minimum number of containers for the offset header
+	roaringWords       = 1024  // Number of 64-bit words in a container
+)
+
+var (
+	errRoaringCookie = errors.New("column: unable to read roaring bitmap, invalid cookie")
+)
+
+// --------------------------- Roaring Writer ----------------------------
+
+// WriteRoaring writes the bitmap into the destination using the portable roaring serialization
+// format, so that it can be read by other systems which support roaring bitmaps.
+func WriteRoaring(dst io.Writer, src bitmap.Bitmap) error {
+	type container struct {
+		key   uint16
+		count int
+		words []uint64
+	}
+
+	// Split the bitmap into the non-empty containers of 65536 bits each
+	containers := make([]container, 0, len(src)/roaringWords+1)
+	for i := 0; i < len(src); i += roaringWords {
+		words := src[i:minInt(i+roaringWords, len(src))]
+		if count := bitmap.Bitmap(words).Count(); count > 0 {
+			containers = append(containers, container{
+				key:   uint16(i / roaringWords),
+				count: count,
+				words: words,
+			})
+		}
+	}
+
+	// Write the cookie and the descriptive header
+	w := bufio.NewWriter(dst)
+	buffer := make([]byte, 8)
+	writeUint32(w, buffer, roaringCookieNoRun)
+	writeUint32(w, buffer, uint32(len(containers)))
+	for _, c := range containers {
+		writeUint16(w, buffer, c.key)
+		writeUint16(w, buffer, uint16(c.count-1))
+	}
+
+	// Write the offset header, pointing at the start of each container
+	offset := 8 + 8*len(containers)
+	for _, c := range containers {
+		writeUint32(w, buffer, uint32(offset))
+		if c.count <= roaringMaxArray {
+			offset += 2 * c.count
+		} else {
+			offset += 8 * roaringWords
+		}
+	}
+
+	// Write the containers, as arrays if they are sparse or as bitmaps otherwise
+	for _, c := range containers {
+		if c.count <= roaringMaxArray {
+			for i, word := range c.words {
+				for ; word != 0; word &= word - 1 {
+					writeUint16(w, buffer, uint16(i<<6+bits.TrailingZeros64(word)))
+				}
+			}
+			continue
+		}
+
+		for i := 0; i < roaringWords; i++ {
+			var word uint64
+			if i < len(c.words) {
+				word = c.words[i]
+			}
+
+			binary.LittleEndian.PutUint64(buffer, word)
+			w.Write(buffer)
+		}
+	}
+
+	return w.Flush()
+}
+
+// writeUint16 writes a little-endian uint16 value
+func writeUint16(w *bufio.Writer, buffer []byte, v uint16) {
+	binary.LittleEndian.PutUint16(buffer, v)
+	w.Write(buffer[:2])
+}
+
+// writeUint32 writes a little-endian uint32 value
+func writeUint32(w *bufio.Writer, buffer []byte, v uint32) {
+	binary.LittleEndian.PutUint32(buffer, v)
+	w.Write(buffer[:4])
+}
+
+// --------------------------- Roaring Reader ----------------------------
+
+// ReadRoaring reads a bitmap serialized in the portable roaring serialization format. Array,
+// bitmap and run containers are all supported.
+func ReadRoaring(src io.Reader) (bitmap.Bitmap, error) {
+	r := bufio.NewReader(src)
+	cookie, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the number of containers and which ones are run containers
+	var size int
+	var runs []byte
+	switch {
+	case cookie == roaringCookieNoRun:
+		n, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		size = int(n)
+	case cookie&0xffff == roaringCookie:
+		size = int(cookie>>16) + 1
+		runs = make([]byte, (size+7)/8)
+		if _, err := io.ReadFull(r, runs); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errRoaringCookie
+	}
+
+	// Read the descriptive header
+	keys := make([]uint16, size)
+	counts := make([]int, size)
+	for i := 0; i < size; i++ {
+		key, err := readUint16(r)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := readUint16(r)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i], counts[i] = key, int(count)+1
+	}
+
+	// Skip the offset header, since the containers are read sequentially
+	if runs == nil || size >= roaringNoOffset {
+		if _, err := r.Discard(4 * size); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the containers
+	dst := make(bitmap.Bitmap, 0, 4)
+	for i, key := range keys {
+		base := uint32(key) << 16
+		switch {
+		case runs != nil && runs[i/8]&(1<<(i%8)) != 0:
+			n, err := readUint16(r)
+			if err != nil {
+				return nil, err
+			}
+
+			for j := 0; j < int(n); j++ {
+				start, err := readUint16(r)
+				if err != nil {
+					return nil, err
+				}
+
+				length, err := readUint16(r)
+				if err != nil {
+					return nil, err
+				}
+
+				for x := int(start); x <= int(start)+int(length); x++ {
+					dst.Set(base + uint32(x))
+				}
+			}
+
+		case counts[i] <= roaringMaxArray:
+			for j := 0; j < counts[i]; j++ {
+				x, err := readUint16(r)
+				if err != nil {
+					return nil, err
+				}
+				dst.Set(base + uint32(x))
+			}
+
+		default:
+			dst.Grow(base + 65535)
+			words := dst[base>>6 : base>>6+roaringWords]
+			for j := range words {
+				var buffer [8]byte
+				if _, err := io.ReadFull(r, buffer[:]); err != nil {
+					return nil, err
+				}
+				words[j] = binary.LittleEndian.Uint64(buffer[:])
+			}
+		}
+	}
+
+	return dst, nil
+}
+
+// readUint16 reads a little-endian uint16 value
+func readUint16(r *bufio.Reader) (uint16, error) {
+	var buffer [2]byte
+	if _, err := io.ReadFull(r, buffer[:]); err != nil {
+		return 0, fmt.Errorf("column: unable to read roaring bitmap, %w", err)
+	}
+	return binary.LittleEndian.Uint16(buffer[:]), nil
+}
+
+// readUint32 reads a little-endian uint32 value
+func readUint32(r *bufio.Reader) (uint32, error) {
+	var buffer [4]byte
+	if _, err := io.ReadFull(r, buffer[:]); err != nil {
+		return 0, fmt.Errorf("column: unable to read roaring bitmap, %w", err)
+	}
+	return binary.LittleEndian.Uint32(buffer[:]), nil
+}
+
+// minInt returns the smaller of two integers
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// --------------------------- Transaction ----------------------------
+
+// WithBitmap filters down the current query to the rows contained in the specified bitmap,
+// for example a set of rows read using ReadRoaring().
+func (txn *Txn) WithBitmap(set bitmap.Bitmap) *Txn {
+	txn.initialize()
+	done := txn.traceStep("WithBitmap", "")
+	txn.index.And(set)
+	done()
+	return txn
+}
+
+// WriteRoaring writes the result set of the transaction into the destination using the
+// portable roaring serialization format.
+func (txn *Txn) WriteRoaring(dst io.Writer) error {
+	txn.resolve()
+	return WriteRoaring(dst, txn.index)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/kelindar/bitmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoaringFormat(t *testing.T) {
+	var set bitmap.Bitmap
+	set.Set(1)
+	set.Set(2)
+	set.Set(3)
+
+	// Must match the reference serialization of {1, 2, 3}
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, WriteRoaring(buffer, set))
+	assert.Equal(t, "3a300000010000000000020010000000010002000300", hex.EncodeToString(buffer.Bytes()))
+}
+
+func TestRoaringRoundTrip(t *testing.T) {
+	var set bitmap.Bitmap
+	for i := uint32(0); i < 10000; i++ {
+		set.Set(i) // Dense, written as a bitmap container
+	}
+	for i := uint32(70000); i < 200000; i += 100 {
+		set.Set(i) // Sparse, written as array containers
+	}
+	set.Set(10000000)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, WriteRoaring(buffer, set))
+
+	out, err := ReadRoaring(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, set.Count(), out.Count())
+	set.Range(func(x uint32) {
+		assert.True(t, out.Contains(x))
+	})
+}
+
+func TestRoaringRuns(t *testing.T) {
+	input, _ := hex.DecodeString("3b30000001" + "00006300" + "01000a006300")
+	out, err := ReadRoaring(bytes.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, out.Count())
+	min, _ := out.Min()
+	max, _ := out.Max()
+	assert.Equal(t, uint32(10), min)
+	assert.Equal(t, uint32(109), max)
+}
+
+func TestRoaringInvalid(t *testing.T) {
+	_, err := ReadRoaring(bytes.NewReader([]byte{1, 2, 3, 4}))
+	assert.Error(t, err)
+
+	_, err = ReadRoaring(bytes.NewReader([]byte{0x3a, 0x30, 0, 0, 1, 0, 0, 0}))
+	assert.Error(t, err)
+
+	_, err = ReadRoaring(bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func TestRoaringQuery(t *testing.T) {
+	players := loadPlayers(500)
+
+	// Export the set of rogues
+	buffer := bytes.NewBuffer(nil)
+	players.Query(func(txn *Txn) error {
+		return txn.With("rogue").WriteRoaring(buffer)
+	})
+
+	// Import them back and intersect with another index
+	set, err := ReadRoaring(buffer)
+	assert.NoError(t, err)
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, txn.With("rogue").Count(), set.Count())
+		return nil
+	})
+
+	var expect int
+	players.Query(func(txn *Txn) error {
+		expect = txn.With("rogue", "male").Count()
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, expect, txn.WithBitmap(set).With("male").Count())
+		return nil
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"google.golang.org/grpc"
+)
+
+// Client represents a client of the remote collections, which converts the values of the
+// rows to and from their messages.
+type Client struct {
+	client ColumnClient
+}
+
+// NewClient creates a new client over the connection to a server.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		client: NewColumnClient(conn),
+	}
+}
+
+// Query executes the SQL statement on the server and invokes the callback for every row of
+// the result set as it is received, along with its offset. The values of the missing columns
+// are not present in the map.
+func (c *Client) Query(ctx context.Context, query string, fn func(index uint32, values column.Object) error) error {
+	stream, err := c.client.Query(ctx, &QueryRequest{Query: query})
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := stream.Recv()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if err := fn(row.Index, valuesOf(row.Values)); err != nil {
+			return err
+		}
+	}
+}
+
+// Insert inserts the objects into the remote collection and returns their offsets.
+func (c *Client) Insert(ctx context.Context, collection string, objects ...column.Object) ([]uint32, error) {
+	rows := make([]*Row, 0, len(objects))
+	for _, object := range objects {
+		values, err := messagesOf(object)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, &Row{Values: values})
+	}
+
+	response, err := c.client.Insert(ctx, &InsertRequest{
+		Collection: collection,
+		Rows:       rows,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Indexes, nil
+}
+
+// UpdateAt updates the values of the row at the specified offset and returns its values.
+func (c *Client) UpdateAt(ctx context.Context, collection string, index uint32, values column.Object) (column.Object, error) {
+	return c.update(ctx, &RowRequest{Collection: collection, By: &RowRequest_Index{Index: index}}, values)
+}
+
+// UpdateKey updates the values of the row with the specified primary key and returns its values.
+func (c *Client) UpdateKey(ctx context.Context, collection string, key string, values column.Object) (column.Object, error) {
+	return c.update(ctx, &RowRequest{Collection: collection, By: &RowRequest_Key{Key: key}}, values)
+}
+
+// update updates the values of a row
+func (c *Client) update(ctx context.Context, req *RowRequest, values column.Object) (column.Object, error) {
+	messages, err := messagesOf(values)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := c.client.Update(ctx, &UpdateRequest{
+		Row:    req,
+		Values: messages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return valuesOf(row.Values), nil
+}
+
+// DeleteAt deletes the row at the specified offset and returns whether it was deleted.
+func (c *Client) DeleteAt(ctx context.Context, collection string, index uint32) (bool, error) {
+	return c.delete(ctx, &RowRequest{Collection: collection, By: &RowRequest_Index{Index: index}})
+}
+
+// DeleteKey deletes the row with the specified primary key and returns whether it was deleted.
+func (c *Client) DeleteKey(ctx context.Context, collection string, key string) (bool, error) {
+	return c.delete(ctx, &RowRequest{Collection: collection, By: &RowRequest_Key{Key: key}})
+}
+
+// delete deletes a row
+func (c *Client) delete(ctx context.Context, req *RowRequest) (bool, error) {
+	response, err := c.client.Delete(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	return response.Deleted, nil
+}
+
+// Subscribe streams the commits of the remote collection and invokes the callback for each of
+// them, until the context is cancelled or the callback returns an error. The commits can be
+// replayed onto a local collection with the same schema, in order to follow the remote one.
+func (c *Client) Subscribe(ctx context.Context, collection string, fn func(commit.Commit) error) error {
+	stream, err := c.client.Subscribe(ctx, &SubscribeRequest{Collection: collection})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		var change commit.Commit
+		if _, err := change.ReadFrom(bytes.NewReader(msg.Data)); err != nil {
+			return err
+		}
+
+		if err := fn(change); err != nil {
+			return err
+		}
+	}
+}
+
+// valuesOf converts the messages into the native values
+func valuesOf(messages map[string]*Value) column.Object {
+	values := make(column.Object, len(messages))
+	for name, value := range messages {
+		values[name] = value.Interface()
+	}
+	return values
+}
+
+// messagesOf converts the native values into their messages
+func messagesOf(values column.Object) (map[string]*Value, error) {
+	messages := make(map[string]*Value, len(values))
+	for name, value := range values {
+		v, err := NewValue(value)
+		if err != nil {
+			return nil, err
+		}
+		messages[name] = v
+	}
+	return messages, nil
+}
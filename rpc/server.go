@@ -0,0 +1,299 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package rpc exposes the collections of a source, such as a catalog, to remote services over
+// gRPC, as described by column.proto. The server queries the collections with SQL and streams
+// the result set row by row, inserts, updates and deletes the rows, and streams the commits of
+// a collection as they happen, so that a remote service can follow its changes. The client
+// wraps the generated stubs with the native values of the columns.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/kelindar/column/sql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscribeQueue is the number of commits buffered for a subscriber
+const subscribeQueue = 1024
+
+// errNotFound is returned when a row does not exist
+var errNotFound = errors.New("column: row not found")
+
+// Server represents the gRPC service which serves the collections of a source.
+type Server struct {
+	UnimplementedColumnServer
+	source sql.Source
+}
+
+// New creates a new gRPC service for the collections of the source.
+func New(source sql.Source) *Server {
+	return &Server{source: source}
+}
+
+// Register registers the service onto the gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	RegisterColumnServer(server, s)
+}
+
+// Query executes the SQL statement and streams the rows of its result set
+func (s *Server) Query(req *QueryRequest, stream Column_QueryServer) error {
+	result, err := sql.Query(s.source, req.Query)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	for i, values := range result.Rows {
+		row := &Row{
+			Index:  result.Indexes[i],
+			Values: make(map[string]*Value, len(values)),
+		}
+
+		for j, value := range values {
+			if value == nil {
+				continue // Missing value
+			}
+
+			v, err := NewValue(value)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			row.Values[result.Columns[j]] = v
+		}
+
+		if err := stream.Send(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insert inserts the rows into the collection, in a single transaction
+func (s *Server) Insert(ctx context.Context, req *InsertRequest) (*InsertResponse, error) {
+	c, err := s.collection(req.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]column.Object, 0, len(req.Rows))
+	for _, row := range req.Rows {
+		object, err := convertObject(c, row.Values)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		objects = append(objects, object)
+	}
+
+	indexes, err := c.InsertMany(objects)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &InsertResponse{Indexes: indexes}, nil
+}
+
+// Update updates the values of an existing row and returns the updated row
+func (s *Server) Update(ctx context.Context, req *UpdateRequest) (*Row, error) {
+	c, err := s.collection(req.Row.GetCollection())
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := lookup(c, req.Row)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := convertObject(c, req.Values)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := c.QueryAt(idx, func(row column.Row) error {
+		for name, value := range object {
+			row.SetAny(name, value)
+		}
+		return nil
+	}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return readRow(c, idx)
+}
+
+// Delete deletes an existing row, if it exists
+func (s *Server) Delete(ctx context.Context, req *RowRequest) (*DeleteResponse, error) {
+	c, err := s.collection(req.GetCollection())
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := lookup(c, req)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return &DeleteResponse{}, nil
+	case err != nil:
+		return nil, err
+	default:
+		return &DeleteResponse{Deleted: c.DeleteAt(idx)}, nil
+	}
+}
+
+// Subscribe streams the commits of the collection as they happen, encoded with the binary
+// format of the commit package, until the client cancels the call. The commits are buffered
+// for the subscriber and, if it falls too far behind, the stream is terminated with the
+// ResourceExhausted code rather than holding back the commits of the collection.
+func (s *Server) Subscribe(req *SubscribeRequest, stream Column_SubscribeServer) error {
+	c, err := s.collection(req.Collection)
+	if err != nil {
+		return err
+	}
+
+	feed := &subscriber{
+		queue:  make(chan commit.Commit, subscribeQueue),
+		behind: make(chan struct{}),
+	}
+
+	unsubscribe := c.Subscribe(feed)
+	defer unsubscribe()
+
+	var buffer bytes.Buffer
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-feed.behind:
+			return status.Error(codes.ResourceExhausted, "column: subscriber fell behind and commits were dropped")
+		case change := <-feed.queue:
+			buffer.Reset()
+			if _, err := change.WriteTo(&buffer); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+
+			if err := stream.Send(&Commit{
+				Id:    change.ID,
+				Chunk: uint32(change.Chunk),
+				Data:  buffer.Bytes(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// collection returns the collection with the specified name
+func (s *Server) collection(name string) (*column.Collection, error) {
+	c, ok := s.source.Collection(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "column: collection '%s' does not exist", name)
+	}
+	return c, nil
+}
+
+// lookup resolves the offset of an existing row, either by its offset or its primary key
+func lookup(c *column.Collection, req *RowRequest) (idx uint32, err error) {
+	found := false
+	fn := func(v column.Selector) {
+		idx, found = v.Index(), true
+	}
+
+	switch by := req.GetBy().(type) {
+	case *RowRequest_Key:
+		err = c.SelectKeys([]string{by.Key}, fn)
+	case *RowRequest_Index:
+		err = c.SelectAt([]uint32{by.Index}, fn)
+	default:
+		return 0, status.Error(codes.InvalidArgument, "column: row must be specified by its index or its key")
+	}
+
+	switch {
+	case err != nil:
+		return 0, status.Error(codes.InvalidArgument, err.Error())
+	case !found:
+		return 0, status.Error(codes.NotFound, errNotFound.Error())
+	default:
+		return idx, nil
+	}
+}
+
+// readRow reads the values of the row at the specified offset
+func readRow(c *column.Collection, idx uint32) (row *Row, err error) {
+	columns := c.Columns()
+	c.SelectAt([]uint32{idx}, func(v column.Selector) {
+		row = &Row{
+			Index:  idx,
+			Values: make(map[string]*Value, len(columns)),
+		}
+
+		for _, name := range columns {
+			value, ok := v.Any(name)
+			if !ok || err != nil {
+				continue
+			}
+
+			row.Values[name], err = NewValue(value)
+		}
+	})
+
+	switch {
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	case row == nil:
+		return nil, status.Error(codes.NotFound, errNotFound.Error())
+	default:
+		return row, nil
+	}
+}
+
+// convertObject converts the values into the types of the columns of the collection
+func convertObject(c *column.Collection, values map[string]*Value) (column.Object, error) {
+	object := make(column.Object, len(values))
+	for name, value := range values {
+		kind, ok := c.KindOf(name)
+		if !ok {
+			return nil, fmt.Errorf("column: column '%s' does not exist", name)
+		}
+
+		v, err := value.convert(kind)
+		if err != nil {
+			return nil, fmt.Errorf("column: invalid value for column '%s', %w", name, err)
+		}
+		object[name] = v
+	}
+	return object, nil
+}
+
+// --------------------------- Subscriber ----------------------------
+
+// subscriber represents a changefeed of a remote subscriber, which is closed once it falls
+// behind instead of blocking the commits.
+type subscriber struct {
+	dropped int32              // Whether a commit was dropped
+	queue   chan commit.Commit // The queue of pending commits
+	behind  chan struct{}      // The channel closed once a commit was dropped
+}
+
+// Append clones the commit and queues it for the subscriber, without ever blocking
+func (s *subscriber) Append(change commit.Commit) error {
+	if atomic.LoadInt32(&s.dropped) == 1 {
+		return nil
+	}
+
+	select {
+	case s.queue <- change.Clone():
+	default:
+		if atomic.CompareAndSwapInt32(&s.dropped, 0, 1) {
+			close(s.behind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestQuery(t *testing.T) {
+	client := newClient(t, newCatalog(t))
+	ctx := context.Background()
+
+	type row struct {
+		index  uint32
+		values column.Object
+	}
+
+	var rows []row
+	assert.NoError(t, client.Query(ctx, "SELECT name, age FROM players WHERE age > 20 ORDER BY age", func(index uint32, values column.Object) error {
+		rows = append(rows, row{index, values})
+		return nil
+	}))
+
+	assert.Equal(t, []row{
+		{1, column.Object{"name": "Arthur", "age": int64(30)}},
+		{0, column.Object{"name": "Merlin", "age": int64(120)}},
+	}, rows)
+
+	err := client.Query(ctx, "SELECT * FROM missing", func(uint32, column.Object) error { return nil })
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestInsertUpdateDelete(t *testing.T) {
+	catalog := newCatalog(t)
+	client := newClient(t, catalog)
+	ctx := context.Background()
+
+	indexes, err := client.Insert(ctx, "players", column.Object{"name": "Roman", "age": 35, "active": true})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{3}, indexes)
+
+	players, _ := catalog.Collection("players")
+	players.QueryKey("Roman", func(r column.Row) error {
+		age, _ := r.Int32("age")
+		assert.Equal(t, int32(35), age)
+		return nil
+	})
+
+	// Update by the offset and by the key
+	values, err := client.UpdateAt(ctx, "players", 3, column.Object{"age": 36})
+	assert.NoError(t, err)
+	assert.Equal(t, column.Object{"name": "Roman", "age": int64(36), "active": true}, values)
+
+	values, err = client.UpdateKey(ctx, "players", "Roman", column.Object{"active": false})
+	assert.NoError(t, err)
+	assert.Equal(t, column.Object{"name": "Roman", "age": int64(36)}, values)
+
+	// Delete by the key, twice
+	deleted, err := client.DeleteKey(ctx, "players", "Roman")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = client.DeleteAt(ctx, "players", 3)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, 3, players.Count())
+}
+
+func TestErrors(t *testing.T) {
+	client := newClient(t, newCatalog(t))
+	ctx := context.Background()
+
+	_, err := client.Insert(ctx, "missing", column.Object{"name": "Roman"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Insert(ctx, "players", column.Object{"unknown": 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Insert(ctx, "players", column.Object{"age": "old"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Insert(ctx, "players", column.Object{"age": 1 << 40})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateAt(ctx, "players", 100, column.Object{"age": 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.UpdateKey(ctx, "players", "Merlin", column.Object{"active": 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.delete(ctx, &RowRequest{Collection: "players"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSubscribe(t *testing.T) {
+	catalog := newCatalog(t)
+	client := newClient(t, catalog)
+	players, _ := catalog.Collection("players")
+
+	// Follow the remote collection with a local one
+	local := column.NewCollection()
+	local.CreateColumn("name", column.ForKey())
+	local.CreateColumn("age", column.ForInt32())
+	local.CreateColumn("active", column.ForBool())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Subscribe(ctx, "players", local.Replay)
+	}()
+
+	// Keep inserting until the subscription picks up the commits
+	assert.Eventually(t, func() bool {
+		players.InsertObject(column.Object{"name": "Roman", "age": int32(35)})
+		return local.Count() > 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-done))
+
+	err := client.Subscribe(context.Background(), "missing", func(commit.Commit) error { return nil })
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSubscriberBehind(t *testing.T) {
+	feed := &subscriber{
+		queue:  make(chan commit.Commit, 1),
+		behind: make(chan struct{}),
+	}
+
+	assert.NoError(t, feed.Append(commit.Commit{ID: 1}))
+	assert.NoError(t, feed.Append(commit.Commit{ID: 2}))
+	assert.NoError(t, feed.Append(commit.Commit{ID: 3}))
+	assert.Len(t, feed.queue, 1)
+
+	select {
+	case <-feed.behind:
+	default:
+		assert.Fail(t, "subscriber should be behind")
+	}
+}
+
+func TestValue(t *testing.T) {
+	for _, v := range []any{
+		int64(-1), uint64(1), 1.5, "hello", true, []byte{1, 2},
+	} {
+		value, err := NewValue(v)
+		assert.NoError(t, err)
+		assert.Equal(t, v, value.Interface())
+	}
+
+	value, err := NewValue(map[string]any{"a": 1.0})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": 1.0}, value.Interface())
+
+	_, err = NewValue(make(chan int))
+	assert.Error(t, err)
+	assert.Nil(t, new(Value).Interface())
+
+	tests := []struct {
+		input  any
+		kind   reflect.Kind
+		output any
+	}{
+		{int64(-5), reflect.Int16, int16(-5)},
+		{int64(5), reflect.Uint32, uint32(5)},
+		{uint64(5), reflect.Int, 5},
+		{int64(5), reflect.Float32, float32(5)},
+		{1.5, reflect.Float64, 1.5},
+		{"a", reflect.String, "a"},
+		{true, reflect.Bool, true},
+		{[]byte{1}, reflect.Interface, []byte{1}},
+		{int64(-5), reflect.Uint, nil},
+		{int64(1 << 20), reflect.Int16, nil},
+		{uint64(1 << 63), reflect.Int64, nil},
+		{1.5, reflect.Int, nil},
+		{"a", reflect.Bool, nil},
+		{true, reflect.String, nil},
+	}
+
+	for _, tc := range tests {
+		value, _ := NewValue(tc.input)
+		output, err := value.convert(tc.kind)
+		assert.Equal(t, tc.output, output, "%v into %v", tc.input, tc.kind)
+		assert.Equal(t, tc.output == nil, err != nil)
+	}
+}
+
+// newCatalog creates a catalog with a collection of players
+func newCatalog(t *testing.T) *column.Catalog {
+	catalog := column.NewCatalog()
+	players, err := catalog.CreateCollection("players")
+	assert.NoError(t, err)
+	players.CreateColumn("name", column.ForKey())
+	players.CreateColumn("age", column.ForInt32())
+	players.CreateColumn("active", column.ForBool())
+	players.InsertMany([]column.Object{
+		{"name": "Merlin", "age": int32(120), "active": true},
+		{"name": "Arthur", "age": int32(30), "active": true},
+		{"name": "Morgana", "age": int32(18)},
+	})
+	return catalog
+}
+
+// newClient serves the catalog over an in-memory listener and connects to it
+func newClient(t *testing.T, catalog *column.Catalog) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	New(catalog).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
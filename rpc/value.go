@@ -0,0 +1,161 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// NewValue converts a value read from a column into its message. The composite values, such
+// as the maps and the slices of an any column, are encoded as JSON.
+func NewValue(value any) (*Value, error) {
+	switch v := value.(type) {
+	case int:
+		return &Value{Kind: &Value_Int{Int: int64(v)}}, nil
+	case int8:
+		return &Value{Kind: &Value_Int{Int: int64(v)}}, nil
+	case int16:
+		return &Value{Kind: &Value_Int{Int: int64(v)}}, nil
+	case int32:
+		return &Value{Kind: &Value_Int{Int: int64(v)}}, nil
+	case int64:
+		return &Value{Kind: &Value_Int{Int: v}}, nil
+	case uint:
+		return &Value{Kind: &Value_Uint{Uint: uint64(v)}}, nil
+	case uint8:
+		return &Value{Kind: &Value_Uint{Uint: uint64(v)}}, nil
+	case uint16:
+		return &Value{Kind: &Value_Uint{Uint: uint64(v)}}, nil
+	case uint32:
+		return &Value{Kind: &Value_Uint{Uint: uint64(v)}}, nil
+	case uint64:
+		return &Value{Kind: &Value_Uint{Uint: v}}, nil
+	case float32:
+		return &Value{Kind: &Value_Float{Float: float64(v)}}, nil
+	case float64:
+		return &Value{Kind: &Value_Float{Float: v}}, nil
+	case string:
+		return &Value{Kind: &Value_String_{String_: v}}, nil
+	case bool:
+		return &Value{Kind: &Value_Bool{Bool: v}}, nil
+	case []byte:
+		return &Value{Kind: &Value_Bytes{Bytes: v}}, nil
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("column: unsupported value of type %T", value)
+		}
+		return &Value{Kind: &Value_Json{Json: string(encoded)}}, nil
+	}
+}
+
+// Interface returns the value as its native type, or nil if it is not set.
+func (x *Value) Interface() any {
+	switch v := x.GetKind().(type) {
+	case *Value_Int:
+		return v.Int
+	case *Value_Uint:
+		return v.Uint
+	case *Value_Float:
+		return v.Float
+	case *Value_String_:
+		return v.String_
+	case *Value_Bool:
+		return v.Bool
+	case *Value_Bytes:
+		return v.Bytes
+	case *Value_Json:
+		var out any
+		if err := json.Unmarshal([]byte(v.Json), &out); err != nil {
+			return nil
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// numberTypes are the types of the numeric columns, by their kind
+var numberTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+// convert converts the value into the specified kind of a column
+func (x *Value) convert(kind reflect.Kind) (any, error) {
+	value := x.Interface()
+	switch {
+	case value == nil:
+		return nil, fmt.Errorf("expected a value of kind %v", kind)
+	case kind == reflect.Interface:
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case int64, uint64, float64:
+		return convertNumber(v, kind)
+	case string:
+		if kind == reflect.String {
+			return v, nil
+		}
+	case bool:
+		if kind == reflect.Bool {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("expected a value of kind %v", kind)
+}
+
+// convertNumber converts a number into the specified numeric kind, as long as it fits. The
+// floating-point numbers are only converted into the floating-point kinds.
+func convertNumber(value any, kind reflect.Kind) (any, error) {
+	typ, ok := numberTypes[kind]
+	if !ok {
+		return nil, fmt.Errorf("expected a value of kind %v", kind)
+	}
+
+	out := reflect.New(typ).Elem()
+	switch v := value.(type) {
+	case int64:
+		switch {
+		case out.CanInt() && !out.OverflowInt(v):
+			out.SetInt(v)
+		case out.CanUint() && v >= 0 && !out.OverflowUint(uint64(v)):
+			out.SetUint(uint64(v))
+		case out.CanFloat():
+			out.SetFloat(float64(v))
+		default:
+			return nil, fmt.Errorf("value %v overflows %v", v, kind)
+		}
+	case uint64:
+		switch {
+		case out.CanInt() && v <= math.MaxInt64 && !out.OverflowInt(int64(v)):
+			out.SetInt(int64(v))
+		case out.CanUint() && !out.OverflowUint(v):
+			out.SetUint(v)
+		case out.CanFloat():
+			out.SetFloat(float64(v))
+		default:
+			return nil, fmt.Errorf("value %v overflows %v", v, kind)
+		}
+	case float64:
+		if !out.CanFloat() {
+			return nil, fmt.Errorf("expected a value of kind %v", kind)
+		}
+		out.SetFloat(v)
+	}
+	return out.Interface(), nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+// Sample filters down the current query to a uniform random sample of n of its rows, so that
+// the rows can be previewed without iterating over all of them. If the query has no more than
+// n rows, all of them are kept.
+func (txn *Txn) Sample(n int) *Txn {
+	txn.resolve()
+	done := txn.traceStep("Sample", "")
+	defer done()
+
+	total := int(txn.index.Count())
+	switch {
+	case n >= total:
+	case n <= 0:
+		txn.index.Clear()
+	case n < total/64:
+		sampleRanks(txn.index, randomRanks(n, total))
+	default:
+		sampleSelection(txn.index, n, total)
+	}
+	return txn
+}
+
+// SampleFraction filters down the current query to a uniform random sample of the specified
+// fraction of its rows, between 0 and 1.
+func (txn *Txn) SampleFraction(p float64) *Txn {
+	txn.resolve()
+	return txn.Sample(int(math.Round(p * float64(txn.index.Count()))))
+}
+
+// randomRanks selects n distinct ranks in [0, total) using Floyd's algorithm, in ascending order
+func randomRanks(n, total int) []int {
+	chosen := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		r := rand.Intn(j + 1)
+		if _, ok := chosen[r]; ok {
+			r = j
+		}
+		chosen[r] = struct{}{}
+	}
+
+	ranks := make([]int, 0, n)
+	for r := range chosen {
+		ranks = append(ranks, r)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
+
+// sampleRanks keeps only the rows of the index at the specified ranks, which must be sorted.
+// The words are skipped using their population count, hence this is efficient for the samples
+// which are much smaller than the index.
+func sampleRanks(index []uint64, ranks []int) {
+	offset := 0
+	for i, word := range index {
+		count := bits.OnesCount64(word)
+		mask := uint64(0)
+		for len(ranks) > 0 && ranks[0] < offset+count {
+			mask |= nthBit(word, ranks[0]-offset)
+			ranks = ranks[1:]
+		}
+
+		index[i] = mask
+		offset += count
+	}
+}
+
+// nthBit returns the n-th set bit of the word, as a mask
+func nthBit(word uint64, n int) uint64 {
+	for ; n > 0; n-- {
+		word &= word - 1
+	}
+	return word & -word
+}
+
+// sampleSelection keeps n rows out of the total rows of the index, selecting each row with
+// the probability of the number of rows still needed over the number of rows remaining.
+func sampleSelection(index []uint64, n, total int) {
+	for i, word := range index {
+		mask := uint64(0)
+		for ; word != 0; word &= word - 1 {
+			if rand.Intn(total) < n {
+				mask |= word & -word
+				n--
+			}
+			total--
+		}
+		index[i] = mask
+	}
+}
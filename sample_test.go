@@ -0,0 +1,83 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/kelindar/bitmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("age", ForInt())
+	for i := 0; i < 100000; i++ {
+		c.InsertObject(Object{"age": i % 100})
+	}
+
+	for _, n := range []int{0, 1, 10, 1000, 40000, 49999, 50000, 60000} {
+		assert.NoError(t, c.Query(func(txn *Txn) error {
+			txn.WithInt("age", func(v int64) bool {
+				return v%2 == 0
+			}).Sample(n)
+
+			// Only the rows of the query are sampled
+			assert.Equal(t, minInt(n, 50000), txn.Count(), n)
+			return txn.Range(func(idx uint32) {
+				age, _ := txn.Int("age").Get()
+				assert.Equal(t, 0, age%2)
+			})
+		}))
+	}
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		assert.Equal(t, 25000, txn.SampleFraction(0.25).Count())
+		assert.Equal(t, 250, txn.SampleFraction(0.01).Count())
+		assert.Equal(t, 0, txn.SampleFraction(-1).Count())
+		return nil
+	}))
+}
+
+func TestSampleUniform(t *testing.T) {
+	index := make(bitmap.Bitmap, 4)
+	for i := uint32(0); i < 256; i += 2 {
+		index.Set(i)
+	}
+
+	// Each of the rows should be sampled about the same number of times
+	for _, n := range []int{1, 64} {
+		var hits [256]int
+		for i := 0; i < 2000; i++ {
+			sample := index.Clone(nil)
+			if n < 128/64 {
+				sampleRanks(sample, randomRanks(n, 128))
+			} else {
+				sampleSelection(sample, n, 128)
+			}
+
+			assert.Equal(t, n, sample.Count())
+			sample.Range(func(x uint32) {
+				hits[x]++
+			})
+		}
+
+		expect := 2000 * n / 128
+		for x, count := range hits {
+			if x%2 == 1 {
+				assert.Zero(t, count)
+			} else {
+				assert.InDelta(t, expect, count, float64(expect)*0.6+5, x)
+			}
+		}
+	}
+}
+
+func TestNthBit(t *testing.T) {
+	word := uint64(0b1011_0100)
+	assert.Equal(t, uint64(0b100), nthBit(word, 0))
+	assert.Equal(t, uint64(0b1_0000), nthBit(word, 1))
+	assert.Equal(t, uint64(0b10_0000), nthBit(word, 2))
+	assert.Equal(t, uint64(0b1000_0000), nthBit(word, 3))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	// ErrSavepoint is returned when a transaction is rolled back to a savepoint which does not
+	// belong to it, or which was discarded by rolling back to an earlier savepoint.
+	ErrSavepoint = errors.New("column: savepoint is not valid for the transaction")
+)
+
+// Savepoint represents a point within a transaction, which the changes queued by the
+// transaction can be rolled back to.
+type Savepoint struct {
+	txn   *Txn          // The transaction which took the savepoint
+	id    uint32        // The identifier of the savepoint within the transaction
+	marks []commit.Mark // The positions of the update buffers, in order
+}
+
+// Savepoint returns a savepoint of the changes queued by the transaction so far. Rolling
+// back to it with RollbackTo() discards the changes queued since, while keeping the ones
+// queued before. Savepoints can be nested, in which case rolling back to a savepoint also
+// discards the savepoints taken after it.
+func (txn *Txn) Savepoint() Savepoint {
+	txn.saved++
+	txn.points = append(txn.points, txn.saved)
+	sp := Savepoint{
+		txn:   txn,
+		id:    txn.saved,
+		marks: make([]commit.Mark, 0, len(txn.updates)),
+	}
+
+	for _, u := range txn.updates {
+		sp.marks = append(sp.marks, u.Mark())
+	}
+	return sp
+}
+
+// RollbackTo discards the changes queued by the transaction since the savepoint was taken,
+// including the insertions and the deletions. The savepoint remains valid and the transaction
+// can roll back to it again, while the savepoints taken after it are discarded. The rows
+// locked since the savepoint remain locked until the transaction commits or rolls back.
+func (txn *Txn) RollbackTo(sp Savepoint) error {
+	if sp.txn != txn || !txn.discardAfter(sp.id) || len(sp.marks) > len(txn.updates) {
+		return ErrSavepoint
+	}
+
+	for i, u := range txn.updates {
+		if i >= len(sp.marks) {
+			if u.Column == rowColumn {
+				txn.releaseInserts(u)
+			}
+			txn.owner.txns.releasePage(u)
+			continue
+		}
+
+		if u.Column == rowColumn {
+			txn.releaseInserts(u.Since(sp.marks[i]))
+		}
+		u.Truncate(sp.marks[i])
+	}
+
+	txn.updates = txn.updates[:len(sp.marks)]
+	return nil
+}
+
+// discardAfter discards the savepoints taken after the specified one and returns whether
+// the specified savepoint is still valid.
+func (txn *Txn) discardAfter(id uint32) bool {
+	for i, v := range txn.points {
+		if v == id {
+			txn.points = txn.points[:i+1]
+			return true
+		}
+	}
+	return false
+}
+
+// releaseInserts releases the indices which were reserved for the insertions of the buffer
+func (txn *Txn) releaseInserts(markers *commit.Buffer) {
+	txn.owner.lock.Lock()
+	defer txn.owner.lock.Unlock()
+	markers.RangeChunks(func(chunk commit.Chunk) {
+		txn.reader.Range(markers, chunk, func(r *commit.Reader) {
+			for r.Next() {
+				if r.Type == commit.Insert {
+					txn.owner.fill.Remove(r.Index())
+				}
+			}
+		})
+	})
+	atomic.StoreUint64(&txn.owner.count, uint64(txn.owner.fill.Count()))
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavepoint(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("balance", ForFloat64())
+	coll.InsertObject(Object{"name": "Roman", "balance": 100.0})
+	coll.InsertObject(Object{"name": "Merlin", "balance": 200.0})
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		balance := txn.Float64("balance")
+		assert.NoError(t, txn.QueryAt(0, func(r Row) error {
+			r.AddFloat64("balance", 10)
+			return nil
+		}))
+
+		// Tentatively update, insert into a new column, insert and delete rows
+		sp := txn.Savepoint()
+		txn.Range(func(idx uint32) {
+			balance.Add(1000)
+		})
+
+		txn.InsertObject(Object{"name": "Arthur", "balance": 300.0})
+		txn.DeleteAt(1)
+		assert.Equal(t, 3, coll.Count()) // The index of Arthur is reserved
+
+		assert.NoError(t, txn.RollbackTo(sp))
+		assert.Equal(t, 2, coll.Count())
+
+		// The savepoint can be rolled back to again
+		txn.DeleteAt(0)
+		assert.NoError(t, txn.RollbackTo(sp))
+
+		// Queue more changes after the rollback
+		return txn.QueryAt(1, func(r Row) error {
+			r.SetString("name", "Merlin the Wise")
+			return nil
+		})
+	}))
+
+	assert.Equal(t, 2, coll.Count())
+	assert.NoError(t, coll.QueryAt(0, func(r Row) error {
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, 110.0, balance)
+		return nil
+	}))
+	assert.NoError(t, coll.QueryAt(1, func(r Row) error {
+		balance, _ := r.Float64("balance")
+		name, _ := r.String("name")
+		assert.Equal(t, 200.0, balance)
+		assert.Equal(t, "Merlin the Wise", name)
+		return nil
+	}))
+
+	// The index released by the rollback can be reused
+	assert.Equal(t, uint32(2), coll.InsertObject(Object{"name": "Arthur"}))
+}
+
+func TestSavepointNested(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		txn.InsertObject(Object{"name": "Roman"})
+		sp1 := txn.Savepoint()
+		txn.InsertObject(Object{"name": "Merlin"})
+		sp2 := txn.Savepoint()
+		txn.InsertObject(Object{"name": "Arthur"})
+
+		assert.NoError(t, txn.RollbackTo(sp2))
+		assert.NoError(t, txn.RollbackTo(sp1))
+
+		// The savepoints taken after the one rolled back to are discarded
+		assert.ErrorIs(t, txn.RollbackTo(sp2), ErrSavepoint)
+		sp3 := txn.Savepoint()
+		assert.ErrorIs(t, txn.RollbackTo(sp2), ErrSavepoint)
+		assert.NoError(t, txn.RollbackTo(sp3))
+		return nil
+	}))
+
+	assert.Equal(t, 1, coll.Count())
+
+	// A savepoint of another transaction, or of a transaction which has completed
+	var previous Savepoint
+	coll.Query(func(txn *Txn) error {
+		previous = txn.Savepoint()
+		return nil
+	})
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.ErrorIs(t, txn.RollbackTo(previous), ErrSavepoint)
+		return nil
+	}))
+}
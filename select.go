@@ -0,0 +1,256 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	errSelectOnly = errors.New("column: unable to write, the selector is read-only")
+)
+
+// Selector represents a cursor at a particular row offset of the collection. The selectors of
+// Collection.SelectAt() are read-only, while the ones of Txn.SelectAt() can also update and
+// delete the row, with the changes queued into the transaction.
+type Selector struct {
+	row      Row
+	writable bool
+}
+
+// Index returns the offset of the row the selector is pointing at
+func (s Selector) Index() uint32 {
+	return s.row.txn.cursor
+}
+
+// Int loads a int value at a particular column
+func (s Selector) Int(columnName string) (int, bool) {
+	return s.row.Int(columnName)
+}
+
+// Int16 loads a int16 value at a particular column
+func (s Selector) Int16(columnName string) (int16, bool) {
+	return s.row.Int16(columnName)
+}
+
+// Int32 loads a int32 value at a particular column
+func (s Selector) Int32(columnName string) (int32, bool) {
+	return s.row.Int32(columnName)
+}
+
+// Int64 loads a int64 value at a particular column
+func (s Selector) Int64(columnName string) (int64, bool) {
+	return s.row.Int64(columnName)
+}
+
+// Uint loads a uint value at a particular column
+func (s Selector) Uint(columnName string) (uint, bool) {
+	return s.row.Uint(columnName)
+}
+
+// Uint16 loads a uint16 value at a particular column
+func (s Selector) Uint16(columnName string) (uint16, bool) {
+	return s.row.Uint16(columnName)
+}
+
+// Uint32 loads a uint32 value at a particular column
+func (s Selector) Uint32(columnName string) (uint32, bool) {
+	return s.row.Uint32(columnName)
+}
+
+// Uint64 loads a uint64 value at a particular column
+func (s Selector) Uint64(columnName string) (uint64, bool) {
+	return s.row.Uint64(columnName)
+}
+
+// Float32 loads a float32 value at a particular column
+func (s Selector) Float32(columnName string) (float32, bool) {
+	return s.row.Float32(columnName)
+}
+
+// Float64 loads a float64 value at a particular column
+func (s Selector) Float64(columnName string) (float64, bool) {
+	return s.row.Float64(columnName)
+}
+
+// Key loads the primary key value of the row
+func (s Selector) Key() (string, bool) {
+	return s.row.Key()
+}
+
+// String loads a string value at a particular column
+func (s Selector) String(columnName string) (string, bool) {
+	return s.row.String(columnName)
+}
+
+// Enum loads a string value at a particular column
+func (s Selector) Enum(columnName string) (string, bool) {
+	return s.row.Enum(columnName)
+}
+
+// Bool loads a bool value at a particular column
+func (s Selector) Bool(columnName string) bool {
+	return s.row.Bool(columnName)
+}
+
+// Any loads a value at a particular column
+func (s Selector) Any(columnName string) (any, bool) {
+	return s.row.Any(columnName)
+}
+
+// Bytes loads a byte slice at a particular column, which must not be modified
+func (s Selector) Bytes(columnName string) ([]byte, bool) {
+	return s.row.Bytes(columnName)
+}
+
+// JSON loads a JSON document at a particular column, which must not be modified
+func (s Selector) JSON(columnName string) (json.RawMessage, bool) {
+	return s.row.JSON(columnName)
+}
+
+// Decimal loads a decimal value at a particular column
+func (s Selector) Decimal(columnName string) (Decimal, bool) {
+	return s.row.Decimal(columnName)
+}
+
+// Update invokes the callback with the row the selector is pointing at, so that its values
+// can be updated. The updates are queued into the transaction which selected the row and are
+// applied once it commits.
+func (s Selector) Update(fn func(r Row)) error {
+	if !s.writable {
+		return errSelectOnly
+	}
+
+	fn(s.row)
+	return nil
+}
+
+// Delete queues the deletion of the row the selector is pointing at into the transaction
+// which selected the row.
+func (s Selector) Delete() error {
+	if !s.writable {
+		return errSelectOnly
+	}
+
+	s.row.txn.deleteAt(s.row.txn.cursor)
+	return nil
+}
+
+// --------------------------- Point Read ----------------------------
+
+// Exists returns whether a row exists at the specified offset.
+func (c *Collection) Exists(idx uint32) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.fill.Contains(idx)
+}
+
+// ReadAt reads a single row at the specified offset and returns whether it exists. Unlike
+// QueryAt(), it does not create a transaction to commit, hence it is cheaper for the point
+// reads. The callback is invoked while the chunk of the row is read-locked, with a read-only
+// selector.
+func (c *Collection) ReadAt(idx uint32, fn func(Selector)) bool {
+	if !c.Exists(idx) {
+		return false
+	}
+
+	txn := c.txns.acquire(c)
+	defer c.txns.release(txn)
+
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	defer c.slock.RUnlock(uint(chunk))
+	c.thaw(chunk)
+	txn.cursor = idx
+	fn(Selector{row: Row{txn}})
+	return true
+}
+
+// --------------------------- Batch Select ----------------------------
+
+// SelectAt reads a batch of rows at the specified offsets, for example the list of row
+// offsets returned by an external search system. The offsets are validated against the
+// fill list once, and the callback is invoked for every row which exists, in the order
+// of their offsets, while each chunk is read-locked. The slice of offsets is not modified.
+func (c *Collection) SelectAt(indexes []uint32, fn func(Selector)) error {
+	return c.selectAt(c.present(indexes), fn)
+}
+
+// SelectAt reads a batch of rows at the specified offsets, just like Collection.SelectAt(),
+// but the selectors are also able to update or delete the rows within this transaction. This
+// is handy when the offsets of the rows to update are already known, without any filtering.
+func (txn *Txn) SelectAt(indexes []uint32, fn func(Selector)) error {
+	found := txn.owner.present(indexes)
+	for i := 0; i < len(found); {
+		i = txn.owner.selectChunk(txn, found, i, true, fn)
+	}
+	return nil
+}
+
+// present returns the sorted and distinct offsets which are present in the collection
+func (c *Collection) present(indexes []uint32) []uint32 {
+	sorted := make([]uint32, len(indexes))
+	copy(sorted, indexes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	// Filter out the offsets which are not present in the collection
+	c.lock.RLock()
+	found := sorted[:0]
+	for i, idx := range sorted {
+		if (i == 0 || idx != sorted[i-1]) && c.fill.Contains(idx) {
+			found = append(found, idx)
+		}
+	}
+	c.lock.RUnlock()
+	return found
+}
+
+// SelectKeys reads a batch of rows with the specified primary keys, in the order of their
+// offsets. The keys which are not present in the collection are skipped.
+func (c *Collection) SelectKeys(keys []string, fn func(Selector)) error {
+	if c.pk == nil {
+		return errNoKey
+	}
+
+	indexes := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		if idx, ok := c.pk.OffsetOf(key); ok {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	return c.SelectAt(indexes, fn)
+}
+
+// selectAt iterates over the sorted offsets chunk by chunk and invokes the callback for
+// every one of them while holding the read lock of the corresponding chunk.
+func (c *Collection) selectAt(indexes []uint32, fn func(Selector)) error {
+	txn := c.txns.acquire(c)
+	defer c.txns.release(txn)
+
+	for i := 0; i < len(indexes); {
+		i = c.selectChunk(txn, indexes, i, false, fn)
+	}
+	return nil
+}
+
+// selectChunk invokes the callback for the offsets which belong to the same chunk as the
+// offset at position i, and returns the position of the first offset of the next chunk.
+func (c *Collection) selectChunk(txn *Txn, indexes []uint32, i int, writable bool, fn func(Selector)) int {
+	chunk := commit.ChunkAt(indexes[i])
+	c.slock.RLock(uint(chunk))
+	defer c.slock.RUnlock(uint(chunk))
+	c.thaw(chunk)
+	for ; i < len(indexes) && commit.ChunkAt(indexes[i]) == chunk; i++ {
+		txn.cursor = indexes[i]
+		fn(Selector{row: Row{txn}, writable: writable})
+	}
+	return i
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectAt(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+	for i := 0; i < 40000; i++ {
+		coll.InsertObject(Object{
+			"name": fmt.Sprintf("user%d", i),
+			"age":  i % 100,
+		})
+	}
+	coll.DeleteAt(20)
+
+	// Offsets are read in order, missing and duplicate ones are skipped
+	input := []uint32{35000, 20, 5, 99999, 17000, 5}
+	var names []string
+	var offsets []uint32
+	assert.NoError(t, coll.SelectAt(input, func(v Selector) {
+		name, _ := v.String("name")
+		names = append(names, name)
+		offsets = append(offsets, v.Index())
+	}))
+
+	assert.Equal(t, []string{"user5", "user17000", "user35000"}, names)
+	assert.Equal(t, []uint32{5, 17000, 35000}, offsets)
+	assert.Equal(t, []uint32{35000, 20, 5, 99999, 17000, 5}, input)
+}
+
+func TestSelectKeys(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("id", ForKey())
+	coll.CreateColumn("age", ForInt())
+	coll.QueryKey("a", func(r Row) error {
+		r.SetInt("age", 10)
+		return nil
+	})
+	coll.QueryKey("b", func(r Row) error {
+		r.SetInt("age", 20)
+		return nil
+	})
+
+	var ages []int
+	assert.NoError(t, coll.SelectKeys([]string{"b", "x", "a"}, func(v Selector) {
+		age, _ := v.Int("age")
+		ages = append(ages, age)
+	}))
+	assert.Equal(t, []int{10, 20}, ages)
+}
+
+func TestSelectKeysNoKey(t *testing.T) {
+	coll := NewCollection()
+	assert.Error(t, coll.SelectKeys([]string{"a"}, func(v Selector) {}))
+}
+
+func TestSelectAtWrite(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("age", ForInt())
+	for i := 0; i < 40000; i++ {
+		coll.InsertObject(Object{"age": i % 100})
+	}
+
+	// Selectors of the collection are read-only
+	assert.NoError(t, coll.SelectAt([]uint32{5}, func(v Selector) {
+		assert.Equal(t, errSelectOnly, v.Delete())
+		assert.Equal(t, errSelectOnly, v.Update(func(r Row) {}))
+	}))
+
+	// Selectors of a transaction queue the updates and deletes into it
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		return txn.SelectAt([]uint32{35000, 5, 17000, 99999}, func(v Selector) {
+			switch v.Index() {
+			case 5:
+				assert.NoError(t, v.Delete())
+			default:
+				assert.NoError(t, v.Update(func(r Row) {
+					r.AddInt("age", 1000)
+				}))
+			}
+		})
+	}))
+
+	assert.Equal(t, 39999, coll.Count())
+	assert.NoError(t, coll.SelectAt([]uint32{5, 17000, 35000}, func(v Selector) {
+		age, _ := v.Int("age")
+		assert.Equal(t, int(v.Index()%100)+1000, age)
+	}))
+}
+
+func TestReadAt(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	for i := 0; i < 20000; i++ {
+		coll.InsertObject(Object{"name": fmt.Sprintf("user%d", i)})
+	}
+	coll.DeleteAt(20)
+
+	assert.True(t, coll.Exists(17000))
+	assert.False(t, coll.Exists(20))
+	assert.False(t, coll.Exists(99999))
+
+	var name string
+	assert.True(t, coll.ReadAt(17000, func(v Selector) {
+		name, _ = v.String("name")
+		assert.Equal(t, errSelectOnly, v.Delete())
+	}))
+	assert.Equal(t, "user17000", name)
+
+	assert.False(t, coll.ReadAt(20, func(v Selector) {
+		assert.Fail(t, "row does not exist")
+	}))
+}
@@ -0,0 +1,218 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zeebo/xxh3"
+)
+
+// ShardedCollection represents a collection whose rows are partitioned across several internal
+// collections (shards) by the hash of their primary key. Each shard has its own index space
+// and its own locks, which lifts the limit of 4 billion rows of a single collection and lets
+// the queries run on all of the shards in parallel. The rows are identified by 64-bit indices
+// composed of the shard number and the index of the row within that shard.
+type ShardedCollection struct {
+	shards []*Collection // The collections holding the partitions of the rows
+	key    string        // The name of the primary key column
+}
+
+// NewShardedCollection creates a new collection partitioned across the specified number of
+// shards, each of them created with the same options.
+func NewShardedCollection(shards int, opts ...Options) *ShardedCollection {
+	if shards <= 0 {
+		shards = 1
+	}
+
+	s := &ShardedCollection{
+		shards: make([]*Collection, 0, shards),
+	}
+	for i := 0; i < shards; i++ {
+		s.shards = append(s.shards, NewCollection(opts...))
+	}
+	return s
+}
+
+// Shards returns the collections holding the partitions of the rows.
+func (s *ShardedCollection) Shards() []*Collection {
+	return s.shards
+}
+
+// CreateColumn creates a column of a specified type on every shard. The rows are partitioned
+// by the column created with ForKey(), which must be created before inserting any row.
+func (s *ShardedCollection) CreateColumn(columnName string, column Column, opts ...ColumnOption) error {
+	empty, ok := column.(Factory)
+	if !ok {
+		return fmt.Errorf("column: unable to create column '%s' of type %T on every shard", columnName, column)
+	}
+
+	for i, shard := range s.shards {
+		target := column
+		if i > 0 {
+			target = empty.MakeEmpty()
+		}
+
+		if err := shard.CreateColumn(columnName, target, opts...); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := column.(*columnKey); ok {
+		s.key = columnName
+	}
+	return nil
+}
+
+// CreateIndex creates an index on every shard.
+func (s *ShardedCollection) CreateIndex(indexName, columnName string, fn func(r Reader) bool) error {
+	for _, shard := range s.shards {
+		if err := shard.CreateIndex(indexName, columnName, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateHashIndex creates a hash index of the column on every shard.
+func (s *ShardedCollection) CreateHashIndex(columnName string) error {
+	for _, shard := range s.shards {
+		if err := shard.CreateHashIndex(columnName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the total number of rows across all of the shards.
+func (s *ShardedCollection) Count() (count int) {
+	for _, shard := range s.shards {
+		count += shard.Count()
+	}
+	return
+}
+
+// Close closes all of the shards.
+func (s *ShardedCollection) Close() error {
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+	return nil
+}
+
+// --------------------------- Rows ----------------------------
+
+// InsertObject adds an object to the shard of its primary key and returns the allocated index.
+func (s *ShardedCollection) InsertObject(obj Object) (index uint64, err error) {
+	if s.key == "" {
+		return 0, errNoKey
+	}
+
+	key, ok := obj[s.key].(string)
+	if !ok {
+		return 0, fmt.Errorf("column: unable to insert object, key '%s' is missing", s.key)
+	}
+
+	shard := s.shardOf(key)
+	err = s.shards[shard].Query(func(txn *Txn) error {
+		idx, err := txn.InsertObject(obj)
+		index = shardIndex(shard, idx)
+		return err
+	})
+	return
+}
+
+// QueryKey jumps at a particular key in the shard of the key, sets the cursor to the provided
+// position and executes given callback fn.
+func (s *ShardedCollection) QueryKey(key string, fn func(Row) error) error {
+	if s.key == "" {
+		return errNoKey
+	}
+
+	return s.shards[s.shardOf(key)].QueryKey(key, fn)
+}
+
+// QueryAt jumps at a particular index, sets the cursor to the provided position and executes
+// given callback fn.
+func (s *ShardedCollection) QueryAt(index uint64, fn func(Row) error) error {
+	shard, idx := splitIndex(index)
+	if shard >= len(s.shards) {
+		return fmt.Errorf("column: shard %d does not exist", shard)
+	}
+
+	return s.shards[shard].QueryAt(idx, fn)
+}
+
+// DeleteAt attempts to delete an item at the specified index. If the item exists, it marks at
+// as deleted and returns true, otherwise it returns false.
+func (s *ShardedCollection) DeleteAt(index uint64) bool {
+	shard, idx := splitIndex(index)
+	return shard < len(s.shards) && s.shards[shard].DeleteAt(idx)
+}
+
+// shardOf returns the shard of a primary key
+func (s *ShardedCollection) shardOf(key string) int {
+	return int(xxh3.HashString(key) % uint64(len(s.shards)))
+}
+
+// shardIndex composes the index of a row from its shard and its index within the shard
+func shardIndex(shard int, idx uint32) uint64 {
+	return uint64(shard)<<32 | uint64(idx)
+}
+
+// splitIndex splits the index of a row into its shard and its index within the shard
+func splitIndex(index uint64) (int, uint32) {
+	return int(index >> 32), uint32(index)
+}
+
+// --------------------------- Fan-out ----------------------------
+
+// Query runs the transaction on every shard in parallel. Each shard commits or rolls back its
+// own transaction independently, hence the writes are not atomic across the shards. If any of
+// the transactions fails, one of the errors is returned.
+func (s *ShardedCollection) Query(fn func(txn *Txn) error) error {
+	_, err := Aggregate(s, func(txn *Txn) (struct{}, error) {
+		return struct{}{}, fn(txn)
+	}, func(a, b struct{}) struct{} {
+		return a
+	})
+	return err
+}
+
+// Aggregate runs the transaction on every shard in parallel and merges their results, in the
+// order of the shards. For example, it can count or sum up the values of the selected rows of
+// every shard. If any of the transactions fails, one of the errors is returned.
+func Aggregate[T any](s *ShardedCollection, fn func(txn *Txn) (T, error), merge func(a, b T) T) (T, error) {
+	results := make([]T, len(s.shards))
+	errs := make([]error, len(s.shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard *Collection) {
+			defer wg.Done()
+			errs[i] = shard.Query(func(txn *Txn) (err error) {
+				results[i], err = fn(txn)
+				return
+			})
+		}(i, shard)
+	}
+	wg.Wait()
+
+	// Merge the results of the shards in order
+	var out T
+	for i := range s.shards {
+		if errs[i] != nil {
+			return out, errs[i]
+		}
+
+		if i == 0 {
+			out = results[i]
+		} else {
+			out = merge(out, results[i])
+		}
+	}
+	return out, nil
+}
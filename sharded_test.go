@@ -0,0 +1,106 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharded(t *testing.T) {
+	s := newShardedPlayers(4)
+	defer s.Close()
+
+	// Insert the rows, partitioned by their key
+	indices := make(map[string]uint64, 100)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("player-%d", i)
+		idx, err := s.InsertObject(Object{"id": key, "age": i, "rogue": i%2 == 0})
+		assert.NoError(t, err)
+		indices[key] = idx
+	}
+
+	assert.Equal(t, 100, s.Count())
+	for _, shard := range s.Shards() {
+		assert.Greater(t, shard.Count(), 0)
+	}
+
+	// Look up the rows by key and by index
+	assert.NoError(t, s.QueryKey("player-42", func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 42, age)
+		return nil
+	}))
+	assert.NoError(t, s.QueryAt(indices["player-7"], func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 7, age)
+		return nil
+	}))
+
+	// Fan out a query and aggregate the results
+	sum, err := Aggregate(s, func(txn *Txn) (int, error) {
+		return txn.With("rogue").Int("age").Sum(), nil
+	}, func(a, b int) int {
+		return a + b
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2450, sum)
+
+	// Fan out a deletion
+	assert.NoError(t, s.Query(func(txn *Txn) error {
+		txn.With("rogue").DeleteAll()
+		return nil
+	}))
+	assert.Equal(t, 50, s.Count())
+
+	// Delete by index
+	assert.True(t, s.DeleteAt(indices["player-7"]))
+	assert.False(t, s.DeleteAt(indices["player-7"]))
+	assert.False(t, s.DeleteAt(shardIndex(10, 0)))
+	assert.Equal(t, 49, s.Count())
+}
+
+func TestShardedErrors(t *testing.T) {
+	s := NewShardedCollection(0)
+	defer s.Close()
+	assert.Len(t, s.Shards(), 1)
+
+	// No key column
+	_, err := s.InsertObject(Object{"id": "a"})
+	assert.Equal(t, errNoKey, err)
+	assert.Equal(t, errNoKey, s.QueryKey("a", func(r Row) error { return nil }))
+
+	// Missing key
+	assert.NoError(t, s.CreateColumn("id", ForKey()))
+	_, err = s.InsertObject(Object{"age": 1})
+	assert.Error(t, err)
+
+	// Duplicate column and missing shard
+	assert.Error(t, s.CreateColumn("id", ForKey()))
+	assert.Error(t, s.QueryAt(shardIndex(3, 0), func(r Row) error { return nil }))
+
+	// The errors of the shards are returned
+	assert.Error(t, s.Query(func(txn *Txn) error {
+		return errors.New("rollback")
+	}))
+}
+
+// newShardedPlayers creates a sharded collection with the schema used by the tests
+func newShardedPlayers(shards int) *ShardedCollection {
+	s := NewShardedCollection(shards, Options{
+		Vacuum: time.Hour,
+	})
+	s.CreateColumn("id", ForKey())
+	s.CreateColumn("age", ForInt())
+	s.CreateColumn("rogue", ForBool())
+	s.CreateHashIndex("age")
+	s.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() > 50
+	})
+	return s
+}
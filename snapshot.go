@@ -4,12 +4,11 @@
 package column
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"sync/atomic"
-	"unsafe"
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
@@ -21,11 +20,40 @@ var (
 	errUnexpectedEOF = errors.New("column: unable to restore, unexpected EOF")
 )
 
+// Versions of the snapshot format. The second version allows each column to be encoded
+// with a codec, while the third one is the format of the incremental snapshots.
+const (
+	stateVersion1 = 0x1
+	stateVersion2 = 0x2
+	stateVersion3 = 0x3 // A delta, which only contains the chunks modified since a commit
+)
+
 // --------------------------- Commit Replay ---------------------------
 
 // Replay replays a commit on a collection, applying the changes.
 func (c *Collection) Replay(change commit.Commit) error {
+	return c.replay(change, true)
+}
+
+// ReplayFrom replays the commits of the log store on a collection, starting with the specified
+// commit ID. The replayed commits are not appended to the writer of the collection, since they
+// are already durable, hence the store can be used as the writer of the collection as well.
+func (c *Collection) ReplayFrom(store commit.LogStore, commitID uint64) error {
+	return store.Range(commitID, func(change commit.Commit) error {
+		return c.replay(change, false)
+	})
+}
+
+// replay replays a commit on a collection and optionally appends it to the writer
+func (c *Collection) replay(change commit.Commit, logged bool) error {
 	return c.Query(func(txn *Txn) error {
+		if !logged {
+			txn.logger = nil
+		}
+
+		txn.meta = change.Meta
+		txn.merged = true
+
 		txn.dirty.Set(uint32(change.Chunk))
 		for i := range change.Updates {
 			if !change.Updates[i].IsEmpty() {
@@ -36,28 +64,58 @@ func (c *Collection) Replay(change commit.Commit) error {
 	})
 }
 
+// ReplayUntil replays the commit log from the source on a collection, applying only the
+// commits up to (and including) the specified commit ID. This reconstructs the state of
+// the collection as it was at that commit, which is useful for debugging.
+func (c *Collection) ReplayUntil(src io.Reader, commitID uint64) error {
+	return commit.Open(src).Range(func(change commit.Commit) error {
+		if change.ID <= commitID {
+			return c.Replay(change)
+		}
+		return nil
+	})
+}
+
 // --------------------------- Snapshotting ---------------------------
 
 // Restore restores the collection from the underlying snapshot reader. This operation
-// should be called before any of transactions, right after initialization.
+// should be called before any of transactions, right after initialization. If the collection
+// has encryption keys, the snapshot is decrypted with them. Incremental snapshots are restored
+// on top of the current state of the collection.
 func (c *Collection) Restore(snapshot io.Reader) error {
-	commits, err := c.readState(s2.NewReader(snapshot))
+	commits, err := c.readSnapshot(snapshot)
 	if err != nil {
 		return err
 	}
 
 	// Reconcile the pending commit log
-	return commit.Open(snapshot).Range(func(commit commit.Commit) error {
-		lastCommit := commits[commit.Chunk]
-		if commit.ID > lastCommit {
+	return c.openLog(snapshot).Range(func(commit commit.Commit) error {
+		if int(commit.Chunk) >= len(commits) || commit.ID > commits[commit.Chunk] {
 			return c.Replay(commit)
 		}
 		return nil
 	})
 }
 
-// Snapshot writes a collection snapshot into the underlying writer.
+// Snapshot writes a collection snapshot into the underlying writer. If the collection has
+// encryption keys, both the state and the commits recorded in the meantime are encrypted,
+// including the temporary file of the recorder.
 func (c *Collection) Snapshot(dst io.Writer) error {
+	return c.snapshot(dst, c.writeState)
+}
+
+// SnapshotSince writes an incremental snapshot into the underlying writer, which only contains
+// the chunks of rows modified after the specified commit, typically the LastCommit() read right
+// before the previous snapshot was taken. An incremental snapshot is restored by calling
+// Restore() on top of the previous snapshot, and the deltas must be restored in order.
+func (c *Collection) SnapshotSince(commitID uint64, dst io.Writer) error {
+	return c.snapshot(dst, func(w io.Writer) (int64, error) {
+		return c.writeDelta(w, commitID)
+	})
+}
+
+// snapshot writes the state into the writer, followed by the commits recorded in the meantime
+func (c *Collection) snapshot(dst io.Writer, writeState func(io.Writer) (int64, error)) error {
 	recorder, err := c.recorderOpen()
 	if err != nil {
 		return err
@@ -65,7 +123,7 @@ func (c *Collection) Snapshot(dst io.Writer) error {
 
 	// Take a snapshot of the current state
 	defer os.Remove(recorder.Name())
-	if _, err := c.writeState(s2.NewWriter(dst)); err != nil {
+	if err := c.writeSnapshot(dst, writeState); err != nil {
 		return err
 	}
 
@@ -74,52 +132,128 @@ func (c *Collection) Snapshot(dst io.Writer) error {
 	return recorder.Copy(dst)
 }
 
-// recorderOpen opens a recorder for commits while the snapshot is in progress
-func (c *Collection) recorderOpen() (log *commit.Log, err error) {
-	if log, err = commit.OpenTemp(); err == nil {
-		dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
-		ptr := unsafe.Pointer(log)
-		if !atomic.CompareAndSwapPointer(dst, nil, ptr) {
-			return nil, fmt.Errorf("column: unable to snapshot, another one might be in progress")
+// writeSnapshot writes the state of the collection, compressed with the codec of the
+// collection and encrypted if there are encryption keys
+func (c *Collection) writeSnapshot(dst io.Writer, writeState func(io.Writer) (int64, error)) (err error) {
+	var encrypter *commit.Encrypter
+	if keys := c.opts.Encryption; keys != nil {
+		if encrypter, err = commit.NewEncrypter(dst, keys); err != nil {
+			return err
 		}
+		dst = encrypter
+	}
+
+	switch codec := c.opts.Compression; codec {
+	case nil:
+		_, err = writeState(s2.NewWriter(dst))
+	default:
+		compressor := commit.NewCompressor(dst, codec)
+		if _, err = writeState(compressor); err == nil {
+			err = compressor.Close()
+		}
+	}
+
+	if err == nil && encrypter != nil {
+		err = encrypter.Close()
 	}
 	return
 }
 
-// recorderClose closes the pending commit recorder and deletes the file
-func (c *Collection) recorderClose() {
-	if _, ok := c.isSnapshotting(); ok {
-		dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
-		atomic.StorePointer(dst, nil)
+// readSnapshot reads the state of the collection from a snapshot, decompressing it with the
+// codec of the collection and decrypting it if there are encryption keys. It returns the
+// commit IDs of the chunks in the snapshot.
+func (c *Collection) readSnapshot(snapshot io.Reader) ([]uint64, error) {
+	var decrypter *commit.Decrypter
+	if keys := c.opts.Encryption; keys != nil {
+		decrypter = commit.NewDecrypter(snapshot, keys)
+		snapshot = decrypter
 	}
+
+	var decompressor *commit.Decompressor
+	state := io.Reader(s2.NewReader(snapshot))
+	if codec := c.opts.Compression; codec != nil {
+		decompressor = commit.NewDecompressor(snapshot, codec)
+		state = decompressor
+	}
+
+	commits, err := c.readState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the state up to its end, so that the commit log which follows can be read
+	if decompressor != nil {
+		if _, err := io.Copy(io.Discard, decompressor); err != nil {
+			return nil, err
+		}
+	}
+
+	// The last frame of the encrypted state must be authenticated as well
+	if decrypter != nil {
+		if _, err := io.Copy(io.Discard, decrypter); err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
 }
 
-// isSnapshotting loads a currently used commit log for a pending snapshot
-func (c *Collection) isSnapshotting() (*commit.Log, bool) {
-	dst := (*unsafe.Pointer)(unsafe.Pointer(&c.record))
-	ptr := atomic.LoadPointer(dst)
-	if ptr == nil {
-		return nil, false
+// logOptions returns the options of the commit logs written along with the snapshots
+func (c *Collection) logOptions() commit.LogOptions {
+	return commit.LogOptions{
+		Codec:      c.opts.Compression,
+		Encryption: c.opts.Encryption,
 	}
+}
 
-	return (*commit.Log)(ptr), true
+// recorderLog opens the temporary commit log which records the commits during a snapshot
+func (c *Collection) recorderLog() (*commit.Log, error) {
+	return commit.OpenTempWith(c.logOptions())
+}
+
+// openLog opens the commit log which follows the state in a snapshot
+func (c *Collection) openLog(src io.Reader) *commit.Log {
+	return commit.OpenWith(src, c.logOptions())
 }
 
 // --------------------------- Collection Encoding ---------------------------
 
 // writeState writes collection state into the specified writer.
 func (c *Collection) writeState(dst io.Writer) (int64, error) {
+	chunks := make([]commit.Chunk, c.chunks())
+	for i := range chunks {
+		chunks[i] = commit.Chunk(i)
+	}
+
+	return c.writeChunks(dst, stateVersion2, chunks)
+}
+
+// writeDelta writes the state of the chunks which were modified after the specified commit
+// into the writer. Every chunk is written along with its index.
+func (c *Collection) writeDelta(dst io.Writer, commitID uint64) (int64, error) {
+	c.lock.RLock()
+	chunks := make([]commit.Chunk, 0, 16)
+	for i, last := range c.commits {
+		if last > commitID {
+			chunks = append(chunks, commit.Chunk(i))
+		}
+	}
+	c.lock.RUnlock()
+
+	return c.writeChunks(dst, stateVersion3, chunks)
+}
+
+// writeChunks writes the state of the specified chunks into the writer
+func (c *Collection) writeChunks(dst io.Writer, version uint64, chunks []commit.Chunk) (int64, error) {
 	writer := iostream.NewWriter(dst)
 	buffer := c.txns.acquirePage(rowColumn)
 	defer c.txns.releasePage(buffer)
 
 	// Write the schema version
-	if err := writer.WriteUvarint(0x1); err != nil {
+	if err := writer.WriteUvarint(version); err != nil {
 		return writer.Offset(), err
 	}
 
-	// Load the number of columns and the max index
-	chunks := c.chunks()
+	// Load the number of columns
 	columns := uint64(c.cols.Count()) + 1 // extra 'insert' column
 
 	// Write the number of columns
@@ -128,10 +262,17 @@ func (c *Collection) writeState(dst io.Writer) (int64, error) {
 	}
 
 	// Write each chunk
-	if err := writer.WriteRange(chunks, func(i int, w *iostream.Writer) error {
-		return c.readChunk(commit.Chunk(i), func(lastCommit uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
+	if err := writer.WriteRange(len(chunks), func(i int, w *iostream.Writer) error {
+		return c.readChunk(chunks[i], func(lastCommit uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
 			offset := chunk.Min()
 
+			// Write the index of the chunk, since the delta only contains some of them
+			if version == stateVersion3 {
+				if err := writer.WriteUvarint(uint64(chunk)); err != nil {
+					return err
+				}
+			}
+
 			// Write the last written commit for this chunk
 			if err := writer.WriteUvarint(lastCommit); err != nil {
 				return err
@@ -142,7 +283,7 @@ func (c *Collection) writeState(dst io.Writer) (int64, error) {
 			fill.Range(func(idx uint32) {
 				buffer.PutOperation(commit.Insert, offset+idx)
 			})
-			if err := writer.WriteSelf(buffer); err != nil {
+			if err := writeBuffer(writer, buffer, ""); err != nil {
 				return err
 			}
 
@@ -151,7 +292,7 @@ func (c *Collection) writeState(dst io.Writer) (int64, error) {
 				if !column.Snapshot(chunk, buffer) {
 					return nil // Skip indexes
 				}
-				return writer.WriteSelf(buffer)
+				return writeBuffer(writer, buffer, column.opts.Codec)
 			})
 		})
 	}); err != nil {
@@ -169,7 +310,7 @@ func (c *Collection) readState(src io.Reader) ([]uint64, error) {
 
 	// Read the version and make sure it matches
 	version, err := r.ReadUvarint()
-	if err != nil || version != 0x1 {
+	if err != nil || (version != stateVersion1 && version != stateVersion2 && version != stateVersion3) {
 		return nil, fmt.Errorf("column: unable to restore (version %d) %v", version, err)
 	}
 
@@ -180,18 +321,27 @@ func (c *Collection) readState(src io.Reader) ([]uint64, error) {
 	}
 
 	// Read each chunk
-	return commits, r.ReadRange(func(chunk int, r *iostream.Reader) error {
+	err = r.ReadRange(func(chunk int, r *iostream.Reader) error {
+		if version == stateVersion3 {
+			if chunk, err = c.readDeltaChunk(r); err != nil {
+				return err
+			}
+		}
+
 		return c.Query(func(txn *Txn) error {
 			txn.dirty.Set(uint32(chunk))
 
 			// Read the last written commit ID for the chunk
+			for len(commits) <= chunk {
+				commits = append(commits, 0)
+			}
 			if commits[chunk], err = r.ReadUvarint(); err != nil {
 				return err
 			}
 
 			for i := uint64(0); i < columns; i++ {
 				buffer := txn.owner.txns.acquirePage("")
-				_, err := buffer.ReadFrom(r)
+				err := readBuffer(r, buffer, version)
 				switch {
 				case err == io.EOF && i < columns:
 					return errUnexpectedEOF
@@ -205,6 +355,88 @@ func (c *Collection) readState(src io.Reader) ([]uint64, error) {
 			return nil
 		})
 	})
+	return commits, err
+}
+
+// readDeltaChunk reads the index of a chunk of a delta and deletes all of its rows, so that
+// the chunk is entirely replaced by the state of the delta.
+func (c *Collection) readDeltaChunk(r *iostream.Reader) (int, error) {
+	index, err := r.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := commit.Chunk(index)
+	return int(chunk), c.Query(func(txn *Txn) error {
+		c.lock.RLock()
+		fill := chunk.OfBitmap(c.fill)
+		c.lock.RUnlock()
+
+		offset := chunk.Min()
+		fill.Range(func(idx uint32) {
+			txn.deleteAt(offset + idx)
+		})
+		return nil
+	})
+}
+
+// writeBuffer writes the buffer into the writer, encoding it with a codec if specified
+func writeBuffer(w *iostream.Writer, buffer *commit.Buffer, codecName string) error {
+	if err := w.WriteString(codecName); err != nil {
+		return err
+	}
+
+	if codecName == "" {
+		return w.WriteSelf(buffer)
+	}
+
+	codec, ok := codecOf(codecName)
+	if !ok {
+		return fmt.Errorf("column: unable to snapshot, codec '%s' does not exist", codecName)
+	}
+
+	encoded := bytes.NewBuffer(nil)
+	if _, err := buffer.WriteTo(encoded); err != nil {
+		return err
+	}
+
+	return w.WriteBytes(codec.Encode(nil, encoded.Bytes()))
+}
+
+// readBuffer reads the buffer from the reader, decoding it with a codec if specified
+func readBuffer(r *iostream.Reader, buffer *commit.Buffer, version uint64) error {
+	if version == stateVersion1 {
+		_, err := buffer.ReadFrom(r)
+		return err
+	}
+
+	codecName, err := r.ReadString()
+	if err != nil {
+		return err
+	}
+
+	if codecName == "" {
+		_, err := buffer.ReadFrom(r)
+		return err
+	}
+
+	codec, ok := codecOf(codecName)
+	if !ok {
+		return fmt.Errorf("column: unable to restore, codec '%s' does not exist", codecName)
+	}
+
+	encoded, err := r.ReadBytes()
+	if err != nil {
+		return err
+	}
+
+	decoded, err := codec.Decode(nil, encoded)
+	if err != nil {
+		return err
+	}
+
+	_, err = buffer.ReadFrom(bytes.NewReader(decoded))
+	return err
 }
 
 // chunks returns the number of chunks and columns
@@ -219,6 +219,115 @@ func TestSnapshotFailures(t *testing.T) {
 	}
 }
 
+func TestSnapshotEncrypted(t *testing.T) {
+	keys := &commit.Keyring{
+		Current: "v1",
+		Keys:    map[string][]byte{"v1": bytes.Repeat([]byte{7}, 32)},
+	}
+
+	newCollection := func(keys commit.KeyProvider) *Collection {
+		out := NewCollection(Options{Encryption: keys})
+		out.CreateColumn("name", ForString())
+		return out
+	}
+
+	input := newCollection(keys)
+	for i := 0; i < 1000; i++ {
+		input.Insert(func(r Row) error {
+			r.SetString("name", fmt.Sprintf("confidential-%d", i))
+			return nil
+		})
+	}
+
+	// Keep updating the collection while the snapshot is in progress
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			input.QueryAt(uint32(i), func(r Row) error {
+				r.SetString("name", "confidential")
+				return nil
+			})
+		}
+	}()
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(buffer))
+	assert.False(t, bytes.Contains(buffer.Bytes(), []byte("confidential")))
+	<-done
+
+	// The snapshot can not be restored without the key
+	assert.Error(t, newCollection(nil).Restore(bytes.NewReader(buffer.Bytes())))
+
+	output := newCollection(keys)
+	assert.NoError(t, output.Restore(bytes.NewReader(buffer.Bytes())))
+	assert.Equal(t, 1000, output.Count())
+	assert.NoError(t, output.QueryAt(999, func(r Row) error {
+		name, _ := r.String("name")
+		assert.Contains(t, name, "confidential")
+		return nil
+	}))
+}
+
+func TestSnapshotSince(t *testing.T) {
+	newCollection := func() *Collection {
+		out := NewCollection()
+		out.CreateColumn("name", ForString())
+		out.CreateColumn("balance", ForFloat64())
+		return out
+	}
+
+	input := newCollection()
+	for i := 0; i < 3*chunkSize; i++ {
+		input.InsertObject(Object{"name": fmt.Sprintf("player-%d", i), "balance": 1.0})
+	}
+
+	since := input.LastCommit()
+	base := bytes.NewBuffer(nil)
+	assert.NoError(t, input.Snapshot(base))
+
+	// Modify the second chunk only
+	assert.NoError(t, input.QueryAt(chunkSize+1, func(r Row) error {
+		r.SetFloat64("balance", 100)
+		r.SetString("name", "Merlin")
+		return nil
+	}))
+	input.DeleteAt(chunkSize + 2)
+
+	delta := bytes.NewBuffer(nil)
+	assert.NoError(t, input.SnapshotSince(since, delta))
+	assert.Less(t, delta.Len()*2, base.Len())
+
+	// Restore the delta on top of the base
+	output := newCollection()
+	assert.NoError(t, output.Restore(base))
+	assert.NoError(t, output.Restore(delta))
+	assert.Equal(t, 3*chunkSize-1, output.Count())
+	assert.False(t, output.fill.Contains(chunkSize+2))
+	assert.NoError(t, output.QueryAt(chunkSize+1, func(r Row) error {
+		name, _ := r.String("name")
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, "Merlin", name)
+		assert.Equal(t, 100.0, balance)
+		return nil
+	}))
+	assert.NoError(t, output.Query(func(txn *Txn) error {
+		assert.Equal(t, float64(3*chunkSize-2)+100, txn.Float64("balance").Sum())
+		return nil
+	}))
+
+	// A chunk whose rows are all deleted is emptied by the delta
+	since = input.LastCommit()
+	for i := uint32(0); i < chunkSize; i++ {
+		input.DeleteAt(i)
+	}
+
+	delta.Reset()
+	assert.NoError(t, input.SnapshotSince(since, delta))
+	assert.NoError(t, output.Restore(delta))
+	assert.Equal(t, 2*chunkSize-1, output.Count())
+}
+
 func TestRestoreIncomplete(t *testing.T) {
 	buffer := bytes.NewBuffer(nil)
 	output := newEmpty(500)
@@ -379,3 +488,76 @@ func (w *limitWriter) Write(p []byte) (int, error) {
 func (w *limitWriter) Read(p []byte) (int, error) {
 	return 0, nil
 }
+
+func TestReplayUntil(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	source := NewCollection(Options{
+		Writer: commit.Open(buffer),
+	})
+	source.CreateColumn("name", ForString())
+	source.CreateColumn("balance", ForFloat64())
+
+	// Insert a row and update it a couple of times
+	source.InsertObject(Object{"name": "Roman", "balance": 10.0})
+	for i := 0; i < 3; i++ {
+		source.QueryAt(0, func(r Row) error {
+			r.AddFloat64("balance", 10.0)
+			return nil
+		})
+	}
+
+	// Collect the commit IDs from the log
+	var commits []uint64
+	assert.NoError(t, commit.Open(bytes.NewReader(buffer.Bytes())).Range(func(c commit.Commit) error {
+		commits = append(commits, c.ID)
+		return nil
+	}))
+	assert.Len(t, commits, 4)
+
+	// Reconstruct the state as of the second commit
+	target := NewCollection()
+	target.CreateColumn("name", ForString())
+	target.CreateColumn("balance", ForFloat64())
+	assert.NoError(t, target.ReplayUntil(bytes.NewReader(buffer.Bytes()), commits[1]))
+	assert.NoError(t, target.QueryAt(0, func(r Row) error {
+		balance, ok := r.Float64("balance")
+		assert.True(t, ok)
+		assert.Equal(t, 20.0, balance)
+		return nil
+	}))
+}
+
+func TestReplayFrom(t *testing.T) {
+	store := commit.NewMemoryStore()
+	source := NewCollection(Options{
+		Writer: store,
+	})
+	source.CreateColumn("name", ForString())
+	source.CreateColumn("balance", ForFloat64())
+	source.InsertObject(Object{"name": "Roman", "balance": 10.0})
+	source.QueryAt(0, func(r Row) error {
+		r.AddFloat64("balance", 10.0)
+		return nil
+	})
+
+	// Recover a collection which uses the same store as its writer
+	target := NewCollection(Options{
+		Writer: store,
+	})
+	target.CreateColumn("name", ForString())
+	target.CreateColumn("balance", ForFloat64())
+	assert.NoError(t, target.ReplayFrom(store, 0))
+	assert.NoError(t, target.QueryAt(0, func(r Row) error {
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, 20.0, balance)
+		return nil
+	}))
+
+	// The replayed commits must not be appended again
+	count := 0
+	assert.NoError(t, store.Range(0, func(commit.Commit) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+}
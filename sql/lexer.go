@@ -0,0 +1,128 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind represents a kind of a lexical token
+type tokenKind uint8
+
+// Various kinds of tokens
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenKeyword
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+// token represents a single lexical token of the query
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+}
+
+// keywords represents the set of reserved words, which are case-insensitive
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true,
+	"NOT": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"LIMIT": true, "IN": true, "IS": true, "NULL": true, "TRUE": true,
+	"FALSE": true,
+}
+
+// tokenize splits the query into a sequence of tokens, terminated by an EOF token.
+func tokenize(query string) ([]token, error) {
+	tokens := make([]token, 0, 16)
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isLetter(c):
+			start := i
+			for i < len(query) && (isLetter(query[i]) || isDigit(query[i])) {
+				i++
+			}
+
+			word := query[start:i]
+			if upper := strings.ToUpper(word); keywords[upper] {
+				tokens = append(tokens, token{kind: tokenKeyword, text: upper, start: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: word, start: start})
+			}
+
+		case isDigit(c) || (c == '-' || c == '.') && i+1 < len(query) && isDigit(query[i+1]):
+			start := i
+			i++
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.' || query[i] == 'e' || query[i] == 'E') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: query[start:i], start: start})
+
+		case c == '\'' || c == '"' || c == '`':
+			text, next, err := readQuoted(query, i)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := tokenIdent
+			if c == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: text, start: i})
+			i = next
+
+		case strings.HasPrefix(query[i:], "<=") || strings.HasPrefix(query[i:], ">=") ||
+			strings.HasPrefix(query[i:], "!=") || strings.HasPrefix(query[i:], "<>"):
+			tokens = append(tokens, token{kind: tokenSymbol, text: query[i : i+2], start: i})
+			i += 2
+
+		case strings.IndexByte("=<>(),*", c) >= 0:
+			tokens = append(tokens, token{kind: tokenSymbol, text: query[i : i+1], start: i})
+			i++
+
+		default:
+			return nil, fmt.Errorf("column: unexpected character '%c' at position %d", c, i)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, start: len(query)}), nil
+}
+
+// readQuoted reads a quoted string or identifier starting at the specified position, where
+// the quote character is escaped by doubling it.
+func readQuoted(query string, start int) (string, int, error) {
+	quote := query[start]
+	var out strings.Builder
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != quote {
+			out.WriteByte(query[i])
+			continue
+		}
+
+		if i+1 < len(query) && query[i+1] == quote {
+			out.WriteByte(quote)
+			i++
+			continue
+		}
+
+		return out.String(), i + 1, nil
+	}
+
+	return "", 0, fmt.Errorf("column: unterminated quote at position %d", start)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
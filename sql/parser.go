@@ -0,0 +1,337 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// statement represents a parsed SELECT statement
+type statement struct {
+	columns []string // The projected columns, empty for all of them
+	table   string   // The name of the collection
+	where   expr     // The filter expression, or nil
+	orderBy []order  // The sort order
+	limit   int      // The maximum number of rows, or -1 if unlimited
+}
+
+// order represents a single sort key of the ORDER BY clause
+type order struct {
+	column string
+	desc   bool
+}
+
+// expr represents a boolean expression of the WHERE clause
+type expr interface{}
+
+// Various kinds of expressions
+type (
+	andExpr     struct{ left, right expr }
+	orExpr      struct{ left, right expr }
+	notExpr     struct{ inner expr }
+	columnExpr  struct{ column string }
+	compareExpr struct {
+		column string
+		op     string
+		value  any // int64, float64, string or bool
+	}
+	inExpr struct {
+		column string
+		values []any
+	}
+	nullExpr struct {
+		column string
+		not    bool
+	}
+)
+
+// comparisons represents the set of supported comparison operators
+var comparisons = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// parser represents a recursive descent parser for the supported subset of SQL
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses a SELECT statement
+func parse(query string) (*statement, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	return p.parseSelect()
+}
+
+// parseSelect parses SELECT columns FROM table [WHERE expr] [ORDER BY ...] [LIMIT n]
+func (p *parser) parseSelect() (*statement, error) {
+	stmt := &statement{limit: -1}
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	// Parse the projection
+	if p.acceptSymbol("*") {
+		stmt.columns = nil
+	} else {
+		for {
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+
+			stmt.columns = append(stmt.columns, name)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	// Parse the source table
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+
+	table, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.table = table
+
+	// Parse the optional filter
+	if p.acceptKeyword("WHERE") {
+		if stmt.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse the optional sort order
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+
+		for {
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+
+			desc := p.acceptKeyword("DESC")
+			if !desc {
+				p.acceptKeyword("ASC")
+			}
+
+			stmt.orderBy = append(stmt.orderBy, order{column: name, desc: desc})
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	// Parse the optional limit
+	if p.acceptKeyword("LIMIT") {
+		tok := p.next()
+		limit, err := strconv.Atoi(tok.text)
+		if tok.kind != tokenNumber || err != nil || limit < 0 {
+			return nil, p.errorAt(tok, "expected a non-negative limit")
+		}
+		stmt.limit = limit
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected input")
+	}
+	return stmt, nil
+}
+
+// parseOr parses a disjunction of conjunctions
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.acceptKeyword("OR") {
+		var right expr
+		if right, err = p.parseAnd(); err == nil {
+			left = orExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseAnd parses a conjunction of unary expressions
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.acceptKeyword("AND") {
+		var right expr
+		if right, err = p.parseUnary(); err == nil {
+			left = andExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseUnary parses a negation, a parenthesized expression or a predicate
+func (p *parser) parseUnary() (expr, error) {
+	switch {
+	case p.acceptKeyword("NOT"):
+		inner, err := p.parseUnary()
+		return notExpr{inner}, err
+	case p.acceptSymbol("("):
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptSymbol(")") {
+			return nil, p.errorAt(p.peek(), "expected ')'")
+		}
+		return inner, nil
+	default:
+		return p.parsePredicate()
+	}
+}
+
+// parsePredicate parses a comparison, a membership test, a null check or a bare column
+func (p *parser) parsePredicate() (expr, error) {
+	column, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := p.peek(); {
+	case tok.kind == tokenSymbol && comparisons[tok.text]:
+		p.next()
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+
+		op := tok.text
+		if op == "<>" {
+			op = "!="
+		}
+		return compareExpr{column: column, op: op, value: value}, nil
+
+	case p.acceptKeyword("IS"):
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return nullExpr{column: column, not: not}, nil
+
+	case p.acceptKeyword("NOT"):
+		if err := p.expectKeyword("IN"); err != nil {
+			return nil, err
+		}
+		in, err := p.parseIn(column)
+		return notExpr{in}, err
+
+	case p.acceptKeyword("IN"):
+		return p.parseIn(column)
+
+	default:
+		return columnExpr{column: column}, nil
+	}
+}
+
+// parseIn parses the list of values of a membership test
+func (p *parser) parseIn(column string) (expr, error) {
+	if !p.acceptSymbol("(") {
+		return nil, p.errorAt(p.peek(), "expected '('")
+	}
+
+	in := inExpr{column: column}
+	for {
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+
+		in.values = append(in.values, value)
+		if p.acceptSymbol(")") {
+			return in, nil
+		}
+		if !p.acceptSymbol(",") {
+			return nil, p.errorAt(p.peek(), "expected ',' or ')'")
+		}
+	}
+}
+
+// parseLiteral parses a number, a string or a boolean literal
+func (p *parser) parseLiteral() (any, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenString:
+		return tok.text, nil
+	case tok.kind == tokenKeyword && tok.text == "TRUE":
+		return true, nil
+	case tok.kind == tokenKeyword && tok.text == "FALSE":
+		return false, nil
+	case tok.kind == tokenNumber:
+		if v, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return v, nil
+		}
+	}
+
+	return nil, p.errorAt(tok, "expected a literal value")
+}
+
+// --------------------------- Tokens ----------------------------
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if tok := p.peek(); tok.kind == tokenKeyword && tok.text == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if tok := p.peek(); tok.kind == tokenSymbol && tok.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.errorAt(p.peek(), "expected "+keyword)
+	}
+	return nil
+}
+
+func (p *parser) expectIdent() (string, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return "", p.errorAt(tok, "expected an identifier")
+	}
+	return tok.text, nil
+}
+
+// errorAt returns a syntax error at the position of the token
+func (p *parser) errorAt(tok token, message string) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("column: syntax error at end of query, %s", message)
+	}
+	return fmt.Errorf("column: syntax error at position %d near '%s', %s", tok.start, tok.text, message)
+}
@@ -0,0 +1,467 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package sql provides a query layer which parses a subset of SQL and plans it onto the
+// bitmap operations and typed filters of the column transactions. The supported grammar is
+//
+//	SELECT * | column [, column ...] FROM collection
+//	[WHERE condition]
+//	[ORDER BY column [ASC | DESC] [, ...]]
+//	[LIMIT n]
+//
+// where a condition combines, with AND, OR, NOT and parentheses, the comparisons of a column
+// with a literal (=, !=, <>, <, <=, >, >=), membership tests (IN, NOT IN), null checks (IS NULL,
+// IS NOT NULL) and bare boolean columns or indexes. The literals are numbers, single-quoted
+// strings, TRUE and FALSE. Numeric comparisons are performed on float64 values.
+package sql
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column"
+)
+
+// Source represents a set of named collections which can be queried, such as a catalog.
+type Source interface {
+	Collection(name string) (*column.Collection, bool)
+}
+
+// Tables represents a set of named collections, which can be used as a source of the queries.
+type Tables map[string]*column.Collection
+
+// Collection returns a collection with the specified name, if it exists.
+func (t Tables) Collection(name string) (*column.Collection, bool) {
+	c, ok := t[name]
+	return c, ok
+}
+
+// Names returns the sorted names of the collections.
+func (t Tables) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Result represents the result set of a query
+type Result struct {
+	Columns []string // The names of the projected columns
+	Rows    [][]any  // The values of the rows, nil for missing values
+	Indexes []uint32 // The offsets of the rows, in the same order
+}
+
+// Query parses the SELECT statement and executes it against the collections of the source. The
+// statement is executed within a read-only view of the collection.
+func Query(source Source, query string) (*Result, error) {
+	stmt, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := source.Collection(stmt.table)
+	if !ok {
+		return nil, fmt.Errorf("column: collection '%s' does not exist", stmt.table)
+	}
+
+	return execute(c, stmt)
+}
+
+// execute executes the statement against the collection
+func execute(c *column.Collection, stmt *statement) (*Result, error) {
+	if err := validate(c, stmt); err != nil {
+		return nil, err
+	}
+
+	// Evaluate the disjunctions and negations into bitmaps before the main query
+	where, err := prepare(c, stmt.where)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: stmt.columns}
+	if len(result.Columns) == 0 {
+		result.Columns = c.Columns()
+	}
+
+	// Only read until the limit, if there is no sort order to satisfy first
+	limit := stmt.limit
+	if len(stmt.orderBy) > 0 {
+		limit = -1
+	}
+
+	var keys [][]any
+	if err := c.View(func(txn *column.Txn) error {
+		apply(txn, where)
+
+		var indexes []uint32
+		if err := txn.Range(func(idx uint32) {
+			if limit < 0 || len(indexes) < limit {
+				indexes = append(indexes, idx)
+			}
+		}); err != nil {
+			return err
+		}
+
+		// Read the projected values and the sort keys of the selected rows
+		result.Rows = make([][]any, 0, len(indexes))
+		result.Indexes = make([]uint32, 0, len(indexes))
+		return c.SelectAt(indexes, func(v column.Selector) {
+			result.Rows = append(result.Rows, readValues(v, result.Columns))
+			result.Indexes = append(result.Indexes, v.Index())
+			if len(stmt.orderBy) > 0 {
+				keys = append(keys, readSortKeys(v, stmt.orderBy))
+			}
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(stmt.orderBy) > 0 {
+		sortRows(result, keys, stmt.orderBy)
+	}
+
+	if stmt.limit >= 0 && len(result.Rows) > stmt.limit {
+		result.Rows = result.Rows[:stmt.limit]
+		result.Indexes = result.Indexes[:stmt.limit]
+	}
+	return result, nil
+}
+
+// validate checks that all of the projected and sort columns exist. The filters are not
+// validated, since they may also refer to indexes which are not part of the columns.
+func validate(c *column.Collection, stmt *statement) error {
+	known := make(map[string]bool, 8)
+	for _, name := range c.Columns() {
+		known[name] = true
+	}
+
+	names := append([]string{}, stmt.columns...)
+	for _, o := range stmt.orderBy {
+		names = append(names, o.column)
+	}
+
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("column: column '%s' does not exist", name)
+		}
+	}
+	return nil
+}
+
+// --------------------------- Planning ----------------------------
+
+// bitmapExpr represents a sub-expression which was already evaluated into a set of rows
+type bitmapExpr struct {
+	rows bitmap.Bitmap
+}
+
+// prepare replaces the disjunctions and negations of the expression, which cannot be applied
+// onto a transaction as a chain of filters, with the bitmaps of the rows they match.
+func prepare(c *column.Collection, e expr) (expr, error) {
+	switch v := e.(type) {
+	case andExpr:
+		left, err := prepare(c, v.left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := prepare(c, v.right)
+		return andExpr{left, right}, err
+
+	case orExpr, notExpr:
+		rows, err := evaluate(c, v)
+		return bitmapExpr{rows}, err
+
+	default:
+		return e, nil
+	}
+}
+
+// evaluate evaluates the expression into the set of rows it matches
+func evaluate(c *column.Collection, e expr) (rows bitmap.Bitmap, err error) {
+	switch v := e.(type) {
+	case orExpr:
+		left, err := evaluate(c, v.left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := evaluate(c, v.right)
+		left.Or(right)
+		return left, err
+
+	case notExpr:
+		all, err := evaluate(c, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		inner, err := evaluate(c, v.inner)
+		all.AndNot(inner)
+		return all, err
+	}
+
+	if e, err = prepare(c, e); err != nil {
+		return nil, err
+	}
+
+	err = c.Query(func(txn *column.Txn) error {
+		return apply(txn, e).Range(func(idx uint32) {
+			rows.Set(idx)
+		})
+	})
+	return
+}
+
+// apply applies the conjunction of filters onto the transaction
+func apply(txn *column.Txn, e expr) *column.Txn {
+	switch v := e.(type) {
+	case nil:
+		return txn
+	case andExpr:
+		return apply(apply(txn, v.left), v.right)
+	case bitmapExpr:
+		return txn.WithBitmap(v.rows)
+	case columnExpr:
+		return txn.With(v.column)
+	case nullExpr:
+		if v.not {
+			return txn.With(v.column)
+		}
+		return txn.Without(v.column)
+	case inExpr:
+		return applyIn(txn, v)
+	case compareExpr:
+		return applyCompare(txn, v)
+	default:
+		panic(fmt.Errorf("column: unsupported expression %T", e))
+	}
+}
+
+// applyCompare applies a comparison of a column with a literal onto the transaction
+func applyCompare(txn *column.Txn, e compareExpr) *column.Txn {
+	switch value := e.value.(type) {
+	case bool:
+		if (e.op == "=") == value {
+			return txn.With(e.column)
+		}
+		return txn.Without(e.column)
+
+	case string:
+		if e.op == "=" {
+			return txn.WithEqual(e.column, value)
+		}
+
+		return txn.WithString(e.column, func(v string) bool {
+			return compare(v, value, e.op)
+		})
+
+	default:
+		number := toFloat(value)
+		switch e.op {
+		case "=":
+			return txn.WithFloatEqual(e.column, number)
+		case "<":
+			return txn.WithFloatLess(e.column, number)
+		case ">":
+			return txn.WithFloatGreater(e.column, number)
+		default:
+			return txn.WithFloat(e.column, func(v float64) bool {
+				return compare(v, number, e.op)
+			})
+		}
+	}
+}
+
+// applyIn applies a membership test onto the transaction
+func applyIn(txn *column.Txn, e inExpr) *column.Txn {
+	texts := make(map[string]bool, len(e.values))
+	numbers := make(map[float64]bool, len(e.values))
+	for _, v := range e.values {
+		switch v := v.(type) {
+		case string:
+			texts[v] = true
+		case int64, float64:
+			numbers[toFloat(v)] = true
+		}
+	}
+
+	if len(texts) > 0 {
+		return txn.WithString(e.column, func(v string) bool {
+			return texts[v]
+		})
+	}
+
+	return txn.WithFloat(e.column, func(v float64) bool {
+		return numbers[v]
+	})
+}
+
+// compare compares two ordered values with the operator
+func compare[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return false
+	}
+}
+
+// toFloat converts a numeric literal into a float64
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// --------------------------- Results ----------------------------
+
+// readValues reads the values of the projected columns
+func readValues(v column.Selector, columns []string) []any {
+	row := make([]any, len(columns))
+	for i, name := range columns {
+		if value, ok := v.Any(name); ok {
+			row[i] = value
+		}
+	}
+	return row
+}
+
+// readSortKeys reads the values of the sort columns
+func readSortKeys(v column.Selector, orderBy []order) []any {
+	keys := make([]any, len(orderBy))
+	for i, o := range orderBy {
+		if value, ok := v.Any(o.column); ok {
+			keys[i] = value
+		}
+	}
+	return keys
+}
+
+// sortRows sorts the rows by their keys, where the missing values come first
+func sortRows(result *Result, keys [][]any, orderBy []order) {
+	sort.Stable(byKeys{rows: result.Rows, indexes: result.Indexes, keys: keys, order: orderBy})
+}
+
+// byKeys sorts the rows along with their offsets and sort keys
+type byKeys struct {
+	rows    [][]any
+	indexes []uint32
+	keys    [][]any
+	order   []order
+}
+
+func (s byKeys) Len() int {
+	return len(s.rows)
+}
+
+func (s byKeys) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+	s.indexes[i], s.indexes[j] = s.indexes[j], s.indexes[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s byKeys) Less(i, j int) bool {
+	for k, o := range s.order {
+		if c := compareAny(s.keys[i][k], s.keys[j][k]); c != 0 {
+			return (c < 0) != o.desc
+		}
+	}
+	return false
+}
+
+// compareAny compares two values read from the columns, the numbers are compared as float64
+func compareAny(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+		}
+		return 0
+	}
+
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok && x != y {
+			if y {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	}
+
+	x, y := numberOf(a), numberOf(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// numberOf converts a numeric value of any type into a float64
+func numberOf(v any) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/kelindar/column"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	tables := Tables{"players": loadPlayers()}
+	tests := []struct {
+		query  string
+		expect [][]any
+	}{
+		{"SELECT name FROM players WHERE age > 30 ORDER BY name", [][]any{{"Lancelot"}, {"Merlin"}}},
+		{"SELECT name, age FROM players WHERE class = 'mage' ORDER BY age DESC", [][]any{{"Merlin", 120}, {"Morgana", 25}}},
+		{"SELECT name FROM players WHERE active AND NOT class = 'mage' ORDER BY name", [][]any{{"Arthur"}, {"Lancelot"}}},
+		{"SELECT name FROM players WHERE class = 'rogue' OR age >= 120 ORDER BY name", [][]any{{"Merlin"}, {"Roman"}}},
+		{"SELECT name FROM players WHERE class IN ('rogue', 'mage') AND active = FALSE ORDER BY name", [][]any{{"Morgana"}, {"Roman"}}},
+		{"SELECT name FROM players WHERE age NOT IN (25, 30, 120) ORDER BY age", [][]any{{"Roman"}, {"Lancelot"}}},
+		{"SELECT name FROM players WHERE score IS NULL", [][]any{{"Roman"}}},
+		{"SELECT name FROM players WHERE score IS NOT NULL ORDER BY score DESC, name LIMIT 2", [][]any{{"Merlin"}, {"Arthur"}}},
+		{"SELECT name FROM players WHERE name <> 'Merlin' AND (age < 26 OR age > 34) ORDER BY name", [][]any{{"Lancelot"}, {"Morgana"}, {"Roman"}}},
+		{"SELECT name FROM players WHERE age > 1000", [][]any{}},
+		{"select name from players where score > 1.5 order by name asc limit 1", [][]any{{"Arthur"}}},
+	}
+
+	for _, tc := range tests {
+		result, err := Query(tables, tc.query)
+		assert.NoError(t, err, tc.query)
+		assert.Equal(t, tc.expect, result.Rows, tc.query)
+	}
+}
+
+func TestQueryAll(t *testing.T) {
+	result, err := Query(Tables{"players": loadPlayers()}, "SELECT * FROM players LIMIT 2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "class", "age", "active", "score"}, result.Columns)
+	assert.Len(t, result.Rows, 2)
+}
+
+func TestQueryIndexes(t *testing.T) {
+	result, err := Query(Tables{"players": loadPlayers()}, "SELECT name FROM players WHERE age > 20 ORDER BY age LIMIT 3")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"Morgana"}, {"Arthur"}, {"Lancelot"}}, result.Rows)
+	assert.Equal(t, []uint32{2, 1, 4}, result.Indexes)
+}
+
+func TestQueryCatalog(t *testing.T) {
+	catalog := column.NewCatalog()
+	players, err := catalog.CreateCollection("players")
+	assert.NoError(t, err)
+	players.CreateColumn("name", column.ForString())
+	players.InsertObject(column.Object{"name": "Roman"})
+
+	result, err := Query(catalog, "SELECT name FROM players")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"Roman"}}, result.Rows)
+}
+
+func TestQueryErrors(t *testing.T) {
+	tables := Tables{"players": loadPlayers()}
+	for _, query := range []string{
+		"SELECT name FROM invalid",
+		"SELECT invalid FROM players",
+		"SELECT name FROM players ORDER BY invalid",
+		"SELECT name FROM players WHERE",
+		"SELECT name FROM players WHERE age >",
+		"SELECT name FROM players WHERE (age > 1",
+		"SELECT name FROM players WHERE age IN 1",
+		"SELECT name FROM players WHERE age IN (1 2)",
+		"SELECT name FROM players WHERE age IS 1",
+		"SELECT name FROM players LIMIT x",
+		"SELECT name FROM players LIMIT 1 2",
+		"SELECT name FROM players WHERE name = 'Roman",
+		"SELECT name FROM players WHERE age ; 1",
+		"SELECT FROM players",
+		"DELETE FROM players",
+		"SELECT name players",
+		"SELECT name FROM players ORDER name",
+		"SELECT name FROM players WHERE name NOT 'x'",
+	} {
+		_, err := Query(tables, query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`SELECT "my col", a FROM t WHERE b >= -1.5 AND c <> 'it''s'`)
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, tok := range tokens[:len(tokens)-1] {
+		texts = append(texts, tok.text)
+	}
+	assert.Equal(t, []string{"SELECT", "my col", ",", "a", "FROM", "t", "WHERE", "b", ">=", "-1.5", "AND", "c", "<>", "it's"}, texts)
+}
+
+func TestCompareAny(t *testing.T) {
+	assert.Equal(t, 0, compareAny(nil, nil))
+	assert.Equal(t, -1, compareAny(nil, 1))
+	assert.Equal(t, 1, compareAny(1, nil))
+	assert.Equal(t, -1, compareAny("a", "b"))
+	assert.Equal(t, 1, compareAny("b", "a"))
+	assert.Equal(t, 0, compareAny("a", 1))
+	assert.Equal(t, -1, compareAny(false, true))
+	assert.Equal(t, 1, compareAny(true, false))
+	assert.Equal(t, -1, compareAny(int32(1), uint8(2)))
+	assert.Equal(t, 1, compareAny(float32(2), int16(1)))
+	assert.Equal(t, 0, compareAny(uint16(1), int8(1)))
+	assert.Equal(t, -1, compareAny(uint(1), uint32(2)))
+	assert.Equal(t, 1, compareAny(uint64(2), 1.0))
+	assert.Equal(t, 1, compareAny(int64(1), struct{}{}))
+}
+
+// loadPlayers creates a small collection of players
+func loadPlayers() *column.Collection {
+	players := column.NewCollection()
+	players.CreateColumn("name", column.ForString())
+	players.CreateColumn("class", column.ForEnum())
+	players.CreateColumn("age", column.ForInt())
+	players.CreateColumn("active", column.ForBool())
+	players.CreateColumn("score", column.ForFloat64())
+	players.InsertMany([]column.Object{
+		{"name": "Merlin", "class": "mage", "age": 120, "active": true, "score": 9.5},
+		{"name": "Arthur", "class": "knight", "age": 30, "active": true, "score": 7.0},
+		{"name": "Morgana", "class": "mage", "age": 25, "active": false, "score": 1.5},
+		{"name": "Roman", "class": "rogue", "age": 18, "active": false},
+		{"name": "Lancelot", "class": "knight", "age": 35, "active": true, "score": 7.0},
+	})
+	return players
+}
@@ -0,0 +1,226 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+	"github.com/zeebo/xxh3"
+)
+
+// ColumnStats represents the statistics of a single column
+type ColumnStats struct {
+	Name     string  // The name of the column
+	Index    bool    // Whether the column is an index
+	Count    int     // The number of rows which have a value
+	Nulls    int     // The number of rows which do not have a value
+	Bytes    int     // The approximate memory used by the column, in bytes
+	Distinct int     // The estimated number of distinct values
+	Numeric  bool    // Whether the column is numeric, hence has a range of values
+	Min      float64 // The smallest value of a numeric column
+	Max      float64 // The largest value of a numeric column
+}
+
+// Stats computes the statistics of every column of the collection, including the indexes, in
+// the order they were created. This scans all of the values, chunk by chunk, and is intended
+// for capacity planning rather than for the hot path. The number of distinct values is an
+// estimate, which is exact for up to a thousand distinct values.
+func (c *Collection) Stats() []ColumnStats {
+	c.lock.RLock()
+	chunks := len(c.commits)
+	c.lock.RUnlock()
+
+	// Prepare the statistics of every column, in order
+	var columns []*column
+	var stats []ColumnStats
+	var sketches []*distinctSketch
+	c.cols.Range(func(column *column) {
+		columns = append(columns, column)
+		sketches = append(sketches, newDistinctSketch(distinctSize))
+		stats = append(stats, ColumnStats{
+			Name:    column.name,
+			Index:   column.IsIndex(),
+			Numeric: column.IsNumeric(),
+			Min:     math.Inf(1),
+			Max:     math.Inf(-1),
+		})
+	})
+
+	// Scan the values of the columns, chunk by chunk
+	var rows bitmap.Bitmap
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		c.readChunk(chunk, func(_ uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
+			for i, column := range columns {
+				stats[i].Bytes += column.sizeOf(chunk)
+				column.Index(chunk).Clone(&rows)
+				rows.And(fill)
+				stats[i].Count += rows.Count()
+				if stats[i].Index {
+					continue
+				}
+
+				offset := chunk.Min()
+				rows.Range(func(x uint32) {
+					stats[i].observe(column, offset+x, sketches[i])
+				})
+			}
+			return nil
+		})
+	}
+
+	// Finalize the statistics
+	total := c.Count()
+	for i := range stats {
+		stats[i].Nulls = total - stats[i].Count
+		stats[i].Distinct = sketches[i].Estimate()
+		if stats[i].Count == 0 || !stats[i].Numeric {
+			stats[i].Min, stats[i].Max = 0, 0
+		}
+	}
+	return stats
+}
+
+// CountDistinct counts the distinct values of the column for the rows selected by this
+// transaction, skipping the rows which do not have a value. The count is exact for up to a
+// thousand distinct values and is otherwise estimated with the same sketch as Stats(), which
+// is typically within a few percent. The second value returned reports whether it is exact.
+func (txn *Txn) CountDistinct(columnName string) (count int, exact bool) {
+	txn.resolve()
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		return 0, true
+	}
+
+	var rows bitmap.Bitmap
+	sketch := newDistinctSketch(distinctSize)
+	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		index.Clone(&rows)
+		rows.And(column.Index(chunk))
+
+		offset := chunk.Min()
+		rows.Range(func(x uint32) {
+			if v, ok := column.Value(offset + x); ok {
+				sketch.Add(hashOf(v))
+			}
+		})
+	})
+	return sketch.Estimate(), sketch.Exact()
+}
+
+// observe observes a value of the column at the specified index
+func (s *ColumnStats) observe(column *column, idx uint32, sketch *distinctSketch) {
+	if s.Numeric {
+		if v, ok := column.Column.(Numeric).LoadFloat64(idx); ok {
+			s.Min = math.Min(s.Min, v)
+			s.Max = math.Max(s.Max, v)
+			sketch.Add(math.Float64bits(v))
+		}
+		return
+	}
+
+	if v, ok := column.Value(idx); ok {
+		sketch.Add(hashOf(v))
+	}
+}
+
+// hashOf computes the hash of a value read from a column
+func hashOf(value any) uint64 {
+	switch v := hashKey(value).(type) {
+	case string:
+		return xxh3.HashString(v)
+	case int64:
+		return mix64(uint64(v))
+	case uint64:
+		return mix64(v)
+	case float64:
+		return mix64(math.Float64bits(v))
+	case bool:
+		if v {
+			return mix64(1)
+		}
+		return mix64(0)
+	default:
+		return xxh3.HashString(fmt.Sprint(v))
+	}
+}
+
+// mix64 scrambles the bits of an integer, so that it can be used as a hash
+func mix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// --------------------------- Distinct Sketch ----------------------------
+
+// distinctSize is the number of hashes kept by the sketch of the distinct values
+const distinctSize = 1024
+
+// distinctSketch estimates the number of distinct values by keeping the smallest k hashes of
+// the values (a "k minimum values" sketch).
+type distinctSketch struct {
+	size   int                 // The number of hashes to keep
+	hashes hashHeap            // The smallest hashes, with the largest one on top
+	seen   map[uint64]struct{} // The set of hashes in the heap
+}
+
+// newDistinctSketch creates a new sketch which keeps the specified number of hashes
+func newDistinctSketch(size int) *distinctSketch {
+	return &distinctSketch{
+		size: size,
+		seen: make(map[uint64]struct{}, size),
+	}
+}
+
+// Add adds the hash of a value into the sketch
+func (s *distinctSketch) Add(hash uint64) {
+	if _, ok := s.seen[hash]; ok {
+		return
+	}
+
+	switch {
+	case len(s.hashes) < s.size:
+		heap.Push(&s.hashes, hash)
+		s.seen[hash] = struct{}{}
+	case hash < s.hashes[0]:
+		delete(s.seen, s.hashes[0])
+		s.hashes[0] = hash
+		s.seen[hash] = struct{}{}
+		heap.Fix(&s.hashes, 0)
+	}
+}
+
+// Estimate returns the estimated number of distinct values added to the sketch
+func (s *distinctSketch) Estimate() int {
+	if s.Exact() {
+		return len(s.hashes)
+	}
+
+	kth := float64(s.hashes[0]) / math.MaxUint64
+	return int(float64(s.size-1) / kth)
+}
+
+// Exact returns whether the estimate is the exact number of distinct hashes, which is the
+// case until the sketch is full
+func (s *distinctSketch) Exact() bool {
+	return len(s.hashes) < s.size
+}
+
+// hashHeap represents a max-heap of hashes
+type hashHeap []uint64
+
+func (h hashHeap) Len() int           { return len(h) }
+func (h hashHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x any)        { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,144 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("class", ForEnum())
+	c.CreateColumn("age", ForInt())
+	c.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() >= 50
+	})
+
+	for i := 0; i < 100; i++ {
+		object := Object{
+			"class": []string{"mage", "rogue", "druid"}[i%3],
+			"age":   i,
+		}
+		if i%2 == 0 {
+			object["name"] = fmt.Sprintf("player-%d", i)
+		}
+		c.InsertObject(object)
+	}
+
+	stats := make(map[string]ColumnStats)
+	for _, s := range c.Stats() {
+		stats[s.Name] = s
+	}
+
+	// The name is only set on half of the rows
+	assert.Equal(t, 50, stats["name"].Count)
+	assert.Equal(t, 50, stats["name"].Nulls)
+	assert.Equal(t, 50, stats["name"].Distinct)
+	assert.False(t, stats["name"].Numeric)
+	assert.Greater(t, stats["name"].Bytes, 0)
+
+	// The class is an enum of three values
+	assert.Equal(t, 100, stats["class"].Count)
+	assert.Equal(t, 3, stats["class"].Distinct)
+
+	// The age is numeric, so it has a range
+	assert.True(t, stats["age"].Numeric)
+	assert.Equal(t, 100, stats["age"].Distinct)
+	assert.Equal(t, 0.0, stats["age"].Min)
+	assert.Equal(t, 99.0, stats["age"].Max)
+	assert.Equal(t, chunkSize*8+chunkSize/8, stats["age"].Bytes)
+
+	// The index only counts the matching rows
+	assert.True(t, stats["old"].Index)
+	assert.Equal(t, 50, stats["old"].Count)
+	assert.Equal(t, 0, stats["old"].Distinct)
+}
+
+func TestStatsEmpty(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("age", ForInt())
+
+	stats := c.Stats()
+	assert.Len(t, stats, 2) // Including the expiration column
+	for _, s := range stats {
+		assert.Equal(t, 0, s.Count)
+		assert.Equal(t, 0.0, s.Min)
+		assert.Equal(t, 0.0, s.Max)
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("class", ForEnum())
+	c.CreateColumn("age", ForInt())
+	c.CreateColumn("id", ForString())
+	for i := 0; i < 20000; i++ {
+		object := Object{
+			"class": []string{"mage", "rogue", "druid"}[i%3],
+			"id":    fmt.Sprintf("player-%d", i),
+		}
+		if i%2 == 0 {
+			object["age"] = i % 100
+		}
+		c.InsertObject(object)
+	}
+
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		count, exact := txn.CountDistinct("class")
+		assert.True(t, exact)
+		assert.Equal(t, 3, count)
+
+		// Only the rows with a value are counted, all of their ages being even
+		count, exact = txn.CountDistinct("age")
+		assert.True(t, exact)
+		assert.Equal(t, 50, count)
+
+		// Beyond a thousand distinct values, the count is estimated
+		count, exact = txn.CountDistinct("id")
+		assert.False(t, exact)
+		assert.InDelta(t, 20000, count, 2000)
+
+		count, exact = txn.CountDistinct("invalid")
+		assert.True(t, exact)
+		assert.Equal(t, 0, count)
+		return nil
+	}))
+
+	// Only the rows selected by the transaction are counted
+	assert.NoError(t, c.Query(func(txn *Txn) error {
+		count, exact := txn.WithValue("class", func(v interface{}) bool {
+			return v == "mage"
+		}).CountDistinct("id")
+		assert.False(t, exact)
+		assert.InDelta(t, 6667, count, 700)
+
+		count, _ = txn.WithValue("id", func(v interface{}) bool {
+			return v == "player-3" || v == "player-6"
+		}).CountDistinct("class")
+		assert.Equal(t, 1, count)
+		return nil
+	}))
+}
+
+func TestDistinctSketch(t *testing.T) {
+	sketch := newDistinctSketch(distinctSize)
+	for i := 0; i < 100000; i++ {
+		sketch.Add(hashOf(i % 50000))
+	}
+
+	// The estimate should be within a few percent of the actual value
+	assert.InDelta(t, 50000, sketch.Estimate(), 5000)
+}
+
+func TestHashOf(t *testing.T) {
+	assert.Equal(t, hashOf(1), hashOf(int64(1)))
+	assert.Equal(t, hashOf("a"), hashOf([]byte("a")))
+	assert.NotEqual(t, hashOf(true), hashOf(false))
+	assert.Equal(t, hashOf(1.5), hashOf(float32(1.5)))
+	assert.NotEqual(t, uint64(0), hashOf([]int{1}))
+}
@@ -0,0 +1,331 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+)
+
+// Retention represents the policy which decides which of the snapshots are retained in the
+// storage. The most recent snapshot is always retained, along with the segments of the commit
+// log which follow the oldest retained snapshot.
+type Retention struct {
+	Snapshots int           // The number of most recent snapshots to retain (default: all)
+	MaxAge    time.Duration // The maximum age of the snapshots to retain (default: forever)
+}
+
+// Options represents the options of an archive
+type Options struct {
+	Backend   Backend           // The storage where the snapshots and the commit log are shipped
+	Prefix    string            // The prefix of the keys of the objects, for example "players/" (optional)
+	Snapshots time.Duration     // The interval at which the snapshots are taken (default: 5m)
+	Segments  time.Duration     // The interval at which the segments of the commit log are shipped (default: 10s)
+	Retention Retention         // The policy which decides which of the snapshots are retained
+	Log       commit.LogOptions // The compression and encryption of the segments of the commit log (optional)
+	OnError   func(error)       // The callback invoked when the shipping fails in the background (optional)
+}
+
+// Archive represents a commit writer which ships the commits of a collection to an object
+// storage in segments, along with periodic snapshots of the collection. The collection can then
+// be restored from the most recent snapshot and the segments which follow it.
+type Archive struct {
+	lock    sync.Mutex // The lock to protect the current segment
+	ship    sync.Mutex // The lock to ship the data one step at a time
+	opts    Options    // The options of the archive
+	current *segment   // The segment which receives the commits
+	pending []*segment // The segments which are waiting to be shipped
+}
+
+// segment represents a segment of the commit log
+type segment struct {
+	first  uint64       // The ID of the first commit of the segment
+	last   uint64       // The ID of the last commit of the segment
+	buffer bytes.Buffer // The encoded commits
+	log    *commit.Log  // The commit log writing into the buffer
+}
+
+// New creates a new archive. The archive must be configured as the writer of the collection,
+// for example
+//
+//	archive, err := storage.New(storage.Options{Backend: bucket, Prefix: "players/"})
+//	players := column.NewCollection(column.Options{
+//		Writer: archive,
+//	})
+//
+//	go archive.Run(ctx, players)
+func New(opts Options) (*Archive, error) {
+	if opts.Backend == nil {
+		return nil, fmt.Errorf("column: archive requires a storage backend")
+	}
+
+	if opts.Snapshots <= 0 {
+		opts.Snapshots = 5 * time.Minute
+	}
+	if opts.Segments <= 0 {
+		opts.Segments = 10 * time.Second
+	}
+
+	return &Archive{opts: opts}, nil
+}
+
+// Append appends the commit into the current segment, which is shipped periodically
+func (a *Archive) Append(change commit.Commit) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.current == nil {
+		a.current = &segment{first: change.ID}
+		a.current.log = commit.OpenWith(&a.current.buffer, a.opts.Log)
+	}
+
+	if err := a.current.log.Append(change); err != nil {
+		return err
+	}
+
+	a.current.last = change.ID
+	return nil
+}
+
+// Run ships the segments of the commit log and takes the snapshots of the collection
+// periodically. It blocks until the context is cancelled, then ships the last segment.
+func (a *Archive) Run(ctx context.Context, collection *column.Collection) error {
+	segments := time.NewTicker(a.opts.Segments)
+	snapshots := time.NewTicker(a.opts.Snapshots)
+	defer segments.Stop()
+	defer snapshots.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return a.Flush(context.Background())
+		case <-segments.C:
+			a.notify(a.Flush(ctx))
+		case <-snapshots.C:
+			a.notify(a.Snapshot(ctx, collection))
+		}
+	}
+}
+
+// notify invokes the error callback, if the error is not nil
+func (a *Archive) notify(err error) {
+	if err != nil && a.opts.OnError != nil {
+		a.opts.OnError(err)
+	}
+}
+
+// Flush ships the current segment of the commit log. The segments which fail to ship are
+// retried on the next flush.
+func (a *Archive) Flush(ctx context.Context) error {
+	a.ship.Lock()
+	defer a.ship.Unlock()
+
+	a.lock.Lock()
+	if a.current != nil {
+		a.pending = append(a.pending, a.current)
+		a.current = nil
+	}
+	a.lock.Unlock()
+
+	for len(a.pending) > 0 {
+		s := a.pending[0]
+		if err := a.opts.Backend.Put(ctx, a.segmentKey(s.first, s.last), bytes.NewReader(s.buffer.Bytes())); err != nil {
+			return err
+		}
+
+		a.pending[0] = nil
+		a.pending = a.pending[1:]
+	}
+	return nil
+}
+
+// Snapshot ships the pending segments of the commit log, takes a snapshot of the collection
+// and ships it, then applies the retention policy. The snapshot is buffered in a temporary file.
+func (a *Archive) Snapshot(ctx context.Context, collection *column.Collection) error {
+	if err := a.Flush(ctx); err != nil {
+		return err
+	}
+
+	a.ship.Lock()
+	defer a.ship.Unlock()
+
+	temp, err := os.CreateTemp("", "column_*.snap")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	// Every commit older than the snapshot is either part of its state or recorded along with it
+	id := commit.Next()
+	if err := collection.Snapshot(temp); err != nil {
+		return err
+	}
+
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := a.opts.Backend.Put(ctx, a.snapshotKey(id), temp); err != nil {
+		return err
+	}
+	return a.retain(ctx, time.Now())
+}
+
+// retain removes the snapshots which are not retained by the policy, along with the segments
+// of the commit log which precede the oldest retained snapshot.
+func (a *Archive) retain(ctx context.Context, now time.Time) error {
+	snapshots, err := a.list(ctx, "snapshots/")
+	if err != nil || len(snapshots) == 0 {
+		return err
+	}
+
+	// Find the oldest snapshot to retain, the most recent one is always retained
+	policy := a.opts.Retention
+	oldest := len(snapshots) - 1
+	for i := oldest - 1; i >= 0; i-- {
+		if policy.Snapshots > 0 && len(snapshots)-i > policy.Snapshots {
+			break
+		}
+		if policy.MaxAge > 0 && now.Sub(snapshots[i].Modified) > policy.MaxAge {
+			break
+		}
+		oldest = i
+	}
+
+	for _, v := range snapshots[:oldest] {
+		if err := a.opts.Backend.Delete(ctx, v.Key); err != nil {
+			return err
+		}
+	}
+
+	// Remove the segments which only contain commits older than the snapshot
+	segments, err := a.list(ctx, "commits/")
+	if err != nil {
+		return err
+	}
+
+	since, _ := parseSnapshot(a.trim(snapshots[oldest].Key))
+	for _, v := range segments {
+		if _, last, ok := parseSegment(a.trim(v.Key)); ok && last < since {
+			if err := a.opts.Backend.Delete(ctx, v.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Restore restores the collection from the most recent snapshot in the storage, if any, and
+// then replays the segments of the commit log which follow it. This operation should be
+// called before any of transactions, right after initialization.
+func (a *Archive) Restore(ctx context.Context, collection *column.Collection) error {
+	snapshots, err := a.list(ctx, "snapshots/")
+	if err != nil {
+		return err
+	}
+
+	// Load the segments which follow the most recent snapshot
+	var since uint64
+	if len(snapshots) > 0 {
+		since, _ = parseSnapshot(a.trim(snapshots[len(snapshots)-1].Key))
+	}
+
+	store, err := a.loadSegments(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		return collection.RestoreFrom(nil, store)
+	}
+
+	snapshot, err := a.opts.Backend.Get(ctx, snapshots[len(snapshots)-1].Key)
+	if err != nil {
+		return err
+	}
+
+	defer snapshot.Close()
+	return collection.RestoreFrom(snapshot, store)
+}
+
+// loadSegments loads the commits of the segments which end after the specified commit
+func (a *Archive) loadSegments(ctx context.Context, since uint64) (*commit.MemoryStore, error) {
+	segments, err := a.list(ctx, "commits/")
+	if err != nil {
+		return nil, err
+	}
+
+	store := commit.NewMemoryStore()
+	for _, v := range segments {
+		if _, last, ok := parseSegment(a.trim(v.Key)); !ok || last < since {
+			continue
+		}
+
+		if err := a.loadSegment(ctx, v.Key, store); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// loadSegment loads the commits of a segment into the store
+func (a *Archive) loadSegment(ctx context.Context, key string, store commit.LogStore) error {
+	object, err := a.opts.Backend.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	defer object.Close()
+	return commit.OpenWith(object, a.opts.Log).Range(store.Append)
+}
+
+// --------------------------- Keys ----------------------------
+
+// list returns the objects of the archive of a kind, sorted by their key
+func (a *Archive) list(ctx context.Context, kind string) ([]Object, error) {
+	objects, err := a.opts.Backend.List(ctx, a.opts.Prefix+kind)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, err
+}
+
+// trim removes the prefix of the archive from a key
+func (a *Archive) trim(key string) string {
+	return strings.TrimPrefix(key, a.opts.Prefix)
+}
+
+// snapshotKey returns the key of a snapshot, which sorts in the order of the snapshots
+func (a *Archive) snapshotKey(id uint64) string {
+	return fmt.Sprintf("%ssnapshots/%020d.snap", a.opts.Prefix, id)
+}
+
+// segmentKey returns the key of a segment of the commit log
+func (a *Archive) segmentKey(first, last uint64) string {
+	return fmt.Sprintf("%scommits/%020d-%020d.log", a.opts.Prefix, first, last)
+}
+
+// parseSnapshot parses the commit ID of the key of a snapshot
+func parseSnapshot(key string) (id uint64, ok bool) {
+	_, err := fmt.Sscanf(key, "snapshots/%020d.snap", &id)
+	return id, err == nil
+}
+
+// parseSegment parses the commit IDs of the key of a segment
+func parseSegment(key string) (first, last uint64, ok bool) {
+	_, err := fmt.Sscanf(key, "commits/%020d-%020d.log", &first, &last)
+	return first, last, err == nil
+}
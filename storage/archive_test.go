@@ -0,0 +1,191 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column"
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewDirectory(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, log := range []commit.LogOptions{{}, {Codec: commit.Zstd}} {
+		opts := Options{Backend: backend, Prefix: "players/" + codecName(log) + "/", Log: log}
+		archive, players := newArchive(t, opts)
+		for i := 0; i < 100; i++ {
+			players.InsertObject(column.Object{"name": "Roman", "balance": i})
+		}
+
+		// Ship the commits, take a snapshot and then write some more
+		assert.NoError(t, archive.Flush(ctx))
+		assert.NoError(t, archive.Snapshot(ctx, players))
+		for i := 0; i < 50; i++ {
+			players.InsertObject(column.Object{"name": "Merlin", "balance": 1000})
+		}
+		assert.NoError(t, players.QueryAt(0, func(r column.Row) error {
+			r.SetFloat64("balance", -1)
+			return nil
+		}))
+		assert.NoError(t, archive.Flush(ctx))
+
+		// Restore from the snapshot and the segments which follow it
+		restorer, restored := newArchive(t, opts)
+		assert.NoError(t, restorer.Restore(ctx, restored))
+		assert.Equal(t, 150, restored.Count())
+		assert.NoError(t, restored.QueryAt(0, func(r column.Row) error {
+			balance, _ := r.Float64("balance")
+			assert.Equal(t, float64(-1), balance)
+			return nil
+		}))
+		assert.NoError(t, restored.Query(func(txn *column.Txn) error {
+			assert.Equal(t, float64(4950+50*1000-1), txn.Float64("balance").Sum())
+			return nil
+		}))
+	}
+}
+
+func TestArchiveWithoutSnapshot(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewDirectory(t.TempDir())
+	assert.NoError(t, err)
+
+	archive, players := newArchive(t, Options{Backend: backend})
+	players.InsertObject(column.Object{"name": "Roman", "balance": 10})
+	assert.NoError(t, archive.Flush(ctx))
+	assert.NoError(t, archive.Flush(ctx)) // Nothing to ship
+
+	restorer, restored := newArchive(t, Options{Backend: backend})
+	assert.NoError(t, restorer.Restore(ctx, restored))
+	assert.Equal(t, 1, restored.Count())
+}
+
+func TestArchiveRetention(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewDirectory(t.TempDir())
+	assert.NoError(t, err)
+
+	archive, players := newArchive(t, Options{
+		Backend:   backend,
+		Retention: Retention{Snapshots: 2},
+	})
+
+	for i := 0; i < 4; i++ {
+		players.InsertObject(column.Object{"name": "Roman", "balance": i})
+		assert.NoError(t, archive.Snapshot(ctx, players))
+	}
+
+	// Only the last two snapshots are retained, along with the segment which follows the oldest
+	snapshots, err := backend.List(ctx, "snapshots/")
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	segments, err := backend.List(ctx, "commits/")
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+
+	// A maximum age of the snapshots only retains the most recent one
+	archive.opts.Retention = Retention{MaxAge: time.Nanosecond}
+	assert.NoError(t, archive.retain(ctx, time.Now().Add(time.Hour)))
+	snapshots, err = backend.List(ctx, "snapshots/")
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+
+	restorer, restored := newArchive(t, Options{Backend: backend})
+	assert.NoError(t, restorer.Restore(ctx, restored))
+	assert.Equal(t, 4, restored.Count())
+}
+
+func TestArchiveFailures(t *testing.T) {
+	_, err := New(Options{})
+	assert.Error(t, err)
+
+	// The segments which fail to ship are retried
+	backend := &failingBackend{Backend: mustDirectory(t), fail: true}
+	archive, players := newArchive(t, Options{Backend: backend})
+	players.InsertObject(column.Object{"name": "Roman"})
+	assert.Error(t, archive.Flush(context.Background()))
+	players.InsertObject(column.Object{"name": "Merlin"})
+
+	backend.fail = false
+	assert.NoError(t, archive.Flush(context.Background()))
+	segments, err := backend.List(context.Background(), "commits/")
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+}
+
+func TestArchiveRun(t *testing.T) {
+	backend := mustDirectory(t)
+	var errs []error
+	archive, players := newArchive(t, Options{
+		Backend:   backend,
+		Segments:  time.Millisecond,
+		Snapshots: 5 * time.Millisecond,
+		OnError:   func(err error) { errs = append(errs, err) },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- archive.Run(ctx, players) }()
+
+	players.InsertObject(column.Object{"name": "Roman"})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Empty(t, errs)
+
+	snapshots, err := backend.List(context.Background(), "snapshots/")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, snapshots)
+}
+
+// newArchive creates an archive and a collection which writes into it
+func newArchive(t *testing.T, opts Options) (*Archive, *column.Collection) {
+	archive, err := New(opts)
+	assert.NoError(t, err)
+
+	players := column.NewCollection(column.Options{
+		Writer:      archive,
+		Compression: opts.Log.Codec,
+	})
+	players.CreateColumn("name", column.ForString())
+	players.CreateColumn("balance", column.ForFloat64())
+	t.Cleanup(func() { players.Close() })
+	return archive, players
+}
+
+func mustDirectory(t *testing.T) *Directory {
+	backend, err := NewDirectory(t.TempDir())
+	assert.NoError(t, err)
+	return backend
+}
+
+func codecName(log commit.LogOptions) string {
+	if log.Codec == nil {
+		return "default"
+	}
+	return "zstd"
+}
+
+// failingBackend represents a backend which fails to write the objects when requested
+type failingBackend struct {
+	Backend
+	fail bool
+}
+
+func (b *failingBackend) Put(ctx context.Context, key string, data io.Reader) error {
+	if b.fail {
+		return errors.New("failed")
+	}
+	return b.Backend.Put(ctx, key, data)
+}
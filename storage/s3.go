@@ -0,0 +1,338 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hashes of the payloads, as required by the signature
+const (
+	emptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Options represents the options of an S3-compatible storage
+type S3Options struct {
+	Endpoint     string       // The endpoint of the service, for example "https://s3.eu-west-1.amazonaws.com"
+	Region       string       // The region of the bucket (default: us-east-1)
+	Bucket       string       // The name of the bucket
+	AccessKey    string       // The access key of the credentials
+	SecretKey    string       // The secret key of the credentials
+	SessionToken string       // The token of temporary credentials (optional)
+	VirtualHost  bool         // Whether the bucket is addressed as a sub-domain rather than a path
+	Client       *http.Client // The HTTP client (default: http.DefaultClient)
+}
+
+// S3 represents a storage backed by a bucket of Amazon S3 or of a compatible service, such as
+// MinIO. The requests are signed with the AWS signature version 4. Since the objects are
+// uploaded in a single request, they are limited to 5GB.
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// NewS3 creates a storage backed by an S3-compatible bucket
+func NewS3(opts S3Options) (*S3, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("column: invalid s3 endpoint, %w", err)
+	case endpoint.Scheme == "" || endpoint.Host == "":
+		return nil, fmt.Errorf("column: invalid s3 endpoint '%s'", opts.Endpoint)
+	case opts.Bucket == "":
+		return nil, fmt.Errorf("column: s3 storage requires a bucket")
+	}
+
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	return &S3{
+		opts:     opts,
+		endpoint: endpoint,
+		now:      time.Now,
+	}, nil
+}
+
+// Put uploads the data into an object. The size of the data must be known in advance, hence
+// the readers which are not seekable are buffered in memory.
+func (s *S3) Put(ctx context.Context, key string, data io.Reader) error {
+	body, size, err := sizeOf(data)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get downloads an object
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// List returns the objects whose key starts with the prefix
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for token := ""; ; {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req, emptyPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("column: unable to list the objects, %w", err)
+		}
+
+		for _, v := range result.Contents {
+			objects = append(objects, Object{Key: v.Key, Size: v.Size, Modified: v.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete removes an object
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	switch {
+	case err == ErrNotFound:
+		return nil
+	case err != nil:
+		return err
+	default:
+		return resp.Body.Close()
+	}
+}
+
+// newRequest creates a request for an object of the bucket, or for the bucket itself
+func (s *S3) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := *s.endpoint
+	target.RawQuery = canonicalQuery(query)
+	switch {
+	case s.opts.VirtualHost:
+		target.Host = s.opts.Bucket + "." + target.Host
+		target.Path = "/" + key
+	default:
+		target.Path = "/" + s.opts.Bucket + "/" + key
+	}
+
+	target.RawPath = escapePath(target.Path)
+	return http.NewRequestWithContext(ctx, method, target.String(), body)
+}
+
+// do signs and sends the request, and converts the unsuccessful responses into errors
+func (s *S3) do(req *http.Request, payload string) (*http.Response, error) {
+	s.sign(req, payload, s.now().UTC())
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+
+	defer resp.Body.Close()
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	return nil, fmt.Errorf("column: s3 request %s %s failed with %s, %s %s",
+		req.Method, req.URL.Path, resp.Status, failure.Code, failure.Message)
+}
+
+// --------------------------- Signature ----------------------------
+
+// sign signs the request with the AWS signature version 4
+func (s *S3) sign(req *http.Request, payload string, now time.Time) {
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if s.opts.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.opts.SessionToken)
+	}
+
+	// Build the canonical request, which covers all of the headers present
+	headers := []string{"host"}
+	for name := range req.Header {
+		headers = append(headers, strings.ToLower(name))
+	}
+	sort.Strings(headers)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(req.URL.EscapedPath() + "\n")
+	canonical.WriteString(canonicalQuery(req.URL.Query()) + "\n")
+	for _, name := range headers {
+		value := req.Host
+		if name != "host" {
+			value = strings.Join(req.Header.Values(name), ",")
+		} else if value == "" {
+			value = req.URL.Host
+		}
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	signed := strings.Join(headers, ";")
+	canonical.WriteString("\n" + signed + "\n" + payload)
+
+	// Sign the request with a key derived from the secret key
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacOf([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacOf(key, s.opts.Region)
+	key = hmacOf(key, "s3")
+	key = hmacOf(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signed, hex.EncodeToString(hmacOf(key, toSign))))
+}
+
+// hmacOf computes the HMAC-SHA256 of the data
+func hmacOf(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes the query with its keys sorted, as required by the signature
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			out = append(out, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(out, "&")
+}
+
+// escapePath encodes the path, leaving its slashes as they are
+func escapePath(path string) string {
+	return escape(path, false)
+}
+
+// escape encodes every byte except the unreserved characters, as required by the signature
+func escape(s string, slash bool) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/' && !slash:
+			out.WriteByte(c)
+		default:
+			fmt.Fprintf(&out, "%%%02X", c)
+		}
+	}
+	return out.String()
+}
+
+// sizeOf returns the size of the data, buffering it in memory if it is not seekable
+func sizeOf(data io.Reader) (io.Reader, int64, error) {
+	if seeker, ok := data.(io.Seeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(data), end - offset, nil // The caller remains the owner of the data
+	}
+
+	buffer, err := io.ReadAll(data)
+	return bytes.NewReader(buffer), int64(len(buffer)), err
+}
+
+// listResult represents the result of listing the objects of a bucket
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
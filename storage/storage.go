@@ -0,0 +1,143 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package storage provides a pluggable object storage for the snapshots and the commit logs of
+// a collection, along with an S3-compatible implementation. An archive ships the snapshots and
+// the segments of the commit log to the storage periodically, removes the ones which are no
+// longer needed according to its retention policy, and restores a collection from them.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist in the storage
+var ErrNotFound = errors.New("column: object does not exist in the storage")
+
+// Object represents an object stored in the storage
+type Object struct {
+	Key      string    // The key of the object
+	Size     int64     // The size of the object, in bytes
+	Modified time.Time // The time at which the object was last modified
+}
+
+// Backend represents an object storage, such as S3, where the snapshots and the segments of
+// the commit log can be shipped. The keys are slash-separated paths.
+type Backend interface {
+
+	// Put writes the data into an object with the specified key, replacing it if it exists.
+	Put(ctx context.Context, key string, data io.Reader) error
+
+	// Get opens the object with the specified key for reading, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// List returns the objects whose key starts with the prefix, sorted by their key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Delete removes the object with the specified key, if it exists.
+	Delete(ctx context.Context, key string) error
+}
+
+var _ Backend = new(Directory)
+var _ Backend = new(S3)
+
+// --------------------------- Directory ----------------------------
+
+// Directory represents a storage backed by a directory of the local disk, where every object
+// is a file. This is mostly useful for testing, or to ship the data to a mounted volume.
+type Directory struct {
+	root string
+}
+
+// NewDirectory creates a storage in the specified directory, which is created if it does
+// not exist.
+func NewDirectory(root string) (*Directory, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Directory{root: root}, nil
+}
+
+// Put writes the data into a temporary file which then replaces the object, so that the
+// readers never see a partially written object.
+func (d *Directory) Put(ctx context.Context, key string, data io.Reader) error {
+	name := d.pathOf(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+	if _, err := io.Copy(temp, data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), name)
+}
+
+// Get opens the object for reading
+func (d *Directory) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(d.pathOf(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// List returns the objects whose key starts with the prefix
+func (d *Directory) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.Walk(d.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		key, err := filepath.Rel(d.root, path)
+		if err != nil {
+			return err
+		}
+
+		if key = filepath.ToSlash(key); strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{
+				Key:      key,
+				Size:     info.Size(),
+				Modified: info.ModTime(),
+			})
+		}
+		return nil
+	})
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, err
+}
+
+// Delete removes the object, if it exists
+func (d *Directory) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(d.pathOf(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// pathOf returns the path of the file of an object
+func (d *Directory) pathOf(key string) string {
+	return filepath.Join(d.root, filepath.FromSlash(key))
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir, err := NewDirectory(t.TempDir())
+	assert.NoError(t, err)
+	testBackend(t, dir)
+
+	_, err = dir.Get(ctx, "missing")
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, dir.Delete(ctx, "missing"))
+}
+
+// testBackend runs the common operations on a backend
+func testBackend(t *testing.T, backend Backend) {
+	ctx := context.Background()
+	assert.NoError(t, backend.Put(ctx, "a/2", strings.NewReader("world")))
+	assert.NoError(t, backend.Put(ctx, "a/1", bytes.NewBufferString("hello")))
+	assert.NoError(t, backend.Put(ctx, "b/1", strings.NewReader("")))
+
+	// Objects are listed by prefix and sorted
+	objects, err := backend.List(ctx, "a/")
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "a/1", objects[0].Key)
+	assert.Equal(t, int64(5), objects[0].Size)
+	assert.Equal(t, "a/2", objects[1].Key)
+
+	// Objects can be read back and replaced
+	assert.NoError(t, backend.Put(ctx, "a/1", strings.NewReader("bonjour")))
+	object, err := backend.Get(ctx, "a/1")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(object)
+	assert.NoError(t, err)
+	assert.NoError(t, object.Close())
+	assert.Equal(t, "bonjour", string(data))
+
+	// And deleted
+	assert.NoError(t, backend.Delete(ctx, "a/1"))
+	_, err = backend.Get(ctx, "a/1")
+	assert.Equal(t, ErrNotFound, err)
+	objects, err = backend.List(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
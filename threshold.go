@@ -0,0 +1,179 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// OnThreshold registers a handler which is invoked whenever an update of a numeric column
+// crosses the boundary of a predicate, that is when the value of a row starts satisfying
+// the predicate while it did not (or the row had no value) before. For example, this
+// allows raising an alert when the temperature of a sensor goes above a limit, but not on
+// every subsequent update while it stays above it.
+//
+// The crossings are detected while the updates are applied, with the predicate evaluated
+// once per updated value. The handler is invoked once the commit is complete and all of
+// the locks are released, so it may query the collection. However, the handlers of the
+// concurrent commits may be invoked in any order.
+func (c *Collection) OnThreshold(columnName string, predicate func(v float64) bool, handler func(idx uint32, v float64)) error {
+	if predicate == nil || handler == nil {
+		return fmt.Errorf("column: threshold must specify a predicate and a handler")
+	}
+
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return fmt.Errorf("column: unable to watch threshold, column '%v' does not exist", columnName)
+	}
+
+	source, ok := column.Column.(Numeric)
+	if !ok {
+		return fmt.Errorf("column: unable to watch threshold, column '%v' is not numeric", columnName)
+	}
+
+	// Register the threshold and fill it with the existing values, without firing the handler
+	return c.lockAll(func() error {
+		threshold := newThreshold(c.alerts.nameOf(columnName), columnName, source, predicate, handler)
+		threshold.Grow(uint32(c.opts.Capacity))
+		c.cols.Store(threshold.name, threshold)
+		c.cols.Store(columnName, column, threshold)
+
+		watcher := threshold.Column.(*columnThreshold)
+		buffer := commit.NewBuffer(c.Count())
+		reader := commit.NewReader()
+		for chunk := commit.Chunk(0); int(chunk) < c.chunks(); chunk++ {
+			threshold.Grow(chunk.Max())
+			if column.Snapshot(chunk, buffer) {
+				reader.Seek(buffer)
+				watcher.columnIndex.Apply(chunk, reader)
+			}
+		}
+
+		c.alerts.add(watcher)
+		return nil
+	})
+}
+
+// --------------------------- Threshold ----------------------------
+
+// crossing represents a value which has crossed the boundary of a threshold
+type crossing struct {
+	index uint32
+	value float64
+}
+
+// columnThreshold represents a computed column which keeps track of the rows satisfying a
+// predicate, and records the rows which started satisfying it.
+type columnThreshold struct {
+	columnIndex
+	source  Numeric                     // The target column, to read the stored values
+	lock    sync.Mutex                  // The lock to protect the crossings
+	crossed []crossing                  // The crossings which are yet to be notified
+	handler func(idx uint32, v float64) // The handler to notify
+}
+
+// newThreshold creates a new threshold column. Since the values are encoded differently
+// depending on the type of the column, the predicate is evaluated on the stored values.
+func newThreshold(name, columnName string, source Numeric, predicate func(v float64) bool, handler func(uint32, float64)) *column {
+	return columnFor(name, &columnThreshold{
+		columnIndex: columnIndex{
+			fill: make(bitmap.Bitmap, 0, 4),
+			name: columnName,
+			rule: func(r Reader) bool {
+				v, ok := source.LoadFloat64(r.Index())
+				return ok && predicate(v)
+			},
+		},
+		source:  source,
+		handler: handler,
+	}, columnOptions{})
+}
+
+// Apply applies a set of operations to the column, recording the crossings. This is applied
+// after the target column, so the updated values are already stored.
+func (c *columnThreshold) Apply(chunk commit.Chunk, r *commit.Reader) {
+	for r.Next() {
+		switch r.Type {
+		case commit.Put, commit.Add, commit.Saturate:
+			idx := uint32(r.Offset)
+			switch {
+			case !c.rule(r):
+				c.fill.Remove(idx)
+			case !c.fill.Contains(idx):
+				c.fill.Set(idx)
+				value, _ := c.source.LoadFloat64(idx)
+				c.lock.Lock()
+				c.crossed = append(c.crossed, crossing{index: idx, value: value})
+				c.lock.Unlock()
+			}
+		case commit.Delete:
+			c.fill.Remove(uint32(r.Offset))
+			c.discard(uint32(r.Offset))
+		}
+	}
+}
+
+// discard removes the pending crossings of a deleted row, since the deletes of a commit are
+// applied after its updates and the row is gone by the time the handler would be invoked.
+func (c *columnThreshold) discard(idx uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := 0; i < len(c.crossed); i++ {
+		if c.crossed[i].index == idx {
+			c.crossed = append(c.crossed[:i], c.crossed[i+1:]...)
+			i--
+		}
+	}
+}
+
+// notify invokes the handler for the crossings recorded so far
+func (c *columnThreshold) notify() {
+	c.lock.Lock()
+	crossed := c.crossed
+	c.crossed = nil
+	c.lock.Unlock()
+
+	for _, v := range crossed {
+		c.handler(v.index, v.value)
+	}
+}
+
+// --------------------------- Alerts ----------------------------
+
+// alerts represents the set of thresholds registered on a collection
+type alerts struct {
+	lock  sync.RWMutex       // The lock to protect the thresholds
+	list  []*columnThreshold // The registered thresholds
+	count int                // The number of thresholds ever registered, for naming
+}
+
+// nameOf returns a unique name for a new threshold of a column
+func (a *alerts) nameOf(columnName string) string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.count++
+	return fmt.Sprintf("%s#threshold%d", columnName, a.count)
+}
+
+// add registers a threshold
+func (a *alerts) add(threshold *columnThreshold) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.list = append(a.list, threshold)
+}
+
+// notify invokes the handlers of the thresholds which were crossed
+func (a *alerts) notify() {
+	a.lock.RLock()
+	list := a.list
+	a.lock.RUnlock()
+
+	for _, threshold := range list {
+		threshold.notify()
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnThreshold(t *testing.T) {
+	sensors := NewCollection()
+	sensors.CreateColumn("temperature", ForFloat64())
+	sensors.CreateColumn("status", ForString())
+	hot := sensors.InsertObject(Object{"temperature": 50.0})
+	cold := sensors.InsertObject(Object{"temperature": 10.0})
+
+	var alerts []float64
+	assert.NoError(t, sensors.OnThreshold("temperature", func(v float64) bool {
+		return v > 40
+	}, func(idx uint32, v float64) {
+		assert.Equal(t, cold, idx)
+		alerts = append(alerts, v)
+
+		// The handler is able to query the collection
+		assert.NoError(t, sensors.QueryAt(idx, func(r Row) error {
+			r.SetString("status", "alert")
+			return nil
+		}))
+	}))
+
+	update := func(idx uint32, v float64) {
+		assert.NoError(t, sensors.QueryAt(idx, func(r Row) error {
+			r.SetFloat64("temperature", v)
+			return nil
+		}))
+	}
+
+	// The rows already above the threshold do not fire
+	update(hot, 60)
+	update(cold, 20)
+	assert.Empty(t, alerts)
+
+	// Crossing the threshold fires once, until it goes below again
+	update(cold, 45)
+	update(cold, 50)
+	assert.Equal(t, []float64{45}, alerts)
+	update(cold, 30)
+	update(cold, 41)
+	assert.Equal(t, []float64{45, 41}, alerts)
+}
+
+func TestOnThresholdAdd(t *testing.T) {
+	counters := NewCollection()
+	counters.CreateColumn("count", ForInt())
+	idx := counters.InsertObject(Object{"count": 0})
+
+	crossed := 0
+	assert.NoError(t, counters.OnThreshold("count", func(v float64) bool {
+		return v >= 10
+	}, func(_ uint32, v float64) {
+		assert.Equal(t, 10.0, v)
+		crossed++
+	}))
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, counters.QueryAt(idx, func(r Row) error {
+			r.AddInt("count", 1)
+			return nil
+		}))
+	}
+	assert.Equal(t, 1, crossed)
+
+	// A deleted row which is inserted again crosses the threshold again
+	counters.DeleteAt(idx)
+	counters.InsertObject(Object{"count": 10})
+	assert.Equal(t, 2, crossed)
+}
+
+func TestOnThresholdInvalid(t *testing.T) {
+	players := NewCollection()
+	players.CreateColumn("name", ForString())
+	handler := func(uint32, float64) {}
+	predicate := func(float64) bool { return true }
+
+	assert.Error(t, players.OnThreshold("name", predicate, handler))
+	assert.Error(t, players.OnThreshold("missing", predicate, handler))
+	assert.Error(t, players.OnThreshold("name", nil, handler))
+}
@@ -0,0 +1,166 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// Tracer represents a sink for the traces of the transactions, such as a slow-query log.
+// When a tracer is configured, every filter step of every transaction is measured.
+type Tracer interface {
+	TraceQuery(trace *QueryTrace)
+}
+
+// QueryTrace represents the trace of a single transaction
+type QueryTrace struct {
+	Steps     []TraceStep   // The filter steps, in the order they were applied
+	Returned  int           // The number of rows iterated over by the transaction
+	Duration  time.Duration // The total duration of the transaction
+	Committed bool          // Whether the transaction was committed or rolled back
+}
+
+// Scanned returns the total number of rows examined by the filter steps
+func (t *QueryTrace) Scanned() (n int) {
+	for _, s := range t.Steps {
+		n += s.Input
+	}
+	return
+}
+
+// Bytes returns the approximate number of bytes read by the filter steps
+func (t *QueryTrace) Bytes() (n int) {
+	for _, s := range t.Steps {
+		n += s.Bytes
+	}
+	return
+}
+
+// TraceStep represents a single filter step of a transaction. The deferred value filters,
+// such as WithFloat, are traced once they are applied, chunk by chunk.
+type TraceStep struct {
+	Operation string        // The name of the operation, such as "With" or "WithFloat"
+	Column    string        // The column or index on which the operation was applied
+	Input     int           // The number of rows before the step
+	Output    int           // The number of rows after the step
+	Bytes     int           // The approximate number of bytes read by the step
+	Duration  time.Duration // The time spent on the step
+}
+
+// Selectivity returns the fraction of the rows which were kept by the step
+func (s TraceStep) Selectivity() float64 {
+	if s.Input == 0 {
+		return 0
+	}
+	return float64(s.Output) / float64(s.Input)
+}
+
+// String returns a human-readable representation of the trace
+func (t *QueryTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "query took %v with %d steps, scanned %d rows (%d bytes) and returned %d rows",
+		t.Duration, len(t.Steps), t.Scanned(), t.Bytes(), t.Returned)
+	for _, s := range t.Steps {
+		fmt.Fprintf(&sb, "\n  %s(%s): %d -> %d rows (%.1f%%) in %v",
+			s.Operation, s.Column, s.Input, s.Output, s.Selectivity()*100, s.Duration)
+	}
+	return sb.String()
+}
+
+// SlowQueryLog creates a tracer which logs the transactions that took longer than the
+// threshold, along with their filter steps. The log function can be log.Printf, for example.
+func SlowQueryLog(threshold time.Duration, logf func(format string, args ...any)) Tracer {
+	return &slowQueryLog{
+		threshold: threshold,
+		logf:      logf,
+	}
+}
+
+// slowQueryLog represents a tracer which logs the slow transactions
+type slowQueryLog struct {
+	threshold time.Duration
+	logf      func(format string, args ...any)
+}
+
+// TraceQuery logs the trace if the transaction was slow
+func (l *slowQueryLog) TraceQuery(trace *QueryTrace) {
+	if trace.Duration >= l.threshold {
+		l.logf("column: slow %s", trace)
+	}
+}
+
+// --------------------------- Tracing ----------------------------
+
+// noopStep is returned for the steps which are not traced
+var noopStep = func() {}
+
+// traceStep starts tracing an eager filter step and returns a function which completes it
+func (txn *Txn) traceStep(operation, column string) func() {
+	if txn.trace == nil && txn.journal == nil {
+		return noopStep
+	}
+
+	input, start := txn.index.Count(), time.Now()
+	return func() {
+		if txn.journal != nil {
+			txn.note("%s(%s): %d -> %d rows", operation, column, input, txn.index.Count())
+		}
+		if txn.trace == nil {
+			return
+		}
+
+		txn.trace.Steps = append(txn.trace.Steps, TraceStep{
+			Operation: operation,
+			Column:    column,
+			Input:     input,
+			Output:    txn.index.Count(),
+			Bytes:     len(txn.index) * 8, // The bitmaps are intersected word by word
+			Duration:  time.Since(start),
+		})
+	}
+}
+
+// traceFilters wraps the pending filters so that they are measured while being applied
+func (txn *Txn) traceFilters() {
+	for i := range txn.filters {
+		step := len(txn.trace.Steps)
+		txn.trace.Steps = append(txn.trace.Steps, TraceStep{
+			Operation: txn.filters[i].op,
+			Column:    txn.filters[i].column,
+		})
+
+		fn := txn.filters[i].fn
+		column, _ := txn.columnAt(txn.filters[i].column)
+		txn.filters[i].fn = func(chunk commit.Chunk, index bitmap.Bitmap) {
+			s := &txn.trace.Steps[step]
+			input := index.Count()
+			start := time.Now()
+			fn(chunk, index)
+			s.Duration += time.Since(start)
+			s.Input += input
+			s.Output += index.Count()
+
+			// Estimate the bytes read from the size of the chunk of the column
+			if column != nil {
+				s.Bytes += column.sizeOf(chunk) * input / chunkSize
+			}
+		}
+	}
+}
+
+// traceQuery completes the trace of a transaction and sends it to the tracer
+func (c *Collection) traceQuery(txn *Txn, start time.Time, err error) {
+	if trace := txn.trace; trace != nil {
+		trace.Duration = time.Since(start)
+		trace.Returned = txn.scanned
+		trace.Committed = err == nil
+		txn.trace = nil
+		c.tracer.TraceQuery(trace)
+	}
+}
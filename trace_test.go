@@ -0,0 +1,140 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	tracer := new(fakeTracer)
+	c := NewCollection(Options{
+		Tracer: tracer,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("rogue", ForBool())
+	c.CreateColumn("balance", ForFloat64())
+	for i := 0; i < 100; i++ {
+		c.InsertObject(Object{"rogue": i%2 == 0, "balance": float64(i)})
+	}
+
+	tracer.reset()
+	c.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.With("rogue").WithFloat("balance", func(v float64) bool {
+			return v < 20
+		}).Count())
+		return nil
+	})
+
+	traces := tracer.all()
+	assert.Len(t, traces, 1)
+	assert.True(t, traces[0].Committed)
+	assert.Equal(t, []TraceStep{
+		{Operation: "With", Column: "rogue", Input: 100, Output: 50},
+		{Operation: "WithFloat", Column: "balance", Input: 50, Output: 10},
+	}, stepsOf(traces[0].Steps))
+	assert.InDelta(t, 50*8, traces[0].Steps[1].Bytes, 10)
+	assert.Equal(t, 150, traces[0].Scanned())
+	assert.Equal(t, 0, traces[0].Returned)
+	assert.Equal(t, 0.5, traces[0].Steps[0].Selectivity())
+	assert.Equal(t, 0.2, traces[0].Steps[1].Selectivity())
+	assert.Contains(t, traces[0].String(), "With(rogue): 100 -> 50 rows (50.0%)")
+}
+
+func TestTraceRollback(t *testing.T) {
+	tracer := new(fakeTracer)
+	c := NewCollection(Options{
+		Tracer: tracer,
+		Vacuum: time.Hour,
+	})
+	defer c.Close()
+	c.CreateColumn("name", ForString())
+
+	tracer.reset()
+	c.Query(func(txn *Txn) error {
+		txn.Without("name").Union("name")
+		return fmt.Errorf("rollback")
+	})
+
+	traces := tracer.all()
+	assert.Len(t, traces, 1)
+	assert.False(t, traces[0].Committed)
+	assert.Len(t, traces[0].Steps, 2)
+}
+
+func TestTraceDisabled(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.InsertObject(Object{"name": "Roman"})
+	c.Query(func(txn *Txn) error {
+		assert.Nil(t, txn.trace)
+		assert.Equal(t, 1, txn.With("name").Count())
+		return nil
+	})
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	var logged []string
+	log := SlowQueryLog(10*time.Millisecond, func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+
+	log.TraceQuery(&QueryTrace{Duration: time.Millisecond})
+	assert.Empty(t, logged)
+
+	log.TraceQuery(&QueryTrace{
+		Duration: 20 * time.Millisecond,
+		Steps:    []TraceStep{{Operation: "With", Column: "rogue", Input: 10, Output: 5}},
+	})
+	assert.Len(t, logged, 1)
+	assert.Contains(t, logged[0], "column: slow query took 20ms with 1 steps, scanned 10 rows")
+	assert.Contains(t, logged[0], "With(rogue): 10 -> 5 rows (50.0%)")
+}
+
+func TestTraceStepSelectivity(t *testing.T) {
+	assert.Equal(t, 0.0, TraceStep{}.Selectivity())
+	assert.Equal(t, 1.0, TraceStep{Input: 4, Output: 4}.Selectivity())
+}
+
+// stepsOf clears the durations and sizes of the steps, so they can be compared
+func stepsOf(steps []TraceStep) []TraceStep {
+	out := make([]TraceStep, 0, len(steps))
+	for _, s := range steps {
+		s.Duration = 0
+		s.Bytes = 0
+		out = append(out, s)
+	}
+	return out
+}
+
+// --------------------------- Fake Tracer ----------------------------
+
+type fakeTracer struct {
+	lock   sync.Mutex
+	traces []*QueryTrace
+}
+
+func (t *fakeTracer) TraceQuery(trace *QueryTrace) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.traces = append(t.traces, trace)
+}
+
+func (t *fakeTracer) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.traces = nil
+}
+
+func (t *fakeTracer) all() []*QueryTrace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]*QueryTrace(nil), t.traces...)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// expiryOf returns the name of the auxiliary column which holds the expiration times of the
+// values of a column with a TTL
+func expiryOf(columnName string) string {
+	return columnName + "#expire"
+}
+
+// createExpiry creates the auxiliary column which holds the expiration times of a column
+func (c *Collection) createExpiry(columnName string) error {
+	name := expiryOf(columnName)
+	if _, ok := c.cols.Load(name); ok {
+		return fmt.Errorf("column: unable to create column '%s', already exists", name)
+	}
+
+	expiry := ForInt64()
+	expiry.Grow(uint32(c.opts.Capacity))
+	c.cols.Store(name, columnFor(name, expiry, columnOptions{
+		expiryOf: columnName,
+	}))
+	return nil
+}
+
+// stampExpiry writes the expiration times of the values written to the columns with a TTL,
+// unless they were written explicitly within the transaction (e.g. when replaying a commit).
+func (txn *Txn) stampExpiry() {
+	var now time.Time
+	for i, n := 0, len(txn.updates); i < n; i++ {
+		u := txn.updates[i]
+		if u.IsEmpty() || u.Column == rowColumn {
+			continue
+		}
+
+		column, ok := txn.columnAt(u.Column)
+		if !ok || column.opts.TTL <= 0 || txn.hasBuffer(expiryOf(u.Column)) {
+			continue
+		}
+
+		if now.IsZero() {
+			now = txn.owner.now()
+		}
+
+		// Stamp the expiration of every value written, and clear it for every value removed
+		expireAt := now.Add(column.opts.TTL).UnixNano()
+		expiry := txn.bufferFor(expiryOf(u.Column))
+		u.RangeChunks(func(chunk commit.Chunk) {
+			txn.reader.Range(u, chunk, func(r *commit.Reader) {
+				for r.Next() {
+					switch r.Type {
+					case commit.Put, commit.Add, commit.Saturate:
+						expiry.PutInt64(r.Index(), expireAt)
+					case commit.Delete: // Also a boolean set to false
+						expiry.PutOperation(commit.Delete, r.Index())
+					}
+				}
+			})
+		})
+	}
+}
+
+// hasBuffer returns whether the transaction has pending updates for a column
+func (txn *Txn) hasBuffer(columnName string) bool {
+	for _, u := range txn.updates {
+		if u.Column == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+// expireValues removes the values of the columns with a TTL which have expired at the
+// specified time, while retaining the rows.
+func (c *Collection) expireValues(now int64) {
+	var columns []*column
+	c.cols.Range(func(column *column) {
+		if column.opts.TTL > 0 {
+			columns = append(columns, column)
+		}
+	})
+
+	for _, column := range columns {
+		name := expiryOf(column.name)
+		c.Query(func(txn *Txn) error {
+			expire := txn.Int64(name)
+			return txn.With(name).Range(func(idx uint32) {
+				if expireAt, ok := expire.Get(); ok && now >= expireAt {
+					txn.bufferFor(column.name).PutOperation(commit.Delete, idx)
+					txn.bufferFor(name).PutOperation(commit.Delete, idx)
+				}
+			})
+		})
+	}
+}
+
+// --------------------------- Filters ----------------------------
+
+// WithExpiringBefore filters down the rows with a time-to-live which would be expired by a
+// cleanup at the specified time. This allows the rows which are about to expire to be found
+// and refreshed ahead of the cleanup.
+func (txn *Txn) WithExpiringBefore(t time.Time) *Txn {
+	return txn.withInt("WithExpiringBefore", expireColumn, compareBetween, 1, t.UnixNano())
+}
+
+// WithoutTTL filters down the rows which never expire, either because they were inserted
+// without a time-to-live or because their eviction was cancelled.
+func (txn *Txn) WithoutTTL() *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(expireColumn)
+	if !ok {
+		return txn
+	}
+
+	// Subtract the rows with an expiration time, computed with a scratch bitmap
+	expire := c.Column.(comparer)
+	expiring := make(bitmap.Bitmap, 0, chunkSize/64)
+	txn.filter(costTyped, "WithoutTTL", expireColumn, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		expiring = append(expiring[:0], index...)
+		expire.compareInt64(chunk, expiring, compareGreater, 0, 0)
+		index.AndNot(expiring)
+	})
+	return txn
+}
@@ -0,0 +1,234 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kelindar/column/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnTTL(t *testing.T) {
+	c := NewCollection()
+	assert.NoError(t, c.CreateColumn("name", ForString()))
+	assert.NoError(t, c.CreateColumn("token", ForString(), WithTTL(time.Minute)))
+	assert.NoError(t, c.CreateColumn("online", ForBool(), WithTTL(time.Minute)))
+	assert.Equal(t, []string{"name", "token", "online"}, c.Columns())
+
+	idx := c.InsertObject(Object{"name": "Roman", "token": "secret", "online": true})
+	c.InsertObject(Object{"name": "Merlin"})
+
+	// The expiration of the values should be stamped on write
+	var expireAt int64
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		expireAt, _ = r.Int64(expiryOf("token"))
+		return nil
+	}))
+	assert.InDelta(t, time.Now().Add(time.Minute).UnixNano(), expireAt, float64(time.Second))
+
+	// Nothing should expire yet
+	c.expire(time.Now().UnixNano())
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		token, ok := r.String("token")
+		assert.True(t, ok)
+		assert.Equal(t, "secret", token)
+		assert.True(t, r.Bool("online"))
+		return nil
+	}))
+
+	// Once expired, the values are removed but the rows are retained
+	c.expire(time.Now().Add(2 * time.Minute).UnixNano())
+	assert.Equal(t, 2, c.Count())
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		_, ok := r.String("token")
+		assert.False(t, ok)
+		assert.False(t, r.Bool("online"))
+
+		name, _ := r.String("name")
+		assert.Equal(t, "Roman", name)
+		return nil
+	}))
+
+	c.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.With(expiryOf("token")).Count())
+		return nil
+	})
+}
+
+func TestColumnTTLRefresh(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("token", ForString(), WithTTL(time.Minute))
+	idx := c.InsertObject(Object{"token": "a"})
+
+	// An explicit expiration is not overwritten
+	c.QueryAt(idx, func(r Row) error {
+		r.SetString("token", "b")
+		r.SetInt64(expiryOf("token"), 42)
+		return nil
+	})
+
+	c.QueryAt(idx, func(r Row) error {
+		expireAt, _ := r.Int64(expiryOf("token"))
+		assert.Equal(t, int64(42), expireAt)
+		return nil
+	})
+
+	c.expire(100)
+	c.QueryAt(idx, func(r Row) error {
+		_, ok := r.String("token")
+		assert.False(t, ok)
+		return nil
+	})
+}
+
+func TestColumnTTLReplay(t *testing.T) {
+	store := commit.NewMemoryStore()
+	primary := NewCollection(Options{Writer: store})
+	primary.CreateColumn("token", ForString(), WithTTL(time.Minute))
+	primary.InsertObject(Object{"token": "a"})
+
+	// The expiration should be replicated as it was stamped
+	replica := NewCollection()
+	replica.CreateColumn("token", ForString(), WithTTL(time.Hour))
+	assert.NoError(t, replica.ReplayFrom(store, 0))
+
+	var expected, actual int64
+	primary.QueryAt(0, func(r Row) error {
+		expected, _ = r.Int64(expiryOf("token"))
+		return nil
+	})
+	replica.QueryAt(0, func(r Row) error {
+		actual, _ = r.Int64(expiryOf("token"))
+		return nil
+	})
+	assert.Equal(t, expected, actual)
+}
+
+func TestColumnTTLDrop(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("token", ForString(), WithTTL(time.Minute))
+	assert.NoError(t, c.DropColumn("token"))
+
+	_, ok := c.cols.Load(expiryOf("token"))
+	assert.False(t, ok)
+	assert.NoError(t, c.CreateColumn("token", ForString(), WithTTL(time.Minute)))
+}
+
+func TestColumnTTLConflict(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("token#expire", ForInt64())
+	assert.Error(t, c.CreateColumn("token", ForString(), WithTTL(time.Minute)))
+}
+
+func TestWithExpiringBefore(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.InsertObjectWithTTL(Object{"name": "Roman"}, time.Minute)
+	c.InsertObjectWithTTL(Object{"name": "Merlin"}, time.Hour)
+	c.InsertObject(Object{"name": "Arthur"})
+	idx := c.InsertObjectWithTTL(Object{"name": "Lancelot"}, time.Minute)
+
+	// Cancel the expiration of one of the rows
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		r.SetInt64(expireColumn, 0)
+		return nil
+	}))
+
+	namesOf := func(fn func(txn *Txn) *Txn) (names []string) {
+		assert.NoError(t, c.Query(func(txn *Txn) error {
+			name := txn.String("name")
+			return fn(txn).Range(func(idx uint32) {
+				v, _ := name.Get()
+				names = append(names, v)
+			})
+		}))
+		return
+	}
+
+	now := time.Now()
+	assert.Equal(t, []string{"Roman"}, namesOf(func(txn *Txn) *Txn {
+		return txn.WithExpiringBefore(now.Add(10 * time.Minute))
+	}))
+	assert.Equal(t, []string{"Roman", "Merlin"}, namesOf(func(txn *Txn) *Txn {
+		return txn.WithExpiringBefore(now.Add(2 * time.Hour))
+	}))
+	assert.Empty(t, namesOf(func(txn *Txn) *Txn {
+		return txn.WithExpiringBefore(now)
+	}))
+	assert.Equal(t, []string{"Arthur", "Lancelot"}, namesOf(func(txn *Txn) *Txn {
+		return txn.WithoutTTL()
+	}))
+	assert.Equal(t, []string{"Arthur"}, namesOf(func(txn *Txn) *Txn {
+		return txn.WithoutTTL().WithString("name", func(v string) bool {
+			return v[0] == 'A'
+		})
+	}))
+}
+
+func TestWithClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := NewCollection(Options{CleanupInterval: time.Millisecond}, WithClock(clock))
+	defer c.Close()
+	assert.NoError(t, c.CreateColumn("name", ForString()))
+	assert.NoError(t, c.CreateColumn("token", ForString(), WithTTL(time.Minute)))
+
+	idx := c.InsertObjectWithTTL(Object{"name": "Roman", "token": "secret"}, time.Hour)
+	c.InsertObject(Object{"name": "Merlin"})
+
+	// The expiration times are computed with the clock of the collection
+	assert.NoError(t, c.QueryAt(idx, func(r Row) error {
+		expireAt, _ := r.Int64(expireColumn)
+		tokenAt, _ := r.Int64(expiryOf("token"))
+		assert.Equal(t, clock.Now().Add(time.Hour).UnixNano(), expireAt)
+		assert.Equal(t, clock.Now().Add(time.Minute).UnixNano(), tokenAt)
+		return nil
+	}))
+
+	// The value expires once the clock moves past its time-to-live
+	clock.Add(2 * time.Minute)
+	assert.Eventually(t, func() bool {
+		return !hasToken(c, idx)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 2, c.Count())
+
+	// The row expires once the clock moves past its time-to-live, the query drives the
+	// vacuum on WASM and TinyGo where there is no background goroutine
+	clock.Add(time.Hour)
+	assert.Eventually(t, func() bool {
+		hasToken(c, idx)
+		return c.Count() == 1
+	}, time.Second, time.Millisecond)
+}
+
+// hasToken returns whether the row still has a token
+func hasToken(c *Collection, idx uint32) (ok bool) {
+	c.QueryAt(idx, func(r Row) error {
+		_, ok = r.String("token")
+		return nil
+	})
+	return
+}
+
+// fakeClock represents a clock which only moves when told to
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// Now returns the current time of the clock
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Add moves the clock forward by the specified duration
+func (c *fakeClock) Add(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+)
+
+// coordinator serializes the prepare phase of the transactions spanning several
+// collections, so that they never acquire their commit barriers in conflicting orders.
+var coordinator sync.Mutex
+
+// Tx represents a transaction which spans several collections. The changes made to each
+// of the collections are buffered until the transaction completes and are then committed
+// together, or not at all.
+type Tx struct {
+	txns []*Txn // The transactions, one per collection
+	err  error  // The first error returned by a query
+}
+
+// Atomic executes a transaction spanning several collections. Within the function, each
+// collection is queried through the Tx, and all of the changes are committed atomically
+// once the function returns. If the function (or any of the queries) returns an error or
+// panics, none of the changes are applied.
+//
+// The commit is done in two phases. First, the commits of every collection involved are
+// held back, then all of the changes are applied. Hence, a view or a catalog snapshot
+// never observes the changes of only some of the collections.
+func Atomic(fn func(tx *Tx) error) error {
+	tx := new(Tx)
+	defer tx.release()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+
+	err := fn(tx)
+	if err == nil {
+		err = tx.err
+	}
+
+	if err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return tx.commit()
+}
+
+// Query executes a function on a collection within the transaction. The changes made by
+// the function are only committed once the entire transaction completes. Querying the
+// same collection several times keeps adding to the pending changes of that collection.
+func (tx *Tx) Query(c *Collection, fn func(txn *Txn) error) error {
+	txn := tx.txnFor(c)
+	txn.setup = false
+	txn.filters = txn.filters[:0]
+	defer txn.closeIterators()
+	if err := fn(txn); err != nil {
+		if tx.err == nil {
+			tx.err = err
+		}
+		return err
+	}
+	return nil
+}
+
+// txnFor loads or acquires a transaction for a given collection.
+func (tx *Tx) txnFor(c *Collection) *Txn {
+	for _, txn := range tx.txns {
+		if txn.owner == c {
+			return txn
+		}
+	}
+
+	c.vacuumIfDue()
+	txn := c.txns.acquire(c)
+	tx.txns = append(tx.txns, txn)
+	return txn
+}
+
+// commit commits all of the transactions. The barriers and fork locks of all collections are
+// acquired before applying any change, and released only once everything is applied. If
+// any of the transactions tracks its reads and conflicts, nothing is applied.
+func (tx *Tx) commit() error {
+	coordinator.Lock()
+	barriers := make([]*sync.RWMutex, 0, len(tx.txns))
+	for _, txn := range tx.txns {
+		if barrier := txn.owner.barrier; barrier != nil && !containsBarrier(barriers, barrier) {
+			barrier.RLock()
+			barriers = append(barriers, barrier)
+		}
+	}
+
+	// Hold back the commits of every collection while any of them is copied
+	pending := make([]*Txn, 0, len(tx.txns))
+	for _, txn := range tx.txns {
+		if txn.hasUpdates() {
+			txn.owner.forks.RLock()
+			pending = append(pending, txn)
+		}
+	}
+
+	// Notify the crossed thresholds once all of the locks are released
+	defer func() {
+		for _, txn := range pending {
+			txn.owner.alerts.notify()
+		}
+	}()
+
+	// Validate the tracked transactions, one at a time for every collection
+	tracked := make([]*Txn, 0, len(tx.txns))
+	for _, txn := range tx.txns {
+		if txn.serialized() {
+			txn.owner.verify.Lock()
+			tracked = append(tracked, txn)
+		}
+	}
+	coordinator.Unlock()
+	defer func() {
+		for _, txn := range tracked {
+			txn.owner.verify.Unlock()
+		}
+		for _, txn := range pending {
+			txn.owner.forks.RUnlock()
+		}
+		for _, barrier := range barriers {
+			barrier.RUnlock()
+		}
+	}()
+
+	for _, txn := range tracked {
+		if err := txn.validate(); err != nil {
+			tx.rollback()
+			return err
+		}
+	}
+
+	for _, txn := range pending {
+		if err := txn.checkLocks(); err != nil {
+			tx.rollback()
+			return err
+		}
+		if err := txn.checkValues(); err != nil {
+			tx.rollback()
+			return err
+		}
+	}
+
+	// Now that everything is held back, apply all of the changes
+	for _, txn := range pending {
+		txn.commitChanges()
+	}
+
+	// Reset the transactions, so that they can be reused
+	for _, txn := range tx.txns {
+		txn.reset()
+	}
+	return nil
+}
+
+// rollback discards the pending changes of all transactions
+func (tx *Tx) rollback() {
+	for _, txn := range tx.txns {
+		txn.rollback()
+	}
+}
+
+// release releases all of the transactions back to their pools
+func (tx *Tx) release() {
+	for _, txn := range tx.txns {
+		txn.owner.hooks.notify(txn)
+		txn.owner.txns.release(txn)
+	}
+	tx.txns = nil
+}
+
+// containsBarrier checks whether the barrier is already in the set
+func containsBarrier(barriers []*sync.RWMutex, barrier *sync.RWMutex) bool {
+	for _, b := range barriers {
+		if b == barrier {
+			return true
+		}
+	}
+	return false
+}
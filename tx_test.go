@@ -0,0 +1,155 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomic(t *testing.T) {
+	catalog := newCatalog(t)
+	defer catalog.Close()
+
+	orders, _ := catalog.Collection("orders")
+	stock, _ := catalog.Collection("inventory")
+	stock.InsertObject(Object{"item": "apple", "qty": 10})
+
+	// Place an order and reserve the stock together
+	assert.NoError(t, Atomic(func(tx *Tx) error {
+		if err := tx.Query(orders, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"item": "apple", "qty": 3})
+			return err
+		}); err != nil {
+			return err
+		}
+
+		return tx.Query(stock, func(txn *Txn) error {
+			qty := txn.Int("qty")
+			return txn.Range(func(idx uint32) {
+				qty.Add(-3)
+			})
+		})
+	}))
+
+	assert.Equal(t, 1, orders.Count())
+	stock.Query(func(txn *Txn) error {
+		assert.Equal(t, 7, txn.Int("qty").Sum())
+		return nil
+	})
+}
+
+func TestAtomicRollback(t *testing.T) {
+	catalog := newCatalog(t)
+	defer catalog.Close()
+
+	orders, _ := catalog.Collection("orders")
+	stock, _ := catalog.Collection("inventory")
+	stock.InsertObject(Object{"item": "apple", "qty": 10})
+
+	// The order is inserted, but the stock update fails
+	assert.Error(t, Atomic(func(tx *Tx) error {
+		tx.Query(orders, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"item": "apple", "qty": 3})
+			return err
+		})
+
+		tx.Query(stock, func(txn *Txn) error {
+			return fmt.Errorf("out of stock")
+		})
+		return nil
+	}))
+
+	assert.Equal(t, 0, orders.Count())
+	stock.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.Int("qty").Sum())
+		return nil
+	})
+}
+
+func TestAtomicPanic(t *testing.T) {
+	orders := NewCollection()
+	orders.CreateColumn("qty", ForInt())
+
+	assert.Panics(t, func() {
+		Atomic(func(tx *Tx) error {
+			tx.Query(orders, func(txn *Txn) error {
+				_, err := txn.InsertObject(Object{"qty": 1})
+				return err
+			})
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, 0, orders.Count())
+	assert.NoError(t, orders.Query(func(txn *Txn) error {
+		_, err := txn.InsertObject(Object{"qty": 1})
+		return err
+	}))
+	assert.Equal(t, 1, orders.Count())
+}
+
+func TestAtomicQueryTwice(t *testing.T) {
+	orders := NewCollection()
+	orders.CreateColumn("qty", ForInt())
+	orders.CreateIndex("big", "qty", func(r Reader) bool {
+		return r.Int() > 5
+	})
+
+	for i := 0; i < 10; i++ {
+		orders.InsertObject(Object{"qty": i})
+	}
+
+	// The second query should not be affected by the filters of the first one
+	assert.NoError(t, Atomic(func(tx *Tx) error {
+		tx.Query(orders, func(txn *Txn) error {
+			assert.Equal(t, 4, txn.With("big").Count())
+			return nil
+		})
+
+		return tx.Query(orders, func(txn *Txn) error {
+			assert.Equal(t, 10, txn.Count())
+			return txn.Range(func(idx uint32) {
+				txn.Int("qty").Set(1)
+			})
+		})
+	}))
+
+	orders.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.Int("qty").Sum())
+		assert.Equal(t, 0, txn.With("big").Count())
+		return nil
+	})
+}
+
+func TestAtomicConflict(t *testing.T) {
+	sessions := newSessions()
+	audit := NewCollection()
+	audit.CreateColumn("event", ForString())
+
+	err := Atomic(func(tx *Tx) error {
+		if err := tx.Query(sessions, func(txn *Txn) error {
+			if txn.Track().With("active").Count() > 0 {
+				return nil
+			}
+
+			_, err := txn.InsertObject(Object{"user": "roman", "active": true})
+			return err
+		}); err != nil {
+			return err
+		}
+
+		sessions.InsertObject(Object{"user": "roman", "active": true})
+		return tx.Query(audit, func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"event": "login"})
+			return err
+		})
+	})
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, 1, sessions.Count())
+	assert.Equal(t, 0, audit.Count())
+}
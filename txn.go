@@ -4,7 +4,12 @@
 package column
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,6 +22,26 @@ var (
 	errNoKey = errors.New("column: collection does not have a key column")
 )
 
+// ErrMissingColumns is returned when an inserted object has keys without a corresponding
+// column. The error is a *MissingColumnsError, which lists the keys.
+var ErrMissingColumns = errors.New("column: object has keys without a column")
+
+// MissingColumnsError represents an error which lists the keys of the inserted objects that
+// have no corresponding column, and hence were not inserted.
+type MissingColumnsError struct {
+	Keys []string // The keys without a column, sorted
+}
+
+// Error returns the message of the error, along with the keys
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("%v: '%s'", ErrMissingColumns, strings.Join(e.Keys, "', '"))
+}
+
+// Unwrap returns ErrMissingColumns, so that the error can be checked with errors.Is()
+func (e *MissingColumnsError) Unwrap() error {
+	return ErrMissingColumns
+}
+
 // --------------------------- Pool of Transactions ----------------------------
 
 // txnPool is a pool of transactions which are retained for the lifetime of the process.
@@ -34,6 +59,7 @@ func newTxnPool() *txnPool {
 					dirty:   make(bitmap.Bitmap, 0, 4),
 					updates: make([]*commit.Buffer, 0, 256),
 					columns: make([]columnCache, 0, 16),
+					filters: make([]filter, 0, 8),
 					reader:  commit.NewReader(),
 				}
 			},
@@ -52,11 +78,21 @@ func (p *txnPool) acquire(owner *Collection) *Txn {
 	txn.owner = owner
 	txn.logger = owner.logger
 	txn.setup = false
+	txn.scanned = 0
+	txn.trace = nil
+	txn.meta = nil
+	txn.merged = false
+	txn.journal = nil
+	txn.summary = nil
+	txn.explain = nil
+	txn.reading = [2]uint64{}
+	txn.ctx = context.Background()
 	return txn
 }
 
 // release the transaction to the pool or the GC
 func (p *txnPool) release(txn *Txn) {
+	txn.ctx = nil
 	p.txns.Put(txn)
 }
 
@@ -79,13 +115,31 @@ func (p *txnPool) releasePage(buffer *commit.Buffer) {
 type Txn struct {
 	cursor  uint32           // The current cursor
 	setup   bool             // Whether the transaction was set up or not
+	ctx     context.Context  // The context of the transaction
 	owner   *Collection      // The target collection
 	index   bitmap.Bitmap	 // The filtering index
 	dirty   bitmap.Bitmap	 // The dirty chunks
 	updates []*commit.Buffer // The update buffers
 	columns []columnCache    // The column mapping
+	filters []filter         // The pending value filters
 	logger  commit.Logger    // The optional commit logger
 	reader  *commit.Reader   // The commit reader to re-use
+	reads   []readVersion    // The versions of the columns read, if tracked
+	tracked bool             // Whether the index reads are tracked
+	scanned int              // The number of rows scanned, if the metrics or tracing are enabled
+	trace   *QueryTrace      // The trace of the transaction, if tracing is enabled
+	meta    []byte           // The metadata attached to the commits of the transaction
+	merged  bool             // Whether the updates were already coalesced
+	journal []string         // The journal of the filters, if journaling is enabled
+	iters   []*Iterator      // The iterators which might hold the read locks
+	summary *Commit          // The summary of the changes for the listeners, if any
+	applied bool             // Whether the summarized changes were committed
+	points  []uint32         // The savepoints which can be rolled back to
+	saved   uint32           // The number of savepoints ever taken, to identify them
+	locked  []uint32         // The rows locked by the transaction
+	hints   []Hint           // The hints to the query planner
+	explain *QueryPlan       // The explanation of the filters, if requested
+	reading [2]uint64        // The shards read-locked by the iterations of the transaction
 }
 
 // Reset resets the transaction state so it can be used again.
@@ -94,10 +148,21 @@ func (txn *Txn) reset() {
 		txn.owner.txns.releasePage(txn.updates[i])
 	}
 
+	// Release the cached columns so the dropped ones can be reclaimed
+	for i := range txn.columns {
+		txn.columns[i] = columnCache{}
+	}
+
 	txn.dirty.Clear()
 	txn.reader.Rewind()
 	txn.columns = txn.columns[:0]
 	txn.updates = txn.updates[:0]
+	txn.filters = txn.filters[:0]
+	txn.reads = txn.reads[:0]
+	txn.points = txn.points[:0]
+	txn.hints = txn.hints[:0]
+	txn.tracked = false
+	txn.unlock()
 }
 
 // bufferFor loads or creates a buffer for a given column.
@@ -147,26 +212,34 @@ func (txn *Txn) columnAt(columnName string) (*column, bool) {
 func (txn *Txn) With(columns ...string) *Txn {
 	txn.initialize()
 	for _, columnName := range columns {
+		done := txn.traceStep("With", columnName)
 		if idx, ok := txn.columnAt(columnName); ok {
+			txn.track(idx)
 			txn.rangeReadPair(idx, func(dst, src bitmap.Bitmap) {
 				dst.And(src)
 			})
 		} else {
 			txn.index.Clear()
 		}
+		done()
 	}
 	return txn
 }
 
 // Without applies a logical AND NOT operation to the current query and the specified index.
+// The index is subtracted word by word, chunk by chunk, hence this is as cheap as With and
+// does not require the complement of the index to be materialized.
 func (txn *Txn) Without(columns ...string) *Txn {
 	txn.initialize()
 	for _, columnName := range columns {
+		done := txn.traceStep("Without", columnName)
 		if idx, ok := txn.columnAt(columnName); ok {
+			txn.track(idx)
 			txn.rangeReadPair(idx, func(dst, src bitmap.Bitmap) {
 				dst.AndNot(src)
 			})
 		}
+		done()
 	}
 	return txn
 }
@@ -174,9 +247,11 @@ func (txn *Txn) Without(columns ...string) *Txn {
 // Union computes a union between the current query and the specified index.
 func (txn *Txn) Union(columns ...string) *Txn {
 	first := !txn.setup
-	txn.initialize()
+	txn.resolve()
 	for _, columnName := range columns {
+		done := txn.traceStep("Union", columnName)
 		if idx, ok := txn.columnAt(columnName); ok {
+			txn.track(idx)
 			txn.rangeReadPair(idx, func(dst, src bitmap.Bitmap) {
 				if first {
 					dst.And(src)
@@ -185,6 +260,7 @@ func (txn *Txn) Union(columns ...string) *Txn {
 				}
 			})
 		}
+		done()
 		first = false
 	}
 	return txn
@@ -200,7 +276,7 @@ func (txn *Txn) WithValue(column string, predicate func(v interface{}) bool) *Tx
 		return txn
 	}
 
-	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+	txn.filter(costValue, "WithValue", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
 		offset := chunk.Min()
 		index.Filter(func(x uint32) (match bool) {
 			if v, ok := c.Value(offset + x); ok {
@@ -222,7 +298,7 @@ func (txn *Txn) WithFloat(column string, predicate func(v float64) bool) *Txn {
 		return txn
 	}
 
-	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+	txn.filter(costTyped, "WithFloat", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
 		c.Column.(Numeric).FilterFloat64(chunk, index, predicate)
 	})
 	return txn
@@ -238,7 +314,7 @@ func (txn *Txn) WithInt(column string, predicate func(v int64) bool) *Txn {
 		return txn
 	}
 
-	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+	txn.filter(costTyped, "WithInt", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
 		c.Column.(Numeric).FilterInt64(chunk, index, predicate)
 	})
 	return txn
@@ -254,12 +330,106 @@ func (txn *Txn) WithUint(column string, predicate func(v uint64) bool) *Txn {
 		return txn
 	}
 
-	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+	txn.filter(costTyped, "WithUint", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
 		c.Column.(Numeric).FilterUint64(chunk, index, predicate)
 	})
 	return txn
 }
 
+// WithFloatEqual filters down the values which are equal to the specified one. The column
+// for this filter must be numerical and convertible to float64. Unlike WithFloat, the values
+// are compared directly in the column, without a predicate being called for each one of them.
+func (txn *Txn) WithFloatEqual(column string, value float64) *Txn {
+	return txn.withFloat("WithFloatEqual", column, compareEqual, value, 0)
+}
+
+// WithFloatLess filters down the values which are less than the specified one. The column
+// for this filter must be numerical and convertible to float64.
+func (txn *Txn) WithFloatLess(column string, value float64) *Txn {
+	return txn.withFloat("WithFloatLess", column, compareLess, value, 0)
+}
+
+// WithFloatGreater filters down the values which are greater than the specified one. The
+// column for this filter must be numerical and convertible to float64.
+func (txn *Txn) WithFloatGreater(column string, value float64) *Txn {
+	return txn.withFloat("WithFloatGreater", column, compareGreater, value, 0)
+}
+
+// WithFloatBetween filters down the values which are within the specified bounds, inclusive.
+// The column for this filter must be numerical and convertible to float64.
+func (txn *Txn) WithFloatBetween(column string, lower, upper float64) *Txn {
+	return txn.withFloat("WithFloatBetween", column, compareBetween, lower, upper)
+}
+
+// WithIntEqual filters down the values which are equal to the specified one. The column
+// for this filter must be numerical and convertible to int64. Unlike WithInt, the values
+// are compared directly in the column, without a predicate being called for each one of them.
+func (txn *Txn) WithIntEqual(column string, value int64) *Txn {
+	return txn.withInt("WithIntEqual", column, compareEqual, value, 0)
+}
+
+// WithIntLess filters down the values which are less than the specified one. The column
+// for this filter must be numerical and convertible to int64.
+func (txn *Txn) WithIntLess(column string, value int64) *Txn {
+	return txn.withInt("WithIntLess", column, compareLess, value, 0)
+}
+
+// WithIntGreater filters down the values which are greater than the specified one. The
+// column for this filter must be numerical and convertible to int64.
+func (txn *Txn) WithIntGreater(column string, value int64) *Txn {
+	return txn.withInt("WithIntGreater", column, compareGreater, value, 0)
+}
+
+// WithIntBetween filters down the values which are within the specified bounds, inclusive.
+// The column for this filter must be numerical and convertible to int64.
+func (txn *Txn) WithIntBetween(column string, lower, upper int64) *Txn {
+	return txn.withInt("WithIntBetween", column, compareBetween, lower, upper)
+}
+
+// withFloat filters down the values which compare to the specified float64 bounds
+func (txn *Txn) withFloat(op, column string, cmp comparison, value, upper float64) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(column)
+	if !ok || !c.IsNumeric() {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, op, column, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if column, ok := c.Column.(comparer); ok {
+			column.compareFloat64(chunk, index, cmp, value, upper)
+			return
+		}
+
+		c.Column.(Numeric).FilterFloat64(chunk, index, func(v float64) bool {
+			return matches(cmp, v, value, upper)
+		})
+	})
+	return txn
+}
+
+// withInt filters down the values which compare to the specified int64 bounds
+func (txn *Txn) withInt(op, column string, cmp comparison, value, upper int64) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(column)
+	if !ok || !c.IsNumeric() {
+		txn.index.Clear()
+		return txn
+	}
+
+	txn.filter(costTyped, op, column, func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if column, ok := c.Column.(comparer); ok {
+			column.compareInt64(chunk, index, cmp, value, upper)
+			return
+		}
+
+		c.Column.(Numeric).FilterInt64(chunk, index, func(v int64) bool {
+			return matches(cmp, v, value, upper)
+		})
+	})
+	return txn
+}
+
 // WithString filters down the values based on the specified predicate. The column for
 // this filter must be a string.
 func (txn *Txn) WithString(column string, predicate func(v string) bool) *Txn {
@@ -270,7 +440,7 @@ func (txn *Txn) WithString(column string, predicate func(v string) bool) *Txn {
 		return txn
 	}
 
-	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+	txn.filter(costTyped, "WithString", column, func(chunk commit.Chunk, index bitmap.Bitmap) {
 		c.Column.(Textual).FilterString(chunk, index, predicate)
 	})
 	return txn
@@ -278,7 +448,7 @@ func (txn *Txn) WithString(column string, predicate func(v string) bool) *Txn {
 
 // Count returns the number of objects matching the query
 func (txn *Txn) Count() int {
-	txn.initialize()
+	txn.resolve()
 	return int(txn.index.Count())
 }
 
@@ -299,10 +469,60 @@ func (txn *Txn) QueryKey(key string, fn func(Row) error) error {
 	return err
 }
 
+// Replace inserts the object at the specified key or, if the key already exists, replaces the
+// entire row with the object. Only the columns whose values differ from the existing row are
+// updated and the columns missing from the object are deleted, which avoids needless index
+// updates and changefeed noise when the same objects are written over and over.
+func (txn *Txn) Replace(key string, object Object) (uint32, error) {
+	pk := txn.owner.pk
+	if pk == nil {
+		return 0, errNoKey
+	}
+
+	if err := txn.invalidValues(object); err != nil {
+		return 0, err
+	}
+
+	// If not found, insert the object at a new index
+	idx, ok := pk.OffsetOf(key)
+	if !ok {
+		idx, err := txn.insertObject(object, 0)
+		if err != nil {
+			return idx, err
+		}
+
+		txn.bufferFor(pk.name).PutString(commit.Put, idx, key)
+		return idx, nil
+	}
+
+	return idx, txn.QueryAt(idx, func(Row) error {
+		txn.owner.cols.Range(func(column *column) {
+			if column.IsIndex() || column.name == pk.name || column.name == expireColumn || column.opts.expiryOf != "" {
+				return
+			}
+
+			current, exists := column.Value(idx)
+			value, ok := object[column.name]
+			switch {
+			case ok && value != nil && (!exists || !sameValue(current, value)):
+				column.PutAny(txn.bufferFor(column.name), idx, value)
+			case (!ok || value == nil) && exists:
+				txn.bufferFor(column.name).PutOperation(commit.Delete, idx)
+			}
+		})
+		return nil
+	})
+}
+
+// sameValue checks whether the two values are equal, regardless of the width of their types
+func sameValue(a, b any) bool {
+	return reflect.DeepEqual(hashKey(a), hashKey(b))
+}
+
 // DeleteAt attempts to delete an item at the specified index for this transaction. If the item
 // exists, it marks at as deleted and returns true, otherwise it returns false.
 func (txn *Txn) DeleteAt(index uint32) bool {
-	txn.initialize()
+	txn.resolve()
 	if !txn.index.Contains(index) {
 		return false
 	}
@@ -316,15 +536,342 @@ func (txn *Txn) deleteAt(idx uint32) {
 	txn.bufferFor(rowColumn).PutOperation(commit.Delete, idx)
 }
 
-// InsertObject adds an object to a collection and returns the allocated index.
+// InsertObject adds an object to a collection and returns the allocated index. The keys of
+// the object without a corresponding column are not inserted and a *MissingColumnsError
+// listing them is returned, along with the index of the row which was inserted regardless. If
+// a value can not be written into its column, nothing is inserted and an error is returned.
 func (txn *Txn) InsertObject(object Object) (uint32, error) {
 	return txn.insertObject(object, 0)
 }
 
+// InsertStrict adds an object to a collection and returns the allocated index. Unlike
+// InsertObject, if any of the keys of the object has no corresponding column, nothing is
+// inserted and a *MissingColumnsError listing the keys is returned.
+func (txn *Txn) InsertStrict(object Object) (uint32, error) {
+	if err := txn.inferColumns(object); err != nil {
+		return 0, err
+	}
+
+	if err := txn.missingColumns(object); err != nil {
+		return 0, err
+	}
+	return txn.insertObject(object, 0)
+}
+
+// InsertMany adds a set of objects to a collection and returns the allocated indices. The
+// indices are reserved at once and the values are appended column by column, which is
+// considerably cheaper than inserting objects one by one. The keys without a corresponding
+// column are not inserted and a *MissingColumnsError listing them is returned. If a value can
+// not be written into its column, none of the objects are inserted and an error is returned.
+func (txn *Txn) InsertMany(objects []Object) ([]uint32, error) {
+	if err := txn.inferColumns(objects...); err != nil {
+		return nil, err
+	}
+
+	if err := txn.invalidValues(objects...); err != nil {
+		return nil, err
+	}
+
+	indices := make([]uint32, len(objects))
+	txn.owner.nextMany(indices)
+	if len(indices) == 0 {
+		return indices, nil
+	}
+
+	// Grow all of the columns once, up to the last reserved index
+	last := indices[0]
+	for _, idx := range indices[1:] {
+		if idx > last {
+			last = idx
+		}
+	}
+	txn.commitCapacity(commit.ChunkAt(last))
+
+	// Add the insertion markers for all of the reserved indices
+	markers := txn.bufferFor(rowColumn)
+	for _, idx := range indices {
+		markers.PutOperation(commit.Insert, idx)
+	}
+
+	// Append the values column by column, skipping the keys without a column
+	txn.owner.cols.Range(func(column *column) {
+		if column.IsIndex() {
+			return
+		}
+
+		var buffer *commit.Buffer
+		for i, object := range objects {
+			if v, ok := object[column.name]; ok {
+				if buffer == nil {
+					buffer = txn.bufferFor(column.name)
+				}
+				column.PutAny(buffer, indices[i], v)
+			}
+		}
+	})
+	return indices, txn.missingColumns(objects...)
+}
+
+// inferColumns creates the columns for the keys of the objects without one, if the collection
+// creates its columns dynamically
+func (txn *Txn) inferColumns(objects ...Object) error {
+	if !txn.owner.opts.Dynamic {
+		return nil
+	}
+	return txn.owner.createColumnsFor(objects...)
+}
+
+// missingColumns returns an error listing the keys of the objects without a column, if any
+func (txn *Txn) missingColumns(objects ...Object) error {
+	var keys []string
+	for _, object := range objects {
+		for k := range object {
+			if _, ok := txn.columnAt(k); !ok && !containsString(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.Strings(keys)
+	return &MissingColumnsError{Keys: keys}
+}
+
+// invalidValues returns an error if any value of the objects can not be written into its column,
+// which allows rejecting the objects before any of the rows are reserved.
+func (txn *Txn) invalidValues(objects ...Object) error {
+	for _, object := range objects {
+		for k, v := range object {
+			if column, ok := txn.columnAt(k); ok && !column.IsIndex() {
+				if err := canWrite(column, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// containsString checks whether the string is in the set
+func containsString(set []string, s string) bool {
+	for _, v := range set {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertColumns adds a batch of rows given column by column, where each value of the batch
+// is a slice holding the values of that column for every row, and returns the allocated
+// indices. This allows ingesting columnar data, such as decoded record batches, without
+// any per-row conversion. All of the slices must have the same length and the nil values
+// of an []any slice are left empty.
+func (txn *Txn) InsertColumns(batch map[string]any) ([]uint32, error) {
+	rows := -1
+	for columnName, values := range batch {
+		column, ok := txn.columnAt(columnName)
+		if !ok {
+			return nil, fmt.Errorf("column: column '%s' does not exist", columnName)
+		}
+
+		n, ok := lengthOf(values)
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("column: unsupported type (%T) for column '%s'", values, columnName)
+		case rows >= 0 && n != rows:
+			return nil, fmt.Errorf("column: column '%s' has %d values, expected %d", columnName, n, rows)
+		}
+
+		// Make sure the values can be written before any of the indices are reserved
+		if err := canInsert(column, values); err != nil {
+			return nil, err
+		}
+		rows = n
+	}
+
+	if rows <= 0 {
+		return nil, nil
+	}
+
+	// Reserve all of the indices and add the insertion markers
+	indices := make([]uint32, rows)
+	txn.owner.nextMany(indices)
+	markers := txn.bufferFor(rowColumn)
+	for _, idx := range indices {
+		markers.PutOperation(commit.Insert, idx)
+	}
+
+	// Append the values, column by column
+	for columnName, values := range batch {
+		buffer := txn.bufferFor(columnName)
+
+		// If the column encodes the values by itself, append them one by one, unless they are
+		// numbers which are already of the type of the column
+		if column, _ := txn.columnAt(columnName); column.IsEncoder() && !column.isNative(values) {
+			slice := reflect.ValueOf(values)
+			for i, idx := range indices {
+				if value := slice.Index(i).Interface(); value != nil {
+					column.PutAny(buffer, idx, value)
+				}
+			}
+			continue
+		}
+
+		switch v := values.(type) {
+		case []float64:
+			putAll(indices, v, buffer.PutFloat64)
+		case []float32:
+			putAll(indices, v, buffer.PutFloat32)
+		case []int:
+			putAll(indices, v, buffer.PutInt)
+		case []int16:
+			putAll(indices, v, buffer.PutInt16)
+		case []int32:
+			putAll(indices, v, buffer.PutInt32)
+		case []int64:
+			putAll(indices, v, buffer.PutInt64)
+		case []uint:
+			putAll(indices, v, buffer.PutUint)
+		case []uint16:
+			putAll(indices, v, buffer.PutUint16)
+		case []uint32:
+			putAll(indices, v, buffer.PutUint32)
+		case []uint64:
+			putAll(indices, v, buffer.PutUint64)
+		case []bool:
+			putAll(indices, v, buffer.PutBool)
+		case []string:
+			for i, value := range v {
+				buffer.PutString(commit.Put, indices[i], value)
+			}
+		case []any:
+			for i, value := range v {
+				if value != nil {
+					buffer.PutAny(commit.Put, indices[i], value)
+				}
+			}
+		}
+	}
+	return indices, nil
+}
+
+// canInsert returns an error if the values of a column slice can not be written into the
+// column, since their type does not match the kind of the column.
+func canInsert(column *column, values any) error {
+	if column.IsIndex() {
+		return fmt.Errorf("column: unable to insert into index '%s'", column.name)
+	}
+
+	kind := kindOf(column.Column)
+	if items, ok := values.([]any); ok {
+		for i, v := range items {
+			if v != nil && !isAssignable(kind, reflect.TypeOf(v).Kind()) {
+				return fmt.Errorf("column: unable to write %T at %d into column '%s' of %v", v, i, column.name, kind)
+			}
+		}
+		return nil
+	}
+
+	if !isAssignable(kind, reflect.TypeOf(values).Elem().Kind()) {
+		return fmt.Errorf("column: unable to write %T into column '%s' of %v", values, column.name, kind)
+	}
+	return nil
+}
+
+// canWrite returns an error if the value can not be written into the column, since its type
+// does not match the kind of the column.
+func canWrite(column *column, value any) error {
+	if value == nil {
+		return nil
+	}
+
+	kind := kindOf(column.Column)
+	ok := isAssignable(kind, reflect.TypeOf(value).Kind())
+	if ok && isNumberKind(kind) {
+		_, ok = convertNumber[float64](value)
+	}
+
+	if !ok {
+		return fmt.Errorf("column: unable to write %T into column '%s' of %v", value, column.name, kind)
+	}
+	return nil
+}
+
+// isAssignable returns whether a value of a kind can be written into a column of a kind. The
+// numbers are converted into the type of a numeric column, while the columns of other types
+// than booleans, numbers and strings accept any value.
+func isAssignable(column, value reflect.Kind) bool {
+	switch column {
+	case reflect.Interface:
+		return true
+	case reflect.Bool, reflect.String:
+		return column == value
+	default:
+		return isNumberKind(value)
+	}
+}
+
+// isNumberKind returns whether the kind is a number
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// lengthOf returns the length of a column slice, if supported
+func lengthOf(values any) (int, bool) {
+	switch v := values.(type) {
+	case []float64:
+		return len(v), true
+	case []float32:
+		return len(v), true
+	case []int:
+		return len(v), true
+	case []int16:
+		return len(v), true
+	case []int32:
+		return len(v), true
+	case []int64:
+		return len(v), true
+	case []uint:
+		return len(v), true
+	case []uint16:
+		return len(v), true
+	case []uint32:
+		return len(v), true
+	case []uint64:
+		return len(v), true
+	case []bool:
+		return len(v), true
+	case []string:
+		return len(v), true
+	case []any:
+		return len(v), true
+	default:
+		return 0, false
+	}
+}
+
+// putAll writes every value of the slice at its corresponding index
+func putAll[T any](indices []uint32, values []T, put func(uint32, T)) {
+	for i, v := range values {
+		put(indices[i], v)
+	}
+}
+
 // InsertObjectWithTTL adds an object to a collection, sets the expiration time
 // based on the specified time-to-live and returns the allocated index.
 func (txn *Txn) InsertObjectWithTTL(object Object, ttl time.Duration) (uint32, error) {
-	return txn.insertObject(object, time.Now().Add(ttl).UnixNano())
+	return txn.insertObject(object, txn.owner.now().Add(ttl).UnixNano())
 }
 
 // Insert executes a mutable cursor transactionally at a new offset.
@@ -335,19 +882,36 @@ func (txn *Txn) Insert(fn func(Row) error) (uint32, error) {
 // InsertWithTTL executes a mutable cursor transactionally at a new offset and sets the expiration time
 // based on the specified time-to-live and returns the allocated index.
 func (txn *Txn) InsertWithTTL(ttl time.Duration, fn func(Row) error) (uint32, error) {
-	return txn.insert(fn, time.Now().Add(ttl).UnixNano())
+	return txn.insert(fn, txn.owner.now().Add(ttl).UnixNano())
 }
 
-// insertObject inserts all of the keys of a map, if previously registered as columns.
+// insertObject inserts all of the keys of a map, if previously registered as columns, and
+// returns an error listing the keys which were not.
 func (txn *Txn) insertObject(object Object, expireAt int64) (uint32, error) {
-	return txn.insert(func(Row) error {
+	if err := txn.inferColumns(object); err != nil {
+		return 0, err
+	}
+
+	if err := txn.invalidValues(object); err != nil {
+		return 0, err
+	}
+
+	missing := false
+	idx, err := txn.insert(func(Row) error {
 		for k, v := range object {
-			if _, ok := txn.columnAt(k); ok {
-				txn.bufferFor(k).PutAny(commit.Put, txn.cursor, v)
+			if column, ok := txn.columnAt(k); ok {
+				column.PutAny(txn.bufferFor(k), txn.cursor, v)
+			} else {
+				missing = true
 			}
 		}
 		return nil
 	}, expireAt)
+
+	if err == nil && missing {
+		err = txn.missingColumns(object)
+	}
+	return idx, err
 }
 
 // insert creates an insertion cursor for a given column and expiration time.
@@ -372,39 +936,204 @@ func (txn *Txn) insert(fn func(Row) error, expireAt int64) (uint32, error) {
 // DeleteAll marks all of the items currently selected by this transaction for deletion. The
 // actual delete will take place once the transaction is committed.
 func (txn *Txn) DeleteAll() {
-	txn.initialize()
-	txn.index.Range(func(x uint32) {
-		txn.deleteAt(x)
+	txn.deleteSelected()
+}
+
+// DeleteWhere marks the selected items whose value of the column satisfies the predicate
+// for deletion and returns their number. Like the filters, it narrows down the selection of
+// the transaction. The actual delete will take place once the transaction is committed.
+func (txn *Txn) DeleteWhere(column string, predicate func(v interface{}) bool) int {
+	return txn.WithValue(column, predicate).deleteSelected()
+}
+
+// DeleteWhereFloat marks the selected items whose value satisfies the predicate for deletion
+// and returns their number. The column must be numerical and convertible to float64, its
+// values are scanned chunk by chunk without going through the interface{} conversion.
+func (txn *Txn) DeleteWhereFloat(column string, predicate func(v float64) bool) int {
+	return txn.WithFloat(column, predicate).deleteSelected()
+}
+
+// DeleteWhereInt marks the selected items whose value satisfies the predicate for deletion
+// and returns their number. The column must be numerical and convertible to int64.
+func (txn *Txn) DeleteWhereInt(column string, predicate func(v int64) bool) int {
+	return txn.WithInt(column, predicate).deleteSelected()
+}
+
+// DeleteWhereUint marks the selected items whose value satisfies the predicate for deletion
+// and returns their number. The column must be numerical and convertible to uint64.
+func (txn *Txn) DeleteWhereUint(column string, predicate func(v uint64) bool) int {
+	return txn.WithUint(column, predicate).deleteSelected()
+}
+
+// DeleteWhereString marks the selected items whose value satisfies the predicate for
+// deletion and returns their number. The column must be a string.
+func (txn *Txn) DeleteWhereString(column string, predicate func(v string) bool) int {
+	return txn.WithString(column, predicate).deleteSelected()
+}
+
+// DeleteWhereIntLess marks the selected items whose value is less than the specified one for
+// deletion and returns their number, for example in order to evict the rows older than a
+// timestamp. The values are compared directly in the column, without calling a predicate.
+func (txn *Txn) DeleteWhereIntLess(column string, value int64) int {
+	return txn.WithIntLess(column, value).deleteSelected()
+}
+
+// DeleteWhereFloatLess marks the selected items whose value is less than the specified one
+// for deletion and returns their number. The values are compared directly in the column,
+// without calling a predicate.
+func (txn *Txn) DeleteWhereFloatLess(column string, value float64) int {
+	return txn.WithFloatLess(column, value).deleteSelected()
+}
+
+// deleteSelected marks the selected items for deletion, a chunk of the index at a time, and
+// returns their number.
+func (txn *Txn) deleteSelected() int {
+	txn.resolve()
+	last, ok := txn.index.Max()
+	if !ok {
+		return 0
+	}
+
+	markers := txn.bufferFor(rowColumn)
+	for chunk := commit.Chunk(0); chunk <= commit.ChunkAt(last); chunk++ {
+		markers.PutBitmap(commit.Delete, chunk, txn.index)
+	}
+	return txn.index.Count()
+}
+
+// UpdateAll sets the value of the column for all of the items currently selected by this
+// transaction, in a single pass. The actual update will take place once the transaction
+// is committed.
+func (txn *Txn) UpdateAll(columnName string, value interface{}) error {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		return fmt.Errorf("column: column '%s' does not exist", columnName)
+	}
+
+	// Validate the value once, as the same value is written into every row
+	if err := canWrite(column, value); err != nil {
+		return err
+	}
+
+	txn.resolve()
+	buffer := txn.bufferFor(columnName)
+	txn.index.Range(func(idx uint32) {
+		column.PutAny(buffer, idx, value)
 	})
+	return nil
 }
 
 // Range selects and iterates over result set. In each iteration step, the internal
-// transaction cursor is updated and can be used by various column accessors.
+// transaction cursor is updated and can be used by various column accessors. If the
+// context of the transaction is cancelled, the iteration stops and the error of the
+// context is returned.
 func (txn *Txn) Range(fn func(idx uint32)) error {
-	txn.initialize()
+	txn.resolve()
 	txn.rangeRead(func(chunk commit.Chunk, index bitmap.Bitmap) {
+		if txn.owner.metrics != nil || txn.trace != nil {
+			txn.scanned += index.Count()
+		}
+
 		offset := chunk.Min()
 		index.Range(func(x uint32) {
 			txn.cursor = offset + x
 			fn(offset + x)
 		})
 	})
-	return nil
+	return txn.ctx.Err()
+}
+
+// Context returns the context of the transaction.
+func (txn *Txn) Context() context.Context {
+	return txn.ctx
+}
+
+// Annotate attaches the metadata to the commits of the transaction, such as the identifier of
+// the originating request. The metadata is passed along with the commits to the commit log,
+// so that the downstream consumers can correlate them with their source.
+func (txn *Txn) Annotate(meta []byte) *Txn {
+	txn.meta = meta
+	return txn
 }
 
 // Rollback empties the pending update and delete queues and does not apply any of
 // the pending updates/deletes. This operation can be called several times for
 // a transaction in order to perform partial rollbacks.
 func (txn *Txn) rollback() {
-	txn.reset()
+	defer txn.reset()
+	txn.closeIterators()
+	txn.summarize(false)
+
+	// Release the indices which were reserved for the insertions
+	if markers, ok := txn.findMarkers(); ok {
+		txn.releaseInserts(markers)
+	}
 }
 
 // Commit commits the transaction by applying all pending updates and deletes to
 // the collection. This operation is can be called several times for a transaction
 // in order to perform partial commits. If there's no pending updates/deletes, this
 // operation will result in a no-op.
-func (txn *Txn) commit() {
+func (txn *Txn) commit() error {
 	defer txn.reset()
+	txn.closeIterators()
+	defer txn.owner.alerts.notify() // Once the locks are released
+
+	// If the collection belongs to a catalog, hold its commit barrier so that catalog-wide
+	// snapshots never observe a partially applied commit.
+	if barrier := txn.owner.barrier; barrier != nil {
+		barrier.RLock()
+		defer barrier.RUnlock()
+	}
+
+	// Hold back the commit while the collection is copied for a view or a clone
+	if txn.hasUpdates() {
+		txn.owner.forks.RLock()
+		defer txn.owner.forks.RUnlock()
+	}
+
+	// If the transaction tracks its reads, make sure none of them were modified meanwhile
+	if txn.serialized() {
+		txn.owner.verify.Lock()
+		defer txn.owner.verify.Unlock()
+		if err := txn.validate(); err != nil {
+			err = txn.journaled(err)
+			txn.rollback()
+			return err
+		}
+	}
+
+	// Make sure none of the modified rows is locked by a concurrent transaction
+	if err := txn.checkLocks(); err != nil {
+		err = txn.journaled(err)
+		txn.rollback()
+		return err
+	}
+
+	// Make sure the written values satisfy the check constraints of their columns
+	if err := txn.checkValues(); err != nil {
+		err = txn.journaled(err)
+		txn.rollback()
+		return err
+	}
+
+	txn.commitChanges()
+	return nil
+}
+
+// commitChanges applies all pending updates and deletes to the collection, chunk by chunk.
+// The caller is responsible for holding the commit barrier and the fork lock of the collection.
+//
+// Each chunk is applied while latched, exclusively if the transaction inserts or deletes rows
+// and only for the updated columns otherwise, in a strict order: first the inserted rows
+// are added to the fill list, then the updates are applied to the columns (and the computed
+// columns) in the order they were issued, and finally the deleted rows are removed from the
+// fill list and from every column. Since the deletes are applied last, a row deleted by the
+// transaction never keeps any values, even if it was updated or inserted after being deleted.
+func (txn *Txn) commitChanges() {
+	txn.coalesce()
+	txn.stampExpiry()
+	summary := txn.summarize(true)
 
 	// Mark the dirty chunks from the updates
 	for _, u := range txn.updates {
@@ -415,18 +1144,27 @@ func (txn *Txn) commit() {
 
 	// Grow the size of the fill list
 	markers, changedRows := txn.findMarkers()
+	txn.touch(changedRows)
 	if last, ok := txn.dirty.Max(); ok {
 		txn.commitCapacity(commit.Chunk(last))
 	}
 
 	// Commit chunk by chunk to reduce lock contentions
-	txn.rangeWrite(func(commitID uint64, chunk commit.Chunk, fill bitmap.Bitmap) {
+	var inserts, deletes int
+	var lastID uint64
+	txn.rangeWrite(txn.latchColumns(changedRows), func(commitID uint64, chunk commit.Chunk, fill bitmap.Bitmap) {
+		if commitID > lastID {
+			lastID = commitID
+		}
 		if changedRows {
-			txn.commitMarkers(chunk, fill, markers)
+			inserts += txn.commitInserts(chunk, markers)
 		}
 
 		// Attemp to update, if nothing was changed we're done
 		updated := txn.commitUpdates(chunk)
+		if changedRows {
+			deletes += txn.commitDeletes(chunk, markers)
+		}
 		if !changedRows && !updated {
 			return
 		}
@@ -437,17 +1175,38 @@ func (txn *Txn) commit() {
 				ID:      commitID,
 				Chunk:   chunk,
 				Updates: txn.updates,
+				Meta:    txn.meta,
 			})
 		}
 
 		if txn.logger != nil {
-			txn.logger.Append(commit.Commit{
+			txn.owner.log(txn.logger, commit.Commit{
 				ID:      commitID,
 				Chunk:   chunk,
 				Updates: txn.updates,
+				Meta:    txn.meta,
 			})
 		}
+
+		// Stream the commit to the in-process replicas, if any
+		txn.owner.replicate(commit.Commit{
+			ID:      commitID,
+			Chunk:   chunk,
+			Updates: txn.updates,
+			Meta:    txn.meta,
+		})
 	})
+
+	if txn.owner.metrics != nil && txn.dirty.Count() > 0 {
+		txn.owner.metrics.ObserveCommit(inserts, deletes)
+	}
+
+	// Keep the actual number of rows changed for the listeners
+	if summary != nil {
+		summary.ID = lastID
+		summary.Inserted = inserts
+		summary.Deleted = deletes
+	}
 }
 
 // commitUpdates applies the pending updates to the collection.
@@ -477,24 +1236,34 @@ func (txn *Txn) commitUpdates(chunk commit.Chunk) (updated bool) {
 	return updated
 }
 
-// commitMarkers commits inserts and deletes to the collection.
-func (txn *Txn) commitMarkers(chunk commit.Chunk, fill bitmap.Bitmap, buffer *commit.Buffer) {
+// commitInserts adds the inserted rows to the fill list and returns their number.
+func (txn *Txn) commitInserts(chunk commit.Chunk, buffer *commit.Buffer) (inserts int) {
 	txn.reader.Range(buffer, chunk, func(r *commit.Reader) {
+		txn.owner.lock.Lock()
+		defer txn.owner.lock.Unlock()
 		for r.Next() {
-			txn.owner.lock.Lock()
-			switch r.Type {
-			case commit.Insert:
+			if r.Type == commit.Insert {
 				txn.owner.fill.Set(r.Index())
-			case commit.Delete:
-				txn.owner.fill.Remove(r.Index())
+				inserts++
 			}
-			txn.owner.lock.Unlock()
 		}
 	})
+	return
+}
 
-	// We also need to apply the delete operations on the column so it
-	// can remove unnecessary data.
+// commitDeletes removes the deleted rows from the fill list and returns their number. The
+// deletes are also applied on all of the columns so they can remove unnecessary data.
+func (txn *Txn) commitDeletes(chunk commit.Chunk, buffer *commit.Buffer) (deletes int) {
 	txn.reader.Range(buffer, chunk, func(r *commit.Reader) {
+		txn.owner.lock.Lock()
+		for r.Next() {
+			if r.Type == commit.Delete {
+				txn.owner.fill.Remove(r.Index())
+				deletes++
+			}
+		}
+		txn.owner.lock.Unlock()
+
 		txn.owner.cols.Range(func(column *column) {
 			column.Apply(chunk, r)
 		})
@@ -503,6 +1272,7 @@ func (txn *Txn) commitMarkers(chunk commit.Chunk, fill bitmap.Bitmap, buffer *co
 	txn.owner.lock.Lock()
 	atomic.StoreUint64(&txn.owner.count, uint64(txn.owner.fill.Count()))
 	txn.owner.lock.Unlock()
+	return
 }
 
 // findMarkers finds a set of insert/deletes
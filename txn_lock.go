@@ -1,112 +1,171 @@
-// Copyright (c) Roman Atachiants and contributors. All rights reserved.
-// Licensed under the MIT license. See LICENSE file in the project root for details.
-
-package column
-
-import (
-	"github.com/kelindar/bitmap"
-	"github.com/kelindar/column/commit"
-)
-
-const (
-	bitmapShift = chunkShift - 6
-	bitmapSize  = 1 << bitmapShift
-	chunkShift  = 14 // 16K
-	chunkSize   = 1 << chunkShift
-)
-
-// initialize ensures that the transaction is pre-initialized with the snapshot
-// of the owner's fill list.
-func (txn *Txn) initialize() {
-	if txn.setup {
-		return
-	}
-
-	txn.owner.lock.RLock()
-	txn.index.Grow(uint32(txn.owner.opts.Capacity))
-	txn.owner.fill.Clone(&txn.index)
-	txn.owner.lock.RUnlock()
-	txn.setup = true
-}
-
-// --------------------------- Locked Seek ---------------------------
-
-// QueryAt jumps at a particular offset in the collection, sets the cursor to the
-// provided position and executes given callback fn.
-func (txn *Txn) QueryAt(index uint32, f func(Row) error) (err error) {
-	lock := txn.owner.slock
-	txn.cursor = index
-
-	chunk := commit.ChunkAt(index)
-	lock.RLock(uint(chunk))
-	err = f(Row{txn})
-	lock.RUnlock(uint(chunk))
-	return err
-}
-
-// --------------------------- Locked Range ---------------------------
-
-// rangeRead iterates over index, chunk by chunk and ensures that each
-// chunk is protected by an appropriate read lock.
-func (txn *Txn) rangeRead(f func(chunk commit.Chunk, index bitmap.Bitmap)) {
-	limit := commit.Chunk(len(txn.index) >> bitmapShift)
-	lock := txn.owner.slock
-
-	for chunk := commit.Chunk(0); chunk <= limit; chunk++ {
-		lock.RLock(uint(chunk))
-		f(chunk, chunk.OfBitmap(txn.index))
-		lock.RUnlock(uint(chunk))
-	}
-}
-
-// rangeReadPair iterates over the index and another bitmap, chunk by chunk and
-// ensures that each chunk is protected by an appropriate read lock.
-func (txn *Txn) rangeReadPair(column *column, f func(a, b bitmap.Bitmap)) {
-	limit := commit.Chunk(len(txn.index) >> bitmapShift)
-	lock := txn.owner.slock
-
-	// Iterate through all of the chunks and acquire appropriate shard locks.
-	for chunk := commit.Chunk(0); chunk <= limit; chunk++ {
-		lock.RLock(uint(chunk))
-		f(chunk.OfBitmap(txn.index), column.Index(chunk))
-		lock.RUnlock(uint(chunk))
-	}
-}
-
-// rangeWrite ranges over the dirty chunks and acquires exclusive latches along
-// the way. This is used to commit a transaction.
-func (txn *Txn) rangeWrite(fn func(commitID uint64, chunk commit.Chunk, fill bitmap.Bitmap)) {
-	lock := txn.owner.slock
-	txn.dirty.Range(func(x uint32) {
-		chunk := commit.Chunk(x)
-		commitID := commit.Next()
-		lock.Lock(uint(chunk))
-
-		// Compute the fill and set the last commit ID
-		txn.owner.lock.RLock()
-		fill := chunk.OfBitmap(txn.owner.fill)
-		txn.owner.commits[chunk] = commitID // OK, since we have a shard lock
-		txn.owner.lock.RUnlock()
-
-		// Call the delegate
-		fn(commitID, chunk, fill)
-		lock.Unlock(uint(chunk))
-	})
-}
-
-// readChunk acquires appropriate locks for a chunk and executes a read callback
-func (c *Collection) readChunk(chunk commit.Chunk, fn func(uint64, commit.Chunk, bitmap.Bitmap) error) (err error) {
-	lock := c.slock
-	lock.RLock(uint(chunk))
-
-	// Compute the fill
-	c.lock.RLock()
-	fill := chunk.OfBitmap(c.fill)
-	commitID := c.commits[chunk]
-	c.lock.RUnlock()
-
-	// Call the delegate
-	err = fn(commitID, chunk, fill)
-	lock.RUnlock(uint(chunk))
-	return
-}
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+const (
+	bitmapShift = chunkShift - 6
+	bitmapSize  = 1 << bitmapShift
+	chunkShift  = 14 // 16K
+	chunkSize   = 1 << chunkShift
+)
+
+// initialize ensures that the transaction is pre-initialized with the snapshot
+// of the owner's fill list.
+func (txn *Txn) initialize() {
+	if txn.setup {
+		return
+	}
+
+	txn.owner.lock.RLock()
+	txn.index.Grow(uint32(txn.owner.opts.Capacity))
+	txn.owner.fill.Clone(&txn.index)
+	txn.owner.lock.RUnlock()
+	txn.setup = true
+}
+
+// --------------------------- Locked Seek ---------------------------
+
+// QueryAt jumps at a particular offset in the collection, sets the cursor to the
+// provided position and executes given callback fn.
+func (txn *Txn) QueryAt(index uint32, f func(Row) error) error {
+	txn.cursor = index
+	chunk := commit.ChunkAt(index)
+	locked := txn.readLock(chunk)
+	defer txn.readUnlock(chunk, locked)
+	return f(Row{txn})
+}
+
+// --------------------------- Locked Range ---------------------------
+
+// rangeRead iterates over index, chunk by chunk and ensures that each
+// chunk is protected by an appropriate read lock. The iteration stops
+// early if the context of the transaction is cancelled.
+func (txn *Txn) rangeRead(f func(chunk commit.Chunk, index bitmap.Bitmap)) {
+	limit := commit.Chunk(len(txn.index) >> bitmapShift)
+	for chunk := commit.Chunk(0); chunk <= limit && txn.ctx.Err() == nil; chunk++ {
+		locked := txn.readLock(chunk)
+		f(chunk, txn.prune(chunk))
+		txn.readUnlock(chunk, locked)
+	}
+}
+
+// rangeReadPair iterates over the index and another bitmap, chunk by chunk and
+// ensures that each chunk is protected by an appropriate read lock.
+func (txn *Txn) rangeReadPair(column *column, f func(a, b bitmap.Bitmap)) {
+	limit := commit.Chunk(len(txn.index) >> bitmapShift)
+	for chunk := commit.Chunk(0); chunk <= limit && txn.ctx.Err() == nil; chunk++ {
+		locked := txn.readLock(chunk)
+		f(txn.prune(chunk), column.Index(chunk))
+		txn.readUnlock(chunk, locked)
+	}
+}
+
+// prune removes the rows which were deleted since the transaction started from the index
+// of a chunk, and returns it. The index is a snapshot of the rows taken when the transaction
+// starts, so without it the rows deleted by the commits in the meantime would still be
+// iterated over, with their values already gone. The chunk must be read-locked.
+func (txn *Txn) prune(chunk commit.Chunk) bitmap.Bitmap {
+	index := chunk.OfBitmap(txn.index)
+	txn.owner.lock.RLock()
+	fill := chunk.OfBitmap(txn.owner.fill)
+	for i := range index {
+		if i < len(fill) {
+			index[i] &= fill[i]
+		} else {
+			index[i] = 0
+		}
+	}
+	txn.owner.lock.RUnlock()
+	return index
+}
+
+// readLock read-locks the shard of a chunk, unless the transaction already holds it, and
+// returns whether it was locked. The reads nested within an iteration, such as a QueryAt()
+// within Range(), must not lock the shard again since the nested lock would wait behind a
+// pending commit, which itself waits for the outer lock to be released.
+func (txn *Txn) readLock(chunk commit.Chunk) bool {
+	shard := uint(chunk) % 128
+	if txn.reading[shard>>6]&(1<<(shard&63)) != 0 {
+		txn.owner.thaw(chunk)
+		return false
+	}
+
+	txn.owner.slock.RLock(shard)
+	txn.owner.thaw(chunk)
+	txn.reading[shard>>6] |= 1 << (shard & 63)
+	return true
+}
+
+// readUnlock releases the read lock of the shard of a chunk, if it was acquired by readLock()
+func (txn *Txn) readUnlock(chunk commit.Chunk, locked bool) {
+	if locked {
+		shard := uint(chunk) % 128
+		txn.reading[shard>>6] &^= 1 << (shard & 63)
+		txn.owner.slock.RUnlock(shard)
+	}
+}
+
+// rangeWrite ranges over the dirty chunks and acquires the latches of the columns
+// written along the way. This is used to commit a transaction.
+func (txn *Txn) rangeWrite(columns uint64, fn func(commitID uint64, chunk commit.Chunk, fill bitmap.Bitmap)) {
+	lock := txn.owner.slock
+	txn.dirty.Range(func(x uint32) {
+		chunk := commit.Chunk(x)
+		lock.LockColumns(uint(chunk), columns)
+		txn.owner.thaw(chunk)
+		commitID := commit.Next()
+
+		// Compute the fill and set the last commit ID
+		txn.owner.lock.RLock()
+		fill := chunk.OfBitmap(txn.owner.fill)
+		setCommit(&txn.owner.commits[chunk], commitID)
+		txn.owner.lock.RUnlock()
+		txn.owner.advance(commitID)
+
+		// Call the delegate
+		fn(commitID, chunk, fill)
+		lock.UnlockColumns(uint(chunk), columns)
+	})
+}
+
+// readChunk acquires appropriate locks for a chunk and executes a read callback
+func (c *Collection) readChunk(chunk commit.Chunk, fn func(uint64, commit.Chunk, bitmap.Bitmap) error) (err error) {
+	lock := c.slock
+	lock.RLock(uint(chunk))
+	c.thaw(chunk)
+
+	// Copy the fill, since the rows of the chunk can be inserted while it is read
+	c.lock.RLock()
+	fill := chunk.OfBitmap(c.fill).Clone(nil)
+	commitID := c.commits[chunk]
+	c.lock.RUnlock()
+
+	// Call the delegate
+	err = fn(commitID, chunk, fill)
+	lock.RUnlock(uint(chunk))
+	return
+}
+
+// lockAll acquires exclusive latches on every shard of the collection, so that no commit
+// can happen while the function is being executed.
+func (c *Collection) lockAll(fn func() error) error {
+	lock := c.slock
+	for shard := uint(0); shard < 128; shard++ {
+		lock.Lock(shard)
+	}
+
+	defer func() {
+		for shard := uint(0); shard < 128; shard++ {
+			lock.Unlock(shard)
+		}
+	}()
+
+	c.thawAll()
+	return fn()
+}
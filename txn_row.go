@@ -3,6 +3,8 @@
 
 package column
 
+import "encoding/json"
+
 // Row represents a cursor at a particular row offest in the transaction.
 type Row struct {
 	txn *Txn
@@ -216,3 +218,59 @@ func (r Row) Any(columnName string) (any, bool) {
 func (r Row) SetAny(columnName string, value interface{}) {
 	r.txn.Any(columnName).Set(value)
 }
+
+// Point loads a point value at a particular column
+func (r Row) Point(columnName string) (Point, bool) {
+	return pointReaderFor(r.txn, columnName).Get()
+}
+
+// SetPoint stores a point value at a particular column
+func (r Row) SetPoint(columnName string, value Point) {
+	r.txn.Point(columnName).Set(value)
+}
+
+// UUID loads a UUID value at a particular column
+func (r Row) UUID(columnName string) (UUID, bool) {
+	return uuidReaderFor(r.txn, columnName).Get()
+}
+
+// SetUUID stores a UUID value at a particular column
+func (r Row) SetUUID(columnName string, value UUID) {
+	r.txn.UUID(columnName).Set(value)
+}
+
+// Bytes loads a byte slice at a particular column, which must not be modified
+func (r Row) Bytes(columnName string) ([]byte, bool) {
+	return bytesReaderFor(r.txn, columnName).Get()
+}
+
+// SetBytes stores a byte slice at a particular column
+func (r Row) SetBytes(columnName string, value []byte) {
+	r.txn.Bytes(columnName).Set(value)
+}
+
+// JSON loads a JSON document at a particular column, which must not be modified
+func (r Row) JSON(columnName string) (json.RawMessage, bool) {
+	return jsonReaderFor(r.txn, columnName).Get()
+}
+
+// SetJSON stores a JSON document at a particular column, encoding the value if it is not
+// a raw document
+func (r Row) SetJSON(columnName string, value any) {
+	r.txn.JSON(columnName).Set(value)
+}
+
+// Decimal loads a decimal value at a particular column
+func (r Row) Decimal(columnName string) (Decimal, bool) {
+	return decimalReaderFor(r.txn, columnName).Get()
+}
+
+// SetDecimal stores a decimal value at a particular column
+func (r Row) SetDecimal(columnName string, value Decimal) error {
+	return r.txn.Decimal(columnName).Set(value)
+}
+
+// AddDecimal adds delta to a decimal value at a particular column
+func (r Row) AddDecimal(columnName string, delta Decimal) error {
+	return r.txn.Decimal(columnName).Add(delta)
+}
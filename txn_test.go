@@ -4,9 +4,11 @@
 package column
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -114,6 +116,80 @@ func TestCount(t *testing.T) {
 	})
 }
 
+func TestWithComparison(t *testing.T) {
+	players := loadPlayers(500)
+	for _, tc := range []struct {
+		column    string
+		filter    func(txn *Txn) *Txn
+		predicate func(v float64) bool
+	}{
+		{"balance", func(txn *Txn) *Txn { return txn.WithFloatGreater("balance", 2500) }, func(v float64) bool { return v > 2500 }},
+		{"balance", func(txn *Txn) *Txn { return txn.WithFloatLess("balance", 2500) }, func(v float64) bool { return v < 2500 }},
+		{"age", func(txn *Txn) *Txn { return txn.WithFloatEqual("age", 30) }, func(v float64) bool { return v == 30 }},
+		{"balance", func(txn *Txn) *Txn { return txn.WithIntGreater("balance", 2500) }, func(v float64) bool { return int64(v) > 2500 }},
+		{"balance", func(txn *Txn) *Txn { return txn.WithIntLess("balance", 2500) }, func(v float64) bool { return int64(v) < 2500 }},
+		{"age", func(txn *Txn) *Txn { return txn.WithIntEqual("age", 30) }, func(v float64) bool { return int64(v) == 30 }},
+		{"balance", func(txn *Txn) *Txn { return txn.WithFloatBetween("balance", 1000, 3000) }, func(v float64) bool { return v >= 1000 && v <= 3000 }},
+		{"age", func(txn *Txn) *Txn { return txn.WithIntBetween("age", 20, 30) }, func(v float64) bool { return int64(v) >= 20 && int64(v) <= 30 }},
+	} {
+		var expect int
+		players.Query(func(txn *Txn) error {
+			expect = txn.With("human").WithFloat(tc.column, tc.predicate).Count()
+			return nil
+		})
+
+		// Must match the equivalent predicate
+		players.Query(func(txn *Txn) error {
+			assert.NotZero(t, expect)
+			assert.Equal(t, expect, tc.filter(txn.With("human")).Count())
+			return nil
+		})
+	}
+
+	// Invalid or non-numeric columns select nothing
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithIntEqual("invalid", 1).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithFloatGreater("name", 1).Count())
+		return nil
+	})
+}
+
+func TestWithComparisonSparse(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("value", ForInt())
+
+	// Only the first chunk has values, and only for every other row
+	coll.Query(func(txn *Txn) error {
+		for i := 0; i < 3*chunkSize; i++ {
+			txn.Insert(func(r Row) error {
+				r.SetString("name", "Roman")
+				if i < chunkSize && i%2 == 0 {
+					r.SetInt("value", i)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 50, txn.WithIntLess("value", 100).Count())
+		return nil
+	})
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, chunkSize/2, txn.WithIntGreater("value", -1).Count())
+		return nil
+	})
+	coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithIntEqual("value", 1).Count())
+		return nil
+	})
+}
+
 func TestIndexInvalid(t *testing.T) {
 	players := loadPlayers(500)
 	players.Query(func(txn *Txn) error {
@@ -238,6 +314,67 @@ func TestDeleteAll(t *testing.T) {
 	}))
 }
 
+func TestDeleteWhere(t *testing.T) {
+	players := loadPlayers(500)
+	count := func(fn func(txn *Txn) int) (n int) {
+		players.Query(func(txn *Txn) error {
+			n = fn(txn)
+			return nil
+		})
+		return
+	}
+
+	// Count the rows to be deleted, before deleting them
+	young := count(func(txn *Txn) int { return txn.WithIntLess("age", 30).Count() })
+	assert.NotZero(t, young)
+
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		assert.Equal(t, young, txn.DeleteWhereIntLess("age", 30))
+		return nil
+	}))
+	assert.Equal(t, 500-young, players.Count())
+	assert.Zero(t, count(func(txn *Txn) int { return txn.WithIntLess("age", 30).Count() }))
+
+	// The typed variants are restricted to the selection of the transaction
+	rich := count(func(txn *Txn) int {
+		return txn.WithFloat("balance", func(v float64) bool { return v > 3000 }).Count()
+	})
+	deleted := count(func(txn *Txn) int {
+		return txn.With("human").DeleteWhereFloat("balance", func(v float64) bool { return v > 3000 })
+	})
+	assert.Equal(t, 500-young-deleted, players.Count())
+	assert.Equal(t, rich-deleted, count(func(txn *Txn) int {
+		return txn.WithFloat("balance", func(v float64) bool { return v > 3000 }).Count()
+	}))
+
+	// Every other variant
+	count(func(txn *Txn) int { return txn.DeleteWhereString("race", func(v string) bool { return v == "elf" }) })
+	count(func(txn *Txn) int { return txn.DeleteWhereInt("age", func(v int64) bool { return v > 60 }) })
+	count(func(txn *Txn) int { return txn.DeleteWhereUint("age", func(v uint64) bool { return v == 50 }) })
+	count(func(txn *Txn) int { return txn.DeleteWhereFloatLess("balance", 1000) })
+	count(func(txn *Txn) int {
+		return txn.DeleteWhere("class", func(v interface{}) bool { return v == "mage" })
+	})
+
+	remaining := players.Count()
+	assert.NotZero(t, remaining)
+	for _, fn := range []func(txn *Txn) int{
+		func(txn *Txn) int { return txn.WithString("race", func(v string) bool { return v == "elf" }).Count() },
+		func(txn *Txn) int { return txn.WithInt("age", func(v int64) bool { return v > 60 || v == 50 }).Count() },
+		func(txn *Txn) int { return txn.WithFloatLess("balance", 1000).Count() },
+		func(txn *Txn) int {
+			return txn.WithValue("class", func(v interface{}) bool { return v == "mage" }).Count()
+		},
+	} {
+		assert.Zero(t, count(fn))
+	}
+
+	// Deleting from an unknown column or an empty selection is a no-op
+	assert.Zero(t, count(func(txn *Txn) int { return txn.DeleteWhereIntLess("unknown", 0) }))
+	assert.Zero(t, count(func(txn *Txn) int { return txn.With("unknown").DeleteWhere("age", nil) }))
+	assert.Equal(t, remaining, players.Count())
+}
+
 func TestDeleteFromIndex(t *testing.T) {
 	players := loadPlayers(500)
 	assert.Equal(t, 500, players.Count())
@@ -354,7 +491,125 @@ func TestUpdateWithRollback(t *testing.T) {
 	})
 }
 
+func TestUpdateWithPanic(t *testing.T) {
+	players := loadPlayers(500)
+	players.CreateIndex("rich", "balance", func(r Reader) bool {
+		return r.Float() >= 3000
+	})
+
+	var rich int
+	players.Query(func(txn *Txn) error {
+		rich = txn.With("rich").Count()
+		return nil
+	})
+
+	// Panic after updating everyone, should be rolled back and propagated
+	assert.Panics(t, func() {
+		players.Query(func(txn *Txn) error {
+			balance := txn.Float64("balance")
+			txn.Range(func(index uint32) {
+				balance.Set(5000.0)
+			})
+			panic("boom")
+		})
+	})
+
+	// Panic while holding a row, the lock should be released
+	assert.Panics(t, func() {
+		players.QueryAt(0, func(r Row) error {
+			r.SetFloat64("balance", 5000.0)
+			panic("boom")
+		})
+	})
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, rich, txn.With("rich").Count())
+		assert.Equal(t, 0, len(txn.updates))
+		return nil
+	})
+
+	assert.NoError(t, players.QueryAt(0, func(r Row) error {
+		r.SetFloat64("balance", 1.0)
+		return nil
+	}))
+}
+
 // Details: https://github.com/kelindar/column/issues/17
+func TestCommitOrder(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+	coll.CreateIndex("old", "age", func(r Reader) bool {
+		return r.Int() > 30
+	})
+
+	var crossed []uint32
+	assert.NoError(t, coll.OnThreshold("age", func(v float64) bool {
+		return v > 30
+	}, func(idx uint32, v float64) {
+		crossed = append(crossed, idx)
+	}))
+
+	idx, err := coll.Insert(func(r Row) error {
+		r.SetString("name", "Roman")
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// The deletes are applied after the updates, even if they were issued before
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		txn.QueryAt(idx, func(r Row) error {
+			r.SetInt("age", 50)
+			return nil
+		})
+		txn.DeleteAt(idx)
+		return nil
+	}))
+
+	// A row inserted and deleted by the same transaction is not kept either
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		row, err := txn.Insert(func(r Row) error {
+			r.SetString("name", "Merlin")
+			r.SetInt("age", 60)
+			return nil
+		})
+		txn.DeleteAt(row)
+		return err
+	}))
+
+	assert.Equal(t, 0, coll.Count())
+	assert.Equal(t, 0, coll.CountWith("old"))
+	assert.Empty(t, crossed)
+
+	// The offset is reused, without the values of the deleted rows
+	next, err := coll.Insert(func(r Row) error {
+		r.SetString("name", "Jane")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, idx, next)
+	assert.NoError(t, coll.QueryAt(next, func(r Row) error {
+		_, ok := r.Int("age")
+		assert.False(t, ok)
+		return nil
+	}))
+
+	// An update of a row inserted by the same transaction is applied
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		row, err := txn.Insert(func(r Row) error {
+			r.SetString("name", "Arthur")
+			return nil
+		})
+		return txn.QueryAt(row, func(r Row) error {
+			r.SetInt("age", 40)
+			return err
+		})
+	}))
+	assert.Equal(t, 2, coll.Count())
+	assert.Equal(t, 1, coll.CountWith("old"))
+	assert.Len(t, crossed, 1)
+}
+
 func TestCountTwice(t *testing.T) {
 	model := NewCollection()
 	model.CreateColumnsOf(map[string]interface{}{
@@ -703,3 +958,265 @@ func TestMaxBalance(t *testing.T) {
 		return nil
 	})
 }
+
+func TestRangeTyped(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		expect := txn.With("human").Float64("balance").Sum()
+		names := txn.Enum("name")
+
+		sum, count := 0.0, 0
+		assert.NoError(t, txn.RangeFloat64("balance", func(idx uint32, v float64) {
+			name, ok := names.Get()
+			assert.True(t, ok)
+			assert.NotEmpty(t, name)
+			sum += v
+			count++
+		}))
+
+		assert.Equal(t, 138, count)
+		assert.Equal(t, expect, sum)
+		return nil
+	})
+}
+
+func TestRangeTypedInvalid(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		assert.Error(t, txn.RangeFloat64("invalid", func(idx uint32, v float64) {}))
+		assert.Error(t, txn.RangeInt64("balance", func(idx uint32, v int64) {}))
+		return nil
+	})
+}
+
+func TestUpdateAll(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		return txn.With("human").UpdateAll("balance", 10.0)
+	}))
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 1380.0, txn.With("human").Float64("balance").Sum())
+		assert.Error(t, txn.UpdateAll("invalid", 1))
+		assert.Error(t, txn.UpdateAll("balance", "hello"))
+		assert.Error(t, txn.UpdateAll("name", 1))
+		return nil
+	})
+
+	// The invalid values are not written
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 1380.0, txn.With("human").Float64("balance").Sum())
+		return nil
+	})
+}
+
+func TestApplyFloat64(t *testing.T) {
+	players := loadPlayers(500)
+	var before float64
+	players.Query(func(txn *Txn) error {
+		before = txn.With("human").Float64("balance").Sum()
+		return nil
+	})
+
+	assert.NoError(t, players.Query(func(txn *Txn) error {
+		return txn.With("human").ApplyFloat64("balance", func(v float64) float64 {
+			return v * 2
+		})
+	}))
+
+	players.Query(func(txn *Txn) error {
+		assert.InDelta(t, before*2, txn.With("human").Float64("balance").Sum(), 0.001)
+		assert.Error(t, txn.ApplyInt("balance", func(v int) int { return v }))
+		return nil
+	})
+}
+
+func TestLateFilter(t *testing.T) {
+	players := loadPlayers(500)
+	var humans int
+	players.Query(func(txn *Txn) error {
+		humans = txn.With("human").Count()
+		return nil
+	})
+
+	// The value filter should only be evaluated for the rows matching the index
+	players.Query(func(txn *Txn) error {
+		calls := 0
+		count := txn.WithValue("balance", func(v interface{}) bool {
+			calls++
+			return true
+		}).With("human").Count()
+
+		assert.Equal(t, humans, count)
+		assert.Equal(t, humans, calls)
+		return nil
+	})
+
+	// Typed filters should be applied prior to the generic ones
+	players.Query(func(txn *Txn) error {
+		calls := 0
+		txn.WithValue("balance", func(v interface{}) bool {
+			calls++
+			return true
+		}).WithString("race", func(v string) bool {
+			return v == "human"
+		}).Count()
+
+		assert.Equal(t, humans, calls)
+		return nil
+	})
+
+	// Union should take the pending filters into account
+	var expect int
+	players.Query(func(txn *Txn) error {
+		expect = txn.Union("human", "mage").Count()
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, expect, txn.WithString("race", func(v string) bool {
+			return v == "human"
+		}).Union("mage").Count())
+		return nil
+	})
+}
+
+func TestQueryContext(t *testing.T) {
+	players := loadPlayers(50000)
+
+	// Cancel in the middle of the iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	visited := 0
+	err := players.QueryContext(ctx, func(txn *Txn) error {
+		balance := txn.Float64("balance")
+		return txn.Range(func(idx uint32) {
+			balance.Set(0)
+			if visited++; visited == 10 {
+				cancel()
+			}
+		})
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, visited, 50000)
+
+	// Nothing should have been committed
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WithFloat("balance", func(v float64) bool {
+			return v == 0
+		}).Count())
+		return nil
+	})
+
+	// Cancelled before the query has even started
+	assert.ErrorIs(t, players.QueryContext(ctx, func(txn *Txn) error {
+		return txn.RangeFloat64("balance", func(idx uint32, v float64) {})
+	}), context.Canceled)
+}
+
+func TestInsertMissingColumns(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+
+	// The keys without a column are reported, while the row is inserted regardless
+	err := coll.Query(func(txn *Txn) error {
+		idx, err := txn.InsertObject(Object{"name": "Roman", "agee": 35, "clas": "mage"})
+		assert.Equal(t, uint32(0), idx)
+
+		var missing *MissingColumnsError
+		assert.ErrorAs(t, err, &missing)
+		assert.Equal(t, []string{"agee", "clas"}, missing.Keys)
+		assert.EqualError(t, err, "column: object has keys without a column: 'agee', 'clas'")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, coll.Count())
+
+	// Returning the error rolls back the insertion
+	assert.ErrorIs(t, coll.Query(func(txn *Txn) error {
+		_, err := txn.InsertMany([]Object{{"name": "Merlin"}, {"name": "Arthur", "agee": 30}})
+		return err
+	}), ErrMissingColumns)
+	assert.Equal(t, 1, coll.Count())
+
+	// The strict insertion does not insert anything
+	_, err = coll.InsertStrict(Object{"name": "Merlin", "agee": 30})
+	assert.ErrorIs(t, err, ErrMissingColumns)
+	assert.Equal(t, 1, coll.Count())
+
+	idx, err := coll.InsertStrict(Object{"name": "Merlin", "age": 30})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, coll.Count())
+	coll.QueryAt(idx, func(r Row) error {
+		name, _ := r.String("name")
+		assert.Equal(t, "Merlin", name)
+		return nil
+	})
+}
+
+func TestRangeDeletedConcurrently(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	for i := 0; i < 10; i++ {
+		coll.InsertObject(Object{"name": fmt.Sprintf("player %d", i)})
+	}
+
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		assert.Equal(t, 10, txn.Count())
+
+		// Delete the rows after the transaction has started
+		coll.DeleteAt(3)
+		coll.DeleteAt(7)
+
+		// The deleted rows are not iterated over, instead of reading as empty
+		var visited []uint32
+		names := txn.String("name")
+		assert.NoError(t, txn.Range(func(idx uint32) {
+			_, ok := names.Get()
+			assert.True(t, ok)
+			visited = append(visited, idx)
+		}))
+		assert.Equal(t, []uint32{0, 1, 2, 4, 5, 6, 8, 9}, visited)
+		return nil
+	}))
+}
+
+func TestRangeNestedRead(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("balance", ForFloat64())
+	coll.InsertObject(Object{"balance": 1.0})
+
+	var wg sync.WaitGroup
+	assert.NoError(t, coll.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+
+			// Start a commit on the same chunk, which waits for the iteration
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				coll.QueryAt(idx, func(r Row) error {
+					r.SetFloat64("balance", 2)
+					return nil
+				})
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			// The nested read must not wait behind the pending commit
+			assert.NoError(t, txn.QueryAt(idx, func(r Row) error {
+				balance, _ := r.Float64("balance")
+				assert.Equal(t, 1.0, balance)
+				return nil
+			}))
+		})
+	}))
+
+	wg.Wait()
+	assert.NoError(t, coll.QueryAt(0, func(r Row) error {
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, 2.0, balance)
+		return nil
+	}))
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+)
+
+var (
+	errReadOnly = errors.New("column: unable to write, the view is read-only")
+)
+
+// View executes a read-only transaction which observes a stable snapshot of the collection.
+// The snapshot is a copy-on-write fork of the collection, sharing the chunks of values with
+// it, so the commits keep going while the view is in progress and a view never observes a
+// mix of pre- and post-commit values, even across chunks. Any write to the collection, even
+// issued from within the view, is not visible to it. The function must not issue any writes
+// on the transaction of the view, otherwise an error is returned.
+//
+// Creating the snapshot holds back the commits briefly and copies the fill lists, the indexes
+// and the primary keys of the collection, while a chunk of values is only copied once it is
+// modified during the view.
+func (c *Collection) View(fn func(txn *Txn) error) error {
+	snapshot, err := c.fork()
+	if err != nil {
+		return err
+	}
+
+	// The snapshot is never cleaned up, so that the expired rows remain visible to the view
+	snapshot.cancel()
+	defer snapshot.Close()
+
+	return snapshot.Query(func(txn *Txn) error {
+		if err := fn(txn); err != nil {
+			return err
+		}
+
+		if txn.hasUpdates() {
+			return errReadOnly
+		}
+		return nil
+	})
+}
+
+// hasUpdates returns whether the transaction has any pending updates
+func (txn *Txn) hasUpdates() bool {
+	for _, u := range txn.updates {
+		if !u.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
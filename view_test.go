@@ -0,0 +1,92 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestView(t *testing.T) {
+	players := loadPlayers(50000)
+	var before float64
+	players.Query(func(txn *Txn) error {
+		before = txn.Float64("balance").Sum()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, players.View(func(txn *Txn) error {
+
+		// Update all of the balances concurrently, across multiple chunks, which goes through
+		// while the view is in progress
+		go func() {
+			defer wg.Done()
+			players.Query(func(txn *Txn) error {
+				return txn.UpdateAll("balance", 0.0)
+			})
+		}()
+
+		wg.Wait()
+		assert.Equal(t, before, txn.Float64("balance").Sum())
+		return nil
+	}))
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0.0, txn.Float64("balance").Sum())
+		return nil
+	})
+}
+
+func TestViewReadOnly(t *testing.T) {
+	players := loadPlayers(500)
+	assert.Equal(t, errReadOnly, players.View(func(txn *Txn) error {
+		return txn.UpdateAll("balance", 0.0)
+	}))
+
+	// Accessors without any writes are fine
+	assert.NoError(t, players.View(func(txn *Txn) error {
+		txn.Float64("balance")
+		return nil
+	}))
+}
+
+func TestViewNestedWrite(t *testing.T) {
+	players := loadPlayers(500)
+	count := players.Count()
+
+	// The writes issued from within a view go through, but are not visible to it
+	assert.NoError(t, players.View(func(txn *Txn) error {
+		assert.NoError(t, players.Query(func(txn *Txn) error {
+			_, err := txn.InsertObject(Object{"name": "Roman"})
+			return err
+		}))
+		assert.NoError(t, Atomic(func(tx *Tx) error {
+			return tx.Query(players, func(txn *Txn) error {
+				_, err := txn.InsertObject(Object{"name": "Roman"})
+				return err
+			})
+		}))
+
+		players.InsertObject(Object{"name": "Roman"})
+		assert.Equal(t, count, txn.Count())
+		assert.Equal(t, count+3, players.Count())
+		return nil
+	}))
+	assert.Equal(t, count+3, players.Count())
+}
+
+func TestViewNested(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.View(func(outer *Txn) error {
+		players.InsertObject(Object{"name": "Roman"})
+		return players.View(func(inner *Txn) error {
+			assert.Equal(t, outer.Count()+1, inner.Count())
+			return nil
+		})
+	}))
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrWriterClosed is returned when a write is queued on a writer which was closed.
+	ErrWriterClosed = errors.New("column: writer is closed")
+)
+
+// WriterOptions represents the options of a writer
+type WriterOptions struct {
+	Capacity  int             // The number of writes queued before the callers block (default: 4096)
+	BatchSize int             // The maximum number of writes applied per transaction (default: 1024)
+	OnFlush   func(count int) // The callback for every committed batch, with its number of writes
+	OnError   func(err error) // The callback for every failed write or batch
+}
+
+// Writer queues the writes of many goroutines and applies them in large transactions on a
+// single committer goroutine. The queue is bounded, so that the callers block once it is full
+// and the ingestion slows down to the pace of the commits, rather than growing the memory.
+//
+// A write which returns an error or panics is rolled back on its own, while the other writes of
+// its batch are still committed. Every write starts from the full collection, regardless of the
+// filters applied by the writes before it in the batch. If the commit itself fails, for example
+// on a constraint, the whole batch is discarded. The errors are passed to the OnError callback
+// and returned by Flush().
+type Writer struct {
+	owner *Collection   // The collection which is written to
+	opts  WriterOptions // The options of the writer
+	lock  sync.RWMutex  // The lock which guards the queue against the close
+	queue chan writeOp  // The queue of the pending writes
+	done  chan struct{} // The channel closed once the committer has stopped
+	mu    sync.Mutex    // The lock which guards the error
+	err   error         // The first error since the last flush
+	close bool          // Whether the writer is closed
+}
+
+// writeOp represents a queued write, or a flush marker if the function is nil
+type writeOp struct {
+	fn    func(txn *Txn) error // The write to apply
+	flush chan struct{}        // The channel closed once the writes before the marker are committed
+}
+
+// NewWriter creates a writer for the collection and starts its committer goroutine. The writer
+// must be closed once done, which applies the remaining writes.
+func NewWriter(collection *Collection, opts ...WriterOptions) *Writer {
+	options := WriterOptions{Capacity: 4096, BatchSize: 1024}
+	if len(opts) > 0 {
+		options.OnFlush = opts[0].OnFlush
+		options.OnError = opts[0].OnError
+		if opts[0].Capacity > 0 {
+			options.Capacity = opts[0].Capacity
+		}
+		if opts[0].BatchSize > 0 {
+			options.BatchSize = opts[0].BatchSize
+		}
+	}
+
+	w := &Writer{
+		owner: collection,
+		opts:  options,
+		queue: make(chan writeOp, options.Capacity),
+		done:  make(chan struct{}),
+	}
+
+	go w.run()
+	return w
+}
+
+// Write queues a write, blocking while the queue is full. The function is called later on the
+// committer goroutine, within a transaction shared with the other writes of its batch.
+func (w *Writer) Write(fn func(txn *Txn) error) error {
+	return w.WriteContext(context.Background(), fn)
+}
+
+// WriteContext queues a write, blocking while the queue is full or until the context is done.
+func (w *Writer) WriteContext(ctx context.Context, fn func(txn *Txn) error) error {
+	return w.enqueue(ctx, writeOp{fn: fn})
+}
+
+// Insert queues the insertion of an object.
+func (w *Writer) Insert(obj Object) error {
+	return w.Write(func(txn *Txn) error {
+		_, err := txn.InsertObject(obj)
+		return err
+	})
+}
+
+// UpdateAt queues an update of the row at the specified index.
+func (w *Writer) UpdateAt(idx uint32, fn func(Row) error) error {
+	return w.Write(func(txn *Txn) error {
+		return txn.QueryAt(idx, fn)
+	})
+}
+
+// UpdateKey queues an update of the row with the specified primary key.
+func (w *Writer) UpdateKey(key string, fn func(Row) error) error {
+	return w.Write(func(txn *Txn) error {
+		return txn.QueryKey(key, fn)
+	})
+}
+
+// DeleteAt queues the deletion of the row at the specified index.
+func (w *Writer) DeleteAt(idx uint32) error {
+	return w.Write(func(txn *Txn) error {
+		txn.DeleteAt(idx)
+		return nil
+	})
+}
+
+// Pending returns the number of writes waiting in the queue.
+func (w *Writer) Pending() int {
+	return len(w.queue)
+}
+
+// Flush waits until the writes queued before it are committed and returns the first error
+// which occurred since the last flush, if any.
+func (w *Writer) Flush() error {
+	marker := writeOp{flush: make(chan struct{})}
+	if err := w.enqueue(context.Background(), marker); err != nil {
+		return err
+	}
+
+	<-marker.flush
+	return w.takeError()
+}
+
+// Close stops accepting the writes, waits until the queued ones are committed and returns the
+// first error which occurred since the last flush, if any.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	if !w.close {
+		w.close = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+
+	<-w.done
+	return w.takeError()
+}
+
+// enqueue adds a write to the queue, unless the writer is closed
+func (w *Writer) enqueue(ctx context.Context, op writeOp) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.close {
+		return ErrWriterClosed
+	}
+
+	select {
+	case w.queue <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// --------------------------- Committer ----------------------------
+
+// run applies the queued writes in batches, until the queue is closed and drained
+func (w *Writer) run() {
+	defer close(w.done)
+	batch := make([]writeOp, 0, w.opts.BatchSize)
+	for op := range w.queue {
+		batch = append(batch[:0], op)
+
+		// Take whatever else is queued, up to the size of a batch
+	drain:
+		for len(batch) < w.opts.BatchSize {
+			select {
+			case next, ok := <-w.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		w.apply(batch)
+	}
+}
+
+// apply applies a batch of writes within a single transaction and releases its flush markers
+func (w *Writer) apply(batch []writeOp) {
+	defer func() {
+		for _, op := range batch {
+			if op.flush != nil {
+				close(op.flush)
+			}
+		}
+	}()
+
+	// A panic of the commit itself discards the batch, but keeps the committer running
+	defer func() {
+		if r := recover(); r != nil {
+			w.fail(fmt.Errorf("column: write panicked: %v", r))
+		}
+	}()
+
+	count := 0
+	err := w.owner.Query(func(txn *Txn) error {
+		for _, op := range batch {
+			if op.fn == nil {
+				continue
+			}
+
+			sp := txn.Savepoint()
+			if err := w.call(txn, op.fn); err != nil {
+				txn.RollbackTo(sp)
+				w.fail(err)
+				continue
+			}
+			count++
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil:
+		w.fail(err)
+	case count > 0 && w.opts.OnFlush != nil:
+		w.opts.OnFlush(count)
+	}
+}
+
+// call applies a single write, clearing the filters left by the previous writes of the batch
+// so that every write starts with the full collection, and turning a panic into an error.
+func (w *Writer) call(txn *Txn, fn func(txn *Txn) error) (err error) {
+	txn.setup = false
+	txn.filters = txn.filters[:0]
+	defer txn.closeIterators()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("column: write panicked: %v", r)
+		}
+	}()
+
+	return fn(txn)
+}
+
+// fail records the error and passes it to the callback
+func (w *Writer) fail(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
+
+// takeError returns the first error since the last flush and clears it
+func (w *Writer) takeError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
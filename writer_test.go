@@ -0,0 +1,158 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	coll.CreateColumn("age", ForInt())
+
+	var flushed int64
+	w := NewWriter(coll, WriterOptions{
+		BatchSize: 100,
+		OnFlush: func(count int) {
+			assert.LessOrEqual(t, count, 100)
+			atomic.AddInt64(&flushed, int64(count))
+		},
+	})
+
+	// Insert from many goroutines at once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				assert.NoError(t, w.Insert(Object{"name": "Roman", "age": 35}))
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, 4000, coll.Count())
+	assert.Equal(t, int64(4000), atomic.LoadInt64(&flushed))
+
+	// Update and delete the rows
+	assert.NoError(t, w.UpdateAt(0, func(r Row) error {
+		r.SetInt("age", 36)
+		return nil
+	}))
+	assert.NoError(t, w.DeleteAt(1))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 3999, coll.Count())
+	assert.NoError(t, coll.QueryAt(0, func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 36, age)
+		return nil
+	}))
+
+	// The closed writer rejects the writes
+	assert.ErrorIs(t, w.Insert(Object{"name": "Merlin"}), ErrWriterClosed)
+	assert.ErrorIs(t, w.Flush(), ErrWriterClosed)
+	assert.NoError(t, w.Close())
+}
+
+func TestWriterErrors(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+
+	var reported []error
+	w := NewWriter(coll, WriterOptions{
+		OnError: func(err error) {
+			reported = append(reported, err)
+		},
+	})
+
+	// The failed write is rolled back on its own
+	errFailed := errors.New("failed")
+	assert.NoError(t, w.Insert(Object{"name": "Roman"}))
+	assert.NoError(t, w.Write(func(txn *Txn) error {
+		txn.InsertObject(Object{"name": "Merlin"})
+		return errFailed
+	}))
+	assert.NoError(t, w.Insert(Object{"name": "Arthur"}))
+	assert.ErrorIs(t, w.Flush(), errFailed)
+	assert.Equal(t, 2, coll.Count())
+
+	// The error is cleared by the flush
+	assert.NoError(t, w.Flush())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []error{errFailed}, reported)
+}
+
+func TestWriterBackpressure(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("name", ForString())
+	w := NewWriter(coll, WriterOptions{Capacity: 2})
+
+	// Block the committer, so that the queue fills up
+	release := make(chan struct{})
+	assert.NoError(t, w.Write(func(txn *Txn) error {
+		<-release
+		return nil
+	}))
+
+	assert.Eventually(t, func() bool {
+		return w.Pending() == 0
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, w.Insert(Object{"name": "Roman"}))
+	assert.NoError(t, w.Insert(Object{"name": "Merlin"}))
+	assert.Equal(t, 2, w.Pending())
+
+	// The writers block until there is room in the queue
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.WriteContext(ctx, func(txn *Txn) error {
+		return nil
+	}), context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 2, coll.Count())
+}
+
+func TestWriterFilteredBatch(t *testing.T) {
+	coll := NewCollection()
+	coll.CreateColumn("active", ForBool())
+	for i := 0; i < 10; i++ {
+		coll.InsertObject(Object{"active": i%2 == 0})
+	}
+
+	// Block the committer, so that the writes below end up in the same batch
+	w := NewWriter(coll)
+	release := make(chan struct{})
+	assert.NoError(t, w.Write(func(txn *Txn) error {
+		<-release
+		return nil
+	}))
+
+	var active int
+	assert.NoError(t, w.Write(func(txn *Txn) error {
+		active = txn.With("active").Count()
+		return nil
+	}))
+	assert.NoError(t, w.DeleteAt(1))
+	assert.NoError(t, w.Write(func(txn *Txn) error {
+		panic("boom")
+	}))
+	assert.NoError(t, w.DeleteAt(3))
+
+	close(release)
+	assert.Error(t, w.Flush())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 5, active)
+	assert.Equal(t, 8, coll.Count())
+}